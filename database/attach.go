@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AttachedDB is a SQLite database attached to every connection of the
+// pool under Name, so one statement can read and join across it and the
+// main database:
+//
+//	attach:
+//	  - name: ref
+//	    path: /var/lib/app/reference.db
+//	    read_only: true
+//	  - name: y2025
+//	    path: /var/lib/app/archive-2025.db
+//
+// Each new connection attaches them from the connect hook, before it's
+// handed out, so they're there whichever connection a query lands on; an
+// ATTACH run by hand would only be on the one connection that ran it.
+// Tables are named schema.table in SQL (ref.countries); an unqualified
+// name is looked up in main first, then in the attached ones in order.
+// Migrations, backups and the schema check only see main.
+type AttachedDB struct {
+	Name     string `config:"name"`      // Schema name in SQL, a plain identifier
+	Path     string `config:"path"`      // Database file, created if missing unless ReadOnly
+	ReadOnly bool   `config:"read_only"` // Open it read-only, so a stray write fails instead of changing it
+
+	// SQLCipher key of the attached file. With EncryptionKey, an attached
+	// database without one is a plain one; without, it can't have one.
+	Key string `config:"key"`
+}
+
+// SQLite keeps its own ATTACH limit, 10 unless built with another
+// SQLITE_MAX_ATTACHED
+const maxAttached = 10
+
+// validateAttach reports what's wrong with c.Attach without connecting
+func validateAttach(c Config, d *dialect) error {
+	if len(c.Attach) == 0 {
+		return nil
+	}
+	if d.attachArchive == "" {
+		return fmt.Errorf("attach is SQLite-only, use %s schemas or a foreign data wrapper instead", d.name)
+	}
+
+	var errs []error
+	limit := maxAttached
+	if c.Retention.ArchiveDB != "" {
+		limit-- // ApplyRetention attaches one more while it runs
+	}
+	if len(c.Attach) > limit {
+		errs = append(errs, fmt.Errorf("attach: %d databases, SQLite attaches at most %d here", len(c.Attach), limit))
+	}
+	seen := map[string]bool{}
+	for i, a := range c.Attach {
+		name := strings.ToLower(a.Name) // SQLite's schema names ignore case
+		switch {
+		case !retentionIdent.MatchString(a.Name):
+			errs = append(errs, fmt.Errorf("attach[%d]: name %q isn't a plain identifier", i, a.Name))
+		case name == "main" || name == "temp":
+			errs = append(errs, fmt.Errorf("attach[%d]: %s is SQLite's own schema", i, name))
+		case name == "archive" && c.Retention.ArchiveDB != "":
+			errs = append(errs, fmt.Errorf("attach[%d]: archive is taken by retention.archive_db", i))
+		case seen[name]:
+			errs = append(errs, fmt.Errorf("attach[%d]: %s is attached twice", i, a.Name))
+		case a.Path == "":
+			errs = append(errs, fmt.Errorf("attach[%d]: path is required", i))
+		case a.Key != "" && c.EncryptionKey == "":
+			errs = append(errs, fmt.Errorf("attach[%d]: key needs encryption_key, and a SQLite built with SQLCipher", i))
+		}
+		seen[name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// IntrospectAttached is Introspect for the database attached as name
+func (db *DB) IntrospectAttached(ctx context.Context, name string) (SchemaInfo, error) {
+	d := defaultDialect()
+	if d.introspectAttached == nil {
+		return SchemaInfo{}, fmt.Errorf("%s has no attached databases", d.name)
+	}
+	if !retentionIdent.MatchString(name) {
+		return SchemaInfo{}, fmt.Errorf("%q isn't a schema name", name)
+	}
+	info, err := d.introspectAttached(ctx, db.Conn, name)
+	if err != nil {
+		return SchemaInfo{}, fmt.Errorf("failed to introspect %s: %w", name, err)
+	}
+	return info, nil
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+const (
+	// defaultMaxBlobSize caps PutAttachment when Config.MaxBlobSize isn't set
+	defaultMaxBlobSize = 1 << 20
+
+	// attachmentChunkSize is how much AttachmentReader fetches per query
+	attachmentChunkSize = 64 << 10
+)
+
+var (
+	// ErrBlobTooLarge is returned by PutAttachment when the content is
+	// bigger than MaxBlobSize
+	ErrBlobTooLarge = errors.New("attachment exceeds the configured MaxBlobSize")
+
+	// ErrBlobCorrupt is returned by AttachmentReader when the stored bytes
+	// don't match the stored sha256
+	ErrBlobCorrupt = errors.New("attachment content does not match its sha256")
+)
+
+func (db *DB) blobLimit() int64 {
+	if db.maxBlobSize > 0 {
+		return db.maxBlobSize
+	}
+	return defaultMaxBlobSize
+}
+
+// PutAttachment stores everything read from r. Reading stops one byte past
+// MaxBlobSize, so an oversized upload fails with ErrBlobTooLarge without
+// being buffered in full. Identical content is stored once; the existing
+// row is returned for it.
+func (db *DB) PutAttachment(ctx context.Context, contentType string, r io.Reader) (PutAttachmentRow, error) {
+	limit := db.blobLimit()
+
+	var buf bytes.Buffer
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(&buf, h), io.LimitReader(r, limit+1))
+	if err != nil {
+		return PutAttachmentRow{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if n > limit {
+		return PutAttachmentRow{}, ErrBlobTooLarge
+	}
+
+	return db.Q.PutAttachment(ctx, PutAttachmentParams{
+		Sha256:      h.Sum(nil),
+		ContentType: contentType,
+		Size:        n,
+		Data:        buf.Bytes(),
+	})
+}
+
+// AttachmentReader streams an attachment in chunks of a few KiB, so reading
+// a large one never holds more than one chunk in memory. The content is
+// hashed as it's read and checked against the stored sha256 at the end.
+type AttachmentReader struct {
+	GetAttachmentMetaRow
+
+	ctx  context.Context
+	q    Querier
+	pos  int64 // Bytes handed out so far
+	buf  []byte
+	hash hash.Hash
+}
+
+// OpenAttachment returns a reader over the attachment's content. No
+// connection is held between reads, so a slow consumer doesn't pin one.
+func (db *DB) OpenAttachment(ctx context.Context, id int64) (*AttachmentReader, error) {
+	meta, err := db.Q.GetAttachmentMeta(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &AttachmentReader{
+		GetAttachmentMetaRow: meta,
+		ctx:                  ctx,
+		q:                    db.Q,
+		hash:                 sha256.New(),
+	}, nil
+}
+
+func (r *AttachmentReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.pos >= r.Size {
+			if !bytes.Equal(r.hash.Sum(nil), r.Sha256) {
+				return 0, ErrBlobCorrupt
+			}
+			return 0, io.EOF
+		}
+
+		chunk, err := r.q.ReadAttachmentChunk(r.ctx, ReadAttachmentChunkParams{
+			Start:  r.pos + 1,
+			Length: attachmentChunkSize,
+			ID:     r.ID,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to read attachment %d: %w", r.ID, err)
+		}
+		if len(chunk) == 0 {
+			return 0, ErrBlobCorrupt // shorter than the stored size
+		}
+		r.buf = chunk
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	r.pos += int64(n)
+	r.hash.Write(p[:n])
+	return n, nil
+}
+
+func (r *AttachmentReader) Close() error {
+	r.buf = nil
+	return nil
+}
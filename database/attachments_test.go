@@ -0,0 +1,110 @@
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestAttachmentRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	// Several chunks' worth, NULs included
+	content := bytes.Repeat([]byte{0, 1, 0, 0xff, 'a', 0}, 40_000)
+	put, err := db.PutAttachment(ctx, "application/octet-stream", bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if put.Size != int64(len(content)) {
+		t.Errorf("stored size %d, want %d", put.Size, len(content))
+	}
+
+	r, err := db.OpenAttachment(ctx, put.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("read back %d bytes, %v; want the %d stored", len(got), err, len(content))
+	}
+
+	again, err := db.PutAttachment(ctx, "application/octet-stream", bytes.NewReader(content))
+	if err != nil || again.ID != put.ID {
+		t.Errorf("the same content again: id %d, %v; want the existing %d", again.ID, err, put.ID)
+	}
+	if _, err := db.OpenAttachment(ctx, put.ID+1); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("an unknown attachment: %v, want ErrNotFound", err)
+	}
+}
+
+func TestAttachmentSizeCap(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { c.MaxBlobSize = 10 }})
+
+	if _, err := db.PutAttachment(ctx, "", bytes.NewReader(make([]byte, 10))); err != nil {
+		t.Errorf("exactly MaxBlobSize: %v", err)
+	}
+
+	// The reader never runs out, so the upload only ends if the cap stops it
+	endless := &countingReader{r: zeros{}}
+	if _, err := db.PutAttachment(ctx, "", endless); !errors.Is(err, database.ErrBlobTooLarge) {
+		t.Errorf("past MaxBlobSize: %v, want ErrBlobTooLarge", err)
+	}
+	if endless.n > 11 {
+		t.Errorf("%d bytes read from an oversized upload, want it stopped one past the cap", endless.n)
+	}
+}
+
+func TestAttachmentCorrupt(t *testing.T) {
+	ctx := context.Background()
+	for _, c := range []struct {
+		name, corrupt string
+	}{
+		{"changed", "UPDATE attachments SET sha256 = data"},
+		{"truncated", "UPDATE attachments SET size = size + 1"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			db := dbtest.NewTestDB(t)
+			put, err := db.PutAttachment(ctx, "", bytes.NewReader([]byte("avatar\x00bytes")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.DBTX().ExecContext(ctx, c.corrupt); err != nil {
+				t.Fatal(err)
+			}
+			r, err := db.OpenAttachment(ctx, put.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if _, err := io.ReadAll(r); !errors.Is(err, database.ErrBlobCorrupt) {
+				t.Errorf("reading it: %v, want ErrBlobCorrupt", err)
+			}
+		})
+	}
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Entries PruneAuditLog deletes per statement, and how often the loop
+// Config.AuditRetention starts prunes
+const (
+	auditPruneBatch    = 1000
+	auditPruneInterval = time.Hour
+)
+
+type actorKey struct{}
+
+// ContextWithActor returns ctx carrying actor, the user or service the
+// audit log names for the changes made with it: the schema's triggers
+// record every insert, update and delete on users, groups and user_group,
+// and Transaction and InTx tell them who by. Writes on db.Q outside a
+// transaction are recorded without an actor. Set it once per request,
+// next to ContextWithRequestID.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor ContextWithActor put in ctx, "" if
+// there's none
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// setAuditActor hands the actor to the audit triggers for the rest of
+// tx. With SQLite that's a row in audit_actor, which clearAuditActor
+// deletes before commit; PostgreSQL's setting ends with the transaction.
+func setAuditActor(ctx context.Context, tx DBTX, actor string) error {
+	d := defaultDialect()
+	if d.setAuditActor == "" || actor == "" {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, d.setAuditActor, actor); err != nil {
+		return fmt.Errorf("failed to set the audit actor: %w", err)
+	}
+	return nil
+}
+
+func clearAuditActor(ctx context.Context, tx DBTX, actor string) error {
+	d := defaultDialect()
+	if d.clearAuditActor == "" || actor == "" {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, d.clearAuditActor); err != nil {
+		return fmt.Errorf("failed to clear the audit actor: %w", err)
+	}
+	return nil
+}
+
+// PruneAuditLog deletes the audit log entries older than olderThan, by
+// the database's clock, and returns how many. It deletes in batches, so
+// other writers aren't held up for long. Run it from a scheduled job, or
+// set Config.AuditRetention to have Open start one.
+func (db *DB) PruneAuditLog(ctx context.Context, olderThan time.Duration) (int64, error) {
+	if olderThan < 0 {
+		return 0, fmt.Errorf("prune audit log: negative age %v", olderThan)
+	}
+	var total int64
+	for {
+		n, err := db.Q.PruneAuditLog(ctx, PruneAuditLogParams{
+			OlderThanSeconds: int64(olderThan / time.Second),
+			BatchSize:        auditPruneBatch,
+		})
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < auditPruneBatch {
+			return total, nil
+		}
+	}
+}
+
+// StartAuditPruneLoop prunes audit log entries older than retention every
+// hour until ctx is canceled, logging failures
+func (db *DB) StartAuditPruneLoop(ctx context.Context, retention time.Duration) error {
+	if retention <= 0 {
+		return fmt.Errorf("audit retention must be positive")
+	}
+
+	go func() {
+		ticker := time.NewTicker(auditPruneInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if _, err := db.PruneAuditLog(ctx, retention); err != nil && ctx.Err() == nil {
+				log.Printf("audit log pruning failed: %v", err)
+			}
+		}
+	}()
+	return nil
+}
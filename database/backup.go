@@ -0,0 +1,360 @@
+package database
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBackupRunning is returned by Backup while another backup of the same
+// DB is still running
+var ErrBackupRunning = errors.New("a backup is already running")
+
+// BackupSchedule configures StartBackupLoop, or Config.Backup
+type BackupSchedule struct {
+	Interval time.Duration `config:"interval"` // Time between backups
+	Dir      string        `config:"dir"`      // Where backup files go, created with 0700 if missing
+	Keep     int           `config:"keep"`     // Newest backups kept, older ones are deleted (0 = keep all)
+	Compress bool          `config:"compress"` // Gzip each backup (.db.gz)
+}
+
+// BackupStatus is the outcome of the latest backups, for monitoring
+type BackupStatus struct {
+	Running     bool
+	LastSuccess time.Time // When the last good backup finished
+	LastFile    string    // Path of the last good backup
+	LastError   error     // Error of the last failed backup, nil once one succeeds again
+	LastErrorAt time.Time
+}
+
+type backupState struct {
+	running atomic.Bool
+	mu      sync.Mutex
+	status  BackupStatus
+	stop    context.CancelFunc // Ends the loop Config.Backup started, nil without one
+}
+
+// Backup file names sort in the order they were taken
+const (
+	backupPrefix     = "backup-"
+	backupTimeFormat = "20060102T150405.000Z"
+)
+
+// StartBackupLoop backs the database up into sched.Dir every
+// sched.Interval until ctx is canceled. Each backup is written under a
+// temporary name, checked with a quick integrity check, optionally
+// compressed and only then given its final name; backups beyond
+// sched.Keep are deleted after that, so a failed run never costs a good
+// backup. Results are logged on failure and reported by BackupStatus.
+func (db *DB) StartBackupLoop(ctx context.Context, sched BackupSchedule) error {
+	if err := backupSupported(defaultDialect()); err != nil {
+		return err
+	}
+	if sched.Interval <= 0 {
+		return fmt.Errorf("backup interval must be positive")
+	}
+	if err := os.MkdirAll(sched.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create backup dir: %w", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(sched.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if _, err := db.Backup(ctx, sched); err != nil && ctx.Err() == nil {
+				log.Printf("database backup failed: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Backup takes one backup as scheduled by sched (Interval is ignored) and
+// returns its path. It fails with ErrBackupRunning instead of overlapping
+// another backup.
+func (db *DB) Backup(ctx context.Context, sched BackupSchedule) (string, error) {
+	d := defaultDialect()
+	if err := backupSupported(d); err != nil {
+		return "", err
+	}
+	if !db.backups.running.CompareAndSwap(false, true) {
+		return "", ErrBackupRunning
+	}
+	defer db.backups.running.Store(false)
+
+	path, err := db.backup(ctx, d, sched)
+
+	db.backups.mu.Lock()
+	defer db.backups.mu.Unlock()
+	switch {
+	case err == nil:
+		db.backups.status.LastSuccess = time.Now()
+		db.backups.status.LastFile = path
+		db.backups.status.LastError = nil
+	case ctx.Err() == nil: // a canceled backup isn't a failure
+		db.backups.status.LastError = err
+		db.backups.status.LastErrorAt = time.Now()
+	}
+	return path, err
+}
+
+// BackupStatus reports the latest backup results
+func (db *DB) BackupStatus() BackupStatus {
+	db.backups.mu.Lock()
+	defer db.backups.mu.Unlock()
+	s := db.backups.status
+	s.Running = db.backups.running.Load()
+	return s
+}
+
+func backupSupported(d *dialect) error {
+	if d.backup == nil {
+		return fmt.Errorf("%s has no online backup here, use its own tools (pg_dump)", d.name)
+	}
+	return nil
+}
+
+func (db *DB) backup(ctx context.Context, d *dialect, sched BackupSchedule) (string, error) {
+	if err := os.MkdirAll(sched.Dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create backup dir: %w", err)
+	}
+	final := filepath.Join(sched.Dir, backupPrefix+time.Now().UTC().Format(backupTimeFormat)+".db")
+	tmp := final + ".tmp"
+	defer os.Remove(tmp)
+
+	if err := d.backup(ctx, db.Conn, tmp); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Chmod(tmp, 0o600); err != nil { // it holds everything the database does
+		return "", err
+	}
+	if err := d.checkBackup(ctx, db.Conn, tmp); err != nil {
+		return "", err
+	}
+
+	if sched.Compress {
+		final += ".gz"
+		if err := gzipFile(tmp, final); err != nil {
+			return "", fmt.Errorf("failed to compress backup: %w", err)
+		}
+	} else if err := os.Rename(tmp, final); err != nil {
+		return "", err
+	}
+
+	if err := rotateBackups(sched.Dir, sched.Keep); err != nil {
+		return final, fmt.Errorf("backup written, but old ones weren't rotated out: %w", err)
+	}
+	return final, nil
+}
+
+// Restore replaces the contents of the live database with a backup
+// written by Backup (.db or .db.gz), while the app runs. The backup has to
+// pass the same check as a new one, then SQLite's backup API copies it in
+// as one write: other connections wait for it like for any writer, up to
+// their busy timeout, and read the restored data afterwards. Migrations
+// the backup predates are applied, and Listen subscribers get ChangeResync.
+// It fails with ErrBackupRunning while a backup runs. Needs the
+// mattn/go-sqlite3 driver; RestoreBackup works with nothing open.
+func (db *DB) Restore(ctx context.Context, from string) error {
+	d := defaultDialect()
+	if err := backupSupported(d); err != nil {
+		return err
+	}
+	if !db.backups.running.CompareAndSwap(false, true) {
+		return ErrBackupRunning
+	}
+	defer db.backups.running.Store(false)
+
+	f, err := os.CreateTemp("", "restore-*.db")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	f.Close()
+	defer os.Remove(tmp)
+	if err := copyBackup(from, tmp); err != nil {
+		return fmt.Errorf("failed to copy backup: %w", err)
+	}
+	if err := d.checkBackup(ctx, db.Conn, tmp); err != nil {
+		return err
+	}
+
+	if err := d.restore(ctx, db.Conn, tmp); err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	var resync []ChangeEvent
+	for table := range changeTables {
+		resync = append(resync, ChangeEvent{Table: table, Operation: ChangeResync})
+	}
+	db.changes.publish(resync)
+
+	if err := db.Migrate(ctx); err != nil {
+		return fmt.Errorf("backup restored, but not migrated: %w", err)
+	}
+	return nil
+}
+
+// RestoreBackup replaces the SQLite database file cfg points at with a
+// backup written by Backup (.db or .db.gz). The backup is copied next to
+// the database and has to pass IntegrityCheck before it's renamed over
+// it, so a bad backup leaves the database alone. Nothing may have the
+// database open meanwhile: stop the app first.
+func RestoreBackup(ctx context.Context, from string, cfg Config) error {
+	if err := backupSupported(defaultDialect()); err != nil {
+		return err
+	}
+	path, err := databaseFile(cfg)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".restore"
+	defer os.Remove(tmp)
+	if err := copyBackup(from, tmp); err != nil {
+		return fmt.Errorf("failed to copy backup: %w", err)
+	}
+
+	check := cfg
+	check.DSN, check.LogLevel, check.Backup, check.AuditRetention, check.Maintenance, check.Retention, check.ReadDSNs = tmp, "silent", BackupSchedule{}, 0, MaintenanceSchedule{}, RetentionPolicy{}, nil
+	check.SchemaCheck = "off"
+	db, err := Open(check)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	problems, err := db.IntegrityCheck(ctx)
+	db.Close()
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("backup failed the integrity check: %s", strings.Join(problems, "; "))
+	}
+
+	// A WAL left by the old database would be replayed into the new one
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmp, path)
+}
+
+// databaseFile is the file behind a SQLite DSN
+func databaseFile(cfg Config) (string, error) {
+	if cfg.DSN == "" {
+		return DefaultDBPath(cfg.AppName)
+	}
+	path, params, _ := strings.Cut(strings.TrimPrefix(cfg.DSN, "file:"), "?")
+	if path == "" || path == ":memory:" || strings.Contains(params, "mode=memory") {
+		return "", fmt.Errorf("DSN %q is not a database file", RedactDSN(cfg.Driver, cfg.DSN))
+	}
+	return path, nil
+}
+
+// copyBackup copies src to dst, decompressing a .gz
+func copyBackup(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	if strings.HasSuffix(src, ".gz") {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// gzipFile streams src into a compressed dst, which only appears once
+// it's complete
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst + ".tmp")
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(dst+".tmp", dst)
+}
+
+// rotateBackups deletes all but the newest keep backups in dir
+func rotateBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		n := e.Name()
+		if strings.HasPrefix(n, backupPrefix) && (strings.HasSuffix(n, ".db") || strings.HasSuffix(n, ".db.gz")) {
+			names = append(names, n)
+		}
+	}
+	if len(names) <= keep {
+		return nil
+	}
+
+	slices.Sort(names)
+	var errs []error
+	for _, n := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, n)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,154 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// backups lists dir's files, which should all be finished backups
+func backups(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestBackupLoop(t *testing.T) {
+	db := dbtest.NewTestDB(t)
+	newUsers(t, db, 1, 2, 3)
+	dir := filepath.Join(t.TempDir(), "backups")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := db.StartBackupLoop(ctx, database.BackupSchedule{Interval: 20 * time.Millisecond, Dir: dir, Keep: 2, Compress: true}); err != nil {
+		t.Fatal(err)
+	}
+	taken := map[string]bool{}
+	for deadline := time.Now().Add(10 * time.Second); len(taken) < 4; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d backups in 10s: %+v", len(taken), db.BackupStatus())
+		}
+		if s := db.BackupStatus(); s.LastFile != "" {
+			taken[s.LastFile] = true
+		}
+	}
+	cancel()
+	for db.BackupStatus().Running {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Canceled, the loop takes no more
+	time.Sleep(100 * time.Millisecond)
+	names := backups(t, dir)
+	if len(names) != 2 {
+		t.Fatalf("backups left %v, want the newest 2", names)
+	}
+	for _, n := range names {
+		if !strings.HasPrefix(n, "backup-") || !strings.HasSuffix(n, ".db.gz") {
+			t.Errorf("backup file %s, want backup-<time>.db.gz", n)
+		}
+	}
+	if last := db.BackupStatus().LastFile; filepath.Base(last) != names[1] {
+		t.Errorf("last backup %s, want the newest file %s", last, names[1])
+	}
+	if time.Sleep(100 * time.Millisecond); !slices.Equal(backups(t, dir), names) {
+		t.Errorf("the loop kept going after its context was canceled")
+	}
+
+	// Decompressed, the backup is the database
+	cfg := database.Config{DSN: filepath.Join(t.TempDir(), "restored.db"), LogLevel: "silent"}
+	if err := database.RestoreBackup(context.Background(), filepath.Join(dir, names[1]), cfg); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := database.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if n, err := restored.Q.CountUsersByStatus(context.Background(), database.StatusActive); err != nil || n != 3 {
+		t.Errorf("users in the restored backup: %d, %v; want 3", n, err)
+	}
+}
+
+func TestBackupOverlap(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	sched := database.BackupSchedule{Dir: t.TempDir()}
+	// Big enough for the backup to still be running when the second comes
+	if _, err := db.Conn.Exec("CREATE TABLE big (b BLOB); INSERT INTO big VALUES (zeroblob(64 << 20))"); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := db.Backup(ctx, sched)
+		done <- err
+	}()
+	for !db.BackupStatus().Running {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := db.Backup(ctx, sched); !errors.Is(err, database.ErrBackupRunning) {
+		t.Errorf("a backup while another runs: %v, want ErrBackupRunning", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := len(backups(t, sched.Dir)); n != 1 {
+		t.Errorf("%d backup files, want the one that ran", n)
+	}
+}
+
+func TestBackupStatusAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	good := database.BackupSchedule{Dir: t.TempDir()}
+	first, err := db.Backup(ctx, good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	okAt := db.BackupStatus().LastSuccess
+
+	// A file where the directory should be can't be written into, even
+	// by root
+	blocked := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocked, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Backup(ctx, database.BackupSchedule{Dir: filepath.Join(blocked, "backups")}); err == nil {
+		t.Fatal("a backup into an unwritable dir succeeded")
+	}
+	s := db.BackupStatus()
+	if s.LastError == nil || s.LastErrorAt.Before(okAt) {
+		t.Errorf("status after a failure: %+v, want its error", s)
+	}
+	if s.LastFile != first || !s.LastSuccess.Equal(okAt) {
+		t.Errorf("status after a failure: %+v, want the earlier success kept", s)
+	}
+	if err := db.StartBackupLoop(ctx, database.BackupSchedule{Interval: time.Hour, Dir: filepath.Join(blocked, "backups")}); err == nil {
+		t.Error("StartBackupLoop accepted an unwritable dir")
+	}
+
+	if _, err := db.Backup(ctx, good); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.BackupStatus(); s.LastError != nil || !s.LastSuccess.After(okAt) {
+		t.Errorf("status after a new success: %+v, want the error cleared", s)
+	}
+}
@@ -0,0 +1,327 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrBatchAborted is the error of every query queued after the one that
+// failed a Batch; none of them ran
+var ErrBatchAborted = errors.New("not run: an earlier query in the batch failed")
+
+// errBatchNotRun is a BatchResult's error until Run
+var errBatchNotRun = errors.New("batch hasn't run yet")
+
+// errBatchUnsupported sends a dialect's sendBatch back to running the
+// queries one by one
+var errBatchUnsupported = errors.New("driver can't pipeline queries")
+
+// Batch queues read queries to send together, for handlers that need
+// several small results at once. On PostgreSQL with pgx, Run pipelines
+// them: five queries cost one round trip instead of five. Elsewhere (and
+// with lib/pq) they run one after another in a single transaction, with
+// the same results. A Batch is used once and isn't safe for concurrent
+// use.
+type Batch struct {
+	db      *DB
+	queries []*queuedQuery
+	ran     bool
+}
+
+// BatchResult is one queued query's result, filled in by Run
+type BatchResult[T any] struct {
+	Value T
+	Err   error // sql.ErrNoRows for a missing row, ErrBatchAborted if it never ran
+}
+
+// BatchItem is a query's result as Run returns it, in queue order
+type BatchItem struct {
+	Query string // Query name, as in Querier
+	Value any
+	Err   error
+}
+
+type queuedQuery struct {
+	name    string
+	sql     string
+	args    []any
+	read    func(batchRows) (any, error)
+	deliver func(v any, err error)
+	invalid error // Why it can't be sent, found while queuing
+
+	done  bool
+	value any
+	err   error
+}
+
+// batchRows is what both *sql.Rows and pgx.Rows (through pgxBatchRows)
+// offer for reading a result
+type batchRows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+}
+
+// NewBatch starts an empty batch
+func (db *DB) NewBatch() *Batch {
+	return &Batch{db: db}
+}
+
+// QueueOne queues a read query returning one row, by its Querier name,
+// with the arguments the generated method passes in the same order:
+//
+//	r := database.QueueOne[database.User](b, "GetUserByEmail", email)
+//
+// Columns go to the fields whose json tags name them, as sqlc tags its
+// structs, or to T itself for a single column. Run reports sql.ErrNoRows
+// like the generated method. Only queue reads: the fallback transaction
+// is rolled back. A name querySQL doesn't know fails the batch before
+// anything is sent. Queuing after Run panics, a mistake of the code
+// itself.
+func QueueOne[T any](b *Batch, name string, args ...any) *BatchResult[T] {
+	return queue(b, name, args, func(rows batchRows) (T, error) {
+		var v T
+		dest, err := scanDest(&v, rows)
+		if err != nil {
+			return v, err
+		}
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return v, err
+			}
+			return v, sql.ErrNoRows
+		}
+		err = rows.Scan(dest...)
+		return v, err
+	})
+}
+
+// QueueMany queues a query returning any number of rows, like QueueOne
+func QueueMany[T any](b *Batch, name string, args ...any) *BatchResult[[]T] {
+	return queue(b, name, args, func(rows batchRows) ([]T, error) {
+		items := []T{} // sqlc's emit_empty_slices
+		var v T
+		dest, err := scanDest(&v, rows)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() { // Every column is scanned, so v needs no reset
+			if err := rows.Scan(dest...); err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, rows.Err()
+	})
+}
+
+func queue[T any](b *Batch, name string, args []any, read func(batchRows) (T, error)) *BatchResult[T] {
+	if b.ran {
+		panic("database: query " + name + " queued on a Batch that already ran")
+	}
+	query, ok := querySQL[name]
+	var invalid error
+	if !ok {
+		invalid = fmt.Errorf("unknown query %q", name)
+	}
+
+	r := &BatchResult[T]{Err: errBatchNotRun}
+	b.queries = append(b.queries, &queuedQuery{
+		name:    name,
+		sql:     query,
+		args:    args,
+		invalid: invalid,
+		read:    func(rows batchRows) (any, error) { return read(rows) },
+		deliver: func(v any, err error) {
+			if err == nil {
+				r.Value = v.(T)
+			}
+			r.Err = err
+		},
+	})
+	return r
+}
+
+// QueueGetUserByTelegramID queues a GetUserByTelegramID call
+func (b *Batch) QueueGetUserByTelegramID(telegramID int64) *BatchResult[User] {
+	return QueueOne[User](b, "GetUserByTelegramID", telegramID)
+}
+
+// QueueGetUserByID queues a GetUserByID call
+func (b *Batch) QueueGetUserByID(id int64) *BatchResult[User] {
+	return QueueOne[User](b, "GetUserByID", id)
+}
+
+// QueueGetGroupByTelegramID queues a GetGroupByTelegramID call
+func (b *Batch) QueueGetGroupByTelegramID(telegramID int64) *BatchResult[Group] {
+	return QueueOne[Group](b, "GetGroupByTelegramID", telegramID)
+}
+
+// QueueGetUserGroup queues a GetUserGroup call
+func (b *Batch) QueueGetUserGroup(arg GetUserGroupParams) *BatchResult[UserGroup] {
+	return QueueOne[UserGroup](b, "GetUserGroup", arg.UserTelegramID, arg.GroupTelegramID)
+}
+
+// QueueGetTopGroupsForUser queues a GetTopGroupsForUser call
+func (b *Batch) QueueGetTopGroupsForUser(userTelegramID int64) *BatchResult[[]GetTopGroupsForUserRow] {
+	return QueueMany[GetTopGroupsForUserRow](b, "GetTopGroupsForUser", userTelegramID)
+}
+
+// QueueListGroupMembers queues a ListGroupMembers call
+func (b *Batch) QueueListGroupMembers(groupTelegramID int64) *BatchResult[[]ListGroupMembersRow] {
+	return QueueMany[ListGroupMembersRow](b, "ListGroupMembers", groupTelegramID)
+}
+
+// QueueListGroupTags queues a ListGroupTags call
+func (b *Batch) QueueListGroupTags(groupTelegramID int64) *BatchResult[[]Tag] {
+	return QueueMany[Tag](b, "ListGroupTags", groupTelegramID)
+}
+
+// QueueCountUsersByStatus queues a CountUsersByStatus call
+func (b *Batch) QueueCountUsersByStatus(status Status) *BatchResult[int64] {
+	return QueueOne[int64](b, "CountUsersByStatus", status)
+}
+
+// Run sends the queued queries and fills in their results, which it also
+// returns in queue order. The first query to fail (other than with
+// sql.ErrNoRows) stops the batch: its error is returned, and every query
+// after it gets ErrBatchAborted. A query that can't be sent, queued by a
+// name querySQL doesn't know, fails the batch before anything is sent,
+// and all the others get ErrBatchAborted. A batch runs once; another Run
+// is an error.
+func (b *Batch) Run(ctx context.Context) ([]BatchItem, error) {
+	if b.ran {
+		return nil, errors.New("batch already ran")
+	}
+	b.ran = true
+	if len(b.queries) == 0 {
+		return nil, nil
+	}
+
+	err := b.check()
+	if err == nil {
+		err = errBatchUnsupported
+		if d := defaultDialect(); d.sendBatch != nil {
+			err = b.send(ctx, d)
+		}
+		if errors.Is(err, errBatchUnsupported) {
+			err = b.runInTx(ctx)
+		}
+	}
+
+	items := make([]BatchItem, len(b.queries))
+	for i, q := range b.queries {
+		if !q.done {
+			q.err = ErrBatchAborted
+		}
+		q.deliver(q.value, q.err)
+		items[i] = BatchItem{Query: q.name, Value: q.value, Err: q.err}
+	}
+	return items, err
+}
+
+// check fails the first query that can't be sent, so none are
+func (b *Batch) check() error {
+	for _, q := range b.queries {
+		if q.invalid != nil {
+			return q.fail(q.invalid)
+		}
+	}
+	return nil
+}
+
+func (b *Batch) send(ctx context.Context, d *dialect) error {
+	if b.db.breaker == nil {
+		return d.sendBatch(ctx, b.db.Conn, b.queries)
+	}
+	call, err := b.db.breaker.allow()
+	if err != nil {
+		return err
+	}
+	err = d.sendBatch(ctx, b.db.Conn, b.queries)
+	b.db.breaker.record(call, err)
+	return err
+}
+
+// runInTx is the fallback: the queries one by one, in a transaction so
+// they all see the same database
+func (b *Batch) runInTx(ctx context.Context) error {
+	tx, err := b.db.beginTx(ctx, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Only reads, nothing to commit
+
+	dbtx := b.db.wrapTx(tx, nil)
+	for _, q := range b.queries {
+		rows, err := dbtx.QueryContext(ctx, q.sql, q.args...)
+		if err != nil {
+			return q.fail(err)
+		}
+		err = q.scan(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scan reads the query's result; only a failure that stops the batch is
+// returned
+func (q *queuedQuery) scan(rows batchRows) error {
+	q.value, q.err = q.read(rows)
+	q.done = true
+	if errors.Is(q.err, sql.ErrNoRows) {
+		return nil
+	}
+	return q.err
+}
+
+func (q *queuedQuery) fail(err error) error {
+	q.err, q.done = err, true
+	return err
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// scanDest lists where rows' columns go: for a row struct, the field
+// whose json tag is the column's name, as sqlc writes it; otherwise the
+// value itself, which then has to be the only column
+func scanDest(ptr any, rows batchRows) ([]any, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(ptr).Elem()
+	if v.Kind() != reflect.Struct || v.Type() == timeType || reflect.PointerTo(v.Type()).Implements(scannerType) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("query returns %d columns, too many for a %s", len(columns), v.Type())
+		}
+		return []any{ptr}, nil
+	}
+
+	fields := map[string]int{}
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		fields[name] = i
+	}
+	dest := make([]any, len(columns))
+	for i, col := range columns {
+		f, ok := fields[col]
+		if !ok {
+			return nil, fmt.Errorf("query returns column %s, which %s has no field for", col, v.Type())
+		}
+		dest[i] = v.Field(f).Addr().Interface()
+	}
+	return dest, nil
+}
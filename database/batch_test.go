@@ -0,0 +1,140 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	newMembers(t, db)
+
+	b := db.NewBatch()
+	user := b.QueueGetUserByTelegramID(1)
+	missing := b.QueueGetUserByTelegramID(99)
+	count := b.QueueCountUsersByStatus(database.StatusActive)
+	members := b.QueueListGroupMembers(20)
+	none := b.QueueListGroupMembers(99)
+	items, err := b.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same results as the generated methods, in queue order
+	wantUser, _ := db.Q.GetUserByTelegramID(ctx, 1)
+	wantMembers, _ := db.Q.ListGroupMembers(ctx, 20)
+	if user.Err != nil || !reflect.DeepEqual(user.Value, wantUser) {
+		t.Errorf("user: %+v, %v; want %+v", user.Value, user.Err, wantUser)
+	}
+	if !errors.Is(missing.Err, sql.ErrNoRows) {
+		t.Errorf("a missing user: %v, want sql.ErrNoRows", missing.Err)
+	}
+	if count.Err != nil || count.Value != 3 {
+		t.Errorf("count: %d, %v; want 3", count.Value, count.Err)
+	}
+	if members.Err != nil || !reflect.DeepEqual(members.Value, wantMembers) {
+		t.Errorf("members: %+v, %v; want %+v", members.Value, members.Err, wantMembers)
+	}
+	if none.Err != nil || none.Value == nil || len(none.Value) != 0 {
+		t.Errorf("no members: %#v, %v; want an empty slice", none.Value, none.Err)
+	}
+	var names []string
+	for _, it := range items {
+		names = append(names, it.Query)
+	}
+	if got := strings.Join(names, " "); got != "GetUserByTelegramID GetUserByTelegramID CountUsersByStatus ListGroupMembers ListGroupMembers" {
+		t.Errorf("items in the order %s", got)
+	}
+
+	if _, err := b.Run(ctx); err == nil {
+		t.Error("a batch ran twice")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("queuing after Run didn't panic")
+		}
+	}()
+	b.QueueCountUsersByStatus(database.StatusActive)
+}
+
+// member is ListGroupMembersRow with its fields in another order, which
+// columns are matched to by name
+type member struct {
+	Username        sql.Null[string]         `json:"username"`
+	FirstName       string                   `json:"first_name"`
+	Balance         sql.Null[database.Money] `json:"balance"`
+	GroupTelegramID int64                    `json:"group_telegram_id"`
+	UserTelegramID  int64                    `json:"user_telegram_id"`
+	ID              int64                    `json:"id"`
+}
+
+func TestBatchColumnsByName(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	newMembers(t, db)
+
+	b := db.NewBatch()
+	got := database.QueueMany[member](b, "ListGroupMembers", int64(20))
+	if _, err := b.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := db.Q.ListGroupMembers(ctx, 20)
+	if len(got.Value) != len(want) {
+		t.Fatalf("%d members, want %d", len(got.Value), len(want))
+	}
+	for i, w := range want {
+		if g := got.Value[i]; g.ID != w.ID || g.UserTelegramID != w.UserTelegramID || g.GroupTelegramID != w.GroupTelegramID || g.FirstName != w.FirstName {
+			t.Errorf("member %d: %+v, want %+v", i, g, w)
+		}
+	}
+
+	// A column without a field fails instead of landing in the wrong one
+	b = db.NewBatch()
+	partial := database.QueueOne[struct {
+		ID int64 `json:"id"`
+	}](b, "GetUserByTelegramID", int64(1))
+	if _, err := b.Run(ctx); err == nil || partial.Err == nil || !strings.Contains(err.Error(), "no field for") {
+		t.Errorf("columns the struct lacks: %v, want an error naming the column", err)
+	}
+}
+
+func TestBatchFailure(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	newMembers(t, db)
+
+	// A user row read into an int fails mid-batch
+	b := db.NewBatch()
+	before := b.QueueCountUsersByStatus(database.StatusActive)
+	bad := database.QueueOne[int64](b, "GetUserByTelegramID", int64(1))
+	after := b.QueueGetUserByTelegramID(1)
+	items, err := b.Run(ctx)
+	if err == nil || !errors.Is(bad.Err, err) {
+		t.Errorf("Run: %v, want the failed query's error %v", err, bad.Err)
+	}
+	if before.Err != nil || before.Value != 3 {
+		t.Errorf("the query before the failure: %d, %v", before.Value, before.Err)
+	}
+	if !errors.Is(after.Err, database.ErrBatchAborted) || !errors.Is(items[2].Err, database.ErrBatchAborted) {
+		t.Errorf("the query after the failure: %v, want ErrBatchAborted", after.Err)
+	}
+
+	// An unknown name fails the batch before anything is sent
+	b = db.NewBatch()
+	first := b.QueueCountUsersByStatus(database.StatusActive)
+	unknown := database.QueueOne[int64](b, "GetUserByShoeSize", 42)
+	if _, err := b.Run(ctx); err == nil || !strings.Contains(err.Error(), `unknown query "GetUserByShoeSize"`) || unknown.Err != err {
+		t.Errorf("an unknown query: %v, %v; want the batch to fail with it", err, unknown.Err)
+	}
+	if !errors.Is(first.Err, database.ErrBatchAborted) {
+		t.Errorf("a query queued before an unknown one: %v, want it not sent", first.Err)
+	}
+}
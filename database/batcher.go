@@ -0,0 +1,293 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBatcherClosed is returned for writes enqueued after Shutdown
+var ErrBatcherClosed = errors.New("write batcher is shut down")
+
+// BatcherOptions controls when a WriteBatcher flushes
+type BatcherOptions struct {
+	MaxItems  int           // Flush once this many writes are queued (default 100)
+	MaxDelay  time.Duration // Flush at least this often (default 10ms)
+	QueueSize int           // Enqueue blocks once this many writes are waiting (default 1000)
+}
+
+// WriteResult is delivered once the batch holding the write has committed
+type WriteResult[T any] struct {
+	Value T
+	Err   error
+}
+
+// WriteBatcher groups many small writes into one transaction, so thousands
+// of inserts pay for one fsync instead of one each. DB.Shutdown shuts down
+// the ones still running before it refuses transactions, so what they
+// hold is written.
+type WriteBatcher struct {
+	db    *DB
+	opts  BatcherOptions
+	items chan *batchItem
+	done  chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type batchItem struct {
+	exec    func(ctx context.Context, q *Queries) error // nil for Flush: write what's queued before it
+	deliver func(err error)
+}
+
+// batcherSet is the WriteBatchers of a DB that haven't been shut down
+type batcherSet struct {
+	mu   sync.Mutex
+	open map[*WriteBatcher]bool
+}
+
+func (s *batcherSet) add(b *WriteBatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.open == nil {
+		s.open = map[*WriteBatcher]bool{}
+	}
+	s.open[b] = true
+}
+
+func (s *batcherSet) remove(b *WriteBatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.open, b)
+}
+
+// shutdown shuts down every batcher still open, each flushing its queue
+func (s *batcherSet) shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	open := make([]*WriteBatcher, 0, len(s.open))
+	for b := range s.open {
+		open = append(open, b)
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, b := range open {
+		if err := b.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush write batcher: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewWriteBatcher starts a batcher. Call Shutdown to flush what's left.
+func (db *DB) NewWriteBatcher(opts BatcherOptions) *WriteBatcher {
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = 100
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 10 * time.Millisecond
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1000
+	}
+
+	b := &WriteBatcher{
+		db:    db,
+		opts:  opts,
+		items: make(chan *batchItem, opts.QueueSize),
+		done:  make(chan struct{}),
+	}
+	db.batchers.add(b)
+	go b.loop()
+	return b
+}
+
+// Enqueue queues any write. Writes run in enqueue order; each one gets its
+// own savepoint, so a constraint error is reported to that write only and
+// the rest of the batch still commits.
+func Enqueue[T any](b *WriteBatcher, fn func(context.Context, *Queries) (T, error)) <-chan WriteResult[T] {
+	ch := make(chan WriteResult[T], 1)
+	var v T
+	it := &batchItem{
+		exec: func(ctx context.Context, q *Queries) (err error) {
+			v, err = fn(ctx, q)
+			return err
+		},
+		deliver: func(err error) {
+			if err != nil {
+				ch <- WriteResult[T]{Err: err}
+			} else {
+				ch <- WriteResult[T]{Value: v}
+			}
+			close(ch)
+		},
+	}
+
+	if err := b.enqueue(context.Background(), it, true); err != nil {
+		it.deliver(err)
+	}
+	return ch
+}
+
+// errBatcherFull is enqueue's error when it mustn't wait and the queue is
+// full
+var errBatcherFull = errors.New("write batcher queue is full")
+
+// enqueue queues it, waiting for room until ctx ends if wait is set
+func (b *WriteBatcher) enqueue(ctx context.Context, it *batchItem, wait bool) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrBatcherClosed
+	}
+	if !wait {
+		select {
+		case b.items <- it:
+			return nil
+		default:
+			return errBatcherFull
+		}
+	}
+	select {
+	case b.items <- it:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// EnqueueCreateUser queues a CreateUser call
+func (b *WriteBatcher) EnqueueCreateUser(arg CreateUserParams) <-chan WriteResult[User] {
+	return Enqueue(b, func(ctx context.Context, q *Queries) (User, error) {
+		return q.CreateUser(ctx, arg)
+	})
+}
+
+// EnqueueCreateGroup queues a CreateGroup call
+func (b *WriteBatcher) EnqueueCreateGroup(arg CreateGroupParams) <-chan WriteResult[Group] {
+	return Enqueue(b, func(ctx context.Context, q *Queries) (Group, error) {
+		return q.CreateGroup(ctx, arg)
+	})
+}
+
+// EnqueueCreateUserGroup queues a CreateUserGroup call
+func (b *WriteBatcher) EnqueueCreateUserGroup(arg CreateUserGroupParams) <-chan WriteResult[UserGroup] {
+	return Enqueue(b, func(ctx context.Context, q *Queries) (UserGroup, error) {
+		return q.CreateUserGroup(ctx, arg)
+	})
+}
+
+// Flush writes everything queued before it now, without waiting for
+// MaxItems or MaxDelay, and returns once that has committed or failed
+func (b *WriteBatcher) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	it := &batchItem{deliver: func(error) { close(done) }}
+	if err := b.enqueue(ctx, it, true); err != nil {
+		return err
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops accepting writes and flushes everything already queued
+func (b *WriteBatcher) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.items)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		b.db.batchers.remove(b)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *WriteBatcher) loop() {
+	defer close(b.done)
+
+	var batch []*batchItem
+	var timer *time.Timer
+	var timeout <-chan time.Time
+
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timeout = nil
+		}
+		b.flush(batch)
+		batch = nil
+	}
+
+	for {
+		select {
+		case it, ok := <-b.items:
+			if !ok {
+				flush()
+				return
+			}
+			if it.exec == nil {
+				flush()
+				it.deliver(nil)
+				continue
+			}
+			batch = append(batch, it)
+			if len(batch) == 1 {
+				timer = time.NewTimer(b.opts.MaxDelay)
+				timeout = timer.C
+			}
+			if len(batch) >= b.opts.MaxItems {
+				flush()
+			}
+		case <-timeout:
+			flush()
+		}
+	}
+}
+
+func (b *WriteBatcher) flush(batch []*batchItem) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	errs := make([]error, len(batch))
+
+	err := b.db.Transaction(ctx, func(q *Queries) error {
+		for i, it := range batch {
+			if _, err := q.db.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+				return fmt.Errorf("failed to create savepoint: %w", err)
+			}
+
+			if errs[i] = it.exec(ctx, q); errs[i] != nil {
+				if _, err := q.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_item"); err != nil {
+					return fmt.Errorf("failed to roll back savepoint: %w", err)
+				}
+			}
+
+			if _, err := q.db.ExecContext(ctx, "RELEASE SAVEPOINT batch_item"); err != nil {
+				return fmt.Errorf("failed to release savepoint: %w", err)
+			}
+		}
+		return nil
+	})
+
+	for i, it := range batch {
+		if err != nil {
+			it.deliver(err)
+		} else {
+			it.deliver(errs[i])
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// wait returns ch's result, failing the test if none comes within a while
+func wait[T any](t testing.TB, ch <-chan database.WriteResult[T]) database.WriteResult[T] {
+	t.Helper()
+	select {
+	case res := <-ch:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result after 5s: the batch was never flushed")
+		panic("unreachable")
+	}
+}
+
+func TestBatcherFlushOnSize(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	b := db.NewWriteBatcher(database.BatcherOptions{MaxItems: 3, MaxDelay: time.Hour})
+	defer b.Shutdown(ctx)
+
+	var chs []<-chan database.WriteResult[database.User]
+	for id := int64(1); id <= 3; id++ {
+		chs = append(chs, b.EnqueueCreateUser(database.CreateUserParams{TelegramID: id, FirstName: "user"}))
+	}
+	var last int64
+	for i, ch := range chs {
+		res := wait(t, ch)
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		if res.Value.TelegramID != int64(i+1) || res.Value.ID <= last {
+			t.Errorf("write %d: got user %+v after id %d; want them in enqueue order", i, res.Value, last)
+		}
+		last = res.Value.ID
+	}
+}
+
+func TestBatcherFlushOnTimer(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	const delay = 20 * time.Millisecond
+	b := db.NewWriteBatcher(database.BatcherOptions{MaxItems: 100, MaxDelay: delay})
+	defer b.Shutdown(ctx)
+
+	start := time.Now()
+	res := wait(t, b.EnqueueCreateUser(database.CreateUserParams{TelegramID: 1, FirstName: "user"}))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("one write of 100 flushed after %v, before MaxDelay", elapsed)
+	}
+}
+
+func TestBatcherShutdownDrains(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	b := db.NewWriteBatcher(database.BatcherOptions{MaxItems: 1000, MaxDelay: time.Hour})
+
+	var chs []<-chan database.WriteResult[database.User]
+	for id := int64(1); id <= 50; id++ {
+		chs = append(chs, b.EnqueueCreateUser(database.CreateUserParams{TelegramID: id, FirstName: "user"}))
+	}
+	dup := b.EnqueueCreateUser(database.CreateUserParams{TelegramID: 7, FirstName: "again"})
+	if err := b.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, ch := range chs {
+		if res := wait(t, ch); res.Err != nil {
+			t.Errorf("write %d: %v", i, res.Err)
+		}
+	}
+	if res := wait(t, dup); res.Err == nil {
+		t.Error("a duplicate telegram_id was written")
+	}
+	n, err := db.Q.CountUsersByStatus(ctx, database.StatusActive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 50 {
+		t.Errorf("%d users after Shutdown, want the 50 queued", n)
+	}
+
+	res := wait(t, b.EnqueueCreateUser(database.CreateUserParams{TelegramID: 51, FirstName: "late"}))
+	if !errors.Is(res.Err, database.ErrBatcherClosed) {
+		t.Errorf("a write after Shutdown: %v, want ErrBatcherClosed", res.Err)
+	}
+}
+
+// BenchmarkCreateUserLoop is the baseline: one transaction per insert
+func BenchmarkCreateUserLoop(b *testing.B) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: int64(i + 1), FirstName: "user"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCreateUserBatcher(b *testing.B) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(b)
+	wb := db.NewWriteBatcher(database.BatcherOptions{MaxItems: 500, MaxDelay: 10 * time.Millisecond, QueueSize: 10000})
+	defer wb.Shutdown(ctx)
+	b.ResetTimer()
+
+	chs := make([]<-chan database.WriteResult[database.User], b.N)
+	for i := range chs {
+		chs[i] = wb.EnqueueCreateUser(database.CreateUserParams{TelegramID: int64(i + 1), FirstName: "user"})
+	}
+	for _, ch := range chs {
+		if res := wait(b, ch); res.Err != nil {
+			b.Fatal(res.Err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without touching the database while the
+// circuit breaker is open
+var ErrCircuitOpen = errors.New("database circuit breaker is open")
+
+// BreakerOptions enables the circuit breaker. After Threshold consecutive
+// connection errors every query fails fast with ErrCircuitOpen for
+// CoolDown; then a single query is let through as a probe, and its result
+// closes the circuit again or restarts the cool-down.
+type BreakerOptions struct {
+	Threshold int           `config:"threshold"` // Consecutive connection errors that open the circuit (0 disables the breaker)
+	CoolDown  time.Duration `config:"cool_down"` // How long the circuit stays open before probing (default 10s)
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type breaker struct {
+	opts    BreakerOptions
+	label   string           // Logged after "circuit breaker", for the breakers of query policies
+	failure func(error) bool // What counts as a failure, isConnectionError by default
+
+	mu       sync.Mutex
+	state    breakerState
+	gen      uint64 // Bumped on every change of state
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// breakerCall is what allow hands record: the state generation the call
+// started under, and whether it's the half-open probe
+type breakerCall struct {
+	gen   uint64
+	probe bool
+}
+
+func newBreaker(opts BreakerOptions) *breaker {
+	if opts.Threshold <= 0 {
+		return nil
+	}
+	if opts.CoolDown <= 0 {
+		opts.CoolDown = 10 * time.Second
+	}
+	return &breaker{opts: opts, failure: isConnectionError}
+}
+
+// allow reports whether a call may go to the database. Every allowed call
+// must be followed by record, with the breakerCall it returned.
+func (b *breaker) allow() (breakerCall, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.opts.CoolDown {
+			return breakerCall{}, ErrCircuitOpen
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return breakerCall{gen: b.gen, probe: true}, nil
+	case breakerHalfOpen:
+		if b.probing {
+			return breakerCall{}, ErrCircuitOpen
+		}
+		b.probing = true
+		return breakerCall{gen: b.gen, probe: true}, nil
+	}
+	return breakerCall{gen: b.gen}, nil
+}
+
+// record counts only connection-class errors as failures, unless failure
+// says otherwise. Constraint violations, sql.ErrNoRows and the like prove
+// the server is answering. A call that started before the state last
+// changed is ignored: a slow success from before the circuit opened says
+// nothing about the server now, and only the probe may close it.
+func (b *breaker) record(call breakerCall, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if call.gen != b.gen {
+		return
+	}
+	if call.probe {
+		b.probing = false
+	}
+
+	if err != nil && b.failure(err) {
+		b.failures++
+		if b.state == breakerHalfOpen || b.failures >= b.opts.Threshold {
+			log.Printf("database circuit breaker%s opened after %d failures: %v", b.label, b.failures, err)
+			b.setState(breakerOpen)
+			b.openedAt = time.Now()
+			b.failures = 0
+		}
+		return
+	}
+
+	if b.state != breakerClosed {
+		log.Printf("database circuit breaker%s closed, the probe query succeeded", b.label)
+		b.setState(breakerClosed)
+	}
+	b.failures = 0
+}
+
+func (b *breaker) setState(state breakerState) {
+	b.state = state
+	b.gen++
+}
+
+// breakerDBTX guards every statement with the breaker
+type breakerDBTX struct {
+	DBTX
+	b *breaker
+}
+
+func (d *breakerDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	call, err := d.b.allow()
+	if err != nil {
+		return nil, err
+	}
+	res, err := d.DBTX.ExecContext(ctx, query, args...)
+	d.b.record(call, err)
+	return res, err
+}
+
+func (d *breakerDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	call, err := d.b.allow()
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := d.DBTX.PrepareContext(ctx, query)
+	d.b.record(call, err)
+	return stmt, err
+}
+
+func (d *breakerDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	call, err := d.b.allow()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.DBTX.QueryContext(ctx, query, args...)
+	d.b.record(call, err)
+	return rows, err
+}
+
+func (d *breakerDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	call, err := d.b.allow()
+	if err != nil {
+		return errRow(ctx, err)
+	}
+	row := d.DBTX.QueryRowContext(ctx, query, args...)
+	d.b.record(call, row.Err())
+	return row
+}
+
+// errRow returns a *sql.Row whose Scan fails with err. *sql.Row can't be
+// built outside database/sql, so the query goes to a throwaway *sql.DB
+// whose connector always returns err.
+func errRow(ctx context.Context, err error) *sql.Row {
+	db := sql.OpenDB(failingConnector{err})
+	defer db.Close()
+	return db.QueryRowContext(ctx, "")
+}
+
+type failingConnector struct{ err error }
+
+func (c failingConnector) Connect(context.Context) (driver.Conn, error) { return nil, c.err }
+func (c failingConnector) Driver() driver.Driver                        { return failingDriver{c.err} }
+
+type failingDriver struct{ err error }
+
+func (d failingDriver) Open(string) (driver.Conn, error) { return nil, d.err }
+
+// String names the state for readiness reports
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// current reports the state; a nil breaker is "disabled"
+func (b *breaker) current() string {
+	if b == nil {
+		return "disabled"
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen && time.Since(b.openedAt) >= b.opts.CoolDown {
+		return breakerHalfOpen.String() // the next query is the probe
+	}
+	return b.state.String()
+}
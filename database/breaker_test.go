@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBreakerIgnoresStaleCalls(t *testing.T) {
+	down := errors.New("connection refused")
+	b := newBreaker(BreakerOptions{Threshold: 1, CoolDown: time.Millisecond})
+	b.failure = func(err error) bool { return errors.Is(err, down) }
+
+	slow, err := b.allow() // A query that is still running when the circuit opens
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing, _ := b.allow()
+	b.record(failing, down)
+	if got := b.current(); got != "open" {
+		t.Fatalf("after a failure: %s, want open", got)
+	}
+
+	b.record(slow, nil)
+	if got := b.current(); got != "open" {
+		t.Fatalf("after a success from before it opened: %s, want open", got)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	probe, err := b.allow()
+	if err != nil {
+		t.Fatalf("after the cool-down: %v, want a probe", err)
+	}
+	b.record(slow, nil) // Again, now while the probe runs
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("a second call while the probe runs: %v, want ErrCircuitOpen", err)
+	}
+	if got := b.current(); got != "half-open" {
+		t.Fatalf("while probing: %s, want half-open", got)
+	}
+
+	b.record(probe, nil)
+	if got := b.current(); got != "closed" {
+		t.Fatalf("after the probe succeeded: %s, want closed", got)
+	}
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("once closed: %v", err)
+	}
+}
+
+func TestBreakerFailsFast(t *testing.T) {
+	ctx := context.Background()
+	db, fc := newFlakyDB(t, 0)
+	db.breaker = newBreaker(BreakerOptions{Threshold: 3, CoolDown: 50 * time.Millisecond})
+	q := New(db.wrap(db.Conn))
+	purge := func() error {
+		_, err := q.PurgeDeletedUsers(ctx, 0)
+		return err
+	}
+
+	if err := purge(); err != nil {
+		t.Fatal(err)
+	}
+
+	fc.down.Store(true)
+	for i := range 3 {
+		if err := purge(); !isConnectionError(err) {
+			t.Fatalf("call %d with the server down: %v, want a connection error", i+1, err)
+		}
+	}
+	if got := db.breaker.current(); got != "open" {
+		t.Fatalf("after 3 connection errors: %s, want open", got)
+	}
+
+	// Nothing reaches the driver now, however slow it has become
+	fc.delay.Store(int64(time.Second))
+	sent := fc.stmts.Load()
+	start := time.Now()
+	for range 20 {
+		if err := purge(); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("while open: %v, want ErrCircuitOpen", err)
+		}
+	}
+	if err := db.InTx(ctx, func(*Tx) error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("a transaction while open: %v, want ErrCircuitOpen", err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("21 calls while open took %v, want them to fail fast", elapsed)
+	}
+	if got := fc.stmts.Load(); got != sent {
+		t.Errorf("%d statements reached the driver while open, want none", got-sent)
+	}
+
+	// After the cool-down one probe goes through, still to a dead server,
+	// and the others keep failing fast while it runs
+	fc.delay.Store(int64(50 * time.Millisecond))
+	time.Sleep(60 * time.Millisecond)
+	errs := make(chan error, 10)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- purge()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	var probes, open int
+	for err := range errs {
+		switch {
+		case errors.Is(err, ErrCircuitOpen):
+			open++
+		case isConnectionError(err):
+			probes++
+		default:
+			t.Errorf("while probing: %v", err)
+		}
+	}
+	if probes != 1 || open != 9 || fc.stmts.Load() != sent+1 {
+		t.Errorf("%d probes and %d fast failures, %d statements sent; want 1, 9 and 1", probes, open, fc.stmts.Load()-sent)
+	}
+	if got := db.breaker.current(); got != "open" {
+		t.Fatalf("after the probe failed: %s, want open again", got)
+	}
+
+	// The server is back: the next probe closes the circuit
+	fc.down.Store(false)
+	fc.delay.Store(0)
+	time.Sleep(60 * time.Millisecond)
+	for i := range 3 {
+		if err := purge(); err != nil {
+			t.Fatalf("call %d after recovery: %v", i+1, err)
+		}
+	}
+	if got := db.breaker.current(); got != "closed" {
+		t.Errorf("after the probe succeeded: %s, want closed", got)
+	}
+}
@@ -0,0 +1,327 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"your-project/database/nulls"
+)
+
+// IDs per statement in BulkByIDs, and rows in BulkInsert. SQLite allows
+// 32766 bound parameters (999 before 3.32), the fewest of the dialects;
+// smaller chunks also keep each statement's locks short.
+const (
+	bulkChunkSize = 1000
+	bulkMaxParams = 32766
+)
+
+// BulkOptions configures BulkByIDs
+type BulkOptions struct {
+	// Strict fails the update, rolling it back, when fewer rows were
+	// affected than distinct IDs given: some of them don't exist
+	Strict bool
+}
+
+// PartialUpdateError is BulkByIDs' error in Strict mode when some IDs
+// matched no row. Nothing was changed.
+type PartialUpdateError struct {
+	Requested int64 // Distinct IDs given
+	Affected  int64 // Rows the update would have changed
+}
+
+func (e *PartialUpdateError) Error() string {
+	return fmt.Sprintf("%d of %d IDs matched no row", e.Requested-e.Affected, e.Requested)
+}
+
+// BulkByIDs runs update over ids in chunks of at most 1000, all in one
+// transaction, and returns the rows affected in total. Duplicate IDs are
+// dropped first. A failing chunk rolls back the ones before it.
+//
+//	n, err := database.BulkByIDs(ctx, db, ids, database.BulkOptions{}, func(tx *database.Tx, chunk []int64) (int64, error) {
+//		return tx.UpdateStatusByIDs(ctx, database.UpdateStatusByIDsParams{Status: database.StatusBanned, IDs: chunk})
+//	})
+func BulkByIDs(ctx context.Context, db *DB, ids []int64, opts BulkOptions, update func(tx *Tx, chunk []int64) (int64, error)) (int64, error) {
+	ids = distinctIDs(ids)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var total int64
+	err := db.InTx(ctx, func(tx *Tx) (err error) {
+		total, err = bulkChunks(tx, ids, opts, update)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// distinctIDs returns ids sorted, without duplicates, leaving ids as it is
+func distinctIDs(ids []int64) []int64 {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// bulkChunks is BulkByIDs' loop over ids, sorted and distinct already, in
+// a transaction of the caller's
+func bulkChunks(tx *Tx, ids []int64, opts BulkOptions, update func(tx *Tx, chunk []int64) (int64, error)) (int64, error) {
+	var total int64
+	for start := 0; start < len(ids); start += bulkChunkSize {
+		n, err := update(tx, ids[start:min(start+bulkChunkSize, len(ids))])
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	if opts.Strict && total != int64(len(ids)) {
+		return 0, &PartialUpdateError{Requested: int64(len(ids)), Affected: total}
+	}
+	return total, nil
+}
+
+// UpdateStatusByIDs sets the status of the users with the given IDs,
+// however many there are, and returns how many rows changed (see
+// BulkByIDs). A CachedQueries doesn't see it; use its
+// BulkUpdateStatusByIDs instead when users are cached.
+func (db *DB) UpdateStatusByIDs(ctx context.Context, status Status, ids []int64, opts BulkOptions) (int64, error) {
+	return BulkByIDs(ctx, db, ids, opts, func(tx *Tx, chunk []int64) (int64, error) {
+		return tx.UpdateStatusByIDs(ctx, UpdateStatusByIDsParams{Status: status, IDs: chunk})
+	})
+}
+
+// BulkInsert inserts rows into table with multi-row INSERT statements, as
+// many rows per statement as the parameter limit allows but at most 1000,
+// all in one transaction, and returns how many rows it inserted. values
+// returns a row's values for columns, in order. A failing statement rolls
+// back the ones before it; errors come through Translate. The table and
+// column names go into the SQL as they are, so they must be plain names
+// from the code, never input.
+//
+//	n, err := database.BulkInsert(ctx, db, "tags", []string{"name"}, names, func(name string) []any {
+//		return []any{name}
+//	})
+//
+// Thousands of rows this way take a few statements instead of one round
+// trip each, in SQLite and PostgreSQL alike.
+func BulkInsert[T any](ctx context.Context, db *DB, table string, columns []string, rows []T, values func(T) []any) (int64, error) {
+	if err := checkBulkNames(table, columns, nil); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	var total int64
+	err := db.InTx(ctx, func(tx *Tx) error {
+		var err error
+		total, err = insertChunks(ctx, tx, table, columns, nil, len(rows), func(i int) []any { return values(rows[i]) })
+		return err
+	})
+	if err != nil {
+		return 0, Translate(err)
+	}
+	return total, nil
+}
+
+// InsertRows is BulkInsert in a transaction that's already open, for
+// callers inserting batch by batch as they read, such as an import: each
+// row is the values for columns, in order. With upsertOn set, a row that
+// conflicts with an existing one on those columns, which need a unique
+// index, updates its other columns instead (ON CONFLICT DO UPDATE). A
+// failed statement leaves the transaction to be rolled back.
+func (t *Tx) InsertRows(ctx context.Context, table string, columns []string, rows [][]any, upsertOn []string) (int64, error) {
+	if err := checkBulkNames(table, columns, upsertOn); err != nil {
+		return 0, err
+	}
+	n, err := insertChunks(ctx, t, table, columns, upsertOn, len(rows), func(i int) []any { return rows[i] })
+	if err != nil {
+		return 0, Translate(err)
+	}
+	return n, nil
+}
+
+// SyncSequences moves the sequences of table's serial columns on past the
+// largest value in them, after rows were inserted with their ids given:
+// otherwise PostgreSQL would hand out those ids again. SQLite needs no
+// such thing, and it does nothing there.
+func (t *Tx) SyncSequences(ctx context.Context, table string) error {
+	d := defaultDialect()
+	if d.syncSequences == nil {
+		return nil
+	}
+	if !bulkNameRe.MatchString(table) {
+		return fmt.Errorf("invalid table name %q", table)
+	}
+	return d.syncSequences(ctx, t.queries.db, table)
+}
+
+func checkBulkNames(table string, columns, upsertOn []string) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("bulk insert into %s: no columns", table)
+	}
+	for _, name := range slices.Concat([]string{table}, columns, upsertOn) {
+		if !bulkNameRe.MatchString(name) {
+			return fmt.Errorf("bulk insert: invalid table or column name %q", name)
+		}
+	}
+	return nil
+}
+
+// errCopyUnsupported sends a dialect's copyRows back to INSERT statements
+var errCopyUnsupported = errors.New("driver can't COPY rows")
+
+// Rows from which a plain insert goes by the dialect's copyRows, when it
+// has one: COPY costs a round trip of its own to look the columns up
+const bulkCopyMinRows = 100
+
+// insertChunks inserts n rows, row(i) giving the values of each. Without
+// upsertOn, and with enough rows, it uses the dialect's copyRows if it
+// can; otherwise it runs INSERTs with as many rows per statement as the
+// parameter limit allows but at most 1000, preparing the statement for a
+// full chunk once if it's needed more than once.
+func insertChunks(ctx context.Context, tx *Tx, table string, columns, upsertOn []string, n int, row func(int) []any) (int64, error) {
+	checked := func(i int) ([]any, error) {
+		v := row(i)
+		if len(v) != len(columns) {
+			return nil, fmt.Errorf("bulk insert into %s: row %d has %d values for %d columns", table, i, len(v), len(columns))
+		}
+		return v, nil
+	}
+	if d := defaultDialect(); d.copyRows != nil && len(upsertOn) == 0 && n >= bulkCopyMinRows {
+		copied, err := d.copyRows(ctx, tx, table, columns, n, checked)
+		if !errors.Is(err, errCopyUnsupported) {
+			return copied, err
+		}
+	}
+
+	perStmt := min(bulkChunkSize, bulkMaxParams/len(columns))
+	var full *sql.Stmt // For full chunks
+	if n/perStmt > 1 {
+		var err error
+		if full, err = tx.queries.db.PrepareContext(ctx, bulkInsertSQL(table, columns, perStmt, upsertOn)); err != nil {
+			return 0, err
+		}
+		defer full.Close()
+	}
+
+	var total int64
+	for start := 0; start < n; start += perStmt {
+		end := min(start+perStmt, n)
+		args := make([]any, 0, (end-start)*len(columns))
+		for i := start; i < end; i++ {
+			v, err := checked(i)
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, v...)
+		}
+
+		var res sql.Result
+		var err error
+		if full != nil && end-start == perStmt {
+			res, err = full.ExecContext(ctx, args...)
+		} else {
+			res, err = tx.Exec(ctx, bulkInsertSQL(table, columns, end-start, upsertOn), args...)
+		}
+		if err != nil {
+			return 0, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total += affected
+	}
+	return total, nil
+}
+
+// Names that work unquoted in every dialect
+var bulkNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// bulkInsertSQL is INSERT INTO table (columns) VALUES (...), (...) for n
+// rows, with the dialect's placeholders, and with upsertOn the dialect's
+// upsert clause updating the columns not in it
+func bulkInsertSQL(table string, columns []string, n int, upsertOn []string) string {
+	d := defaultDialect()
+	numbered := d.numberedParams
+
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(table)
+	b.WriteString(" (")
+	b.WriteString(strings.Join(columns, ", "))
+	b.WriteString(") VALUES ")
+
+	param := 0
+	for r := 0; r < n; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for c := 0; c < len(columns); c++ {
+			if c > 0 {
+				b.WriteString(", ")
+			}
+			param++
+			if numbered {
+				b.WriteString("$" + strconv.Itoa(param))
+			} else {
+				b.WriteByte('?')
+			}
+		}
+		b.WriteByte(')')
+	}
+
+	if len(upsertOn) > 0 {
+		var set []string
+		for _, c := range columns {
+			if !slices.Contains(upsertOn, c) {
+				set = append(set, c)
+			}
+		}
+		upsert := d.upsert
+		if upsert == nil {
+			upsert = onConflictUpdate
+		}
+		b.WriteString(upsert(upsertOn, set))
+	}
+	return b.String()
+}
+
+// onConflictUpdate is the upsert clause of SQLite and PostgreSQL
+func onConflictUpdate(conflict, set []string) string {
+	if len(set) == 0 {
+		return " ON CONFLICT (" + strings.Join(conflict, ", ") + ") DO NOTHING"
+	}
+	for i, c := range set {
+		set[i] = c + " = excluded." + c
+	}
+	return " ON CONFLICT (" + strings.Join(conflict, ", ") + ") DO UPDATE SET " + strings.Join(set, ", ")
+}
+
+// BulkCreateUsers inserts users the way CreateUser does, in bulk (see
+// BulkInsert), and returns how many it inserted. A telegram_id or email
+// that's taken fails all of them with ErrDuplicate.
+func (db *DB) BulkCreateUsers(ctx context.Context, users []CreateUserParams) (int64, error) {
+	columns := []string{"telegram_id", "first_name", "username", "status", "language", "refer_from_id", "email"}
+	return BulkInsert(ctx, db, "users", columns, users, func(u CreateUserParams) []any {
+		username, email := nulls.String(u.Username.V), nulls.String(NormalizeEmail(u.Email.V))
+		return []any{u.TelegramID, u.FirstName, username, u.Status, u.Language, u.ReferFromID, email}
+	})
+}
+
+// BulkCreateGroups inserts groups the way CreateGroup does, in bulk (see
+// BulkInsert), and returns how many it inserted
+func (db *DB) BulkCreateGroups(ctx context.Context, groups []CreateGroupParams) (int64, error) {
+	return BulkInsert(ctx, db, "groups", []string{"telegram_id", "title"}, groups, func(g CreateGroupParams) []any {
+		return []any{g.TelegramID, g.Title}
+	})
+}
@@ -0,0 +1,79 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestBulkInsert(t *testing.T) {
+	bulkInsertRows(t, dbtest.NewTestDB(t))
+}
+
+// bulkInsertRows inserts more users than two full INSERT chunks, so the
+// statement for them is prepared and reused (or with pgx, COPYs them),
+// checks the insert and upsert paths, and returns the rows as written,
+// for comparing the drivers
+func bulkInsertRows(t *testing.T, db *database.DB) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	const n = 2500
+	users := make([]database.CreateUserParams, n)
+	for i := range users {
+		users[i] = database.CreateUserParams{
+			TelegramID: int64(i + 1),
+			FirstName:  fmt.Sprintf("user %d", i+1),
+			Status:     database.StatusActive,
+			Language:   "en",
+		}
+		if i%3 == 0 {
+			users[i].Username = sql.Null[string]{V: fmt.Sprintf("u%d", i+1), Valid: true}
+			users[i].Email = sql.Null[string]{V: fmt.Sprintf("U%d@Example.com", i+1), Valid: true}
+		}
+		if i > 0 && i%5 == 0 {
+			users[i].ReferFromID = sql.Null[int64]{V: 1, Valid: true}
+		}
+	}
+	got, err := db.BulkCreateUsers(ctx, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != n {
+		t.Errorf("BulkCreateUsers: %d rows, want %d", got, n)
+	}
+
+	if _, err := db.BulkCreateUsers(ctx, users[n-150:]); !errors.Is(err, database.ErrDuplicate) {
+		t.Errorf("inserting taken telegram_ids: %v, want ErrDuplicate", err)
+	}
+
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		n, err := tx.InsertRows(ctx, "users", []string{"telegram_id", "first_name"},
+			[][]any{{int64(1), "renamed"}, {int64(n + 1), "new"}}, []string{"telegram_id"})
+		if err == nil && n != 2 {
+			err = fmt.Errorf("upserted %d rows, want 2", n)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []string
+	for _, id := range []int64{1, 2, 4, 6, n, n + 1} {
+		u, err := db.Q.GetUserByTelegramID(ctx, id)
+		if err != nil {
+			t.Fatalf("user %d: %v", id, err)
+		}
+		rows = append(rows, fmt.Sprint(u.TelegramID, u.FirstName, u.Username, u.Status, u.Language, u.ReferFromID, u.Email, u.BalanceGame))
+	}
+	if count, err := db.Q.CountUsersByStatus(ctx, database.StatusActive); err != nil || count != n+1 {
+		t.Errorf("%d users, %v; want %d", count, err, n+1)
+	}
+	return rows
+}
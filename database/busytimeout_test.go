@@ -0,0 +1,50 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"your-project/database"
+)
+
+func TestBusyTimeout(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Open(database.Config{
+		DSN:          filepath.Join(t.TempDir(), "app.db"),
+		LogLevel:     "silent",
+		JournalMode:  "delete",
+		MaxOpenConns: 16,
+		BusyTimeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Every connection in the pool has it, not just the one Open checked
+	var conns []*sql.Conn
+	for range 4 {
+		c, err := db.Conn.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+		var ms int
+		if err := c.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&ms); err != nil || ms != 5000 {
+			t.Errorf("connection %d: busy_timeout %d, %v; want 5000", len(conns), ms, err)
+		}
+	}
+	for _, c := range conns {
+		c.Close()
+	}
+
+	// The writers wait their turn instead of failing
+	if errs := writeConcurrently(db); len(errs) > 0 {
+		t.Errorf("%d of the concurrent writes failed within the busy timeout, first: %v", len(errs), errs[0])
+	}
+}
@@ -0,0 +1,398 @@
+package database
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Cache is the storage behind CachedQueries, in-process (LRU) or shared
+// by every process of the app (Redis, memcached). Implementations must be
+// safe for concurrent use.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+
+	// Incr adds one to the counter at key, 0 if there's none, and returns
+	// the new value. The tables' generations are these counters, so every
+	// process sharing the store sees the others' invalidations. They're
+	// read with Get, as decimal text, and mustn't expire or be evicted
+	// (Redis: INCR, with an eviction policy that spares keys without TTL).
+	Incr(key string) (uint64, error)
+}
+
+// CacheCodec turns rows into the bytes a Cache stores, and back
+type CacheCodec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the default CacheCodec
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// CachedQueries is a read-through cache over the Get queries. Writes made
+// through it invalidate every cached row of the table they touch; writes
+// made through db.Q directly are invisible to it, so route them here.
+type CachedQueries struct {
+	db    *DB
+	q     Querier
+	tx    *Tx
+	cache Cache
+	codec CacheCodec
+	ttl   time.Duration
+}
+
+// Keys are "<table>@<generation>:<query>:<args>". Bumping a table's
+// generation, in the Cache, orphans every key built from the old one.
+// While a write of this DB's is pending, its reads skip the cache
+// entirely, so a reader can't refill it with a row that's about to
+// change; a write in another process can only race a refill until its
+// generation bump, and the refill then lands under the old generation.
+type cacheState struct {
+	mu      sync.Mutex
+	pending map[string]int
+}
+
+func (s *cacheState) isPending(table string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending[table] > 0
+}
+
+// CachedQueries returns a caching wrapper around db.Q. Every wrapper of
+// the DB shares its pending writes, and every one over the same cache its
+// generations.
+func (db *DB) CachedQueries(cache Cache, ttl time.Duration) *CachedQueries {
+	return &CachedQueries{db: db, q: db.Q, cache: cache, codec: JSONCodec{}, ttl: ttl}
+}
+
+// WithCodec stores the rows with codec instead of JSON
+func (c *CachedQueries) WithCodec(codec CacheCodec) *CachedQueries {
+	cc := *c
+	cc.codec = codec
+	return &cc
+}
+
+// WithTx binds the wrapper to a transaction. Reads inside it bypass the
+// cache, and the touched tables are invalidated only if the tx commits.
+func (c *CachedQueries) WithTx(tx *Tx) *CachedQueries {
+	cc := *c
+	cc.q, cc.tx = tx.Querier, tx
+	return &cc
+}
+
+func generationKey(table string) string {
+	return "gen:" + table
+}
+
+// generation is the table's current generation, 0 if it never had one
+func (c *CachedQueries) generation(table string) uint64 {
+	v, ok := c.cache.Get(generationKey(table))
+	if !ok {
+		return 0
+	}
+	gen, _ := strconv.ParseUint(string(v), 10, 64)
+	return gen
+}
+
+func cachedGet[T any](c *CachedQueries, table, name string, arg any, fetch func() (T, error)) (T, error) {
+	if c.tx != nil {
+		return fetch()
+	}
+
+	s := &c.db.cache
+	if s.isPending(table) {
+		return fetch()
+	}
+	gen := c.generation(table)
+
+	key := fmt.Sprintf("%s@%d:%s:%v", table, gen, name, arg)
+	if data, ok := c.cache.Get(key); ok {
+		var v T
+		if err := c.codec.Unmarshal(data, &v); err == nil {
+			return v, nil
+		}
+		// Written by another version of the app; fetched and replaced below
+	}
+
+	v, err := fetch()
+	if err != nil {
+		return v, err
+	}
+
+	if !s.isPending(table) && c.generation(table) == gen {
+		if data, err := c.codec.Marshal(v); err == nil {
+			c.cache.Set(key, data, c.ttl)
+		}
+	}
+	return v, nil
+}
+
+func cachedWrite[T any](c *CachedQueries, table string, write func() (T, error)) (T, error) {
+	return cachedWriteTables(c, []string{table}, write)
+}
+
+// cachedWriteTables is cachedWrite for a statement that changes several
+// tables, through a cascade or otherwise
+func cachedWriteTables[T any](c *CachedQueries, tables []string, write func() (T, error)) (T, error) {
+	for _, table := range tables {
+		c.begin(table)
+		if c.tx == nil {
+			defer c.end(table, true)
+		}
+	}
+	return write()
+}
+
+func (c *CachedQueries) begin(table string) {
+	s := &c.db.cache
+	s.mu.Lock()
+	if s.pending == nil {
+		s.pending = map[string]int{}
+	}
+	s.pending[table]++
+	s.mu.Unlock()
+
+	if c.tx != nil {
+		c.tx.onFinish = append(c.tx.onFinish, func(committed bool) {
+			c.end(table, committed)
+		})
+	}
+}
+
+func (c *CachedQueries) end(table string, changed bool) {
+	if changed {
+		c.Invalidate(table)
+	}
+	s := &c.db.cache
+	s.mu.Lock()
+	s.pending[table]--
+	s.mu.Unlock()
+}
+
+// Invalidate drops every cached row of table, for changes the cache
+// can't see, such as those Listen reports. If the cache can't bump the
+// generation, the rows stay until their TTL; that's logged.
+func (c *CachedQueries) Invalidate(table string) {
+	if _, err := c.cache.Incr(generationKey(table)); err != nil {
+		log.Printf("failed to invalidate the cached %s rows: %v", table, err)
+	}
+}
+
+// Reads
+
+func (c *CachedQueries) GetUserByID(ctx context.Context, id int64) (User, error) {
+	return cachedGet(c, "users", "GetUserByID", id, func() (User, error) {
+		return c.q.GetUserByID(ctx, id)
+	})
+}
+
+func (c *CachedQueries) GetUserByTelegramID(ctx context.Context, telegramID int64) (User, error) {
+	return cachedGet(c, "users", "GetUserByTelegramID", telegramID, func() (User, error) {
+		return c.q.GetUserByTelegramID(ctx, telegramID)
+	})
+}
+
+func (c *CachedQueries) GetGroupByTelegramID(ctx context.Context, telegramID int64) (Group, error) {
+	return cachedGet(c, "groups", "GetGroupByTelegramID", telegramID, func() (Group, error) {
+		return c.q.GetGroupByTelegramID(ctx, telegramID)
+	})
+}
+
+func (c *CachedQueries) GetUserGroup(ctx context.Context, arg GetUserGroupParams) (UserGroup, error) {
+	return cachedGet(c, "user_group", "GetUserGroup", arg, func() (UserGroup, error) {
+		return c.q.GetUserGroup(ctx, arg)
+	})
+}
+
+// Writes
+
+func (c *CachedQueries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	return cachedWrite(c, "users", func() (User, error) { return c.q.CreateUser(ctx, arg) })
+}
+
+func (c *CachedQueries) CreateUserIfMissing(ctx context.Context, arg CreateUserIfMissingParams) (User, error) {
+	return cachedWrite(c, "users", func() (User, error) { return c.q.CreateUserIfMissing(ctx, arg) })
+}
+
+func (c *CachedQueries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	return cachedWrite(c, "users", func() (User, error) { return c.q.UpdateUser(ctx, arg) })
+}
+
+func (c *CachedQueries) UpdateUserBalanceChats(ctx context.Context, arg UpdateUserBalanceChatsParams) (User, error) {
+	return cachedWrite(c, "users", func() (User, error) { return c.q.UpdateUserBalanceChats(ctx, arg) })
+}
+
+func (c *CachedQueries) UpdateUserIfVersion(ctx context.Context, arg UpdateUserIfVersionParams) (int64, error) {
+	return cachedWrite(c, "users", func() (int64, error) { return c.q.UpdateUserIfVersion(ctx, arg) })
+}
+
+func (c *CachedQueries) UpdateUserBalanceChatsIfVersion(ctx context.Context, arg UpdateUserBalanceChatsIfVersionParams) (int64, error) {
+	return cachedWrite(c, "users", func() (int64, error) { return c.q.UpdateUserBalanceChatsIfVersion(ctx, arg) })
+}
+
+func (c *CachedQueries) UpsertUser(ctx context.Context, arg UpsertUserParams) (User, error) {
+	return cachedWrite(c, "users", func() (User, error) { return c.q.UpsertUser(ctx, arg) })
+}
+
+func (c *CachedQueries) UpsertUserByEmail(ctx context.Context, arg UpsertUserByEmailParams) (User, error) {
+	return cachedWrite(c, "users", func() (User, error) { return c.q.UpsertUserByEmail(ctx, arg) })
+}
+
+func (c *CachedQueries) SoftDeleteUser(ctx context.Context, id int64) (int64, error) {
+	return cachedWrite(c, "users", func() (int64, error) { return c.q.SoftDeleteUser(ctx, id) })
+}
+
+func (c *CachedQueries) RestoreUser(ctx context.Context, id int64) (int64, error) {
+	return cachedWrite(c, "users", func() (int64, error) { return c.q.RestoreUser(ctx, id) })
+}
+
+// PurgeDeletedUsers also drops the cached user_group rows, which go with
+// the users by ON DELETE CASCADE
+func (c *CachedQueries) PurgeDeletedUsers(ctx context.Context, olderThanSeconds int64) (int64, error) {
+	return cachedWriteTables(c, []string{"users", "user_group"}, func() (int64, error) {
+		return c.q.PurgeDeletedUsers(ctx, olderThanSeconds)
+	})
+}
+
+func (c *CachedQueries) UpdateStatusByIDs(ctx context.Context, arg UpdateStatusByIDsParams) (int64, error) {
+	return cachedWrite(c, "users", func() (int64, error) { return c.q.UpdateStatusByIDs(ctx, arg) })
+}
+
+// BulkUpdateStatusByIDs is DB.UpdateStatusByIDs through the cache: the
+// chunks run in one transaction, or in the one c is bound to, and the
+// users rows are dropped once it commits
+func (c *CachedQueries) BulkUpdateStatusByIDs(ctx context.Context, status Status, ids []int64, opts BulkOptions) (int64, error) {
+	update := func(tx *Tx, chunk []int64) (int64, error) {
+		return c.WithTx(tx).UpdateStatusByIDs(ctx, UpdateStatusByIDsParams{Status: status, IDs: chunk})
+	}
+	if c.tx != nil {
+		return bulkChunks(c.tx, distinctIDs(ids), opts, update)
+	}
+	return BulkByIDs(ctx, c.db, ids, opts, update)
+}
+
+func (c *CachedQueries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error) {
+	return cachedWrite(c, "groups", func() (Group, error) { return c.q.CreateGroup(ctx, arg) })
+}
+
+func (c *CachedQueries) UpsertGroup(ctx context.Context, arg UpsertGroupParams) (Group, error) {
+	return cachedWrite(c, "groups", func() (Group, error) { return c.q.UpsertGroup(ctx, arg) })
+}
+
+// DeleteGroup drops the cached user_group rows along with the groups
+// ones, so no cached membership outlives its group
+func (c *CachedQueries) DeleteGroup(ctx context.Context, telegramID int64) (Group, error) {
+	return cachedWriteTables(c, []string{"groups", "user_group"}, func() (Group, error) {
+		return c.q.DeleteGroup(ctx, telegramID)
+	})
+}
+
+func (c *CachedQueries) CreateUserGroup(ctx context.Context, arg CreateUserGroupParams) (UserGroup, error) {
+	return cachedWrite(c, "user_group", func() (UserGroup, error) { return c.q.CreateUserGroup(ctx, arg) })
+}
+
+func (c *CachedQueries) GetOrCreateUserGroup(ctx context.Context, arg GetOrCreateUserGroupParams) (UserGroup, error) {
+	return cachedWrite(c, "user_group", func() (UserGroup, error) { return c.q.GetOrCreateUserGroup(ctx, arg) })
+}
+
+func (c *CachedQueries) UpdateUserGroupBalance(ctx context.Context, arg UpdateUserGroupBalanceParams) (UserGroup, error) {
+	return cachedWrite(c, "user_group", func() (UserGroup, error) { return c.q.UpdateUserGroupBalance(ctx, arg) })
+}
+
+func (c *CachedQueries) UpsertUserGroupBalance(ctx context.Context, arg UpsertUserGroupBalanceParams) (UserGroup, error) {
+	return cachedWrite(c, "user_group", func() (UserGroup, error) { return c.q.UpsertUserGroupBalance(ctx, arg) })
+}
+
+func (c *CachedQueries) AddToUserGroupBalance(ctx context.Context, arg AddToUserGroupBalanceParams) (UserGroup, error) {
+	return cachedWrite(c, "user_group", func() (UserGroup, error) { return c.q.AddToUserGroupBalance(ctx, arg) })
+}
+
+// LRU is an in-memory Cache that evicts the least recently used entry once
+// it holds size entries. Its counters (Incr) are kept apart and never
+// evicted.
+type LRU struct {
+	mu       sync.Mutex
+	size     int
+	ll       *list.List
+	items    map[string]*list.Element
+	counters map[string]uint64
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRU returns an LRU holding at most size entries
+func NewLRU(size int) *LRU {
+	return &LRU{size: size, ll: list.New(), items: map[string]*list.Element{}, counters: map[string]uint64{}}
+}
+
+func (l *LRU) Get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if n, ok := l.counters[key]; ok {
+		return strconv.AppendUint(nil, n, 10), true
+	}
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expires) {
+		l.ll.Remove(el)
+		delete(l.items, key)
+		return nil, false
+	}
+	l.ll.MoveToFront(el)
+	return e.value, true
+}
+
+func (l *LRU) Set(key string, value []byte, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expires := time.Now().Add(ttl)
+	if el, ok := l.items[key]; ok {
+		el.Value = &lruEntry{key: key, value: value, expires: expires}
+		l.ll.MoveToFront(el)
+		return
+	}
+
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	if l.ll.Len() > l.size {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (l *LRU) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[key]; ok {
+		l.ll.Remove(el)
+		delete(l.items, key)
+	}
+}
+
+func (l *LRU) Incr(key string) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.counters[key]++
+	return l.counters[key], nil
+}
@@ -0,0 +1,161 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestCachedBulkUpdateStatus(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	cq := db.CachedQueries(database.NewLRU(100), time.Hour)
+	u, err := cq.CreateUser(ctx, database.CreateUserParams{TelegramID: 1, FirstName: "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := func(want database.Status) {
+		t.Helper()
+		got, err := cq.GetUserByID(ctx, u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != want {
+			t.Errorf("cached status %q, want %q", got.Status, want)
+		}
+	}
+	status(database.StatusActive) // Now cached
+
+	if _, err := cq.BulkUpdateStatusByIDs(ctx, database.StatusBanned, []int64{u.ID, u.ID}, database.BulkOptions{Strict: true}); err != nil {
+		t.Fatal(err)
+	}
+	status(database.StatusBanned)
+
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		_, err := cq.WithTx(tx).BulkUpdateStatusByIDs(ctx, database.StatusBlocked, []int64{u.ID}, database.BulkOptions{})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status(database.StatusBlocked)
+}
+
+func TestCachedDeletes(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	cq := db.CachedQueries(database.NewLRU(100), time.Hour)
+	u, err := cq.CreateUserIfMissing(ctx, database.CreateUserIfMissingParams{TelegramID: 1, FirstName: "Ann", Status: database.StatusActive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{10, 11} {
+		if _, err := cq.CreateGroup(ctx, database.CreateGroupParams{TelegramID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	member := database.GetUserGroupParams{UserTelegramID: u.TelegramID, GroupTelegramID: 10}
+	if _, err := cq.CreateUserGroup(ctx, database.CreateUserGroupParams(member)); err != nil {
+		t.Fatal(err)
+	}
+	gone := func(what string, err error) {
+		t.Helper()
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("%s after it was deleted: got %v, want sql.ErrNoRows", what, err)
+		}
+	}
+
+	if _, err := cq.GetGroupByTelegramID(ctx, 11); err != nil { // Now cached
+		t.Fatal(err)
+	}
+	if _, err := cq.DeleteGroup(ctx, 11); err != nil {
+		t.Fatal(err)
+	}
+	_, err = cq.GetGroupByTelegramID(ctx, 11)
+	gone("GetGroupByTelegramID", err)
+
+	if _, err := cq.SoftDeleteUser(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE users SET deleted_at = '2000-01-01 00:00:00' WHERE deleted_at IS NOT NULL")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cq.GetUserGroup(ctx, member); err != nil { // Now cached
+		t.Fatal(err)
+	}
+	if n, err := cq.PurgeDeletedUsers(ctx, 60); err != nil || n != 1 {
+		t.Fatalf("PurgeDeletedUsers: %d, %v; want 1 user", n, err)
+	}
+	_, err = cq.GetUserGroup(ctx, member)
+	gone("GetUserGroup", err)
+}
+
+// countingCache is an LRU that counts the rows it served
+type countingCache struct {
+	*database.LRU
+	hits atomic.Int64
+}
+
+func (c *countingCache) Get(key string) ([]byte, bool) {
+	v, ok := c.LRU.Get(key)
+	if ok && !strings.HasPrefix(key, "gen:") {
+		c.hits.Add(1)
+	}
+	return v, ok
+}
+
+func TestCachedQueries(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	cache := &countingCache{LRU: database.NewLRU(100)}
+	cq := db.CachedQueries(cache, time.Hour)
+	u, err := cq.CreateUser(ctx, database.CreateUserParams{TelegramID: 1, FirstName: "Ann", Username: sql.Null[string]{V: "ann", Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(wantName string, wantHits int64) {
+		t.Helper()
+		got, err := cq.GetUserByTelegramID(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.FirstName != wantName || got.Username != u.Username || !got.CreatedAt.V.Equal(u.CreatedAt.V) {
+			t.Errorf("got %+v, want %s as created", got, wantName)
+		}
+		if n := cache.hits.Load(); n != wantHits {
+			t.Errorf("%d cache hits, want %d", n, wantHits)
+		}
+	}
+	get("Ann", 0) // A miss, which fills the cache
+	get("Ann", 1) // A hit, decoded from JSON
+
+	if _, err := cq.UpdateUser(ctx, database.UpdateUserParams{TelegramID: 1, FirstName: "Bea", Username: u.Username}); err != nil {
+		t.Fatal(err)
+	}
+	get("Bea", 1) // The update dropped it
+
+	fail := errors.New("roll back")
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		if _, err := cq.WithTx(tx).UpdateUser(ctx, database.UpdateUserParams{TelegramID: 1, FirstName: "Cal", Username: u.Username}); err != nil {
+			return err
+		}
+		return fail
+	})
+	if !errors.Is(err, fail) {
+		t.Fatal(err)
+	}
+	get("Bea", 2) // Still cached: nothing changed
+}
@@ -0,0 +1,282 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tables Listen reports changes to, on "<table>_changes". On PostgreSQL
+// the schema's notify_change triggers announce them.
+var changeTables = map[string]bool{"users": true, "groups": true, "user_group": true}
+
+// ChangeResync is the Operation of the event Listen sends when events may
+// have been lost: after reconnecting, or once the channel has room again
+// after dropping some. Reload whatever was built from the table.
+const ChangeResync = "RESYNC"
+
+// Events a listener may fall behind by before new ones are dropped
+const changeBuffer = 256
+
+// Reconnect backoff of Listen on PostgreSQL
+const (
+	listenMinBackoff = 100 * time.Millisecond
+	listenMaxBackoff = 30 * time.Second
+)
+
+// ChangeEvent is a committed change to a row of a table Listen watches
+type ChangeEvent struct {
+	Table      string // "users", "groups" or "user_group"
+	Operation  string // "INSERT", "UPDATE", "DELETE" or ChangeResync
+	ID         int64  // The row's id; 0 on SQLite, which can't tell which rows changed
+	TelegramID int64  // The row's telegram_id, 0 if it has none or on SQLite
+}
+
+// Listen delivers the changes committed to a table on channel
+// "<table>_changes" (users_changes, groups_changes, user_group_changes)
+// until ctx is done, then closes the returned channel.
+//
+// On PostgreSQL the schema's triggers NOTIFY every change, including those
+// made by other services, and Listen waits for them on a connection of
+// its own, taken from the pool for as long as it runs (pgx only). When the
+// connection drops it reconnects with backoff and sends a ChangeResync
+// event, since changes made meanwhile are lost.
+//
+// On SQLite there's nobody else to hear from: the events come from this
+// DB's own writes, published when they commit (after the statement without
+// a transaction, from OnCommit inside one). It's best effort. Events name
+// the table but no row, an upsert counts as an INSERT, changes made by
+// cascades or triggers aren't seen, and neither are writes from other
+// processes or through other connections.
+//
+// A listener that falls 256 events behind loses the new ones; they're
+// counted in DroppedChanges, and a ChangeResync event follows once there's
+// room again. Reading the channel must never wait on a write to the
+// database, or the listener can't keep up.
+func (db *DB) Listen(ctx context.Context, channel string) (<-chan ChangeEvent, error) {
+	table, ok := strings.CutSuffix(channel, "_changes")
+	if !ok || !changeTables[table] {
+		return nil, fmt.Errorf("unknown change channel %q", channel)
+	}
+
+	if l := defaultDialect().listen; l != nil {
+		return db.listenDB(ctx, channel, l)
+	}
+	return db.changes.subscribe(ctx, table), nil
+}
+
+// DroppedChanges returns how many change events listeners have lost by
+// not keeping up, since the DB was opened
+func (db *DB) DroppedChanges() uint64 {
+	return db.changes.dropped.Load()
+}
+
+// changeSender delivers to one listener without ever blocking. After a
+// drop it sends ChangeResync before the next event.
+type changeSender struct {
+	ch      chan ChangeEvent
+	dropped *atomic.Uint64
+	lost    bool
+}
+
+func (s *changeSender) send(ev ChangeEvent) {
+	if s.lost {
+		select {
+		case s.ch <- ChangeEvent{Table: ev.Table, Operation: ChangeResync}:
+			s.lost = false
+		default:
+			s.dropped.Add(1)
+			return
+		}
+	}
+	select {
+	case s.ch <- ev:
+	default:
+		s.lost = true
+		s.dropped.Add(1)
+	}
+}
+
+// listenDB runs listen on a dedicated connection, reopening it whenever it
+// fails. The first attempt's error is returned; later ones are retried.
+func (db *DB) listenDB(ctx context.Context, channel string, listen listenFunc) (<-chan ChangeEvent, error) {
+	table, _ := strings.CutSuffix(channel, "_changes")
+	s := &changeSender{ch: make(chan ChangeEvent, changeBuffer), dropped: &db.changes.dropped}
+
+	deliver := func(payload string) {
+		var p struct {
+			Table      string `json:"table"`
+			Op         string `json:"op"`
+			ID         int64  `json:"id"`
+			TelegramID *int64 `json:"telegram_id"`
+		}
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return // Not from notify_change
+		}
+		ev := ChangeEvent{Table: p.Table, Operation: p.Op, ID: p.ID}
+		if p.TelegramID != nil {
+			ev.TelegramID = *p.TelegramID
+		}
+		s.send(ev)
+	}
+
+	first := make(chan error, 1)
+	go func() {
+		defer close(s.ch)
+
+		backoff := listenMinBackoff
+		connected := false
+		for {
+			ready := func() {
+				if connected {
+					s.send(ChangeEvent{Table: table, Operation: ChangeResync})
+				} else {
+					first <- nil
+				}
+				connected, backoff = true, listenMinBackoff
+			}
+			err := listen(ctx, db.Conn, channel, ready, deliver)
+			if ctx.Err() != nil {
+				if !connected {
+					first <- ctx.Err()
+				}
+				return
+			}
+			if !connected {
+				first <- err
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, listenMaxBackoff)
+		}
+	}()
+
+	if err := <-first; err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+	return s.ch, nil
+}
+
+// listenFunc subscribes to channel on a connection of its own, calls
+// ready once it's listening, then notify with each payload until the
+// connection fails or ctx is done
+type listenFunc func(ctx context.Context, conn *sql.DB, channel string, ready func(), notify func(payload string)) error
+
+// errListenUnsupported is what a dialect's listen returns for a driver
+// that can't wait for notifications
+var errListenUnsupported = errors.New("Listen needs the pgx driver")
+
+// changeHub fans this process's own writes out to the SQLite listeners
+type changeHub struct {
+	mu        sync.Mutex
+	listeners map[*changeSender]string // Table each one listens to
+	active    atomic.Int32             // len(listeners), read without the lock
+	dropped   atomic.Uint64
+}
+
+func (h *changeHub) subscribe(ctx context.Context, table string) <-chan ChangeEvent {
+	s := &changeSender{ch: make(chan ChangeEvent, changeBuffer), dropped: &h.dropped}
+
+	h.mu.Lock()
+	if h.listeners == nil {
+		h.listeners = map[*changeSender]string{}
+	}
+	h.listeners[s] = table
+	h.active.Add(1)
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.listeners, s)
+		h.active.Add(-1)
+		close(s.ch)
+		h.mu.Unlock()
+	}()
+	return s.ch
+}
+
+func (h *changeHub) publish(events []ChangeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s, table := range h.listeners {
+		for _, ev := range events {
+			if ev.Table == table {
+				s.send(ev)
+			}
+		}
+	}
+}
+
+// The table a write statement changes, after sqlc's "-- name:" line
+var writeTableRe = regexp.MustCompile(`(?is)^\s*(?:--[^\n]*\n\s*|/\*.*?\*/\s*)*(?:INSERT\s+(?:OR\s+\w+\s+)?INTO|REPLACE\s+INTO|UPDATE(?:\s+OR\s+\w+)?|DELETE\s+FROM)\s+["` + "`" + `]?(\w+)`)
+
+// changedTable returns the event a successful statement makes, if it
+// writes to a table Listen watches
+func changedTable(query string) (ChangeEvent, bool) {
+	m := writeTableRe.FindStringSubmatch(query)
+	if m == nil || !changeTables[m[1]] {
+		return ChangeEvent{}, false
+	}
+	op := firstKeyword(query)
+	if op == "REPLACE" {
+		op = "INSERT"
+	}
+	return ChangeEvent{Table: m[1], Operation: op}, true
+}
+
+// changesDBTX publishes this DB's writes to its SQLite listeners: at once
+// outside a transaction, from OnCommit inside one (tx set). It costs a
+// load per statement while nobody listens.
+type changesDBTX struct {
+	DBTX
+	hub *changeHub
+	tx  *Tx
+}
+
+func (d *changesDBTX) changed(query string, err error) {
+	if err != nil || d.hub.active.Load() == 0 {
+		return
+	}
+	ev, ok := changedTable(query)
+	if !ok {
+		return
+	}
+	if d.tx == nil {
+		d.hub.publish([]ChangeEvent{ev})
+		return
+	}
+	d.tx.OnCommit(func() { d.hub.publish([]ChangeEvent{ev}) })
+}
+
+func (d *changesDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := d.DBTX.ExecContext(ctx, query, args...)
+	d.changed(query, err)
+	return res, err
+}
+
+func (d *changesDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := d.DBTX.QueryContext(ctx, query, args...)
+	d.changed(query, err)
+	return rows, err
+}
+
+// A *sql.Row holds its error until Scan, so a failed INSERT ... RETURNING
+// is reported too; listeners only ever reload more than they needed to
+func (d *changesDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := d.DBTX.QueryRowContext(ctx, query, args...)
+	d.changed(query, nil)
+	return row
+}
@@ -0,0 +1,44 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// nextChange waits for the listener's next event
+func nextChange(t *testing.T, ch <-chan database.ChangeEvent) database.ChangeEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatal("the change channel closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change event within 5s")
+	}
+	return database.ChangeEvent{}
+}
+
+// noChange checks nothing more arrives for a moment
+func noChange(t *testing.T, ch <-chan database.ChangeEvent) {
+	t.Helper()
+	select {
+	case ev := <-ch:
+		t.Errorf("unexpected change event %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestListenUnknownChannel(t *testing.T) {
+	db := dbtest.NewTestDB(t)
+	for _, channel := range []string{"users", "orders_changes", "jobs_changes", ""} {
+		if _, err := db.Listen(context.Background(), channel); err == nil {
+			t.Errorf("Listen(%q) succeeded, want an unknown channel", channel)
+		}
+	}
+}
@@ -0,0 +1,912 @@
+// Package cli is the operator's side of the database package: migrations,
+// seed data, backups, integrity checks, exports and maintenance from the
+// command line. Mount it under a subcommand of the app's own binary, so
+// the tool always matches the schema the app was built with:
+//
+//	func main() {
+//		if len(os.Args) > 1 && os.Args[1] == "db" {
+//			os.Exit(cli.Run(os.Args[2:]))
+//		}
+//		// ... the app itself
+//	}
+//
+// and then run "app db help" for the subcommands. cmd/dbctl is the same
+// commands as a binary of its own, for hosts without the app.
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"your-project/database"
+	"your-project/database/nulls"
+	"your-project/database/seed"
+)
+
+// Exit codes of Run
+const (
+	ExitOK     = 0 // Done, nothing wrong found
+	ExitFailed = 1 // The command failed, or found problems
+	ExitUsage  = 2 // Bad subcommand or flags
+)
+
+// usageError is a mistake in the command line, reported with ExitUsage.
+// An empty one was already reported by the flag package.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+func usagef(format string, args ...any) error {
+	return usageError(fmt.Sprintf(format, args...))
+}
+
+// errFound is returned once problems were found and printed
+var errFound = errors.New("problems found")
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"migrate":         {"migrate [-dir dir] [-dry-run] up|down [n]|status|to <version>|new <name>", migrate},
+	"seed":            {"seed -file seed.json | -env dev", seedCmd},
+	"backup":          {"backup [-dir backups] [-keep n] [-compress]", backup},
+	"restore":         {"restore -from backup.db[.gz]", restore},
+	"integrity-check": {"integrity-check", integrityCheck},
+	"export":          {"export [-tables a,b] [-o file]", export},
+	"snapshot":        {"snapshot [-o file]", snapshot},
+	"docs":            {"docs [-format markdown|mermaid|dot] [-live] [-internal] [-o file [-check]]", docs},
+	"purge":           {"purge -older-than 720h", purge},
+	"prune-audit":     {"prune-audit -older-than 2160h", pruneAudit},
+	"maintain":        {"maintain", maintain},
+	"vacuum":          {"vacuum", vacuum},
+	"backfill-keys":   {"backfill-keys -table orders [-key id] [-column uid] [-kind uuid|ulid]", backfillKeys},
+}
+
+var order = []string{"migrate", "seed", "backup", "restore", "integrity-check", "export", "snapshot", "docs", "purge", "prune-audit", "maintain", "vacuum", "backfill-keys"}
+
+// Run runs the subcommand named by args[0] and returns the process exit
+// code. Every subcommand takes -config (a YAML or TOML file, default
+// $DB_CONFIG), -driver, -dsn and -json; flags beat DB_* environment
+// variables (see database.ConfigFromEnv), which beat the file.
+func Run(args []string) int {
+	return run("db", args, os.Stdout, os.Stderr)
+}
+
+// RunAs is Run for a binary of its own, such as cmd/dbctl: usage and
+// errors name prog instead of "db"
+func RunAs(prog string, args []string) int {
+	return run(prog, args, os.Stdout, os.Stderr)
+}
+
+func run(prog string, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(prog, stderr)
+		if len(args) == 0 {
+			return ExitUsage
+		}
+		return ExitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "%s: unknown command %q\n", prog, args[0])
+		usage(prog, stderr)
+		return ExitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := &cli{prog: prog, name: args[0], stdout: stdout, stderr: stderr}
+	err := cmd.run(ctx, c, args[1:])
+	var uerr usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &uerr):
+		if uerr != "" {
+			fmt.Fprintf(stderr, "%s %s: %v\nusage: %s %s\n", prog, c.name, uerr, prog, cmd.usage)
+		}
+		return ExitUsage
+	case errors.Is(err, errFound):
+		return ExitFailed
+	default:
+		fmt.Fprintf(stderr, "%s %s: %v\n", prog, c.name, err)
+		return ExitFailed
+	}
+}
+
+func usage(prog string, w io.Writer) {
+	fmt.Fprintf(w, "usage: %s <command> [-config file] [-driver name] [-dsn dsn] [-json] ...\n", prog)
+	fmt.Fprintln(w, "commands:")
+	for _, name := range order {
+		fmt.Fprintf(w, "  %s %s\n", prog, commands[name].usage)
+	}
+}
+
+// cli is the state of one subcommand run
+type cli struct {
+	prog           string // "db" under the app, or the binary's name
+	name           string
+	stdout, stderr io.Writer
+
+	configFile, driver, dsn string
+	json                    bool
+}
+
+// flags starts the subcommand's flag set with the flags every one takes
+func (c *cli) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.prog+" "+c.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.configFile, "config", os.Getenv("DB_CONFIG"), "YAML or TOML config file")
+	fs.StringVar(&c.driver, "driver", "", "database driver (overrides the config)")
+	fs.StringVar(&c.dsn, "dsn", "", "connection string (overrides the config)")
+	fs.BoolVar(&c.json, "json", false, "print JSON instead of text")
+	return fs
+}
+
+// parse parses the flags, which prints its own errors
+func (c *cli) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError("")
+	}
+	return nil
+}
+
+// config layers the file, the environment and the flags over the defaults
+func (c *cli) config() (database.Config, error) {
+	cfg := database.DefaultConfig()
+	cfg.LogLevel = "silent" // the output is the command's own
+
+	if c.configFile != "" {
+		file, err := database.ConfigFromFile(c.configFile)
+		if err != nil {
+			return database.Config{}, err
+		}
+		cfg = cfg.Merge(file)
+	}
+	env, err := database.ConfigFromEnv("DB_")
+	if err != nil {
+		return database.Config{}, err
+	}
+	cfg = cfg.Merge(env).Merge(database.Config{Driver: c.driver, DSN: c.dsn})
+	if err := cfg.Validate(); err != nil {
+		return database.Config{}, err
+	}
+	return cfg, nil
+}
+
+// open opens the configured database, migrating it unless readOnly
+func (c *cli) open(readOnly bool) (*database.DB, error) {
+	cfg, err := c.config()
+	if err != nil {
+		return nil, err
+	}
+	cfg.SkipMigrations = readOnly
+	cfg.Backup, cfg.AuditRetention, cfg.Maintenance, cfg.Retention = database.BackupSchedule{}, 0, database.MaintenanceSchedule{}, database.RetentionPolicy{} // The app's schedules, not a command's
+	// migrate status reports drift, and migrate up has to run on a drifted database
+	cfg.SchemaCheck = "off"
+	return database.Open(cfg)
+}
+
+// print writes v as JSON with -json, the text otherwise
+func (c *cli) print(v any, format string, args ...any) {
+	if c.json {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+		return
+	}
+	fmt.Fprintf(c.stdout, format+"\n", args...)
+}
+
+func migrate(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	dir := fs.String("dir", filepath.Join("database", database.MigrationsDir()), "where migrate new writes its files")
+	dryRun := fs.Bool("dry-run", false, "print the SQL up, down or to would run, and the resulting version, without running it")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usagef("want one of up, down, status, to, new")
+	}
+
+	if fs.Arg(0) == "new" {
+		if fs.NArg() != 2 {
+			return usagef("want a name: migrate new add_widgets")
+		}
+		up, down, err := database.NewMigrationFile(*dir, fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		c.print(map[string]string{"up": up, "down": down}, "created %s\ncreated %s", up, down)
+		return nil
+	}
+	if fs.Arg(0) == "to" {
+		if fs.NArg() != 2 {
+			return usagef("want a version: migrate to 3")
+		}
+		version, err := strconv.ParseInt(fs.Arg(1), 10, 64)
+		if err != nil {
+			return usagef("bad version %q", fs.Arg(1))
+		}
+		db, err := c.open(true)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if *dryRun {
+			return c.printPlan(db.PlanMigrateTo(ctx, version))
+		}
+		if err := db.MigrateTo(ctx, version); err != nil {
+			return err
+		}
+		c.print(map[string]int64{"version": version}, "schema is at version %d", version)
+		return nil
+	}
+	if fs.Arg(0) == "down" {
+		n := 1
+		if fs.NArg() > 2 {
+			return usagef("want at most a count: migrate down 3")
+		}
+		if fs.NArg() == 2 {
+			var err error
+			if n, err = strconv.Atoi(fs.Arg(1)); err != nil || n < 1 {
+				return usagef("bad count %q", fs.Arg(1))
+			}
+		}
+		db, err := c.open(true)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if *dryRun {
+			return c.printPlan(db.PlanRollback(ctx, n))
+		}
+		undone, err := db.Rollback(ctx, n)
+		if len(undone) > 0 {
+			c.print(map[string][]string{"undone": migrationNames(undone)}, "undid %s; deploy a build without them, or the next start applies them again", strings.Join(migrationNames(undone), ", "))
+		}
+		if err != nil {
+			return fmt.Errorf("%w; to go back further, restore a backup taken before the upgrade (db restore)", err)
+		}
+		return nil
+	}
+	if fs.NArg() != 1 {
+		return usagef("%s takes no arguments", fs.Arg(0))
+	}
+	if *dryRun && fs.Arg(0) != "up" {
+		return usagef("-dry-run is for up, down and to")
+	}
+
+	switch fs.Arg(0) {
+	case "up":
+		db, err := c.open(true)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if *dryRun {
+			return c.printPlan(db.PlanMigrate(ctx))
+		}
+		pending, err := db.PendingMigrations(ctx)
+		if err != nil {
+			return err
+		}
+		if err := db.Migrate(ctx); err != nil {
+			return err
+		}
+		c.print(struct {
+			Schema  string   `json:"schema"`
+			Applied []string `json:"applied"`
+		}{"current", migrationNames(pending)}, "schema is up to date%s", listed(", applied ", migrationNames(pending)))
+		return nil
+
+	case "status":
+		db, err := c.open(true)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		r := db.Readiness(ctx)
+		if r.Schema == "unknown" && !r.Ready {
+			return errors.New(r.Reason)
+		}
+		pending, err := db.PendingMigrations(ctx)
+		if err != nil {
+			return err
+		}
+		schema := r.Schema
+		if len(pending) > 0 {
+			schema = "outdated"
+		}
+		var drift *database.SchemaDrift
+		if schema == "current" {
+			d, err := db.SchemaDrift(ctx)
+			if err != nil {
+				return err
+			}
+			if !d.None() {
+				schema, drift = "drifted", &d
+			}
+		}
+		c.print(struct {
+			Schema        string                `json:"schema"`
+			MissingTables []string              `json:"missing_tables,omitempty"`
+			Pending       []string              `json:"pending_migrations,omitempty"`
+			Drift         *database.SchemaDrift `json:"drift,omitempty"`
+		}{schema, r.MissingTables, migrationNames(pending), drift},
+			"schema is %s%s%s%s", schema, listed(", missing ", r.MissingTables), listed(", pending ", migrationNames(pending)), driftNote(drift))
+		if schema == "outdated" || schema == "drifted" {
+			return errFound
+		}
+		return nil
+
+	default:
+		return usagef("unknown migrate action %q", fs.Arg(0))
+	}
+}
+
+// printPlan prints a dry run: the SQL, then the version it would leave,
+// or with -json all of it
+func (c *cli) printPlan(p database.MigrationPlan, err error) error {
+	if err != nil {
+		return err
+	}
+	if c.json {
+		c.print(struct {
+			From    int64    `json:"from_version"`
+			To      int64    `json:"to_version"`
+			Applied []string `json:"apply,omitempty"`
+			Undone  []string `json:"undo,omitempty"`
+			SQL     string   `json:"sql"`
+		}{p.From, p.To, migrationNames(p.Apply), migrationNames(p.Undo), p.SQL}, "")
+		return nil
+	}
+	fmt.Fprint(c.stdout, p.SQL)
+	fmt.Fprintf(c.stdout, "-- dry run, nothing was run: version %d would become %d\n", p.From, p.To)
+	return nil
+}
+
+func migrationName(m database.Migration) string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name) // as the files are named
+}
+
+func migrationNames(ms []database.Migration) []string {
+	names := make([]string, len(ms))
+	for i, m := range ms {
+		names[i] = migrationName(m)
+	}
+	return names
+}
+
+func driftNote(d *database.SchemaDrift) string {
+	if d == nil {
+		return ""
+	}
+	return ": " + d.String()
+}
+
+func listed(prefix string, items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return prefix + strings.Join(items, ", ")
+}
+
+// seedData is the file seed reads, for example:
+//
+//	{
+//	  "users":   [{"telegram_id": 1, "first_name": "Ann", "username": "ann"}],
+//	  "groups":  [{"telegram_id": 100, "title": "Chess"}],
+//	  "members": [{"user": 1, "group": 100}]
+//	}
+type seedData struct {
+	Users []struct {
+		TelegramID int64   `json:"telegram_id"`
+		FirstName  string  `json:"first_name"`
+		Username   *string `json:"username"`
+	} `json:"users"`
+	Groups []struct {
+		TelegramID int64   `json:"telegram_id"`
+		Title      *string `json:"title"`
+	} `json:"groups"`
+	Members []struct {
+		User  int64 `json:"user"`
+		Group int64 `json:"group"`
+	} `json:"members"`
+}
+
+func seedCmd(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	file := fs.String("file", "", "JSON file with users, groups and members")
+	env := fs.String("env", "", "run the embedded seeds for this environment (dev, test, prod) instead")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if (*file == "") == (*env == "") {
+		return usagef("one of -file and -env is required")
+	}
+	if *env != "" {
+		return seedEnv(ctx, c, *env)
+	}
+
+	raw, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	var data seedData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", *file, err)
+	}
+
+	db, err := c.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Upserts, so seeding twice leaves the same rows; all or nothing
+	err = db.Transaction(ctx, func(q *database.Queries) error {
+		for _, u := range data.Users {
+			_, err := q.UpsertUser(ctx, database.UpsertUserParams{
+				TelegramID: u.TelegramID,
+				FirstName:  u.FirstName,
+				Username:   nulls.StringPtr(u.Username),
+			})
+			if err != nil {
+				return fmt.Errorf("user %d: %w", u.TelegramID, err)
+			}
+		}
+		for _, g := range data.Groups {
+			_, err := q.UpsertGroup(ctx, database.UpsertGroupParams{
+				TelegramID: g.TelegramID,
+				Title:      nulls.StringPtr(g.Title),
+			})
+			if err != nil {
+				return fmt.Errorf("group %d: %w", g.TelegramID, err)
+			}
+		}
+		for _, m := range data.Members {
+			_, err := q.GetOrCreateUserGroup(ctx, database.GetOrCreateUserGroupParams{
+				UserTelegramID:  m.User,
+				GroupTelegramID: m.Group,
+			})
+			if err != nil {
+				return fmt.Errorf("member %d of %d: %w", m.User, m.Group, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.print(map[string]int{"users": len(data.Users), "groups": len(data.Groups), "members": len(data.Members)},
+		"seeded %d users, %d groups, %d members", len(data.Users), len(data.Groups), len(data.Members))
+	return nil
+}
+
+// seedEnv runs the seed package's seeds for env
+func seedEnv(ctx context.Context, c *cli, env string) error {
+	db, err := c.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ran, err := seed.Seed(ctx, db, env)
+	if err != nil {
+		return err
+	}
+	c.print(map[string]any{"env": env, "seeds": ran}, "ran %d seeds for %s%s", len(ran), env, listed(": ", ran))
+	return nil
+}
+
+func backup(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	var sched database.BackupSchedule
+	fs.StringVar(&sched.Dir, "dir", "backups", "directory for backup files")
+	fs.IntVar(&sched.Keep, "keep", 0, "newest backups to keep, older ones are deleted (0 = all)")
+	fs.BoolVar(&sched.Compress, "compress", false, "gzip the backup")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	path, err := db.Backup(ctx, sched)
+	if err != nil {
+		return err
+	}
+	c.print(map[string]string{"file": path}, "backup written to %s", path)
+	return nil
+}
+
+func restore(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	from := fs.String("from", "", "backup file written by db backup")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return usagef("-from is required")
+	}
+
+	cfg, err := c.config()
+	if err != nil {
+		return err
+	}
+	if err := database.RestoreBackup(ctx, *from, cfg); err != nil {
+		return err
+	}
+	c.print(map[string]string{"restored": *from}, "restored %s", *from)
+	return nil
+}
+
+func integrityCheck(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	problems, err := db.IntegrityCheck(ctx)
+	if err != nil {
+		return err
+	}
+	orphans, err := db.ForeignKeyCheck(ctx, database.ReportOrphans)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		c.print(struct {
+			OK       bool              `json:"ok"`
+			Problems []string          `json:"problems,omitempty"`
+			Orphans  []database.Orphan `json:"orphans,omitempty"`
+		}{len(problems) == 0 && len(orphans) == 0, problems, orphans}, "")
+	} else {
+		for _, p := range problems {
+			fmt.Fprintln(c.stdout, p)
+		}
+		for _, o := range orphans {
+			fmt.Fprintf(c.stdout, "%s row %d: %s points at a missing %s row\n", o.Table, o.RowID, strings.Join(o.Columns, ", "), o.Parent)
+		}
+		if len(problems) == 0 && len(orphans) == 0 {
+			fmt.Fprintln(c.stdout, "ok")
+		}
+	}
+
+	if len(problems) > 0 || len(orphans) > 0 {
+		return errFound
+	}
+	return nil
+}
+
+func export(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	tables := fs.String("tables", "", "comma-separated tables to export (default all)")
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	var names []string
+	for _, t := range strings.Split(*tables, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			names = append(names, t)
+		}
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *out == "" {
+		return db.ExportJSON(ctx, c.stdout, names...)
+	}
+
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := db.ExportJSON(ctx, f, names...); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	c.print(map[string]string{"file": *out}, "exported to %s", *out)
+	return nil
+}
+
+func snapshot(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *out == "" {
+		_, err := db.SnapshotTo(ctx, c.stdout)
+		return err
+	}
+
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	n, err := db.SnapshotTo(ctx, f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	c.print(map[string]any{"file": *out, "bytes": n}, "snapshot of %d bytes written to %s", n, *out)
+	return nil
+}
+
+func docs(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	format := fs.String("format", "markdown", "markdown, mermaid or dot")
+	live := fs.Bool("live", false, "document the live database's schema instead of the embedded one")
+	internal := fs.Bool("internal", false, "include the package's own tables")
+	out := fs.String("o", "", "output file (default stdout)")
+	check := fs.Bool("check", false, "don't write -o, fail if it isn't what would be written")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if *check && *out == "" {
+		return usagef("-check needs -o")
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var d database.SchemaDocs
+	if *live {
+		d.Schema, err = db.Introspect(ctx)
+	} else {
+		d, err = db.SchemaDocs(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	d.Internal = *internal
+
+	var buf bytes.Buffer
+	switch *format {
+	case "markdown":
+		err = d.Markdown(&buf)
+	case "mermaid":
+		err = d.Mermaid(&buf)
+	case "dot":
+		err = d.DOT(&buf)
+	default:
+		return usagef("unknown -format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *out == "":
+		_, err := c.stdout.Write(buf.Bytes())
+		return err
+	case *check:
+		old, err := os.ReadFile(*out)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if !bytes.Equal(old, buf.Bytes()) {
+			c.print(map[string]any{"file": *out, "current": false}, "%s is out of date, run dbctl docs to update it", *out)
+			return errFound
+		}
+		c.print(map[string]any{"file": *out, "current": true}, "%s is up to date", *out)
+		return nil
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	c.print(map[string]string{"file": *out}, "schema docs written to %s", *out)
+	return nil
+}
+
+func purge(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	olderThan := fs.Duration("older-than", 0, "hard-delete users soft-deleted longer ago than this")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if *olderThan <= 0 {
+		return usagef("-older-than is required")
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := database.PurgeSoftDeleted(ctx, db, *olderThan)
+	if err != nil {
+		return err
+	}
+	rows := map[string]int64{}
+	for _, t := range report.Tables {
+		rows[t.Table] = t.Rows
+	}
+	c.print(map[string]any{"purged": report.Rows("users"), "tables": rows}, "purged %s", report)
+	return nil
+}
+
+func pruneAudit(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	olderThan := fs.Duration("older-than", 0, "delete audit log entries older than this")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if *olderThan <= 0 {
+		return usagef("-older-than is required")
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	n, err := db.PruneAuditLog(ctx, *olderThan)
+	if err != nil {
+		return err
+	}
+	c.print(map[string]int64{"pruned": n}, "pruned %d audit log entries", n)
+	return nil
+}
+
+func maintain(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	results, err := db.Maintain(ctx)
+	if results == nil {
+		return err
+	}
+
+	type result struct {
+		Task       string  `json:"task"`
+		DurationMS float64 `json:"duration_ms"`
+		Detail     string  `json:"detail,omitempty"`
+		Error      string  `json:"error,omitempty"`
+	}
+	out := make([]result, len(results))
+	for i, r := range results {
+		out[i] = result{Task: r.Task, DurationMS: float64(r.Duration.Microseconds()) / 1000, Detail: r.Detail}
+		if r.Err != nil {
+			out[i].Error = r.Err.Error()
+		}
+	}
+	if c.json {
+		c.print(out, "")
+	} else {
+		for _, r := range out {
+			switch {
+			case r.Error != "":
+				fmt.Fprintf(c.stdout, "%s: failed: %s\n", r.Task, r.Error)
+			case r.Detail != "":
+				fmt.Fprintf(c.stdout, "%s: %s (%.1fms)\n", r.Task, r.Detail, r.DurationMS)
+			default:
+				fmt.Fprintf(c.stdout, "%s: done (%.1fms)\n", r.Task, r.DurationMS)
+			}
+		}
+	}
+	return err
+}
+
+func vacuum(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	start := time.Now()
+	if err := db.Vacuum(ctx); err != nil {
+		return err
+	}
+	took := time.Since(start)
+	c.print(map[string]float64{"duration_ms": float64(took.Microseconds()) / 1000}, "vacuumed in %v", took.Round(time.Millisecond))
+	return nil
+}
+
+func backfillKeys(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags()
+	var b database.KeyBackfill
+	fs.StringVar(&b.Table, "table", "", "integer-keyed table to give UUIDs or ULIDs")
+	fs.StringVar(&b.Key, "key", "", "its integer key (default id)")
+	fs.StringVar(&b.Column, "column", "", "column for the new IDs (default uid)")
+	fs.StringVar(&b.Kind, "kind", "", "uuid (default) or ulid")
+	fs.IntVar(&b.BatchSize, "batch-size", 0, "rows per transaction (default 1000)")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if b.Table == "" {
+		return usagef("-table is required")
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	res, err := db.BackfillKeys(ctx, b)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		c.print(map[string]any{"rows": res.Rows, "references": res.References}, "")
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "gave %d rows of %s an ID\n", res.Rows, b.Table)
+	for _, r := range res.References {
+		fmt.Fprintf(c.stdout, "filled %s\n", r)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+//go:build !postgres
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI runs a subcommand against dsn and returns its exit code and output
+func runCLI(t *testing.T, dsn string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	if len(args) > 0 {
+		args = append([]string{args[0], "-dsn", dsn}, args[1:]...)
+	}
+	code := run("db", args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestMigrateStatus(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "app.db")
+
+	if code, out, errOut := runCLI(t, dsn, "migrate", "up"); code != ExitOK || !strings.HasPrefix(out, "schema is up to date, applied 0000_baseline, ") {
+		t.Fatalf("migrate up: %d %q %q", code, out, errOut)
+	}
+	if code, out, _ := runCLI(t, dsn, "migrate", "status"); code != ExitOK || out != "schema is current\n" {
+		t.Errorf("migrate status: %d %q, want current and exit 0", code, out)
+	}
+
+	if code, out, errOut := runCLI(t, dsn, "migrate", "down"); code != ExitOK || !strings.HasPrefix(out, "undid ") {
+		t.Fatalf("migrate down: %d %q %q", code, out, errOut)
+	}
+	code, out, _ := runCLI(t, dsn, "migrate", "-json", "status")
+	var status struct {
+		Schema  string   `json:"schema"`
+		Pending []string `json:"pending_migrations"`
+	}
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		t.Fatalf("migrate status -json printed %q: %v", out, err)
+	}
+	if code != ExitFailed || status.Schema != "outdated" || len(status.Pending) != 1 {
+		t.Errorf("migrate status after a rollback: %d %+v, want outdated, one pending and exit 1", code, status)
+	}
+
+	if code, _, errOut := runCLI(t, dsn, "migrate", "sideways"); code != ExitUsage || !strings.Contains(errOut, `unknown migrate action "sideways"`) {
+		t.Errorf("an unknown action: %d %q, want a usage error", code, errOut)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "app.db")
+	dir := t.TempDir()
+	if code, _, errOut := runCLI(t, dsn, "migrate", "up"); code != ExitOK {
+		t.Fatalf("migrate up: %d %q", code, errOut)
+	}
+
+	code, out, errOut := runCLI(t, dsn, "backup", "-json", "-dir", dir, "-compress")
+	var written struct{ File string }
+	if err := json.Unmarshal([]byte(out), &written); code != ExitOK || err != nil {
+		t.Fatalf("backup: %d %q %q", code, out, errOut)
+	}
+	if filepath.Dir(written.File) != dir || !strings.HasSuffix(written.File, ".db.gz") {
+		t.Errorf("backup written to %s, want a .db.gz in %s", written.File, dir)
+	}
+	if _, err := os.Stat(written.File); err != nil {
+		t.Error(err)
+	}
+	if code, out, _ := runCLI(t, dsn, "integrity-check"); code != ExitOK || out != "ok\n" {
+		t.Errorf("integrity-check: %d %q", code, out)
+	}
+
+	restored := filepath.Join(t.TempDir(), "restored.db")
+	if code, out, errOut := runCLI(t, restored, "restore", "-from", written.File); code != ExitOK || out != "restored "+written.File+"\n" {
+		t.Fatalf("restore: %d %q %q", code, out, errOut)
+	}
+	if code, out, _ := runCLI(t, restored, "migrate", "status"); code != ExitOK || out != "schema is current\n" {
+		t.Errorf("status of the restored database: %d %q", code, out)
+	}
+
+	if code, _, errOut := runCLI(t, dsn, "backup", "-dir", filepath.Join(written.File, "sub")); code != ExitFailed || errOut == "" {
+		t.Errorf("a backup into a file's directory: %d %q, want exit 1 and the error", code, errOut)
+	}
+	if code, _, _ := runCLI(t, dsn, "restore"); code != ExitUsage {
+		t.Errorf("restore without -from: exit %d, want %d", code, ExitUsage)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		code int
+	}{
+		{nil, ExitUsage},
+		{[]string{"help"}, ExitOK},
+		{[]string{"frobnicate"}, ExitUsage},
+		{[]string{"backup", "-nope"}, ExitUsage},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run("db", tc.args, &stdout, &stderr); code != tc.code || !strings.Contains(strings.ToLower(stderr.String()), "usage") {
+			t.Errorf("%q: exit %d with %q, want %d and the usage", tc.args, code, stderr.String(), tc.code)
+		}
+	}
+}
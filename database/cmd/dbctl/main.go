@@ -0,0 +1,26 @@
+// Command dbctl runs the cli package's commands as a binary of its own,
+// for operators on hosts without the app: migrations, seeds, backups,
+// checks and maintenance, configured like the app (-config or
+// $DB_CONFIG, DB_* variables, -driver and -dsn).
+//
+//	go build -o dbctl ./database/cmd/dbctl                 # SQLite
+//	go build -tags postgres -o dbctl ./database/cmd/dbctl  # PostgreSQL
+//
+//	dbctl migrate up|down|status
+//	dbctl seed -env dev
+//	dbctl backup -dir backups -keep 7
+//	dbctl vacuum
+//
+// It embeds the schema and migrations it was built with, so build it from
+// the same commit as the app it serves; "dbctl help" lists the commands.
+package main
+
+import (
+	"os"
+
+	"your-project/database/cli"
+)
+
+func main() {
+	os.Exit(cli.RunAs("dbctl", os.Args[1:]))
+}
@@ -0,0 +1,85 @@
+//go:build grpc
+
+// Command grpcserver serves package grpcserver's UserService, configured
+// like the app (-config or $DB_CONFIG, DB_* variables). Generate the
+// proto code first, see package grpcserver:
+//
+//	go generate ./database/grpcserver
+//	go run -tags grpc ./database/cmd/grpcserver -addr :9090
+//	go run -tags grpc,postgres ./database/cmd/grpcserver -addr :9090
+//
+// On SIGTERM it stops taking calls, lets the running ones finish, then
+// shuts the database down.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"your-project/database"
+	"your-project/database/grpcserver"
+	"your-project/database/grpcserver/usersv1"
+)
+
+// How long running calls, then the database, get to finish
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	addr := flag.String("addr", ":9090", "address to listen on")
+	configFile := flag.String("config", os.Getenv("DB_CONFIG"), "YAML or TOML config file")
+	flag.Parse()
+
+	cfg, err := database.LoadConfig(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	gs := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpcserver.ErrorInterceptor(), grpcserver.TxInterceptor(db)),
+		grpc.ChainStreamInterceptor(grpcserver.StreamErrorInterceptor()),
+	)
+	usersv1.RegisterUserServiceServer(gs, grpcserver.New(db))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		log.Printf("listening on %s", *addr)
+		if err := gs.Serve(lis); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+
+	db.Drain()
+	sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-sctx.Done():
+		gs.Stop() // Cuts off the streams still running
+	}
+	if err := db.Shutdown(sctx); err != nil {
+		log.Printf("database shutdown: %v", err)
+	}
+}
@@ -0,0 +1,338 @@
+// Command server is a small REST service over the users table, showing
+// the database package end to end: the generated queries behind each
+// endpoint, a transaction, keyset pagination, the typed errors mapped to
+// HTTP statuses, health probes and a graceful shutdown. It's configured
+// like the app (-config or $DB_CONFIG, DB_* variables).
+//
+//	go run ./database/cmd/server -addr :8080                # SQLite
+//	go run -tags postgres ./database/cmd/server -addr :8080 # PostgreSQL
+//
+//	POST   /users             {"telegram_id": 1, "first_name": "Ann", "email": "ann@example.com"}
+//	GET    /users?limit=20&cursor=...
+//	GET    /users/{id}
+//	PATCH  /users/{id}        {"first_name": "Anna", "version": 1}
+//	DELETE /users/{id}
+//	GET    /livez, /readyz
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"your-project/database"
+	"your-project/database/nulls"
+)
+
+// How long in-flight requests, then the database, get to finish
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	configFile := flag.String("config", os.Getenv("DB_CONFIG"), "YAML or TOML config file")
+	flag.Parse()
+
+	cfg, err := database.LoadConfig(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           routes(&server{db: db}),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		log.Printf("listening on %s", *addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+
+	// Out of the load balancer first, then let the requests finish, then
+	// the database, which waits for its transactions
+	db.Drain()
+	sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(sctx); err != nil {
+		log.Printf("http shutdown: %v", err)
+	}
+	if err := db.Shutdown(sctx); err != nil {
+		log.Printf("database shutdown: %v", err)
+	}
+}
+
+type server struct {
+	db *database.DB
+}
+
+func routes(s *server) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /livez", database.HealthHandler(s.db))
+	mux.Handle("GET /readyz", database.HealthHandler(s.db))
+	mux.HandleFunc("POST /users", s.createUser)
+	mux.HandleFunc("GET /users", s.listUsers)
+	mux.HandleFunc("GET /users/{id}", s.getUser)
+	mux.HandleFunc("PATCH /users/{id}", s.updateUser)
+	mux.HandleFunc("DELETE /users/{id}", s.deleteUser)
+	return requestID(mux)
+}
+
+// requestID tags the request's statements in the query log and slow
+// query records with the caller's X-Request-ID
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := r.Header.Get("X-Request-ID"); id != "" {
+			r = r.WithContext(database.ContextWithRequestID(r.Context(), id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// userJSON is a User as the API shows it: plain nulls rather than
+// sql.Null's {"V": ..., "Valid": ...}
+type userJSON struct {
+	ID         int64           `json:"id"`
+	TelegramID int64           `json:"telegram_id"`
+	FirstName  string          `json:"first_name"`
+	Username   *string         `json:"username"`
+	Email      *string         `json:"email"`
+	Status     database.Status `json:"status"`
+	Language   string          `json:"language"`
+	CreatedAt  *time.Time      `json:"created_at"`
+	UpdatedAt  *time.Time      `json:"updated_at"`
+	Version    int64           `json:"version"`
+}
+
+func toJSON(u database.User) userJSON {
+	return userJSON{
+		ID:         u.ID,
+		TelegramID: u.TelegramID,
+		FirstName:  u.FirstName,
+		Username:   nulls.Ptr[string](u.Username),
+		Email:      nulls.Ptr[string](u.Email),
+		Status:     u.Status,
+		Language:   u.Language,
+		CreatedAt:  nulls.Ptr[time.Time](u.CreatedAt),
+		UpdatedAt:  nulls.Ptr[time.Time](u.UpdatedAt),
+		Version:    u.Version,
+	}
+}
+
+// createUser inserts the user and its user.created event in one
+// transaction, so the event is there exactly when the user is
+func (s *server) createUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64   `json:"telegram_id"`
+		FirstName  string  `json:"first_name"`
+		Username   *string `json:"username"`
+		Email      *string `json:"email"`
+		Language   string  `json:"language"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Language == "" {
+		req.Language = "en"
+	}
+
+	var user database.User
+	err := s.db.InTx(r.Context(), func(tx *database.Tx) error {
+		var err error
+		user, err = tx.Events().CreateUser(r.Context(), database.CreateUserParams{
+			TelegramID: req.TelegramID,
+			FirstName:  req.FirstName,
+			Username:   nulls.FromPtr(req.Username),
+			Email:      nulls.FromPtr(req.Email),
+			Status:     database.StatusActive,
+			Language:   req.Language,
+		})
+		return err
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/users/"+strconv.FormatInt(user.ID, 10))
+	writeJSON(w, http.StatusCreated, toJSON(user))
+}
+
+// listUsers pages through the users oldest first; pass next_cursor back
+// as cursor for the following page
+func (s *server) listUsers(w http.ResponseWriter, r *http.Request) {
+	limit := int64(20)
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 || n > 100 {
+			writeMessage(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+
+	page, err := s.db.UsersByCreatedAt(r.Context(), r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := struct {
+		Users      []userJSON `json:"users"`
+		NextCursor string     `json:"next_cursor,omitempty"`
+	}{Users: []userJSON{}, NextCursor: page.NextCursor}
+	for _, u := range page.Items {
+		resp.Users = append(resp.Users, toJSON(u))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *server) getUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	user, err := s.db.Q.GetUserByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toJSON(user))
+}
+
+// updateUser renames the user at the version the client read, so two
+// clients editing at once can't overwrite each other: the second gets a
+// 409 and reloads
+func (s *server) updateUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		FirstName string  `json:"first_name"`
+		Username  *string `json:"username"`
+		Version   int64   `json:"version"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	err := s.db.UpdateUserIfVersion(r.Context(), database.UpdateUserIfVersionParams{
+		ID:        id,
+		Version:   req.Version,
+		FirstName: req.FirstName,
+		Username:  nulls.FromPtr(req.Username),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	user, err := s.db.Q.GetUserByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toJSON(user))
+}
+
+// deleteUser soft-deletes the user; a second delete is a 404
+func (s *server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	n, err := s.db.Q.SoftDeleteUser(r.Context(), id)
+	if err == nil && n == 0 {
+		err = database.ErrNotFound
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "id must be a number")
+		return 0, false
+	}
+	return id, true
+}
+
+// decode reads a JSON body of at most 1 MiB into v, answering 400 if it
+// can't
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeMessage(w, http.StatusBadRequest, "malformed request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeError answers with the status the database error calls for. What
+// isn't the client's fault is logged and shown only as its status text,
+// since the driver's message may name tables and values.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		validation *database.ValidationError
+		constraint *database.ConstraintError
+	)
+	switch {
+	case errors.As(err, &validation):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"field": validation.Field, "error": validation.Message})
+	case errors.As(err, &constraint):
+		writeMessage(w, http.StatusUnprocessableEntity, "a value is out of range")
+	case errors.Is(err, database.ErrNotFound):
+		writeMessage(w, http.StatusNotFound, "not found")
+	case errors.Is(err, database.ErrDuplicate):
+		writeMessage(w, http.StatusConflict, "already exists")
+	case errors.Is(err, database.ErrStaleVersion):
+		writeMessage(w, http.StatusConflict, "changed by someone else; reload and try again")
+	case errors.Is(err, database.ErrForeignKey):
+		writeMessage(w, http.StatusUnprocessableEntity, "refers to something that doesn't exist")
+	case errors.Is(err, database.ErrInvalidCursor):
+		writeMessage(w, http.StatusBadRequest, "invalid cursor")
+	case errors.Is(err, database.ErrReadOnly):
+		writeMessage(w, http.StatusForbidden, "this instance is read-only")
+	case errors.Is(err, database.ErrQueryTimeout):
+		writeMessage(w, http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout))
+	case errors.Is(err, database.ErrShuttingDown), errors.Is(err, database.ErrCircuitOpen),
+		errors.Is(err, database.ErrConnection), errors.Is(err, database.ErrStorageExhausted):
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		w.Header().Set("Retry-After", "5")
+		writeMessage(w, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+	case errors.Is(err, context.Canceled):
+		// The client went away; nobody reads the answer
+	default:
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		writeMessage(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+func writeMessage(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
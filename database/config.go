@@ -0,0 +1,457 @@
+package database
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFromFile reads a Config from a YAML (.yaml, .yml), TOML (.toml)
+// or JSON (.json) file. Keys are the config tags of Config and BreakerOptions (dsn,
+// max_open_conns, breaker.threshold, ...), durations are strings like "5s"
+// and a key the Config doesn't have is an error, so typos don't go
+// unnoticed. ${NAME} inside a string value is replaced by the environment
+// variable NAME, which must be set; that keeps secrets out of the file.
+// SQLiteFuncs and Collations are code and can't come from a file.
+func ConfigFromFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var raw map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		return Config{}, fmt.Errorf("config file %s: unknown format, use .yaml, .yml, .toml or .json", path)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	expanded, err := expandEnv(raw)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := setConfig(reflect.ValueOf(&cfg).Elem(), expanded.(map[string]any), ""); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ConfigFromEnv reads a Config from environment variables named prefix
+// plus the upper-cased config key, dots becoming underscores: with prefix
+// "DB_", DB_DSN, DB_MAX_OPEN_CONNS, DB_BREAKER_THRESHOLD. Lists are
+// comma-separated. Unset variables leave their field zero.
+func ConfigFromEnv(prefix string) (Config, error) {
+	raw := map[string]any{}
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		val, ok := os.LookupEnv(prefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_")))
+		if !ok {
+			continue
+		}
+
+		m := raw
+		parts := strings.Split(key, ".")
+		for _, p := range parts[:len(parts)-1] {
+			if _, ok := m[p]; !ok {
+				m[p] = map[string]any{}
+			}
+			m = m[p].(map[string]any)
+		}
+		m[parts[len(parts)-1]] = val
+	}
+
+	var cfg Config
+	if err := setConfig(reflect.ValueOf(&cfg).Elem(), raw, ""); err != nil {
+		return Config{}, fmt.Errorf("environment: %w", err)
+	}
+	return cfg, nil
+}
+
+// LoadConfig is the usual layering for a deployment: DefaultConfig, then
+// the file at path (none if path is empty), then DB_* environment
+// variables, checked with Validate
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if path != "" {
+		file, err := ConfigFromFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg = cfg.Merge(file)
+	}
+	env, err := ConfigFromEnv("DB_")
+	if err != nil {
+		return Config{}, err
+	}
+	cfg = cfg.Merge(env)
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every setting Open would reject that can be told
+// without connecting: a driver that's unknown or not in this build, a DSN
+// the driver can't parse, a setting made both in a field and in the DSN,
+// an unknown log level. Open runs it first.
+func (c Config) Validate() error {
+	var errs []error
+	if d, err := dialectFor(c.Driver); err != nil {
+		errs = append(errs, err)
+	} else {
+		if d.checkConfig != nil {
+			driver := cmp.Or(c.Driver, d.drivers[0])
+			if err := d.checkConfig(driver, c); err != nil {
+				errs = append(errs, redactError(err, driver, c.DSN))
+			}
+		}
+		if c.EncryptionKey != "" && d.rekey == nil {
+			errs = append(errs, fmt.Errorf("encryption_key is SQLite-only, encrypt %s's storage instead", d.name))
+		}
+	}
+
+	switch c.LogLevel {
+	case "", "silent", "error", "warn", "info":
+	default:
+		errs = append(errs, fmt.Errorf("unknown log level %q (want silent, error, warn or info)", c.LogLevel))
+	}
+	switch c.SchemaCheck {
+	case "", "warn", "fail", "off":
+	default:
+		errs = append(errs, fmt.Errorf("unknown schema_check %q (want warn, fail or off)", c.SchemaCheck))
+	}
+	if _, err := newQueryLog(false, nil, c.LogQueryArgs, 0, nil); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Redaction.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Backup.Interval > 0 && c.Backup.Dir == "" {
+		errs = append(errs, errors.New("backup.dir is required with backup.interval"))
+	}
+	if c.QueryTimeout < 0 {
+		errs = append(errs, errors.New("query_timeout can't be negative"))
+	}
+	if err := validateQueryPolicies(c.QueryPolicies); err != nil {
+		errs = append(errs, err)
+	}
+	if p := c.PgxPool; p.enabled() {
+		if d, err := dialectFor(c.Driver); err == nil && cmp.Or(c.Driver, d.drivers[0]) != "pgx" {
+			errs = append(errs, errors.New("pgx_pool needs the pgx driver"))
+		}
+		if c.MaxOpenConns != 0 || c.MaxIdleConns != 0 || c.ConnMaxLifetime != 0 || c.ConnMaxIdleTime != 0 {
+			errs = append(errs, errors.New("pgx_pool sizes the pool, leave max_open_conns, max_idle_conns, conn_max_lifetime and conn_max_idle_time at 0"))
+		}
+		if p.MaxConns < 0 || p.MinConns < 0 || p.MaxConnLifetime < 0 || p.MaxConnIdleTime < 0 || p.HealthCheckPeriod < 0 {
+			errs = append(errs, errors.New("pgx_pool settings can't be negative"))
+		}
+		if p.MaxConns > 0 && p.MinConns > p.MaxConns {
+			errs = append(errs, errors.New("pgx_pool.min_conns can't be more than pgx_pool.max_conns"))
+		}
+	}
+	if m := c.Maintenance; m.Checkpoint < 0 || m.Analyze < 0 || m.Vacuum < 0 || m.VacuumPages < 0 {
+		errs = append(errs, errors.New("maintenance intervals and maintenance.vacuum_pages can't be negative"))
+	}
+	if c.ReadOnly {
+		for _, w := range []struct {
+			name string
+			set  bool
+		}{
+			{"shadow_dsn", c.ShadowDSN != ""},
+			{"audit_retention", c.AuditRetention > 0},
+			{"retention", c.Retention.enabled()},
+			{"maintenance", c.Maintenance.enabled()},
+		} {
+			if w.set {
+				errs = append(errs, fmt.Errorf("%s writes, which read_only rules out", w.name))
+			}
+		}
+	}
+	if d, err := dialectFor(c.Driver); err == nil {
+		if err := c.Retention.validate(d); err != nil {
+			errs = append(errs, err)
+		}
+		if err := validateAttach(c, d); err != nil {
+			errs = append(errs, err)
+		}
+		if err := validateReplication(c, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Merge returns c with every field that is set in o (not the zero value)
+// replaced by o's, nested structs field by field. Layer sources with it,
+// lowest precedence first:
+//
+//	cfg := fileCfg.Merge(envCfg).Merge(database.Config{LogLevel: "info"})
+//
+// A zero value can't override: QueryLatency true in the file stays true.
+func (c Config) Merge(o Config) Config {
+	mergeStruct(reflect.ValueOf(&c).Elem(), reflect.ValueOf(o))
+	return c
+}
+
+func mergeStruct(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		d, s := dst.Field(i), src.Field(i)
+		switch {
+		case s.Kind() == reflect.Struct:
+			mergeStruct(d, s)
+		case !s.IsZero():
+			d.Set(s)
+		}
+	}
+}
+
+// configKeys lists the keys ConfigFromFile and ConfigFromEnv know for t
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, ok := f.Tag.Lookup("config")
+		if !ok || key == "-" {
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct {
+			keys = append(keys, configKeys(f.Type, prefix+key+".")...)
+		} else {
+			keys = append(keys, prefix+key)
+		}
+	}
+	return keys
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setConfig fills the struct v from raw, reporting every unknown key
+func setConfig(v reflect.Value, raw map[string]any, prefix string) error {
+	var unknown []string
+	if err := setKeys(v, raw, prefix, &unknown); err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return fmt.Errorf("unknown config keys: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// setKeys is setConfig collecting unknown keys from sections too
+func setKeys(v reflect.Value, raw map[string]any, prefix string, unknown *[]string) error {
+	fields := map[string]reflect.Value{}
+	for i := 0; i < v.NumField(); i++ {
+		if key, ok := v.Type().Field(i).Tag.Lookup("config"); ok && key != "-" {
+			fields[key] = v.Field(i)
+		}
+	}
+
+	for key, val := range raw {
+		f, ok := fields[key]
+		if !ok {
+			*unknown = append(*unknown, prefix+key)
+			continue
+		}
+
+		if f.Kind() == reflect.Struct {
+			section, ok := val.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s%s must be a section", prefix, key)
+			}
+			if err := setKeys(f, section, prefix+key+".", unknown); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setField(f, val); err != nil {
+			return fmt.Errorf("%s%s: %w", prefix, key, err)
+		}
+	}
+	return nil
+}
+
+// setField converts a decoded value, or an environment variable's string,
+// to the field's type
+func setField(f reflect.Value, val any) error {
+	s, isString := val.(string)
+
+	switch {
+	case f.Type() == durationType:
+		if !isString {
+			return fmt.Errorf("want a duration like \"5s\", got %v", val)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+
+	case f.Kind() == reflect.String:
+		if !isString {
+			return fmt.Errorf("want a string, got %v", val)
+		}
+		f.SetString(s)
+
+	case f.Kind() == reflect.Bool:
+		b, ok := val.(bool)
+		if isString {
+			var err error
+			if b, err = strconv.ParseBool(s); err != nil {
+				return err
+			}
+		} else if !ok {
+			return fmt.Errorf("want true or false, got %v", val)
+		}
+		f.SetBool(b)
+
+	case f.Kind() == reflect.Int || f.Kind() == reflect.Int64:
+		var n int64
+		switch x := val.(type) {
+		case int:
+			n = int64(x)
+		case int64:
+			n = x
+		case float64:
+			if x != float64(int64(x)) {
+				return fmt.Errorf("want a whole number, got %v", x)
+			}
+			n = int64(x)
+		case string:
+			var err error
+			if n, err = strconv.ParseInt(x, 10, 64); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("want a number, got %v", val)
+		}
+		f.SetInt(n)
+
+	case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String:
+		var list []string
+		switch x := val.(type) {
+		case string:
+			for _, item := range strings.Split(x, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+		case []any:
+			for _, item := range x {
+				s, ok := item.(string)
+				if !ok {
+					return fmt.Errorf("want a list of strings, got %v", item)
+				}
+				list = append(list, s)
+			}
+		default:
+			return fmt.Errorf("want a list of strings, got %v", val)
+		}
+		f.Set(reflect.ValueOf(list))
+
+	case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Struct:
+		var items []any
+		switch x := val.(type) {
+		case []any:
+			items = x
+		case []map[string]any: // TOML's [[array.of.tables]]
+			for _, m := range x {
+				items = append(items, m)
+			}
+		default:
+			return fmt.Errorf("want a list of sections, got %v", val)
+		}
+		list := reflect.MakeSlice(f.Type(), len(items), len(items))
+		for i, item := range items {
+			section, ok := item.(map[string]any)
+			if !ok {
+				return fmt.Errorf("item %d must be a section", i)
+			}
+			if err := setConfig(list.Index(i), section, fmt.Sprintf("[%d].", i)); err != nil {
+				return err
+			}
+		}
+		f.Set(list)
+
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
+
+var envRef = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// expandEnv replaces ${NAME} in every string of a decoded file
+func expandEnv(val any) (any, error) {
+	switch x := val.(type) {
+	case string:
+		var missing []string
+		out := envRef.ReplaceAllStringFunc(x, func(ref string) string {
+			name := envRef.FindStringSubmatch(ref)[1]
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			return v
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+		}
+		return out, nil
+
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, v := range x {
+			e, err := expandEnv(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = e
+		}
+		return out, nil
+
+	case []any:
+		out := make([]any, len(x))
+		for i, v := range x {
+			e, err := expandEnv(v)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = e
+		}
+		return out, nil
+
+	case []map[string]any:
+		out := make([]any, len(x))
+		for i, v := range x {
+			e, err := expandEnv(v)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = e
+		}
+		return out, nil
+	}
+	return val, nil
+}
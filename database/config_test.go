@@ -0,0 +1,146 @@
+package database_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"your-project/database"
+)
+
+// fill sets every field of v that has a config key and returns the file
+// contents that set them, so each field's key and type are checked
+func fill(t *testing.T, v reflect.Value) map[string]any {
+	t.Helper()
+	raw := map[string]any{}
+	for i := 0; i < v.NumField(); i++ {
+		key, ok := v.Type().Field(i).Tag.Lookup("config")
+		if !ok || key == "-" {
+			continue
+		}
+		f := v.Field(i)
+		switch {
+		case f.Type() == reflect.TypeOf(time.Duration(0)):
+			f.SetInt(int64(7 * time.Second))
+			raw[key] = "7s"
+		case f.Kind() == reflect.Struct:
+			raw[key] = fill(t, f)
+		case f.Kind() == reflect.String:
+			f.SetString(key + "-value")
+			raw[key] = key + "-value"
+		case f.Kind() == reflect.Bool:
+			f.SetBool(true)
+			raw[key] = true
+		case f.Kind() == reflect.Int || f.Kind() == reflect.Int64:
+			f.SetInt(7)
+			raw[key] = 7
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String:
+			f.Set(reflect.ValueOf([]string{"a", "b"}))
+			raw[key] = []any{"a", "b"}
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Struct:
+			list := reflect.MakeSlice(f.Type(), 1, 1)
+			raw[key] = []any{fill(t, list.Index(0))}
+			f.Set(list)
+		default:
+			t.Fatalf("config key %s has type %s, which a file can't set", key, f.Type())
+		}
+	}
+	return raw
+}
+
+func TestConfigFromFile(t *testing.T) {
+	var want database.Config
+	raw := fill(t, reflect.ValueOf(&want).Elem())
+	t.Setenv("TEST_DB_PASSWORD", "s3cret")
+	raw["dsn"] = "postgres://app:${TEST_DB_PASSWORD}@db/app"
+	want.DSN = "postgres://app:s3cret@db/app"
+
+	dir := t.TempDir()
+	for ext, marshal := range map[string]func(any) ([]byte, error){
+		".yaml": yaml.Marshal,
+		".json": json.Marshal,
+		".toml": func(v any) ([]byte, error) {
+			var b strings.Builder
+			err := toml.NewEncoder(&b).Encode(v)
+			return []byte(b.String()), err
+		},
+	} {
+		data, err := marshal(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "db"+ext)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := database.ConfigFromFile(path)
+		if err != nil {
+			t.Errorf("%s: %v", ext, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s read\n%+v\nwant\n%+v", ext, got, want)
+		}
+	}
+}
+
+func writeConfig(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFromFileErrors(t *testing.T) {
+	_, err := database.ConfigFromFile(writeConfig(t, "db.yaml", "max_open_con: 3\nbreaker:\n  threshhold: 2\n"))
+	if err == nil || !strings.Contains(err.Error(), "unknown config keys: breaker.threshhold, max_open_con") {
+		t.Errorf("misspelled keys: %v, want both named", err)
+	}
+	_, err = database.ConfigFromFile(writeConfig(t, "db.toml", "[breaker]\nthreshhold = 2\n"))
+	if err == nil || !strings.Contains(err.Error(), "breaker.threshhold") {
+		t.Errorf("a misspelled key in a section: %v, want it named", err)
+	}
+
+	os.Unsetenv("TEST_DB_UNSET")
+	_, err = database.ConfigFromFile(writeConfig(t, "db.yaml", "dsn: postgres://app:${TEST_DB_UNSET}@db/app\n"))
+	if err == nil || !strings.Contains(err.Error(), "TEST_DB_UNSET is not set") {
+		t.Errorf("a missing environment variable: %v, want it named", err)
+	}
+
+	for name, contents := range map[string]string{
+		"db.yaml": "max_open_conns: many\n",
+		"db.json": `{"query_timeout": 5}`,
+		"db.ini":  "dsn = app.db\n",
+	} {
+		if _, err := database.ConfigFromFile(writeConfig(t, name, contents)); err == nil {
+			t.Errorf("%s %q read without an error", name, contents)
+		}
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	file, err := database.ConfigFromFile(writeConfig(t, "db.yaml", "dsn: file.db\nmax_open_conns: 3\nlog_level: warn\nquery_latency: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TESTCFG_MAX_OPEN_CONNS", "9")
+	t.Setenv("TESTCFG_LOG_LEVEL", "error")
+	env, err := database.ConfigFromEnv("TESTCFG_")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := file.Merge(env).Merge(database.Config{LogLevel: "info"})
+	if cfg.DSN != "file.db" || cfg.MaxOpenConns != 9 || cfg.LogLevel != "info" || !cfg.QueryLatency {
+		t.Errorf("merged %+v, want the file's DSN, the environment's pool size, the code's log level and QueryLatency kept", cfg)
+	}
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Estimates below this are replaced by an exact COUNT(*), which is cheap
+// at that size
+const defaultExactCountBelow = 10000
+
+var createTableRe = regexp.MustCompile(`(?i)CREATE TABLE IF NOT EXISTS (\w+)`)
+
+// schemaTables lists the tables the embedded schema creates
+func schemaTables(schema string) map[string]bool {
+	tables := map[string]bool{}
+	for _, m := range createTableRe.FindAllStringSubmatch(schema, -1) {
+		tables[m[1]] = true
+	}
+	return tables
+}
+
+// ApproxCount returns the number of rows in table without scanning it when
+// the dialect has a cheaper source: sqlite_stat1 (after ANALYZE) or the
+// rowid range on SQLite, pg_class.reltuples on PostgreSQL. Small tables,
+// and tables without an estimate, are counted exactly; exact says which
+// happened. table must be one the schema creates.
+func (db *DB) ApproxCount(ctx context.Context, table string) (n int64, exact bool, err error) {
+	d := defaultDialect()
+	if !schemaTables(d.schema)[table] {
+		return 0, false, fmt.Errorf("unknown table %q", table)
+	}
+
+	threshold := db.exactCountBelow
+	if threshold == 0 {
+		threshold = defaultExactCountBelow
+	}
+
+	if d.approxCount != nil {
+		n, ok, err := d.approxCount(ctx, db.Conn, table)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to estimate rows of %s: %w", table, err)
+		}
+		if ok && n >= threshold {
+			return n, false, nil
+		}
+	}
+
+	// The name was checked against the schema, so it's safe to quote in
+	if err := db.Conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM "`+table+`"`).Scan(&n); err != nil {
+		return 0, false, fmt.Errorf("failed to count rows of %s: %w", table, err)
+	}
+	return n, true, nil
+}
@@ -0,0 +1,60 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestApproxCount(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { c.ExactCountBelow = 500 }})
+	exec := func(query string) {
+		t.Helper()
+		if _, err := db.DBTX().ExecContext(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(what string, want int64, wantExact bool) {
+		t.Helper()
+		n, exact, err := db.ApproxCount(ctx, "users")
+		if err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+		if exact != wantExact {
+			t.Errorf("%s: exact %v, want %v", what, exact, wantExact)
+		}
+		if d := n - want; d < -want/10 || d > want/10 {
+			t.Errorf("%s: %d users, want %d within 10%%", what, n, want)
+		}
+	}
+
+	users := make([]database.CreateUserParams, 3000)
+	for i := range users {
+		users[i] = database.CreateUserParams{TelegramID: int64(i + 1), FirstName: "user", Status: database.StatusActive, Language: "en"}
+	}
+	if _, err := db.BulkCreateUsers(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+	exec("ANALYZE")
+	check("after the bulk insert", 3000, false)
+
+	exec("DELETE FROM users WHERE id % 3 = 0")
+	exec("ANALYZE")
+	check("after deleting a third", 2000, false)
+
+	// Below ExactCountBelow the estimate is replaced by a count
+	exec("DELETE FROM users WHERE id > 100")
+	exec("ANALYZE")
+	if n, exact, err := db.ApproxCount(ctx, "users"); err != nil || !exact || n != 67 {
+		t.Errorf("a small table: %d (exact %v), %v; want exactly 67", n, exact, err)
+	}
+
+	for _, table := range []string{"nope", "users; DROP TABLE users", `users" --`, "sqlite_master"} {
+		if _, _, err := db.ApproxCount(ctx, table); err == nil {
+			t.Errorf("ApproxCount(%q): no error, want it refused", table)
+		}
+	}
+}
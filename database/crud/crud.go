@@ -0,0 +1,285 @@
+// Package crud is Get, List, Create, Update and Delete on the models sqlc
+// generates, for tables too plain to be worth queries of their own:
+//
+//	tags, err := crud.New[database.Tag, int64](ctx, db, "tags")
+//
+//	tag, err := tags.Create(ctx, database.Tag{Name: "go"})
+//	tag, err = tags.Get(ctx, tag.ID)
+//	page, err := tags.List(ctx, "", 50) // then page.NextCursor
+//	err = tags.Delete(ctx, tag.ID)
+//
+// A model's columns are its fields' json tags, which sqlc sets to the
+// column names; New checks them against the table. Keys are integers,
+// strings, or ids.UUID and ids.ULID, which Create makes for a new row. Anything with a join,
+// a filter, a partial update, a version check or a soft delete stays a
+// sqlc query, next to the Repo for the rest.
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"your-project/database"
+	"your-project/database/ids"
+)
+
+// Key is the types a Repo's primary key can have
+type Key interface {
+	int | int64 | string | ids.UUID | ids.ULID
+}
+
+// Repo reads and writes one table's rows as T, keyed by its single-column
+// primary key of type PK. It's safe for concurrent use.
+type Repo[T any, PK Key] struct {
+	db     *database.DB
+	dbtx   database.DBTX
+	table  string
+	fields []field // T's columns, in T's order
+	pk     int     // Index into fields
+	cols   string  // The quoted column list, for SELECT and RETURNING
+}
+
+// field is a field of T and what the table says of its column
+type field struct {
+	column    string
+	index     int  // In T
+	defaulted bool // The database fills it in if Create leaves it out: a DEFAULT, or the rowid or serial key
+	generated bool // Computed, never written
+}
+
+var ident = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// New maps T, a struct, onto table. Fields without a json tag, or tagged
+// "-", aren't columns. It fails when a field has no column, the table
+// lacks a single-column primary key among T's fields of type PK, or a
+// column Create must fill has no field. New reads the whole schema, so
+// make the Repos once, at startup.
+func New[T any, PK Key](ctx context.Context, db *database.DB, table string) (*Repo[T, PK], error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("crud: %s isn't a struct", typ)
+	}
+	if !ident.MatchString(table) {
+		return nil, fmt.Errorf("crud: %q isn't a plain table name", table)
+	}
+	schema, err := db.Introspect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info, ok := schema.Table(table)
+	if !ok || info.View {
+		return nil, fmt.Errorf("crud: no table %s", table)
+	}
+	pk := info.PrimaryKey()
+	if len(pk) != 1 {
+		return nil, fmt.Errorf("crud: %s needs a primary key of one column, it has %d", table, len(pk))
+	}
+
+	r := &Repo[T, PK]{db: db, dbtx: db.DBTX(), table: table, pk: -1}
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		c := slices.IndexFunc(info.Columns, func(c database.ColumnInfo) bool { return c.Name == name })
+		if c < 0 {
+			return nil, fmt.Errorf("crud: %s has no column %s for %s.%s", table, name, typ.Name(), sf.Name)
+		}
+		col := info.Columns[c]
+		f := field{column: name, index: i, defaulted: col.Default != nil, generated: col.Generated}
+		if name == pk[0] {
+			if sf.Type != reflect.TypeFor[PK]() {
+				return nil, fmt.Errorf("crud: %s.%s is a %s, not the %s the Repo is keyed by", typ.Name(), sf.Name, sf.Type, reflect.TypeFor[PK]())
+			}
+			// SQLite's INTEGER PRIMARY KEY takes the next rowid without a DEFAULT
+			f.defaulted = f.defaulted || sf.Type.Kind() == reflect.Int || sf.Type.Kind() == reflect.Int64
+			r.pk = len(r.fields)
+		}
+		r.fields = append(r.fields, f)
+	}
+	if r.pk < 0 {
+		return nil, fmt.Errorf("crud: %s has no field for the primary key %s", typ.Name(), pk[0])
+	}
+	for _, c := range info.Columns {
+		required := !c.Nullable && c.Default == nil && !c.Generated && c.Name != pk[0]
+		if required && !slices.ContainsFunc(r.fields, func(f field) bool { return f.column == c.Name }) {
+			return nil, fmt.Errorf("crud: %s has no field for %s.%s, which can't be left out", typ.Name(), table, c.Name)
+		}
+	}
+
+	cols := make([]string, len(r.fields))
+	for i, f := range r.fields {
+		cols[i] = quote(f.column)
+	}
+	r.cols = strings.Join(cols, ", ")
+	return r, nil
+}
+
+// WithTx returns the Repo running in tx
+func (r *Repo[T, PK]) WithTx(tx *database.Tx) *Repo[T, PK] {
+	c := *r
+	c.dbtx = tx.DBTX()
+	return &c
+}
+
+// Get returns the row whose key is id, or database.ErrNotFound
+func (r *Repo[T, PK]) Get(ctx context.Context, id PK) (T, error) {
+	return r.one(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s",
+		r.cols, quote(r.table), quote(r.fields[r.pk].column), database.Param(1)), id)
+}
+
+// List returns a page of rows in key order. Pass "" as cursor for the
+// first page and the previous page's NextCursor after that.
+func (r *Repo[T, PK]) List(ctx context.Context, cursor string, pageSize int64) (database.Page[T], error) {
+	var after PK
+	key := quote(r.fields[r.pk].column)
+	return database.Paginate(r.db, cursor, pageSize, []any{&after},
+		func(limit int64) ([]T, error) {
+			if cursor == "" {
+				return r.many(ctx, fmt.Sprintf("SELECT %s FROM %s ORDER BY %s LIMIT %s",
+					r.cols, quote(r.table), key, database.Param(1)), limit)
+			}
+			return r.many(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s > %s ORDER BY %s LIMIT %s",
+				r.cols, quote(r.table), key, database.Param(1), key, database.Param(2)), after, limit)
+		},
+		func(v T) []any { return []any{r.key(v)} },
+	)
+}
+
+// Create inserts v and returns the row as stored. A column with a DEFAULT,
+// and an integer key, is left to the database when v's field is the zero
+// value; generated columns always are. A zero UUID or ULID key gets a new
+// one.
+func (r *Repo[T, PK]) Create(ctx context.Context, v T) (T, error) {
+	rv := reflect.ValueOf(v)
+	var (
+		cols, params []string
+		args         []any
+	)
+	for i, f := range r.fields {
+		val := rv.Field(f.index)
+		if i == r.pk && val.IsZero() {
+			if k, ok := newKey[PK](); ok {
+				val = reflect.ValueOf(k)
+			}
+		}
+		if f.generated || f.defaulted && val.IsZero() {
+			continue
+		}
+		args = append(args, val.Interface())
+		cols = append(cols, quote(f.column))
+		params = append(params, database.Param(len(args)))
+	}
+	if len(cols) == 0 {
+		return r.one(ctx, fmt.Sprintf("INSERT INTO %s DEFAULT VALUES RETURNING %s", quote(r.table), r.cols))
+	}
+	return r.one(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s",
+		quote(r.table), strings.Join(cols, ", "), strings.Join(params, ", "), r.cols), args...)
+}
+
+// Update writes every column of v to the row with v's key, and returns the
+// row as stored, or database.ErrNotFound. Read the row first, as Get does,
+// so the fields left alone keep their values.
+func (r *Repo[T, PK]) Update(ctx context.Context, v T) (T, error) {
+	rv := reflect.ValueOf(v)
+	var (
+		set  []string
+		args []any
+	)
+	for i, f := range r.fields {
+		if i == r.pk || f.generated {
+			continue
+		}
+		args = append(args, rv.Field(f.index).Interface())
+		set = append(set, quote(f.column)+" = "+database.Param(len(args)))
+	}
+	if len(set) == 0 {
+		return r.Get(ctx, r.key(v)) // Nothing but the key to write
+	}
+	args = append(args, r.key(v))
+	return r.one(ctx, fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s RETURNING %s",
+		quote(r.table), strings.Join(set, ", "), quote(r.fields[r.pk].column), database.Param(len(args)), r.cols), args...)
+}
+
+// Delete removes the row whose key is id, or returns database.ErrNotFound
+func (r *Repo[T, PK]) Delete(ctx context.Context, id PK) error {
+	res, err := r.dbtx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = %s",
+		quote(r.table), quote(r.fields[r.pk].column), database.Param(1)), id)
+	if err != nil {
+		return database.Translate(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return database.ErrNotFound
+	}
+	return nil
+}
+
+// newKey makes the key Create gives a new row of a table keyed by UUID or
+// ULID; integer and string keys are the database's or the caller's
+func newKey[PK Key]() (PK, bool) {
+	var k PK
+	switch p := any(&k).(type) {
+	case *ids.UUID:
+		*p = ids.NewUUID()
+	case *ids.ULID:
+		*p = ids.NewULID()
+	default:
+		return k, false
+	}
+	return k, true
+}
+
+func (r *Repo[T, PK]) key(v T) PK {
+	return reflect.ValueOf(v).Field(r.fields[r.pk].index).Interface().(PK)
+}
+
+// dest is what Scan fills in v, in the order of r.cols
+func (r *Repo[T, PK]) dest(v *T) []any {
+	rv := reflect.ValueOf(v).Elem()
+	dest := make([]any, len(r.fields))
+	for i, f := range r.fields {
+		dest[i] = rv.Field(f.index).Addr().Interface()
+	}
+	return dest
+}
+
+// one runs a query returning a row: sql.ErrNoRows comes back as
+// database.ErrNotFound, like the rest of Translate's errors
+func (r *Repo[T, PK]) one(ctx context.Context, query string, args ...any) (T, error) {
+	var v T
+	if err := r.dbtx.QueryRowContext(ctx, query, args...).Scan(r.dest(&v)...); err != nil {
+		return v, database.Translate(err)
+	}
+	return v, nil
+}
+
+func (r *Repo[T, PK]) many(ctx context.Context, query string, args ...any) ([]T, error) {
+	rows, err := r.dbtx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+	items := []T{}
+	for rows.Next() {
+		var v T
+		if err := rows.Scan(r.dest(&v)...); err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, database.Translate(rows.Err())
+}
+
+// quote makes a name an identifier, for a column called "order" or "group"
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,295 @@
+package database
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrInvalidCursor is returned by DecodeCursor for a cursor that is
+// malformed, was tampered with, doesn't match the fields asked for or has
+// expired. It's the client's fault, so answer it with a 400.
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+const (
+	cursorVersion = 2  // 1 held times as UnixNano, which only spans 1678 to 2262
+	cursorMACSize = 16 // Truncated HMAC-SHA256
+)
+
+// Page is one page of a keyset-paginated list. NextCursor is empty on the
+// last page; otherwise pass it back to get the next one.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// cursorSecret is Config.CursorSecret, or a random key when it's empty.
+// A random key means cursors don't survive a restart or work across
+// instances.
+func cursorSecret(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// EncodeCursor packs fields (int, int64, float64, string, bool, time.Time
+// or an encoding.TextMarshaler, such as ids.UUID) into an opaque, URL-safe
+// string signed with Config.CursorSecret, so clients can't fabricate
+// cursors to probe for data
+func (db *DB) EncodeCursor(fields ...any) string {
+	buf := []byte{cursorVersion}
+	if db.cursorTTL > 0 {
+		buf = binary.AppendVarint(buf, time.Now().Unix())
+	} else {
+		buf = binary.AppendVarint(buf, 0)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(fields)))
+
+	for _, f := range fields {
+		switch v := f.(type) {
+		case int:
+			buf = binary.AppendVarint(append(buf, 'i'), int64(v))
+		case int64:
+			buf = binary.AppendVarint(append(buf, 'i'), v)
+		case float64:
+			buf = binary.BigEndian.AppendUint64(append(buf, 'f'), math.Float64bits(v))
+		case string:
+			buf = binary.AppendUvarint(append(buf, 's'), uint64(len(v)))
+			buf = append(buf, v...)
+		case bool:
+			b := byte(0)
+			if v {
+				b = 1
+			}
+			buf = append(buf, 'b', b)
+		case time.Time:
+			buf = binary.AppendVarint(append(buf, 't'), v.Unix())
+			buf = binary.AppendUvarint(buf, uint64(v.Nanosecond()))
+		case encoding.TextMarshaler:
+			text, err := v.MarshalText()
+			if err != nil {
+				panic(fmt.Sprintf("database: cursor field %T: %v", f, err))
+			}
+			buf = binary.AppendUvarint(append(buf, 'x'), uint64(len(text)))
+			buf = append(buf, text...)
+		default:
+			panic(fmt.Sprintf("database: unsupported cursor field type %T", f))
+		}
+	}
+
+	mac := hmac.New(sha256.New, db.cursorSecret)
+	mac.Write(buf)
+	buf = mac.Sum(buf)[:len(buf)+cursorMACSize]
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// DecodeCursor verifies a cursor made by EncodeCursor and unpacks it into
+// dest (*int, *int64, *float64, *string, *bool, *time.Time or an
+// encoding.TextUnmarshaler), which must match the encoded fields in number
+// and type. With Config.CursorTTL set, older cursors are rejected. Every failure wraps ErrInvalidCursor.
+func (db *DB) DecodeCursor(s string, dest ...any) error {
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(buf) < 1+cursorMACSize {
+		return fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+
+	body, sum := buf[:len(buf)-cursorMACSize], buf[len(buf)-cursorMACSize:]
+	mac := hmac.New(sha256.New, db.cursorSecret)
+	mac.Write(body)
+	if !hmac.Equal(sum, mac.Sum(nil)[:cursorMACSize]) {
+		return fmt.Errorf("%w: bad signature", ErrInvalidCursor)
+	}
+	version := body[0]
+	if version != cursorVersion && version != 1 {
+		return fmt.Errorf("%w: unknown version %d", ErrInvalidCursor, version)
+	}
+
+	r := cursorReader{buf: body[1:]}
+	issued := r.varint()
+	if db.cursorTTL > 0 && time.Since(time.Unix(issued, 0)) > db.cursorTTL {
+		return fmt.Errorf("%w: expired", ErrInvalidCursor)
+	}
+	if n := r.uvarint(); r.err == nil && n != uint64(len(dest)) {
+		return fmt.Errorf("%w: has %d fields, want %d", ErrInvalidCursor, n, len(dest))
+	}
+
+	for i, d := range dest {
+		tag := r.byte()
+		switch d := d.(type) {
+		case *int:
+			r.expect(tag, 'i', i)
+			*d = int(r.varint())
+		case *int64:
+			r.expect(tag, 'i', i)
+			*d = r.varint()
+		case *float64:
+			r.expect(tag, 'f', i)
+			if b := r.bytes(8); len(b) == 8 {
+				*d = math.Float64frombits(binary.BigEndian.Uint64(b))
+			}
+		case *string:
+			r.expect(tag, 's', i)
+			*d = string(r.bytes(r.uvarint()))
+		case *bool:
+			r.expect(tag, 'b', i)
+			*d = r.byte() == 1
+		case *time.Time:
+			r.expect(tag, 't', i)
+			if version == 1 {
+				*d = time.Unix(0, r.varint()).UTC()
+			} else if sec, nsec := r.varint(), r.uvarint(); nsec < 1e9 {
+				*d = time.Unix(sec, int64(nsec)).UTC()
+			} else {
+				r.fail(fmt.Errorf("field %d has %d nanoseconds", i, nsec))
+			}
+		case encoding.TextUnmarshaler:
+			r.expect(tag, 'x', i)
+			if text := r.bytes(r.uvarint()); r.err == nil {
+				if err := d.UnmarshalText(text); err != nil {
+					r.fail(err)
+				}
+			}
+		default:
+			return fmt.Errorf("database: unsupported cursor destination type %T", d)
+		}
+	}
+	if r.err == nil && len(r.buf) > 0 {
+		r.err = errors.New("trailing bytes")
+	}
+	if r.err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, r.err)
+	}
+	return nil
+}
+
+// cursorReader reads the fields of a cursor, keeping the first error
+type cursorReader struct {
+	buf []byte
+	err error
+}
+
+func (r *cursorReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+	r.buf = nil
+}
+
+func (r *cursorReader) byte() byte {
+	if len(r.buf) == 0 {
+		r.fail(errors.New("truncated"))
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *cursorReader) varint() int64 {
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail(errors.New("truncated"))
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *cursorReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail(errors.New("truncated"))
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *cursorReader) bytes(n uint64) []byte {
+	if uint64(len(r.buf)) < n {
+		r.fail(errors.New("truncated"))
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *cursorReader) expect(tag, want byte, field int) {
+	if r.err == nil && tag != want {
+		r.fail(fmt.Errorf("field %d has type %q, want %q", field, tag, want))
+	}
+}
+
+// Paginate runs one page of a keyset-paginated list query, one whose
+// WHERE starts after the ordering columns of the last row seen, such as
+//
+//	WHERE (created_at, id) > (?, ?) ORDER BY created_at, id LIMIT ?
+//
+// after points at those columns' values, as DecodeCursor takes them: it
+// fills them from cursor, or leaves them as they are for the first page
+// (cursor ""), so set them to values that sort before every row first.
+// list runs the query, reading after, for at most limit rows; key returns
+// a row's values of the same columns, in the same order, the last of
+// which has to be unique. Paginate asks for one row more than pageSize to
+// know whether there's a next page, so the last page has no NextCursor.
+func Paginate[T any](db *DB, cursor string, pageSize int64, after []any, list func(limit int64) ([]T, error), key func(T) []any) (Page[T], error) {
+	if pageSize <= 0 {
+		return Page[T]{}, fmt.Errorf("page size must be positive, got %d", pageSize)
+	}
+	if cursor != "" {
+		if err := db.DecodeCursor(cursor, after...); err != nil {
+			return Page[T]{}, err
+		}
+	}
+
+	items, err := list(pageSize + 1)
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	page := Page[T]{Items: items}
+	if int64(len(items)) > pageSize {
+		page.Items = items[:pageSize]
+		page.NextCursor = db.EncodeCursor(key(items[pageSize-1])...)
+	}
+	return page, nil
+}
+
+// GroupsByTag returns a page of the groups carrying tag. Pass "" as cursor
+// for the first page and the previous page's NextCursor after that.
+func (db *DB) GroupsByTag(ctx context.Context, tag, cursor string, pageSize int64) (Page[Group], error) {
+	var afterID int64
+	return Paginate(db, cursor, pageSize, []any{&afterID},
+		func(limit int64) ([]Group, error) {
+			return db.Q.ListGroupsByTag(ctx, ListGroupsByTagParams{Tag: tag, AfterID: afterID, PageSize: limit})
+		},
+		func(g Group) []any { return []any{g.ID} },
+	)
+}
+
+// UsersByCreatedAt returns a page of users, oldest signup first, the same
+// way as GroupsByTag
+func (db *DB) UsersByCreatedAt(ctx context.Context, cursor string, pageSize int64) (Page[User], error) {
+	var (
+		afterCreatedAt time.Time
+		afterID        int64
+	)
+	return Paginate(db, cursor, pageSize, []any{&afterCreatedAt, &afterID},
+		func(limit int64) ([]User, error) {
+			return db.Q.ListUsersByCreatedAt(ctx, ListUsersByCreatedAtParams{AfterCreatedAt: afterCreatedAt, AfterID: afterID, PageSize: limit})
+		},
+		func(u User) []any { return []any{u.CreatedAt.V, u.ID} },
+	)
+}
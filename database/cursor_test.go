@@ -0,0 +1,42 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	db := dbtest.NewTestDB(t)
+	for _, want := range []time.Time{
+		{}, // A NULL created_at sorts as this
+		time.Date(1500, 3, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.FixedZone("UTC+3", 3*3600)),
+		time.Date(2300, 1, 1, 0, 0, 0, 1, time.UTC),
+		time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC),
+	} {
+		var (
+			got  time.Time
+			id   int64
+			name string
+		)
+		cursor := db.EncodeCursor(want, int64(-42), "Zoë")
+		if err := db.DecodeCursor(cursor, &got, &id, &name); err != nil {
+			t.Fatalf("%v: %v", want, err)
+		}
+		if !got.Equal(want) || got.IsZero() != want.IsZero() || id != -42 || name != "Zoë" {
+			t.Errorf("encoded %v, -42, Zoë; decoded %v, %d, %s", want, got, id, name)
+		}
+		if got.Location() != time.UTC {
+			t.Errorf("%v decoded in %v, want UTC", want, got.Location())
+		}
+	}
+
+	var n int64
+	if err := db.DecodeCursor(db.EncodeCursor(time.Now()), &n); !errors.Is(err, database.ErrInvalidCursor) {
+		t.Errorf("a time decoded into an int64: got %v, want ErrInvalidCursor", err)
+	}
+}
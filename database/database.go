@@ -0,0 +1,363 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// DB holds the database connection and query interface
+type DB struct {
+	Conn *sql.DB
+	Q    Querier
+
+	maxTreeDepth    int
+	maxBlobSize     int64
+	maxIdleConns    int // Restored by resetIdleConns
+	breaker         *breaker
+	queryTimeout    time.Duration
+	policies        *queryPolicies // nil without Config.QueryPolicies
+	writes          *writeLimiter
+	slow            *slowLog
+	latency         *latencyStats
+	stmts           *stmtCache // nil without Config.StatementCache
+	queryLog        *queryLog
+	tracer          *queryTracer
+	cursorSecret    []byte
+	cursorTTL       time.Duration
+	keys            KeyProvider // For EncryptField and BlindIndex, nil without Config.FieldEncryptionKey or FieldKeys
+	exactCountBelow int64
+	health          healthMonitor
+	counters        statsCounters
+	draining        atomic.Bool
+	closed          atomic.Bool
+	shuttingDown    atomic.Bool
+	storage         storageMonitor
+	backups         backupState
+	stopAuditPrune  context.CancelFunc // Ends the loop Config.AuditRetention started, nil without one
+	stopRetention   context.CancelFunc // Ends the loop Config.Retention started, nil without one
+	maintenance     maintenanceState
+	replication     replicationState
+	batchers        batcherSet // The WriteBatchers Shutdown flushes first
+	immediateTx     bool
+	readOnly        bool // Config.ReadOnly: readOnlyDBTX is in front of every statement
+	changes         changeHub
+	cache           cacheState // Writes pending through its CachedQueries
+	hooks           []QueryHook
+	shadow          *shadowMirror
+	replicas        *replicaPool // nil without read replicas
+	dsn             string       // As opened, with the defaults added; empty from NewFromConn
+	borrowed        bool         // Conn is the caller's, from NewFromConn
+	schemaCfg       Config       // What SchemaDrift opens its scratch database with, see scratchConfig
+}
+
+// wrap layers the DBTX middleware (the optional statement cache, the
+// statement count for Stats, the optional query timeout, storage error
+// watch, then the optional query timing, circuit breaker, query policies,
+// tracing, hooks, read-only guard, change feed and write limiter) over the
+// connection
+func (db *DB) wrap(conn DBTX) DBTX {
+	dbtx := db.watchChanges(db.wrapStatements(conn, nil, false), nil)
+	if db.writes != nil {
+		dbtx = &limiterDBTX{DBTX: dbtx, l: db.writes}
+	}
+	return dbtx
+}
+
+// wrapTx is wrap for a transaction, which already holds its write slot;
+// t is nil outside InTx
+func (db *DB) wrapTx(tx DBTX, t *Tx) DBTX {
+	return db.wrapStatements(tx, t, true)
+}
+
+// wrapStatements is what wrap and wrapTx share; query policies retry only
+// outside a transaction
+func (db *DB) wrapStatements(tx DBTX, t *Tx, inTx bool) DBTX {
+	conn := tx
+	tx = &countingDBTX{DBTX: db.stmts.wrap(tx), n: &db.counters.queries}
+	if db.queryTimeout > 0 || db.policies.timeouts() {
+		tx = &timeoutDBTX{DBTX: tx, timeout: db.queryTimeout, policies: db.policies}
+	}
+	tx = &storageDBTX{DBTX: tx, db: db}
+	if db.slow != nil || db.latency != nil || db.queryLog != nil {
+		tx = &timingDBTX{DBTX: tx, slow: db.slow, latency: db.latency, log: db.queryLog}
+	}
+	if db.breaker != nil {
+		tx = &breakerDBTX{DBTX: tx, b: db.breaker}
+	}
+	if db.policies != nil {
+		tx = &policyDBTX{DBTX: tx, p: db.policies, retry: !inTx, retries: &db.counters.retries}
+	}
+	tx = db.wrapHooks(db.tracer.wrap(tx, t), conn)
+	if db.readOnly {
+		tx = readOnlyDBTX{tx}
+	}
+	return tx
+}
+
+// watchChanges feeds the writes made through dbtx to Listen, on dialects
+// without notifications of their own; tx is the transaction dbtx runs,
+// nil outside one
+func (db *DB) watchChanges(dbtx DBTX, tx *Tx) DBTX {
+	if defaultDialect().listen != nil {
+		return dbtx
+	}
+	return &changesDBTX{DBTX: dbtx, hub: &db.changes, tx: tx}
+}
+
+// Close stops the DB's background jobs and closes the connection pool,
+// unless the DB was made by NewFromConn: the pool is the caller's then.
+func (db *DB) Close() error {
+	db.stop()
+	if db.Conn == nil || db.borrowed {
+		return nil
+	}
+	return db.Conn.Close()
+}
+
+// stop is Close without closing db.Conn
+func (db *DB) stop() {
+	db.Drain()
+	db.closed.Store(true)
+	if db.backups.stop != nil {
+		db.backups.stop()
+	}
+	if db.stopAuditPrune != nil {
+		db.stopAuditPrune()
+	}
+	if db.stopRetention != nil {
+		db.stopRetention()
+	}
+	if db.maintenance.stop != nil {
+		db.maintenance.stop()
+	}
+	if db.replication.stop != nil {
+		db.replication.stop()
+	}
+	if err := db.shadow.close(); err != nil {
+		log.Printf("failed to close the shadow database: %v", err)
+	}
+	db.replicas.close()
+}
+
+// Tx is the transaction handle passed to InTx. It embeds the queries bound
+// to the transaction, so tx.CreateUser works like db.Q.CreateUser, errors
+// translated the same way.
+type Tx struct {
+	Querier
+	queries  *Queries        // Untranslated, for Transaction's fn
+	ctx      context.Context // Carries the Tx, see Context
+	tx       sqlTx
+	onFinish []func(committed bool)
+	depth    int  // Savepoints it's nested in, 0 for a real transaction
+	done     bool // Committed or rolled back; later transactions begin afresh
+}
+
+// OnCommit registers fn to run after the transaction commits successfully.
+// It never runs if the transaction rolls back. In a nested transaction it
+// waits for the outermost one to commit.
+func (t *Tx) OnCommit(fn func()) {
+	t.onFinish = append(t.onFinish, func(committed bool) {
+		if committed {
+			fn()
+		}
+	})
+}
+
+// OnRollback registers fn to run after the transaction rolls back, or
+// fails to commit, for undoing what fn did outside the database (a file
+// written, a cache entry set). In a nested transaction it runs when the
+// savepoint is rolled back, or with the outer transaction's rollback if
+// the savepoint was released.
+func (t *Tx) OnRollback(fn func()) {
+	t.onFinish = append(t.onFinish, func(committed bool) {
+		if !committed {
+			fn()
+		}
+	})
+}
+
+// Exec runs a statement sqlc has no query for, such as a SQL script, in
+// the transaction and through the same middleware as its queries
+func (t *Tx) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.queries.db.ExecContext(ctx, query, args...)
+}
+
+// DBTX is the transaction's connection behind the same middleware as its
+// queries, for packages like crud that build their SQL at run time
+func (t *Tx) DBTX() DBTX {
+	return t.queries.db
+}
+
+// DBTX is Tx.DBTX for the pool: db.Q's middleware, replicas included,
+// without its error translation
+func (db *DB) DBTX() DBTX {
+	return db.wrap(db.routeReads(db.Conn))
+}
+
+// Transaction executes a function within a database transaction
+func (db *DB) Transaction(ctx context.Context, fn func(*Queries) error) error {
+	return db.InTx(ctx, func(tx *Tx) error {
+		return fn(tx.queries)
+	})
+}
+
+// InTx is like Transaction but hands fn the full *Tx
+func (db *DB) InTx(ctx context.Context, fn func(*Tx) error) error {
+	return db.inTx(ctx, db.immediateTx, nil, fn)
+}
+
+// TransactionWithOptions is Transaction with an isolation level or a
+// read-only transaction; nil opts is the same as Transaction.
+//
+// SQLite transactions are always serializable, so any level up to
+// sql.LevelSerializable gets a plain one and higher levels fail. ReadOnly
+// sets PRAGMA query_only for the transaction, since the drivers ignore
+// it. A read-only transaction doesn't take a Config.MaxConcurrentWrites
+// slot, nor the write lock up front with Config.ImmediateWriteTx.
+func (db *DB) TransactionWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	return db.inTx(ctx, db.immediateTx, opts, func(tx *Tx) error {
+		return fn(tx.queries)
+	})
+}
+
+func (db *DB) inTx(ctx context.Context, immediate bool, opts *sql.TxOptions, fn func(*Tx) error) (err error) {
+	if outer, ok := db.TxFromContext(ctx); ok {
+		return db.nestedTx(ctx, outer, opts, fn)
+	}
+	ctx, endSpan := db.tracer.startTx(ctx)
+	defer func() { endSpan(err) }()
+	if db.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+	if db.storage.degraded.Load() {
+		return db.storageErr()
+	}
+	// Released before the hooks run, so a hook may start its own transaction
+	release := func() {}
+	if opts == nil || !opts.ReadOnly {
+		if err := db.writes.acquire(ctx); err != nil {
+			return err
+		}
+		release = db.writes.release
+	}
+
+	tx, err := db.beginTx(ctx, immediate, opts)
+	if err != nil {
+		release()
+		db.noteStorageError(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	db.counters.transactions.Add(1)
+
+	t := &Tx{tx: tx}
+	t.ctx = context.WithValue(ctx, txKey{db}, t)
+	dbtx := db.watchChanges(db.wrapTx(tx, t), t)
+	if db.shadow != nil && db.shadow.db != nil {
+		dbtx = &shadowTxDBTX{DBTX: dbtx, m: db.shadow, tx: t}
+	}
+	t.queries = New(dbtx)
+	t.Querier = translatingQuerier{t.queries}
+
+	actor := ""
+	if (opts == nil || !opts.ReadOnly) && !db.readOnly { // Nothing to attribute writes to
+		actor = ActorFromContext(ctx)
+	}
+	if err := setAuditActor(ctx, tx, actor); err != nil {
+		tx.Rollback()
+		release()
+		db.counters.rollbacks.Add(1)
+		t.finish(false)
+		return err
+	}
+
+	err = fn(t)
+	if err == nil {
+		err = clearAuditActor(ctx, tx, actor)
+	}
+	if err != nil {
+		rbErr := tx.Rollback()
+		release()
+		db.counters.rollbacks.Add(1)
+		t.finish(false)
+		// pgx rolls back on its own once ctx is canceled; that's not a rollback failure
+		if rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return fmt.Errorf("tx error: %v, rollback error: %w", err, rbErr)
+		}
+		return Translate(err)
+	}
+
+	err = tx.Commit()
+	release()
+	if err != nil {
+		db.noteStorageError(err)
+		db.counters.rollbacks.Add(1)
+		t.finish(false)
+		return fmt.Errorf("failed to commit transaction: %w", Translate(err))
+	}
+
+	t.finish(true)
+	return nil
+}
+
+func (t *Tx) finish(committed bool) {
+	t.done = true
+	for _, fn := range t.onFinish {
+		fn(committed)
+	}
+}
+
+func (db *DB) beginTx(ctx context.Context, immediate bool, opts *sql.TxOptions) (sqlTx, error) {
+	if db.breaker == nil {
+		return db.begin(ctx, immediate, opts)
+	}
+	call, err := db.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.begin(ctx, immediate, opts)
+	db.breaker.record(call, err)
+	return tx, err
+}
+
+func (db *DB) begin(ctx context.Context, immediate bool, opts *sql.TxOptions) (sqlTx, error) {
+	d := defaultDialect()
+	if opts != nil {
+		o := *opts
+		if d.txIsolation != nil {
+			level, err := d.txIsolation(o.Isolation)
+			if err != nil {
+				return nil, err
+			}
+			o.Isolation = level
+		}
+		if o.ReadOnly {
+			immediate = false
+			if d.readOnlyTx {
+				return beginOnConn(ctx, db.Conn, d.readOnlyOff, d.readOnlyOn, "BEGIN")
+			}
+		}
+		opts = &o
+	}
+	if immediate && d.beginImmediate != "" {
+		return beginOnConn(ctx, db.Conn, "", d.beginImmediate)
+	}
+	if d.beginStmt != nil {
+		begin, err := d.beginStmt(db.Conn, opts)
+		if err != nil {
+			return nil, err
+		}
+		if begin != "" {
+			return beginOnConn(ctx, db.Conn, "", begin)
+		}
+	}
+	tx, err := db.Conn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
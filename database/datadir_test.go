@@ -0,0 +1,82 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"your-project/database"
+)
+
+func TestDefaultDBPath(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("the XDG layout is for Linux and other Unixes")
+	}
+	home, data := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+
+	t.Setenv("XDG_DATA_HOME", data)
+	if got, err := database.DefaultDBPath("notes"); err != nil || got != filepath.Join(data, "notes", "notes.db") {
+		t.Errorf("DefaultDBPath: %q, %v", got, err)
+	}
+	if got, _ := database.DefaultDBPath(""); got != filepath.Join(data, "app", "app.db") {
+		t.Errorf("DefaultDBPath without a name: %q, want app/app.db", got)
+	}
+	t.Setenv("XDG_DATA_HOME", "relative")
+	if got, _ := database.DefaultDBPath("notes"); got != filepath.Join(home, ".local", "share", "notes", "notes.db") {
+		t.Errorf("DefaultDBPath with a relative XDG_DATA_HOME: %q, want it ignored", got)
+	}
+	for _, name := range []string{"a/b", "..", "."} {
+		if _, err := database.DefaultDBPath(name); err == nil {
+			t.Errorf("DefaultDBPath(%q) accepted a path", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(data, "notes")); !os.IsNotExist(err) {
+		t.Errorf("DefaultDBPath created its directory: %v", err)
+	}
+}
+
+func TestDefaultDSN(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("the XDG layout is for Linux and other Unixes")
+	}
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+
+	cfg := database.DefaultConfig()
+	cfg.AppName, cfg.LogLevel = "notes", "silent"
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	dir := filepath.Join(data, "notes")
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Errorf("database directory mode %o, want 0700", perm)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.db")); err != nil {
+		t.Errorf("the database isn't at the default path: %v", err)
+	}
+
+	// An explicit path, relative too, is used as is
+	cwd := t.TempDir()
+	t.Chdir(cwd)
+	cfg.AppName, cfg.DSN = "other", "here.db"
+	if db, err = database.Open(cfg); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if _, err := os.Stat(filepath.Join(cwd, "here.db")); err != nil {
+		t.Errorf("a relative DSN wasn't opened in the working directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(data, "other")); !os.IsNotExist(err) {
+		t.Errorf("an explicit DSN still created the data directory: %v", err)
+	}
+}
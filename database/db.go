@@ -1,3 +1,5 @@
+//go:build !postgres
+
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
 //   sqlc v1.30.0
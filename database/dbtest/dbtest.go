@@ -0,0 +1,33 @@
+// Package dbtest has helpers for tests of code built on the database
+// package: a fresh database per test, and assertions on query plans
+package dbtest
+
+import (
+	"testing"
+
+	"your-project/database"
+)
+
+// AssertUsesIndex fails the test unless the plan uses the index
+func AssertUsesIndex(t testing.TB, plan database.Plan, index string) {
+	t.Helper()
+	if !plan.UsesIndex(index) {
+		t.Errorf("plan doesn't use index %s (uses %v):\n%s", index, plan.Indexes, format(plan))
+	}
+}
+
+// AssertNoFullScan fails the test if any step of the plan scans a whole table
+func AssertNoFullScan(t testing.TB, plan database.Plan) {
+	t.Helper()
+	if plan.FullScan {
+		t.Errorf("plan scans a full table:\n%s", format(plan))
+	}
+}
+
+func format(plan database.Plan) string {
+	var s string
+	for _, n := range plan.Nodes {
+		s += "  " + n.Detail + "\n"
+	}
+	return s
+}
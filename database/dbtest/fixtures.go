@@ -0,0 +1,41 @@
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/fixtures"
+)
+
+// LoadFixtures loads YAML fixture files into db as one set (see package
+// fixtures), failing the test if they don't load
+func LoadFixtures(t testing.TB, db *database.DB, paths ...string) *fixtures.Loaded {
+	t.Helper()
+	set, err := fixtures.Files(paths...)
+	if err != nil {
+		t.Fatalf("failed to read fixtures: %v", err)
+	}
+	return LoadSet(t, db, set)
+}
+
+// LoadSet is LoadFixtures for fixtures built in Go
+func LoadSet(t testing.TB, db *database.DB, set *fixtures.Set) *fixtures.Loaded {
+	t.Helper()
+	loaded, err := fixtures.Load(context.Background(), db, set)
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	return loaded
+}
+
+// Truncate empties the app's tables, or just tables, for tests sharing one
+// database:
+//
+//	t.Cleanup(func() { dbtest.Truncate(t, db) })
+func Truncate(t testing.TB, db *database.DB, tables ...string) {
+	t.Helper()
+	if err := fixtures.Truncate(context.Background(), db, tables...); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+}
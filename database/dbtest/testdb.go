@@ -0,0 +1,87 @@
+package dbtest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/seed"
+)
+
+// Options adjusts NewTestDBWith
+type Options struct {
+	InMemory bool                   // SQLite: :memory: on a single connection instead of a file in t.TempDir()
+	From     *database.Snapshot     // Starts as a clone of this snapshot (see NewSnapshot) instead of an empty schema
+	Seeds    string                 // Environment whose seeds run after the schema, none if empty (see package seed)
+	Fixtures []string               // SQL files run after the seeds, in order, each in its own transaction (LoadFixtures for YAML)
+	Config   func(*database.Config) // Changes the config before Open
+}
+
+// NewTestDB opens a new database with the schema, closed when the test
+// ends: a SQLite file, or with -tags postgres a database on a throwaway
+// PostgreSQL server. Every call gets a database of its own, so tests using
+// it can run in parallel.
+func NewTestDB(t testing.TB) *database.DB {
+	t.Helper()
+	return NewTestDBWith(t, Options{})
+}
+
+// NewTestDBWith is NewTestDB with seeds, fixtures or other settings
+func NewTestDBWith(t testing.TB, opts Options) *database.DB {
+	t.Helper()
+
+	db := openTestDB(t, opts)
+
+	ctx := context.Background()
+	if opts.Seeds != "" {
+		if _, err := seed.Seed(ctx, db, opts.Seeds); err != nil {
+			t.Fatalf("failed to seed test database: %v", err)
+		}
+	}
+	for _, file := range opts.Fixtures {
+		script, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read fixture: %v", err)
+		}
+		err = db.InTx(ctx, func(tx *database.Tx) error {
+			_, err := tx.Exec(ctx, string(script))
+			return err
+		})
+		if err != nil {
+			t.Fatalf("fixture %s: %v", file, err)
+		}
+	}
+	return db
+}
+
+// NewSnapshot sets up a database with opts, as NewTestDBWith does, and
+// snapshots it, so tests whose seeds and fixtures take a while to load
+// can start from a clone of it with Options.From instead. The snapshot
+// outlives t, to be taken once per test binary:
+//
+//	var (
+//		seededOnce sync.Once
+//		seeded     database.Snapshot
+//	)
+//
+//	func newSeededDB(t *testing.T) *database.DB {
+//		seededOnce.Do(func() { seeded = dbtest.NewSnapshot(t, dbtest.Options{Seeds: "dev"}) })
+//		return dbtest.NewTestDBWith(t, dbtest.Options{From: &seeded})
+//	}
+//
+// A SQLite snapshot is a file in the OS's temp dir, a PostgreSQL one a
+// database on the test server.
+func NewSnapshot(t testing.TB, opts Options) database.Snapshot {
+	t.Helper()
+	db := NewTestDBWith(t, opts)
+	name, err := snapshotName()
+	if err != nil {
+		t.Fatalf("failed to name snapshot: %v", err)
+	}
+	snap, err := db.Snapshot(context.Background(), name)
+	if err != nil {
+		t.Fatalf("failed to snapshot test database: %v", err)
+	}
+	return snap
+}
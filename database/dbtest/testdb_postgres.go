@@ -0,0 +1,126 @@
+//go:build postgres
+
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+
+	"your-project/database"
+)
+
+// The image the throwaway server runs; keep it at the production version
+const postgresImage = "postgres:16-alpine"
+
+// One server per test binary, started by the first test that needs it and
+// removed by testcontainers' reaper once the binary exits. Each test gets
+// a database of its own on it.
+var (
+	serverOnce sync.Once
+	serverDSN  string
+	serverErr  error
+	testDBs    atomic.Int64
+)
+
+// postgresServer returns the URL of the server test databases are
+// created on: $DB_TEST_DSN if set (a CI service container, say), a new
+// container otherwise. A new container's URL goes into $DB_TEST_DSN, so a
+// test binary's child processes use it too.
+func postgresServer() (string, error) {
+	serverOnce.Do(func() {
+		if serverDSN = os.Getenv("DB_TEST_DSN"); serverDSN != "" {
+			return
+		}
+		ctx := context.Background()
+		ctr, err := postgres.Run(ctx, postgresImage,
+			postgres.WithDatabase("app"),
+			postgres.WithUsername("app"),
+			postgres.WithPassword("app"),
+			postgres.BasicWaitStrategies())
+		if err != nil {
+			serverErr = fmt.Errorf("failed to start PostgreSQL container (is Docker running?): %w", err)
+			return
+		}
+		if serverDSN, serverErr = ctr.ConnectionString(ctx, "sslmode=disable"); serverErr == nil {
+			os.Setenv("DB_TEST_DSN", serverDSN)
+		}
+	})
+	return serverDSN, serverErr
+}
+
+// openTestDB creates a database on the test server, migrated by Open or
+// cloned from opts.From, and drops it after the test
+func openTestDB(t testing.TB, opts Options) *database.DB {
+	t.Helper()
+
+	server, err := postgresServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Scheme == "" {
+		t.Fatalf("DB_TEST_DSN must be a postgres:// URL")
+	}
+
+	admin, err := sql.Open("pgx", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := fmt.Sprintf("test_%d_%d", os.Getpid(), testDBs.Add(1))
+	if opts.From == nil {
+		if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+			t.Fatalf("failed to create test database: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)"); err != nil {
+			t.Logf("failed to drop test database %s: %v", name, err)
+		}
+	})
+
+	u.Path = "/" + name
+	cfg := database.Config{Driver: testDriver(), DSN: u.String(), LogLevel: "silent"}
+	if opts.Config != nil {
+		opts.Config(&cfg)
+	}
+
+	open := database.Open
+	if opts.From != nil {
+		open = func(cfg database.Config) (*database.DB, error) {
+			return database.CloneFrom(context.Background(), *opts.From, cfg) // creates the database
+		}
+	}
+	db, err := open(cfg)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testDriver is the driver test databases are opened with: pgx, or lib/pq
+// with DB_TEST_DRIVER=postgres
+func testDriver() string {
+	if d := os.Getenv("DB_TEST_DRIVER"); d != "" {
+		return d
+	}
+	return "pgx"
+}
+
+// snapshotName is a database name no other test binary on the server uses
+func snapshotName() (string, error) {
+	if _, err := postgresServer(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("snapshot_%d_%d", os.Getpid(), testDBs.Add(1)), nil
+}
@@ -0,0 +1,55 @@
+//go:build !postgres
+
+package dbtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"your-project/database"
+)
+
+// openTestDB opens a SQLite file in t.TempDir(), or :memory:, cloned
+// from opts.From if set
+func openTestDB(t testing.TB, opts Options) *database.DB {
+	t.Helper()
+	if opts.InMemory && opts.From != nil {
+		t.Fatalf("an in-memory database can't be cloned from a snapshot")
+	}
+
+	cfg := database.Config{LogLevel: "silent", JournalMode: "wal"}
+	if opts.InMemory {
+		// Open keeps :memory: on one connection, each one would be a
+		// database of its own
+		cfg.DSN, cfg.JournalMode = ":memory:", ""
+	} else {
+		cfg.DSN = filepath.Join(t.TempDir(), "test.db")
+	}
+	if opts.Config != nil {
+		opts.Config(&cfg)
+	}
+
+	open := database.Open
+	if opts.From != nil {
+		open = func(cfg database.Config) (*database.DB, error) {
+			return database.CloneFrom(context.Background(), *opts.From, cfg)
+		}
+	}
+	db, err := open(cfg)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// snapshotName is a file in a directory of its own under the OS's temp dir
+func snapshotName() (string, error) {
+	dir, err := os.MkdirTemp("", "dbtest-snapshot-")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snapshot.db"), nil
+}
@@ -0,0 +1,306 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"slices"
+	"time"
+)
+
+// dialect is one SQL flavor compiled into the binary: the drivers that
+// speak it and the SQL assets embedded for it. Each dialect_*.go file
+// registers itself, and build tags decide which of them are linked in.
+type dialect struct {
+	name    string   // Name used in messages ("SQLite", "PostgreSQL")
+	system  string   // OpenTelemetry's db.system ("sqlite", "postgresql")
+	drivers []string // database/sql driver names, the first one is the default
+	schema  string   // Embedded sql/<dialect>/schema.sql
+
+	migrations    fs.FS  // Embedded sql/<dialect>/migrations, see Migration
+	migrationsDir string // Their directory, relative to the package
+
+	isUniqueViolation     func(error) bool                         // Recognizes the driver's unique constraint error
+	isForeignKeyViolation func(error) bool                         // Recognizes the driver's foreign key error
+	isConnectionError     func(error) bool                         // Recognizes driver-specific connection failures
+	isStorageError        func(error) bool                         // Recognizes a full or failing disk, may be nil
+	isRetryable           func(error) bool                         // Recognizes lock contention a retried transaction may not hit again, may be nil
+	isStaleStatement      func(error) bool                         // Recognizes a prepared statement the schema changed under, may be nil
+	isReadOnlyError       func(error) bool                         // Recognizes a write refused by a read-only database or transaction, may be nil
+	checkViolation        func(error) (constraint string, ok bool) // Recognizes a CHECK failure and names the constraint
+	violatedConstraint    func(error) string                       // Names what a unique or foreign key violation broke, "" if the driver doesn't say
+	checkVersion          func(context.Context, *sql.DB) error     // Rejects servers/libraries too old for the queries, may be nil
+
+	// checkConfig rejects what Config.Validate can tell is wrong without
+	// connecting, such as a DSN the driver can't parse. May be nil.
+	checkConfig func(driver string, cfg Config) error
+
+	// dsnDefaults turns Config settings, and defaults for what the DSN
+	// leaves out, into driver DSN parameters and describes each one it
+	// added; checkDSN reads them back once connected. poolDefaults sizes
+	// the pool when Config doesn't. All may be nil.
+	dsnDefaults  func(driver string, cfg Config) (dsn string, applied []string, err error)
+	checkDSN     func(context.Context, *sql.DB, Config) error
+	poolDefaults func(context.Context, *sql.DB) (maxOpen int, why string, err error)
+
+	// Defaults for Config.ConnMaxLifetime and ConnMaxIdleTime; zero keeps
+	// connections for as long as the pool likes
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+
+	// open replaces sql.Open, for dialects that set connections up in
+	// ways the DSN can't express. May be nil.
+	open func(driver, dsn string, cfg Config) (*sql.DB, error)
+
+	// migrate runs after the schema, for upkeep plain SQL can't decide on
+	// by itself. May be nil.
+	migrate func(context.Context, *sql.DB) error
+
+	// insertID backs InsertReturningID with the one strategy the dialect supports
+	insertID func(ctx context.Context, dbtx DBTX, query string, args ...any) (int64, error)
+
+	// explain backs DB.Explain, may be nil
+	explain func(ctx context.Context, dbtx DBTX, query string, args ...any) ([]PlanNode, error)
+
+	// approxCount backs DB.ApproxCount with a cheap row estimate for a
+	// table from the schema; ok is false when there is none. May be nil.
+	approxCount func(ctx context.Context, dbtx DBTX, table string) (n int64, ok bool, err error)
+
+	// listTables names the tables in the database, for Readiness. May be nil.
+	listTables func(ctx context.Context, dbtx DBTX) ([]string, error)
+
+	// journalMode and setJournalMode back DB.JournalMode and
+	// DB.SetJournalMode; nil where there are no journal modes
+	journalMode    func(ctx context.Context, conn *sql.DB) (string, error)
+	setJournalMode func(ctx context.Context, conn *sql.DB, dsn, mode string) error
+
+	// introspect backs DB.Introspect, may be nil
+	introspect func(ctx context.Context, dbtx DBTX) (SchemaInfo, error)
+
+	// introspectAttached backs DB.IntrospectAttached; nil where databases
+	// can't be attached
+	introspectAttached func(ctx context.Context, dbtx DBTX, schema string) (SchemaInfo, error)
+
+	// expectedSchema introspects the embedded schema created in scratch
+	// space, empty before and gone after, for DB.SchemaDrift; cfg has the
+	// driver and functions the database was opened with. May be nil.
+	expectedSchema func(ctx context.Context, conn *sql.DB, cfg Config) (SchemaInfo, error)
+
+	// foreignKeyCheck backs DB.ForeignKeyCheck; nil when the database
+	// can't hold orphans in the first place
+	foreignKeyCheck func(ctx context.Context, dbtx DBTX) ([]Orphan, error)
+
+	// foreignKeysOff turns foreign keys off on c for a migration, which
+	// can then rebuild a table others reference, as SQLite has to for most
+	// changes; on is nil if they were off already. applyMigration runs
+	// foreignKeyCheck before it commits. Nil where ALTER TABLE does it all.
+	foreignKeysOff func(ctx context.Context, c *sql.Conn) (on func(), err error)
+
+	// backup writes a consistent copy of the live database to path, and
+	// checkBackup verifies one; restore copies one over the live database.
+	// All nil when the dialect has no online backup from SQL.
+	backup      func(ctx context.Context, conn *sql.DB, path string) error
+	checkBackup func(ctx context.Context, conn *sql.DB, path string) error
+	restore     func(ctx context.Context, conn *sql.DB, path string) error
+
+	// snapshot copies the live database, which dsn opened, into the
+	// snapshot name, calling closeIdle first where connections to it
+	// would be in the way; clone creates the database cfg points at from
+	// one, and dropSnapshot deletes one. All nil where databases can't be
+	// copied.
+	snapshot     func(ctx context.Context, conn *sql.DB, dsn, name string, closeIdle func()) error
+	clone        func(ctx context.Context, name string, cfg Config) error
+	dropSnapshot func(ctx context.Context, dsn, name string) error
+
+	// rekey re-encrypts a database opened with Config.EncryptionKey; nil
+	// when the dialect can't open one
+	rekey func(ctx context.Context, conn *sql.DB, newKey string) error
+
+	// checkpoint moves what the write-ahead log holds into the database
+	// file and empties the log, for Shutdown; nil where there's nothing to
+	// do
+	checkpoint func(ctx context.Context, conn *sql.DB) error
+
+	// walCheckpoint backs DB.Checkpoint and Maintain's checkpoint in one
+	// of the checkpoint modes. analyze and incrementalVacuum back the rest
+	// of DB.Maintain, each returning a note on what it did for the log.
+	// All nil where the server maintains itself (autovacuum).
+	walCheckpoint     func(ctx context.Context, conn *sql.DB, mode string) (CheckpointResult, error)
+	analyze           func(ctx context.Context, conn *sql.DB) (string, error)
+	incrementalVacuum func(ctx context.Context, conn *sql.DB, pages int) (string, error)
+
+	// databaseSize backs DB.DatabaseSize; nil where the database isn't a file
+	databaseSize func(ctx context.Context, conn *sql.DB) (DatabaseSize, error)
+
+	// vacuum is the statement behind DB.Vacuum
+	vacuum string
+
+	// createArchive makes a RetentionRule's archive table (the first %s)
+	// with the columns of its table (the second). attachArchive attaches
+	// RetentionPolicy.ArchiveDB, the file as its parameter, as "archive";
+	// empty where archives live in schemas instead.
+	createArchive string
+	attachArchive string
+
+	// uuidColumn and ulidColumn are the column types BackfillKeys adds
+	// for the new keys
+	uuidColumn string
+	ulidColumn string
+
+	// integrityCheck backs DB.IntegrityCheck, may be nil
+	integrityCheck func(ctx context.Context, dbtx DBTX) (problems []string, err error)
+
+	// lock takes the named lock behind WithLock, waiting for it unless try
+	// is set (ErrLockHeld then), and calls lost if it goes away before
+	// release. Nil where the database has no way to hold one.
+	lock func(ctx context.Context, conn *sql.DB, name string, try bool, lost func()) (release func() error, err error)
+
+	// beginStmt is the BEGIN for transactions begun by hand on a pool
+	// connection, which lets Tx.Pgx reach the driver's connection; ""
+	// leaves them to database/sql. May be nil.
+	beginStmt func(conn *sql.DB, opts *sql.TxOptions) (string, error)
+
+	// beginImmediate starts a transaction that takes the write lock up
+	// front, for WriteTransaction; empty when a plain BEGIN already
+	// handles concurrent writers
+	beginImmediate string
+
+	// readOnlyOn makes a connection refuse writes and readOnlyOff lets it
+	// write again, for RawQuery; both empty if there's no such setting
+	readOnlyOn, readOnlyOff string
+
+	// txIsolation maps the isolation level TransactionWithOptions asks for
+	// to the one to begin with, or fails if the database can't give it.
+	// Nil passes it to the driver as is. readOnlyTx makes read-only
+	// transactions with readOnlyOn, for drivers that ignore
+	// TxOptions.ReadOnly.
+	txIsolation func(sql.IsolationLevel) (sql.IsolationLevel, error)
+	readOnlyTx  bool
+
+	// numberedParams is set when placeholders are $1, $2, ... rather
+	// than ?, for SQL built at run time such as BulkInsert's
+	numberedParams bool
+
+	// upsert is the clause after an INSERT that updates the row it
+	// conflicts with on the conflict columns instead, setting the set
+	// columns to the inserted values, for SQL built at run time such as
+	// Tx.InsertRows'. Nil is ON CONFLICT ... DO UPDATE SET c = excluded.c,
+	// which SQLite and PostgreSQL share.
+	upsert func(conflict, set []string) string
+
+	// syncSequences moves the sequences behind a table's serial columns
+	// past the values in them, for Tx.SyncSequences; nil where ids that
+	// were inserted explicitly don't hold back the next one
+	syncSequences func(ctx context.Context, dbtx DBTX, table string) error
+
+	// timeOrder wraps a DATETIME column or a time parameter (the %s) so
+	// that they compare in time order, for SQL built at run time; "%s"
+	// where they already do
+	timeOrder string
+
+	// searchQuery turns words into the query SearchUsers and SearchGroups
+	// match with, every word required and matched as a prefix. Nil when
+	// there's no full-text search.
+	searchQuery func(words []string) string
+
+	// searchAvailable returns ErrSearchUnavailable, wrapped, when the
+	// database can't search after all; nil when it always can
+	searchAvailable func(ctx context.Context, dbtx DBTX) error
+
+	// setAuditActor names the actor ($1 or ?) for the audit triggers
+	// until the transaction ends, and clearAuditActor undoes it before
+	// commit where it would outlast the transaction. Empty records no
+	// actors.
+	setAuditActor, clearAuditActor string
+
+	// sendBatch runs a Batch's queries in one round trip, scanning each
+	// result in order and stopping at the first error. Nil, or returning
+	// errBatchUnsupported, runs them one by one in a transaction instead.
+	sendBatch func(ctx context.Context, conn *sql.DB, queries []*queuedQuery) error
+
+	// copyRows inserts n rows into table in the transaction the fastest
+	// way the driver has, row(i) giving each one's values for columns, and
+	// returns how many it inserted. Nil, or returning errCopyUnsupported,
+	// leaves BulkInsert and InsertRows to multi-row INSERTs.
+	copyRows func(ctx context.Context, tx *Tx, table string, columns []string, n int, row func(int) ([]any, error)) (int64, error)
+
+	// listen backs DB.Listen with the database's own notifications; nil
+	// feeds it from the DB's writes instead
+	listen listenFunc
+}
+
+var dialects []*dialect
+
+func registerDialect(d *dialect) {
+	dialects = append(dialects, d)
+}
+
+// Every driver the package knows about, with the build that links it in
+var driverBuilds = map[string]string{
+	"sqlite3":  "the default build (no tags)",
+	"sqlite":   "the default build (no tags)",
+	"pgx":      "-tags postgres",
+	"postgres": "-tags postgres",
+}
+
+// Drivers no build supports, and why
+var unsupportedDrivers = map[string]string{
+	"mysql": "MySQL isn't supported; dialect_mysql.go.example lists what wiring it up takes",
+}
+
+// dialectFor picks the compiled-in dialect for cfg.Driver. An empty driver
+// selects the default dialect of this build.
+// A binary has one dialect, picked by build tag, and the driver picks
+// among its drivers; README.md ("One dialect per build") says why.
+func dialectFor(driver string) (*dialect, error) {
+	if driver == "" {
+		return defaultDialect(), nil
+	}
+
+	for _, d := range dialects {
+		if slices.Contains(d.drivers, driver) {
+			return d, nil
+		}
+	}
+
+	if why, ok := unsupportedDrivers[driver]; ok {
+		return nil, fmt.Errorf("driver %q: %s", driver, why)
+	}
+	if build, ok := driverBuilds[driver]; ok {
+		return nil, fmt.Errorf("driver %q is not compiled into this binary (it supports %v); rebuild with %s",
+			driver, supportedDrivers(), build)
+	}
+	return nil, fmt.Errorf("unknown driver %q (this binary supports %v)", driver, supportedDrivers())
+}
+
+// queryStrings runs a query returning one text column
+func queryStrings(ctx context.Context, dbtx DBTX, query string, args ...any) ([]string, error) {
+	rows, err := dbtx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func defaultDialect() *dialect {
+	return dialects[0]
+}
+
+func supportedDrivers() []string {
+	var names []string
+	for _, d := range dialects {
+		names = append(names, d.drivers...)
+	}
+	return names
+}
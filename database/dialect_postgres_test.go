@@ -0,0 +1,67 @@
+//go:build postgres
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+// recordingConnector is a driver that keeps the last query it was sent
+// and answers it with a single id. It has no Exec, so LastInsertId can't
+// be what InsertReturningID relies on.
+type recordingConnector struct {
+	query string
+}
+
+func (c *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return recordingConn{c}, nil
+}
+func (c *recordingConnector) Driver() driver.Driver { return nil }
+
+type recordingConn struct {
+	c *recordingConnector
+}
+
+func (c recordingConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.c.query = query
+	return &idRows{}, nil
+}
+
+func (c recordingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c recordingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (c recordingConn) Close() error                        { return nil }
+
+type idRows struct {
+	read bool
+}
+
+func (r *idRows) Columns() []string { return []string{"id"} }
+func (r *idRows) Close() error      { return nil }
+
+func (r *idRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = int64(42)
+	return nil
+}
+
+func TestInsertReturningIDPostgres(t *testing.T) {
+	rc := &recordingConnector{}
+	conn := sql.OpenDB(rc)
+	defer conn.Close()
+
+	id, err := InsertReturningID(context.Background(), conn, " INSERT INTO groups (telegram_id) VALUES ($1); ", 10)
+	if err != nil || id != 42 {
+		t.Fatalf("InsertReturningID: %d, %v; want the id the driver returned", id, err)
+	}
+	if want := "INSERT INTO groups (telegram_id) VALUES ($1) RETURNING id"; rc.query != want {
+		t.Errorf("sent %q, want %q", rc.query, want)
+	}
+}
@@ -0,0 +1,882 @@
+//go:build !postgres
+
+// ============================================================================
+// SQLite dialect (default build)
+// ============================================================================
+//
+// DRIVER: github.com/mattn/go-sqlite3 (requires CGO)
+//   For CGO-free alternative: modernc.org/sqlite
+//
+// DSN FORMAT:
+//   file.db              - Regular file
+//   :memory:             - In-memory database
+//   file.db?mode=ro      - Read-only
+//   file.db?_journal=WAL - Write-Ahead Logging (better concurrency)
+//
+// SQL ASSETS: sql/sqlite/schema.sql, sql/sqlite/queries.sql
+// ============================================================================
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3" // SQLite driver (CGO required)
+	// Alternative CGO-free driver:
+	// _ "modernc.org/sqlite"
+)
+
+//go:embed sql/sqlite/schema.sql
+var sqliteSchema string
+
+//go:embed sql/sqlite/search.sql
+var sqliteSearchSchema string
+
+//go:embed sql/sqlite/migrations
+var sqliteMigrations embed.FS
+
+func init() {
+	registerDialect(&dialect{
+		name:    "SQLite",
+		system:  "sqlite",
+		drivers: []string{"sqlite3", "sqlite"},
+		schema:  sqliteSchema,
+
+		migrations:    migrationsFS(sqliteMigrations, "sql/sqlite/migrations"),
+		migrationsDir: "sql/sqlite/migrations",
+
+		isUniqueViolation:     sqliteUniqueViolation,
+		isForeignKeyViolation: sqliteForeignKeyViolation,
+		checkViolation:        sqliteCheckViolation,
+		violatedConstraint:    sqliteViolatedConstraint,
+		isConnectionError:     sqliteConnectionError,
+		isStorageError:        sqliteStorageError,
+		isReadOnlyError:       sqliteReadOnlyError,
+		isRetryable:           sqliteBusy,
+		checkVersion:          sqliteCheckVersion,
+		checkConfig:           sqliteCheckConfig,
+		dsnDefaults:           sqliteDSNDefaults,
+		checkDSN:              sqliteCheckDSN,
+		poolDefaults:          sqlitePoolDefaults,
+		open:                  sqliteOpen,
+		migrate:               sqliteMigrate,
+		insertID:              lastInsertID,
+		explain:               sqliteExplain,
+		approxCount:           sqliteApproxCount,
+		listTables:            sqliteListTables,
+		foreignKeyCheck:       sqliteForeignKeyCheck,
+		foreignKeysOff:        sqliteForeignKeysOff,
+		backup:                sqliteBackup,
+		checkBackup:           sqliteCheckBackup,
+		restore:               sqliteRestore,
+		snapshot:              sqliteSnapshot,
+		clone:                 sqliteClone,
+		dropSnapshot:          sqliteDropSnapshot,
+		rekey:                 sqliteRekey,
+		integrityCheck:        sqliteIntegrityCheck,
+		beginImmediate:        "BEGIN IMMEDIATE",
+		readOnlyOn:            "PRAGMA query_only = ON",
+		readOnlyOff:           "PRAGMA query_only = OFF",
+		txIsolation:           sqliteTxIsolation,
+		readOnlyTx:            true,
+		introspect:            sqliteIntrospect,
+		introspectAttached:    sqliteIntrospectSchema,
+		timeOrder:             "datetime(%s)",
+		expectedSchema:        sqliteExpectedSchema,
+		journalMode:           sqliteJournalMode,
+		setJournalMode:        sqliteSetJournalMode,
+		checkpoint:            sqliteCheckpoint,
+		walCheckpoint:         sqliteWALCheckpoint,
+		databaseSize:          sqliteDatabaseSize,
+		analyze:               sqliteAnalyze,
+		incrementalVacuum:     sqliteIncrementalVacuum,
+		searchQuery:           sqliteSearchQuery,
+		searchAvailable:       sqliteSearchAvailable,
+		vacuum:                "VACUUM",
+		createArchive:         "CREATE TABLE IF NOT EXISTS %s AS SELECT * FROM %s WHERE 0",
+		attachArchive:         "ATTACH DATABASE ? AS archive",
+		uuidColumn:            "TEXT",
+		ulidColumn:            "TEXT",
+		setAuditActor:         "INSERT OR REPLACE INTO audit_actor (id, actor) VALUES (1, ?)",
+		clearAuditActor:       "DELETE FROM audit_actor",
+		lock:                  sqliteLock,
+	})
+}
+
+// RETURNING, which every write query uses, needs SQLite 3.35.0 (generated
+// columns need 3.31.0). modernc.org/sqlite bundles a newer SQLite than both.
+const sqliteMinVersion = "3.35.0"
+
+func sqliteCheckVersion(ctx context.Context, conn *sql.DB) error {
+	var version string
+	if err := conn.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read SQLite version: %w", err)
+	}
+
+	var have, want [3]int
+	fmt.Sscanf(version, "%d.%d.%d", &have[0], &have[1], &have[2])
+	fmt.Sscanf(sqliteMinVersion, "%d.%d.%d", &want[0], &want[1], &want[2])
+	if slices.Compare(have[:], want[:]) < 0 {
+		return fmt.Errorf("SQLite %s is too old: the schema and queries need %s or newer (update the driver or the system libsqlite3)",
+			version, sqliteMinVersion)
+	}
+	return nil
+}
+
+// sqliteMigrate is the upkeep after schema.sql: the search index, then
+// the collations
+func sqliteMigrate(ctx context.Context, conn *sql.DB) error {
+	if err := sqliteCreateSearch(ctx, conn); err != nil {
+		return err
+	}
+	return sqliteReindexCollations(ctx, conn)
+}
+
+// sqliteHasFTS5 reports whether the SQLite library was built with FTS5
+func sqliteHasFTS5(ctx context.Context, dbtx DBTX) (bool, error) {
+	var fts5 bool
+	if err := dbtx.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		return false, fmt.Errorf("failed to read SQLite compile options: %w", err)
+	}
+	return fts5, nil
+}
+
+// sqliteCreateSearch runs search.sql when the library has FTS5, filling
+// the tables from the rows already there when it creates them. Without
+// FTS5 the database works but for Search, unless it has the search
+// tables already: their triggers would fail every write to users and
+// groups.
+func sqliteCreateSearch(ctx context.Context, conn *sql.DB) error {
+	fts5, err := sqliteHasFTS5(ctx, conn)
+	if err != nil {
+		return err
+	}
+	var tables int
+	if err := conn.QueryRowContext(ctx,
+		"SELECT count(*) FROM sqlite_master WHERE name IN ('users_fts', 'groups_fts')").Scan(&tables); err != nil {
+		return err
+	}
+	if !fts5 {
+		if tables > 0 {
+			return errors.New("the database has FTS5 search tables, but this SQLite was built without FTS5 (with mattn/go-sqlite3, build with -tags sqlite_fts5)")
+		}
+		return nil
+	}
+
+	if _, err := conn.ExecContext(ctx, sqliteSearchSchema); err != nil {
+		return fmt.Errorf("failed to create the search index: %w", err)
+	}
+	if tables < 2 {
+		_, err := conn.ExecContext(ctx, `INSERT INTO users_fts (users_fts) VALUES ('rebuild');
+			INSERT INTO groups_fts (groups_fts) VALUES ('rebuild')`)
+		if err != nil {
+			return fmt.Errorf("failed to fill the search index: %w", err)
+		}
+	}
+	return nil
+}
+
+func sqliteSearchAvailable(ctx context.Context, dbtx DBTX) error {
+	fts5, err := sqliteHasFTS5(ctx, dbtx)
+	if err != nil {
+		return err
+	}
+	if !fts5 {
+		return fmt.Errorf("%w: SQLite was built without FTS5 (with mattn/go-sqlite3, build with -tags sqlite_fts5)", ErrSearchUnavailable)
+	}
+	return nil
+}
+
+// sqliteSearchQuery quotes each word, so FTS5 doesn't read AND, OR, NOT
+// or NEAR as operators
+func sqliteSearchQuery(words []string) string {
+	terms := make([]string, len(words))
+	for i, w := range words {
+		terms[i] = `"` + w + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// How long a connection waits for another one's lock before failing with
+// "database is locked", unless Config.BusyTimeout says otherwise
+const defaultBusyTimeout = 5 * time.Second
+
+// sqliteBusyTimeout is the busy timeout Open puts into the DSN; ok is false
+// when it leaves the DSN alone
+func sqliteBusyTimeout(cfg Config) (timeout time.Duration, ok bool, err error) {
+	lower := strings.ToLower(cfg.DSN)
+	if strings.Contains(lower, "busy_timeout") || strings.Contains(lower, "_timeout=") {
+		if cfg.BusyTimeout > 0 {
+			return 0, false, fmt.Errorf("the busy timeout is set in both Config.BusyTimeout and the DSN, keep one")
+		}
+		return 0, false, nil
+	}
+
+	switch {
+	case cfg.BusyTimeout > 0:
+		return cfg.BusyTimeout, true, nil
+	case cfg.BusyTimeout < 0:
+		return 0, false, nil
+	}
+	return defaultBusyTimeout, true, nil
+}
+
+// sqliteForeignKeys reports whether Open adds foreign_keys to the DSN, and
+// with which value; SQLite leaves foreign keys off on every new connection
+// otherwise. A DSN that sets them itself (even to off) is left alone.
+func sqliteForeignKeys(cfg Config) (on, ok bool, err error) {
+	lower := strings.ToLower(cfg.DSN)
+	if strings.Contains(lower, "foreign_keys") || strings.Contains(lower, "_fk=") {
+		if cfg.NoForeignKeys {
+			return false, false, fmt.Errorf("foreign keys are set in both Config.NoForeignKeys and the DSN, keep one")
+		}
+		return false, false, nil
+	}
+	return !cfg.NoForeignKeys, true, nil
+}
+
+// sqlitePragma is a Config setting Open puts into the DSN
+type sqlitePragma struct {
+	name  string // journal_mode, synchronous or cache_size
+	value string // As the DSN takes it
+	want  string // As PRAGMA <name> reads it back
+}
+
+// Shorter DSN parameters mattn/go-sqlite3 takes for the same pragmas
+var sqlitePragmaAliases = map[string]string{"journal_mode": "_journal=", "synchronous": "_sync="}
+
+// sqlitePragmas checks Config's journal mode, synchronous and cache size
+// settings, and returns those that are set
+func sqlitePragmas(cfg Config) ([]sqlitePragma, error) {
+	var pragmas []sqlitePragma
+	if mode := strings.ToLower(cfg.JournalMode); mode != "" {
+		switch mode {
+		case "wal", "delete", "truncate", "persist", "memory", "off":
+		default:
+			return nil, fmt.Errorf("unknown journal mode %q", cfg.JournalMode)
+		}
+		pragmas = append(pragmas, sqlitePragma{"journal_mode", mode, mode})
+	}
+	if sync := strings.ToLower(cfg.Synchronous); sync != "" {
+		level := slices.Index([]string{"off", "normal", "full", "extra"}, sync)
+		if level < 0 {
+			return nil, fmt.Errorf("unknown synchronous setting %q", cfg.Synchronous)
+		}
+		pragmas = append(pragmas, sqlitePragma{"synchronous", sync, strconv.Itoa(level)})
+	}
+	if cfg.CacheSize != 0 {
+		size := strconv.Itoa(cfg.CacheSize)
+		pragmas = append(pragmas, sqlitePragma{"cache_size", size, size})
+	}
+
+	lower := strings.ToLower(cfg.DSN)
+	for _, p := range pragmas {
+		alias, ok := sqlitePragmaAliases[p.name]
+		if strings.Contains(lower, p.name) || ok && strings.Contains(lower, alias) {
+			return nil, fmt.Errorf("%s is set in both Config and the DSN, keep one", p.name)
+		}
+	}
+	return pragmas, nil
+}
+
+// sqliteReplicationDefaults fills in the journal mode and synchronous
+// setting Config.Replication wants, where neither Config nor the DSN has
+// one
+func sqliteReplicationDefaults(cfg Config) Config {
+	if !cfg.Replication.Enabled || cfg.ReadOnly {
+		return cfg
+	}
+	lower := strings.ToLower(cfg.DSN)
+	for _, p := range []struct {
+		field       *string
+		name, value string
+	}{
+		{&cfg.JournalMode, "journal_mode", "wal"},
+		{&cfg.Synchronous, "synchronous", "normal"},
+	} {
+		if *p.field == "" && !strings.Contains(lower, p.name) && !strings.Contains(lower, sqlitePragmaAliases[p.name]) {
+			*p.field = p.value
+		}
+	}
+	return cfg
+}
+
+// sqliteCheckConfig catches what sqliteDSNDefaults would refuse, and DSN
+// parameters that don't parse, which the drivers would mostly ignore
+func sqliteCheckConfig(driver string, cfg Config) error {
+	cfg = sqliteReplicationDefaults(cfg)
+	if _, query, ok := strings.Cut(cfg.DSN, "?"); ok {
+		params, err := url.ParseQuery(query)
+		if err != nil {
+			return fmt.Errorf("malformed DSN parameters: %w", err)
+		}
+		if mode := params.Get("mode"); mode != "" && !slices.Contains([]string{"ro", "rw", "rwc", "memory"}, mode) {
+			return fmt.Errorf("unknown mode=%s in the DSN, use ro, rw, rwc or memory", mode)
+		}
+		if mode := params.Get("mode"); cfg.ReadOnly && mode != "" && mode != "ro" {
+			return fmt.Errorf("read_only and mode=%s in the DSN contradict each other", mode)
+		}
+		if cfg.EncryptionKey != "" {
+			// The driver applies these before the ConnectHook gives the key,
+			// and they read the file
+			for _, name := range []string{"_journal_mode", "_journal", "_auto_vacuum", "_vacuum"} {
+				if params.Has(name) {
+					return fmt.Errorf("%s can't be in the DSN with an encryption key, it's applied before the key (use Config.JournalMode for the journal mode)", name)
+				}
+			}
+		}
+	}
+	if cfg.ReadOnly {
+		if path, _, _ := strings.Cut(strings.TrimPrefix(cfg.DSN, "file:"), "?"); path == ":memory:" {
+			return errors.New("read_only needs a database file, not :memory:")
+		}
+		if cfg.JournalMode != "" {
+			return errors.New("journal_mode can't be set on a read_only database, it's the file's")
+		}
+	}
+	if cfg.Replication.Enabled {
+		if path, _, _ := strings.Cut(strings.TrimPrefix(cfg.DSN, "file:"), "?"); path == ":memory:" {
+			return errors.New("replication needs a database file, not :memory:")
+		}
+		if mode := strings.ToLower(cfg.JournalMode); mode != "" && mode != "wal" {
+			return fmt.Errorf("replication needs journal_mode wal, not %s", mode)
+		}
+		if strings.EqualFold(cfg.Synchronous, "off") {
+			return errors.New("replication needs synchronous normal or above, off can lose commits to a power cut")
+		}
+	}
+	if cfg.EncryptionKey != "" && driver != "sqlite3" {
+		return fmt.Errorf("EncryptionKey is unsupported by the %q driver, use mattn/go-sqlite3 (\"sqlite3\") built with SQLCipher", driver)
+	}
+	if _, _, err := sqliteBusyTimeout(cfg); err != nil {
+		return err
+	}
+	if _, _, err := sqliteForeignKeys(cfg); err != nil {
+		return err
+	}
+	_, err := sqlitePragmas(cfg)
+	return err
+}
+
+// Every connection applies the DSN parameters when it opens, which is the
+// only way to reach all of them; the parameter differs per driver
+func sqliteDSNDefaults(driver string, cfg Config) (string, []string, error) {
+	var params, applied []string
+	r := sqliteReplicationDefaults(cfg)
+	if r.JournalMode != cfg.JournalMode {
+		applied = append(applied, "journal_mode=wal (replication)")
+	}
+	if r.Synchronous != cfg.Synchronous {
+		applied = append(applied, "synchronous=normal (replication)")
+	}
+	cfg = r
+	if cfg.DSN == "" { // An explicit path, relative or not, is used as is
+		path, err := DefaultDBPath(cfg.AppName)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to find the default database path: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return "", nil, fmt.Errorf("failed to create the database directory: %w", err)
+		}
+		cfg.DSN = path
+		applied = append(applied, "DSN="+path)
+	}
+
+	timeout, ok, err := sqliteBusyTimeout(cfg)
+	if err != nil {
+		return cfg.DSN, nil, err
+	}
+
+	if ok {
+		ms := timeout.Milliseconds()
+		param := fmt.Sprintf("_busy_timeout=%d", ms)
+		if driver == "sqlite" { // modernc.org/sqlite only takes pragmas
+			param = fmt.Sprintf("_pragma=busy_timeout(%d)", ms)
+		}
+		params = append(params, param)
+		if cfg.BusyTimeout == 0 {
+			applied = append(applied, fmt.Sprintf("busy_timeout=%dms", ms))
+		}
+	}
+	on, ok, err := sqliteForeignKeys(cfg)
+	if err != nil {
+		return cfg.DSN, nil, err
+	}
+	if ok {
+		fk := 0
+		if on {
+			fk = 1
+		}
+		param := fmt.Sprintf("_foreign_keys=%d", fk)
+		if driver == "sqlite" {
+			param = fmt.Sprintf("_pragma=foreign_keys(%d)", fk)
+		}
+		params = append(params, param)
+		if on {
+			applied = append(applied, "foreign_keys=on")
+		}
+	}
+
+	pragmas, err := sqlitePragmas(cfg)
+	if err != nil {
+		return cfg.DSN, nil, err
+	}
+	for _, p := range pragmas {
+		if p.name == "journal_mode" && cfg.EncryptionKey != "" {
+			continue // Switching it reads the file, so it waits for the key in the ConnectHook
+		}
+		param := fmt.Sprintf("_%s=%s", p.name, p.value)
+		if driver == "sqlite" {
+			param = fmt.Sprintf("_pragma=%s(%s)", p.name, p.value)
+		}
+		params = append(params, param)
+	}
+	if cfg.Replication.ExternalCheckpoints && driver == "sqlite" {
+		params = append(params, "_pragma=wal_autocheckpoint(0)") // mattn/go-sqlite3's is set in sqliteOpen's ConnectHook
+	}
+	dsn := cfg.DSN
+	if cfg.ReadOnly && !strings.Contains(strings.ToLower(dsn), "mode=ro") {
+		// Both drivers only pass mode on to SQLite in a file: URI
+		if !strings.HasPrefix(dsn, "file:") {
+			path, query, ok := strings.Cut(dsn, "?")
+			dsn = "file:" + sqlitePathEscaper.Replace(path)
+			if ok {
+				dsn += "?" + query
+			}
+		}
+		params = append(params, "mode=ro")
+	}
+	if len(params) == 0 {
+		return dsn, applied, nil
+	}
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + strings.Join(params, "&"), applied, nil
+}
+
+// sqliteCheckDSN reads the parameters back, since one the driver doesn't
+// know is silently ignored and leaves the setting at SQLite's default
+func sqliteCheckDSN(ctx context.Context, conn *sql.DB, cfg Config) error {
+	cfg = sqliteReplicationDefaults(cfg)
+	if want, ok, _ := sqliteBusyTimeout(cfg); ok {
+		var ms int64
+		if err := conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&ms); err != nil {
+			return fmt.Errorf("failed to read busy_timeout: %w", err)
+		}
+		if ms != want.Milliseconds() {
+			return fmt.Errorf("busy_timeout is %dms instead of %dms: the driver ignored the DSN parameter", ms, want.Milliseconds())
+		}
+	}
+
+	if want, ok, _ := sqliteForeignKeys(cfg); ok {
+		var on bool
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&on); err != nil {
+			return fmt.Errorf("failed to read foreign_keys: %w", err)
+		}
+		if on != want {
+			state := "off"
+			if on {
+				state = "on"
+			}
+			return fmt.Errorf("foreign_keys is %s: the driver ignored the DSN parameter", state)
+		}
+	}
+
+	// The DSN was checked before it was opened
+	pragmas, _ := sqlitePragmas(cfg)
+	for _, p := range pragmas {
+		var got string
+		if err := conn.QueryRowContext(ctx, "PRAGMA "+p.name).Scan(&got); err != nil {
+			return fmt.Errorf("failed to read %s: %w", p.name, err)
+		}
+		// SQLite keeps the old journal mode when it can't switch, e.g. an
+		// in-memory database asked for WAL
+		if got = strings.ToLower(got); got != p.want {
+			return fmt.Errorf("%s is %s instead of %s: the driver ignored the DSN parameter or SQLite refused the value", p.name, got, p.value)
+		}
+	}
+	if cfg.Replication.Enabled {
+		return sqliteCheckReplication(ctx, conn, cfg.Replication)
+	}
+	return nil
+}
+
+// sqliteCheckReplication reads back what Config.Replication needs, which a
+// DSN parameter may have set otherwise
+func sqliteCheckReplication(ctx context.Context, conn *sql.DB, r ReplicationConfig) error {
+	var mode string
+	var sync, autocheckpoint int
+	if err := conn.QueryRowContext(ctx, "SELECT j.journal_mode, s.synchronous FROM pragma_journal_mode() j, pragma_synchronous() s").Scan(&mode, &sync); err != nil {
+		return fmt.Errorf("failed to read the replication settings: %w", err)
+	}
+	if err := conn.QueryRowContext(ctx, "PRAGMA wal_autocheckpoint").Scan(&autocheckpoint); err != nil {
+		return fmt.Errorf("failed to read wal_autocheckpoint: %w", err)
+	}
+	switch {
+	case !strings.EqualFold(mode, "wal"):
+		return fmt.Errorf("replication needs journal_mode wal, the database is in %s", strings.ToLower(mode))
+	case sync == 0:
+		return errors.New("replication needs synchronous normal or above, the DSN turns it off")
+	case r.ExternalCheckpoints && autocheckpoint != 0:
+		return fmt.Errorf("wal_autocheckpoint is %d instead of 0, so SQLite still checkpoints by itself", autocheckpoint)
+	}
+	return nil
+}
+
+// Outside WAL mode a writer locks out every other connection, so extra
+// connections only turn waiting into "database is locked" errors (and an
+// in-memory database exists once per connection). WAL lets readers run
+// next to the writer.
+func sqlitePoolDefaults(ctx context.Context, conn *sql.DB) (int, string, error) {
+	mode, err := sqliteJournalMode(ctx, conn)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if mode == "wal" {
+		return runtime.NumCPU(), "journal_mode=wal", nil
+	}
+	return 1, "journal_mode=" + mode, nil
+}
+
+// SQLite transactions are serializable whatever the level, so a plain
+// BEGIN gives every level up to serializable
+func sqliteTxIsolation(level sql.IsolationLevel) (sql.IsolationLevel, error) {
+	if level > sql.LevelSerializable {
+		return 0, fmt.Errorf("SQLite doesn't support isolation level %v", level)
+	}
+	return sql.LevelDefault, nil
+}
+
+// EXPLAIN QUERY PLAN returns one row per step: id, parent, notused, detail.
+// Details look like "SCAN users", "SEARCH users USING INDEX idx (a=?)" or
+// "SEARCH users USING INTEGER PRIMARY KEY (rowid=?)"; SQLite before 3.36
+// wrote "SCAN TABLE users". Scans of a CTE or subquery count as full scans
+// too, since the detail doesn't tell them apart from tables.
+func sqliteExplain(ctx context.Context, dbtx DBTX, query string, args ...any) ([]PlanNode, error) {
+	rows, err := dbtx.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []PlanNode
+	for rows.Next() {
+		var n PlanNode
+		var notused int
+		if err := rows.Scan(&n.ID, &n.Parent, &notused, &n.Detail); err != nil {
+			return nil, err
+		}
+
+		words := strings.Fields(n.Detail)
+		if len(words) >= 2 && (words[0] == "SCAN" || words[0] == "SEARCH") {
+			words = words[1:]
+			if words[0] == "TABLE" && len(words) > 1 {
+				words = words[1:]
+			}
+			n.Table = words[0]
+			if words[0] == "CONSTANT" { // SELECT without FROM
+				n.Table = ""
+			}
+		}
+		// "USING [COVERING] INDEX name"; AUTOMATIC indexes have no name
+		for i := 1; i+1 < len(words); i++ {
+			if words[i] == "INDEX" && !strings.HasPrefix(words[i+1], "(") {
+				n.Index = words[i+1]
+			}
+		}
+		n.FullScan = n.Table != "" && strings.HasPrefix(n.Detail, "SCAN ") && !strings.Contains(n.Detail, " USING ")
+
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+// sqlite_stat1 exists once ANALYZE has run; the first number of each stat
+// is the table's row count at that time. Without it, the rowid range is an
+// upper bound that deletes make too high. (The dbstat module would be more
+// exact, but mattn/go-sqlite3 only has it with the sqlite_dbstat tag.)
+func sqliteApproxCount(ctx context.Context, dbtx DBTX, table string) (int64, bool, error) {
+	var hasStats bool
+	err := dbtx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_stat1')").Scan(&hasStats)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if hasStats {
+		var stat string
+		err := dbtx.QueryRowContext(ctx, "SELECT stat FROM sqlite_stat1 WHERE tbl = ? LIMIT 1", table).Scan(&stat)
+		if err == nil {
+			var n int64
+			if _, err := fmt.Sscanf(stat, "%d", &n); err == nil {
+				return n, true, nil
+			}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return 0, false, err
+		}
+	}
+
+	// The name was checked against the schema by ApproxCount
+	var n sql.NullInt64
+	if err := dbtx.QueryRowContext(ctx, `SELECT max(rowid) - min(rowid) + 1 FROM "`+table+`"`).Scan(&n); err != nil {
+		return 0, false, err
+	}
+	return n.Int64, true, nil
+}
+
+// VACUUM INTO copies the database from inside one read transaction, so
+// the copy is consistent and writers carry on meanwhile
+func sqliteBackup(ctx context.Context, conn *sql.DB, path string) error {
+	_, err := conn.ExecContext(ctx, "VACUUM INTO ?", path)
+	return err
+}
+
+// The copy is attached to a single pooled connection for PRAGMA
+// quick_check, which reports "ok" or one row per problem
+func sqliteCheckBackup(ctx context.Context, conn *sql.DB, path string) error {
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if _, err := c.ExecContext(ctx, "ATTACH DATABASE ? AS backup_check", path); err != nil {
+		return fmt.Errorf("failed to open backup for checking: %w", err)
+	}
+	defer c.ExecContext(context.Background(), "DETACH DATABASE backup_check")
+
+	problems, err := queryStrings(ctx, c, "PRAGMA backup_check.quick_check")
+	if err != nil {
+		return fmt.Errorf("failed to check backup: %w", err)
+	}
+	if len(problems) != 1 || problems[0] != "ok" {
+		return fmt.Errorf("backup failed the integrity check: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// sqliteRestore copies the database at path over the live one in a single
+// backup step, which holds the write lock throughout, so nobody sees half
+// of it. A busy database is waited for like in sqliteSetJournalMode.
+func sqliteRestore(ctx context.Context, conn *sql.DB, path string) error {
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	sc, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+	dc, err := conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dc.Close()
+
+	return sc.Raw(func(s any) error {
+		return dc.Raw(func(d any) error {
+			from, ok1 := sqliteRawConn(s)
+			to, ok2 := sqliteRawConn(d)
+			if !ok1 || !ok2 {
+				return errors.New("Restore needs the mattn/go-sqlite3 driver")
+			}
+
+			for wait := 10 * time.Millisecond; ; wait = min(2*wait, time.Second) {
+				b, err := to.Backup("main", from, "main")
+				if err != nil {
+					return err
+				}
+				_, err = b.Step(-1)
+				if ferr := b.Finish(); err == nil {
+					err = ferr
+				}
+				if err == nil || !sqliteBusy(err) {
+					return err
+				}
+
+				select {
+				case <-ctx.Done():
+					return fmt.Errorf("the database stayed busy: %w", ctx.Err())
+				case <-time.After(wait):
+				}
+			}
+		})
+	})
+}
+
+// PRAGMA integrity_check reads every page and index, and reports "ok" or
+// one row per problem
+func sqliteIntegrityCheck(ctx context.Context, dbtx DBTX) ([]string, error) {
+	problems, err := queryStrings(ctx, dbtx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) == 1 && problems[0] == "ok" {
+		return nil, nil
+	}
+	return problems, nil
+}
+
+func sqliteListTables(ctx context.Context, dbtx DBTX) ([]string, error) {
+	return queryStrings(ctx, dbtx, "SELECT name FROM sqlite_master WHERE type = 'table'")
+}
+
+// PRAGMA foreign_key_check lists every row whose parent is missing, with
+// the index of the broken key in PRAGMA foreign_key_list of its table. The
+// rows are read completely before the key lists, which matters on a pool
+// of one connection.
+func sqliteForeignKeyCheck(ctx context.Context, dbtx DBTX) ([]Orphan, error) {
+	rows, err := dbtx.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type key struct {
+		table string
+		fkid  int
+	}
+	var orphans []Orphan
+	var keys []key
+	for rows.Next() {
+		var o Orphan
+		var rowid sql.NullInt64
+		var k key
+		if err := rows.Scan(&o.Table, &rowid, &o.Parent, &k.fkid); err != nil {
+			return nil, err
+		}
+		o.RowID = rowid.Int64 // NULL for WITHOUT ROWID tables, which the schema has none of
+		k.table = o.Table
+		orphans = append(orphans, o)
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	columns := map[key][]string{}
+	for i, k := range keys {
+		if _, ok := columns[k]; !ok {
+			cols, err := queryStrings(ctx, dbtx,
+				"SELECT \"from\" FROM pragma_foreign_key_list(?) WHERE id = ? ORDER BY seq", k.table, k.fkid)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read foreign keys of %s: %w", k.table, err)
+			}
+			columns[k] = cols
+		}
+		orphans[i].Columns = columns[k]
+	}
+	return orphans, nil
+}
+
+// sqliteForeignKeysOff is the first step of SQLite's procedure for
+// changing a table it can't ALTER that way: PRAGMA foreign_keys only
+// changes outside a transaction. A connection that can't turn them back
+// on is closed rather than returned to the pool.
+func sqliteForeignKeysOff(ctx context.Context, c *sql.Conn) (func(), error) {
+	var enabled bool
+	if err := c.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enabled); err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, nil
+	}
+	if _, err := c.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return nil, err
+	}
+	return func() {
+		if _, err := c.ExecContext(context.WithoutCancel(ctx), "PRAGMA foreign_keys = ON"); err != nil {
+			c.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}, nil
+}
+
+func sqliteUniqueViolation(err error) bool {
+	var se sqlite3.Error
+	if errors.As(err, &se) {
+		return se.ExtendedCode == sqlite3.ErrConstraintUnique || se.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	// modernc.org/sqlite has its own error type but the same message
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// An ON DELETE RESTRICT fails as SQLITE_CONSTRAINT_TRIGGER, since SQLite
+// runs the action as a trigger, with the same message as any other
+func sqliteForeignKeyViolation(err error) bool {
+	var se sqlite3.Error
+	if errors.As(err, &se) {
+		return se.ExtendedCode == sqlite3.ErrConstraintForeignKey ||
+			se.ExtendedCode == sqlite3.ErrConstraintTrigger && strings.Contains(se.Error(), "FOREIGN KEY constraint failed")
+	}
+	return strings.Contains(err.Error(), "FOREIGN KEY constraint failed")
+}
+
+// SQLite reports "UNIQUE constraint failed: users.email" with the columns,
+// or "...: index 'name'" for an index on expressions; a foreign key
+// failure names nothing
+func sqliteViolatedConstraint(err error) string {
+	_, what, ok := strings.Cut(err.Error(), "UNIQUE constraint failed: ")
+	if !ok {
+		return ""
+	}
+	what = strings.TrimSpace(what)
+	if index, ok := strings.CutPrefix(what, "index "); ok {
+		return strings.Trim(index, "'")
+	}
+	return what
+}
+
+// SQLite reports "CHECK constraint failed: <name>" for named constraints
+// (and the expression for unnamed ones), from mattn and modernc alike
+func sqliteCheckViolation(err error) (string, bool) {
+	_, name, ok := strings.Cut(err.Error(), "CHECK constraint failed: ")
+	return strings.TrimSpace(name), ok
+}
+
+// SQLite has no server; the closest thing to a lost connection is the file
+// becoming unreadable
+func sqliteConnectionError(err error) bool {
+	var se sqlite3.Error
+	if !errors.As(err, &se) {
+		return false
+	}
+	return se.Code == sqlite3.ErrCantOpen || se.Code == sqlite3.ErrNotADB
+}
+
+// SQLITE_READONLY: a file opened with mode=ro, or PRAGMA query_only
+func sqliteReadOnlyError(err error) bool {
+	var se sqlite3.Error
+	if errors.As(err, &se) {
+		return se.Code == sqlite3.ErrReadonly
+	}
+	return strings.Contains(err.Error(), "attempt to write a readonly database")
+}
+
+func sqliteStorageError(err error) bool {
+	var se sqlite3.Error
+	if errors.As(err, &se) {
+		return se.Code == sqlite3.ErrFull || se.Code == sqlite3.ErrIoErr
+	}
+	// modernc.org/sqlite reports the same message as SQLite itself
+	return strings.Contains(err.Error(), "database or disk is full")
+}
@@ -0,0 +1,110 @@
+//go:build !postgres
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// versionConnector is a driver that answers every query with one row
+// holding version, as SELECT sqlite_version() would; a one-value PRAGMA
+// reads it too
+type versionConnector struct {
+	version string
+}
+
+func (c versionConnector) Connect(context.Context) (driver.Conn, error) { return versionConn(c), nil }
+func (c versionConnector) Driver() driver.Driver                        { return nil }
+
+type versionConn versionConnector
+
+func (c versionConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &versionRows{version: c.version}, nil
+}
+
+func (c versionConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c versionConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (c versionConn) Close() error                        { return nil }
+
+type versionRows struct {
+	version string
+	read    bool
+}
+
+func (r *versionRows) Columns() []string { return []string{"sqlite_version()"} }
+func (r *versionRows) Close() error      { return nil }
+
+func (r *versionRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = r.version
+	return nil
+}
+
+func TestSQLiteCheckVersion(t *testing.T) {
+	for version, ok := range map[string]bool{
+		"3.34.1": false,
+		"3.9.0":  false, // Compared as numbers, not strings
+		"3.35.0": true,
+		"3.46.1": true,
+		"4.0.0":  true,
+	} {
+		conn := sql.OpenDB(versionConnector{version})
+		err := sqliteCheckVersion(context.Background(), conn)
+		conn.Close()
+		if ok && err != nil {
+			t.Errorf("SQLite %s: %v", version, err)
+		}
+		if !ok && (err == nil || !strings.Contains(err.Error(), sqliteMinVersion)) {
+			t.Errorf("SQLite %s: %v, want it refused naming %s", version, err, sqliteMinVersion)
+		}
+	}
+}
+
+func TestSQLiteBusyTimeoutDSN(t *testing.T) {
+	for _, c := range []struct {
+		driver, dsn string
+		timeout     time.Duration
+		want        string // "" for no busy timeout parameter
+	}{
+		{"sqlite3", "app.db", 250 * time.Millisecond, "_busy_timeout=250"},
+		{"sqlite", "app.db", 250 * time.Millisecond, "_pragma=busy_timeout(250)"},
+		{"sqlite3", "app.db", 0, "_busy_timeout=5000"},
+		{"sqlite", "app.db", 0, "_pragma=busy_timeout(5000)"},
+		{"sqlite3", "app.db", -1, ""},
+		{"sqlite3", "app.db?_busy_timeout=10", 0, ""}, // The DSN's own, left alone
+	} {
+		dsn, _, err := sqliteDSNDefaults(c.driver, Config{DSN: c.dsn, BusyTimeout: c.timeout})
+		if err != nil {
+			t.Errorf("%s %q %v: %v", c.driver, c.dsn, c.timeout, err)
+			continue
+		}
+		added := strings.TrimPrefix(dsn, c.dsn)
+		if c.want == "" && strings.Contains(added, "busy_timeout") || c.want != "" && !strings.Contains(added, c.want) {
+			t.Errorf("%s %q %v: DSN %q, want it to add %q", c.driver, c.dsn, c.timeout, dsn, c.want)
+		}
+	}
+
+	if _, _, err := sqliteDSNDefaults("sqlite3", Config{DSN: "app.db?_busy_timeout=10", BusyTimeout: time.Second}); err == nil {
+		t.Error("BusyTimeout and a DSN parameter both: no error")
+	}
+}
+
+func TestSQLiteCheckBusyTimeout(t *testing.T) {
+	// The driver ignored the parameter, so the connection has its default
+	conn := sql.OpenDB(versionConnector{"0"})
+	defer conn.Close()
+	err := sqliteCheckDSN(context.Background(), conn, Config{BusyTimeout: 250 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "busy_timeout is 0ms instead of 250ms") {
+		t.Errorf("a busy timeout that didn't take: %v, want Open to fail", err)
+	}
+}
@@ -0,0 +1,26 @@
+package database_test
+
+import (
+	"strings"
+	"testing"
+
+	"your-project/database"
+)
+
+func TestOpenUnsupportedDriver(t *testing.T) {
+	for driver, want := range map[string]string{
+		"mysql":   "dialect_mysql.go.example",
+		"oracle":  "unknown driver",
+		"sqlite4": "unknown driver",
+	} {
+		db, err := database.Open(database.Config{Driver: driver, DSN: "test"})
+		if err == nil {
+			db.Close()
+			t.Errorf("driver %q opened", driver)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("driver %q: %v; want it to mention %q", driver, err, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// ErrSchemaDrift is returned by Open with Config.SchemaCheck "fail" when
+// the database's tables, columns or indexes aren't the embedded schema's
+var ErrSchemaDrift = errors.New("database schema doesn't match the embedded schema")
+
+// Tables the package's helpers create outside schema.sql, which a
+// database may have or not
+var driftIgnoredTables = map[string]bool{
+	"seed_history": true, // the seed package's
+}
+
+// SchemaDrift is how the live schema differs from the one the embedded
+// schema.sql and migrations create. Columns are "table.column", indexes
+// "table.index".
+type SchemaDrift struct {
+	MissingTables  []string `json:"missing_tables,omitempty"`
+	ExtraTables    []string `json:"extra_tables,omitempty"`
+	MissingColumns []string `json:"missing_columns,omitempty"`
+	ExtraColumns   []string `json:"extra_columns,omitempty"`
+	MissingIndexes []string `json:"missing_indexes,omitempty"`
+	ExtraIndexes   []string `json:"extra_indexes,omitempty"`
+}
+
+// None reports a schema that matches
+func (s SchemaDrift) None() bool {
+	return len(s.MissingTables)+len(s.ExtraTables)+len(s.MissingColumns)+
+		len(s.ExtraColumns)+len(s.MissingIndexes)+len(s.ExtraIndexes) == 0
+}
+
+func (s SchemaDrift) String() string {
+	var parts []string
+	for _, p := range []struct {
+		what  string
+		names []string
+	}{
+		{"missing tables", s.MissingTables},
+		{"extra tables", s.ExtraTables},
+		{"missing columns", s.MissingColumns},
+		{"extra columns", s.ExtraColumns},
+		{"missing indexes", s.MissingIndexes},
+		{"extra indexes", s.ExtraIndexes},
+	} {
+		if len(p.names) > 0 {
+			parts = append(parts, p.what+" "+strings.Join(p.names, ", "))
+		}
+	}
+	if len(parts) == 0 {
+		return "no drift"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// SchemaDrift compares the live database with the schema this build
+// expects: the embedded schema.sql and migrations, applied to an empty
+// scratch database (SQLite: one in memory; PostgreSQL: a schema of its own,
+// in a transaction that's rolled back). It compares names only, not column
+// types or index definitions. Open runs it as Config.SchemaCheck says.
+func (db *DB) SchemaDrift(ctx context.Context) (SchemaDrift, error) {
+	return schemaDrift(ctx, defaultDialect(), db.Conn, db.schemaCfg)
+}
+
+func schemaDrift(ctx context.Context, d *dialect, conn *sql.DB, cfg Config) (SchemaDrift, error) {
+	if d.expectedSchema == nil || d.introspect == nil {
+		return SchemaDrift{}, fmt.Errorf("%s has no schema drift check here", d.name)
+	}
+	want, err := d.expectedSchema(ctx, conn, cfg)
+	if err != nil {
+		return SchemaDrift{}, fmt.Errorf("failed to build the expected schema: %w", err)
+	}
+	have, err := d.introspect(ctx, conn)
+	if err != nil {
+		return SchemaDrift{}, fmt.Errorf("failed to introspect schema: %w", err)
+	}
+	return compareSchemas(want, have), nil
+}
+
+// compareSchemas lists what have lacks of want and has beyond it. The
+// columns and indexes of a missing or extra table aren't listed again.
+func compareSchemas(want, have SchemaInfo) SchemaDrift {
+	var s SchemaDrift
+	for _, w := range want.Tables {
+		h, ok := have.Table(w.Name)
+		if !ok {
+			s.MissingTables = append(s.MissingTables, w.Name)
+			continue
+		}
+		for _, c := range w.Columns {
+			if !slices.ContainsFunc(h.Columns, func(hc ColumnInfo) bool { return hc.Name == c.Name }) {
+				s.MissingColumns = append(s.MissingColumns, w.Name+"."+c.Name)
+			}
+		}
+		for _, c := range h.Columns {
+			if !slices.ContainsFunc(w.Columns, func(wc ColumnInfo) bool { return wc.Name == c.Name }) {
+				s.ExtraColumns = append(s.ExtraColumns, w.Name+"."+c.Name)
+			}
+		}
+		for _, ix := range namedIndexes(w) {
+			if !slices.Contains(namedIndexes(h), ix) {
+				s.MissingIndexes = append(s.MissingIndexes, w.Name+"."+ix)
+			}
+		}
+		for _, ix := range namedIndexes(h) {
+			if !slices.Contains(namedIndexes(w), ix) {
+				s.ExtraIndexes = append(s.ExtraIndexes, w.Name+"."+ix)
+			}
+		}
+	}
+	for _, h := range have.Tables {
+		if _, ok := want.Table(h.Name); !ok && !driftIgnoredTables[h.Name] {
+			s.ExtraTables = append(s.ExtraTables, h.Name)
+		}
+	}
+	return s
+}
+
+// namedIndexes leaves out the indexes SQLite names itself for UNIQUE and
+// PRIMARY KEY constraints, which are numbered in the order the constraints
+// were added and so differ between a table created whole and one altered
+func namedIndexes(t TableInfo) []string {
+	var names []string
+	for _, ix := range t.Indexes {
+		if !strings.HasPrefix(ix.Name, "sqlite_autoindex_") {
+			names = append(names, ix.Name)
+		}
+	}
+	return names
+}
+
+// checkSchema is Open's drift check, as cfg.SchemaCheck says: "warn" (the
+// default) logs the drift, "fail" returns it, "off" skips the check. A
+// check that can't run is logged, and only fails Open with "fail".
+func checkSchema(ctx context.Context, d *dialect, conn *sql.DB, cfg Config) error {
+	mode := cmp.Or(cfg.SchemaCheck, "warn")
+	if mode == "off" || d.expectedSchema == nil {
+		return nil
+	}
+	drift, err := schemaDrift(ctx, d, conn, scratchConfig(cfg))
+	switch {
+	case err != nil && mode == "fail":
+		return fmt.Errorf("schema check: %w", err)
+	case err != nil:
+		if cfg.LogLevel != "silent" {
+			log.Printf("schema check skipped: %v", err)
+		}
+	case drift.None():
+	case mode == "fail":
+		return fmt.Errorf("%w: %s", ErrSchemaDrift, drift)
+	case cfg.LogLevel != "silent":
+		log.Printf("schema drift: %s (run the migrations, or set schema_check to fail to refuse to start)", drift)
+	}
+	return nil
+}
+
+// scratchConfig is what of cfg the scratch database for the expected
+// schema is opened with: the driver, what the schema may call on, and
+// whether the database may be written to at all
+func scratchConfig(cfg Config) Config {
+	return Config{
+		Driver:           cfg.Driver,
+		SQLiteFuncs:      cfg.SQLiteFuncs,
+		Collations:       cfg.Collations,
+		SQLiteExtensions: cfg.SQLiteExtensions,
+		ReadOnly:         cfg.ReadOnly,
+	}
+}
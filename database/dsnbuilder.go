@@ -0,0 +1,191 @@
+package database
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLiteDSN builds a SQLite DSN:
+//
+//	database.SQLiteDSN{Path: "app.db", ReadOnly: true, JournalWAL: true, BusyTimeout: 10 * time.Second}.String()
+//	// file:app.db?_busy_timeout=10000&_journal_mode=WAL&mode=ro
+//
+// Config returns it ready for Open, with the journal mode and busy timeout
+// in the Config fields, where Open checks they took.
+type SQLiteDSN struct {
+	Path        string        // Database file; empty is :memory:
+	Driver      string        // "sqlite3" (mattn, default) or "sqlite" (modernc), which name the parameters differently
+	ReadOnly    bool          // mode=ro: writes fail, and the file must exist
+	JournalWAL  bool          // Write-ahead logging, see Config.JournalMode
+	BusyTimeout time.Duration // See Config.BusyTimeout; 0 leaves it to Open's default
+	Params      url.Values    // Any other parameters, as the driver names them
+}
+
+// String returns the DSN, with every setting as a parameter for Driver
+func (d SQLiteDSN) String() string {
+	q := d.params()
+	pragma := func(name, mattn, value string) {
+		if d.Driver == "sqlite" {
+			q.Add("_pragma", name+"("+value+")")
+		} else {
+			q.Set(mattn, value)
+		}
+	}
+	if d.JournalWAL {
+		pragma("journal_mode", "_journal_mode", "WAL")
+	}
+	if d.BusyTimeout > 0 {
+		pragma("busy_timeout", "_busy_timeout", strconv.FormatInt(d.BusyTimeout.Milliseconds(), 10))
+	}
+	return d.uri(q)
+}
+
+// Config returns a Config for Open with this database
+func (d SQLiteDSN) Config() Config {
+	cfg := Config{Driver: d.Driver, DSN: d.uri(d.params()), BusyTimeout: d.BusyTimeout}
+	if cfg.Driver == "" {
+		cfg.Driver = "sqlite3"
+	}
+	if d.JournalWAL {
+		cfg.JournalMode = "wal"
+	}
+	return cfg
+}
+
+func (d SQLiteDSN) params() url.Values {
+	q := url.Values{}
+	for k, v := range d.Params {
+		q[k] = append([]string(nil), v...)
+	}
+	if d.ReadOnly {
+		q.Set("mode", "ro")
+	}
+	return q
+}
+
+// ?, # and % would end or garble the path of a file: URI
+var sqlitePathEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+// uri is the path alone without parameters, a file: URI with them, which
+// both drivers hand to SQLite as such
+func (d SQLiteDSN) uri(q url.Values) string {
+	path := d.Path
+	if path == "" {
+		path = ":memory:"
+	}
+	if len(q) == 0 {
+		return path
+	}
+	return "file:" + sqlitePathEscaper.Replace(path) + "?" + q.Encode()
+}
+
+// PostgresDSN builds a PostgreSQL URL, which pgx and lib/pq both take:
+//
+//	database.PostgresDSN{Host: "db", User: "app", Password: pw, Database: "app", SSLMode: "require"}.String()
+//	// postgres://app:...@db:5432/app?sslmode=require
+//
+// The user, password and database name are escaped, so any characters
+// work.
+type PostgresDSN struct {
+	Host            string // Default "localhost"
+	Port            int    // Default 5432
+	User            string
+	Password        string
+	Database        string
+	SSLMode         string        // disable, require, verify-ca, verify-full (empty = the driver's default, prefer)
+	ApplicationName string        // Shown in pg_stat_activity
+	ConnectTimeout  time.Duration // Per connection attempt, rounded up to seconds (0 = none)
+	Params          url.Values    // Any other parameters
+}
+
+// String returns the URL
+func (d PostgresDSN) String() string {
+	host, port := d.Host, d.Port
+	if host == "" {
+		host = "localhost"
+	}
+	if port == 0 {
+		port = 5432
+	}
+
+	u := url.URL{Scheme: "postgres", Host: net.JoinHostPort(host, strconv.Itoa(port)), Path: "/" + d.Database}
+	switch {
+	case d.Password != "":
+		u.User = url.UserPassword(d.User, d.Password)
+	case d.User != "":
+		u.User = url.User(d.User)
+	}
+
+	q := url.Values{}
+	for k, v := range d.Params {
+		q[k] = append([]string(nil), v...)
+	}
+	if d.SSLMode != "" {
+		q.Set("sslmode", d.SSLMode)
+	}
+	if d.ApplicationName != "" {
+		q.Set("application_name", d.ApplicationName)
+	}
+	if d.ConnectTimeout > 0 {
+		q.Set("connect_timeout", strconv.Itoa(int((d.ConnectTimeout+time.Second-1)/time.Second)))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// Config returns a Config for Open with this database, on pgx
+func (d PostgresDSN) Config() Config {
+	return Config{Driver: "pgx", DSN: d.String()}
+}
+
+// MySQLDSN builds a DSN for github.com/go-sql-driver/mysql, for the MySQL
+// dialect template. parseTime and multiStatements are always on, since
+// the package needs both.
+type MySQLDSN struct {
+	Host     string // Default "localhost"
+	Port     int    // Default 3306
+	User     string
+	Password string
+	Database string
+	TLS      string     // true, false, skip-verify, preferred or a registered config name
+	Params   url.Values // Any other parameters
+}
+
+// String returns the DSN
+func (d MySQLDSN) String() string {
+	host, port := d.Host, d.Port
+	if host == "" {
+		host = "localhost"
+	}
+	if port == 0 {
+		port = 3306
+	}
+
+	q := url.Values{}
+	for k, v := range d.Params {
+		q[k] = append([]string(nil), v...)
+	}
+	q.Set("parseTime", "true")
+	q.Set("multiStatements", "true")
+	if d.TLS != "" {
+		q.Set("tls", d.TLS)
+	}
+
+	// The driver splits the user from the password at the first ':' and
+	// the credentials from the address at the last '@', so neither needs
+	// escaping; it has no escape for them anyway
+	user := d.User
+	if d.Password != "" {
+		user += ":" + d.Password
+	}
+	return fmt.Sprintf("%s@tcp(%s)/%s?%s", user, net.JoinHostPort(host, strconv.Itoa(port)), d.Database, q.Encode())
+}
+
+// Config returns a Config for Open with this database
+func (d MySQLDSN) Config() Config {
+	return Config{Driver: "mysql", DSN: d.String()}
+}
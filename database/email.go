@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"your-project/database/nulls"
+)
+
+// NormalizeEmail trims the address and lowercases its domain. The local part
+// keeps its casing, since RFC 5321 lets servers treat it as case-sensitive;
+// uniqueness and lookups ignore case anyway (see idx_users_email).
+func NormalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return email
+	}
+	return email[:at+1] + strings.ToLower(email[at+1:])
+}
+
+// CreateUser is Q.CreateUser with the email normalized. An empty username
+// or email is stored as NULL (see package nulls). An email already taken
+// in any casing fails with ErrDuplicate.
+func (db *DB) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	arg.Username = nulls.String(arg.Username.V)
+	arg.Email = nulls.String(NormalizeEmail(arg.Email.V))
+
+	user, err := db.Q.CreateUser(ctx, arg)
+	return user, Translate(err)
+}
+
+// GetUserByEmail finds a user by email in any casing
+func (db *DB) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	return db.Q.GetUserByEmail(ctx, NormalizeEmail(email))
+}
+
+// UpsertUserByEmail is Q.UpsertUserByEmail with the email normalized: it
+// creates the user, or updates the name and username of the one with that
+// email in any casing, keeping what's given empty. The telegram_id only
+// goes into a new row; one another user has fails with ErrDuplicate. A
+// soft-deleted user stays deleted, as with UpsertUser.
+func (db *DB) UpsertUserByEmail(ctx context.Context, arg UpsertUserByEmailParams) (User, error) {
+	arg.Username = nulls.String(arg.Username.V)
+	arg.Email = nulls.String(NormalizeEmail(arg.Email.V))
+	if !arg.Email.Valid {
+		return User{}, errors.New("UpsertUserByEmail needs an email")
+	}
+
+	user, err := db.Q.UpsertUserByEmail(ctx, arg)
+	return user, Translate(err)
+}
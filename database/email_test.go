@@ -0,0 +1,64 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	for in, want := range map[string]string{
+		" Alice@Example.COM\n": "Alice@example.com",
+		"a@b@Example.com":      "a@b@example.com",
+		"no-at-sign":           "no-at-sign",
+		"":                     "",
+	} {
+		if got := database.NormalizeEmail(in); got != want {
+			t.Errorf("NormalizeEmail(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestEmailIgnoresCase(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	email := func(s string) sql.Null[string] { return sql.Null[string]{V: s, Valid: true} }
+
+	alice, err := db.CreateUser(ctx, database.CreateUserParams{TelegramID: 1, FirstName: "Alice", Email: email(" Alice@Example.COM ")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Email.V != "Alice@example.com" {
+		t.Errorf("stored %q, want the local part's casing kept", alice.Email.V)
+	}
+	for _, e := range []string{"alice@example.com", "ALICE@EXAMPLE.COM", "Alice@example.com"} {
+		if u, err := db.GetUserByEmail(ctx, e); err != nil || u.TelegramID != 1 {
+			t.Errorf("GetUserByEmail(%q): user %d, %v; want Alice", e, u.TelegramID, err)
+		}
+	}
+
+	// Through Q directly, as rows from before the unique index were written
+	if _, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: 2, FirstName: "Bob", Email: email("bob@example.com")}); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := db.GetUserByEmail(ctx, "Bob@Example.com"); err != nil || u.TelegramID != 2 {
+		t.Errorf("lowercase data by mixed case: user %d, %v; want Bob", u.TelegramID, err)
+	}
+
+	for _, e := range []string{"alice@example.com", "ALICE@example.com"} {
+		if _, err := db.CreateUser(ctx, database.CreateUserParams{TelegramID: 3, FirstName: "Eve", Email: email(e)}); !errors.Is(err, database.ErrDuplicate) {
+			t.Errorf("creating %q: %v, want ErrDuplicate", e, err)
+		}
+	}
+	u, err := db.UpsertUserByEmail(ctx, database.UpsertUserByEmailParams{TelegramID: 4, FirstName: "Alicia", Email: email("ALICE@example.com")})
+	if err != nil || u.TelegramID != 1 || u.FirstName != "Alicia" || u.Email.V != "Alice@example.com" {
+		t.Errorf("upserting another casing: %+v, %v; want Alice's row renamed, email as first entered", u, err)
+	}
+
+	// Users without an email don't collide
+	newUsers(t, db, 5, 6)
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrWrongEncryptionKey means Config.EncryptionKey doesn't open the
+// database: the key is wrong, or the file was never encrypted
+var ErrWrongEncryptionKey = errors.New("wrong encryption key, or the database isn't encrypted")
+
+// ErrNotEncrypted is returned by Rekey on a database opened without
+// Config.EncryptionKey
+var ErrNotEncrypted = errors.New("database wasn't opened with an encryption key")
+
+// Rekey re-encrypts the database with newKey and opens every connection
+// from now on with it. The connections opened with the old key are
+// closed as they come back to the pool; a statement run on one of them
+// in the meantime may fail. It takes the write lock for a rewrite of
+// every page, so run it when the app is quiet, and put newKey in the
+// config before the next Open.
+//
+// Read replicas (Config.ReadDSNs) keep the old key, so Rekey refuses a
+// DB with them: rekey with a DB opened without ReadDSNs, then reopen.
+func (db *DB) Rekey(ctx context.Context, newKey string) error {
+	d := defaultDialect()
+	if d.rekey == nil {
+		return fmt.Errorf("%s databases can't be encrypted here, encrypt their storage instead", d.name)
+	}
+	if newKey == "" {
+		return errors.New("rekey: empty key, which would decrypt the database")
+	}
+	if db.replicas != nil {
+		return errors.New("rekey: the read replicas would keep the old key, rekey with a DB opened without ReadDSNs")
+	}
+	if err := d.rekey(ctx, db.Conn, newKey); err != nil {
+		return err
+	}
+	db.resetIdleConns()
+	return nil
+}
@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// ErrConnection marks errors meaning the database couldn't be reached or
+// the connection broke, as opposed to the query itself failing
+var ErrConnection = errors.New("database connection failed")
+
+// ErrNotFound means a query for one row found none. It wraps
+// sql.ErrNoRows, so errors.Is matches either.
+var ErrNotFound = errors.New("not found")
+
+// ErrDuplicate means a write hit a unique constraint. The driver's error is
+// kept in the chain, so errors.As still reaches it.
+var ErrDuplicate = errors.New("duplicate value violates a unique constraint")
+
+// ErrForeignKey means a write referenced a row that doesn't exist, or a
+// delete hit a row other rows still depend on (ON DELETE RESTRICT)
+var ErrForeignKey = errors.New("foreign key constraint violated")
+
+// ErrStorageExhausted means the database is out of disk space or can't
+// write to it (SQLITE_FULL, SQLITE_IOERR, PostgreSQL disk_full). While
+// the DB is write-degraded, writes fail with it right away.
+var ErrStorageExhausted = errors.New("database storage is exhausted")
+
+// ErrReadOnly means a write reached a database opened with
+// Config.ReadOnly, or a read-only transaction. Open's guard refuses the
+// statements it can tell are writes before they're sent; the database
+// itself refuses the rest, and Translate maps its error here too.
+var ErrReadOnly = errors.New("database is read-only")
+
+// IntegrityError is a unique or foreign key violation. errors.Is matches
+// it to Kind, ErrDuplicate or ErrForeignKey. Constraint is what the
+// driver names: the constraint or index on PostgreSQL; on SQLite the
+// columns ("users.email") or the index of a unique violation, and nothing
+// for a foreign key.
+type IntegrityError struct {
+	Kind       error
+	Constraint string
+	Err        error // The driver's error
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *IntegrityError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *IntegrityError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is a CHECK constraint failure that was registered with
+// RegisterConstraintMessage, ready to be shown next to a form field
+type ValidationError struct {
+	Field      string
+	Message    string
+	Constraint string
+	Err        error // The driver's error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ConstraintError is a CHECK constraint failure nobody registered a message
+// for. Constraint is empty if the driver didn't say which one failed.
+type ConstraintError struct {
+	Constraint string
+	Err        error // The driver's error
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("check constraint %q failed: %v", e.Constraint, e.Err)
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+type fieldMessage struct {
+	field   string
+	message string
+}
+
+// The CHECK constraints in schema.sql; name them in both dialects, since
+// the name is what the drivers report
+var (
+	constraintMu       sync.RWMutex
+	constraintMessages = map[string]fieldMessage{
+		"users_status_check":    {"status", "must be one of active, blocked, banned"},
+		"categories_name_check": {"name", "must not be empty"},
+	}
+)
+
+// RegisterConstraintMessage makes Translate report failures of the named
+// CHECK constraint as a ValidationError for field. Registering a name again
+// replaces its message.
+func RegisterConstraintMessage(constraint, field, message string) {
+	constraintMu.Lock()
+	defer constraintMu.Unlock()
+	constraintMessages[constraint] = fieldMessage{field, message}
+}
+
+// Translate maps driver-specific errors onto this package's errors, so
+// callers can use errors.Is(err, ErrNotFound) (or ErrDuplicate,
+// ErrForeignKey) or errors.As with *IntegrityError and *ValidationError
+// on any dialect. db.Q and the transaction helpers already return their
+// errors through it. Errors it doesn't recognize, or has translated
+// before, are returned unchanged.
+func Translate(err error) error {
+	if err == nil || translated(err) {
+		return err
+	}
+
+	d := defaultDialect()
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	if isStorageError(err) {
+		return fmt.Errorf("%w: %w", ErrStorageExhausted, err)
+	}
+	if isConnectionError(err) {
+		return fmt.Errorf("%w: %w", ErrConnection, err)
+	}
+	if d.isReadOnlyError != nil && d.isReadOnlyError(err) {
+		return fmt.Errorf("%w: %w", ErrReadOnly, err)
+	}
+	if d.isUniqueViolation(err) {
+		return &IntegrityError{Kind: ErrDuplicate, Constraint: d.violatedConstraint(err), Err: err}
+	}
+	if d.isForeignKeyViolation(err) {
+		return &IntegrityError{Kind: ErrForeignKey, Constraint: d.violatedConstraint(err), Err: err}
+	}
+	if name, ok := d.checkViolation(err); ok {
+		constraintMu.RLock()
+		m, registered := constraintMessages[name]
+		constraintMu.RUnlock()
+
+		if registered {
+			return &ValidationError{Field: m.field, Message: m.message, Constraint: name, Err: err}
+		}
+		return &ConstraintError{Constraint: name, Err: err}
+	}
+	return err
+}
+
+// translated is true for an error Translate has already been through
+func translated(err error) bool {
+	for _, sentinel := range []error{ErrNotFound, ErrDuplicate, ErrForeignKey, ErrConnection, ErrStorageExhausted, ErrQueryTimeout, ErrReadOnly} {
+		if errors.Is(err, sentinel) {
+			return true
+		}
+	}
+	var ve *ValidationError
+	var ce *ConstraintError
+	return errors.As(err, &ve) || errors.As(err, &ce)
+}
+
+// isStorageError is true for a full or failing disk
+func isStorageError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	d := defaultDialect()
+	return d.isStorageError != nil && d.isStorageError(err)
+}
+
+// isConnectionError is true for network and driver-level failures. Context
+// errors don't count: the caller gave up, the database may be fine.
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return defaultDialect().isConnectionError(err)
+}
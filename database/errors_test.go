@@ -0,0 +1,66 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestTranslateCheckViolations(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	newUsers(t, db, 1)
+
+	// The schema's own, registered in errors.go
+	for _, c := range []struct {
+		field string
+		write func() error
+	}{
+		{"name", func() error {
+			_, err := db.Q.CreateCategory(ctx, database.CreateCategoryParams{Name: "  "})
+			return err
+		}},
+		{"status", func() error {
+			_, err := db.DBTX().ExecContext(ctx, "UPDATE users SET status = 'deleted'")
+			return database.Translate(err)
+		}},
+	} {
+		err := c.write()
+		var ve *database.ValidationError
+		if !errors.As(err, &ve) || ve.Field != c.field || ve.Message == "" {
+			t.Errorf("violating the %s check: %v, want a ValidationError for %s", c.field, err, c.field)
+		}
+	}
+
+	if _, err := db.DBTX().ExecContext(ctx, `CREATE TABLE widgets (
+		qty INTEGER CONSTRAINT widgets_qty_check CHECK (qty >= 0),
+		size INTEGER CHECK (size > 0))`); err != nil {
+		t.Fatal(err)
+	}
+	negativeQty := func() error {
+		_, err := db.DBTX().ExecContext(ctx, "INSERT INTO widgets VALUES (-1, 1)")
+		return database.Translate(err)
+	}
+
+	// Unregistered, so only the constraint is known
+	var ce *database.ConstraintError
+	if err := negativeQty(); !errors.As(err, &ce) || ce.Constraint != "widgets_qty_check" {
+		t.Errorf("an unregistered constraint: %v, want a ConstraintError naming widgets_qty_check", err)
+	}
+	_, err := db.DBTX().ExecContext(ctx, "INSERT INTO widgets VALUES (1, 0)")
+	if err = database.Translate(err); !errors.As(err, &ce) {
+		t.Errorf("an unnamed constraint: %v, want a ConstraintError", err)
+	}
+
+	database.RegisterConstraintMessage("widgets_qty_check", "qty", "must be non-negative")
+	var ve *database.ValidationError
+	if err := negativeQty(); !errors.As(err, &ve) || ve.Field != "qty" || ve.Error() != "qty must be non-negative" {
+		t.Errorf("once registered: %v, want a ValidationError for qty", err)
+	}
+	if err := database.Translate(ve); err != ve {
+		t.Errorf("translating again: %v, want it unchanged", err)
+	}
+}
@@ -0,0 +1,251 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// RowEvent is one of the typed events EventQueries publishes: UserCreated,
+// UserUpdated, UserDeleted, UserRestored, GroupCreated, GroupUpdated,
+// MembershipCreated or MembershipUpdated. Each is an outbox event under
+// its own topic, with the event as JSON for the payload.
+type RowEvent interface {
+	topic() string
+}
+
+// The row events. An upsert is an Updated event, since the row may have
+// been there before; UserDeleted is a soft delete.
+type (
+	UserCreated       struct{ User User }
+	UserUpdated       struct{ User User }
+	UserDeleted       struct{ ID int64 }
+	UserRestored      struct{ ID int64 }
+	GroupCreated      struct{ Group Group }
+	GroupUpdated      struct{ Group Group }
+	MembershipCreated struct{ Membership UserGroup }
+	MembershipUpdated struct{ Membership UserGroup }
+)
+
+func (UserCreated) topic() string       { return "user.created" }
+func (UserUpdated) topic() string       { return "user.updated" }
+func (UserDeleted) topic() string       { return "user.deleted" }
+func (UserRestored) topic() string      { return "user.restored" }
+func (GroupCreated) topic() string      { return "group.created" }
+func (GroupUpdated) topic() string      { return "group.updated" }
+func (MembershipCreated) topic() string { return "membership.created" }
+func (MembershipUpdated) topic() string { return "membership.updated" }
+
+// rowEventTopics decodes an outbox payload by topic
+var rowEventTopics = map[string]func([]byte) (RowEvent, error){
+	"user.created":       decodeRowEvent[UserCreated],
+	"user.updated":       decodeRowEvent[UserUpdated],
+	"user.deleted":       decodeRowEvent[UserDeleted],
+	"user.restored":      decodeRowEvent[UserRestored],
+	"group.created":      decodeRowEvent[GroupCreated],
+	"group.updated":      decodeRowEvent[GroupUpdated],
+	"membership.created": decodeRowEvent[MembershipCreated],
+	"membership.updated": decodeRowEvent[MembershipUpdated],
+}
+
+func decodeRowEvent[E RowEvent](payload []byte) (RowEvent, error) {
+	var e E
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// EventQueries runs a transaction's writes and publishes a RowEvent for
+// each to the outbox, so the event exists exactly when the write commits.
+// Writes made through tx's own queries publish nothing.
+type EventQueries struct {
+	tx *Tx
+}
+
+// Events returns the transaction's writes that publish row events
+func (t *Tx) Events() *EventQueries {
+	return &EventQueries{tx: t}
+}
+
+// publishRow runs write and publishes the event made from its result;
+// event reports false when there is nothing to announce
+func publishRow[T any, E RowEvent](e *EventQueries, write func() (T, error), event func(T) (E, bool)) (T, error) {
+	v, err := write()
+	if err != nil {
+		return v, err
+	}
+	ev, ok := event(v)
+	if !ok {
+		return v, nil
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return v, fmt.Errorf("failed to encode %s event: %w", ev.topic(), err)
+	}
+	return v, e.tx.Publish(ev.topic(), payload)
+}
+
+func (e *EventQueries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	return publishRow(e, func() (User, error) { return e.tx.CreateUser(ctx, arg) },
+		func(u User) (UserCreated, bool) { return UserCreated{u}, true })
+}
+
+func (e *EventQueries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	return publishRow(e, func() (User, error) { return e.tx.UpdateUser(ctx, arg) },
+		func(u User) (UserUpdated, bool) { return UserUpdated{u}, true })
+}
+
+func (e *EventQueries) UpdateUserBalanceChats(ctx context.Context, arg UpdateUserBalanceChatsParams) (User, error) {
+	return publishRow(e, func() (User, error) { return e.tx.UpdateUserBalanceChats(ctx, arg) },
+		func(u User) (UserUpdated, bool) { return UserUpdated{u}, true })
+}
+
+func (e *EventQueries) UpsertUser(ctx context.Context, arg UpsertUserParams) (User, error) {
+	return publishRow(e, func() (User, error) { return e.tx.UpsertUser(ctx, arg) },
+		func(u User) (UserUpdated, bool) { return UserUpdated{u}, true })
+}
+
+func (e *EventQueries) UpsertUserByEmail(ctx context.Context, arg UpsertUserByEmailParams) (User, error) {
+	return publishRow(e, func() (User, error) { return e.tx.UpsertUserByEmail(ctx, arg) },
+		func(u User) (UserUpdated, bool) { return UserUpdated{u}, true })
+}
+
+func (e *EventQueries) SoftDeleteUser(ctx context.Context, id int64) (int64, error) {
+	return publishRow(e, func() (int64, error) { return e.tx.SoftDeleteUser(ctx, id) },
+		func(n int64) (UserDeleted, bool) { return UserDeleted{id}, n > 0 })
+}
+
+func (e *EventQueries) RestoreUser(ctx context.Context, id int64) (int64, error) {
+	return publishRow(e, func() (int64, error) { return e.tx.RestoreUser(ctx, id) },
+		func(n int64) (UserRestored, bool) { return UserRestored{id}, n > 0 })
+}
+
+func (e *EventQueries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error) {
+	return publishRow(e, func() (Group, error) { return e.tx.CreateGroup(ctx, arg) },
+		func(g Group) (GroupCreated, bool) { return GroupCreated{g}, true })
+}
+
+func (e *EventQueries) UpsertGroup(ctx context.Context, arg UpsertGroupParams) (Group, error) {
+	return publishRow(e, func() (Group, error) { return e.tx.UpsertGroup(ctx, arg) },
+		func(g Group) (GroupUpdated, bool) { return GroupUpdated{g}, true })
+}
+
+func (e *EventQueries) CreateUserGroup(ctx context.Context, arg CreateUserGroupParams) (UserGroup, error) {
+	return publishRow(e, func() (UserGroup, error) { return e.tx.CreateUserGroup(ctx, arg) },
+		func(m UserGroup) (MembershipCreated, bool) { return MembershipCreated{m}, true })
+}
+
+func (e *EventQueries) UpdateUserGroupBalance(ctx context.Context, arg UpdateUserGroupBalanceParams) (UserGroup, error) {
+	return publishRow(e, func() (UserGroup, error) { return e.tx.UpdateUserGroupBalance(ctx, arg) },
+		func(m UserGroup) (MembershipUpdated, bool) { return MembershipUpdated{m}, true })
+}
+
+func (e *EventQueries) UpsertUserGroupBalance(ctx context.Context, arg UpsertUserGroupBalanceParams) (UserGroup, error) {
+	return publishRow(e, func() (UserGroup, error) { return e.tx.UpsertUserGroupBalance(ctx, arg) },
+		func(m UserGroup) (MembershipUpdated, bool) { return MembershipUpdated{m}, true })
+}
+
+func (e *EventQueries) AddToUserGroupBalance(ctx context.Context, arg AddToUserGroupBalanceParams) (UserGroup, error) {
+	return publishRow(e, func() (UserGroup, error) { return e.tx.AddToUserGroupBalance(ctx, arg) },
+		func(m UserGroup) (MembershipUpdated, bool) { return MembershipUpdated{m}, true })
+}
+
+// EventBus hands the row events coming out of the outbox to subscribers
+// in this process. Pass its Handle to StartOutboxDispatcher:
+//
+//	bus := database.NewEventBus(nil)
+//	users := database.Subscribe[database.UserUpdated](ctx, bus)
+//	db.StartOutboxDispatcher(ctx, bus.Handle, database.OutboxOptions{})
+//
+// An event is delivered once every subscriber to its type has received
+// it, so a slow subscriber holds the dispatcher back rather than losing
+// events, and it may see an event twice after a crash. An event nobody
+// subscribes to is delivered to nobody.
+type EventBus struct {
+	mu    sync.Mutex
+	subs  map[string][]*eventSub
+	other func(Event) error
+}
+
+type eventSub struct {
+	mu     sync.Mutex
+	send   func(RowEvent) // Blocks until received or ctx is done
+	closed bool
+	close  func()
+}
+
+// NewEventBus returns a bus that passes outbox events of other topics,
+// Tx.Publish's own, to other; nil marks them delivered
+func NewEventBus(other func(Event) error) *EventBus {
+	return &EventBus{subs: map[string][]*eventSub{}, other: other}
+}
+
+// Subscribe delivers bus's events of type E until ctx is done, then
+// closes the channel
+func Subscribe[E RowEvent](ctx context.Context, bus *EventBus) <-chan E {
+	var zero E
+	topic := zero.topic()
+	ch := make(chan E)
+	s := &eventSub{close: func() { close(ch) }}
+	s.send = func(ev RowEvent) {
+		select {
+		case ch <- ev.(E):
+		case <-ctx.Done():
+		}
+	}
+
+	bus.mu.Lock()
+	bus.subs[topic] = append(bus.subs[topic], s)
+	bus.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		bus.mu.Lock()
+		subs := bus.subs[topic]
+		for i, other := range subs {
+			if other == s {
+				bus.subs[topic] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+		bus.mu.Unlock()
+
+		s.mu.Lock()
+		s.closed = true
+		s.close()
+		s.mu.Unlock()
+	}()
+	return ch
+}
+
+// Handle delivers an outbox event to the subscribers of its type. A
+// row event whose payload doesn't decode fails, and the outbox retries
+// it.
+func (b *EventBus) Handle(e Event) error {
+	decode, ok := rowEventTopics[e.Topic]
+	if !ok {
+		if b.other == nil {
+			return nil
+		}
+		return b.other(e)
+	}
+	ev, err := decode(e.Payload)
+	if err != nil {
+		return fmt.Errorf("malformed %s event %d: %w", e.Topic, e.ID, err)
+	}
+
+	b.mu.Lock()
+	subs := append([]*eventSub(nil), b.subs[e.Topic]...)
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.mu.Lock()
+		if !s.closed {
+			s.send(ev)
+		}
+		s.mu.Unlock()
+	}
+	return nil
+}
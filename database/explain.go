@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Plan is a query plan as the database reported it, flattened into a list
+// of steps
+type Plan struct {
+	Nodes    []PlanNode
+	FullScan bool     // Some step reads a table row by row, without an index
+	Indexes  []string // Indexes the plan uses, in plan order, without duplicates
+}
+
+// PlanNode is one step of a Plan
+type PlanNode struct {
+	ID       int
+	Parent   int    // ID of the enclosing step, 0 at the top
+	Detail   string // "SEARCH users USING INDEX idx_users_email (<expr>=?)", "Index Scan on users using idx_users_email"
+	Table    string // Table the step reads, if any
+	Index    string // Index the step uses, if any
+	FullScan bool
+}
+
+// Explain asks the database how it would run the named generated query
+// (e.g. "GetUserByEmail") with args, without running it: EXPLAIN QUERY
+// PLAN on SQLite, EXPLAIN (FORMAT JSON) on PostgreSQL. Arguments matter on
+// PostgreSQL, whose planner looks at the values.
+func (db *DB) Explain(ctx context.Context, query string, args ...any) (Plan, error) {
+	sql, ok := querySQL[query]
+	if !ok {
+		return Plan{}, fmt.Errorf("unknown query %q", query)
+	}
+
+	d := defaultDialect()
+	if d.explain == nil {
+		return Plan{}, fmt.Errorf("explain is not supported on %s", d.name)
+	}
+
+	nodes, err := d.explain(ctx, db.Conn, sql, args...)
+	if err != nil {
+		return Plan{}, fmt.Errorf("failed to explain %s: %w", query, err)
+	}
+
+	plan := Plan{Nodes: nodes}
+	for _, n := range nodes {
+		plan.FullScan = plan.FullScan || n.FullScan
+		if n.Index != "" && !slices.Contains(plan.Indexes, n.Index) {
+			plan.Indexes = append(plan.Indexes, n.Index)
+		}
+	}
+	return plan, nil
+}
+
+// UsesIndex reports whether any step of the plan uses the index
+func (p Plan) UsesIndex(name string) bool {
+	return slices.Contains(p.Indexes, name)
+}
@@ -0,0 +1,63 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+	"your-project/database/nulls"
+)
+
+// failures is a testing.TB that records failures instead of failing
+type failures struct {
+	testing.TB
+	n int
+}
+
+func (f *failures) Helper()               {}
+func (f *failures) Errorf(string, ...any) { f.n++ }
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	for i := range 500 { // So PostgreSQL's planner wants the index too
+		email := fmt.Sprintf("user%d@example.com", i)
+		if _, err := db.CreateUser(ctx, database.CreateUserParams{TelegramID: int64(i + 1), FirstName: "user", Email: nulls.String(email)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.DBTX().ExecContext(ctx, "ANALYZE"); err != nil {
+		t.Fatal(err)
+	}
+
+	indexed, err := db.Explain(ctx, "GetUserByEmail", "user7@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbtest.AssertUsesIndex(t, indexed, "idx_users_email")
+	dbtest.AssertNoFullScan(t, indexed)
+	if len(indexed.Nodes) == 0 || indexed.Nodes[0].Detail == "" {
+		t.Errorf("plan nodes %+v, want the database's details", indexed.Nodes)
+	}
+
+	// status has no index, so every user is read
+	scan, err := db.Explain(ctx, "CountUsersByStatus", database.StatusBanned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scan.FullScan || scan.UsesIndex("idx_users_email") {
+		t.Errorf("CountUsersByStatus: full scan %v, indexes %v; want a full scan", scan.FullScan, scan.Indexes)
+	}
+	f := &failures{TB: t}
+	dbtest.AssertUsesIndex(f, scan, "idx_users_email")
+	dbtest.AssertNoFullScan(f, scan)
+	if f.n != 2 {
+		t.Errorf("the assertions failed %d times on a full scan, want 2", f.n)
+	}
+
+	if _, err := db.Explain(ctx, "NoSuchQuery"); err == nil {
+		t.Error("explaining an unknown query: no error")
+	}
+}
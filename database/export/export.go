@@ -0,0 +1,602 @@
+// Package export writes a table, or what a query returns, as CSV or
+// NDJSON (one JSON object per line) while it reads the rows, and imports
+// the same formats into a table, for data migrations and support tools:
+//
+//	n, err := export.Table(ctx, db, w, "users", export.Options{Format: export.CSV})
+//	n, err = export.Query(ctx, db, w, export.Options{Format: export.NDJSON},
+//		"SELECT id, email FROM users WHERE status = ?", database.StatusBanned)
+//
+//	n, err = export.Import(ctx, db, r, "users", export.ImportOptions{
+//		Format:  export.CSV,
+//		Columns: map[string]string{"name": "first_name", "notes": "-"},
+//		Upsert:  true,
+//	})
+//
+// CSV starts with a header of column names. Both formats write NULL as
+// Options.Null in CSV and null in JSON, BLOBs as base64 and times as
+// RFC 3339, and import them back by the table's column types.
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"your-project/database"
+)
+
+// Format is how rows are written
+type Format string
+
+const (
+	CSV    Format = "csv"    // A header of column names, then a record per row
+	NDJSON Format = "ndjson" // A JSON object per line, keys in column order
+)
+
+// Rows per INSERT statement in Import, unless ImportOptions.BatchSize says
+// otherwise
+const defaultBatchSize = 500
+
+// FormatFor picks the format by a file name's extension: .csv, or .ndjson,
+// .jsonl or .json for NDJSON
+func FormatFor(name string) (Format, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return CSV, nil
+	case ".ndjson", ".jsonl", ".json":
+		return NDJSON, nil
+	}
+	return "", fmt.Errorf("no export format for %q (want .csv, .ndjson or .jsonl)", name)
+}
+
+// Options configures Table and Query
+type Options struct {
+	Format Format
+	Null   string // What CSV writes for NULL ("" by default, which makes empty strings NULL on import)
+}
+
+// Table writes every row of table, in primary key order, and returns how
+// many it wrote. The table must exist; views work too.
+func Table(ctx context.Context, db *database.DB, w io.Writer, table string, opts Options) (int64, error) {
+	info, err := tableInfo(ctx, db, table)
+	if err != nil {
+		return 0, err
+	}
+	query := "SELECT * FROM " + quote(info.Name)
+	if pk := info.PrimaryKey(); len(pk) > 0 {
+		for i, c := range pk {
+			pk[i] = quote(c)
+		}
+		query += " ORDER BY " + strings.Join(pk, ", ")
+	}
+	n, err := Query(ctx, db, w, opts, query)
+	if err != nil {
+		return n, fmt.Errorf("failed to export %s: %w", table, err)
+	}
+	return n, nil
+}
+
+// Query runs query and writes its rows as they come, and returns how many
+// it wrote. Columns take their names from the select list, so alias
+// expressions (count(*) AS members). Canceling ctx stops it, leaving what
+// was written.
+func Query(ctx context.Context, db *database.DB, w io.Writer, opts Options, query string, args ...any) (int64, error) {
+	var rw rowWriter
+	switch opts.Format {
+	case CSV:
+		rw = &csvWriter{w: csv.NewWriter(w), null: opts.Null}
+	case NDJSON:
+		rw = &jsonWriter{w: bufio.NewWriter(w)}
+	default:
+		return 0, fmt.Errorf("unknown export format %q", opts.Format)
+	}
+
+	rows, err := db.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, database.Translate(err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	columns := make([]string, len(types))
+	kinds := make([]kind, len(types))
+	for i, t := range types {
+		columns[i], kinds[i] = t.Name(), kindOf(t.DatabaseTypeName())
+	}
+	if err := rw.header(columns); err != nil {
+		return 0, err
+	}
+
+	vals := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		for i, v := range vals {
+			vals[i] = exportValue(v, kinds[i])
+		}
+		if err := rw.row(vals); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, database.Translate(err)
+	}
+	return n, rw.flush()
+}
+
+type rowWriter interface {
+	header(columns []string) error
+	row(vals []any) error // Values as exportValue makes them
+	flush() error
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	null   string
+	record []string
+}
+
+func (c *csvWriter) header(columns []string) error {
+	c.record = make([]string, len(columns))
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) row(vals []any) error {
+	for i, v := range vals {
+		switch v := v.(type) {
+		case nil:
+			c.record[i] = c.null
+		case string:
+			c.record[i] = v
+		case int64:
+			c.record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			c.record[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		case bool:
+			c.record[i] = strconv.FormatBool(v)
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			c.record[i] = string(b)
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonWriter struct {
+	w       *bufio.Writer
+	columns [][]byte // Quoted, with the colon
+}
+
+func (j *jsonWriter) header(columns []string) error {
+	j.columns = make([][]byte, len(columns))
+	for i, c := range columns {
+		b, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		j.columns[i] = append(b, ':')
+	}
+	return nil
+}
+
+func (j *jsonWriter) row(vals []any) error {
+	j.w.WriteByte('{')
+	for i, v := range vals {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		j.w.Write(j.columns[i])
+		j.w.Write(b)
+	}
+	j.w.WriteString("}\n")
+	// Write out every so often rather than holding it all
+	if j.w.Buffered() >= 64<<10 {
+		return j.w.Flush()
+	}
+	return nil
+}
+
+func (j *jsonWriter) flush() error {
+	return j.w.Flush()
+}
+
+// kind is what a column's values are, read off its database type, for
+// writing them out and converting them back
+type kind int
+
+const (
+	kindText kind = iota
+	kindInt
+	kindFloat
+	kindBlob
+	kindTime
+)
+
+func kindOf(dbType string) kind {
+	t := strings.ToUpper(dbType)
+	switch {
+	case (strings.Contains(t, "INT") && !strings.Contains(t, "INTERVAL") && !strings.Contains(t, "POINT")) || strings.Contains(t, "SERIAL"):
+		return kindInt
+	case strings.Contains(t, "REAL") || strings.Contains(t, "DOUB") || strings.Contains(t, "FLOA"):
+		return kindFloat
+	case strings.Contains(t, "BLOB") || strings.Contains(t, "BYTEA"):
+		return kindBlob
+	case strings.Contains(t, "TIME") || strings.Contains(t, "DATE"):
+		return kindTime
+	}
+	return kindText
+}
+
+// exportValue narrows what the driver scanned to nil, string, int64,
+// float64 or bool, and JSON values PostgreSQL drivers decode
+func exportValue(v any, k kind) any {
+	switch v := v.(type) {
+	case []byte:
+		if k == kindBlob {
+			return base64.StdEncoding.EncodeToString(v)
+		}
+		return string(v) // Text some drivers hand over as bytes
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case float32:
+		return float64(v)
+	}
+	return v
+}
+
+// ImportOptions configures Import
+type ImportOptions struct {
+	Format Format
+	Null   string // The CSV field that's NULL ("" by default)
+
+	// Columns maps the names in the input, a CSV header's or the JSON
+	// keys, to the table's columns; "-" leaves a field out. Names that
+	// aren't in it go to the column of the same name.
+	Columns map[string]string
+
+	// Upsert updates a row that's there already, found by UpsertOn (the
+	// primary key if empty), instead of failing with ErrDuplicate. Two
+	// input rows for the same key in one batch fail on PostgreSQL.
+	Upsert   bool
+	UpsertOn []string
+
+	BatchSize int // Rows per INSERT statement (0 = 500)
+}
+
+// Import reads rows in opts.Format from r and inserts them into table in
+// batches, all in one transaction, and returns how many rows it inserted
+// or updated. Any error, a malformed row's included, rolls all of them
+// back. The input's fields are converted by the column types (numbers,
+// base64 BLOBs, RFC 3339 times), and PostgreSQL's sequences are moved on
+// past the ids it inserted. NDJSON may also be what DB.ExportJSON writes,
+// of which the lines for other tables are skipped.
+//
+// The transaction holds the write lock while it reads r, on SQLite for
+// the whole database, so import from a file rather than a slow stream.
+func Import(ctx context.Context, db *database.DB, r io.Reader, table string, opts ImportOptions) (int64, error) {
+	info, err := tableInfo(ctx, db, table)
+	if err != nil {
+		return 0, err
+	}
+	if info.View {
+		return 0, fmt.Errorf("%s is a view", table)
+	}
+	var rr rowReader
+	switch opts.Format {
+	case CSV:
+		c := csv.NewReader(r)
+		c.ReuseRecord = true
+		rr = &csvReader{r: c, null: opts.Null}
+	case NDJSON:
+		d := json.NewDecoder(r)
+		d.UseNumber()
+		rr = &jsonReader{d: d, table: table}
+	default:
+		return 0, fmt.Errorf("unknown import format %q", opts.Format)
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	upsertOn := opts.UpsertOn
+	if opts.Upsert && len(upsertOn) == 0 {
+		if upsertOn = info.PrimaryKey(); len(upsertOn) == 0 {
+			return 0, fmt.Errorf("%s has no primary key to upsert on, set UpsertOn", table)
+		}
+	}
+	if !opts.Upsert {
+		upsertOn = nil
+	}
+
+	var total int64
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		total = 0
+		var m *mapping
+		var batch [][]any
+		insert := func() error {
+			n, err := tx.InsertRows(ctx, table, m.columns, batch, upsertOn)
+			total += n
+			batch = batch[:0]
+			return err
+		}
+		for line := 1; ; line++ {
+			fields, vals, err := rr.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("row %d: %w", line, err)
+			}
+			if vals == nil {
+				continue // Another table's line of ExportJSON
+			}
+			if m == nil {
+				if m, err = newMapping(info, fields, opts.Columns); err != nil {
+					return err
+				}
+			}
+			row, err := m.row(fields, vals)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", line, err)
+			}
+			if batch = append(batch, row); len(batch) == batchSize {
+				if err := insert(); err != nil {
+					return err
+				}
+			}
+		}
+		if len(batch) > 0 {
+			if err := insert(); err != nil {
+				return err
+			}
+		}
+		if m == nil {
+			return nil
+		}
+		return tx.SyncSequences(ctx, table)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import into %s: %w", table, err)
+	}
+	return total, nil
+}
+
+// rowReader reads the input a row at a time: the field names and their
+// values, strings for CSV and JSON values for NDJSON. vals is nil for a
+// row to skip.
+type rowReader interface {
+	next() (fields []string, vals []any, err error)
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	null   string
+	header []string
+}
+
+func (c *csvReader) next() ([]string, []any, error) {
+	if c.header == nil {
+		h, err := c.r.Read()
+		if err != nil {
+			return nil, nil, err
+		}
+		c.header = slices.Clone(h)
+	}
+	record, err := c.r.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	vals := make([]any, len(record))
+	for i, f := range record {
+		if f != c.null {
+			vals[i] = f
+		}
+	}
+	return c.header, vals, nil
+}
+
+type jsonReader struct {
+	d     *json.Decoder
+	table string
+}
+
+func (j *jsonReader) next() ([]string, []any, error) {
+	var obj map[string]json.RawMessage
+	if err := j.d.Decode(&obj); err != nil {
+		return nil, nil, err
+	}
+	// ExportJSON's lines are {"table": ..., "row": {...}}
+	if t, ok := obj["table"]; ok && len(obj) == 2 && obj["row"] != nil {
+		var table string
+		var row map[string]json.RawMessage
+		if json.Unmarshal(t, &table) == nil && json.Unmarshal(obj["row"], &row) == nil {
+			if table != j.table {
+				return nil, nil, nil
+			}
+			obj = row
+		}
+	}
+
+	fields := make([]string, 0, len(obj))
+	for f := range obj {
+		fields = append(fields, f)
+	}
+	slices.Sort(fields)
+	vals := make([]any, len(fields))
+	for i, f := range fields {
+		var v any
+		if err := unmarshalNumbers(obj[f], &v); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", f, err)
+		}
+		vals[i] = v
+	}
+	return fields, vals, nil
+}
+
+// unmarshalNumbers is json.Unmarshal keeping numbers as json.Number, so
+// that large ids stay exact
+func unmarshalNumbers(data []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// mapping is where the input's fields go: the table columns inserted,
+// and for each field its column's position, -1 to leave it out
+type mapping struct {
+	columns []string
+	kinds   []kind
+	target  map[string]int
+}
+
+// newMapping maps the first row's fields to info's columns
+func newMapping(info database.TableInfo, fields []string, rename map[string]string) (*mapping, error) {
+	m := &mapping{target: map[string]int{}}
+	for _, f := range fields {
+		col := f
+		if to, ok := rename[f]; ok {
+			col = to
+		}
+		if col == "-" {
+			m.target[f] = -1
+			continue
+		}
+		i := slices.IndexFunc(info.Columns, func(c database.ColumnInfo) bool { return c.Name == col })
+		if i < 0 {
+			return nil, fmt.Errorf("%s has no column %q", info.Name, col)
+		}
+		if info.Columns[i].Generated { // Exported with the rest, computed again on import
+			m.target[f] = -1
+			continue
+		}
+		if slices.Contains(m.columns, col) {
+			return nil, fmt.Errorf("two fields go to column %q", col)
+		}
+		m.target[f] = len(m.columns)
+		m.columns = append(m.columns, col)
+		m.kinds = append(m.kinds, kindOf(info.Columns[i].Type))
+	}
+	if len(m.columns) == 0 {
+		return nil, errors.New("no fields to import")
+	}
+	return m, nil
+}
+
+// row is the column values for one input row. A field the first row
+// didn't have is an error; one it lacks is NULL.
+func (m *mapping) row(fields []string, vals []any) ([]any, error) {
+	row := make([]any, len(m.columns))
+	if len(fields) != len(vals) {
+		return nil, fmt.Errorf("%d fields for a header of %d", len(vals), len(fields))
+	}
+	for i, f := range fields {
+		c, ok := m.target[f]
+		if !ok {
+			return nil, fmt.Errorf("field %q isn't in the first row", f)
+		}
+		if c < 0 {
+			continue
+		}
+		v, err := importValue(vals[i], m.kinds[c])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.columns[c], err)
+		}
+		row[c] = v
+	}
+	return row, nil
+}
+
+// importValue converts what the input has for a column to what the
+// driver takes for its kind
+func importValue(v any, k kind) (any, error) {
+	switch v := v.(type) {
+	case nil, bool:
+		return v, nil
+	case json.Number:
+		if k == kindInt {
+			return v.Int64()
+		}
+		if k == kindFloat {
+			return v.Float64()
+		}
+		return v.String(), nil
+	case string:
+		switch k {
+		case kindInt:
+			return strconv.ParseInt(v, 10, 64)
+		case kindFloat:
+			return strconv.ParseFloat(v, 64)
+		case kindBlob:
+			return base64.StdEncoding.DecodeString(v)
+		case kindTime:
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t, nil
+			}
+			return v, nil // Leave other layouts to the database
+		}
+		return v, nil
+	}
+	// An object or array, for a JSON column
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// tableInfo finds table in the live schema, which also checks the name
+// before it goes into SQL
+func tableInfo(ctx context.Context, db *database.DB, table string) (database.TableInfo, error) {
+	schema, err := db.Introspect(ctx)
+	if err != nil {
+		return database.TableInfo{}, err
+	}
+	info, ok := schema.Table(table)
+	if !ok {
+		return database.TableInfo{}, fmt.Errorf("unknown table %q", table)
+	}
+	return info, nil
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
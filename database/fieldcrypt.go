@@ -0,0 +1,381 @@
+package database
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrFieldDecrypt is returned by DecryptField for a value that doesn't
+// decrypt: a wrong key, tampered ciphertext, or one that belongs to
+// another row or column
+var ErrFieldDecrypt = errors.New("failed to decrypt field")
+
+// ErrNoFieldKeys is returned when a field is encrypted or decrypted on a
+// DB opened without keys (Config.FieldEncryptionKey or FieldKeys)
+var ErrNoFieldKeys = errors.New("no field encryption keys configured")
+
+// FieldKey is one AES key for encrypted fields. Its version is stored in
+// front of every value it encrypts, so values keep decrypting while keys
+// are rotated.
+type FieldKey struct {
+	Version uint8  // 1 to 255
+	Key     []byte // 16, 24 or 32 bytes: AES-128, -192 or -256
+}
+
+// KeyProvider supplies the keys for EncryptField and BlindIndex, for
+// keys that live in a KMS or vault rather than in the config
+type KeyProvider interface {
+	CurrentKey() (FieldKey, error)       // Encrypts new values
+	Key(version uint8) (FieldKey, error) // Decrypts values written with that version
+	IndexKey() ([]byte, error)           // Keys BlindIndex; it never changes
+}
+
+type staticKeys struct {
+	index []byte
+	keys  []FieldKey
+}
+
+// StaticKeys is a KeyProvider over fixed keys. The first key encrypts,
+// all of them decrypt.
+func StaticKeys(indexKey []byte, keys ...FieldKey) KeyProvider {
+	return &staticKeys{index: indexKey, keys: keys}
+}
+
+func (s *staticKeys) CurrentKey() (FieldKey, error) {
+	if len(s.keys) == 0 {
+		return FieldKey{}, ErrNoFieldKeys
+	}
+	return s.keys[0], nil
+}
+
+func (s *staticKeys) Key(version uint8) (FieldKey, error) {
+	for _, k := range s.keys {
+		if k.Version == version {
+			return k, nil
+		}
+	}
+	return FieldKey{}, fmt.Errorf("no field key with version %d", version)
+}
+
+func (s *staticKeys) IndexKey() ([]byte, error) {
+	if len(s.index) == 0 {
+		return nil, ErrNoFieldKeys
+	}
+	return s.index, nil
+}
+
+// ParseFieldKey reads a key written as "<version>:<base64 key>", the form
+// Config.FieldEncryptionKey lists them in. Generate one with
+// `openssl rand -base64 32`.
+func ParseFieldKey(s string) (FieldKey, error) {
+	v, b64, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return FieldKey{}, errors.New(`field key must look like "<version>:<base64 key>"`)
+	}
+	version, err := strconv.ParseUint(v, 10, 8)
+	if err != nil || version == 0 {
+		return FieldKey{}, fmt.Errorf("field key version %q must be 1 to 255", v)
+	}
+	key, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return FieldKey{}, fmt.Errorf("field key %d is not valid base64: %w", version, err)
+	}
+	k := FieldKey{Version: uint8(version), Key: key}
+	if _, err := k.aead(); err != nil {
+		return FieldKey{}, err
+	}
+	return k, nil
+}
+
+func (k FieldKey) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.Key)
+	if err != nil {
+		return nil, fmt.Errorf("field key %d: %w", k.Version, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// fieldKeysFor is the KeyProvider a Config describes, nil if it has none
+func fieldKeysFor(cfg Config) (KeyProvider, error) {
+	if cfg.FieldKeys != nil {
+		return cfg.FieldKeys, nil
+	}
+	if cfg.FieldEncryptionKey == "" {
+		if cfg.FieldIndexKey != "" {
+			return nil, errors.New("field_index_key is set without field_encryption_key")
+		}
+		return nil, nil
+	}
+
+	var keys []FieldKey
+	seen := map[uint8]bool{}
+	for _, s := range strings.Split(cfg.FieldEncryptionKey, ",") {
+		k, err := ParseFieldKey(s)
+		if err != nil {
+			return nil, err
+		}
+		if seen[k.Version] {
+			return nil, fmt.Errorf("field key version %d is listed twice", k.Version)
+		}
+		seen[k.Version] = true
+		keys = append(keys, k)
+	}
+
+	index, err := base64.StdEncoding.DecodeString(cfg.FieldIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("field_index_key is not valid base64: %w", err)
+	}
+	if len(index) < 16 {
+		return nil, errors.New("field_index_key must be set, 16 bytes or more")
+	}
+	return StaticKeys(index, keys...), nil
+}
+
+// FieldRef is where an encrypted value is stored: its column, as
+// "table.column", and the key of its row. Both are authenticated with the
+// value, so ciphertext copied to another row or column doesn't decrypt.
+type FieldRef struct {
+	Column string
+	Row    int64
+}
+
+// nationalIDColumn is where SetUserNationalID stores national IDs, by
+// user_telegram_id
+const nationalIDColumn = "user_national_ids.national_id"
+
+// fieldKeys is the DB's KeyProvider, ErrNoFieldKeys without one
+func (db *DB) fieldKeys() (KeyProvider, error) {
+	if db.keys == nil {
+		return nil, ErrNoFieldKeys
+	}
+	return db.keys, nil
+}
+
+// EncryptField encrypts plaintext with AES-GCM under the DB's current
+// key, for a column like a national ID that dumps and backups mustn't
+// expose. The result holds the key version, a random nonce and the
+// sealed value, so the same text encrypts differently every time and the
+// column can't be searched: look rows up by a BlindIndex column instead.
+func (db *DB) EncryptField(ref FieldRef, plaintext string) ([]byte, error) {
+	keys, err := db.fieldKeys()
+	if err != nil {
+		return nil, err
+	}
+	k, err := keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	return sealField(k, ref, []byte(plaintext))
+}
+
+// DecryptField decrypts what EncryptField stored at ref, with the key its
+// version names
+func (db *DB) DecryptField(ref FieldRef, ciphertext []byte) (string, error) {
+	if len(ciphertext) == 0 {
+		return "", fmt.Errorf("%w: empty value", ErrFieldDecrypt)
+	}
+	keys, err := db.fieldKeys()
+	if err != nil {
+		return "", err
+	}
+	k, err := keys.Key(ciphertext[0])
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrFieldDecrypt, err)
+	}
+	plaintext, err := openField(k, ref, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// fieldAAD is the data sealField authenticates besides the value: the
+// key version, the column and the row
+func fieldAAD(version uint8, ref FieldRef) []byte {
+	aad := append([]byte{version}, ref.Column...)
+	aad = append(aad, 0)
+	return binary.BigEndian.AppendUint64(aad, uint64(ref.Row))
+}
+
+// sealField encrypts to version | nonce | ciphertext and tag
+func sealField(k FieldKey, ref FieldRef, plaintext []byte) ([]byte, error) {
+	aead, err := k.aead()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out[0] = k.Version
+	if _, err := rand.Read(out[1:]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, out[1:], plaintext, fieldAAD(k.Version, ref)), nil
+}
+
+func openField(k FieldKey, ref FieldRef, ciphertext []byte) ([]byte, error) {
+	aead, err := k.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < 1+aead.NonceSize()+aead.Overhead() || ciphertext[0] != k.Version {
+		return nil, fmt.Errorf("%w: malformed value", ErrFieldDecrypt)
+	}
+	nonce := ciphertext[1 : 1+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, ciphertext[1+aead.NonceSize():], fieldAAD(k.Version, ref))
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong key, tampered value or another row's", ErrFieldDecrypt)
+	}
+	return plaintext, nil
+}
+
+// BlindIndex is the HMAC-SHA256 of s under the index key, stored next to
+// an encrypted column so rows can be found by exact value. Equal inputs
+// give equal indexes, so normalize first. The index key never rotates:
+// changing it means recomputing every index from the plaintext.
+func (db *DB) BlindIndex(s string) ([]byte, error) {
+	keys, err := db.fieldKeys()
+	if err != nil {
+		return nil, err
+	}
+	key, err := keys.IndexKey()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return mac.Sum(nil), nil
+}
+
+// NormalizeNationalID drops spaces and dashes and upper-cases the rest, so
+// "ab 123-456" and "AB123456" are the same ID
+func NormalizeNationalID(id string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, id))
+}
+
+// SetUserNationalID stores the user's national ID, normalized and
+// encrypted, with its blind index. An ID another user already has fails
+// with ErrDuplicate.
+func (db *DB) SetUserNationalID(ctx context.Context, telegramID int64, nationalID string) error {
+	nationalID = NormalizeNationalID(nationalID)
+	index, err := db.BlindIndex(nationalID)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := db.EncryptField(FieldRef{Column: nationalIDColumn, Row: telegramID}, nationalID)
+	if err != nil {
+		return err
+	}
+	return Translate(db.Q.SetUserNationalID(ctx, SetUserNationalIDParams{
+		UserTelegramID:  telegramID,
+		NationalID:      ciphertext,
+		NationalIDIndex: index,
+	}))
+}
+
+// GetUserNationalID returns the user's national ID, decrypted;
+// ErrFieldDecrypt if the DB's keys don't open it
+func (db *DB) GetUserNationalID(ctx context.Context, telegramID int64) (string, error) {
+	ciphertext, err := db.Q.GetUserNationalID(ctx, telegramID)
+	if err != nil {
+		return "", err
+	}
+	return db.DecryptField(FieldRef{Column: nationalIDColumn, Row: telegramID}, ciphertext)
+}
+
+// GetUserByNationalID finds the user with that national ID, in any of the
+// forms NormalizeNationalID accepts
+func (db *DB) GetUserByNationalID(ctx context.Context, nationalID string) (User, error) {
+	index, err := db.BlindIndex(NormalizeNationalID(nationalID))
+	if err != nil {
+		return User{}, err
+	}
+	return db.Q.GetUserByNationalIDIndex(ctx, index)
+}
+
+// Rows re-encrypted per transaction by RotateFieldKeys
+const rotateBatchSize = 500
+
+// RotateFieldKeys re-encrypts every value written with oldKey under newKey
+// and returns how many it changed. It works in batches of a few hundred
+// rows, a transaction each, so it can run on a live database and be
+// started again after a failure; values already on another version are
+// left alone. Blind indexes don't change. To rotate:
+//
+//  1. put the new key first in Config.FieldEncryptionKey, keeping the old
+//     one after it, and restart, so new values use the new key
+//  2. run RotateFieldKeys
+//  3. remove the old key
+func RotateFieldKeys(ctx context.Context, db *DB, oldKey, newKey FieldKey) (int, error) {
+	if oldKey.Version == newKey.Version {
+		return 0, fmt.Errorf("old and new field keys are both version %d", oldKey.Version)
+	}
+	if _, err := newKey.aead(); err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	after := int64(math.MinInt64)
+	for {
+		var rows []ListNationalIDCiphertextsRow
+		n := 0
+		err := db.InTx(ctx, func(tx *Tx) error {
+			var err error
+			rows, err = tx.ListNationalIDCiphertexts(ctx, ListNationalIDCiphertextsParams{
+				AfterTelegramID: after,
+				Limit:           rotateBatchSize,
+			})
+			if err != nil {
+				return err
+			}
+			n = 0
+			for _, r := range rows {
+				if len(r.Ciphertext) == 0 || r.Ciphertext[0] != oldKey.Version {
+					continue
+				}
+				ref := FieldRef{Column: nationalIDColumn, Row: r.UserTelegramID}
+				plaintext, err := openField(oldKey, ref, r.Ciphertext)
+				if err != nil {
+					return fmt.Errorf("user %d: %w", r.UserTelegramID, err)
+				}
+				ciphertext, err := sealField(newKey, ref, plaintext)
+				if err != nil {
+					return err
+				}
+				changed, err := tx.ReplaceNationalIDCiphertext(ctx, ReplaceNationalIDCiphertextParams{
+					NewCiphertext:  ciphertext,
+					UserTelegramID: r.UserTelegramID,
+					OldCiphertext:  r.Ciphertext,
+				})
+				if err != nil {
+					return err
+				}
+				n += int(changed)
+			}
+			return nil
+		})
+		if err != nil {
+			return rotated, fmt.Errorf("failed to rotate field keys: %w", err)
+		}
+		rotated += n
+
+		if len(rows) < rotateBatchSize {
+			return rotated, nil
+		}
+		after = rows[len(rows)-1].UserTelegramID
+	}
+}
@@ -0,0 +1,146 @@
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// testKeys is a KeyProvider the test can swap keys in and out of
+type testKeys struct {
+	keys []database.FieldKey // The first encrypts
+}
+
+func (k *testKeys) CurrentKey() (database.FieldKey, error) { return k.keys[0], nil }
+func (k *testKeys) IndexKey() ([]byte, error)              { return bytes.Repeat([]byte{7}, 32), nil }
+
+func (k *testKeys) Key(version uint8) (database.FieldKey, error) {
+	for _, key := range k.keys {
+		if key.Version == version {
+			return key, nil
+		}
+	}
+	return database.FieldKey{}, fmt.Errorf("no key %d", version)
+}
+
+func fieldKey(version uint8, b byte) database.FieldKey {
+	return database.FieldKey{Version: version, Key: bytes.Repeat([]byte{b}, 32)}
+}
+
+func newUsers(t *testing.T, db *database.DB, ids ...int64) {
+	t.Helper()
+	for _, id := range ids {
+		if _, err := db.Q.CreateUser(context.Background(), database.CreateUserParams{TelegramID: id, FirstName: "user"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNationalIDRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	keys := &testKeys{keys: []database.FieldKey{fieldKey(1, 1)}}
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { c.FieldKeys = keys }})
+	newUsers(t, db, 1, 2)
+
+	if err := db.SetUserNationalID(ctx, 1, "ab 123-456"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db.GetUserNationalID(ctx, 1); err != nil || got != "AB123456" {
+		t.Errorf("GetUserNationalID: %q, %v; want AB123456", got, err)
+	}
+	stored, err := db.Q.GetUserNationalID(ctx, 1)
+	if err != nil || bytes.Contains(stored, []byte("AB123456")) {
+		t.Errorf("stored %q, %v; want ciphertext", stored, err)
+	}
+
+	// Found through the blind index, in any form NormalizeNationalID takes
+	u, err := db.GetUserByNationalID(ctx, "AB123456")
+	if err != nil || u.TelegramID != 1 {
+		t.Errorf("GetUserByNationalID: user %d, %v; want user 1", u.TelegramID, err)
+	}
+	if _, err := db.GetUserByNationalID(ctx, "AB000000"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("an unknown ID: %v, want ErrNotFound", err)
+	}
+	if err := db.SetUserNationalID(ctx, 2, "AB123456"); !errors.Is(err, database.ErrDuplicate) {
+		t.Errorf("another user's ID: %v, want ErrDuplicate", err)
+	}
+
+	// Ciphertext moved to another row doesn't decrypt there
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		return tx.SetUserNationalID(ctx, database.SetUserNationalIDParams{UserTelegramID: 2, NationalID: stored, NationalIDIndex: []byte("elsewhere")})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetUserNationalID(ctx, 2); !errors.Is(err, database.ErrFieldDecrypt) {
+		t.Errorf("user 1's ciphertext on user 2: %v, want ErrFieldDecrypt", err)
+	}
+	if _, err := db.DecryptField(database.FieldRef{Column: "users.email", Row: 1}, stored); !errors.Is(err, database.ErrFieldDecrypt) {
+		t.Errorf("the ciphertext read as another column: %v, want ErrFieldDecrypt", err)
+	}
+
+	// The same version with other key bytes
+	keys.keys = []database.FieldKey{fieldKey(1, 9)}
+	if _, err := db.GetUserNationalID(ctx, 1); !errors.Is(err, database.ErrFieldDecrypt) {
+		t.Errorf("with the wrong key: %v, want ErrFieldDecrypt", err)
+	}
+}
+
+func TestFieldKeysPerDB(t *testing.T) {
+	ctx := context.Background()
+	withKeys := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		c.FieldKeys = database.StaticKeys(bytes.Repeat([]byte{1}, 32), fieldKey(1, 1))
+	}})
+	without := dbtest.NewTestDB(t) // Opened after, and mustn't pick up the other's keys
+	newUsers(t, without, 1)
+
+	if err := without.SetUserNationalID(ctx, 1, "AB123456"); !errors.Is(err, database.ErrNoFieldKeys) {
+		t.Errorf("a DB without keys: %v, want ErrNoFieldKeys", err)
+	}
+	sealed, err := withKeys.EncryptField(database.FieldRef{Column: "t.c", Row: 1}, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := without.DecryptField(database.FieldRef{Column: "t.c", Row: 1}, sealed); !errors.Is(err, database.ErrNoFieldKeys) {
+		t.Errorf("decrypting without keys: %v, want ErrNoFieldKeys", err)
+	}
+}
+
+func TestRotateFieldKeys(t *testing.T) {
+	ctx := context.Background()
+	v1, v2 := fieldKey(1, 1), fieldKey(2, 2)
+	keys := &testKeys{keys: []database.FieldKey{v1}}
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { c.FieldKeys = keys }})
+	newUsers(t, db, 1, 2, 3)
+	for id := int64(1); id <= 2; id++ {
+		if err := db.SetUserNationalID(ctx, id, fmt.Sprintf("ID%d", id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Step 1: the new key first, so new values are already on it
+	keys.keys = []database.FieldKey{v2, v1}
+	if err := db.SetUserNationalID(ctx, 3, "ID3"); err != nil {
+		t.Fatal(err)
+	}
+	n, err := database.RotateFieldKeys(ctx, db, v1, v2)
+	if err != nil || n != 2 {
+		t.Fatalf("RotateFieldKeys: %d, %v; want the 2 values on v1", n, err)
+	}
+
+	// Step 3: v1 is gone and everything still decrypts
+	keys.keys = []database.FieldKey{v2}
+	for id := int64(1); id <= 3; id++ {
+		if got, err := db.GetUserNationalID(ctx, id); err != nil || got != fmt.Sprintf("ID%d", id) {
+			t.Errorf("user %d after rotating: %q, %v", id, got, err)
+		}
+	}
+	if n, err := database.RotateFieldKeys(ctx, db, v1, v2); err != nil || n != 0 {
+		t.Errorf("rotating again: %d, %v; want nothing left to do", n, err)
+	}
+}
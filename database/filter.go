@@ -0,0 +1,299 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSort is returned by ListUsersWhere for a sort column that
+// isn't in UserSortColumns. Like ErrInvalidCursor, it's the client's
+// fault, so answer it with a 400.
+var ErrInvalidSort = errors.New("invalid sort column")
+
+// UserFilter narrows ListUsersWhere, for the combinations of optional
+// criteria a fixed sqlc query can't cover. A field left at its zero value
+// matches every user; the ones that are set must all match.
+type UserFilter struct {
+	Status          Status          // Exactly this status
+	Language        string          // Exactly this language code
+	FirstNamePrefix string          // First name starting with this, ignoring case
+	UsernamePrefix  string          // Username starting with this, ignoring case
+	Email           string          // This address, ignoring case, as GetUserByEmail matches it
+	ReferFromID     int64           // Referred by this user
+	IDs             []int64         // One of these IDs; nil for any, empty for none
+	MinBalanceGame  sql.Null[Money] // balance_game at least this
+	MaxBalanceGame  sql.Null[Money] // balance_game at most this
+	CreatedAfter    time.Time       // Signed up at or after this
+	CreatedBefore   time.Time       // Signed up before this
+	IncludeDeleted  bool            // Include soft-deleted users, who are left out otherwise
+}
+
+// UserSort orders ListUsersWhere by Column, then by id, which breaks ties
+// so that pages don't skip or repeat rows. Column "" is "id".
+type UserSort struct {
+	Column string // One of UserSortColumns
+	Desc   bool
+}
+
+// UserSortColumns are the columns ListUsersWhere sorts by. NULL usernames
+// and balances sort as "" and 0, on every dialect alike.
+var UserSortColumns = []string{"id", "telegram_id", "first_name", "username", "balance_game", "created_at"}
+
+// userSortColumn is how ListUsersWhere sorts by a column and resumes after
+// a row: expr is what ORDER BY and the keyset comparison use, given the
+// dialect's timeOrder, key the row's value of it, and after a pointer for
+// DecodeCursor to fill with one
+type userSortColumn struct {
+	expr  func(timeOrder string) string
+	param func(timeOrder, placeholder string) string
+	key   func(User) any
+	after func() any
+}
+
+var userSortColumns = map[string]userSortColumn{
+	"telegram_id":  plainSortColumn("telegram_id", func(u User) any { return u.TelegramID }, func() any { return new(int64) }),
+	"first_name":   plainSortColumn("first_name", func(u User) any { return u.FirstName }, func() any { return new(string) }),
+	"username":     plainSortColumn("COALESCE(username, '')", func(u User) any { return u.Username.V }, func() any { return new(string) }),
+	"balance_game": plainSortColumn("COALESCE(balance_game, 0)", func(u User) any { return int64(u.BalanceGame.V) }, func() any { return new(int64) }),
+	"created_at": {
+		// A NULL sorts as the zero time.Time, which is what its key is
+		expr: func(timeOrder string) string {
+			return fmt.Sprintf("COALESCE(%s, %s)", fmt.Sprintf(timeOrder, "created_at"), fmt.Sprintf(timeOrder, "'0001-01-01 00:00:00'"))
+		},
+		param: func(timeOrder, placeholder string) string { return fmt.Sprintf(timeOrder, placeholder) },
+		key:   func(u User) any { return u.CreatedAt.V },
+		after: func() any { return new(time.Time) },
+	},
+}
+
+func plainSortColumn(expr string, key func(User) any, after func() any) userSortColumn {
+	return userSortColumn{
+		expr:  func(string) string { return expr },
+		param: func(_, placeholder string) string { return placeholder },
+		key:   key,
+		after: after,
+	}
+}
+
+// ListUsersWhere returns a page of the users matching filter, in sort's
+// order, the same way as UsersByCreatedAt: pass "" as cursor for the first
+// page and the previous page's NextCursor after that. The SQL is built
+// from an allow-list of columns, with every value a parameter, and rows
+// scan into the generated User. A cursor only continues the sort it came
+// from, with ErrInvalidCursor otherwise; keep the filter the same too.
+// Each combination is a query of its own, so index the columns that the
+// common filters and sorts use.
+func (db *DB) ListUsersWhere(ctx context.Context, filter UserFilter, sort UserSort, cursor string, pageSize int64) (Page[User], error) {
+	d := defaultDialect()
+	column := sort.Column
+	if column == "" {
+		column = "id"
+	}
+	col, byColumn := userSortColumns[column] // Not for "id", which is the keyset on its own
+	if !byColumn && column != "id" {
+		return Page[User]{}, fmt.Errorf("%w %q (want one of %s)", ErrInvalidSort, sort.Column, strings.Join(UserSortColumns, ", "))
+	}
+	sortKey := column
+	if sort.Desc {
+		sortKey += " desc"
+	}
+
+	var (
+		afterSort string
+		afterID   int64
+		after     = []any{&afterSort, &afterID}
+		afterKey  any
+	)
+	if byColumn {
+		afterKey = col.after()
+		after = []any{&afterSort, afterKey, &afterID}
+	}
+
+	return Paginate(db, cursor, pageSize, after,
+		func(limit int64) ([]User, error) {
+			if cursor != "" && afterSort != sortKey {
+				return nil, fmt.Errorf("%w: for sorting by %s, not %s", ErrInvalidCursor, afterSort, sortKey)
+			}
+			q := &queryBuilder{numbered: d.numberedParams}
+			where := filter.where(q, d)
+
+			order, cmp := "ASC", ">"
+			if sort.Desc {
+				order, cmp = "DESC", "<"
+			}
+			var orderBy string
+			if byColumn {
+				expr := col.expr(d.timeOrder)
+				orderBy = expr + " " + order + ", id " + order
+				if cursor != "" {
+					where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)",
+						expr, cmp, col.param(d.timeOrder, q.param(derefCursor(afterKey))), q.param(afterID)))
+				}
+			} else {
+				orderBy = "id " + order
+				if cursor != "" {
+					where = append(where, "id "+cmp+" "+q.param(afterID))
+				}
+			}
+
+			query := "SELECT " + userColumns + " FROM users"
+			if len(where) > 0 {
+				query += " WHERE " + strings.Join(where, " AND ")
+			}
+			query += " ORDER BY " + orderBy + " LIMIT " + q.param(limit)
+			users, err := db.queryUsers(ctx, query, q.args...)
+			if err != nil {
+				return nil, Translate(err)
+			}
+			return users, nil
+		},
+		func(u User) []any {
+			if byColumn {
+				return []any{sortKey, col.key(u), u.ID}
+			}
+			return []any{sortKey, u.ID}
+		},
+	)
+}
+
+// where turns the filter into conditions, every value a parameter of q
+func (f UserFilter) where(q *queryBuilder, d *dialect) []string {
+	var where []string
+	if !f.IncludeDeleted {
+		where = append(where, "deleted_at IS NULL")
+	}
+	if f.Status != "" {
+		where = append(where, "status = "+q.param(f.Status))
+	}
+	if f.Language != "" {
+		where = append(where, "language = "+q.param(f.Language))
+	}
+	if f.FirstNamePrefix != "" {
+		where = append(where, `lower(first_name) LIKE lower(`+q.param(likePrefix(f.FirstNamePrefix))+`) ESCAPE '\'`)
+	}
+	if f.UsernamePrefix != "" {
+		where = append(where, `lower(username) LIKE lower(`+q.param(likePrefix(f.UsernamePrefix))+`) ESCAPE '\'`)
+	}
+	if f.Email != "" {
+		where = append(where, "lower(email) = lower("+q.param(f.Email)+")")
+	}
+	if f.ReferFromID != 0 {
+		where = append(where, "refer_from_id = "+q.param(f.ReferFromID))
+	}
+	if f.IDs != nil {
+		if len(f.IDs) == 0 {
+			where = append(where, "1 = 0") // IN () isn't SQL
+		} else {
+			ids := make([]string, len(f.IDs))
+			for i, id := range f.IDs {
+				ids[i] = q.param(id)
+			}
+			where = append(where, "id IN ("+strings.Join(ids, ", ")+")")
+		}
+	}
+	if f.MinBalanceGame.Valid {
+		where = append(where, "balance_game >= "+q.param(f.MinBalanceGame.V))
+	}
+	if f.MaxBalanceGame.Valid {
+		where = append(where, "balance_game <= "+q.param(f.MaxBalanceGame.V))
+	}
+	if !f.CreatedAfter.IsZero() {
+		where = append(where, fmt.Sprintf(d.timeOrder, "created_at")+" >= "+fmt.Sprintf(d.timeOrder, q.param(f.CreatedAfter.UTC())))
+	}
+	if !f.CreatedBefore.IsZero() {
+		where = append(where, fmt.Sprintf(d.timeOrder, "created_at")+" < "+fmt.Sprintf(d.timeOrder, q.param(f.CreatedBefore.UTC())))
+	}
+	return where
+}
+
+// likePrefix is a LIKE pattern matching s literally at the start
+func likePrefix(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s) + "%"
+}
+
+// derefCursor is the value behind one of userSortColumn's after pointers
+func derefCursor(p any) any {
+	switch p := p.(type) {
+	case *int64:
+		return *p
+	case *string:
+		return *p
+	case *time.Time:
+		return p.UTC()
+	}
+	panic(fmt.Sprintf("database: unsupported cursor field type %T", p))
+}
+
+// queryBuilder collects the arguments of SQL built at run time, naming
+// each with the dialect's placeholder
+type queryBuilder struct {
+	numbered bool
+	args     []any
+}
+
+func (q *queryBuilder) param(v any) string {
+	q.args = append(q.args, v)
+	if q.numbered {
+		return "$" + strconv.Itoa(len(q.args))
+	}
+	return "?"
+}
+
+// Param is the placeholder of the nth bind parameter, from 1, in this
+// build's SQL: ? on SQLite, $n on PostgreSQL
+func Param(n int) string {
+	if defaultDialect().numberedParams {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// The columns of User, in the order sqlc scans them
+const userColumns = "id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version"
+
+// queryUsers runs a query built at run time that selects userColumns,
+// through the same middleware as db.Q, replicas included
+func (db *DB) queryUsers(ctx context.Context, query string, args ...any) ([]User, error) {
+	rows, err := db.wrap(db.routeReads(db.Conn)).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []User{}
+	for rows.Next() {
+		i, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, i)
+	}
+	return users, rows.Err()
+}
+
+// scanUser scans a row of userColumns into a User
+func scanUser(rows *sql.Rows) (User, error) {
+	var i User
+	err := rows.Scan(
+		&i.ID,
+		&i.TelegramID,
+		&i.FirstName,
+		&i.Username,
+		&i.BalanceGame,
+		&i.BalanceChats,
+		&i.Status,
+		&i.Language,
+		&i.ReferFromID,
+		&i.LastStreakClaimAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
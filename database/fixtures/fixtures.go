@@ -0,0 +1,561 @@
+// Package fixtures loads the rows a test starts with, from YAML or Go,
+// with rows referring to each other by label rather than by an id the
+// database hands out:
+//
+//	users:
+//	  alice:
+//	    telegram_id: 1001
+//	    first_name: Alice
+//	  bob:
+//	    telegram_id: 1002
+//	    refer_from_id: $alice     # alice's id
+//	groups:
+//	  chat:
+//	    telegram_id: -1001
+//	    title: Chat
+//	user_group:
+//	  alice_in_chat:
+//	    user_telegram_id: $alice  # alice's telegram_id, which the foreign key references
+//	    group_telegram_id: $chat
+//	    balance: 1000             # 10.00: balances are in cents (database.Money)
+//
+//	set, err := fixtures.Files("testdata/users.yaml")
+//	loaded, err := fixtures.Load(ctx, db, set)
+//	user, err := db.Q.GetUserByID(ctx, loaded.ID("bob"))
+//
+// A string "$label" is the column the foreign key on that column
+// references, in the labelled row, or the row's primary key if there's no
+// foreign key; "$label.column" names the column. "$$" starts a string
+// that begins with "$". Labels are unique across a Set, whatever the
+// table. Load inserts every row in one transaction, each after the rows
+// it refers to and after the rows of the tables its table references, so
+// the order in the file doesn't matter.
+//
+// In Go, a row is a Row or a sqlc model, whose json tags are the column
+// names:
+//
+//	set := fixtures.New().
+//		Add("users", "alice", database.User{TelegramID: 1001, FirstName: "Alice"}).
+//		Add("users", "bob", database.User{TelegramID: 1002}, fixtures.Row{"refer_from_id": "$alice"})
+//
+// A model's fields are taken as they are, "$" or not; its zero fields are
+// left out where the column has a default, as crud.Repo.Create does, and
+// the Rows after it add references or override fields.
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"your-project/database"
+)
+
+// Row is one row's columns, by name. Strings starting with "$" refer to
+// another row; maps and slices are stored as JSON.
+type Row map[string]any
+
+// Set is rows to load, each with a label. Add records the first error it
+// finds, and Load returns it.
+type Set struct {
+	rows   []row
+	labels map[string]int // Index into rows
+	err    error
+}
+
+type row struct {
+	table, label string
+	columns      []string
+	values       []any
+	literal      []bool // From a model: never a reference
+	zero         []bool // From a model and its zero value: left out where the column has a default
+}
+
+// New returns an empty Set
+func New() *Set {
+	return &Set{labels: map[string]int{}}
+}
+
+// Add adds a row to table under label: a Row, or a struct with json tags
+// such as a sqlc model. The Rows in with are set over it, in order.
+func (s *Set) Add(table, label string, model any, with ...Row) *Set {
+	if s.err != nil {
+		return s
+	}
+	r := row{table: table, label: label}
+	switch m := model.(type) {
+	case Row:
+		r.set(m)
+	case map[string]any:
+		r.set(m)
+	case nil:
+	default:
+		if err := r.setModel(m); err != nil {
+			s.err = fmt.Errorf("fixture %s: %w", label, err)
+			return s
+		}
+	}
+	for _, w := range with {
+		r.set(w)
+	}
+	if err := s.add(r); err != nil {
+		s.err = err
+	}
+	return s
+}
+
+func (s *Set) add(r row) error {
+	if r.label == "" || strings.ContainsAny(r.label, ". $") {
+		return fmt.Errorf("fixture %q: labels can't be empty or have dots, spaces or $", r.label)
+	}
+	if i, ok := s.labels[r.label]; ok {
+		return fmt.Errorf("fixture %s is in %s and %s", r.label, s.rows[i].table, r.table)
+	}
+	s.labels[r.label] = len(s.rows)
+	s.rows = append(s.rows, r)
+	return nil
+}
+
+// set puts m's columns in r, replacing what's there, in name order
+func (r *row) set(m map[string]any) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		r.put(name, m[name], false, false)
+	}
+}
+
+func (r *row) put(column string, v any, literal, zero bool) {
+	if i := slices.Index(r.columns, column); i >= 0 {
+		r.values[i], r.literal[i], r.zero[i] = v, literal, zero
+		return
+	}
+	r.columns = append(r.columns, column)
+	r.values = append(r.values, v)
+	r.literal = append(r.literal, literal)
+	r.zero = append(r.zero, zero)
+}
+
+// setModel puts the fields of a struct in r, by their json tags
+func (r *row) setModel(model any) error {
+	v := reflect.ValueOf(model)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("a row is a fixtures.Row or a struct, not %T", model)
+	}
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		r.put(name, v.Field(i).Interface(), true, v.Field(i).IsZero())
+	}
+	return nil
+}
+
+// Parse reads YAML fixtures: a mapping of tables to a mapping of labels
+// to each row's columns
+func Parse(data []byte) (*Set, error) {
+	s := New()
+	if err := s.parse(data, "fixtures"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Files reads YAML fixture files into one Set, so rows in one can refer
+// to rows in another
+func Files(paths ...string) (*Set, error) {
+	s := New()
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.parse(data, p); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// parse adds the rows in data, in the order they're written; name is
+// for errors
+func (s *Set) parse(data []byte, name string) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil // An empty file
+	}
+	tables := doc.Content[0]
+	if tables.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s:%d: want a mapping of table names to rows", name, tables.Line)
+	}
+	for i := 0; i+1 < len(tables.Content); i += 2 {
+		table, rows := tables.Content[i].Value, tables.Content[i+1]
+		if rows.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s:%d: want a mapping of labels to the rows of %s", name, rows.Line, table)
+		}
+		for j := 0; j+1 < len(rows.Content); j += 2 {
+			label, cols := rows.Content[j].Value, rows.Content[j+1]
+			var m Row
+			if err := cols.Decode(&m); err != nil {
+				return fmt.Errorf("%s:%d: %s: %w", name, cols.Line, label, err)
+			}
+			r := row{table: table, label: label}
+			r.set(m)
+			if err := s.add(r); err != nil {
+				return fmt.Errorf("%s:%d: %w", name, rows.Content[j].Line, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reference reads the label and column of a "$label.column" value; ok is
+// false for anything else
+func reference(v any, literal bool) (label, column string, ok bool) {
+	s, isString := v.(string)
+	if literal || !isString || !strings.HasPrefix(s, "$") || strings.HasPrefix(s, "$$") {
+		return "", "", false
+	}
+	label, column, _ = strings.Cut(s[1:], ".")
+	return label, column, true
+}
+
+// Loaded is the rows Load inserted, as the database returned them
+type Loaded struct {
+	rows map[string]loadedRow
+}
+
+type loadedRow struct {
+	table  database.TableInfo
+	values map[string]any
+}
+
+// ID is the primary key of the row labelled label. It panics if there's
+// no such row or its key isn't one integer column, which is a mistake in
+// the test.
+func (l *Loaded) ID(label string) int64 {
+	r := l.row(label)
+	pk := r.table.PrimaryKey()
+	if len(pk) != 1 {
+		panic(fmt.Sprintf("fixtures: %s has no primary key of one column", r.table.Name))
+	}
+	switch id := r.values[pk[0]].(type) {
+	case int64:
+		return id
+	case int32:
+		return int64(id)
+	}
+	panic(fmt.Sprintf("fixtures: %s's key is a %T, use Value", label, r.values[pk[0]]))
+}
+
+// Value is a column of the row labelled label, as the driver read it
+// back. It panics if there's no such row or column.
+func (l *Loaded) Value(label, column string) any {
+	r := l.row(label)
+	v, ok := r.values[column]
+	if !ok {
+		panic(fmt.Sprintf("fixtures: %s has no column %s", r.table.Name, column))
+	}
+	return v
+}
+
+func (l *Loaded) row(label string) loadedRow {
+	r, ok := l.rows[label]
+	if !ok {
+		panic(fmt.Sprintf("fixtures: no row labelled %s", label))
+	}
+	return r
+}
+
+// Load inserts s into db in one transaction, and returns the rows as
+// inserted, defaults filled in; nothing is inserted if it fails
+func Load(ctx context.Context, db *database.DB, s *Set) (*Loaded, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	schema, err := db.Introspect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.order(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Loaded{rows: map[string]loadedRow{}}
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		for _, i := range order {
+			r := s.rows[i]
+			if err := l.insert(ctx, tx.DBTX(), schema, r); err != nil {
+				return fmt.Errorf("fixture %s (%s): %w", r.label, r.table, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// order is the order s's rows go in: each after the rows it refers to
+// and the rows of the tables its table has a foreign key to, otherwise in
+// the order they were added
+func (s *Set) order(schema database.SchemaInfo) ([]int, error) {
+	deps := make([][]int, len(s.rows))
+	for i, r := range s.rows {
+		t, ok := schema.Table(r.table)
+		if !ok || t.View {
+			return nil, fmt.Errorf("fixture %s: no table %s", r.label, r.table)
+		}
+		for c, v := range r.values {
+			label, _, ok := reference(v, r.literal[c])
+			if !ok {
+				continue
+			}
+			j, ok := s.labels[label]
+			if !ok {
+				return nil, fmt.Errorf("fixture %s: %s refers to no fixture %s", r.label, r.columns[c], label)
+			}
+			if j == i {
+				return nil, fmt.Errorf("fixture %s: %s refers to its own row", r.label, r.columns[c])
+			}
+			deps[i] = append(deps[i], j)
+		}
+		for j, p := range s.rows {
+			if p.table != r.table && slices.ContainsFunc(t.ForeignKeys, func(fk database.ForeignKeyInfo) bool { return fk.RefTable == p.table }) {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+
+	done := make([]bool, len(s.rows))
+	order := make([]int, 0, len(s.rows))
+	for len(order) < len(s.rows) {
+		progress := false
+		for i := range s.rows {
+			if !done[i] && !slices.ContainsFunc(deps[i], func(j int) bool { return !done[j] }) {
+				done[i], progress = true, true
+				order = append(order, i)
+			}
+		}
+		if !progress {
+			var stuck []string
+			for i, r := range s.rows {
+				if !done[i] {
+					stuck = append(stuck, r.label)
+				}
+			}
+			return nil, fmt.Errorf("fixtures %s refer to each other, or their tables do", strings.Join(stuck, ", "))
+		}
+	}
+	return order, nil
+}
+
+// insert inserts r, its references resolved against the rows before it
+func (l *Loaded) insert(ctx context.Context, q database.DBTX, schema database.SchemaInfo, r row) error {
+	t, _ := schema.Table(r.table)
+	pk := t.PrimaryKey()
+	var cols, params []string
+	var args []any
+	for i, name := range r.columns {
+		c := slices.IndexFunc(t.Columns, func(c database.ColumnInfo) bool { return c.Name == name })
+		if c < 0 {
+			return fmt.Errorf("%s has no column %s", r.table, name)
+		}
+		col := t.Columns[c]
+		if r.zero[i] && (col.Default != nil || col.Generated || isRowID(pk, name, r.values[i])) {
+			continue
+		}
+		v, err := l.value(t, name, r.values[i], r.literal[i])
+		if err != nil {
+			return err
+		}
+		args = append(args, v)
+		cols = append(cols, quote(name))
+		params = append(params, database.Param(len(args)))
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s DEFAULT VALUES RETURNING *", quote(r.table))
+	if len(cols) > 0 {
+		query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *",
+			quote(r.table), strings.Join(cols, ", "), strings.Join(params, ", "))
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return database.Translate(err)
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	vals := make([]any, len(names))
+	ptrs := make([]any, len(names))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return err
+	}
+	values := make(map[string]any, len(names))
+	for i, name := range names {
+		if b, ok := vals[i].([]byte); ok && !isBlob(t, name) {
+			vals[i] = string(b) // Text some drivers hand over as bytes
+		}
+		values[name] = vals[i]
+	}
+	l.rows[r.label] = loadedRow{table: t, values: values}
+	return rows.Close()
+}
+
+// value is what goes into column for v: a reference resolved, a map or
+// slice as JSON
+func (l *Loaded) value(t database.TableInfo, column string, v any, literal bool) (any, error) {
+	if label, col, ok := reference(v, literal); ok {
+		return l.resolve(t, column, label, col)
+	}
+	switch v := v.(type) {
+	case string:
+		if !literal && strings.HasPrefix(v, "$$") {
+			return v[1:], nil
+		}
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+	return v, nil
+}
+
+// resolve finds the value a reference from t.column to label stands for
+func (l *Loaded) resolve(t database.TableInfo, column, label, col string) (any, error) {
+	target := l.rows[label] // Inserted already, order saw to that
+	if col == "" {
+		i := slices.IndexFunc(t.ForeignKeys, func(fk database.ForeignKeyInfo) bool {
+			return slices.Equal(fk.Columns, []string{column})
+		})
+		switch pk := target.table.PrimaryKey(); {
+		case i >= 0 && t.ForeignKeys[i].RefTable != target.table.Name:
+			return nil, fmt.Errorf("%s references %s, but %s is in %s", column, t.ForeignKeys[i].RefTable, label, target.table.Name)
+		case i >= 0:
+			col = t.ForeignKeys[i].RefColumns[0]
+		case len(pk) == 1:
+			col = pk[0]
+		default:
+			return nil, fmt.Errorf("%s has no foreign key and %s no primary key of one column, name the column: $%s.column", column, target.table.Name, label)
+		}
+	}
+	v, ok := target.values[col]
+	if !ok {
+		return nil, fmt.Errorf("%s refers to %s.%s, which %s doesn't have", column, label, col, target.table.Name)
+	}
+	return v, nil
+}
+
+// isRowID is whether column is the integer key the database numbers
+// itself, SQLite's rowid or a serial, and v the integer a model leaves
+// at zero for it
+func isRowID(pk []string, column string, v any) bool {
+	switch v.(type) {
+	case int, int64:
+		return len(pk) == 1 && pk[0] == column
+	}
+	return false
+}
+
+// isBlob is whether column holds bytes rather than text
+func isBlob(t database.TableInfo, column string) bool {
+	i := slices.IndexFunc(t.Columns, func(c database.ColumnInfo) bool { return c.Name == column })
+	if i < 0 {
+		return false
+	}
+	typ := strings.ToUpper(t.Columns[i].Type)
+	return strings.Contains(typ, "BLOB") || strings.Contains(typ, "BYTEA")
+}
+
+// Truncate deletes every row of tables, in one transaction, before the
+// rows of the tables they reference. Without tables, it's every table
+// but the package's own (SchemaInfo's Internal ones and seed_history):
+// the audit log and history tables keep their entries, which the deletes
+// add to. Keys carry on from where they were, so refer to rows by label.
+func Truncate(ctx context.Context, db *database.DB, tables ...string) error {
+	schema, err := db.Introspect(ctx)
+	if err != nil {
+		return err
+	}
+	var infos []database.TableInfo
+	if len(tables) == 0 {
+		for _, t := range schema.Tables {
+			if !t.View && !t.Internal && t.Name != "seed_history" {
+				infos = append(infos, t)
+			}
+		}
+	}
+	for _, name := range tables {
+		t, ok := schema.Table(name)
+		if !ok || t.View {
+			return fmt.Errorf("no table %s", name)
+		}
+		infos = append(infos, t)
+	}
+
+	// Children first: a table goes once no table left references it
+	var order []string
+	for len(infos) > 0 {
+		i := slices.IndexFunc(infos, func(t database.TableInfo) bool {
+			return !slices.ContainsFunc(infos, func(o database.TableInfo) bool {
+				return o.Name != t.Name && slices.ContainsFunc(o.ForeignKeys, func(fk database.ForeignKeyInfo) bool { return fk.RefTable == t.Name })
+			})
+		})
+		if i < 0 {
+			names := make([]string, len(infos))
+			for i, t := range infos {
+				names[i] = t.Name
+			}
+			return errors.New("tables " + strings.Join(names, ", ") + " reference each other, truncate them by hand")
+		}
+		order = append(order, infos[i].Name)
+		infos = slices.Delete(infos, i, i+1)
+	}
+
+	return db.InTx(ctx, func(tx *database.Tx) error {
+		for _, name := range order {
+			if _, err := tx.DBTX().ExecContext(ctx, "DELETE FROM "+quote(name)); err != nil {
+				return fmt.Errorf("failed to truncate %s: %w", name, database.Translate(err))
+			}
+		}
+		return nil
+	})
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Orphan is a row whose foreign key points at a parent row that doesn't
+// exist, as found by ForeignKeyCheck
+type Orphan struct {
+	Table   string   // Table holding the row
+	RowID   int64    // rowid of the row
+	Parent  string   // Table the key should point into
+	Columns []string // Columns of the broken key
+}
+
+// OrphanRepair tells ForeignKeyCheck what to do with the orphans it finds
+type OrphanRepair int
+
+const (
+	ReportOrphans OrphanRepair = iota // Only report them
+	DeleteOrphans                     // Delete the orphaned rows
+	NullOrphans                       // Set the broken key columns to NULL (fails on NOT NULL columns)
+)
+
+// ForeignKeyCheck finds rows whose foreign keys point nowhere. They can
+// only exist in SQLite databases written with foreign keys off: older
+// files, other tools, or a DSN turning them off. With DeleteOrphans or
+// NullOrphans every orphan is repaired in one transaction, and nothing is
+// changed if any repair fails. The orphans found are returned either way.
+//
+// PostgreSQL always enforces foreign keys, so it reports none.
+func (db *DB) ForeignKeyCheck(ctx context.Context, repair OrphanRepair) ([]Orphan, error) {
+	d := defaultDialect()
+	if d.foreignKeyCheck == nil {
+		return nil, nil
+	}
+
+	orphans, err := d.foreignKeyCheck(ctx, db.Conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	if repair == ReportOrphans || len(orphans) == 0 {
+		return orphans, nil
+	}
+
+	err = db.InTx(ctx, func(tx *Tx) error {
+		for _, o := range orphans {
+			// Only SQLite has orphans, so the placeholders and rowid are SQLite's
+			query := fmt.Sprintf("DELETE FROM %s WHERE rowid = ?", quoteIdent(o.Table))
+			if repair == NullOrphans {
+				set := make([]string, len(o.Columns))
+				for i, c := range o.Columns {
+					set[i] = quoteIdent(c) + " = NULL"
+				}
+				query = fmt.Sprintf("UPDATE %s SET %s WHERE rowid = ?", quoteIdent(o.Table), strings.Join(set, ", "))
+			}
+
+			if _, err := tx.tx.ExecContext(ctx, query, o.RowID); err != nil {
+				return fmt.Errorf("failed to repair orphan %d of %s: %w", o.RowID, o.Table, Translate(err))
+			}
+		}
+		return nil
+	})
+	return orphans, err
+}
+
+// IntegrityCheck reads the whole database looking for corruption and
+// returns the problems found, none when it's sound. It takes a while on a
+// big database. PostgreSQL has no check built in (see the amcheck
+// extension), so it returns an error there.
+func (db *DB) IntegrityCheck(ctx context.Context) ([]string, error) {
+	d := defaultDialect()
+	if d.integrityCheck == nil {
+		return nil, fmt.Errorf("%s has no integrity check here", d.name)
+	}
+	problems, err := d.integrityCheck(ctx, db.Conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	return problems, nil
+}
+
+// quoteIdent quotes a table or column name read from the database itself
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
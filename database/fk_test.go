@@ -0,0 +1,69 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestForeignKeys(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	newUsers(t, db, 1, 2)
+	if _, err := db.Q.CreateGroup(ctx, database.CreateGroupParams{TelegramID: 10}); err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []int64{1, 2} {
+		if _, err := db.Q.CreateUserGroup(ctx, database.CreateUserGroupParams{UserTelegramID: user, GroupTelegramID: 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	members := func() int {
+		t.Helper()
+		var n int
+		if err := db.DBTX().QueryRowContext(ctx, "SELECT count(*) FROM user_group").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if _, err := db.Q.CreateUserGroup(ctx, database.CreateUserGroupParams{UserTelegramID: 3, GroupTelegramID: 10}); !errors.Is(err, database.ErrForeignKey) {
+		t.Errorf("a membership of a missing user: %v, want ErrForeignKey", err)
+	}
+
+	// Users cascade to their memberships
+	if _, err := db.DBTX().ExecContext(ctx, "DELETE FROM users WHERE telegram_id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := members(); n != 1 {
+		t.Errorf("%d memberships after deleting a member, want 1", n)
+	}
+
+	// Groups with members can't be deleted
+	if _, err := db.Q.DeleteGroup(ctx, 10); !errors.Is(err, database.ErrForeignKey) {
+		t.Errorf("deleting a group with members: %v, want ErrForeignKey", err)
+	}
+	if n := members(); n != 1 {
+		t.Errorf("%d memberships after the refused delete, want 1", n)
+	}
+
+	// Categories cascade to their subtrees
+	parent, err := db.Q.CreateCategory(ctx, database.CreateCategoryParams{Name: "games"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := db.Q.CreateCategory(ctx, database.CreateCategoryParams{ParentID: sql.Null[int64]{V: parent.ID, Valid: true}, Name: "chess"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DBTX().ExecContext(ctx, "DELETE FROM categories WHERE parent_id IS NULL"); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := db.Q.ListCategoriesByName(ctx, child.Name); err != nil || len(found) != 0 {
+		t.Errorf("the child of a deleted category: %+v, %v; want it deleted too", found, err)
+	}
+}
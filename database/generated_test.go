@@ -0,0 +1,67 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestGeneratedNameNormalized(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	// sqlc leaves the column out of what's written
+	if _, ok := reflect.TypeFor[database.CreateCategoryParams]().FieldByName("NameNormalized"); ok {
+		t.Error("CreateCategoryParams has NameNormalized; the database maintains it")
+	}
+
+	c, err := db.Q.CreateCategory(ctx, database.CreateCategoryParams{Name: "  Board Games "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NameNormalized != "board games" {
+		t.Errorf("created with name %q: name_normalized %q, want board games", c.Name, c.NameNormalized)
+	}
+	found, err := db.Q.ListCategoriesByName(ctx, "BOARD GAMES")
+	if err != nil || len(found) != 1 || found[0].ID != c.ID {
+		t.Errorf("ListCategoriesByName: %+v, %v; want the category", found, err)
+	}
+
+	c, err = db.Q.RenameCategory(ctx, database.RenameCategoryParams{Name: "Puzzles", ID: c.ID})
+	if err != nil || c.NameNormalized != "puzzles" {
+		t.Errorf("renamed: name_normalized %q, %v; want puzzles", c.NameNormalized, err)
+	}
+	if found, err := db.Q.ListCategoriesByName(ctx, "board games"); err != nil || len(found) != 0 {
+		t.Errorf("the old name after renaming: %+v, %v; want nothing", found, err)
+	}
+	child, err := db.Q.CreateCategory(ctx, database.CreateCategoryParams{ParentID: sql.Null[int64]{V: c.ID, Valid: true}, Name: "Jigsaw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.Q.GetChildCategoryByName(ctx, database.GetChildCategoryByNameParams{ParentID: child.ParentID, Name: " jigsaw"})
+	if err != nil || got.ID != child.ID {
+		t.Errorf("GetChildCategoryByName: %+v, %v; want the child", got, err)
+	}
+
+	// Enough rows, with statistics, that PostgreSQL's planner wants the
+	// index too
+	for i := range 500 {
+		if _, err := db.Q.CreateCategory(ctx, database.CreateCategoryParams{Name: fmt.Sprint("category ", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.DBTX().ExecContext(ctx, "ANALYZE"); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := db.Explain(ctx, "ListCategoriesByName", "puzzles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbtest.AssertUsesIndex(t, plan, "idx_categories_name")
+	dbtest.AssertNoFullScan(t, plan)
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"your-project/database/nulls"
+)
+
+// GetOrCreateUser returns the user with telegramID, creating it from
+// defaults (whose TelegramID is ignored) if there's none; created reports
+// which. Callers racing on the same ID all get the same row and exactly
+// one of them creates it: the insert skips a conflicting row instead of
+// failing, and the lookup after it runs in the same transaction, which on
+// SQLite holds the write lock from the start. Username and email are
+// normalized as in DB.CreateUser.
+func GetOrCreateUser(ctx context.Context, db *DB, telegramID int64, defaults CreateUserParams) (user User, created bool, err error) {
+	err = db.inTx(ctx, true, nil, func(tx *Tx) error {
+		user, err = tx.CreateUserIfMissing(ctx, CreateUserIfMissingParams{
+			TelegramID:  telegramID,
+			FirstName:   defaults.FirstName,
+			Username:    nulls.String(defaults.Username.V),
+			Status:      defaults.Status,
+			Language:    defaults.Language,
+			ReferFromID: defaults.ReferFromID,
+			Email:       nulls.String(NormalizeEmail(defaults.Email.V)),
+		})
+		if err == nil {
+			created = true
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return Translate(err)
+		}
+		created = false
+		user, err = tx.GetUserByTelegramID(ctx, telegramID)
+		return err
+	})
+	if err != nil {
+		return User{}, false, fmt.Errorf("failed to get or create user %d: %w", telegramID, err)
+	}
+	return user, created, nil
+}
+
+// GetOrCreateGroup is GetOrCreateUser for groups
+func GetOrCreateGroup(ctx context.Context, db *DB, telegramID int64, defaults CreateGroupParams) (group Group, created bool, err error) {
+	err = db.inTx(ctx, true, nil, func(tx *Tx) error {
+		group, err = tx.CreateGroupIfMissing(ctx, CreateGroupIfMissingParams{
+			TelegramID: telegramID,
+			Title:      defaults.Title,
+		})
+		if err == nil {
+			created = true
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return Translate(err)
+		}
+		created = false
+		group, err = tx.GetGroupByTelegramID(ctx, telegramID)
+		return err
+	})
+	if err != nil {
+		return Group{}, false, fmt.Errorf("failed to get or create group %d: %w", telegramID, err)
+	}
+	return group, created, nil
+}
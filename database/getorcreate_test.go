@@ -0,0 +1,84 @@
+package database_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+	"your-project/database/nulls"
+)
+
+// race calls getOrCreate from 50 goroutines at once and returns the IDs
+// they got and how many reported creating the row
+func race(t *testing.T, getOrCreate func(i int) (int64, bool, error)) (map[int64]bool, int64) {
+	t.Helper()
+	var (
+		mu      sync.Mutex
+		ids     = map[int64]bool{}
+		created atomic.Int64
+		wg      sync.WaitGroup
+	)
+	start := make(chan struct{})
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			id, c, err := getOrCreate(i)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if c {
+				created.Add(1)
+			}
+			mu.Lock()
+			ids[id] = true
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return ids, created.Load()
+}
+
+func TestGetOrCreateUser(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	ids, created := race(t, func(i int) (int64, bool, error) {
+		u, c, err := database.GetOrCreateUser(ctx, db, 7, database.CreateUserParams{FirstName: "racer", Email: nulls.String(" Racer@Example.com")})
+		return u.ID, c, err
+	})
+	if len(ids) != 1 || created != 1 {
+		t.Errorf("%d distinct rows and %d creations from 50 callers, want one of each", len(ids), created)
+	}
+	if n, err := db.Q.CountUsersByStatus(ctx, database.StatusActive); err != nil || n != 1 {
+		t.Errorf("%d users, %v; want 1", n, err)
+	}
+
+	// An existing user keeps its own fields
+	u, c, err := database.GetOrCreateUser(ctx, db, 7, database.CreateUserParams{FirstName: "someone else"})
+	if err != nil || c || u.FirstName != "racer" || u.Email.V != "Racer@example.com" {
+		t.Errorf("the existing user: %+v, created %v, %v", u, c, err)
+	}
+}
+
+func TestGetOrCreateGroup(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	ids, created := race(t, func(i int) (int64, bool, error) {
+		g, c, err := database.GetOrCreateGroup(ctx, db, 100, database.CreateGroupParams{Title: nulls.String("chess")})
+		return g.ID, c, err
+	})
+	if len(ids) != 1 || created != 1 {
+		t.Errorf("%d distinct rows and %d creations from 50 callers, want one of each", len(ids), created)
+	}
+	if g, err := db.Q.GetGroupByTelegramID(ctx, 100); err != nil || g.Title.V != "chess" {
+		t.Errorf("the group: %+v, %v", g, err)
+	}
+}
@@ -0,0 +1,28 @@
+// Package grpcserver serves the users table over gRPC: the UserService of
+// proto/users/v1/users.proto, mapped to the generated queries. Its
+// interceptors run each unary call in a transaction of its own, which
+// commits when the call succeeds, and turn the package's typed errors
+// into gRPC status codes; StreamUsers streams large lists a row at a
+// time instead of loading them.
+//
+// The Go code for the proto is generated into usersv1 by buf, with
+// buf.yaml and buf.gen.yaml, and tagged grpc like the rest of the
+// package, so builds without the grpc dependencies skip it. After editing
+// the proto, regenerate it (buf, protoc-gen-go and protoc-gen-go-grpc on
+// the PATH) and build:
+//
+//	go generate ./database/grpcserver
+//	go get google.golang.org/grpc google.golang.org/protobuf
+//	go build -tags grpc ./database/cmd/grpcserver
+//
+// Wiring it into a server of your own:
+//
+//	gs := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(grpcserver.ErrorInterceptor(), grpcserver.TxInterceptor(db)),
+//		grpc.ChainStreamInterceptor(grpcserver.StreamErrorInterceptor()),
+//	)
+//	usersv1.RegisterUserServiceServer(gs, grpcserver.New(db))
+package grpcserver
+
+//go:generate buf generate
+//go:generate sh -c "for f in usersv1/*.go; do { printf '//go:build grpc\\n\\n'; cat ${DOLLAR}f; } > ${DOLLAR}f.tmp && mv ${DOLLAR}f.tmp ${DOLLAR}f; done"
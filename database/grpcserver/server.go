@@ -0,0 +1,297 @@
+//go:build grpc
+
+package grpcserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"your-project/database"
+	"your-project/database/grpcserver/usersv1"
+	"your-project/database/nulls"
+)
+
+// Calls that only read run in read-only transactions, which on SQLite
+// neither take a write slot nor the write lock
+var readOnlyMethods = map[string]bool{
+	usersv1.UserService_GetUser_FullMethodName:   true,
+	usersv1.UserService_ListUsers_FullMethodName: true,
+}
+
+// Server implements usersv1.UserServiceServer on a database
+type Server struct {
+	usersv1.UnimplementedUserServiceServer
+	db *database.DB
+}
+
+// New returns the service for db. Its unary methods expect TxInterceptor
+// in front of them, and run outside a transaction without it.
+func New(db *database.DB) *Server {
+	return &Server{db: db}
+}
+
+type txKey struct{}
+
+// callTx is the transaction TxInterceptor runs a call in
+type callTx struct {
+	q  database.Querier
+	tx *database.Tx // nil in a read-only transaction
+}
+
+// TxInterceptor runs each unary call in a transaction on db: it commits
+// when the handler returns no error and rolls back otherwise, so a call
+// that fails halfway leaves nothing behind. GetUser and ListUsers get
+// read-only ones. Streaming calls aren't wrapped, since a transaction
+// held for as long as the client reads would hold up the database.
+func TxInterceptor(db *database.DB) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var resp any
+		if readOnlyMethods[info.FullMethod] {
+			err := db.TransactionWithOptions(ctx, &sql.TxOptions{ReadOnly: true}, func(q *database.Queries) error {
+				var err error
+				resp, err = handler(context.WithValue(ctx, txKey{}, &callTx{q: q}), req)
+				return err
+			})
+			return resp, err
+		}
+		err := db.InTx(ctx, func(tx *database.Tx) error {
+			var err error
+			resp, err = handler(context.WithValue(tx.Context(), txKey{}, &callTx{q: tx.Querier, tx: tx}), req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+// queries returns the call's transaction's queries, or the DB's outside
+// TxInterceptor
+func (s *Server) queries(ctx context.Context) database.Querier {
+	if t, ok := ctx.Value(txKey{}).(*callTx); ok {
+		return t.q
+	}
+	return s.db.Q
+}
+
+// ErrorInterceptor turns the errors of unary calls into gRPC statuses,
+// see errorStatus. Chain it in front of TxInterceptor, so that it sees the
+// commit's errors too.
+func ErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, errorStatus(info.FullMethod, err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamErrorInterceptor is ErrorInterceptor for streaming calls
+func StreamErrorInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return errorStatus(info.FullMethod, err)
+		}
+		return nil
+	}
+}
+
+// errorStatus maps the database's typed errors to status codes. Errors
+// that are already statuses pass through; the rest are logged and sent
+// as INTERNAL without their text, which may name tables and values.
+func errorStatus(method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	err = database.Translate(err)
+	var (
+		validation *database.ValidationError
+		constraint *database.ConstraintError
+	)
+	switch {
+	case errors.As(err, &validation):
+		return status.Errorf(codes.InvalidArgument, "%s %s", validation.Field, validation.Message)
+	case errors.As(err, &constraint):
+		return status.Error(codes.InvalidArgument, "a value is out of range")
+	case errors.Is(err, database.ErrNotFound):
+		return status.Error(codes.NotFound, "not found")
+	case errors.Is(err, database.ErrDuplicate):
+		return status.Error(codes.AlreadyExists, "already exists")
+	case errors.Is(err, database.ErrStaleVersion):
+		return status.Error(codes.Aborted, "changed by someone else; reload and try again")
+	case errors.Is(err, database.ErrForeignKey):
+		return status.Error(codes.FailedPrecondition, "refers to something that doesn't exist")
+	case errors.Is(err, database.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, "invalid page token")
+	case errors.Is(err, database.ErrReadOnly):
+		return status.Error(codes.FailedPrecondition, "this instance is read-only")
+	case errors.Is(err, database.ErrQueryTimeout), errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "canceled")
+	case errors.Is(err, database.ErrStorageExhausted):
+		log.Printf("%s: %v", method, err)
+		return status.Error(codes.ResourceExhausted, "database storage is exhausted")
+	case errors.Is(err, database.ErrShuttingDown), errors.Is(err, database.ErrCircuitOpen), errors.Is(err, database.ErrConnection):
+		log.Printf("%s: %v", method, err)
+		return status.Error(codes.Unavailable, "database unavailable")
+	default:
+		log.Printf("%s: %v", method, err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func toProto(u database.User) *usersv1.User {
+	return &usersv1.User{
+		Id:         u.ID,
+		TelegramId: u.TelegramID,
+		FirstName:  u.FirstName,
+		Username:   nulls.Ptr[string](u.Username),
+		Email:      nulls.Ptr[string](u.Email),
+		Status:     string(u.Status),
+		Language:   u.Language,
+		CreatedAt:  timestamp(u.CreatedAt.V, u.CreatedAt.Valid),
+		UpdatedAt:  timestamp(u.UpdatedAt.V, u.UpdatedAt.Valid),
+		Version:    u.Version,
+	}
+}
+
+func timestamp(t time.Time, valid bool) *timestamppb.Timestamp {
+	if !valid {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// CreateUser inserts the user and its user.created row event, which
+// commit together
+func (s *Server) CreateUser(ctx context.Context, req *usersv1.CreateUserRequest) (*usersv1.User, error) {
+	arg := database.CreateUserParams{
+		TelegramID: req.TelegramId,
+		FirstName:  req.FirstName,
+		Username:   nulls.FromPtr(req.Username),
+		Email:      nulls.FromPtr(req.Email),
+		Status:     database.StatusActive,
+		Language:   req.Language,
+	}
+	if arg.Language == "" {
+		arg.Language = "en"
+	}
+
+	var (
+		user database.User
+		err  error
+	)
+	if t, ok := ctx.Value(txKey{}).(*callTx); ok && t.tx != nil {
+		user, err = t.tx.Events().CreateUser(ctx, arg)
+	} else {
+		err = s.db.InTx(ctx, func(tx *database.Tx) error {
+			user, err = tx.Events().CreateUser(ctx, arg)
+			return err
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toProto(user), nil
+}
+
+func (s *Server) GetUser(ctx context.Context, req *usersv1.GetUserRequest) (*usersv1.User, error) {
+	user, err := s.queries(ctx).GetUserByID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return toProto(user), nil
+}
+
+// UpdateUser renames the user at the version the client read, returning
+// ABORTED when another update got there first and NOT_FOUND when the user
+// is gone
+func (s *Server) UpdateUser(ctx context.Context, req *usersv1.UpdateUserRequest) (*usersv1.User, error) {
+	q := s.queries(ctx)
+	err := database.CheckVersion(q.UpdateUserIfVersion(ctx, database.UpdateUserIfVersionParams{
+		ID:        req.Id,
+		Version:   req.Version,
+		FirstName: req.FirstName,
+		Username:  nulls.FromPtr(req.Username),
+	}))
+	if errors.Is(err, database.ErrStaleVersion) {
+		if _, gerr := q.GetUserByID(ctx, req.Id); errors.Is(database.Translate(gerr), database.ErrNotFound) {
+			return nil, database.ErrNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := q.GetUserByID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return toProto(user), nil
+}
+
+// DeleteUser soft-deletes the user; NOT_FOUND if it already is
+func (s *Server) DeleteUser(ctx context.Context, req *usersv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	n, err := s.queries(ctx).SoftDeleteUser(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, database.ErrNotFound
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ListUsers returns a page of users, oldest signup first, paginated like
+// DB.UsersByCreatedAt
+func (s *Server) ListUsers(ctx context.Context, req *usersv1.ListUsersRequest) (*usersv1.ListUsersResponse, error) {
+	size := int64(req.PageSize)
+	switch {
+	case size == 0:
+		size = 20
+	case size < 0 || size > 100:
+		return nil, status.Error(codes.InvalidArgument, "page_size must be between 1 and 100")
+	}
+
+	var (
+		afterCreatedAt time.Time
+		afterID        int64
+	)
+	q := s.queries(ctx)
+	page, err := database.Paginate(s.db, req.PageToken, size, []any{&afterCreatedAt, &afterID},
+		func(limit int64) ([]database.User, error) {
+			return q.ListUsersByCreatedAt(ctx, database.ListUsersByCreatedAtParams{AfterCreatedAt: afterCreatedAt, AfterID: afterID, PageSize: limit})
+		},
+		func(u database.User) []any { return []any{u.CreatedAt.V, u.ID} },
+	)
+	if err != nil {
+		return nil, err
+	}
+	resp := &usersv1.ListUsersResponse{NextPageToken: page.NextCursor}
+	for _, u := range page.Items {
+		resp.Users = append(resp.Users, toProto(u))
+	}
+	return resp, nil
+}
+
+// StreamUsers sends every matching user, one row read from the database
+// per message sent, so a large list never sits in memory. It holds a
+// connection until the client has read to the end or gone away.
+func (s *Server) StreamUsers(req *usersv1.StreamUsersRequest, stream usersv1.UserService_StreamUsersServer) error {
+	filter := database.UserFilter{Status: database.Status(req.Status), Language: req.Language}
+	if filter.Status != "" && !filter.Status.Valid() {
+		return status.Errorf(codes.InvalidArgument, "unknown status %q", req.Status)
+	}
+	ctx := stream.Context()
+	return s.db.ForEachUser(ctx, filter, func(u database.User) error {
+		return stream.Send(toProto(u))
+	})
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// HealthStatus is the coarse state reported by HealthState
+type HealthStatus string
+
+const (
+	Healthy  HealthStatus = "healthy"
+	Degraded HealthStatus = "degraded" // Recent pings failed, but fewer than healthDownAfter in a row
+	Down     HealthStatus = "down"
+)
+
+// Consecutive failed pings before the database counts as Down
+const healthDownAfter = 3
+
+// database/sql keeps 2 idle connections unless told otherwise
+const defaultMaxIdleConns = 2
+
+// Health is a snapshot of the health monitor's view of the database
+type Health struct {
+	Status    HealthStatus
+	Failures  int       // Consecutive failed pings
+	LastError error     // Error of the last failed ping, nil while Healthy
+	CheckedAt time.Time // Time of the last ping
+	Since     time.Time // When Status last changed
+
+	WriteDegraded bool // Writes are paused after a storage error, see ErrStorageExhausted
+}
+
+type healthMonitor struct {
+	mu    sync.Mutex
+	state Health
+}
+
+// StartHealthMonitor pings the database every interval until ctx is
+// canceled. When a ping fails with a connection error, and again once the
+// database answers, idle connections are dropped so requests after a
+// failover don't land on dead pooled connections. State changes are
+// logged once; read the current state with HealthState.
+func (db *DB) StartHealthMonitor(ctx context.Context, interval time.Duration) {
+	db.health.mu.Lock()
+	db.health.state = Health{Status: Healthy, Since: time.Now()}
+	db.health.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			db.checkHealth(ctx, interval)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// HealthState returns the latest result of the health monitor. Before
+// StartHealthMonitor is called it reports Healthy with zero times.
+func (db *DB) HealthState() Health {
+	db.health.mu.Lock()
+	defer db.health.mu.Unlock()
+
+	h := db.health.state
+	if h.Status == "" {
+		h.Status = Healthy
+	}
+	h.WriteDegraded = db.WriteDegraded()
+	return h
+}
+
+func (db *DB) checkHealth(ctx context.Context, timeout time.Duration) {
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := db.Conn.PingContext(pingCtx)
+	cancel()
+	if ctx.Err() != nil {
+		return // shutting down, not a database failure
+	}
+
+	db.health.mu.Lock()
+	defer db.health.mu.Unlock()
+
+	h := &db.health.state
+	prev, since := h.Status, h.Since
+	h.CheckedAt = time.Now()
+
+	if err == nil {
+		h.Failures = 0
+		h.LastError = nil
+		h.Status = Healthy
+	} else {
+		h.Failures++
+		h.LastError = err
+		h.Status = Degraded
+		if h.Failures >= healthDownAfter {
+			h.Status = Down
+		}
+	}
+
+	if h.Status == prev {
+		return
+	}
+	h.Since = h.CheckedAt
+
+	switch {
+	case h.Status == Healthy:
+		log.Printf("database is healthy again after %s", h.CheckedAt.Sub(since).Round(time.Second))
+		db.resetIdleConns()
+	case prev == Healthy:
+		log.Printf("database is %s: %v", h.Status, err)
+		if isConnectionError(err) {
+			db.resetIdleConns()
+		}
+	default:
+		log.Printf("database is %s after %d failed pings: %v", h.Status, h.Failures, err)
+	}
+}
+
+// resetIdleConns closes every idle connection, so the next statements
+// open new ones; connections in use go back to the pool as usual. A pool
+// of one connection is left alone: that's how Open keeps SQLite's
+// :memory:, whose database goes with its connection, and database/sql
+// already replaces a connection the driver reports as bad.
+func (db *DB) resetIdleConns() {
+	if db.Conn.Stats().MaxOpenConnections == 1 {
+		return
+	}
+	db.Conn.SetMaxIdleConns(0)
+	db.Conn.SetMaxIdleConns(db.maxIdleConns)
+}
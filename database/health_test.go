@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyConnector is a driver whose server can be taken down: pings and
+// statements then fail with a connection error, as after a failover,
+// while the pooled connections stay open
+type flakyConnector struct {
+	down   atomic.Bool
+	delay  atomic.Int64 // How long a statement takes, in nanoseconds
+	opened atomic.Int64
+	closed atomic.Int64
+	stmts  atomic.Int64 // Statements that reached the driver
+}
+
+func (c *flakyConnector) Connect(context.Context) (driver.Conn, error) {
+	c.opened.Add(1)
+	return &flakyConn{c: c}, nil
+}
+
+func (c *flakyConnector) Driver() driver.Driver { return nil }
+
+type flakyConn struct {
+	c *flakyConnector
+}
+
+func (c *flakyConn) Ping(context.Context) error {
+	if c.c.down.Load() {
+		return &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	}
+	return nil
+}
+
+// ExecContext runs any statement, affecting no rows
+func (c *flakyConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	c.c.stmts.Add(1)
+	time.Sleep(time.Duration(c.c.delay.Load()))
+	if c.c.down.Load() {
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *flakyConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *flakyConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *flakyConn) Close() error {
+	c.c.closed.Add(1)
+	return nil
+}
+
+func newFlakyDB(t *testing.T, maxOpen int) (*DB, *flakyConnector) {
+	t.Helper()
+	fc := &flakyConnector{}
+	conn := sql.OpenDB(fc)
+	conn.SetMaxOpenConns(maxOpen)
+	conn.SetMaxIdleConns(defaultMaxIdleConns)
+	t.Cleanup(func() { conn.Close() })
+	return &DB{Conn: conn, maxIdleConns: defaultMaxIdleConns}, fc
+}
+
+// waitHealth polls HealthState until it reports want
+func waitHealth(t *testing.T, db *DB, want HealthStatus) Health {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		h := db.HealthState()
+		if h.Status == want {
+			return h
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("health %s after 5s, want %s", h.Status, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHealthMonitorOutage(t *testing.T) {
+	db, fc := newFlakyDB(t, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := db.Conn.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	db.StartHealthMonitor(ctx, 10*time.Millisecond)
+	if h := db.HealthState(); h.Status != Healthy || h.LastError != nil {
+		t.Fatalf("at the start: %+v, want Healthy", h)
+	}
+
+	fc.down.Store(true)
+	h := waitHealth(t, db, Degraded)
+	if !isConnectionError(h.LastError) {
+		t.Errorf("degraded with %v, want the ping's connection error", h.LastError)
+	}
+	h = waitHealth(t, db, Down)
+	if h.Failures < healthDownAfter || h.Since.IsZero() {
+		t.Errorf("down after %d failures since %v, want at least %d", h.Failures, h.Since, healthDownAfter)
+	}
+	// The first failed ping dropped the connections from before the outage
+	if got := fc.closed.Load(); got == 0 {
+		t.Error("no idle connection closed when the database went away")
+	}
+
+	closed := fc.closed.Load()
+	fc.down.Store(false)
+	h = waitHealth(t, db, Healthy)
+	if h.Failures != 0 || h.LastError != nil {
+		t.Errorf("after recovery: %+v, want a fresh Healthy", h)
+	}
+	// And recovering dropped those opened during it
+	if fc.closed.Load() == closed {
+		t.Error("no idle connection closed when the database came back")
+	}
+}
+
+func TestHealthResetKeepsSingleConnection(t *testing.T) {
+	ctx := context.Background()
+	db, fc := newFlakyDB(t, 1) // Like SQLite's :memory:, where the connection is the database
+	db.health.state = Health{Status: Healthy, Since: time.Now()}
+
+	fc.down.Store(true)
+	for range healthDownAfter {
+		db.checkHealth(ctx, time.Second)
+	}
+	if h := db.HealthState(); h.Status != Down {
+		t.Fatalf("after %d failed pings: %s, want down", healthDownAfter, h.Status)
+	}
+	fc.down.Store(false)
+	db.checkHealth(ctx, time.Second)
+	if h := db.HealthState(); h.Status != Healthy {
+		t.Fatalf("after a good ping: %s, want healthy", h.Status)
+	}
+
+	if opened, closed := fc.opened.Load(), fc.closed.Load(); opened != 1 || closed != 0 {
+		t.Errorf("%d connections opened and %d closed, want the one kept throughout", opened, closed)
+	}
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserAsOf rebuilds the user as it was at t from user_history. It returns
+// sql.ErrNoRows if the user didn't exist yet at t, or had been deleted.
+func (db *DB) UserAsOf(ctx context.Context, id int64, t time.Time) (User, error) {
+	history, err := db.Q.GetUserHistory(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+
+	h, ok := lastChangeAt(history, t, func(h UserHistory) (time.Time, string) { return h.ChangedAt, h.Operation })
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	return User{
+		ID:                h.ID,
+		TelegramID:        h.TelegramID,
+		FirstName:         h.FirstName,
+		Username:          h.Username,
+		BalanceGame:       h.BalanceGame,
+		BalanceChats:      h.BalanceChats,
+		Status:            h.Status,
+		Language:          h.Language,
+		ReferFromID:       h.ReferFromID,
+		LastStreakClaimAt: h.LastStreakClaimAt,
+		CreatedAt:         h.CreatedAt,
+		UpdatedAt:         h.UpdatedAt,
+		Email:             h.Email,
+		DeletedAt:         h.DeletedAt,
+		Version:           h.Version,
+	}, nil
+}
+
+// GroupAsOf rebuilds the group as it was at t from group_history, like
+// UserAsOf
+func (db *DB) GroupAsOf(ctx context.Context, id int64, t time.Time) (Group, error) {
+	history, err := db.Q.GetGroupHistory(ctx, id)
+	if err != nil {
+		return Group{}, err
+	}
+
+	h, ok := lastChangeAt(history, t, func(h GroupHistory) (time.Time, string) { return h.ChangedAt, h.Operation })
+	if !ok {
+		return Group{}, sql.ErrNoRows
+	}
+	return Group{
+		ID:         h.ID,
+		Balance:    h.Balance,
+		TelegramID: h.TelegramID,
+		Title:      h.Title,
+		Url:        h.Url,
+		CreatedAt:  h.CreatedAt,
+		UpdatedAt:  h.UpdatedAt,
+	}, nil
+}
+
+// lastChangeAt picks the last entry made at or before t from a history
+// sorted oldest first. A row deleted by then doesn't exist.
+func lastChangeAt[H any](history []H, t time.Time, change func(H) (time.Time, string)) (H, bool) {
+	var last H
+	found := false
+	for _, h := range history {
+		at, op := change(h)
+		if at.After(t) {
+			break
+		}
+		last, found = h, op != "DELETE"
+	}
+	return last, found
+}
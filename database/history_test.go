@@ -0,0 +1,105 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestUserHistory(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	exec := func(query string) {
+		t.Helper()
+		time.Sleep(5 * time.Millisecond) // changed_at has millisecond resolution
+		if _, err := db.DBTX().ExecContext(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: 1, FirstName: "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newUsers(t, db, 2) // Someone else's changes stay out of Ann's history
+	time.Sleep(5 * time.Millisecond)
+	if _, err := db.Q.UpdateUserBalanceChats(ctx, database.UpdateUserBalanceChatsParams{
+		BalanceChats: sql.Null[database.Money]{V: 500, Valid: true}, TelegramID: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Ad-hoc SQL is recorded the same
+	exec("UPDATE users SET first_name = 'Annie' WHERE telegram_id = 1")
+	exec("DELETE FROM users WHERE telegram_id = 1")
+
+	history, err := db.Q.GetUserHistory(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []string
+	for _, h := range history {
+		ops = append(ops, h.Operation)
+	}
+	if want := []string{"INSERT", "UPDATE", "UPDATE", "DELETE"}; !slices.Equal(ops, want) {
+		t.Fatalf("history %v, want %v: one entry per change, oldest first", ops, want)
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i].ChangedAt.Before(history[i-1].ChangedAt) {
+			t.Errorf("entry %d changed at %v, before the one before it", i, history[i].ChangedAt)
+		}
+	}
+
+	// The row as of each change, and between changes
+	for _, c := range []struct {
+		at      time.Time
+		name    string
+		balance database.Money
+	}{
+		{history[0].ChangedAt, "Ann", 0},
+		{history[1].ChangedAt, "Ann", 500},
+		{history[2].ChangedAt.Add(-time.Millisecond), "Ann", 500},
+		{history[2].ChangedAt, "Annie", 500},
+	} {
+		got, err := db.UserAsOf(ctx, u.ID, c.at)
+		if err != nil || got.FirstName != c.name || got.BalanceChats.V != c.balance || got.TelegramID != 1 {
+			t.Errorf("as of %v: %s with %v, %v; want %s with %v", c.at, got.FirstName, got.BalanceChats.V, err, c.name, c.balance)
+		}
+	}
+	if _, err := db.UserAsOf(ctx, u.ID, history[0].ChangedAt.Add(-time.Millisecond)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("before the user existed: %v, want sql.ErrNoRows", err)
+	}
+	if _, err := db.UserAsOf(ctx, u.ID, time.Now().Add(time.Hour)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("after the user was deleted: %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestGroupHistory(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	g, err := db.Q.CreateGroup(ctx, database.CreateGroupParams{TelegramID: 10, Title: sql.Null[string]{V: "chess", Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := db.DBTX().ExecContext(ctx, "UPDATE groups SET title = 'go' WHERE telegram_id = 10"); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := db.Q.GetGroupHistory(ctx, g.ID)
+	if err != nil || len(history) != 2 {
+		t.Fatalf("group history: %d entries, %v; want 2", len(history), err)
+	}
+	for i, want := range []string{"chess", "go"} {
+		got, err := db.GroupAsOf(ctx, g.ID, history[i].ChangedAt)
+		if err != nil || got.Title.V != want {
+			t.Errorf("as of change %d: %q, %v; want %q", i, got.Title.V, err, want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// QueryEvent is one statement, as the hooks see it
+type QueryEvent struct {
+	Name     string        // sqlc query name, or "other"
+	Query    string        // Before may rewrite it
+	Args     []any         // Before may rewrite them
+	InTx     bool          // Runs inside a transaction
+	Duration time.Duration // Until the driver returned; for queries, before the rows are read
+	Rows     int64         // Rows affected by an Exec, -1 for queries or when unknown
+	Err      error         // A QueryRow's error only shows at Scan, so it's nil here
+}
+
+// QueryHook runs around every statement made through db.Q, a Tx or the
+// raw query helpers, for cross-cutting work such as tenant filters, audit
+// logs and metrics. Any of the funcs may be nil.
+//
+// Before runs in the order the hooks were given, After and OnError in
+// reverse, like middleware. Before may change e.Query and e.Args, which
+// the statement then runs with, and return a new context for the hooks
+// after it and the statement. An error from Before stops the statement:
+// it's returned to the caller, and After and OnError run with it for the
+// hooks before the one that failed. OnError runs after After, only when
+// the statement failed.
+//
+// Hooks run on the goroutine making the statement, so a slow hook slows
+// every query; they must be safe for concurrent use.
+type QueryHook struct {
+	Before  func(ctx context.Context, e *QueryEvent) (context.Context, error)
+	After   func(ctx context.Context, e QueryEvent)
+	OnError func(ctx context.Context, e QueryEvent)
+}
+
+// hooksDBTX runs the hooks around each statement, above the rest of the
+// middleware so it sees what they rewrite
+type hooksDBTX struct {
+	DBTX
+	hooks []QueryHook
+	inTx  bool
+}
+
+// before runs the Before funcs and returns how many ran, for after
+func (d *hooksDBTX) before(ctx context.Context, e *QueryEvent) (context.Context, int, error) {
+	for i, h := range d.hooks {
+		if h.Before == nil {
+			continue
+		}
+		next, err := h.Before(ctx, e)
+		if err != nil {
+			return ctx, i, err
+		}
+		if next != nil {
+			ctx = next
+		}
+	}
+	return ctx, len(d.hooks), nil
+}
+
+// after runs the After and OnError funcs of the first n hooks, last first
+func (d *hooksDBTX) after(ctx context.Context, e QueryEvent, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if h := d.hooks[i]; h.After != nil {
+			h.After(ctx, e)
+		}
+	}
+	if e.Err == nil {
+		return
+	}
+	for i := n - 1; i >= 0; i-- {
+		if h := d.hooks[i]; h.OnError != nil {
+			h.OnError(ctx, e)
+		}
+	}
+}
+
+func (d *hooksDBTX) event(query string, args []any) *QueryEvent {
+	return &QueryEvent{Name: queryName(query), Query: query, Args: args, InTx: d.inTx, Rows: -1}
+}
+
+func (d *hooksDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e := d.event(query, args)
+	ctx, n, err := d.before(ctx, e)
+	if err != nil {
+		e.Err = err
+		d.after(ctx, *e, n)
+		return nil, err
+	}
+
+	start := time.Now()
+	res, err := d.DBTX.ExecContext(ctx, e.Query, e.Args...)
+	e.Duration, e.Err = time.Since(start), err
+	if err == nil {
+		if rows, err := res.RowsAffected(); err == nil {
+			e.Rows = rows
+		}
+	}
+	d.after(ctx, *e, n)
+	return res, err
+}
+
+func (d *hooksDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	e := d.event(query, args)
+	ctx, n, err := d.before(ctx, e)
+	if err != nil {
+		e.Err = err
+		d.after(ctx, *e, n)
+		return nil, err
+	}
+
+	start := time.Now()
+	rows, err := d.DBTX.QueryContext(ctx, e.Query, e.Args...)
+	e.Duration, e.Err = time.Since(start), err
+	d.after(ctx, *e, n)
+	return rows, err
+}
+
+func (d *hooksDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	e := d.event(query, args)
+	ctx, n, err := d.before(ctx, e)
+	if err != nil {
+		e.Err = err
+		d.after(ctx, *e, n)
+		return errRow(ctx, err)
+	}
+
+	start := time.Now()
+	row := d.DBTX.QueryRowContext(ctx, e.Query, e.Args...)
+	e.Duration = time.Since(start)
+	d.after(ctx, *e, n)
+	return row
+}
+
+// wrapHooks puts the hooks over dbtx, the middleware on top of conn, if
+// there are any
+func (db *DB) wrapHooks(dbtx, conn DBTX) DBTX {
+	if len(db.hooks) == 0 {
+		return dbtx
+	}
+	_, inTx := conn.(sqlTx)
+	return &hooksDBTX{DBTX: dbtx, hooks: db.hooks, inTx: inTx}
+}
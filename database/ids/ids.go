@@ -0,0 +1,317 @@
+// Package ids is the UUID and ULID types for tables keyed by one instead
+// of an integer, as sqlc overrides:
+//
+//	overrides:
+//	  - column: "orders.id"
+//	    go_type:
+//	      import: "your-project/database/ids"
+//	      type: "UUID"
+//
+// Both store their text in a TEXT column, which reads back in the sqlite3
+// shell and is what a PostgreSQL uuid column takes; Binary stores the 16
+// bytes in a BLOB or bytea column instead. Scan reads either, so a column
+// can move from one to the other. NULL only scans into sql.Null[UUID] (or
+// ULID), as with the other types.
+//
+// NewUUID and NewULID start with the time, so new rows go to the end of
+// the key's index, like integer keys do, instead of all over it.
+package ids
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UUID is an RFC 9562 UUID, as text in the database and in JSON
+// ("018f3c6e-9a4b-7c1d-8e2f-3a4b5c6d7e8f")
+type UUID [16]byte
+
+// NewUUID returns a version 7 UUID: the time in milliseconds, then 74
+// random bits. IDs from the same millisecond aren't ordered among
+// themselves.
+func NewUUID() UUID {
+	var u UUID
+	putTime(u[:], time.Now())
+	rand.Read(u[6:])
+	u[6] = u[6]&0x0f | 0x70 // Version 7
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+	return u
+}
+
+// NewRandomUUID returns a version 4 UUID, all random bits, for IDs that
+// mustn't tell when they were made
+func NewRandomUUID() UUID {
+	var u UUID
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return u
+}
+
+// ParseUUID reads a UUID as String writes it, in either case, or as 32
+// hex digits without the hyphens
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) == 36 && s[8] == '-' && s[13] == '-' && s[18] == '-' && s[23] == '-' {
+		s = s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	}
+	if len(s) != 32 {
+		return UUID{}, fmt.Errorf("invalid UUID %q", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(s)); err != nil {
+		return UUID{}, fmt.Errorf("invalid UUID %q", s)
+	}
+	return u, nil
+}
+
+// MustParseUUID is ParseUUID for constants, panicking on a bad one
+func MustParseUUID(s string) UUID {
+	u, err := ParseUUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[:8], u[:4])
+	hex.Encode(b[9:13], u[4:6])
+	hex.Encode(b[14:18], u[6:8])
+	hex.Encode(b[19:23], u[8:10])
+	hex.Encode(b[24:], u[10:])
+	b[8], b[13], b[18], b[23] = '-', '-', '-', '-'
+	return string(b[:])
+}
+
+// IsZero reports whether u is the nil UUID, the one a new row's key has
+// before it gets one
+func (u UUID) IsZero() bool {
+	return u == UUID{}
+}
+
+// Version is u's version: 7 from NewUUID, 4 from NewRandomUUID
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// Time is when a version 7 UUID was made, to the millisecond; the zero
+// time for other versions
+func (u UUID) Time() time.Time {
+	if u.Version() != 7 {
+		return time.Time{}
+	}
+	return getTime(u[:])
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(b []byte) error {
+	v, err := ParseUUID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// Value stores u as text
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// Scan reads u from text or from 16 bytes
+func (u *UUID) Scan(src any) error {
+	v, err := scan(src, "UUID", func(s string) ([16]byte, error) { return ParseUUID(s) })
+	*u = v
+	return err
+}
+
+// ULID is a ULID, as 26 characters of Crockford base32 in the database
+// and in JSON ("01HX3V8Q9N4T6W2Y5Z7A9C1E3G"), which sort by time
+type ULID [16]byte
+
+// NewULID returns a ULID of the time in milliseconds and 80 random bits.
+// IDs from the same millisecond aren't ordered among themselves.
+func NewULID() ULID {
+	var u ULID
+	putTime(u[:], time.Now())
+	rand.Read(u[6:])
+	return u
+}
+
+// Crockford's base32: no I, L, O or U
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var crockfordValue = func() [256]byte {
+	var v [256]byte
+	for i := range v {
+		v[i] = 0xff
+	}
+	for i := 0; i < len(crockford); i++ {
+		v[crockford[i]] = byte(i)
+		v[strings.ToLower(crockford[i : i+1])[0]] = byte(i)
+	}
+	return v
+}()
+
+// ParseULID reads a ULID as String writes it, in either case
+func ParseULID(s string) (ULID, error) {
+	if len(s) != 26 || crockfordValue[s[0]] > 7 { // 26 characters hold 130 bits, the first can only use 3
+		return ULID{}, fmt.Errorf("invalid ULID %q", s)
+	}
+	var u ULID
+	var acc uint64
+	var bits, n int
+	for i := 0; i < len(s); i++ {
+		v := crockfordValue[s[i]]
+		if v == 0xff {
+			return ULID{}, fmt.Errorf("invalid ULID %q", s)
+		}
+		acc = acc<<5 | uint64(v)
+		bits += 5
+		if i == 0 {
+			bits = 3 // The two bits over 128 are always zero
+		}
+		for bits >= 8 {
+			bits -= 8
+			u[n] = byte(acc >> bits)
+			n++
+		}
+	}
+	return u, nil
+}
+
+// MustParseULID is ParseULID for constants, panicking on a bad one
+func MustParseULID(s string) ULID {
+	u, err := ParseULID(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func (u ULID) String() string {
+	var b [26]byte
+	var acc uint64
+	bits, n := 2, 0 // The 130 bits 26 characters hold start with two zeros
+	for _, c := range u {
+		acc = acc<<8 | uint64(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			b[n] = crockford[acc>>bits&0x1f]
+			n++
+		}
+	}
+	return string(b[:])
+}
+
+// IsZero reports whether u is all zeros, the key of a new row before it
+// gets one
+func (u ULID) IsZero() bool {
+	return u == ULID{}
+}
+
+// Time is when u was made, to the millisecond
+func (u ULID) Time() time.Time {
+	return getTime(u[:])
+}
+
+func (u ULID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *ULID) UnmarshalText(b []byte) error {
+	v, err := ParseULID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// Value stores u as text
+func (u ULID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// Scan reads u from text or from 16 bytes
+func (u *ULID) Scan(src any) error {
+	v, err := scan(src, "ULID", func(s string) ([16]byte, error) { return ParseULID(s) })
+	*u = v
+	return err
+}
+
+// Binary stores an ID as its 16 bytes, for a BLOB (SQLite) or bytea
+// (PostgreSQL) column: less than half the text's size, in the table and
+// in every index on it. JSON and String still have the text.
+//
+//	go_type:
+//	  import: "your-project/database/ids"
+//	  type: "Binary[ids.UUID]"
+type Binary[T UUID | ULID] struct {
+	ID T
+}
+
+func (b Binary[T]) String() string {
+	return fmt.Sprint(b.ID)
+}
+
+func (b Binary[T]) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprint(b.ID)), nil
+}
+
+func (b *Binary[T]) UnmarshalText(text []byte) error {
+	return any(&b.ID).(interface{ UnmarshalText([]byte) error }).UnmarshalText(text)
+}
+
+// Value stores b's 16 bytes
+func (b Binary[T]) Value() (driver.Value, error) {
+	id := [16]byte(b.ID)
+	return id[:], nil
+}
+
+// Scan reads b from 16 bytes, or from text
+func (b *Binary[T]) Scan(src any) error {
+	return any(&b.ID).(interface{ Scan(any) error }).Scan(src)
+}
+
+// scan reads an ID from what a driver returns for the column: 16 bytes
+// from a BLOB, text otherwise
+func scan(src any, kind string, parse func(string) ([16]byte, error)) ([16]byte, error) {
+	switch v := src.(type) {
+	case string:
+		return parse(v)
+	case []byte:
+		if len(v) == 16 {
+			return [16]byte(v), nil
+		}
+		return parse(string(v))
+	case nil:
+		return [16]byte{}, errors.New("NULL into ids." + kind + ", scan into sql.Null[ids." + kind + "] for a nullable column")
+	}
+	return [16]byte{}, fmt.Errorf("can't scan %T into ids.%s", src, kind)
+}
+
+// putTime puts t's Unix milliseconds into the first 6 bytes of b, as
+// UUIDv7 and ULID both start
+func putTime(b []byte, t time.Time) {
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(b[:6], ms[2:])
+}
+
+func getTime(b []byte) time.Time {
+	var ms [8]byte
+	copy(ms[2:], b[:6])
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(ms[:])))
+}
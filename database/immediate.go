@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+// WriteTransaction is Transaction for callbacks that read and then write.
+// On SQLite it begins with BEGIN IMMEDIATE, taking the write lock before
+// fn runs and waiting up to the busy timeout for it. A plain transaction
+// only asks for the lock at its first write, and if another writer
+// committed since its reads, SQLite fails that write with "database is
+// locked" right away, waiting doesn't help: the whole callback has to
+// be retried. PostgreSQL locks rows, not the database, so there it's the
+// same as Transaction.
+//
+// Config.ImmediateWriteTx makes every Transaction and InTx do this. Read
+// only transactions then wait for writers too, so leave it off when reads
+// in transactions are common.
+func (db *DB) WriteTransaction(ctx context.Context, fn func(*Queries) error) error {
+	return db.inTx(ctx, true, nil, func(tx *Tx) error {
+		return fn(tx.queries)
+	})
+}
+
+// sqlTx is the transaction InTx runs on: a *sql.Tx, or a connTx when the
+// dialect begins transactions in ways database/sql has no option for
+type sqlTx interface {
+	DBTX
+	Commit() error
+	Rollback() error
+}
+
+// connTx is a transaction begun by hand on a connection taken out of the
+// pool; the connection goes back once it ends, after reset undoes the
+// settings begin changed
+type connTx struct {
+	*sql.Conn
+	reset string
+	done  bool
+}
+
+func beginOnConn(ctx context.Context, pool *sql.DB, reset string, begin ...string) (*connTx, error) {
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t := &connTx{Conn: conn, reset: reset}
+	for _, stmt := range begin {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			t.release(reset != "") // It may have got the settings but no transaction
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Commit and Rollback ignore ctx: fn may have failed because it was
+// canceled, and the transaction still has to end
+func (t *connTx) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	if _, err := t.Conn.ExecContext(context.Background(), "COMMIT"); err != nil {
+		// A COMMIT that failed (SQLITE_BUSY) leaves the transaction open
+		t.rollback()
+		return err
+	}
+	return t.release(false)
+}
+
+func (t *connTx) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	return t.rollback()
+}
+
+// rollback hands the connection back, or closes it if ROLLBACK failed
+// and the transaction might still be open on it. A connection that is
+// already gone, as pgx closes one whose query was canceled, took the
+// transaction with it, which is sql.ErrTxDone as for a *sql.Tx.
+func (t *connTx) rollback() error {
+	_, err := t.Conn.ExecContext(context.Background(), "ROLLBACK")
+	t.release(err != nil)
+	if errors.Is(err, driver.ErrBadConn) {
+		return sql.ErrTxDone
+	}
+	return err
+}
+
+// release hands the connection back to the pool once reset has run,
+// or closes it if discard is set or reset failed
+func (t *connTx) release(discard bool) error {
+	if !discard && t.reset != "" {
+		if _, err := t.Conn.ExecContext(context.Background(), t.reset); err != nil {
+			discard = true
+		}
+	}
+	if discard {
+		t.Conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	return t.Conn.Close()
+}
@@ -0,0 +1,76 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// readThenWrite runs 8 goroutines of transactions that read, then write
+// a user of their own, through begin; it returns the failures
+func readThenWrite(db *database.DB, begin func(context.Context, func(*database.Queries) error) error) []error {
+	ctx := context.Background()
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 10 {
+				err := begin(ctx, func(q *database.Queries) error {
+					if _, err := q.CountUsersByStatus(ctx, database.StatusActive); err != nil {
+						return err
+					}
+					time.Sleep(time.Millisecond) // Long enough for another writer to commit
+					_, err := q.CreateUser(ctx, database.CreateUserParams{TelegramID: int64(g*100 + i + 1), FirstName: "user"})
+					return err
+				})
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestWriteTransaction(t *testing.T) {
+	wal := func(c *database.Config) { c.JournalMode, c.MaxOpenConns = "wal", 8 }
+
+	// A deferred transaction's first write fails at once if another writer
+	// committed since its read; the busy timeout doesn't help
+	deferred := dbtest.NewTestDBWith(t, dbtest.Options{Config: wal})
+	if errs := readThenWrite(deferred, deferred.Transaction); len(errs) == 0 {
+		t.Error("no deferred transaction failed; the test doesn't provoke the upgrade")
+	} else {
+		t.Logf("%d of 80 deferred transactions failed: %v", len(errs), errs[0])
+	}
+
+	immediate := dbtest.NewTestDBWith(t, dbtest.Options{Config: wal})
+	if errs := readThenWrite(immediate, immediate.WriteTransaction); len(errs) > 0 {
+		t.Errorf("%d of 80 WriteTransactions failed: %v", len(errs), errs[0])
+	}
+
+	always := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		wal(c)
+		c.ImmediateWriteTx = true
+	}})
+	if errs := readThenWrite(always, always.Transaction); len(errs) > 0 {
+		t.Errorf("%d of 80 transactions failed with ImmediateWriteTx: %v", len(errs), errs[0])
+	}
+	if n, err := always.Q.CountUsersByStatus(context.Background(), database.StatusActive); err != nil || n != 80 {
+		t.Errorf("%d users, %v; want every transaction's", n, err)
+	}
+}
@@ -1,18 +1,13 @@
 // ============================================================================
-// SQLite Init (Default) - This is the active init file
+// Open / Init - opens the database for the dialect picked by Config.Driver
 // ============================================================================
 //
-// DRIVER: github.com/mattn/go-sqlite3 (requires CGO)
-//   For CGO-free alternative: modernc.org/sqlite
+// DIALECTS (chosen at build time, see dialect_*.go):
+//   go build                  - SQLite   (Driver: "sqlite3" or "sqlite")
+//   go build -tags postgres   - PostgreSQL (Driver: "pgx" or "postgres")
 //
-// DSN FORMAT:
-//   file.db              - Regular file
-//   :memory:             - In-memory database
-//   file.db?mode=ro      - Read-only
-//   file.db?_journal=WAL - Write-Ahead Logging (better concurrency)
-//
-// TO SWITCH DATABASE:
-//   See init_postgresql.go.example or init_mysql.go.example
+// Each dialect embeds its own sql/<dialect>/schema.sql and has its own
+// sqlc-generated code, so a SQLite binary never links pgx and vice versa.
 // ============================================================================
 
 package database
@@ -20,134 +15,531 @@ package database
 import (
 	"context"
 	"database/sql"
-	_ "embed"
+	"errors"
 	"fmt"
 	"log"
-	"sync"
-
-	_ "github.com/mattn/go-sqlite3" // SQLite driver (CGO required)
-	// Alternative CGO-free driver:
-	// _ "modernc.org/sqlite"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 )
 
-//go:embed schema.sql
-var schemaSQL string
+// Config holds database configuration. The config tags are the keys of
+// ConfigFromFile and ConfigFromEnv.
+type Config struct {
+	Driver   string `config:"driver"`    // "sqlite3", "sqlite" (modernc), "pgx" or "postgres" (lib/pq)
+	DSN      string `config:"dsn"`       // Database file path, :memory: or connection string (SQLite: empty = DefaultDBPath(AppName))
+	AppName  string `config:"app_name"`  // SQLite: names the default database's directory and file (default "app")
+	LogLevel string `config:"log_level"` // "silent", "error", "warn", "info"
 
-// DB holds the database connection and query interface
-type DB struct {
-	Conn *sql.DB
-	Q    *Queries
-}
+	SkipMigrations bool   `config:"skip_migrations"` // Open leaves the schema alone, for tools that only look
+	SchemaCheck    string `config:"schema_check"`    // Compare the live tables, columns and indexes with the embedded schema: "warn" (default) logs drift, "fail" makes Open fail, "off"
 
-// Global instance
-var (
-	instance *DB
-	once     sync.Once
-)
+	// Open the database read-only, for reporting and replica-only
+	// instances: mode=ro on SQLite, default_transaction_read_only on
+	// PostgreSQL. Migrations are skipped, and writes fail with ErrReadOnly,
+	// most before they're sent. Settings that write on a schedule, and
+	// ShadowDSN, are rejected with it.
+	ReadOnly bool `config:"read_only"`
 
-// Config holds database configuration
-type Config struct {
-	Driver   string // "sqlite3" or "sqlite" (for modernc)
-	DSN      string // Database file path or :memory:
-	LogLevel string // "silent", "error", "warn", "info"
+	// Read replicas, opened with the same Driver and settings. Reads made
+	// through db.Q outside a transaction go to them in turn; writes,
+	// transactions and raw queries stay on DSN. ContextWithPrimaryReads
+	// sends a read to DSN anyway. On SQLite in WAL mode, the same file
+	// with ?mode=ro makes a pool of readers next to one writer.
+	ReadDSNs []string `config:"read_dsns"`
+
+	// Startup in containers, where the database may come up after the app:
+	// each ping gets ConnectTimeout (0 = 10s, negative = only ctx's
+	// deadline), and one that can't reach the database is retried
+	// ConnectRetries times (negative = until ctx ends), ConnectBackoff
+	// apart (0 = 500ms), doubling up to 30s
+	ConnectTimeout time.Duration `config:"connect_timeout"`
+	ConnectRetries int           `config:"connect_retries"`
+	ConnectBackoff time.Duration `config:"connect_backoff"`
+
+	MaxTreeDepth int   `config:"max_tree_depth"` // Recursion cap for the category tree queries (default 100)
+	MaxBlobSize  int64 `config:"max_blob_size"`  // Largest attachment PutAttachment accepts, in bytes (default 1 MiB)
+
+	Breaker BreakerOptions `config:"breaker"` // Fail fast while the database is unreachable (off by default)
+	Backup  BackupSchedule `config:"backup"`  // SQLite: back up every Backup.Interval from Open until Close (0 = no scheduled backups)
+
+	// Deadline for every statement whose context has none, so a stuck
+	// query can't hold a connection forever (0 = none). Running out is
+	// a *QueryTimeoutError (errors.Is ErrQueryTimeout); see
+	// ContextWithoutQueryTimeout for the statements that need longer.
+	QueryTimeout time.Duration `config:"query_timeout"`
+
+	// Timeouts, retries and circuit breakers for particular queries, or
+	// for all reads or writes, see QueryPolicy. The policies are a list of
+	// sections, so they can only come from a file.
+	QueryPolicies []QueryPolicy `config:"query_policies"`
+
+	Maintenance MaintenanceSchedule `config:"maintenance"` // SQLite: checkpoint, ANALYZE and vacuum on a schedule from Open until Close (all 0 = none)
+	Replication ReplicationConfig   `config:"replication"` // SQLite: the settings and events a WAL replicator such as Litestream needs (off by default)
+
+	AuditRetention time.Duration `config:"audit_retention"` // Prune audit log entries older than this every hour from Open until Close (0 = keep them all)
+
+	// Delete or archive old rows of the tables that only grow, on a
+	// schedule from Open until Close (no rules = none). The rules are a
+	// list of sections, so they can only come from a file.
+	Retention RetentionPolicy `config:"retention"`
+
+	MaxConcurrentWrites int  `config:"max_concurrent_writes"` // Transactions and writes allowed at once, the rest queue (0 = unlimited)
+	ImmediateWriteTx    bool `config:"immediate_write_tx"`    // SQLite: Transaction and InTx take the write lock up front, like WriteTransaction
+
+	MaxOpenConns int `config:"max_open_conns"` // Pool size (0 = dialect default: 1 for SQLite, NumCPU in WAL mode; unlimited elsewhere)
+	MaxIdleConns int `config:"max_idle_conns"` // Idle connections kept (0 = 2, negative = none)
+
+	ConnMaxLifetime time.Duration `config:"conn_max_lifetime"`  // Connections are replaced after this long (0 = dialect default: forever for SQLite, 30m elsewhere; negative = forever)
+	ConnMaxIdleTime time.Duration `config:"conn_max_idle_time"` // Idle connections are closed after this long (0 = dialect default: forever for SQLite, 5m elsewhere; negative = forever)
+
+	// PostgreSQL with pgx: a pgxpool.Pool holds the connections instead of
+	// database/sql, with its health checks and a minimum kept open. Setting
+	// any field turns it on and takes over from the four settings above,
+	// which must be 0 then. DB.PgxPool returns the pool.
+	PgxPool PgxPoolConfig `config:"pgx_pool"`
+
+	BusyTimeout time.Duration `config:"busy_timeout"` // SQLite: how long to wait for a lock before "database is locked" (0 = 5s, negative = leave to the DSN)
+
+	// SQLite pragmas, set on every connection as it opens and read back by
+	// Open, which fails if one didn't take. Empty or 0 leaves them to the
+	// DSN and SQLite's defaults; setting one both here and in the DSN is
+	// an error. CacheSize is as PRAGMA cache_size takes it: pages, or KiB
+	// if negative. Foreign keys are on unless NoForeignKeys says otherwise.
+	JournalMode   string `config:"journal_mode"` // "wal", "delete", "truncate", "persist", "memory" or "off"
+	Synchronous   string `config:"synchronous"`  // "off", "normal", "full" or "extra"
+	CacheSize     int    `config:"cache_size"`
+	NoForeignKeys bool   `config:"no_foreign_keys"`
+
+	SQLiteFuncs []CustomFunc `config:"-"` // SQLite: extra SQL functions on every connection (regexp is always there)
+
+	// SQLite: extra collations on every connection, by name (NOCASE_UNICODE
+	// is always there). They must be safe for concurrent use. Indexes built
+	// with one go stale if its order changes; run REINDEX <name> then.
+	Collations map[string]CollationFunc `config:"-"`
+
+	// SQLite: shared libraries loaded into every connection, with the
+	// default entry point. Needs mattn/go-sqlite3 and -tags sqlite_extensions.
+	// An extension is native code running with the privileges of the
+	// process, so only list files nobody else can write to; the SQL
+	// function load_extension() stays disabled either way.
+	SQLiteExtensions []string `config:"sqlite_extensions"`
+
+	// SQLite: more databases attached to every connection, for queries
+	// across them (SELECT ... FROM ref.countries), see AttachedDB
+	Attach []AttachedDB `config:"attach"`
+
+	SlowQueries  int  `config:"slow_queries"`  // Slowest executions kept per query for DB.SlowQueries (0 = off)
+	QueryLatency bool `config:"query_latency"` // Track latency percentiles per query for DB.LatencySnapshot
+
+	// Prepare each generated query once per connection and reuse it, see
+	// DB.StatementCacheStats. Migrations run through DB drop the cache.
+	StatementCache bool `config:"statement_cache"`
+
+	// Log every statement (name, duration, rows, request and trace ID) to
+	// QueryLogger, or slog.Default() if that's nil. Setting QueryLogger
+	// turns it on too. LogQueryArgs adds the arguments (LogArgsRedacted or
+	// LogArgsFull). With LogSlowerThan set, only statements that took at
+	// least that long are logged, at warn level; failed ones always are.
+	LogQueries    bool          `config:"log_queries"`
+	QueryLogger   *slog.Logger  `config:"-"`
+	LogQueryArgs  string        `config:"log_query_args"`
+	LogSlowerThan time.Duration `config:"log_slower_than"`
+
+	// An OpenTelemetry span for every statement, named after its query,
+	// and for every transaction, from the global TracerProvider. Needs a
+	// build with -tags otel.
+	TraceQueries bool `config:"trace_queries"`
+
+	// What the query log, the slow query log and the spans show of the
+	// arguments, by query, column or position (default: none of them,
+	// RedactAll), see RedactionPolicy
+	Redaction RedactionPolicy `config:"redaction"`
+
+	Hooks []QueryHook `config:"-"` // Run around every statement, see QueryHook
+
+	CursorSecret string        `config:"cursor_secret"` // Signs pagination cursors (random per Open if empty, so cursors die with the process)
+	CursorTTL    time.Duration `config:"cursor_ttl"`    // Pagination cursors older than this are rejected (0 = never expire)
+
+	// Keys for DB.EncryptField, "<version>:<base64 key>" separated by commas
+	// (see ParseFieldKey). The first encrypts; the others only decrypt, until
+	// RotateFieldKeys has moved everything off them. FieldIndexKey is the
+	// base64 key for DB.BlindIndex, which never changes. FieldKeys, if set,
+	// replaces both. A DB without any can't encrypt or decrypt fields.
+	FieldEncryptionKey string      `config:"field_encryption_key"`
+	FieldIndexKey      string      `config:"field_index_key"`
+	FieldKeys          KeyProvider `config:"-"`
+
+	// SQLite: open the database encrypted with SQLCipher, giving every
+	// connection this key (PRAGMA key) before it reads anything. A
+	// passphrase, or a raw key as x'<64 hex digits>'. Needs mattn/go-sqlite3
+	// built against SQLCipher; a wrong key fails Open with
+	// ErrWrongEncryptionKey. DB.Rekey changes it.
+	EncryptionKey string `config:"encryption_key"`
+
+	ExactCountBelow int64 `config:"exact_count_below"` // ApproxCount counts exactly when the estimate is below this (default 10000)
+
+	// A second database every successful write is replayed on, in the
+	// background, to compare the two before moving to it (see
+	// DB.ShadowReport). ShadowDriver defaults to Driver and must be of the
+	// same dialect. Reads only ever go to the primary.
+	ShadowDSN    string `config:"shadow_dsn"`
+	ShadowDriver string `config:"shadow_driver"`
 }
 
-// DefaultConfig returns default SQLite configuration
+// CustomFunc is a scalar SQL function installed on every SQLite connection.
+// Impl is a Go func as mattn/go-sqlite3's RegisterFunc takes it: arguments
+// and result of the types database/sql uses (int64, float64, string,
+// []byte, bool, any), optionally followed by an error, which fails the query.
+type CustomFunc struct {
+	Name string
+	Impl any
+	Pure bool // Same arguments, same result; lets SQLite use it in indexes and CHECKs
+}
+
+// CollationFunc compares two strings for a collation like strings.Compare:
+// negative, zero or positive
+type CollationFunc func(a, b string) int
+
+// DefaultConfig returns default SQLite configuration, with the database
+// in the per-user data directory (see DefaultDBPath)
 func DefaultConfig() Config {
 	return Config{
 		Driver:   "sqlite3",
-		DSN:      "app.db",
+		AppName:  "app",
 		LogLevel: "error",
 	}
 }
 
-// Init initializes the database with the given configuration
-func Init(cfg Config) (*DB, error) {
-	var initErr error
+// DefaultDBPath is where Open puts a SQLite database when Config.DSN is
+// empty: <data dir>/<appName>/<appName>.db, with the data dir being
+// $XDG_DATA_HOME or ~/.local/share on Linux and other Unixes,
+// ~/Library/Application Support on macOS and %AppData% on Windows. It
+// doesn't create anything; Open creates the directory with 0700.
+func DefaultDBPath(appName string) (string, error) {
+	if appName == "" {
+		appName = "app"
+	}
+	if appName != filepath.Base(appName) || appName == "." || appName == ".." {
+		return "", fmt.Errorf("app name %q must not contain a path", appName)
+	}
+
+	var dir string
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios", "plan9":
+		var err error
+		if dir, err = os.UserConfigDir(); err != nil {
+			return "", err
+		}
+	default:
+		dir = os.Getenv("XDG_DATA_HOME")
+		if !filepath.IsAbs(dir) { // unset, or relative, which the spec says to ignore
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(home, ".local", "share")
+		}
+	}
+	return filepath.Join(dir, appName, appName+".db"), nil
+}
+
+// migrate runs the embedded migrations and schema, then the dialect's own
+// upkeep
+func migrate(ctx context.Context, d *dialect, conn *sql.DB) error {
+	if err := runMigrations(ctx, d, conn); err != nil {
+		return fmt.Errorf("failed to run schema migrations: %w", err)
+	}
+	if d.migrate != nil {
+		if err := d.migrate(ctx, conn); err != nil {
+			return fmt.Errorf("failed to run schema migrations: %w", err)
+		}
+	}
+	return nil
+}
+
+// Open connects to the database and runs the schema migrations. Every call
+// returns a new, independent *DB that the caller owns and closes; use it
+// when you'd rather pass the database around than reach for Get.
+func Open(cfg Config) (*DB, error) {
+	return OpenContext(context.Background(), cfg)
+}
+
+// OpenContext is Open giving up once ctx ends, while it connects, retries
+// or migrates
+func OpenContext(ctx context.Context, cfg Config) (*DB, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	d, err := dialectFor(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fieldKeysFor(cfg); err != nil {
+		return nil, err
+	}
+
+	driver := cfg.Driver
+	if driver == "" {
+		driver = d.drivers[0]
+	}
 
-	once.Do(func() {
-		driver := cfg.Driver
-		if driver == "" {
-			driver = "sqlite3"
+	dsn := cfg.DSN
+	var applied []string
+	if d.dsnDefaults != nil {
+		if dsn, applied, err = d.dsnDefaults(driver, cfg); err != nil {
+			return nil, redactError(err, driver, cfg.DSN)
 		}
+	}
+
+	open := sql.Open
+	if d.open != nil {
+		open = func(driver, dsn string) (*sql.DB, error) { return d.open(driver, dsn, cfg) }
+	}
+	// Driver errors may quote the DSN, password included; these end up in
+	// logs and error trackers
+	redact := func(err error) error { return redactError(err, driver, dsn) }
 
-		conn, err := sql.Open(driver, cfg.DSN)
-		if err != nil {
-			initErr = fmt.Errorf("failed to open database: %w", err)
-			return
+	conn, err := open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", RedactDSN(driver, dsn), redact(err))
+	}
+
+	if err := ping(ctx, conn, cfg, redact); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to ping database %s: %w", RedactDSN(driver, dsn), redact(err))
+	}
+
+	if d.checkVersion != nil {
+		if err := d.checkVersion(ctx, conn); err != nil {
+			conn.Close()
+			return nil, redact(err)
 		}
+	}
 
-		// Test connection
-		if err := conn.Ping(); err != nil {
-			initErr = fmt.Errorf("failed to ping database: %w", err)
-			return
+	if d.checkDSN != nil {
+		if err := d.checkDSN(ctx, conn, cfg); err != nil {
+			conn.Close()
+			return nil, redact(err)
 		}
+	}
 
-		// Run schema migrations
-		if _, err := conn.ExecContext(context.Background(), schemaSQL); err != nil {
-			initErr = fmt.Errorf("failed to run schema migrations: %w", err)
-			return
+	maxIdle := cfg.MaxIdleConns
+	if cfg.PgxPool.enabled() {
+		// The pgxpool.Pool underneath sizes and recycles the connections;
+		// database/sql hands each one back to it as soon as it's done
+		maxIdle = -1
+	} else {
+		maxOpen := cfg.MaxOpenConns
+		if maxOpen == 0 && d.poolDefaults != nil {
+			n, why, err := d.poolDefaults(ctx, conn)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			maxOpen = n
+			applied = append(applied, fmt.Sprintf("MaxOpenConns=%d (%s)", n, why))
 		}
+		if maxOpen > 0 {
+			conn.SetMaxOpenConns(maxOpen)
+		}
+		if maxIdle == 0 {
+			maxIdle = defaultMaxIdleConns
+		}
+		conn.SetConnMaxLifetime(poolDuration(cfg.ConnMaxLifetime, d.connMaxLifetime))
+		conn.SetConnMaxIdleTime(poolDuration(cfg.ConnMaxIdleTime, d.connMaxIdleTime))
+	}
+	conn.SetMaxIdleConns(maxIdle)
 
-		instance = &DB{
-			Conn: conn,
-			Q:    New(conn),
+	if len(applied) > 0 && cfg.LogLevel == "info" {
+		log.Printf("%s defaults applied: %s", d.name, strings.Join(applied, ", "))
+	}
+	if d.journalMode != nil && cfg.LogLevel == "info" {
+		if mode, err := d.journalMode(ctx, conn); err == nil {
+			log.Printf("%s journal mode: %s", d.name, mode)
 		}
+	}
 
-		if cfg.LogLevel != "silent" {
-			log.Println("SQLite connected successfully!")
+	if !cfg.SkipMigrations && !cfg.ReadOnly {
+		if err := migrate(ctx, d, conn); err != nil {
+			conn.Close()
+			return nil, err
 		}
-	})
+	}
+	if err := checkSchema(ctx, d, conn, cfg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	db, err := newDB(ctx, cfg, d, conn, maxIdle)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	db.dsn = dsn
 
-	if initErr != nil {
-		return nil, initErr
+	if cfg.LogLevel != "silent" {
+		log.Printf("%s connected successfully! (%s)", d.name, RedactDSN(driver, dsn))
 	}
 
-	return instance, nil
+	return db, nil
 }
 
-// MustInit initializes the database and panics on error
-func MustInit(cfg Config) *DB {
-	db, err := Init(cfg)
+// newDB builds the DB around conn that Open and NewFromConn return, with
+// the middleware, replicas, shadow and background jobs cfg asks for.
+// Closing the DB on an error leaves conn to the caller.
+func newDB(ctx context.Context, cfg Config, d *dialect, conn *sql.DB, maxIdle int) (*DB, error) {
+	keys, err := fieldKeysFor(cfg)
 	if err != nil {
-		log.Fatalf("Database initialization failed: %v", err)
+		return nil, err
+	}
+	argRedactor := newRedactor(cfg.Redaction)
+	queryLog, err := newQueryLog(cfg.LogQueries, cfg.QueryLogger, cfg.LogQueryArgs, cfg.LogSlowerThan, argRedactor)
+	if err != nil {
+		return nil, err
+	}
+	tracer, err := newQueryTracer(cfg.TraceQueries, argRedactor)
+	if err != nil {
+		return nil, err
+	}
+	replicas, err := openReplicas(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open read replica: %w", err)
 	}
-	return db
-}
 
-// Get returns the global database instance
-func Get() *DB {
-	if instance == nil {
-		log.Fatal("Database not initialized. Call Init() first.")
+	db := &DB{
+		Conn:            conn,
+		replicas:        replicas,
+		maxTreeDepth:    cfg.MaxTreeDepth,
+		maxBlobSize:     cfg.MaxBlobSize,
+		maxIdleConns:    maxIdle,
+		breaker:         newBreaker(cfg.Breaker),
+		queryTimeout:    cfg.QueryTimeout,
+		policies:        newQueryPolicies(cfg.QueryPolicies),
+		writes:          newWriteLimiter(cfg.MaxConcurrentWrites),
+		slow:            newSlowLog(cfg.SlowQueries, argRedactor),
+		latency:         newLatencyStats(cfg.QueryLatency),
+		stmts:           newStmtCache(cfg.StatementCache, conn),
+		queryLog:        queryLog,
+		tracer:          tracer,
+		cursorSecret:    cursorSecret(cfg.CursorSecret),
+		cursorTTL:       cfg.CursorTTL,
+		keys:            keys,
+		exactCountBelow: cfg.ExactCountBelow,
+		immediateTx:     cfg.ImmediateWriteTx,
+		readOnly:        cfg.ReadOnly,
+		replication:     replicationState{onEvent: cfg.Replication.OnEvent, external: cfg.Replication.ExternalCheckpoints},
+		hooks:           cfg.Hooks,
+		schemaCfg:       scratchConfig(cfg),
+	}
+	db.Q = translatingQuerier{New(db.wrap(db.routeReads(conn)))}
+	if cfg.ShadowDSN != "" {
+		db.shadow = openShadow(cfg, d)
+		if db.shadow.db != nil {
+			db.Q = &shadowQuerier{Querier: db.Q, m: db.shadow}
+		}
+	}
+	if cfg.Backup.Interval > 0 {
+		loopCtx, stop := context.WithCancel(context.Background())
+		if err := db.StartBackupLoop(loopCtx, cfg.Backup); err != nil {
+			stop()
+			db.stop()
+			return nil, err
+		}
+		db.backups.stop = stop
+	}
+	if cfg.AuditRetention > 0 {
+		loopCtx, stop := context.WithCancel(context.Background())
+		if err := db.StartAuditPruneLoop(loopCtx, cfg.AuditRetention); err != nil {
+			stop()
+			db.stop()
+			return nil, err
+		}
+		db.stopAuditPrune = stop
+	}
+	if cfg.Retention.enabled() {
+		loopCtx, stop := context.WithCancel(context.Background())
+		if err := db.StartRetentionLoop(loopCtx, cfg.Retention); err != nil {
+			stop()
+			db.stop()
+			return nil, err
+		}
+		db.stopRetention = stop
+	}
+	if cfg.Maintenance.enabled() {
+		loopCtx, stop := context.WithCancel(context.Background())
+		if err := db.StartMaintenanceLoop(loopCtx, cfg.Maintenance); err != nil {
+			stop()
+			db.stop()
+			return nil, err
+		}
+		db.maintenance.stop = stop
 	}
-	return instance
+	if cfg.Replication.SizeInterval > 0 && cfg.Replication.OnEvent != nil {
+		loopCtx, stop := context.WithCancel(context.Background())
+		db.startSizeEvents(loopCtx, cfg.Replication.SizeInterval)
+		db.replication.stop = stop
+	}
+	return db, nil
 }
 
-// Close closes the database connection
-func Close() error {
-	if instance != nil && instance.Conn != nil {
-		return instance.Conn.Close()
+// poolDuration resolves a ConnMaxLifetime-style setting: zero takes the
+// dialect's default, negative means forever (0 to database/sql)
+func poolDuration(configured, dialectDefault time.Duration) time.Duration {
+	switch {
+	case configured < 0:
+		return 0
+	case configured == 0:
+		return dialectDefault
 	}
-	return nil
+	return configured
 }
 
-// Transaction executes a function within a database transaction
-func (db *DB) Transaction(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := db.Conn.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+// Startup connection defaults, see Config.ConnectTimeout
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultConnectBackoff = 500 * time.Millisecond
+	maxConnectBackoff     = 30 * time.Second
+)
+
+// ping waits for the database to answer, retrying attempts that couldn't
+// reach it or timed out as cfg allows
+func ping(ctx context.Context, conn *sql.DB, cfg Config, redact func(error) error) error {
+	timeout := cfg.ConnectTimeout
+	if timeout == 0 {
+		timeout = defaultConnectTimeout
+	}
+	backoff := cfg.ConnectBackoff
+	if backoff <= 0 {
+		backoff = defaultConnectBackoff
 	}
 
-	if err := fn(db.Q.WithTx(tx)); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx error: %v, rollback error: %w", err, rbErr)
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		err := conn.PingContext(attemptCtx)
+		cancel()
+		if err == nil {
+			return nil
 		}
-		return err
-	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		unreachable := isConnectionError(err) || (errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil)
+		if !unreachable || (cfg.ConnectRetries >= 0 && attempt > cfg.ConnectRetries) {
+			return err
+		}
+		if cfg.LogLevel != "silent" {
+			log.Printf("database not reachable (attempt %d): %v; retrying in %s", attempt, redact(err), backoff)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxConnectBackoff)
 	}
-
-	return nil
 }
@@ -0,0 +1,20 @@
+package database
+
+import "context"
+
+// InsertReturningID runs an INSERT written without a RETURNING clause and
+// returns the new row's id. The strategy comes from the dialect compiled
+// into the binary: LastInsertId on SQLite and MySQL, RETURNING id on
+// PostgreSQL, where lib/pq doesn't implement LastInsertId at all. Prefer
+// a :one query with RETURNING *; this is for SQL built at runtime.
+func InsertReturningID(ctx context.Context, dbtx DBTX, query string, args ...any) (int64, error) {
+	return defaultDialect().insertID(ctx, dbtx, query, args...)
+}
+
+func lastInsertID(ctx context.Context, dbtx DBTX, query string, args ...any) (int64, error) {
+	res, err := dbtx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
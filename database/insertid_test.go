@@ -0,0 +1,27 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestInsertReturningID(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	for _, telegramID := range []int64{20, 10} {
+		id, err := database.InsertReturningID(ctx, db.DBTX(),
+			fmt.Sprintf("INSERT INTO groups (telegram_id) VALUES (%d);", telegramID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := db.Q.GetGroupByTelegramID(ctx, telegramID)
+		if err != nil || g.ID != id {
+			t.Errorf("group %d: id %d, %v; InsertReturningID said %d", telegramID, g.ID, err, id)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// SchemaInfo is the database's schema as Introspect finds it, the same
+// shape for every dialect
+type SchemaInfo struct {
+	Tables []TableInfo `json:"tables"` // By name
+}
+
+// TableInfo describes one table or view
+type TableInfo struct {
+	Name        string           `json:"name"`
+	View        bool             `json:"view,omitempty"`
+	Internal    bool             `json:"internal,omitempty"` // Kept by the package itself (history, upkeep), not app data
+	Columns     []ColumnInfo     `json:"columns"`            // In declaration order
+	Indexes     []IndexInfo      `json:"indexes,omitempty"`  // By name
+	ForeignKeys []ForeignKeyInfo `json:"foreign_keys,omitempty"`
+}
+
+// ColumnInfo describes one column
+type ColumnInfo struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"` // As declared (SQLite) or as information_schema names it (PostgreSQL)
+	Nullable   bool    `json:"nullable"`
+	Default    *string `json:"default,omitempty"`     // SQL expression, nil if none
+	PrimaryKey int     `json:"primary_key,omitempty"` // Position in the primary key, from 1; 0 if not in it
+	Generated  bool    `json:"generated,omitempty"`   // GENERATED ALWAYS AS (...), computed and never written
+}
+
+// IndexInfo describes one index. SQLite has none for an INTEGER PRIMARY
+// KEY, which is the table's rowid.
+type IndexInfo struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary,omitempty"`
+	Columns []string `json:"columns"` // "" for an expression, like lower(email)
+}
+
+// ForeignKeyInfo describes one foreign key
+type ForeignKeyInfo struct {
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+	OnDelete   string   `json:"on_delete"` // "NO ACTION", "RESTRICT", "CASCADE", "SET NULL" or "SET DEFAULT"
+	OnUpdate   string   `json:"on_update"`
+}
+
+// The tables schema.sql creates for the package's own bookkeeping. Keep
+// in step with the schema.
+var internalTables = map[string]bool{
+	"collation_versions": true,
+	"schema_migrations":  true,
+	"storage_probe":      true,
+	"user_history":       true,
+	"group_history":      true,
+	"audit_log":          true,
+	"audit_actor":        true,
+	"locks":              true,
+
+	// SQLite's FTS5 search index: the virtual tables and their shadow
+	// tables
+	"users_fts":          true,
+	"users_fts_config":   true,
+	"users_fts_data":     true,
+	"users_fts_docsize":  true,
+	"users_fts_idx":      true,
+	"groups_fts":         true,
+	"groups_fts_config":  true,
+	"groups_fts_data":    true,
+	"groups_fts_docsize": true,
+	"groups_fts_idx":     true,
+}
+
+// Introspect reads tables, views, columns, indexes and foreign keys from
+// the database itself, for tools that work on any table. SQLite's own
+// sqlite_* tables are left out.
+func (db *DB) Introspect(ctx context.Context) (SchemaInfo, error) {
+	d := defaultDialect()
+	if d.introspect == nil {
+		return SchemaInfo{}, fmt.Errorf("%s has no introspection here", d.name)
+	}
+	info, err := d.introspect(ctx, db.Conn)
+	if err != nil {
+		return SchemaInfo{}, fmt.Errorf("failed to introspect schema: %w", err)
+	}
+	for i := range info.Tables {
+		info.Tables[i].Internal = internalTables[info.Tables[i].Name]
+	}
+	return info, nil
+}
+
+// Table finds a table or view by name
+func (s SchemaInfo) Table(name string) (TableInfo, bool) {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return TableInfo{}, false
+}
+
+// PrimaryKey lists the table's primary key columns in key order
+func (t TableInfo) PrimaryKey() []string {
+	var pk []ColumnInfo
+	for _, c := range t.Columns {
+		if c.PrimaryKey > 0 {
+			pk = append(pk, c)
+		}
+	}
+	slices.SortFunc(pk, func(a, b ColumnInfo) int { return a.PrimaryKey - b.PrimaryKey })
+
+	cols := make([]string, len(pk))
+	for i, c := range pk {
+		cols[i] = c.Name
+	}
+	return cols
+}
@@ -0,0 +1,95 @@
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"slices"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got, as indented JSON, with the golden file path,
+// or writes it there with -update
+func checkGolden(t *testing.T, path string, got any) {
+	t.Helper()
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+	if *update {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Skipf("%s is missing: run the test with -update to write it", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("%s is out of date (run the test with -update and review the diff); got:\n%s", path, data)
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	if _, err := db.Conn.Exec("CREATE VIEW active_users AS SELECT id, first_name FROM users WHERE status = 'active'"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := db.Introspect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, ok := info.Table("users")
+	if !ok || users.View || users.Internal {
+		t.Fatalf("users: %+v, %v; want an app table", users, ok)
+	}
+	if pk := users.PrimaryKey(); !slices.Equal(pk, []string{"id"}) {
+		t.Errorf("users' primary key %v, want id", pk)
+	}
+	var telegramID *database.ColumnInfo
+	for i, c := range users.Columns {
+		if c.Name == "telegram_id" {
+			telegramID = &users.Columns[i]
+		}
+	}
+	if telegramID == nil || telegramID.Nullable || telegramID.Default != nil {
+		t.Errorf("users.telegram_id: %+v, want NOT NULL without a default", telegramID)
+	}
+	if !slices.ContainsFunc(users.Indexes, func(ix database.IndexInfo) bool {
+		return ix.Unique && slices.Equal(ix.Columns, []string{"telegram_id"})
+	}) {
+		t.Errorf("users' indexes %+v, want a unique one on telegram_id", users.Indexes)
+	}
+
+	ug, _ := info.Table("user_group")
+	actions := map[string]string{}
+	for _, fk := range ug.ForeignKeys {
+		actions[fk.RefTable+"."+fk.RefColumns[0]] = fk.OnDelete
+	}
+	if actions["users.telegram_id"] != "CASCADE" || actions["groups.telegram_id"] != "RESTRICT" {
+		t.Errorf("user_group's foreign keys %+v, want CASCADE to users and RESTRICT to groups", ug.ForeignKeys)
+	}
+
+	if v, ok := info.Table("active_users"); !ok || !v.View || len(v.Columns) != 2 {
+		t.Errorf("a view: %+v, %v; want it flagged, with its columns", v, ok)
+	}
+	for _, name := range []string{"schema_migrations", "audit_log", "user_history"} {
+		if tbl, ok := info.Table(name); !ok || !tbl.Internal {
+			t.Errorf("%s: %+v, %v; want it flagged internal", name, tbl, ok)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// JournalMode reports how SQLite journals writes: "wal", "delete",
+// "truncate", "persist", "memory" or "off"
+func (db *DB) JournalMode(ctx context.Context) (string, error) {
+	d := defaultDialect()
+	if d.journalMode == nil {
+		return "", fmt.Errorf("%s has no journal modes", d.name)
+	}
+	return d.journalMode(ctx, db.Conn)
+}
+
+// SetJournalMode moves a live database between "wal" and "delete", for
+// databases created before WAL was the plan. The switch needs the
+// database to itself, so it's retried with backoff while other
+// connections are reading, until ctx ends; the mode is read back
+// afterwards. WAL is refused where it can't work: a read-only or
+// in-memory DSN, or a file on a network filesystem (detected on Linux),
+// where its shared memory isn't shared. The other modes only last as
+// long as a connection, so put them in the DSN (_journal_mode=...).
+//
+// The pool keeps the size Open picked for the old mode; open the database
+// again to get the WAL default.
+func (db *DB) SetJournalMode(ctx context.Context, mode string) error {
+	d := defaultDialect()
+	if d.setJournalMode == nil {
+		return fmt.Errorf("%s has no journal modes", d.name)
+	}
+	return d.setJournalMode(ctx, db.Conn, db.dsn, mode)
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONColumn is a column holding T as JSON: TEXT in SQLite, json or jsonb
+// in PostgreSQL. Value marshals V and Scan unmarshals it, so code works
+// with T instead of a string it marshals by hand; give the column to sqlc
+// as an override:
+//
+//	overrides:
+//	  - column: "users.settings"
+//	    go_type:
+//	      type: "JSONColumn[UserSettings]"
+//
+// Valid false is NULL, as with sql.Null[T]. Numbers decoded into an any
+// (map[string]any, []any) are json.Number, so that ids past 2^53 stay
+// exact. The model's own JSON has V as an object, not a string of it.
+type JSONColumn[T any] struct {
+	V     T
+	Valid bool
+}
+
+// JSONOf returns a valid JSONColumn holding v
+func JSONOf[T any](v T) JSONColumn[T] {
+	return JSONColumn[T]{V: v, Valid: true}
+}
+
+// Value marshals V, as a string: mattn/go-sqlite3 stores []byte as a
+// BLOB, which SQLite's JSON functions refuse
+func (j JSONColumn[T]) Value() (driver.Value, error) {
+	if !j.Valid {
+		return nil, nil
+	}
+	b, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON column: %w", err)
+	}
+	return string(b), nil
+}
+
+// Scan unmarshals text or bytes into V, and sets Valid false for NULL
+func (j *JSONColumn[T]) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*j = JSONColumn[T]{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("JSONColumn can't scan %T", src)
+	}
+	var v T
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON column: %w", err)
+	}
+	*j = JSONColumn[T]{V: v, Valid: true}
+	return nil
+}
+
+func (j JSONColumn[T]) MarshalJSON() ([]byte, error) {
+	if !j.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(j.V)
+}
+
+func (j *JSONColumn[T]) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*j = JSONColumn[T]{}
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*j = JSONColumn[T]{V: v, Valid: true}
+	return nil
+}
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"your-project/database/ids"
+)
+
+// KeyBackfill is the first half of moving a table from an integer key to
+// UUIDs or ULIDs (see package ids): BackfillKeys adds Column next to the
+// integer key and gives every row an ID in it, and does the same for the
+// columns referencing the table, so a migration can then swap the keys
+// over without computing anything. Rows inserted after it ran have no ID
+// yet, so run it again right before that migration, with writes stopped.
+type KeyBackfill struct {
+	Table     string // The integer-keyed table
+	Key       string // Its key (default id)
+	Column    string // Column for the new IDs (default uid)
+	Kind      string // "uuid", version 7 (default), or "ulid"
+	BatchSize int    // Rows per transaction (default 1000)
+}
+
+// KeyBackfillResult is what BackfillKeys did
+type KeyBackfillResult struct {
+	Rows       int64    // Rows of Table given an ID
+	References []string // Columns filled with the ID of the row they reference, as table.column
+}
+
+func (b KeyBackfill) withDefaults() KeyBackfill {
+	b.Key = cmp.Or(b.Key, "id")
+	b.Column = cmp.Or(b.Column, "uid")
+	b.Kind = cmp.Or(b.Kind, "uuid")
+	b.BatchSize = cmp.Or(b.BatchSize, retentionBatch)
+	return b
+}
+
+// refColumn names the new column next to a foreign key column: user_id
+// gets user_uid for the default Key and Column
+func (b KeyBackfill) refColumn(column string) string {
+	if base, ok := strings.CutSuffix(column, "_"+b.Key); ok {
+		return base + "_" + b.Column
+	}
+	return column + "_" + b.Column
+}
+
+// BackfillKeys runs b. It can be run again: columns already there are
+// kept, and only rows whose new column is still NULL are filled. The new
+// column gets a unique index; each table referencing Table through a
+// single-column foreign key gets a column named after the foreign key's
+// (user_id: user_uid), filled in one statement per table.
+//
+// Existing rows get IDs made as they're filled, so among themselves
+// they're in the order of their integer key, not of when they were
+// created.
+func (db *DB) BackfillKeys(ctx context.Context, b KeyBackfill) (KeyBackfillResult, error) {
+	d := defaultDialect()
+	b = b.withDefaults()
+	if db.readOnly {
+		return KeyBackfillResult{}, fmt.Errorf("%w: BackfillKeys writes", ErrReadOnly)
+	}
+	var colType string
+	var newID func() string
+	switch b.Kind {
+	case "uuid":
+		colType, newID = d.uuidColumn, func() string { return ids.NewUUID().String() }
+	case "ulid":
+		colType, newID = d.ulidColumn, func() string { return ids.NewULID().String() }
+	default:
+		return KeyBackfillResult{}, fmt.Errorf("unknown key kind %q (want uuid or ulid)", b.Kind)
+	}
+	if colType == "" {
+		return KeyBackfillResult{}, fmt.Errorf("%s has no key backfill here", d.name)
+	}
+	if !retentionIdent.MatchString(b.Table) || !retentionIdent.MatchString(b.Key) || !retentionIdent.MatchString(b.Column) {
+		return KeyBackfillResult{}, errors.New("table, key and column must be plain names")
+	}
+	if b.BatchSize < 0 {
+		return KeyBackfillResult{}, errors.New("batch size can't be negative")
+	}
+
+	schema, err := db.Introspect(ctx)
+	if err != nil {
+		return KeyBackfillResult{}, err
+	}
+	table, ok := schema.Table(b.Table)
+	if !ok || table.View {
+		return KeyBackfillResult{}, fmt.Errorf("no table %s", b.Table)
+	}
+	if pk := table.PrimaryKey(); !slices.Equal(pk, []string{b.Key}) {
+		return KeyBackfillResult{}, fmt.Errorf("%s's primary key is (%s), not %s", b.Table, strings.Join(pk, ", "), b.Key)
+	}
+	if err := db.addKeyColumn(ctx, table, b.Column, colType); err != nil {
+		return KeyBackfillResult{}, err
+	}
+
+	var res KeyBackfillResult
+	for {
+		n, err := db.backfillBatch(ctx, d, b, newID)
+		res.Rows += n
+		if err != nil {
+			return res, fmt.Errorf("failed to fill %s.%s: %w", b.Table, b.Column, err)
+		}
+		if n < int64(b.BatchSize) {
+			break
+		}
+	}
+	index := fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s_%s_key ON %s (%s)", b.Table, b.Column, b.Table, b.Column)
+	if _, err := db.Conn.ExecContext(ctx, index); err != nil {
+		return res, fmt.Errorf("failed to index %s.%s: %w", b.Table, b.Column, err)
+	}
+
+	for _, t := range schema.Tables {
+		for _, fk := range t.ForeignKeys {
+			if fk.RefTable != b.Table || !slices.Equal(fk.RefColumns, []string{b.Key}) || len(fk.Columns) != 1 {
+				continue
+			}
+			col := b.refColumn(fk.Columns[0])
+			if err := db.addKeyColumn(ctx, t, col, colType); err != nil {
+				return res, err
+			}
+			fill := fmt.Sprintf("UPDATE %s SET %s = (SELECT ref.%s FROM %s AS ref WHERE ref.%s = %s.%s) WHERE %s IS NULL AND %s IS NOT NULL",
+				t.Name, col, b.Column, b.Table, b.Key, t.Name, fk.Columns[0], col, fk.Columns[0])
+			if _, err := db.Conn.ExecContext(ctx, fill); err != nil {
+				return res, fmt.Errorf("failed to fill %s.%s: %w", t.Name, col, err)
+			}
+			res.References = append(res.References, t.Name+"."+col)
+		}
+	}
+	return res, nil
+}
+
+// addKeyColumn adds a nullable column to t, unless it's there already
+func (db *DB) addKeyColumn(ctx context.Context, t TableInfo, column, colType string) error {
+	if slices.ContainsFunc(t.Columns, func(c ColumnInfo) bool { return c.Name == column }) {
+		return nil
+	}
+	if _, err := db.Conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", t.Name, column, colType)); err != nil {
+		return fmt.Errorf("failed to add %s.%s: %w", t.Name, column, err)
+	}
+	return nil
+}
+
+// backfillBatch gives up to b.BatchSize rows without an ID one, in a
+// transaction, and returns how many it found
+func (db *DB) backfillBatch(ctx context.Context, d *dialect, b KeyBackfill, newID func() string) (int64, error) {
+	if err := db.writes.acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer db.writes.release()
+
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s IS NULL ORDER BY %s LIMIT %d",
+		b.Key, b.Table, b.Column, b.Key, b.BatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var keys []int64
+	for rows.Next() {
+		var k int64
+		if err := rows.Scan(&k); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	q := &queryBuilder{numbered: d.numberedParams}
+	update := fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = %s", b.Table, b.Column, q.param(nil), b.Key, q.param(nil))
+	for _, k := range keys {
+		if _, err := tx.ExecContext(ctx, update, newID(), k); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(keys)), tx.Commit()
+}
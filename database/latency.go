@@ -0,0 +1,194 @@
+package database
+
+import (
+	"math/bits"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// QueryLatency is the latency distribution of one query since the DB was
+// opened
+type QueryLatency struct {
+	Query         string // sqlc query name, or "other"
+	Count         uint64
+	P50, P95, P99 time.Duration
+	Max           time.Duration
+}
+
+// Log-linear buckets in the style of HDR histograms: every power of two
+// is split into 16 linear buckets, so a percentile is off by at most
+// 1/16 (~6%) of the true value, for any latency, in fixed memory.
+const (
+	sketchSubBits = 4
+	sketchSub     = 1 << sketchSubBits
+	sketchBuckets = (64 - sketchSubBits + 1) * sketchSub
+)
+
+type latencySketch struct {
+	counts [sketchBuckets]atomic.Uint64
+	total  atomic.Uint64
+	max    atomic.Int64
+	sum    atomic.Int64 // Nanoseconds
+	errors atomic.Uint64
+}
+
+func sketchBucket(ns uint64) int {
+	if ns < sketchSub {
+		return int(ns)
+	}
+	exp := bits.Len64(ns) - 1
+	mantissa := ns >> (exp - sketchSubBits) // in [sketchSub, 2*sketchSub)
+	return (exp-sketchSubBits+1)*sketchSub + int(mantissa) - sketchSub
+}
+
+// sketchValue is the midpoint of bucket i
+func sketchValue(i int) uint64 {
+	if i < sketchSub {
+		return uint64(i)
+	}
+	shift := i/sketchSub - 1
+	low := uint64(i%sketchSub+sketchSub) << shift
+	return low + (uint64(1)<<shift)/2
+}
+
+func (s *latencySketch) observe(d time.Duration, failed bool) {
+	ns := max(d, 0)
+	s.counts[sketchBucket(uint64(ns))].Add(1)
+	s.total.Add(1)
+	s.sum.Add(int64(ns))
+	if failed {
+		s.errors.Add(1)
+	}
+	for {
+		cur := s.max.Load()
+		if int64(ns) <= cur || s.max.CompareAndSwap(cur, int64(ns)) {
+			return
+		}
+	}
+}
+
+// quantiles reads the percentiles in one pass. Observations landing while
+// it runs may or may not be counted.
+func (s *latencySketch) quantiles(qs ...float64) (uint64, []time.Duration) {
+	var counts [sketchBuckets]uint64
+	var total uint64
+	for i := range s.counts {
+		counts[i] = s.counts[i].Load()
+		total += counts[i]
+	}
+
+	out := make([]time.Duration, len(qs))
+	if total == 0 {
+		return 0, out
+	}
+
+	maxNS := s.max.Load()
+	for j, q := range qs {
+		rank := uint64(q*float64(total) + 0.5)
+		rank = min(max(rank, 1), total)
+
+		var seen uint64
+		for i, c := range counts {
+			seen += c
+			if seen >= rank {
+				out[j] = time.Duration(min(int64(sketchValue(i)), maxNS))
+				break
+			}
+		}
+	}
+	return total, out
+}
+
+// latencyStats has one sketch per generated query plus "other", built up
+// front so recording never takes a lock
+type latencyStats struct {
+	byName map[string]*latencySketch
+}
+
+func newLatencyStats(enabled bool) *latencyStats {
+	if !enabled {
+		return nil
+	}
+	l := &latencyStats{byName: map[string]*latencySketch{"other": {}}}
+	for name := range querySQL {
+		l.byName[name] = &latencySketch{}
+	}
+	return l
+}
+
+func (l *latencyStats) observe(name string, d time.Duration, failed bool) {
+	l.byName[name].observe(d, failed)
+}
+
+// LatencySnapshot returns p50/p95/p99 per query, for every query that ran
+// at least once, sorted by name. It's empty unless Config.QueryLatency is
+// set.
+func (db *DB) LatencySnapshot() []QueryLatency {
+	if db.latency == nil {
+		return nil
+	}
+
+	var out []QueryLatency
+	for name, s := range db.latency.byName {
+		n, qs := s.quantiles(0.50, 0.95, 0.99)
+		if n == 0 {
+			continue
+		}
+		out = append(out, QueryLatency{
+			Query: name,
+			Count: n,
+			P50:   qs[0],
+			P95:   qs[1],
+			P99:   qs[2],
+			Max:   time.Duration(s.max.Load()),
+		})
+	}
+	slices.SortFunc(out, func(a, b QueryLatency) int { return strings.Compare(a.Query, b.Query) })
+	return out
+}
+
+// QueryHistogram is the latency histogram of one query since the DB was
+// opened, in the shape metrics systems like Prometheus take
+type QueryHistogram struct {
+	Query   string // sqlc query name, or "other"
+	Count   uint64
+	Errors  uint64 // Executions that failed, reading their rows included
+	Sum     time.Duration
+	Buckets []uint64 // Buckets[i] counts the executions that took at most bounds[i]
+}
+
+// LatencyHistograms returns the histogram of every query that ran at least
+// once over the given ascending bounds, sorted by name. Counts come from
+// the same buckets as LatencySnapshot, so one near a bound may land on
+// either side of it. It's empty unless Config.QueryLatency is set.
+func (db *DB) LatencyHistograms(bounds []time.Duration) []QueryHistogram {
+	if db.latency == nil {
+		return nil
+	}
+
+	var out []QueryHistogram
+	for name, s := range db.latency.byName {
+		h := QueryHistogram{Query: name, Buckets: make([]uint64, len(bounds))}
+		for i := range s.counts {
+			c := s.counts[i].Load()
+			if c == 0 {
+				continue
+			}
+			h.Count += c
+			v := time.Duration(sketchValue(i))
+			for j := len(bounds) - 1; j >= 0 && v <= bounds[j]; j-- {
+				h.Buckets[j] += c
+			}
+		}
+		if h.Count == 0 {
+			continue
+		}
+		h.Sum = time.Duration(s.sum.Load())
+		h.Errors = s.errors.Load()
+		out = append(out, h)
+	}
+	slices.SortFunc(out, func(a, b QueryHistogram) int { return strings.Compare(a.Query, b.Query) })
+	return out
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// writeLimiter is a counting semaphore in front of writes. SQLite allows
+// one writer at a time, and making the others wait here is much cheaper
+// than letting them all spin on SQLITE_BUSY.
+type writeLimiter struct {
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+func newWriteLimiter(n int) *writeLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &writeLimiter{slots: make(chan struct{}, n)}
+}
+
+// acquire waits for a slot until ctx is done. A nil limiter never blocks.
+func (l *writeLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to acquire write slot: %w", ctx.Err())
+	}
+}
+
+func (l *writeLimiter) release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+// WriteWaiters is how many transactions and writes are queued for a slot
+// right now (always 0 without Config.MaxConcurrentWrites)
+func (db *DB) WriteWaiters() int64 {
+	if db.writes == nil {
+		return 0
+	}
+	return db.writes.waiting.Load()
+}
+
+// limiterDBTX takes a write slot for write statements run outside a
+// transaction. Reads pass straight through.
+type limiterDBTX struct {
+	DBTX
+	l *writeLimiter
+}
+
+func (d *limiterDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if !isWriteQuery(query) {
+		return d.DBTX.ExecContext(ctx, query, args...)
+	}
+	if err := d.l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer d.l.release()
+	return d.DBTX.ExecContext(ctx, query, args...)
+}
+
+// The slot is held until the rows are closed, or the row scanned: the
+// statement may not run before then, which is when a RETURNING write
+// writes
+func (d *limiterDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if !isWriteQuery(query) {
+		return d.DBTX.QueryContext(ctx, query, args...)
+	}
+	rows, err := d.query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return watchRows(ctx, rows, rowsWatch{done: d.released})
+}
+
+func (d *limiterDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if !isWriteQuery(query) {
+		return d.DBTX.QueryRowContext(ctx, query, args...)
+	}
+	rows, err := d.query(ctx, query, args)
+	if err != nil {
+		return errRow(ctx, err)
+	}
+	return watchRow(ctx, rows, rowsWatch{done: d.released})
+}
+
+// query takes a slot and runs the write, freeing the slot if it fails
+func (d *limiterDBTX) query(ctx context.Context, query string, args []any) (*sql.Rows, error) {
+	if err := d.l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := d.DBTX.QueryContext(ctx, query, args...)
+	if err != nil {
+		d.l.release()
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (d *limiterDBTX) released(int64, error) { d.l.release() }
+
+// isWriteQuery looks at the first keyword after the sqlc "-- name:" line
+// and any other leading comments
+func isWriteQuery(query string) bool {
+	switch firstKeyword(query) {
+	case "INSERT", "UPDATE", "DELETE", "REPLACE", "UPSERT":
+		return true
+	}
+	return false
+}
+
+// firstKeyword is the statement's first word, upper-cased, after leading
+// -- and /* */ comments
+func firstKeyword(query string) string {
+	query = skipComments(query)
+	end := strings.IndexFunc(query, func(r rune) bool { return unicode.IsSpace(r) || r == '(' || r == ';' })
+	if end < 0 {
+		end = len(query)
+	}
+	return strings.ToUpper(query[:end])
+}
+
+// skipComments drops leading space and -- and /* */ comments
+func skipComments(query string) string {
+	for {
+		query = strings.TrimSpace(query)
+		if strings.HasPrefix(query, "--") {
+			_, query, _ = strings.Cut(query, "\n")
+		} else if strings.HasPrefix(query, "/*") {
+			_, query, _ = strings.Cut(query, "*/")
+		} else {
+			return query
+		}
+	}
+}
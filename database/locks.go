@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLockHeld is returned by TryWithLock when someone else holds the lock
+var ErrLockHeld = errors.New("lock is held elsewhere")
+
+// ErrLockLost is the cause of fn's context ending when WithLock could no
+// longer keep the lock, and is returned along with fn's own error
+var ErrLockLost = errors.New("lock was lost")
+
+// How long a SQLite lock outlives a holder that stopped renewing it, how
+// often holders renew it or check their connection, and how often a
+// waiter tries again
+const (
+	lockLease = 30 * time.Second
+	lockCheck = lockLease / 3
+	lockPoll  = 250 * time.Millisecond
+)
+
+// WithLock runs fn while holding the lock called name, waiting for it
+// until ctx is done, so that of all the processes sharing the database
+// one at a time runs fn: a singleton job, a migration of data, a cron
+// task. The lock is given back when fn returns.
+//
+// If the lock is lost while fn runs (PostgreSQL: its connection dropped;
+// SQLite: the lease couldn't be renewed for 30s), fn's context ends with
+// ErrLockLost as its cause, and WithLock returns ErrLockLost along with
+// fn's error, since someone else may have the lock by then.
+func (db *DB) WithLock(ctx context.Context, name string, fn func(context.Context) error) error {
+	return db.withLock(ctx, name, false, fn)
+}
+
+// TryWithLock is WithLock that returns ErrLockHeld at once, without
+// running fn, if the lock is taken
+func (db *DB) TryWithLock(ctx context.Context, name string, fn func(context.Context) error) error {
+	return db.withLock(ctx, name, true, fn)
+}
+
+func (db *DB) withLock(ctx context.Context, name string, try bool, fn func(context.Context) error) error {
+	d := defaultDialect()
+	if d.lock == nil {
+		return fmt.Errorf("%s has no named locks", d.name)
+	}
+	lctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	release, err := d.lock(ctx, db.Conn, name, try, func() { cancel(ErrLockLost) })
+	if err != nil {
+		if errors.Is(err, ErrLockHeld) {
+			return err
+		}
+		return fmt.Errorf("failed to take lock %q: %w", name, err)
+	}
+
+	err = fn(lctx)
+	lost := errors.Is(context.Cause(lctx), ErrLockLost)
+	if rerr := release(); rerr != nil && !lost && err == nil {
+		return fmt.Errorf("failed to release lock %q: %w", name, rerr)
+	}
+	if lost {
+		if err == nil {
+			return ErrLockLost
+		}
+		return fmt.Errorf("%w: %w", ErrLockLost, err)
+	}
+	return err
+}
+
+// sessionLock takes a lock that lasts as long as the session holding it,
+// on a connection kept out of the pool until release. lockStmt waits for
+// the lock, tryStmt returns whether it got it, and unlockStmt gives it
+// back, each with the name as their one parameter. The connection is
+// pinged meanwhile, and lost called once it fails, since the server
+// drops the lock along with the session.
+func sessionLock(ctx context.Context, conn *sql.DB, name string, try bool, lost func(), lockStmt, tryStmt, unlockStmt string) (release func() error, err error) {
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Closed rather than returned to the pool, with whatever it holds
+	discard := func() { c.Raw(func(any) error { return driver.ErrBadConn }); c.Close() }
+
+	if try {
+		var ok bool
+		if err := c.QueryRowContext(ctx, tryStmt, name).Scan(&ok); err != nil {
+			discard()
+			return nil, err
+		}
+		if !ok {
+			c.Close()
+			return nil, ErrLockHeld
+		}
+	} else if _, err := c.ExecContext(ctx, lockStmt, name); err != nil {
+		discard() // A canceled wait may still get the lock
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(lockCheck)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			pctx, cancel := context.WithTimeout(context.Background(), lockCheck)
+			err := c.PingContext(pctx)
+			cancel()
+			if err != nil {
+				lost()
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		close(done)
+		<-stopped
+		uctx, cancel := context.WithTimeout(context.Background(), lockCheck)
+		defer cancel()
+		if _, err := c.ExecContext(uctx, unlockStmt, name); err != nil {
+			discard()
+			return err
+		}
+		return c.Close()
+	}, nil
+}
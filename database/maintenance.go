@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// The tasks Maintain runs, in this order
+const (
+	MaintenanceCheckpoint = "checkpoint" // PRAGMA wal_checkpoint(TRUNCATE), so the WAL doesn't grow without bound
+	MaintenanceAnalyze    = "analyze"    // ANALYZE, so the query planner's statistics follow the data
+	MaintenanceVacuum     = "vacuum"     // PRAGMA incremental_vacuum, so deleted data gives disk space back
+)
+
+var maintenanceTasks = []string{MaintenanceCheckpoint, MaintenanceAnalyze, MaintenanceVacuum}
+
+// MaintenanceSchedule configures StartMaintenanceLoop, or Config.Maintenance.
+// Each task runs every its interval; 0 leaves it out.
+type MaintenanceSchedule struct {
+	Checkpoint  time.Duration `config:"checkpoint"`   // Checkpoint and truncate the WAL (SQLite's own checkpoints never truncate it)
+	Analyze     time.Duration `config:"analyze"`      // Refresh the query planner's statistics
+	Vacuum      time.Duration `config:"vacuum"`       // Release free pages; needs auto_vacuum = INCREMENTAL
+	VacuumPages int           `config:"vacuum_pages"` // Free pages released per vacuum (0 = all)
+}
+
+func (s MaintenanceSchedule) enabled() bool {
+	return s.Checkpoint > 0 || s.Analyze > 0 || s.Vacuum > 0
+}
+
+// MaintenanceResult is what one task did in a Maintain run
+type MaintenanceResult struct {
+	Task     string
+	Duration time.Duration
+	Detail   string // e.g. "125 frames copied, WAL truncated", or why the task was skipped
+	Err      error
+}
+
+// MaintenanceStatus is the outcome of the latest runs of a task, for
+// monitoring
+type MaintenanceStatus struct {
+	Runs         int64
+	Failures     int64
+	LastRun      time.Time // When the last run finished, good or not
+	LastSuccess  time.Time
+	LastDuration time.Duration
+	LastDetail   string
+	LastError    error // Error of the last failed run, nil once one succeeds again
+}
+
+type maintenanceState struct {
+	run    sync.Mutex // Held for a whole Maintain, so scheduled and manual runs don't overlap
+	mu     sync.Mutex
+	status map[string]MaintenanceStatus
+	stop   context.CancelFunc // Ends the loop Config.Maintenance started, nil without one
+}
+
+// Maintain runs every maintenance task now and returns what each did; the
+// error joins those of the tasks that failed. A run already in progress,
+// such as a scheduled one, is waited for first. The tasks run on the pool
+// beside the app's statements; the checkpoint and ANALYZE take the write
+// lock, briefly on a small database.
+//
+// It's for SQLite. PostgreSQL's autovacuum does this work, so Maintain
+// returns an error there.
+func (db *DB) Maintain(ctx context.Context) ([]MaintenanceResult, error) {
+	return db.maintain(ctx, MaintenanceSchedule{}, maintenanceTasks)
+}
+
+func (db *DB) maintain(ctx context.Context, sched MaintenanceSchedule, tasks []string) ([]MaintenanceResult, error) {
+	d := defaultDialect()
+	if err := maintenanceSupported(d); err != nil {
+		return nil, err
+	}
+	db.maintenance.run.Lock()
+	defer db.maintenance.run.Unlock()
+
+	var results []MaintenanceResult
+	var errs []error
+	for _, task := range tasks {
+		start := time.Now()
+		var detail string
+		var err error
+		switch task {
+		case MaintenanceCheckpoint:
+			if db.replication.external {
+				detail = "skipped, the replicator checkpoints"
+				break
+			}
+			var r CheckpointResult
+			if r, err = db.checkpoint(ctx, d, CheckpointTruncate); err == nil {
+				detail = r.detail()
+			}
+		case MaintenanceAnalyze:
+			detail, err = d.analyze(ctx, db.Conn)
+		case MaintenanceVacuum:
+			detail, err = d.incrementalVacuum(ctx, db.Conn, sched.VacuumPages)
+		}
+		r := MaintenanceResult{Task: task, Duration: time.Since(start), Detail: detail, Err: err}
+		results = append(results, r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", task, err))
+		}
+		if ctx.Err() == nil { // a canceled run isn't a failure
+			db.recordMaintenance(r)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (db *DB) recordMaintenance(r MaintenanceResult) {
+	db.maintenance.mu.Lock()
+	defer db.maintenance.mu.Unlock()
+	if db.maintenance.status == nil {
+		db.maintenance.status = map[string]MaintenanceStatus{}
+	}
+	s := db.maintenance.status[r.Task]
+	s.Runs++
+	s.LastRun = time.Now()
+	s.LastDuration = r.Duration
+	s.LastDetail = r.Detail
+	if r.Err != nil {
+		s.Failures++
+		s.LastError = r.Err
+	} else {
+		s.LastSuccess = s.LastRun
+		s.LastError = nil
+	}
+	db.maintenance.status[r.Task] = s
+}
+
+// MaintenanceStatus reports the latest maintenance results by task, for
+// the tasks that have run
+func (db *DB) MaintenanceStatus() map[string]MaintenanceStatus {
+	db.maintenance.mu.Lock()
+	defer db.maintenance.mu.Unlock()
+	out := make(map[string]MaintenanceStatus, len(db.maintenance.status))
+	for task, s := range db.maintenance.status {
+		out[task] = s
+	}
+	return out
+}
+
+func maintenanceSupported(d *dialect) error {
+	if d.walCheckpoint == nil {
+		return fmt.Errorf("%s maintains itself (autovacuum), there's no maintenance to schedule", d.name)
+	}
+	return nil
+}
+
+// StartMaintenanceLoop runs each maintenance task every its interval in
+// sched until ctx is canceled, one task at a time. Each run is logged,
+// failures included, and reported by MaintenanceStatus.
+func (db *DB) StartMaintenanceLoop(ctx context.Context, sched MaintenanceSchedule) error {
+	if err := maintenanceSupported(defaultDialect()); err != nil {
+		return err
+	}
+	if sched.Checkpoint < 0 || sched.Analyze < 0 || sched.Vacuum < 0 {
+		return fmt.Errorf("maintenance intervals can't be negative")
+	}
+	if !sched.enabled() {
+		return fmt.Errorf("maintenance schedule has no task with an interval")
+	}
+
+	// A nil channel never fires, which leaves out the tasks without one
+	tick := func(every time.Duration) (<-chan time.Time, func()) {
+		if every <= 0 {
+			return nil, func() {}
+		}
+		t := time.NewTicker(every)
+		return t.C, t.Stop
+	}
+	checkpoint, stopCheckpoint := tick(sched.Checkpoint)
+	analyze, stopAnalyze := tick(sched.Analyze)
+	vacuum, stopVacuum := tick(sched.Vacuum)
+
+	go func() {
+		defer stopCheckpoint()
+		defer stopAnalyze()
+		defer stopVacuum()
+
+		for {
+			var task string
+			select {
+			case <-ctx.Done():
+				return
+			case <-checkpoint:
+				task = MaintenanceCheckpoint
+			case <-analyze:
+				task = MaintenanceAnalyze
+			case <-vacuum:
+				task = MaintenanceVacuum
+			}
+
+			results, _ := db.maintain(ctx, sched, []string{task})
+			if ctx.Err() != nil {
+				return
+			}
+			for _, r := range results {
+				switch {
+				case r.Err != nil:
+					log.Printf("database maintenance: %s failed: %v", r.Task, r.Err)
+				case r.Detail != "":
+					log.Printf("database maintenance: %s done in %v, %s", r.Task, r.Duration.Round(time.Millisecond), r.Detail)
+				default:
+					log.Printf("database maintenance: %s done in %v", r.Task, r.Duration.Round(time.Millisecond))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Vacuum rebuilds the whole database at once, for after a large delete
+// or to apply a new auto_vacuum setting. With SQLite it rewrites the file
+// without its free pages, holding the write lock throughout, and needs
+// up to the database's size again in free disk space. With PostgreSQL
+// it's VACUUM (ANALYZE) of every table, which runs next to reads and
+// writes. Scheduled upkeep is lighter with Config.Maintenance.
+func (db *DB) Vacuum(ctx context.Context) error {
+	d := defaultDialect()
+	if _, err := db.Conn.ExecContext(ctx, d.vacuum); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Package metrics exports the database package's query statistics,
+// connection pool stats and maintenance runs as Prometheus metrics:
+//
+//	reg.MustRegister(metrics.Collector(db))
+//
+// Per-query metrics need Config.QueryLatency and the statement cache ones
+// Config.StatementCache; the pool metrics and the statement and
+// transaction counts are always there. To export several databases from
+// one registry, tell them apart with a constant label:
+//
+//	prometheus.WrapRegistererWith(prometheus.Labels{"db": "analytics"}, reg).MustRegister(metrics.Collector(analytics))
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"your-project/database"
+)
+
+// Bounds of the latency histograms, from index lookups to stuck statements
+var buckets = []time.Duration{
+	100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+var (
+	queries = prometheus.NewDesc("db_queries_total",
+		"Statements run, by sqlc query name.", []string{"query"}, nil)
+	queryErrors = prometheus.NewDesc("db_query_errors_total",
+		"Statements that failed, by sqlc query name.", []string{"query"}, nil)
+	queryDuration = prometheus.NewDesc("db_query_duration_seconds",
+		"Statement latency, by sqlc query name.", []string{"query"}, nil)
+
+	maxOpen = prometheus.NewDesc("db_max_open_connections",
+		"Maximum number of open connections to the database.", nil, nil)
+	open = prometheus.NewDesc("db_open_connections",
+		"Established connections, in use and idle.", nil, nil)
+	inUse = prometheus.NewDesc("db_in_use_connections",
+		"Connections currently in use.", nil, nil)
+	idle = prometheus.NewDesc("db_idle_connections",
+		"Idle connections.", nil, nil)
+	waits = prometheus.NewDesc("db_wait_count_total",
+		"Times a caller waited for a connection.", nil, nil)
+	waitDuration = prometheus.NewDesc("db_wait_duration_seconds_total",
+		"Time spent waiting for a connection.", nil, nil)
+	closedIdle = prometheus.NewDesc("db_closed_max_idle_total",
+		"Connections closed because of MaxIdleConns.", nil, nil)
+	closedIdleTime = prometheus.NewDesc("db_closed_max_idle_time_total",
+		"Connections closed because of ConnMaxIdleTime.", nil, nil)
+	closedLifetime = prometheus.NewDesc("db_closed_max_lifetime_total",
+		"Connections closed because of ConnMaxLifetime.", nil, nil)
+
+	statements = prometheus.NewDesc("db_statements_total",
+		"Statements sent to the database, with or without Config.QueryLatency.", nil, nil)
+	transactions = prometheus.NewDesc("db_transactions_total",
+		"Transactions begun, not counting nested ones.", nil, nil)
+	rollbacks = prometheus.NewDesc("db_rollbacks_total",
+		"Transactions that didn't commit.", nil, nil)
+	retries = prometheus.NewDesc("db_transaction_retries_total",
+		"Transactions RetryTransaction ran again.", nil, nil)
+
+	cachedStatements = prometheus.NewDesc("db_statement_cache_statements",
+		"Prepared statements the statement cache holds.", nil, nil)
+	cacheHits = prometheus.NewDesc("db_statement_cache_hits_total",
+		"Statements run on one the cache had prepared.", nil, nil)
+	cacheMisses = prometheus.NewDesc("db_statement_cache_misses_total",
+		"Statements the cache prepared.", nil, nil)
+	cacheStale = prometheus.NewDesc("db_statement_cache_stale_total",
+		"Prepared statements dropped because the server refused them as stale.", nil, nil)
+	cacheInvalidations = prometheus.NewDesc("db_statement_cache_invalidations_total",
+		"Times migrations dropped the whole statement cache.", nil, nil)
+
+	maintenanceRuns = prometheus.NewDesc("db_maintenance_runs_total",
+		"Maintenance runs, by task.", []string{"task"}, nil)
+	maintenanceFailures = prometheus.NewDesc("db_maintenance_failures_total",
+		"Maintenance runs that failed, by task.", []string{"task"}, nil)
+	maintenanceLastSuccess = prometheus.NewDesc("db_maintenance_last_success_timestamp_seconds",
+		"When a maintenance task last succeeded, as a Unix time.", []string{"task"}, nil)
+	maintenanceDuration = prometheus.NewDesc("db_maintenance_last_duration_seconds",
+		"How long the last run of a maintenance task took.", []string{"task"}, nil)
+)
+
+type collector struct {
+	db *database.DB
+}
+
+// Collector returns a prometheus.Collector reading db's stats at every
+// scrape
+func Collector(db *database.DB) prometheus.Collector {
+	return collector{db: db}
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		queries, queryErrors, queryDuration,
+		maxOpen, open, inUse, idle, waits, waitDuration, closedIdle, closedIdleTime, closedLifetime,
+		statements, transactions, rollbacks, retries,
+		cachedStatements, cacheHits, cacheMisses, cacheStale, cacheInvalidations,
+		maintenanceRuns, maintenanceFailures, maintenanceLastSuccess, maintenanceDuration,
+	} {
+		ch <- d
+	}
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	for _, h := range c.db.LatencyHistograms(buckets) {
+		ch <- prometheus.MustNewConstMetric(queries, prometheus.CounterValue, float64(h.Count), h.Query)
+		ch <- prometheus.MustNewConstMetric(queryErrors, prometheus.CounterValue, float64(h.Errors), h.Query)
+
+		counts := make(map[float64]uint64, len(buckets))
+		for i, b := range buckets {
+			counts[b.Seconds()] = h.Buckets[i]
+		}
+		ch <- prometheus.MustNewConstHistogram(queryDuration, h.Count, h.Sum.Seconds(), counts, h.Query)
+	}
+
+	s := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(open, prometheus.GaugeValue, float64(s.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(inUse, prometheus.GaugeValue, float64(s.InUse))
+	ch <- prometheus.MustNewConstMetric(idle, prometheus.GaugeValue, float64(s.Idle))
+	ch <- prometheus.MustNewConstMetric(waits, prometheus.CounterValue, float64(s.WaitCount))
+	ch <- prometheus.MustNewConstMetric(waitDuration, prometheus.CounterValue, s.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(closedIdle, prometheus.CounterValue, float64(s.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(closedIdleTime, prometheus.CounterValue, float64(s.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(closedLifetime, prometheus.CounterValue, float64(s.MaxLifetimeClosed))
+	ch <- prometheus.MustNewConstMetric(statements, prometheus.CounterValue, float64(s.Queries))
+	ch <- prometheus.MustNewConstMetric(transactions, prometheus.CounterValue, float64(s.Transactions))
+	ch <- prometheus.MustNewConstMetric(rollbacks, prometheus.CounterValue, float64(s.Rollbacks))
+	ch <- prometheus.MustNewConstMetric(retries, prometheus.CounterValue, float64(s.Retries))
+
+	sc := c.db.StatementCacheStats()
+	ch <- prometheus.MustNewConstMetric(cachedStatements, prometheus.GaugeValue, float64(sc.Statements))
+	ch <- prometheus.MustNewConstMetric(cacheHits, prometheus.CounterValue, float64(sc.Hits))
+	ch <- prometheus.MustNewConstMetric(cacheMisses, prometheus.CounterValue, float64(sc.Misses))
+	ch <- prometheus.MustNewConstMetric(cacheStale, prometheus.CounterValue, float64(sc.Stale))
+	ch <- prometheus.MustNewConstMetric(cacheInvalidations, prometheus.CounterValue, float64(sc.Invalidations))
+
+	for task, m := range c.db.MaintenanceStatus() {
+		ch <- prometheus.MustNewConstMetric(maintenanceRuns, prometheus.CounterValue, float64(m.Runs), task)
+		ch <- prometheus.MustNewConstMetric(maintenanceFailures, prometheus.CounterValue, float64(m.Failures), task)
+		ch <- prometheus.MustNewConstMetric(maintenanceDuration, prometheus.GaugeValue, m.LastDuration.Seconds(), task)
+		if !m.LastSuccess.IsZero() {
+			ch <- prometheus.MustNewConstMetric(maintenanceLastSuccess, prometheus.GaugeValue, float64(m.LastSuccess.UnixNano())/1e9, task)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MigrationPlan is what a migration command would do, worked out from
+// schema_migrations without changing anything, for a dry run or a review
+// before the real one
+type MigrationPlan struct {
+	From, To int64       // Newest applied migration before and after, 0 for none
+	Apply    []Migration // Migrations that would be applied, in order
+	Undo     []Migration // Migrations that would be undone, in order
+	SQL      string      // Every statement that would run, in order, in the transactions they'd run in
+}
+
+// PlanMigrate is what Migrate would do. A new database gets schema.sql,
+// and its migrations are only recorded, so they're in Apply without their
+// SQL. The upkeep a dialect does in Go after the schema, such as SQLite
+// rebuilding indexes on changed collations, isn't in SQL.
+func (db *DB) PlanMigrate(ctx context.Context) (MigrationPlan, error) {
+	d := defaultDialect()
+	migrations, err := loadMigrations(d.migrations)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	fresh, err := freshDatabase(ctx, d, db.Conn)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	tracked, err := migrationsTracked(ctx, d, db.Conn)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+
+	var apply []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			apply = append(apply, m)
+		}
+	}
+	var b strings.Builder
+	b.WriteString(createMigrationsTable + ";\n\n")
+	if !fresh {
+		for _, m := range apply {
+			planUp(&b, m, tracked)
+		}
+	}
+	b.WriteString("-- schema.sql\n" + strings.TrimSpace(d.schema) + "\n")
+	if fresh && len(apply) > 0 {
+		b.WriteString("\n-- schema.sql has what the migrations add, so they're only recorded\n")
+		for _, m := range apply {
+			b.WriteString(recordMigration(m) + ";\n")
+		}
+	}
+	return newMigrationPlan(applied, apply, nil, b.String()), nil
+}
+
+// PlanRollback is what Rollback(ctx, n) would do, refusing what it would
+// refuse
+func (db *DB) PlanRollback(ctx context.Context, n int) (MigrationPlan, error) {
+	migrations, err := loadMigrations(defaultDialect().migrations)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	undo, err := rollbackSet(migrations, applied, n)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	var b strings.Builder
+	for _, m := range undo {
+		planTx(&b, fmt.Sprintf("%d_%s down", m.Version, m.Name), m.Down, forgetMigration(m))
+	}
+	return newMigrationPlan(applied, nil, undo, b.String()), nil
+}
+
+// PlanMigrateTo is what MigrateTo(ctx, version) would do, refusing what it
+// would refuse
+func (db *DB) PlanMigrateTo(ctx context.Context, version int64) (MigrationPlan, error) {
+	d := defaultDialect()
+	migrations, err := loadMigrations(d.migrations)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	latest, err := checkMigrateTo(migrations, version)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	if latest {
+		return db.PlanMigrate(ctx)
+	}
+	fresh, err := freshDatabase(ctx, d, db.Conn)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	if fresh {
+		return MigrationPlan{}, errors.New("a new database can only be migrated to the newest version")
+	}
+	tracked, err := migrationsTracked(ctx, d, db.Conn)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	undo, apply := migrateToSets(migrations, applied, version)
+	if err := checkDown(undo); err != nil {
+		return MigrationPlan{}, err
+	}
+
+	var b strings.Builder
+	b.WriteString(createMigrationsTable + ";\n\n")
+	for _, m := range undo {
+		planTx(&b, fmt.Sprintf("%d_%s down", m.Version, m.Name), m.Down, forgetMigration(m))
+	}
+	for _, m := range apply {
+		planUp(&b, m, tracked)
+	}
+	return newMigrationPlan(applied, apply, undo, b.String()), nil
+}
+
+// planUp writes a migration as it would be applied; the baseline is only
+// recorded where schema_migrations exists (see adoptBaseline)
+func planUp(b *strings.Builder, m Migration, tracked bool) {
+	if tracked && m.Version == baselineVersion {
+		fmt.Fprintf(b, "-- %d_%s, only recorded: schema.sql made this database with it\n%s;\n\n", m.Version, m.Name, recordMigration(m))
+		return
+	}
+	planTx(b, fmt.Sprintf("%d_%s up", m.Version, m.Name), m.Up, recordMigration(m))
+}
+
+// planTx writes a migration's SQL and its bookkeeping as applyMigration
+// runs them, in one transaction
+func planTx(b *strings.Builder, title, script, bookkeeping string) {
+	if defaultDialect().foreignKeysOff != nil {
+		title += ", with foreign keys off and checked before COMMIT"
+	}
+	fmt.Fprintf(b, "-- %s\nBEGIN;\n%s\n%s;\nCOMMIT;\n\n", title, strings.TrimSpace(script), bookkeeping)
+}
+
+func newMigrationPlan(applied map[int64]bool, apply, undo []Migration, sql string) MigrationPlan {
+	p := MigrationPlan{Apply: apply, Undo: undo, SQL: sql}
+	after := make(map[int64]bool, len(applied))
+	for v := range applied {
+		p.From = max(p.From, v)
+		after[v] = true
+	}
+	for _, m := range undo {
+		delete(after, m.Version)
+	}
+	for _, m := range apply {
+		after[m.Version] = true
+	}
+	for v := range after {
+		p.To = max(p.To, v)
+	}
+	return p
+}
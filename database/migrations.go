@@ -0,0 +1,687 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one numbered change to the schema, from a pair of files in
+// sql/<dialect>/migrations: <version>_<name>.up.sql and .down.sql.
+//
+// schema.sql stays the whole, current schema (sqlc reads it, and a new
+// database is created from it); migrations bring databases created by an
+// older schema.sql up to it, for what CREATE ... IF NOT EXISTS can't do,
+// like adding a column. A change goes in both: the new column in
+// schema.sql, the ALTER TABLE in a migration.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string // SQL
+	Down    string // SQL undoing Up, empty if there's no down file
+}
+
+var (
+	migrationFile = regexp.MustCompile(`^(\d+)_([a-z][a-z0-9_]*)\.(up|down)\.sql$`)
+	migrationName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// Versions are either sequential, 0001 and up, or UTC timestamps, after
+// the baseline
+const (
+	sequentialWidth = 4
+	timestampFormat = "20060102150405"
+)
+
+// baselineVersion is migration 0000_baseline's. It brings a database made
+// before there were migrations up to the schema the first one starts
+// from. A database that has schema_migrations was made by a schema.sql
+// that already had all of it, so there it's only recorded.
+const baselineVersion = 0
+
+// migrationsFS roots a dialect's embedded migrations directory
+func migrationsFS(files embed.FS, dir string) fs.FS {
+	sub, err := fs.Sub(files, dir)
+	if err != nil {
+		panic(err) // dir is a constant go:embed has already checked
+	}
+	return sub
+}
+
+// MigrationsDir is where the built-in dialect keeps its migration files,
+// relative to the database package
+func MigrationsDir() string {
+	return defaultDialect().migrationsDir
+}
+
+// ValidateMigrations checks the embedded migration files: names that
+// parse, one up file per version, no version used twice, and, when
+// numbered sequentially, no gaps. Version 0 is the baseline, which comes
+// before either kind. Open checks the same before migrating.
+func ValidateMigrations() error {
+	_, err := loadMigrations(defaultDialect().migrations)
+	return err
+}
+
+// loadMigrations reads and checks the migrations in fsys, by version.
+// Files that aren't .sql (a README) are skipped.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	if fsys == nil {
+		return nil, nil
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	var problems []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		m := migrationFile.FindStringSubmatch(e.Name())
+		if m == nil {
+			problems = append(problems, fmt.Errorf("migration %s: want <version>_<name>.up.sql or .down.sql, name in lowercase letters, digits and _", e.Name()))
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("migration %s: bad version", e.Name()))
+			continue
+		}
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			problems = append(problems, fmt.Errorf("migration version %s is used by both %s and %s", m[1], mig.Name, m[2]))
+			continue
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == "" {
+			problems = append(problems, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name))
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+
+	numbered := migrations
+	if len(numbered) > 0 && numbered[0].Version == baselineVersion {
+		numbered = numbered[1:]
+	}
+	timestamped := 0
+	for _, m := range numbered {
+		if isTimestampVersion(m.Version) {
+			timestamped++
+		}
+	}
+	switch {
+	case timestamped > 0 && timestamped < len(numbered):
+		problems = append(problems, errors.New("migrations mix sequential and timestamp versions"))
+	case timestamped == 0:
+		for i, m := range numbered {
+			if m.Version != int64(i+1) {
+				problems = append(problems, fmt.Errorf("migration versions skip from %d to %d", i, m.Version))
+				break
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	return migrations, nil
+}
+
+func isTimestampVersion(v int64) bool {
+	_, err := time.Parse(timestampFormat, strconv.FormatInt(v, 10))
+	return err == nil
+}
+
+// NewMigrationFile writes the up and down files for a new migration into
+// dir (normally sql/<dialect>/migrations; see MigrationsDir), numbered
+// after the ones already there: the next sequential number, or the current
+// UTC time if they use timestamps. name is lowercase letters, digits and
+// underscores ("add_widgets"). It refuses a name already taken, a version
+// already used, and a dir whose files don't pass ValidateMigrations.
+func NewMigrationFile(dir, name string) (upPath, downPath string, err error) {
+	if !migrationName.MatchString(name) {
+		return "", "", fmt.Errorf("migration name %q must be lowercase letters, digits and _, starting with a letter", name)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+	existing, err := loadMigrations(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+
+	timestamped := len(existing) > 0 && isTimestampVersion(existing[len(existing)-1].Version)
+	var last int64
+	for _, m := range existing {
+		if m.Name == name {
+			return "", "", fmt.Errorf("there's already a migration named %s (version %d)", name, m.Version)
+		}
+		last = max(last, m.Version)
+	}
+
+	var prefix string
+	if timestamped {
+		now := time.Now().UTC().Format(timestampFormat)
+		if v, _ := strconv.ParseInt(now, 10, 64); v <= last {
+			return "", "", fmt.Errorf("version %s isn't after the newest migration, %d; wait a second and try again", now, last)
+		}
+		prefix = now
+	} else {
+		prefix = fmt.Sprintf("%0*d", sequentialWidth, last+1)
+	}
+
+	base := filepath.Join(dir, prefix+"_"+name)
+	upPath, downPath = base+".up.sql", base+".down.sql"
+	created := time.Now().UTC().Format(time.DateOnly)
+	if err := writeNewFile(upPath, fmt.Sprintf(
+		"-- Migration %s_%s, created %s.\n"+
+			"-- Brings databases made by an older schema.sql up to date; make the same\n"+
+			"-- change in schema.sql, which new databases are created from. Runs once,\n"+
+			"-- in a transaction, when Open finds it hasn't been applied.\n\n",
+		prefix, name, created)); err != nil {
+		return "", "", err
+	}
+	if err := writeNewFile(downPath, fmt.Sprintf(
+		"-- Undoes migration %s_%s, for `db migrate down`.\n\n",
+		prefix, name)); err != nil {
+		os.Remove(upPath)
+		return "", "", err
+	}
+	return upPath, downPath, nil
+}
+
+// writeNewFile fails rather than overwrite a file that exists
+func writeNewFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Applied migrations are recorded here; written with plain SQL both
+// dialects accept, since the table has to exist before schema.sql runs
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Migrate brings the schema up to date, as Open does unless
+// Config.SkipMigrations is set
+func (db *DB) Migrate(ctx context.Context) error {
+	defer db.stmts.invalidate()
+	return migrate(ctx, defaultDialect(), db.Conn)
+}
+
+// runMigrations brings the schema up to date. A new database gets
+// everything from schema.sql, so its migrations are only recorded; an
+// older one gets the migrations it lacks first, then schema.sql, which may
+// already refer to what they add. One from before schema_migrations starts
+// with the baseline.
+func runMigrations(ctx context.Context, d *dialect, conn *sql.DB) error {
+	migrations, err := loadMigrations(d.migrations)
+	if err != nil {
+		return err
+	}
+	fresh, err := freshDatabase(ctx, d, conn)
+	if err != nil {
+		return err
+	}
+	tracked, err := migrationsTracked(ctx, d, conn)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if tracked {
+		if err := adoptBaseline(ctx, conn, migrations, applied); err != nil {
+			return err
+		}
+	}
+
+	if !fresh {
+		for _, m := range migrations {
+			if applied[m.Version] {
+				continue
+			}
+			if err := applyMigration(ctx, d, conn, m.Up, recordMigration(m)); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+		}
+	}
+	if _, err := conn.ExecContext(ctx, d.schema); err != nil {
+		return err
+	}
+	if fresh {
+		for _, m := range migrations {
+			if _, err := conn.ExecContext(ctx, recordMigration(m)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// freshDatabase reports a database with none of schema.sql's tables yet.
+// Without listTables every database counts as existing.
+func freshDatabase(ctx context.Context, d *dialect, conn *sql.DB) (bool, error) {
+	if d.listTables == nil {
+		return false, nil
+	}
+	have, err := d.listTables(ctx, conn)
+	if err != nil {
+		return false, err
+	}
+	for table := range schemaTables(d.schema) {
+		if slices.Contains(have, table) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// migrationsTracked reports a database that has schema_migrations, which
+// doesn't need the baseline. Without listTables every database counts as
+// tracked.
+func migrationsTracked(ctx context.Context, d *dialect, conn *sql.DB) (bool, error) {
+	if d.listTables == nil {
+		return true, nil
+	}
+	have, err := d.listTables(ctx, conn)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(have, "schema_migrations"), nil
+}
+
+// adoptBaseline records the baseline as applied, without running it, in
+// applied too
+func adoptBaseline(ctx context.Context, dbtx DBTX, migrations []Migration, applied map[int64]bool) error {
+	if len(migrations) == 0 || migrations[0].Version != baselineVersion || applied[baselineVersion] {
+		return nil
+	}
+	if _, err := dbtx.ExecContext(ctx, recordMigration(migrations[0])); err != nil {
+		return err
+	}
+	applied[baselineVersion] = true
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, dbtx DBTX) (map[int64]bool, error) {
+	rows, err := dbtx.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int64]bool{}
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// recordMigration and forgetMigration inline the version and name, which
+// loadMigrations has checked are digits and [a-z0-9_], so the same SQL
+// works with either placeholder style
+func recordMigration(m Migration) string {
+	return fmt.Sprintf("INSERT INTO schema_migrations (version, name) VALUES (%d, '%s')", m.Version, m.Name)
+}
+
+func forgetMigration(m Migration) string {
+	return fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %d", m.Version)
+}
+
+// applyMigration runs a migration's SQL and its bookkeeping in one
+// transaction, with foreign keys off where the dialect turns them off, and
+// checked before it commits
+func applyMigration(ctx context.Context, d *dialect, conn *sql.DB, script, bookkeeping string) error {
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	check := false
+	if d.foreignKeysOff != nil {
+		on, err := d.foreignKeysOff(ctx, c)
+		if err != nil {
+			return err
+		}
+		if on != nil {
+			defer on()
+			check = d.foreignKeyCheck != nil
+		}
+	}
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if check {
+		orphans, err := d.foreignKeyCheck(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if len(orphans) > 0 {
+			o := orphans[0]
+			return fmt.Errorf("it leaves %d rows whose foreign keys point nowhere, the first %s row %d into %s", len(orphans), o.Table, o.RowID, o.Parent)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PendingMigrations lists the migrations the database hasn't applied yet
+func (db *DB) PendingMigrations(ctx context.Context) ([]Migration, error) {
+	migrations, err := loadMigrations(defaultDialect().migrations)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// appliedMigrations is empty for a database older than schema_migrations
+func (db *DB) appliedMigrations(ctx context.Context) (map[int64]bool, error) {
+	times, err := db.migrationTimes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]bool, len(times))
+	for v := range times {
+		applied[v] = true
+	}
+	return applied, nil
+}
+
+// MigrateDown undoes the newest applied migration with its down file and
+// returns it. The next Open applies it again, so only run it ahead of
+// deploying a build without that migration.
+func (db *DB) MigrateDown(ctx context.Context) (Migration, error) {
+	undone, err := db.Rollback(ctx, 1)
+	if err != nil {
+		return Migration{}, err
+	}
+	return undone[0], nil
+}
+
+// Rollback undoes the newest n applied migrations with their down files,
+// newest first, and returns them in that order. It refuses before undoing
+// any if fewer than n are applied or one of them has no down SQL. Each is
+// undone in a transaction of its own, so a failure leaves the ones before
+// it undone. As with MigrateDown, the next Open applies them again.
+func (db *DB) Rollback(ctx context.Context, n int) ([]Migration, error) {
+	migrations, err := loadMigrations(defaultDialect().migrations)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	undo, err := rollbackSet(migrations, applied, n)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range undo {
+		if err := db.undoMigration(ctx, m); err != nil {
+			return undo[:i], err
+		}
+	}
+	return undo, nil
+}
+
+// rollbackSet picks the newest n applied migrations, newest first
+func rollbackSet(migrations []Migration, applied map[int64]bool, n int) ([]Migration, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("can't roll back %d migrations", n)
+	}
+	var undo []Migration
+	for i := len(migrations) - 1; i >= 0 && len(undo) < n; i-- {
+		if applied[migrations[i].Version] {
+			undo = append(undo, migrations[i])
+		}
+	}
+	switch {
+	case len(undo) == 0:
+		return nil, errors.New("no applied migration to undo")
+	case len(undo) < n:
+		return nil, fmt.Errorf("only %d applied migrations to undo, not %d", len(undo), n)
+	}
+	return undo, checkDown(undo)
+}
+
+// checkDown refuses migrations to undo that have nothing in their down file
+func checkDown(undo []Migration) error {
+	for _, m := range undo {
+		if !hasDown(m) {
+			return fmt.Errorf("migration %d_%s has no down SQL", m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+func hasDown(m Migration) bool {
+	return strings.TrimSpace(stripSQLComments(m.Down)) != ""
+}
+
+func (db *DB) undoMigration(ctx context.Context, m Migration) error {
+	if !hasDown(m) {
+		return fmt.Errorf("migration %d_%s has no down SQL", m.Version, m.Name)
+	}
+	defer db.stmts.invalidate()
+	if err := applyMigration(ctx, defaultDialect(), db.Conn, m.Down, forgetMigration(m)); err != nil {
+		return fmt.Errorf("failed to undo migration %d_%s: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// MigrateTo moves the schema to version: it applies the migrations up to
+// it that the database lacks, or undoes the applied ones after it, newest
+// first. Version 0 undoes them all but the baseline, which has no down. It doesn't run schema.sql, so short of
+// the newest version it only suits a database that already exists; one
+// without any tables yet can only go to the newest, which is Migrate.
+func (db *DB) MigrateTo(ctx context.Context, version int64) error {
+	defer db.stmts.invalidate()
+	d := defaultDialect()
+	migrations, err := loadMigrations(d.migrations)
+	if err != nil {
+		return err
+	}
+	latest, err := checkMigrateTo(migrations, version)
+	if err != nil {
+		return err
+	}
+	if latest {
+		return db.Migrate(ctx)
+	}
+	fresh, err := freshDatabase(ctx, d, db.Conn)
+	if err != nil {
+		return err
+	}
+	if fresh {
+		return errors.New("a new database can only be migrated to the newest version")
+	}
+	tracked, err := migrationsTracked(ctx, d, db.Conn)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx, db.Conn)
+	if err != nil {
+		return err
+	}
+	if tracked {
+		if err := adoptBaseline(ctx, db.Conn, migrations, applied); err != nil {
+			return err
+		}
+	}
+	undo, apply := migrateToSets(migrations, applied, version)
+	if err := checkDown(undo); err != nil {
+		return err
+	}
+
+	for _, m := range undo {
+		if err := db.undoMigration(ctx, m); err != nil {
+			return err
+		}
+	}
+	for _, m := range apply {
+		if err := applyMigration(ctx, d, db.Conn, m.Up, recordMigration(m)); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkMigrateTo checks version is one MigrateTo can go to, and reports
+// whether it's the newest, which is a plain Migrate
+func checkMigrateTo(migrations []Migration, version int64) (latest bool, err error) {
+	if version != 0 && !slices.ContainsFunc(migrations, func(m Migration) bool { return m.Version == version }) {
+		return false, fmt.Errorf("no migration %d", version)
+	}
+	return len(migrations) > 0 && version == migrations[len(migrations)-1].Version, nil
+}
+
+// migrateToSets is what MigrateTo undoes, newest first, and then applies
+func migrateToSets(migrations []Migration, applied map[int64]bool, version int64) (undo, apply []Migration) {
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if m := migrations[i]; m.Version > version && applied[m.Version] {
+			undo = append(undo, m)
+		}
+	}
+	for _, m := range migrations {
+		if m.Version <= version && !applied[m.Version] {
+			apply = append(apply, m)
+		}
+	}
+	return undo, apply
+}
+
+// MigrationStatus is whether a migration has been applied to the database
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time // When it was recorded, zero if it's pending
+}
+
+// Status lists every embedded migration, oldest first, with whether the
+// database has applied it
+func (db *DB) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(defaultDialect().migrations)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := db.migrationTimes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		at, ok := applied[m.Version]
+		status[i] = MigrationStatus{Migration: m, Applied: ok, AppliedAt: at}
+	}
+	return status, nil
+}
+
+// migrationTimes returns when each applied migration was recorded
+func (db *DB) migrationTimes(ctx context.Context) (map[int64]time.Time, error) {
+	if d := defaultDialect(); d.listTables != nil {
+		have, err := d.listTables(ctx, db.Conn)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(have, "schema_migrations") {
+			return map[int64]time.Time{}, nil
+		}
+	}
+	rows, err := db.Conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var v int64
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		applied[v] = at
+	}
+	return applied, rows.Err()
+}
+
+// stripSQLComments drops -- comments, to tell an untouched down template
+// from one with SQL in it
+func stripSQLComments(script string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,172 @@
+package database_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// TestMigrateFromBaseline opens a database made by the schema.sql this
+// project started from, before there were migrations
+func TestMigrateFromBaseline(t *testing.T) {
+	ctx := context.Background()
+	cfg := baselineDB(t, `
+		INSERT INTO users (telegram_id, first_name, balance_game) VALUES (1, 'Ann', 1.5), (2, 'Bob', 0.1);
+		INSERT INTO groups (telegram_id, title, balance) VALUES (10, 'Chess', 20.25);
+		INSERT INTO user_group (user_telegram_id, group_telegram_id, balance) VALUES (1, 10, 3), (2, 10, 0), (3, 10, 0), (1, 11, 0);
+		DELETE FROM user_group WHERE user_telegram_id = 2;
+		DELETE FROM users WHERE telegram_id = 2;`)
+
+	db := open(t, cfg)
+	user, err := db.Q.GetUserByTelegramID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.BalanceGame.V != 150 || user.Version != 1 || user.DeletedAt.Valid {
+		t.Errorf("user after migrating: %+v", user)
+	}
+	members, err := db.Q.ListGroupMembers(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 {
+		t.Errorf("got %d members of 10, want Ann; the memberships of user 3 and group 11 point nowhere", len(members))
+	}
+	pending, err := db.PendingMigrations(ctx)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("pending migrations: %v, %v", pending, err)
+	}
+	drift, err := db.SchemaDrift(ctx)
+	if err != nil || !drift.None() {
+		t.Errorf("schema drift: %v, %v", drift, err)
+	}
+
+	// What the baseline adds is enforced
+	for _, stmt := range []string{
+		"UPDATE users SET status = 'gone' WHERE telegram_id = 1",
+		"INSERT INTO user_group (user_telegram_id, group_telegram_id) VALUES (3, 10)",
+		"DELETE FROM groups WHERE telegram_id = 10",
+	} {
+		if _, err := db.Conn.ExecContext(ctx, stmt); err == nil {
+			t.Errorf("%s: succeeded, want a constraint error", stmt)
+		}
+	}
+	if _, err := db.Conn.ExecContext(ctx, "UPDATE users SET email = 'Ann@example.com' WHERE telegram_id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Q.GetUserByEmail(ctx, "ann@example.com"); err != nil {
+		t.Errorf("GetUserByEmail: %v", err)
+	}
+
+	// The ids carry on after the deleted user's
+	created, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: 4, FirstName: "Cy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != 3 {
+		t.Errorf("new user got id %d, want 3", created.ID)
+	}
+
+	db.Close()
+	open(t, cfg) // Nothing left to migrate
+}
+
+// TestBaselineOnlyRecorded opens a database schema.sql made, which has
+// what the baseline adds, without the baseline recorded, as builds from
+// before it left them
+func TestBaselineOnlyRecorded(t *testing.T) {
+	ctx := context.Background()
+	var cfg database.Config
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { cfg = *c }})
+	if _, err := db.Conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = 0"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	status, err := open(t, cfg).Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) == 0 || status[0].Version != 0 || !status[0].Applied {
+		t.Errorf("baseline status: %+v", status)
+	}
+}
+
+func TestValidateMigrations(t *testing.T) {
+	if err := database.ValidateMigrations(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func open(t *testing.T, cfg database.Config) *database.DB {
+	t.Helper()
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestMigratedSchemaMatchesSchemaSQL checks the migrations bring a
+// baseline database to the schema schema.sql creates: the same tables,
+// columns in the same order with the same types, defaults and keys, and
+// the same indexes and foreign keys
+func TestMigratedSchemaMatchesSchemaSQL(t *testing.T) {
+	ctx := context.Background()
+	want, err := dbtest.NewTestDB(t).Introspect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := open(t, baselineDB(t, "")).Introspect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, w := range want.Tables {
+		g, ok := got.Table(w.Name)
+		if !ok {
+			t.Errorf("migrated database has no table %s", w.Name)
+			continue
+		}
+		for _, diff := range tableDiff(g, w) {
+			t.Errorf("%s: %s", w.Name, diff)
+		}
+	}
+	for _, g := range got.Tables {
+		if _, ok := want.Table(g.Name); !ok {
+			t.Errorf("migrated database has table %s, which schema.sql doesn't", g.Name)
+		}
+	}
+}
+
+// tableDiff lists how the migrated table got differs from want, each
+// part as JSON
+func tableDiff(got, want database.TableInfo) []string {
+	var diffs []string
+	differ := func(what string, g, w any) {
+		if !reflect.DeepEqual(g, w) {
+			gj, _ := json.Marshal(g)
+			wj, _ := json.Marshal(w)
+			diffs = append(diffs, fmt.Sprintf("%s %s after migrating, %s from schema.sql", what, gj, wj))
+		}
+	}
+	for i := range max(len(got.Columns), len(want.Columns)) {
+		var g, w *database.ColumnInfo
+		if i < len(got.Columns) {
+			g = &got.Columns[i]
+		}
+		if i < len(want.Columns) {
+			w = &want.Columns[i]
+		}
+		differ(fmt.Sprintf("column %d", i+1), g, w)
+	}
+	differ("indexes", got.Indexes, want.Indexes)
+	differ("foreign keys", got.ForeignKeys, want.ForeignKeys)
+	return diffs
+}
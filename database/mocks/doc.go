@@ -0,0 +1,16 @@
+// Package mocks has generated mocks of the database package's repository
+// interfaces (UserRepository and the rest), for unit tests of services
+// that take them instead of a *database.DB:
+//
+//	users := &mocks.UserRepositoryMock{
+//		GetUserByTelegramIDFunc: func(ctx context.Context, telegramID int64) (database.User, error) {
+//			return database.User{}, database.ErrNotFound
+//		},
+//	}
+//	svc := NewProfileService(users)
+//	// ... call svc, then inspect users.GetUserByTelegramIDCalls()
+//
+// A method a test leaves nil panics when called. repository_mock.go is
+// written by moq from database/repository.go, see the go:generate line
+// there; don't edit it by hand.
+package mocks
@@ -1,3 +1,5 @@
+//go:build !postgres
+
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
 //   sqlc v1.30.0
@@ -6,36 +8,155 @@ package database
 
 import (
 	"database/sql"
+	"time"
 )
 
+type Attachment struct {
+	ID          int64               `json:"id"`
+	Sha256      []byte              `json:"sha256"`
+	ContentType string              `json:"content_type"`
+	Size        int64               `json:"size"`
+	Data        []byte              `json:"data"`
+	CreatedAt   sql.Null[time.Time] `json:"created_at"`
+}
+
+type AuditActor struct {
+	ID    int64  `json:"id"`
+	Actor string `json:"actor"`
+}
+
+type AuditLog struct {
+	ID        int64                      `json:"id"`
+	TableName string                     `json:"table_name"`
+	RowID     int64                      `json:"row_id"`
+	Operation string                     `json:"operation"`
+	Actor     sql.Null[string]           `json:"actor"`
+	OldData   JSONColumn[map[string]any] `json:"old_data"`
+	NewData   JSONColumn[map[string]any] `json:"new_data"`
+	ChangedAt time.Time                  `json:"changed_at"`
+}
+
+type Category struct {
+	ID             int64           `json:"id"`
+	ParentID       sql.Null[int64] `json:"parent_id"`
+	Name           string          `json:"name"`
+	NameNormalized string          `json:"name_normalized"`
+}
+
+type CollationVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
 type Group struct {
-	ID         int64           `json:"id"`
-	Balance    sql.NullFloat64 `json:"balance"`
-	TelegramID int64           `json:"telegram_id"`
-	Title      sql.NullString  `json:"title"`
-	Url        sql.NullString  `json:"url"`
-	CreatedAt  sql.NullTime    `json:"created_at"`
-	UpdatedAt  sql.NullTime    `json:"updated_at"`
+	ID         int64               `json:"id"`
+	Balance    sql.Null[Money]     `json:"balance"`
+	TelegramID int64               `json:"telegram_id"`
+	Title      sql.Null[string]    `json:"title"`
+	Url        sql.Null[string]    `json:"url"`
+	CreatedAt  sql.Null[time.Time] `json:"created_at"`
+	UpdatedAt  sql.Null[time.Time] `json:"updated_at"`
+}
+
+type GroupHistory struct {
+	HistoryID  int64               `json:"history_id"`
+	Operation  string              `json:"operation"`
+	ChangedAt  time.Time           `json:"changed_at"`
+	ID         int64               `json:"id"`
+	Balance    sql.Null[Money]     `json:"balance"`
+	TelegramID int64               `json:"telegram_id"`
+	Title      sql.Null[string]    `json:"title"`
+	Url        sql.Null[string]    `json:"url"`
+	CreatedAt  sql.Null[time.Time] `json:"created_at"`
+	UpdatedAt  sql.Null[time.Time] `json:"updated_at"`
+}
+
+type GroupTag struct {
+	GroupTelegramID int64 `json:"group_telegram_id"`
+	TagID           int64 `json:"tag_id"`
+}
+
+type Job struct {
+	ID          int64               `json:"id"`
+	Type        string              `json:"type"`
+	Payload     []byte              `json:"payload"`
+	Status      string              `json:"status"`
+	Attempts    int64               `json:"attempts"`
+	MaxAttempts int64               `json:"max_attempts"`
+	LastError   sql.Null[string]    `json:"last_error"`
+	RunAt       time.Time           `json:"run_at"`
+	CreatedAt   sql.Null[time.Time] `json:"created_at"`
+}
+
+type Outbox struct {
+	ID          int64               `json:"id"`
+	Topic       string              `json:"topic"`
+	Payload     []byte              `json:"payload"`
+	Attempts    int64               `json:"attempts"`
+	LastError   sql.Null[string]    `json:"last_error"`
+	AvailableAt time.Time           `json:"available_at"`
+	CreatedAt   sql.Null[time.Time] `json:"created_at"`
+	DeliveredAt sql.Null[time.Time] `json:"delivered_at"`
+}
+
+type StorageProbe struct {
+	ID        int64     `json:"id"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Tag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
 }
 
 type User struct {
-	ID                int64           `json:"id"`
-	TelegramID        int64           `json:"telegram_id"`
-	FirstName         string          `json:"first_name"`
-	Username          sql.NullString  `json:"username"`
-	BalanceGame       sql.NullFloat64 `json:"balance_game"`
-	BalanceChats      sql.NullFloat64 `json:"balance_chats"`
-	Status            string          `json:"status"`
-	Language          string          `json:"language"`
-	ReferFromID       sql.NullInt64   `json:"refer_from_id"`
-	LastStreakClaimAt sql.NullTime    `json:"last_streak_claim_at"`
-	CreatedAt         sql.NullTime    `json:"created_at"`
-	UpdatedAt         sql.NullTime    `json:"updated_at"`
+	ID                int64               `json:"id"`
+	TelegramID        int64               `json:"telegram_id"`
+	FirstName         string              `json:"first_name"`
+	Username          sql.Null[string]    `json:"username"`
+	BalanceGame       sql.Null[Money]     `json:"balance_game"`
+	BalanceChats      sql.Null[Money]     `json:"balance_chats"`
+	Status            Status              `json:"status"`
+	Language          string              `json:"language"`
+	ReferFromID       sql.Null[int64]     `json:"refer_from_id"`
+	LastStreakClaimAt sql.Null[time.Time] `json:"last_streak_claim_at"`
+	CreatedAt         sql.Null[time.Time] `json:"created_at"`
+	UpdatedAt         sql.Null[time.Time] `json:"updated_at"`
+	Email             sql.Null[string]    `json:"email"`
+	DeletedAt         sql.Null[time.Time] `json:"deleted_at"`
+	Version           int64               `json:"version"`
 }
 
 type UserGroup struct {
 	ID              int64           `json:"id"`
 	UserTelegramID  int64           `json:"user_telegram_id"`
 	GroupTelegramID int64           `json:"group_telegram_id"`
-	Balance         sql.NullFloat64 `json:"balance"`
+	Balance         sql.Null[Money] `json:"balance"`
+}
+
+type UserHistory struct {
+	HistoryID         int64               `json:"history_id"`
+	Operation         string              `json:"operation"`
+	ChangedAt         time.Time           `json:"changed_at"`
+	ID                int64               `json:"id"`
+	TelegramID        int64               `json:"telegram_id"`
+	FirstName         string              `json:"first_name"`
+	Username          sql.Null[string]    `json:"username"`
+	BalanceGame       sql.Null[Money]     `json:"balance_game"`
+	BalanceChats      sql.Null[Money]     `json:"balance_chats"`
+	Status            Status              `json:"status"`
+	Language          string              `json:"language"`
+	ReferFromID       sql.Null[int64]     `json:"refer_from_id"`
+	LastStreakClaimAt sql.Null[time.Time] `json:"last_streak_claim_at"`
+	CreatedAt         sql.Null[time.Time] `json:"created_at"`
+	UpdatedAt         sql.Null[time.Time] `json:"updated_at"`
+	Email             sql.Null[string]    `json:"email"`
+	DeletedAt         sql.Null[time.Time] `json:"deleted_at"`
+	Version           int64               `json:"version"`
+}
+
+type UserNationalID struct {
+	UserTelegramID  int64  `json:"user_telegram_id"`
+	NationalID      []byte `json:"national_id"`
+	NationalIDIndex []byte `json:"national_id_index"`
 }
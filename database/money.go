@@ -0,0 +1,375 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units, hundredths (cents): Money(1050) is
+// 10.50. The balance columns hold it as an integer, so sums and
+// comparisons are exact in SQL and in Go alike, where float64 balances
+// drifted (0.1 + 0.2 != 0.3). Give a column of your own to sqlc as an
+// override, as sqlc.yaml does for the balances:
+//
+//	overrides:
+//	  - column: "orders.total"
+//	    go_type:
+//	      type: "Money"
+//
+// The arithmetic methods return ErrMoneyOverflow instead of wrapping
+// around, and the ones that divide round halves away from zero, as ROUND
+// does on an exact value in SQLite, PostgreSQL and MySQL. Money is
+// written to JSON as a number with two decimals (10.50), and read from a
+// number or string of one.
+type Money int64
+
+// ErrMoneyOverflow means an amount doesn't fit in Money, about ±92
+// quadrillion units
+var ErrMoneyOverflow = errors.New("money amount out of range")
+
+// moneyScale is the minor units per unit
+const moneyScale = 100
+
+// ParseMoney reads a decimal amount ("10.50", "-3", "0.5"), with no more
+// than two decimals that aren't zero: "10.505" is an error rather than
+// rounded. No exponents and no thousands separators.
+func ParseMoney(s string) (Money, error) {
+	m, err := parseDecimal(s, false)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	return m, nil
+}
+
+// MoneyFromFloat rounds f to the nearest cent, halves away from zero, as
+// its shortest decimal spelling reads: 10.005 is 10.01, though the float
+// is a hair below. For amounts arriving as floats from elsewhere; keep
+// them out of arithmetic before this.
+func MoneyFromFloat(f float64) (Money, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid amount %v", f)
+	}
+	return parseDecimal(strconv.FormatFloat(f, 'f', -1, 64), true)
+}
+
+// parseDecimal reads [sign]digits[.digits] into cents, rounding what's
+// past the second decimal if round is set and refusing it otherwise
+func parseDecimal(s string, round bool) (Money, error) {
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !digits(whole) || !digits(frac) {
+		return 0, errors.New("not a decimal number")
+	}
+
+	cents := frac + "00"
+	rest := strings.TrimRight(frac[min(len(frac), 2):], "0")
+	up := false
+	if rest != "" {
+		if !round {
+			return 0, errors.New("more than two decimals")
+		}
+		up = rest[0] >= '5'
+	}
+	var u uint64
+	for _, c := range whole + cents[:2] {
+		hi, lo := bits.Mul64(u, 10)
+		u, _ = bits.Add64(lo, uint64(c-'0'), 0)
+		if hi != 0 || u < lo {
+			return 0, ErrMoneyOverflow
+		}
+	}
+	if up {
+		u++
+	}
+	if u > math.MaxInt64 {
+		return 0, ErrMoneyOverflow
+	}
+	if neg {
+		return Money(-int64(u)), nil
+	}
+	return Money(u), nil
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String spells m with two decimals: "10.50", "-0.05"
+func (m Money) String() string {
+	u, sign := uint64(m), ""
+	if m < 0 {
+		u, sign = -u, "-"
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, u/moneyScale, u%moneyScale)
+}
+
+// Add returns m + n
+func (m Money) Add(n Money) (Money, error) {
+	s := m + n
+	if (s > m) != (n > 0) {
+		return 0, ErrMoneyOverflow
+	}
+	return s, nil
+}
+
+// Sub returns m - n
+func (m Money) Sub(n Money) (Money, error) {
+	d := m - n
+	if (d < m) != (n > 0) {
+		return 0, ErrMoneyOverflow
+	}
+	return d, nil
+}
+
+// Mul returns m times n, such as a price times a quantity
+func (m Money) Mul(n int64) (Money, error) {
+	return m.MulRate(n, 1)
+}
+
+// MulRate returns m * num / den, rounded to the cent: fees, taxes and
+// discounts, at rates such as 2.5% (25, 1000). den must be positive.
+func (m Money) MulRate(num, den int64) (Money, error) {
+	if den <= 0 {
+		return 0, fmt.Errorf("rate %d/%d: the denominator must be positive", num, den)
+	}
+	neg := (m < 0) != (num < 0)
+	hi, lo := bits.Mul64(absU(int64(m)), absU(num))
+	if hi >= uint64(den) {
+		return 0, ErrMoneyOverflow
+	}
+	q, r := bits.Div64(hi, lo, uint64(den))
+	if r >= uint64(den)-r { // r >= den/2, exactly
+		q++
+	}
+	return signed(q, neg)
+}
+
+// Round rounds m to a multiple of unit, halves away from zero: Round(100)
+// to whole units, Round(5) to the nickel. unit must be positive.
+func (m Money) Round(unit Money) (Money, error) {
+	if unit <= 0 {
+		return 0, fmt.Errorf("rounding unit %v must be positive", unit)
+	}
+	q, r := absU(int64(m))/uint64(unit), absU(int64(m))%uint64(unit)
+	if r >= uint64(unit)-r {
+		q++
+	}
+	hi, lo := bits.Mul64(q, uint64(unit))
+	if hi != 0 {
+		return 0, ErrMoneyOverflow
+	}
+	return signed(lo, m < 0)
+}
+
+// Allocate splits m into parts in proportion to weights, which sum to m
+// exactly: the cents that don't divide evenly go one each to the first
+// parts. Allocate(1, 1, 1) of 1.00 is 0.34, 0.33 and 0.33. The weights
+// can't be negative, and one has to be positive.
+func (m Money) Allocate(weights ...int64) ([]Money, error) {
+	var total int64
+	for _, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("weight %d is negative", w)
+		}
+		if total += w; total < 0 {
+			return nil, errors.New("weights overflow")
+		}
+	}
+	if total == 0 {
+		return nil, errors.New("no positive weight to allocate by")
+	}
+
+	parts := make([]Money, len(weights))
+	left := absU(int64(m))
+	for i, w := range weights {
+		hi, lo := bits.Mul64(absU(int64(m)), uint64(w))
+		q, _ := bits.Div64(hi, lo, uint64(total)) // q <= |m|, since w <= total
+		parts[i], left = Money(q), left-q
+	}
+	for i := 0; left > 0; i++ {
+		if weights[i] > 0 {
+			parts[i]++
+			left--
+		}
+	}
+	if m < 0 {
+		for i := range parts {
+			parts[i] = -parts[i]
+		}
+	}
+	return parts, nil
+}
+
+// SumMoney adds amounts up
+func SumMoney(amounts ...Money) (Money, error) {
+	var sum Money
+	for _, a := range amounts {
+		var err error
+		if sum, err = sum.Add(a); err != nil {
+			return 0, err
+		}
+	}
+	return sum, nil
+}
+
+func absU(n int64) uint64 {
+	if n < 0 {
+		return -uint64(n)
+	}
+	return uint64(n)
+}
+
+func signed(u uint64, neg bool) (Money, error) {
+	switch {
+	case neg && u <= 1<<63:
+		return Money(-u), nil // -2^63 included
+	case !neg && u <= math.MaxInt64:
+		return Money(u), nil
+	}
+	return 0, ErrMoneyOverflow
+}
+
+// Value stores the cents as an integer
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// Scan reads cents as the drivers return them: int64 from an INTEGER or
+// BIGINT column, float64 from a SQLite REAL column or a sum over one (a
+// whole number, up to 2^53), and text from NUMERIC and DECIMAL results,
+// which SUM and ROUND give on PostgreSQL and MySQL ("1050", "1050.000").
+// Scan into sql.Null[Money] for a column that may be NULL.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*m = Money(v)
+		return nil
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return fmt.Errorf("Money can't scan %v, which isn't a whole number of cents", v)
+		}
+		*m = Money(v)
+		return nil
+	case []byte:
+		return m.scanText(string(v))
+	case string:
+		return m.scanText(v)
+	}
+	return fmt.Errorf("Money can't scan %T", src)
+}
+
+func (m *Money) scanText(s string) error {
+	whole, frac, _ := strings.Cut(s, ".")
+	if strings.Trim(frac, "0") != "" {
+		return fmt.Errorf("Money can't scan %q, which isn't a whole number of cents", s)
+	}
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Money can't scan %q: %w", s, err)
+	}
+	*m = Money(n)
+	return nil
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+func (m Money) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalText(b []byte) error {
+	v, err := ParseMoney(string(b))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// MoneyOf reads what a generated query returns as interface{}, which
+// sqlc gives sums and other expressions, as Money, so the same code works
+// with each driver's type for them (see Scan). NULL, a SUM over no rows,
+// is 0.
+//
+//	total, err := database.MoneyOf(db.Q.GetTotalUserBalance(ctx, telegramID))
+func MoneyOf(v any, err error) (Money, error) {
+	if err != nil {
+		return 0, Translate(err)
+	}
+	if v == nil {
+		return 0, nil
+	}
+	var m Money
+	if err := m.Scan(v); err != nil {
+		return 0, err
+	}
+	return m, nil
+}
+
+// BalanceSummary is a group's member balances, summed and rounded in SQL
+type BalanceSummary struct {
+	Members      int64 `json:"members"`
+	Total        Money `json:"total"`
+	Average      Money `json:"average"`       // To the cent
+	TotalRounded Money `json:"total_rounded"` // To whole units
+	Largest      Money `json:"largest"`
+}
+
+// GroupBalanceSummary runs GetGroupBalanceSummary, whose SQL answers the
+// same on SQLite, PostgreSQL and MySQL, and reads its sums as Money
+func (db *DB) GroupBalanceSummary(ctx context.Context, groupTelegramID int64) (BalanceSummary, error) {
+	row, err := db.Q.GetGroupBalanceSummary(ctx, groupTelegramID)
+	if err != nil {
+		return BalanceSummary{}, err
+	}
+	s := BalanceSummary{Members: row.Members}
+	for _, f := range []struct {
+		dst *Money
+		v   any
+	}{
+		{&s.Total, row.Total},
+		{&s.Average, row.Average},
+		{&s.TotalRounded, row.TotalRounded},
+		{&s.Largest, row.Largest},
+	} {
+		if *f.dst, err = MoneyOf(f.v, nil); err != nil {
+			return BalanceSummary{}, err
+		}
+	}
+	return s, nil
+}
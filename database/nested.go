@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// txKey finds a DB's open transaction in a context, see Tx.Context
+type txKey struct{ db *DB }
+
+// Context returns the context the transaction runs with. Transaction, InTx
+// and the other transaction helpers called with it, by fn itself or by
+// helpers it calls, nest in this transaction instead of beginning one of
+// their own, which could wait forever for a connection or a write slot
+// this one holds:
+//
+//	err := db.InTx(ctx, func(tx *database.Tx) error {
+//		// ...
+//		return chargeUser(tx.Context(), db, userID) // calls db.Transaction
+//	})
+//
+// A nested transaction is a SAVEPOINT. If its fn fails, only its own
+// writes are rolled back, and the outer fn decides what to do with the
+// error; if it succeeds, its writes commit with the outer transaction.
+func (t *Tx) Context() context.Context {
+	return t.ctx
+}
+
+// nestedTx runs fn in a savepoint of outer
+func (db *DB) nestedTx(ctx context.Context, outer *Tx, opts *sql.TxOptions, fn func(*Tx) error) error {
+	if opts != nil && *opts != (sql.TxOptions{}) {
+		return errors.New("a nested transaction can't change the isolation level or read-only mode")
+	}
+
+	t := &Tx{Querier: outer.Querier, queries: outer.queries, tx: outer.tx, depth: outer.depth + 1}
+	t.ctx = context.WithValue(ctx, txKey{db}, t)
+	// Through the transaction's middleware, so the shadow replay sees them
+	dbtx := outer.queries.db
+	name := fmt.Sprintf("nested_tx_%d", t.depth)
+
+	if _, err := dbtx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to begin nested transaction: %w", err)
+	}
+
+	if err := fn(t); err != nil {
+		_, rbErr := dbtx.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+name)
+		if rbErr == nil {
+			_, rbErr = dbtx.ExecContext(context.Background(), "RELEASE SAVEPOINT "+name)
+		}
+		t.finish(false)
+		if rbErr != nil {
+			return fmt.Errorf("tx error: %v, rollback error: %w", err, rbErr)
+		}
+		return Translate(err)
+	}
+
+	if _, err := dbtx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		t.finish(false)
+		return fmt.Errorf("failed to release nested transaction: %w", err)
+	}
+	// Its hooks now wait for the outer transaction
+	t.done = true
+	outer.onFinish = append(outer.onFinish, t.onFinish...)
+	return nil
+}
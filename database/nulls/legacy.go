@@ -0,0 +1,56 @@
+package nulls
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"time"
+)
+
+// The generated code used sql.NullString, NullInt64, NullFloat64, NullBool
+// and NullTime before it moved to sql.Null[T]. These convert between the
+// two for code that still holds the old types, such as a library's
+// structs; new code shouldn't need them.
+
+// FromLegacy converts a sql.NullString, NullInt64 or other sql.Null* value
+// to sql.Null[T], T being the type it holds (as in ValueOr)
+//
+// Deprecated: keep values as sql.Null[T] from the start.
+func FromLegacy[T any](n driver.Valuer) sql.Null[T] {
+	v, ok := value[T](n)
+	return sql.Null[T]{V: v, Valid: ok}
+}
+
+// LegacyString converts n to a sql.NullString
+//
+// Deprecated: use sql.Null[string] throughout.
+func LegacyString(n sql.Null[string]) sql.NullString {
+	return sql.NullString{String: n.V, Valid: n.Valid}
+}
+
+// LegacyInt64 converts n to a sql.NullInt64
+//
+// Deprecated: use sql.Null[int64] throughout.
+func LegacyInt64(n sql.Null[int64]) sql.NullInt64 {
+	return sql.NullInt64{Int64: n.V, Valid: n.Valid}
+}
+
+// LegacyFloat64 converts n to a sql.NullFloat64
+//
+// Deprecated: use sql.Null[float64] throughout.
+func LegacyFloat64(n sql.Null[float64]) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: n.V, Valid: n.Valid}
+}
+
+// LegacyBool converts n to a sql.NullBool
+//
+// Deprecated: use sql.Null[bool] throughout.
+func LegacyBool(n sql.Null[bool]) sql.NullBool {
+	return sql.NullBool{Bool: n.V, Valid: n.Valid}
+}
+
+// LegacyTime converts n to a sql.NullTime
+//
+// Deprecated: use sql.Null[time.Time] throughout.
+func LegacyTime(n sql.Null[time.Time]) sql.NullTime {
+	return sql.NullTime{Time: n.V, Valid: n.Valid}
+}
@@ -0,0 +1,120 @@
+// Package nulls converts between plain Go values and the sql.Null[T] types
+// sqlc generates for nullable columns.
+//
+// Convention: for text columns an empty string means NULL, so String("")
+// is an invalid sql.Null[string]. Numbers, booleans and times have no such
+// sentinel (0 and false are real values); use the *Ptr constructors when
+// you need a NULL. The one exception is Time, where the zero time.Time is
+// never a meaningful timestamp and is stored as NULL.
+package nulls
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// String returns a sql.Null[string] that is NULL for ""
+func String(s string) sql.Null[string] {
+	return sql.Null[string]{V: s, Valid: s != ""}
+}
+
+// StringPtr returns a sql.Null[string] that is NULL for nil. A non-nil
+// pointer to "" is stored as "", not NULL.
+func StringPtr(p *string) sql.Null[string] {
+	return FromPtr(p)
+}
+
+// Int64 returns a valid sql.Null[int64]
+func Int64(n int64) sql.Null[int64] {
+	return Of(n)
+}
+
+// Int64Ptr returns a sql.Null[int64] that is NULL for nil
+func Int64Ptr(p *int64) sql.Null[int64] {
+	return FromPtr(p)
+}
+
+// Float64 returns a valid sql.Null[float64]
+func Float64(f float64) sql.Null[float64] {
+	return Of(f)
+}
+
+// Float64Ptr returns a sql.Null[float64] that is NULL for nil
+func Float64Ptr(p *float64) sql.Null[float64] {
+	return FromPtr(p)
+}
+
+// Bool returns a valid sql.Null[bool]
+func Bool(b bool) sql.Null[bool] {
+	return Of(b)
+}
+
+// BoolPtr returns a sql.Null[bool] that is NULL for nil
+func BoolPtr(p *bool) sql.Null[bool] {
+	return FromPtr(p)
+}
+
+// Time returns a sql.Null[time.Time] that is NULL for the zero time
+func Time(t time.Time) sql.Null[time.Time] {
+	return sql.Null[time.Time]{V: t, Valid: !t.IsZero()}
+}
+
+// TimePtr returns a sql.Null[time.Time] that is NULL for nil
+func TimePtr(p *time.Time) sql.Null[time.Time] {
+	return FromPtr(p)
+}
+
+// Of returns a valid sql.Null[T] holding v, whatever its value
+func Of[T any](v T) sql.Null[T] {
+	return sql.Null[T]{V: v, Valid: true}
+}
+
+// FromPtr returns a sql.Null[T] that is NULL for nil
+func FromPtr[T any](p *T) sql.Null[T] {
+	if p == nil {
+		return sql.Null[T]{}
+	}
+	return sql.Null[T]{V: *p, Valid: true}
+}
+
+// ValueOr returns the value held by n, or def when n is NULL. T must be the
+// type n holds (string for sql.Null[string] or sql.NullString, and so on);
+// a mismatch such as ValueOr(nullFloat, 0), where 0 is an int, panics
+// rather than quietly returning def.
+func ValueOr[T any](n driver.Valuer, def T) T {
+	v, ok := value[T](n)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// Ptr returns a pointer to the value held by n, or nil when n is NULL.
+// T follows the same rule as in ValueOr.
+func Ptr[T any](n driver.Valuer) *T {
+	v, ok := value[T](n)
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+func value[T any](n driver.Valuer) (T, bool) {
+	// Straight from the field, as Value gives what T's own Valuer makes of
+	// it: the int64 for a sql.Null[database.Money]
+	if s, ok := n.(sql.Null[T]); ok {
+		return s.V, s.Valid
+	}
+	var zero T
+	v, err := n.Value()
+	if err != nil || v == nil {
+		return zero, false
+	}
+	t, ok := v.(T)
+	if !ok {
+		panic(fmt.Sprintf("nulls: %T holds %T, not %T", n, v, zero))
+	}
+	return t, true
+}
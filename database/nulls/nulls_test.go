@@ -0,0 +1,149 @@
+package nulls_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+	"your-project/database/nulls"
+)
+
+// roundTrip writes each kind of value through the constructors into a
+// nullables table with the columns s, i, f, b and t, made with create, and
+// reads it back; insert takes the five in that order
+func roundTrip(t *testing.T, dbtx database.DBTX, create, insert string) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := dbtx.ExecContext(ctx, create); err != nil {
+		t.Fatal(err)
+	}
+	s, i, f, b, at := "x", int64(-3), 2.5, false, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	type row struct {
+		s sql.Null[string]
+		i sql.Null[int64]
+		f sql.Null[float64]
+		b sql.Null[bool]
+		t sql.Null[time.Time]
+	}
+	for _, c := range []struct {
+		name string
+		in   row
+		want row // Zero fields are NULL
+	}{
+		{"values", row{nulls.String(s), nulls.Int64(i), nulls.Float64(f), nulls.Bool(b), nulls.Time(at)},
+			row{nulls.Of(s), nulls.Of(i), nulls.Of(f), nulls.Of(b), nulls.Of(at)}},
+		{"pointers", row{nulls.StringPtr(&s), nulls.Int64Ptr(&i), nulls.Float64Ptr(&f), nulls.BoolPtr(&b), nulls.TimePtr(&at)},
+			row{nulls.Of(s), nulls.Of(i), nulls.Of(f), nulls.Of(b), nulls.Of(at)}},
+		{"nil pointers", row{nulls.StringPtr(nil), nulls.Int64Ptr(nil), nulls.Float64Ptr(nil), nulls.BoolPtr(nil), nulls.TimePtr(nil)}, row{}},
+		// Only "" and the zero time stand for NULL; 0 and false are values
+		{"zero values", row{nulls.String(""), nulls.Int64(0), nulls.Float64(0), nulls.Bool(false), nulls.Time(time.Time{})},
+			row{i: nulls.Of[int64](0), f: nulls.Of[float64](0), b: nulls.Of(false)}},
+	} {
+		if _, err := dbtx.ExecContext(ctx, "DELETE FROM nullables"); err != nil {
+			t.Fatal(err)
+		}
+		in := c.in
+		if _, err := dbtx.ExecContext(ctx, insert, in.s, in.i, in.f, in.b, in.t); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var got row
+		if err := dbtx.QueryRowContext(ctx, "SELECT s, i, f, b, t FROM nullables").Scan(&got.s, &got.i, &got.f, &got.b, &got.t); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		got.t.V, c.want.t.V = got.t.V.UTC(), c.want.t.V.UTC()
+		if got != c.want {
+			t.Errorf("%s: read back %+v, want %+v", c.name, got, c.want)
+		}
+
+		// And out again
+		if v := nulls.ValueOr(got.s, "def"); v != nulls.ValueOr(c.want.s, "def") {
+			t.Errorf("%s: ValueOr(string) = %q", c.name, v)
+		}
+		if p := nulls.Ptr[int64](got.i); (p == nil) == c.want.i.Valid || p != nil && *p != c.want.i.V {
+			t.Errorf("%s: Ptr(int64) = %v, want %+v", c.name, p, c.want.i)
+		}
+	}
+}
+
+func TestValueOr(t *testing.T) {
+	if got := nulls.ValueOr(sql.NullString{}, "def"); got != "def" {
+		t.Errorf("a NULL sql.NullString: %q, want the default", got)
+	}
+	if got := nulls.ValueOr(sql.NullInt64{Int64: 5, Valid: true}, int64(0)); got != 5 {
+		t.Errorf("a sql.NullInt64: %d, want 5", got)
+	}
+	if got := nulls.Ptr[float64](sql.NullFloat64{}); got != nil {
+		t.Errorf("Ptr of a NULL: %v, want nil", got)
+	}
+	if got := nulls.FromLegacy[string](sql.NullString{String: "x", Valid: true}); got != nulls.Of("x") {
+		t.Errorf("FromLegacy: %+v", got)
+	}
+	if got := nulls.LegacyTime(nulls.Time(time.Time{})); got.Valid {
+		t.Errorf("LegacyTime of NULL: %+v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("ValueOr with the wrong type didn't panic")
+		}
+	}()
+	nulls.ValueOr(nulls.Float64(1), 0) // 0 is an int
+}
+
+func TestGeneratedModels(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	referrer, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: 1, FirstName: "referrer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if referrer.Username.Valid || referrer.ReferFromID.Valid || referrer.Email.Valid || referrer.DeletedAt.Valid {
+		t.Errorf("a user created with NULLs: %+v", referrer)
+	}
+	if !referrer.CreatedAt.Valid || referrer.CreatedAt.V.IsZero() {
+		t.Errorf("created_at %+v, want the column's default", referrer.CreatedAt)
+	}
+
+	u, err := db.Q.CreateUser(ctx, database.CreateUserParams{
+		TelegramID:  2,
+		FirstName:   "user",
+		Username:    nulls.String("name"),
+		ReferFromID: nulls.Int64(referrer.TelegramID),
+		Email:       nulls.String("user@example.com"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Q.UpdateUserBalanceChats(ctx, database.UpdateUserBalanceChatsParams{BalanceChats: nulls.Of[database.Money](1250), TelegramID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.Q.GetUserByTelegramID(ctx, u.TelegramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Username != nulls.Of("name") || got.ReferFromID != nulls.Of(int64(1)) || got.Email != nulls.Of("user@example.com") {
+		t.Errorf("read back username %+v, refer_from_id %+v, email %+v", got.Username, got.ReferFromID, got.Email)
+	}
+	if got.BalanceChats != nulls.Of[database.Money](1250) {
+		t.Errorf("balance_chats %+v, want 12.50", got.BalanceChats)
+	}
+
+	// And back to NULL
+	if _, err := db.Q.UpdateUserBalanceChats(ctx, database.UpdateUserBalanceChatsParams{TelegramID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Q.UpdateUser(ctx, database.UpdateUserParams{FirstName: "user", TelegramID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = db.Q.GetUserByTelegramID(ctx, u.TelegramID); err != nil {
+		t.Fatal(err)
+	}
+	if got.Username.Valid || got.BalanceChats.Valid {
+		t.Errorf("after setting NULL: username %+v, balance_chats %+v", got.Username, got.BalanceChats)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build postgres
+
+package nulls_test
+
+import (
+	"testing"
+
+	"your-project/database/dbtest"
+)
+
+func TestRoundTrip(t *testing.T) {
+	roundTrip(t, dbtest.NewTestDB(t).DBTX(),
+		"CREATE TABLE nullables (s TEXT, i BIGINT, f DOUBLE PRECISION, b BOOLEAN, t TIMESTAMPTZ)",
+		"INSERT INTO nullables VALUES ($1, $2, $3, $4, $5)")
+}
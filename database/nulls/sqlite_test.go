@@ -0,0 +1,35 @@
+//go:build !postgres
+
+package nulls_test
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"your-project/database/dbtest"
+)
+
+const (
+	sqliteCreate = "CREATE TABLE nullables (s TEXT, i INTEGER, f REAL, b BOOLEAN, t DATETIME)"
+	sqliteInsert = "INSERT INTO nullables VALUES (?, ?, ?, ?, ?)"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Run("mattn", func(t *testing.T) {
+		roundTrip(t, dbtest.NewTestDB(t).DBTX(), sqliteCreate, sqliteInsert)
+	})
+
+	// Open doesn't register the schema's functions with modernc.org/sqlite,
+	// so it gets a bare database
+	t.Run("modernc", func(t *testing.T) {
+		db, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetMaxOpenConns(1) // Each connection has a database of its own
+		t.Cleanup(func() { db.Close() })
+		roundTrip(t, db, sqliteCreate, sqliteInsert)
+	})
+}
@@ -0,0 +1,80 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"your-project/database"
+)
+
+func TestOpenIndependent(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	open := func(name string, skipMigrations bool) *database.DB {
+		t.Helper()
+		db, err := database.Open(database.Config{DSN: filepath.Join(dir, name), LogLevel: "silent", SkipMigrations: skipMigrations})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	app, other := open("app.db", false), open("other.db", false)
+	events := open("events.db", true)
+	if _, err := events.Conn.Exec("CREATE TABLE events (name TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Open doesn't register anything for Get
+	if _, err := database.GetNamed(database.DefaultName); !errors.Is(err, database.ErrNotInitialized) {
+		t.Errorf("the default instance after Open: %v, want ErrNotInitialized", err)
+	}
+
+	newUsers(t, app, 1)
+	if n, err := other.Q.CountUsersByStatus(ctx, database.StatusActive); err != nil || n != 0 {
+		t.Errorf("users in the other database: %d, %v; want none", n, err)
+	}
+	if _, err := events.Q.CountUsersByStatus(ctx, database.StatusActive); err == nil {
+		t.Error("events has a users table, want only its own schema")
+	}
+	if _, err := app.Conn.Exec("INSERT INTO events VALUES ('x')"); err == nil {
+		t.Error("app has an events table, want only its own schema")
+	}
+
+	// Closing one leaves the rest open
+	if err := app.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := events.Conn.Exec("INSERT INTO events VALUES ('after closing app')"); err != nil {
+		t.Errorf("events after closing app: %v", err)
+	}
+	if _, err := other.Q.CountUsersByStatus(ctx, database.StatusActive); err != nil {
+		t.Errorf("other after closing app: %v", err)
+	}
+}
+
+func TestInitWrapsOpen(t *testing.T) {
+	cfg := database.Config{DSN: filepath.Join(t.TempDir(), "default.db"), LogLevel: "silent"}
+	db, err := database.Init(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	if got := database.Get(); got != db {
+		t.Errorf("Get: %p, want the DB Init returned (%p)", got, db)
+	}
+	if err := database.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.GetNamed(database.DefaultName); !errors.Is(err, database.ErrNotInitialized) {
+		t.Errorf("after Close: %v, want ErrNotInitialized", err)
+	}
+	if err := db.Conn.Ping(); err == nil {
+		t.Error("the default DB still answers after Close")
+	}
+}
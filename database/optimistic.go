@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStaleVersion means an update expected a row at a version that another
+// update has replaced since. Read the row again and redo the change, or
+// tell the user someone else changed it.
+var ErrStaleVersion = errors.New("row was changed by another update")
+
+// CheckVersion turns what a versioned :execrows update returned into an
+// error: ErrStaleVersion when it matched no row. It's for the IfVersion
+// queries run in a transaction, and queries of your own that follow the
+// pattern (SET version = version + 1 ... WHERE id = ? AND version = ?).
+func CheckVersion(rows int64, err error) error {
+	if err != nil {
+		return Translate(err)
+	}
+	if rows == 0 {
+		return ErrStaleVersion
+	}
+	return nil
+}
+
+// UpdateUserIfVersion renames the user, unless it has changed since it was
+// read at arg.Version: then it returns ErrStaleVersion, or ErrNotFound if
+// the user is gone. On success the user is at arg.Version+1.
+//
+//	err := db.UpdateUserIfVersion(ctx, database.UpdateUserIfVersionParams{
+//		ID: user.ID, Version: user.Version, FirstName: name, Username: user.Username,
+//	})
+//	if errors.Is(err, database.ErrStaleVersion) {
+//		// someone saved first: reload and ask again
+//	}
+func (db *DB) UpdateUserIfVersion(ctx context.Context, arg UpdateUserIfVersionParams) error {
+	err := CheckVersion(db.Q.UpdateUserIfVersion(ctx, arg))
+	return db.staleUser(ctx, arg.ID, arg.Version, err)
+}
+
+// UpdateUserBalanceChatsIfVersion is UpdateUserIfVersion for the chats
+// balance
+func (db *DB) UpdateUserBalanceChatsIfVersion(ctx context.Context, arg UpdateUserBalanceChatsIfVersionParams) error {
+	err := CheckVersion(db.Q.UpdateUserBalanceChatsIfVersion(ctx, arg))
+	return db.staleUser(ctx, arg.ID, arg.Version, err)
+}
+
+// staleUser tells a user that changed from one that doesn't exist, when a
+// versioned update matched nothing. Soft-deleted users count as existing,
+// since updates by id still reach them.
+func (db *DB) staleUser(ctx context.Context, id, version int64, err error) error {
+	if !errors.Is(err, ErrStaleVersion) {
+		return err
+	}
+	if _, err := db.WithDeleted().GetUserByID(ctx, id); err != nil {
+		return fmt.Errorf("user %d: %w", id, err)
+	}
+	return fmt.Errorf("user %d at version %d: %w", id, version, ErrStaleVersion)
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// NewFromConn wraps a connection you already manage, with the settings of
+// cfg that Open applies once it's connected: the middleware, field keys,
+// cursors, logs, replicas, shadow and background jobs. What concerns
+// opening the connection is up to whoever opened conn and is ignored:
+// the DSN and its defaults, the connection retries, and the pool settings
+// but for MaxIdleConns, which the health monitor restores after dropping
+// the idle connections, so set it to what conn keeps.
+//
+// It doesn't ping, migrate (call Migrate for that) or register the
+// instance, and Close leaves conn open, as do Close and CloseAll.
+func NewFromConn(conn *sql.DB, cfg Config) (*DB, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	d, err := dialectFor(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+	maxIdle := cfg.MaxIdleConns
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+	db, err := newDB(context.Background(), cfg, d, conn, maxIdle)
+	if err != nil {
+		return nil, err
+	}
+	db.borrowed = true
+	return db, nil
+}
+
+// NewFromDBTX returns just the query interface on top of any DBTX
+// (*sql.DB, *sql.Tx, *sql.Conn). There is no Transaction helper here;
+// use NewFromConn if you need one.
+func NewFromDBTX(dbtx DBTX) *Queries {
+	return New(dbtx)
+}
@@ -0,0 +1,59 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestNewFromConn(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.NewTestDB(t).Conn // Someone else's pool, with the schema
+
+	if _, err := database.NewFromConn(conn, database.Config{LogLevel: "loud"}); err == nil {
+		t.Error("an invalid config: no error, want Validate's")
+	}
+
+	keys := &testKeys{keys: []database.FieldKey{fieldKey(1, 1)}}
+	db, err := database.NewFromConn(conn, database.Config{LogLevel: "silent", SlowQueries: 1, FieldKeys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate on an up-to-date schema: %v", err)
+	}
+
+	err = db.Transaction(ctx, func(q *database.Queries) error {
+		_, err := q.CreateUser(ctx, database.CreateUserParams{TelegramID: 1, FirstName: "user"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Q.GetUserByTelegramID(ctx, 1); err != nil {
+		t.Errorf("the user created in the transaction: %v", err)
+	}
+	if _, err := db.Q.GetUserByTelegramID(ctx, 2); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("a missing user: %v, want ErrNotFound", err)
+	}
+	// Settings from the config apply as they do with Open
+	if len(db.SlowQueries()) == 0 {
+		t.Error("no slow queries kept, want them with SlowQueries set")
+	}
+	if err := db.SetUserNationalID(ctx, 1, "AB123456"); err != nil {
+		t.Errorf("with the configured field keys: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.PingContext(ctx); err != nil {
+		t.Errorf("the caller's connection after Close: %v, want it open", err)
+	}
+}
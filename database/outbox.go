@@ -0,0 +1,127 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"your-project/database/nulls"
+)
+
+// Event is an outbox row handed to the dispatcher's handler
+type Event struct {
+	ID        int64
+	Topic     string
+	Payload   []byte
+	Attempts  int64 // Failed deliveries so far
+	CreatedAt time.Time
+}
+
+// OutboxOptions controls the outbox dispatcher
+type OutboxOptions struct {
+	PollInterval time.Duration // How often to look for new events (default 1s)
+	BatchSize    int           // Events claimed per poll (default 100)
+	Lease        time.Duration // How long a claimed event is hidden from other dispatchers (default 30s)
+	MinBackoff   time.Duration // Delay before the first retry, doubled per attempt (default 1s)
+	MaxBackoff   time.Duration // Upper bound for the retry delay (default 5m)
+}
+
+// Publish writes an event to the outbox in the same transaction, so it is
+// dispatched only if the transaction commits
+func (t *Tx) Publish(topic string, payload []byte) error {
+	_, err := t.InsertOutboxEvent(t.ctx, InsertOutboxEventParams{
+		Topic:   topic,
+		Payload: payload,
+	})
+	return err
+}
+
+// StartOutboxDispatcher polls the outbox in the background until ctx is
+// canceled. Delivery is at-least-once: an event is marked delivered only
+// after handler returns nil, and a failed event is retried with backoff.
+// Events of the same topic are handled in the order they were published.
+func (db *DB) StartOutboxDispatcher(ctx context.Context, handler func(Event) error, opts OutboxOptions) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = 30 * time.Second
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 5 * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(opts.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := db.dispatchOutbox(ctx, handler, opts); err != nil && ctx.Err() == nil {
+				log.Printf("outbox dispatch failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (db *DB) dispatchOutbox(ctx context.Context, handler func(Event) error, opts OutboxOptions) error {
+	events, err := db.Q.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{
+		AvailableAt: time.Now().UTC().Add(opts.Lease),
+		Limit:       int64(opts.BatchSize),
+	})
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(events, func(a, b Outbox) int { return cmp.Compare(a.ID, b.ID) })
+
+	// Once an event fails, later events of its topic wait for the retry
+	failed := map[string]bool{}
+
+	for _, e := range events {
+		if failed[e.Topic] {
+			continue
+		}
+
+		err := handler(Event{
+			ID:        e.ID,
+			Topic:     e.Topic,
+			Payload:   e.Payload,
+			Attempts:  e.Attempts,
+			CreatedAt: e.CreatedAt.V,
+		})
+		if err == nil {
+			if err := db.Q.MarkOutboxEventDelivered(ctx, e.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		failed[e.Topic] = true
+		backoff := min(opts.MinBackoff<<e.Attempts, opts.MaxBackoff)
+		if backoff <= 0 { // shift overflow
+			backoff = opts.MaxBackoff
+		}
+		if err := db.Q.FailOutboxEvent(ctx, FailOutboxEventParams{
+			LastError:   nulls.String(err.Error()),
+			AvailableAt: time.Now().UTC().Add(backoff),
+			ID:          e.ID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
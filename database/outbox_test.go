@@ -0,0 +1,178 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// deliveries records what an outbox handler was given, in order
+type deliveries struct {
+	mu   sync.Mutex
+	seen []database.Event
+}
+
+func (d *deliveries) add(e database.Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seen = append(d.seen, e)
+}
+
+func (d *deliveries) events() []database.Event {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.seen)
+}
+
+// payloads lists what was delivered of topic, in order
+func (d *deliveries) payloads(topic string) []string {
+	var got []string
+	for _, e := range d.events() {
+		if e.Topic == topic {
+			got = append(got, string(e.Payload))
+		}
+	}
+	return got
+}
+
+// waitDelivered waits until d has seen n events. The outbox compares with
+// CURRENT_TIMESTAMP, to the second, so a lease or backoff can take a
+// second longer than asked.
+func waitDelivered(t *testing.T, d *deliveries, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(d.events()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d events delivered after 5s, want %d", len(d.events()), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func publish(t *testing.T, db *database.DB, events ...[2]string) {
+	t.Helper()
+	err := db.InTx(context.Background(), func(tx *database.Tx) error {
+		for _, e := range events {
+			if err := tx.Publish(e[0], []byte(e[1])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+var fastOutbox = database.OutboxOptions{PollInterval: 10 * time.Millisecond, Lease: 200 * time.Millisecond, MinBackoff: 10 * time.Millisecond}
+
+func TestOutboxRollback(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	errAbort := errors.New("abort")
+	err := db.InTx(ctx, func(tx *database.Tx) error {
+		if err := tx.Publish("users", []byte("rolled back")); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatal(err)
+	}
+	publish(t, db, [2]string{"users", "committed"})
+
+	var d deliveries
+	dctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	db.StartOutboxDispatcher(dctx, func(e database.Event) error { d.add(e); return nil }, fastOutbox)
+	waitDelivered(t, &d, 1)
+	time.Sleep(50 * time.Millisecond) // Anything else would have come by now
+
+	if got := d.payloads("users"); !slices.Equal(got, []string{"committed"}) {
+		t.Errorf("delivered %q, want only the committed event", got)
+	}
+}
+
+func TestOutboxRedelivery(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	publish(t, db, [2]string{"leased", "1"}, [2]string{"failing", "1"})
+
+	// A dispatcher claims the first event and dies before delivering it
+	claimed, err := db.Q.ClaimOutboxEvents(ctx, database.ClaimOutboxEventsParams{AvailableAt: time.Now().UTC().Add(200 * time.Millisecond), Limit: 1})
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("claimed %d events, %v; want 1", len(claimed), err)
+	}
+
+	var d deliveries
+	failed := false
+	dctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	db.StartOutboxDispatcher(dctx, func(e database.Event) error {
+		d.add(e)
+		if e.Topic == "failing" && !failed {
+			failed = true
+			return errors.New("webhook down")
+		}
+		return nil
+	}, fastOutbox)
+
+	time.Sleep(50 * time.Millisecond)
+	if got := d.payloads("leased"); len(got) != 0 {
+		t.Errorf("the leased event was delivered within its lease")
+	}
+	waitDelivered(t, &d, 3)
+
+	attempts := map[string][]int64{}
+	for _, e := range d.events() {
+		attempts[e.Topic] = append(attempts[e.Topic], e.Attempts)
+	}
+	// The lease running out isn't a failed delivery
+	if got := attempts["leased"]; !slices.Equal(got, []int64{0}) {
+		t.Errorf("the leased event handled with attempts %v, want once, after its lease, with 0", got)
+	}
+	if got := attempts["failing"]; !slices.Equal(got, []int64{0, 1}) {
+		t.Errorf("the failing event handled with attempts %v, want [0 1]: again after the failure", got)
+	}
+}
+
+func TestOutboxOrderPerTopic(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	publish(t, db, [2]string{"a", "a1"}, [2]string{"b", "b1"}, [2]string{"a", "a2"}, [2]string{"b", "b2"}, [2]string{"a", "a3"})
+
+	var d deliveries
+	var delivered []string // Handled successfully, in order
+	dctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	db.StartOutboxDispatcher(dctx, func(e database.Event) error {
+		d.add(e)
+		if string(e.Payload) == "a1" && e.Attempts == 0 {
+			return errors.New("first try fails")
+		}
+		d.mu.Lock()
+		delivered = append(delivered, string(e.Payload))
+		d.mu.Unlock()
+		return nil
+	}, fastOutbox)
+	waitDelivered(t, &d, 6)
+
+	if got := d.payloads("a"); !slices.Equal(got, []string{"a1", "a1", "a2", "a3"}) {
+		t.Errorf("topic a handled as %q, want a1 retried before a2 and a3", got)
+	}
+	if got := d.payloads("b"); !slices.Equal(got, []string{"b1", "b2"}) {
+		t.Errorf("topic b handled as %q, want b1 then b2", got)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if i := slices.Index(delivered, "b2"); i < 0 || i > slices.Index(delivered, "a1") {
+		t.Errorf("delivered %q, want b done while a1 waited for its retry", delivered)
+	}
+}
@@ -0,0 +1,17 @@
+package database
+
+import "time"
+
+// PgxPoolConfig configures Config.PgxPool. A field left at 0 takes
+// pgxpool's default.
+type PgxPoolConfig struct {
+	MaxConns          int           `config:"max_conns"`           // Pool size (0 = 4 or NumCPU, whichever is more)
+	MinConns          int           `config:"min_conns"`           // Connections kept open while idle, opened in the background (0 = none)
+	MaxConnLifetime   time.Duration `config:"max_conn_lifetime"`   // Connections are replaced after this long (0 = 1h)
+	MaxConnIdleTime   time.Duration `config:"max_conn_idle_time"`  // Idle connections are closed after this long (0 = 30m)
+	HealthCheckPeriod time.Duration `config:"health_check_period"` // How often idle connections are checked and replaced (0 = 1m)
+}
+
+func (p PgxPoolConfig) enabled() bool {
+	return p != PgxPoolConfig{}
+}
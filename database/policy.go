@@ -0,0 +1,252 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// QueryPolicy gives the statements Match picks out a timeout, retries and
+// a circuit breaker of their own, so a query that's allowed to be slow, or
+// one worth retrying, is configured once instead of at every call site.
+// Match is a query's name as in Querier (GetUserByID), "read" or "write";
+// a statement takes the policy naming it, else its category's, so a name
+// overrides read and write for that one query.
+type QueryPolicy struct {
+	Match string `config:"match"` // Query name, "read" (statements that change neither rows nor the schema) or "write"
+
+	// Deadline for these statements when their context has none, in place
+	// of Config.QueryTimeout (0 = QueryTimeout's)
+	Timeout time.Duration `config:"timeout"`
+
+	// Runs a failed statement again up to Retries times, outside
+	// transactions only: after lock contention (see IsRetryable), and
+	// for reads after a connection error too, which a write can't be
+	// retried after since it may have been applied. The wait is random
+	// up to RetryBackoff (0 = 10ms), doubling per retry up to 1s.
+	Retries      int           `config:"retries"`
+	RetryBackoff time.Duration `config:"retry_backoff"`
+
+	// Fails these statements fast with ErrCircuitOpen after Threshold
+	// consecutive connection errors or timeouts, as Config.Breaker does
+	// for all of them (0 = no breaker of their own)
+	Breaker BreakerOptions `config:"breaker"`
+}
+
+// validateQueryPolicies is Config.Validate's check of the policies
+func validateQueryPolicies(policies []QueryPolicy) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for _, p := range policies {
+		switch _, known := querySQL[p.Match]; {
+		case p.Match == "":
+			errs = append(errs, errors.New("query_policies: match is required"))
+			continue
+		case p.Match != "read" && p.Match != "write" && !known:
+			errs = append(errs, fmt.Errorf("query_policies: unknown query %q (want a query name, read or write)", p.Match))
+		case seen[p.Match]:
+			errs = append(errs, fmt.Errorf("query_policies: %s has two policies", p.Match))
+		}
+		seen[p.Match] = true
+		if p.Timeout < 0 || p.Retries < 0 || p.RetryBackoff < 0 || p.Breaker.Threshold < 0 || p.Breaker.CoolDown < 0 {
+			errs = append(errs, fmt.Errorf("query_policies: %s's settings can't be negative", p.Match))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// queryPolicies finds a statement's policy; nil has none
+type queryPolicies struct {
+	byName      map[string]*queryPolicy
+	read, write *queryPolicy
+}
+
+type queryPolicy struct {
+	QueryPolicy
+	breaker *breaker
+}
+
+func newQueryPolicies(policies []QueryPolicy) *queryPolicies {
+	if len(policies) == 0 {
+		return nil
+	}
+	p := &queryPolicies{byName: make(map[string]*queryPolicy)}
+	for _, qp := range policies {
+		pol := &queryPolicy{QueryPolicy: qp, breaker: newBreaker(qp.Breaker)}
+		if pol.breaker != nil {
+			pol.breaker.label = " for " + qp.Match
+			pol.breaker.failure = func(err error) bool {
+				return isConnectionError(err) || errors.Is(err, ErrQueryTimeout)
+			}
+		}
+		switch qp.Match {
+		case "read":
+			p.read = pol
+		case "write":
+			p.write = pol
+		default:
+			p.byName[qp.Match] = pol
+		}
+	}
+	return p
+}
+
+func (p *queryPolicies) match(query string) *queryPolicy {
+	if p == nil {
+		return nil
+	}
+	if pol, ok := p.byName[queryName(query)]; ok {
+		return pol
+	}
+	if isMutatingQuery(query) {
+		return p.write
+	}
+	return p.read
+}
+
+// timeout is the statement's Timeout, or def without one
+func (p *queryPolicies) timeout(query string, def time.Duration) time.Duration {
+	if pol := p.match(query); pol != nil && pol.Timeout > 0 {
+		return pol.Timeout
+	}
+	return def
+}
+
+// timeouts reports whether any policy sets a Timeout
+func (p *queryPolicies) timeouts() bool {
+	if p == nil {
+		return false
+	}
+	for _, pol := range p.all() {
+		if pol.Timeout > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *queryPolicies) all() []*queryPolicy {
+	var all []*queryPolicy
+	for _, pol := range p.byName {
+		all = append(all, pol)
+	}
+	for _, pol := range []*queryPolicy{p.read, p.write} {
+		if pol != nil {
+			all = append(all, pol)
+		}
+	}
+	return all
+}
+
+// breakers reports the state of every policy's own breaker, by Match
+func (p *queryPolicies) breakers() map[string]string {
+	if p == nil {
+		return nil
+	}
+	var states map[string]string
+	for _, pol := range p.all() {
+		if pol.breaker != nil {
+			if states == nil {
+				states = make(map[string]string)
+			}
+			states[pol.Match] = pol.breaker.current()
+		}
+	}
+	return states
+}
+
+// run runs the statement, fn, under the policy's breaker, then again
+// while it may be retried
+func (pol *queryPolicy) run(ctx context.Context, query string, retry bool, retries *atomic.Int64, fn func() error) error {
+	backoff := cmp.Or(pol.RetryBackoff, 10*time.Millisecond)
+	for attempt := 0; ; attempt++ {
+		err := pol.attempt(fn)
+		if err == nil || !retry || attempt >= pol.Retries || !retryableStatement(query, err) || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(rand.N(backoff) + 1):
+		}
+		retries.Add(1)
+		backoff = min(2*backoff, time.Second)
+	}
+}
+
+func (pol *queryPolicy) attempt(fn func() error) error {
+	if pol.breaker == nil {
+		return fn()
+	}
+	call, err := pol.breaker.allow()
+	if err != nil {
+		return fmt.Errorf("%w for %s", err, pol.Match)
+	}
+	err = fn()
+	pol.breaker.record(call, err)
+	return err
+}
+
+// retryableStatement is lock contention, which means the statement didn't
+// run, or a connection error on a read, which has nothing to apply twice
+func retryableStatement(query string, err error) bool {
+	return IsRetryable(err) || (!isMutatingQuery(query) && isConnectionError(err))
+}
+
+// policyDBTX enforces the retries and breakers of Config.QueryPolicies;
+// their timeouts are timeoutDBTX's. retry is false in a transaction, where
+// a failed statement may have aborted everything before it.
+type policyDBTX struct {
+	DBTX
+	p       *queryPolicies
+	retry   bool
+	retries *atomic.Int64
+}
+
+func (d *policyDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	pol := d.p.match(query)
+	if pol == nil {
+		return d.DBTX.ExecContext(ctx, query, args...)
+	}
+	var res sql.Result
+	err := pol.run(ctx, query, d.retry, d.retries, func() (err error) {
+		res, err = d.DBTX.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+func (d *policyDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	pol := d.p.match(query)
+	if pol == nil {
+		return d.DBTX.QueryContext(ctx, query, args...)
+	}
+	var rows *sql.Rows
+	err := pol.run(ctx, query, d.retry, d.retries, func() (err error) {
+		rows, err = d.DBTX.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (d *policyDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	pol := d.p.match(query)
+	if pol == nil {
+		return d.DBTX.QueryRowContext(ctx, query, args...)
+	}
+	var row *sql.Row
+	err := pol.run(ctx, query, d.retry, d.retries, func() error {
+		row = d.DBTX.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	if row == nil || row.Err() != err {
+		return errRow(ctx, err) // the breaker refused the last attempt
+	}
+	return row
+}
@@ -0,0 +1,87 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"your-project/database"
+)
+
+// writeConcurrently inserts users from many goroutines at once and returns
+// the errors. It writes to the pool directly, since query policies may
+// retry a "database is locked" that this is about.
+func writeConcurrently(db *database.DB) []error {
+	const workers, writes = 20, 20
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range writes {
+				_, err := db.Conn.ExecContext(context.Background(),
+					"INSERT INTO users (telegram_id, first_name) VALUES (?, 'user')", w*writes+i+1)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestSQLitePoolDefaults(t *testing.T) {
+	open := func(cfg database.Config) *database.DB { // cfg.DSN is the query string, if any
+		t.Helper()
+		cfg.DSN = filepath.Join(t.TempDir(), "app.db") + cfg.DSN
+		cfg.LogLevel = "silent"
+		db, err := database.Open(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	// What database/sql does on its own, with no busy timeout, as
+	// modernc.org/sqlite has none (mattn/go-sqlite3 brings its own 5s)
+	old := open(database.Config{Driver: "sqlite3", DSN: "?_busy_timeout=0", JournalMode: "delete", MaxOpenConns: 16})
+	errs := writeConcurrently(old)
+	if len(errs) == 0 {
+		t.Fatal("no write failed without the defaults; the test no longer reproduces the problem")
+	}
+	if !strings.Contains(errs[0].Error(), "locked") && !strings.Contains(errs[0].Error(), "busy") {
+		t.Errorf("without the defaults: %v, want database is locked", errs[0])
+	}
+
+	db := open(database.Config{JournalMode: "delete"})
+	if errs := writeConcurrently(db); len(errs) > 0 {
+		t.Errorf("%d writes failed with the defaults, first: %v", len(errs), errs[0])
+	}
+	if n := db.Conn.Stats().MaxOpenConnections; n != 1 {
+		t.Errorf("rollback journal: MaxOpenConns %d, want 1", n)
+	}
+	var ms int
+	if err := db.Conn.QueryRow("PRAGMA busy_timeout").Scan(&ms); err != nil || ms != 5000 {
+		t.Errorf("busy_timeout %d, %v; want 5000", ms, err)
+	}
+
+	if n := open(database.Config{}).Conn.Stats().MaxOpenConnections; n != runtime.NumCPU() {
+		t.Errorf("WAL: MaxOpenConns %d, want NumCPU (%d)", n, runtime.NumCPU())
+	}
+	if n := open(database.Config{JournalMode: "delete", MaxOpenConns: 3}).Conn.Stats().MaxOpenConnections; n != 3 {
+		t.Errorf("MaxOpenConns set: %d, want 3", n)
+	}
+}
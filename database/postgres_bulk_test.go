@@ -0,0 +1,26 @@
+//go:build postgres
+
+package database_test
+
+import (
+	"slices"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// TestBulkInsertDrivers checks that pgx, which COPYs the rows, and
+// lib/pq, which INSERTs them, write the same rows
+func TestBulkInsertDrivers(t *testing.T) {
+	written := map[string][]string{}
+	for _, driver := range []string{"pgx", "postgres"} {
+		t.Run(driver, func(t *testing.T) {
+			db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { c.Driver = driver }})
+			written[driver] = bulkInsertRows(t, db)
+		})
+	}
+	if !slices.Equal(written["pgx"], written["postgres"]) {
+		t.Errorf("pgx wrote %q\nlib/pq wrote %q", written["pgx"], written["postgres"])
+	}
+}
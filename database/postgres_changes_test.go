@@ -0,0 +1,83 @@
+//go:build postgres
+
+package database_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestListenNotifications(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := dbtest.NewTestDB(t)
+
+	ch, err := db.Listen(ctx, "users_changes")
+	if os.Getenv("DB_TEST_DRIVER") == "postgres" {
+		if err == nil || !strings.Contains(err.Error(), "pgx") {
+			t.Errorf("Listen on lib/pq: %v, want it to need pgx", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Written straight to the pool, as another service would, with none of
+	// the DB's own layers to see it
+	other := db.Conn
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := other.ExecContext(ctx, query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var id int64
+	if err := other.QueryRowContext(ctx, "INSERT INTO users (telegram_id, first_name) VALUES (42, 'user') RETURNING id").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	exec("UPDATE users SET first_name = 'renamed' WHERE id = $1", id)
+	exec("DELETE FROM users WHERE id = $1", id)
+	for _, op := range []string{"INSERT", "UPDATE", "DELETE"} {
+		want := database.ChangeEvent{Table: "users", Operation: op, ID: id, TelegramID: 42}
+		if ev := nextChange(t, ch); ev != want {
+			t.Errorf("got %+v, want %+v", ev, want)
+		}
+	}
+
+	// Rolled back, so never notified
+	tx, err := other.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO users (telegram_id, first_name) VALUES (43, 'user')"); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	noChange(t, ch)
+
+	// Drop the listener's connection: it comes back with a resync, and
+	// later changes arrive as before
+	var n int
+	err = other.QueryRowContext(ctx, `SELECT count(pg_terminate_backend(pid)) FROM pg_stat_activity
+		WHERE datname = current_database() AND query LIKE 'LISTEN%' AND pid <> pg_backend_pid()`).Scan(&n)
+	if err != nil || n != 1 {
+		t.Fatalf("terminated %d listening backends, %v; want 1", n, err)
+	}
+	if ev := nextChange(t, ch); ev != (database.ChangeEvent{Table: "users", Operation: database.ChangeResync}) {
+		t.Errorf("after the connection dropped: %+v, want a resync", ev)
+	}
+	exec("INSERT INTO users (telegram_id, first_name) VALUES (44, 'user')")
+	if ev := nextChange(t, ch); ev.Operation != "INSERT" || ev.TelegramID != 44 {
+		t.Errorf("after reconnecting: %+v, want user 44's INSERT", ev)
+	}
+
+	cancel()
+	for range ch { // Closed once ctx is done
+	}
+}
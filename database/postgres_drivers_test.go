@@ -0,0 +1,29 @@
+//go:build postgres
+
+package database_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestMain runs the package's tests on pgx, then runs them again on lib/pq
+// in a child process, so every query is checked on both drivers. Set
+// DB_TEST_DRIVER to run them on one only.
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if os.Getenv("DB_TEST_DRIVER") != "" {
+		os.Exit(code)
+	}
+
+	fmt.Println("=== lib/pq: the same tests with DB_TEST_DRIVER=postgres")
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), "DB_TEST_DRIVER=postgres")
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil && code == 0 {
+		code = 1
+	}
+	os.Exit(code)
+}
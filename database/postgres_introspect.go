@@ -0,0 +1,202 @@
+//go:build postgres
+
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// postgresIntrospect reads information_schema for the current schema.
+// Indexes aren't part of the SQL standard, so they come from pg_index.
+func postgresIntrospect(ctx context.Context, dbtx DBTX) (SchemaInfo, error) {
+	var info SchemaInfo
+	byName := map[string]int{}
+	table := func(name string) *TableInfo {
+		if i, ok := byName[name]; ok {
+			return &info.Tables[i]
+		}
+		return nil
+	}
+
+	err := scanRows(ctx, dbtx, `SELECT table_name::text, table_type = 'VIEW'
+		FROM information_schema.tables WHERE table_schema = current_schema() ORDER BY table_name`,
+		func(rows *sql.Rows) error {
+			var t TableInfo
+			if err := rows.Scan(&t.Name, &t.View); err != nil {
+				return err
+			}
+			byName[t.Name] = len(info.Tables)
+			info.Tables = append(info.Tables, t)
+			return nil
+		})
+	if err != nil {
+		return SchemaInfo{}, err
+	}
+
+	// Enums and other types of our own show up as USER-DEFINED
+	err = scanRows(ctx, dbtx, `SELECT table_name::text, column_name::text,
+			CASE WHEN data_type IN ('USER-DEFINED', 'ARRAY') THEN udt_name ELSE data_type END::text,
+			is_nullable = 'YES', column_default::text, is_generated = 'ALWAYS'
+		FROM information_schema.columns WHERE table_schema = current_schema()
+		ORDER BY table_name, ordinal_position`,
+		func(rows *sql.Rows) error {
+			var name string
+			var c ColumnInfo
+			var def sql.NullString
+			if err := rows.Scan(&name, &c.Name, &c.Type, &c.Nullable, &def, &c.Generated); err != nil {
+				return err
+			}
+			if def.Valid {
+				c.Default = &def.String
+			}
+			if t := table(name); t != nil {
+				t.Columns = append(t.Columns, c)
+			}
+			return nil
+		})
+	if err != nil {
+		return SchemaInfo{}, err
+	}
+
+	err = scanRows(ctx, dbtx, `SELECT kcu.table_name::text, kcu.column_name::text, kcu.ordinal_position::int
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+		WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = current_schema()`,
+		func(rows *sql.Rows) error {
+			var name, col string
+			var pos int
+			if err := rows.Scan(&name, &col, &pos); err != nil {
+				return err
+			}
+			if t := table(name); t != nil {
+				for i := range t.Columns {
+					if t.Columns[i].Name == col {
+						t.Columns[i].PrimaryKey = pos
+					}
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return SchemaInfo{}, err
+	}
+
+	lastFK := ""
+	err = scanRows(ctx, dbtx, `SELECT kcu.table_name::text, rc.constraint_name::text, kcu.column_name::text,
+			ref.table_name::text, ref.column_name::text, rc.delete_rule::text, rc.update_rule::text
+		FROM information_schema.referential_constraints rc
+		JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_schema = rc.constraint_schema AND kcu.constraint_name = rc.constraint_name
+		JOIN information_schema.key_column_usage ref
+			ON ref.constraint_schema = rc.unique_constraint_schema AND ref.constraint_name = rc.unique_constraint_name
+			AND ref.ordinal_position = kcu.position_in_unique_constraint
+		WHERE rc.constraint_schema = current_schema()
+		ORDER BY kcu.table_name, rc.constraint_name, kcu.ordinal_position`,
+		func(rows *sql.Rows) error {
+			var name, constraint, col, parent, parentCol, onDelete, onUpdate string
+			if err := rows.Scan(&name, &constraint, &col, &parent, &parentCol, &onDelete, &onUpdate); err != nil {
+				return err
+			}
+			t := table(name)
+			if t == nil {
+				return nil
+			}
+			if key := name + "." + constraint; key != lastFK {
+				t.ForeignKeys = append(t.ForeignKeys, ForeignKeyInfo{RefTable: parent, OnDelete: onDelete, OnUpdate: onUpdate})
+				lastFK = key
+			}
+			fk := &t.ForeignKeys[len(t.ForeignKeys)-1]
+			fk.Columns = append(fk.Columns, col)
+			fk.RefColumns = append(fk.RefColumns, parentCol)
+			return nil
+		})
+	if err != nil {
+		return SchemaInfo{}, err
+	}
+
+	// indkey holds 0 for an expression
+	lastIndex := ""
+	err = scanRows(ctx, dbtx, `SELECT t.relname::text, i.relname::text, x.indisunique, x.indisprimary, coalesce(a.attname::text, '')
+		FROM pg_index x
+		JOIN pg_class t ON t.oid = x.indrelid
+		JOIN pg_class i ON i.oid = x.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(x.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum AND k.attnum <> 0
+		WHERE n.nspname = current_schema()
+		ORDER BY t.relname, i.relname, k.ord`,
+		func(rows *sql.Rows) error {
+			var name, index, col string
+			var unique, primary bool
+			if err := rows.Scan(&name, &index, &unique, &primary, &col); err != nil {
+				return err
+			}
+			t := table(name)
+			if t == nil {
+				return nil
+			}
+			if index != lastIndex {
+				t.Indexes = append(t.Indexes, IndexInfo{Name: index, Unique: unique, Primary: primary})
+				lastIndex = index
+			}
+			ix := &t.Indexes[len(t.Indexes)-1]
+			ix.Columns = append(ix.Columns, col)
+			return nil
+		})
+	if err != nil {
+		return SchemaInfo{}, err
+	}
+	return info, nil
+}
+
+// postgresExpectedSchema creates the embedded schema in a scratch schema
+// of its own and introspects it, all in a transaction that's rolled back,
+// so nothing is left behind. It needs the CREATE privilege on the
+// database.
+func postgresExpectedSchema(ctx context.Context, conn *sql.DB, cfg Config) (SchemaInfo, error) {
+	if cfg.ReadOnly {
+		return SchemaInfo{}, errors.New("the expected schema is made in a scratch schema, which read_only can't create")
+	}
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	scratch := "schema_check_" + hex.EncodeToString(suffix) // So concurrent checks don't wait on each other
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return SchemaInfo{}, err
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{
+		"CREATE SCHEMA " + scratch,
+		"SET LOCAL search_path = " + scratch,
+		createMigrationsTable,
+		defaultDialect().schema,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return SchemaInfo{}, fmt.Errorf("scratch schema: %w", err)
+		}
+	}
+	return postgresIntrospect(ctx, tx)
+}
+
+// scanRows runs query and hands each row to fn
+func scanRows(ctx context.Context, dbtx DBTX, query string, fn func(*sql.Rows) error) error {
+	rows, err := dbtx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
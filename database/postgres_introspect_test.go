@@ -0,0 +1,21 @@
+//go:build postgres
+
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"your-project/database/dbtest"
+)
+
+// TestIntrospectGolden compares a freshly migrated database with
+// testdata/introspect/postgres.json, to be rewritten with -update when the
+// schema changes
+func TestIntrospectGolden(t *testing.T) {
+	info, err := dbtest.NewTestDB(t).Introspect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "testdata/introspect/postgres.json", info)
+}
@@ -0,0 +1,32 @@
+//go:build postgres
+
+package database_test
+
+import (
+	"os"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// baselineDB empties a test database and creates in it
+// testdata/baseline/postgres.sql, a copy of the first PostgreSQL
+// schema.sql, and rows, and returns the config to open it
+func baselineDB(t *testing.T, rows string) database.Config {
+	t.Helper()
+	schema, err := os.ReadFile("testdata/baseline/postgres.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg database.Config
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { cfg = *c }})
+	if _, err := db.Conn.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Conn.Exec(string(schema) + rows); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	return cfg
+}
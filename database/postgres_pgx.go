@@ -0,0 +1,146 @@
+//go:build postgres
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+var errPgxUnsupported = errors.New("Tx.Pgx needs the pgx driver")
+
+// postgresOpen opens the database on a pgxpool.Pool with Config.PgxPool,
+// and like sql.Open otherwise
+func postgresOpen(driverName, dsn string, cfg Config) (*sql.DB, error) {
+	if !cfg.PgxPool.enabled() {
+		return sql.Open(driverName, dsn)
+	}
+	pc, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	p := cfg.PgxPool
+	if p.MaxConns > 0 {
+		pc.MaxConns = int32(p.MaxConns)
+	}
+	if p.MinConns > 0 {
+		pc.MinConns = int32(p.MinConns)
+	}
+	if p.MaxConnLifetime > 0 {
+		pc.MaxConnLifetime = p.MaxConnLifetime
+	}
+	if p.MaxConnIdleTime > 0 {
+		pc.MaxConnIdleTime = p.MaxConnIdleTime
+	}
+	if p.HealthCheckPeriod > 0 {
+		pc.HealthCheckPeriod = p.HealthCheckPeriod
+	}
+	// Connects lazily, so ping still reports an unreachable server
+	pool, err := pgxpool.NewWithConfig(context.Background(), pc)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&pgxPoolConnector{Connector: stdlib.GetPoolConnector(pool), pool: pool}), nil
+}
+
+// pgxPoolConnector closes the pool along with the *sql.DB, and names it
+// to DB.PgxPool through Driver
+type pgxPoolConnector struct {
+	driver.Connector
+	pool *pgxpool.Pool
+}
+
+func (c *pgxPoolConnector) Driver() driver.Driver {
+	return &pgxPoolDriver{Driver: c.Connector.Driver(), pool: c.pool}
+}
+
+func (c *pgxPoolConnector) Close() error {
+	c.pool.Close()
+	return nil
+}
+
+type pgxPoolDriver struct {
+	driver.Driver
+	pool *pgxpool.Pool
+}
+
+// PgxPool is the pool the database was opened on with Config.PgxPool,
+// nil without one. Queries run on it directly skip the DBTX middleware,
+// the circuit breaker and query log included.
+func (db *DB) PgxPool() *pgxpool.Pool {
+	if d, ok := db.Conn.Driver().(*pgxPoolDriver); ok {
+		return d.pool
+	}
+	return nil
+}
+
+// Pgx runs fn on the pgx connection the transaction holds, for what
+// database/sql can't do, such as CopyFrom or a pgx.Batch: what it runs
+// commits or rolls back with the rest. Statements there skip the DBTX
+// middleware. fn must not end the transaction, nor run tx's queries,
+// which would wait for fn to return first.
+func (t *Tx) Pgx(fn func(*pgx.Conn) error) error {
+	ct, ok := t.tx.(*connTx)
+	if !ok {
+		return errPgxUnsupported
+	}
+	return ct.Conn.Raw(func(driverConn any) error {
+		pc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errPgxUnsupported
+		}
+		return fn(pc.Conn())
+	})
+}
+
+// postgresCopyRows sends the rows with COPY FROM STDIN on the
+// transaction's pgx connection. lib/pq's transactions have none to use.
+func postgresCopyRows(ctx context.Context, tx *Tx, table string, columns []string, n int, row func(int) ([]any, error)) (int64, error) {
+	var copied int64
+	err := tx.Pgx(func(c *pgx.Conn) (err error) {
+		copied, err = c.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromSlice(n, row))
+		return err
+	})
+	if errors.Is(err, errPgxUnsupported) {
+		return 0, errCopyUnsupported
+	}
+	return copied, err
+}
+
+// postgresBeginStmt begins pgx transactions by hand, so Tx.Pgx can reach
+// the connection; lib/pq's are left to database/sql
+func postgresBeginStmt(conn *sql.DB, opts *sql.TxOptions) (string, error) {
+	switch conn.Driver().(type) {
+	case *stdlib.Driver, *pgxPoolDriver:
+	default:
+		return "", nil
+	}
+	begin := []string{"BEGIN"}
+	if opts != nil {
+		switch opts.Isolation {
+		case sql.LevelDefault:
+		case sql.LevelReadUncommitted:
+			begin = append(begin, "ISOLATION LEVEL READ UNCOMMITTED")
+		case sql.LevelReadCommitted:
+			begin = append(begin, "ISOLATION LEVEL READ COMMITTED")
+		case sql.LevelRepeatableRead, sql.LevelSnapshot:
+			begin = append(begin, "ISOLATION LEVEL REPEATABLE READ")
+		case sql.LevelSerializable:
+			begin = append(begin, "ISOLATION LEVEL SERIALIZABLE")
+		default:
+			return "", fmt.Errorf("unsupported isolation level: %v", opts.Isolation)
+		}
+		if opts.ReadOnly {
+			begin = append(begin, "READ ONLY")
+		}
+	}
+	return strings.Join(begin, " "), nil
+}
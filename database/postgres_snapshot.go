@@ -0,0 +1,86 @@
+//go:build postgres
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresAdmin connects to the postgres database on the server dsn points
+// at, for the statements that can't run in the database they're about,
+// and returns the database dsn names
+func postgresAdmin(dsn string) (*sql.DB, string, error) {
+	if dsn == "" {
+		return nil, "", errors.New("the DB's DSN is unknown (NewFromConn), open it with Open")
+	}
+	cc, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, "", fmt.Errorf("malformed DSN: %w", err)
+	}
+	database := cc.Database
+	cc.Database = "postgres"
+	delete(cc.RuntimeParams, "default_transaction_read_only") // Config.ReadOnly's, which refuses CREATE DATABASE
+	return stdlib.OpenDB(*cc), database, nil
+}
+
+// postgresSnapshot copies the database with CREATE DATABASE ... TEMPLATE,
+// which the server refuses with 55006 while anything is connected to it.
+// The copy is made under a name of its own and renamed once complete, so
+// the snapshot it replaces is there until then.
+func postgresSnapshot(ctx context.Context, _ *sql.DB, dsn, name string, closeIdle func()) error {
+	admin, source, err := postgresAdmin(dsn)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	closeIdle()
+	tmp := quoteIdent(name + "_new")
+	if _, err := admin.ExecContext(ctx, "DROP DATABASE IF EXISTS "+tmp); err != nil {
+		return err
+	}
+	if _, err := admin.ExecContext(ctx, "CREATE DATABASE "+tmp+" TEMPLATE "+quoteIdent(source)); err != nil {
+		var se sqlStater
+		if errors.As(err, &se) && se.SQLState() == "55006" {
+			return fmt.Errorf("other connections to %s are open, close them first: %w", source, err)
+		}
+		return err
+	}
+	for _, stmt := range []string{
+		// Whatever connects to a template keeps it from being copied, so nothing may
+		"ALTER DATABASE " + tmp + " ALLOW_CONNECTIONS false",
+		"DROP DATABASE IF EXISTS " + quoteIdent(name),
+		"ALTER DATABASE " + tmp + " RENAME TO " + quoteIdent(name),
+	} {
+		if _, err := admin.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func postgresClone(ctx context.Context, name string, cfg Config) error {
+	admin, target, err := postgresAdmin(cfg.DSN)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	_, err = admin.ExecContext(ctx, "CREATE DATABASE "+quoteIdent(target)+" TEMPLATE "+quoteIdent(name))
+	return err
+}
+
+func postgresDropSnapshot(ctx context.Context, dsn, name string) error {
+	admin, _, err := postgresAdmin(dsn)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	_, err = admin.ExecContext(ctx, "DROP DATABASE "+quoteIdent(name))
+	return err
+}
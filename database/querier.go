@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier is every query sqlc generates, for whichever dialect is compiled
+// in. It's written by hand rather than emitted (emit_interface) so both
+// dialects share one definition: the assertion below breaks the build of a
+// dialect whose generated code lacks a method or has a different signature.
+// Add new queries here after running sqlc generate, and to their repository
+// in repository.go if they have one, then build both ways.
+type Querier interface {
+	AddToUserGroupBalance(ctx context.Context, arg AddToUserGroupBalanceParams) (UserGroup, error)
+	AttachTag(ctx context.Context, arg AttachTagParams) error
+	BuryJob(ctx context.Context, arg BuryJobParams) error
+	ClaimJobs(ctx context.Context, arg ClaimJobsParams) ([]Job, error)
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error)
+	CompleteJob(ctx context.Context, arg CompleteJobParams) error
+	CountUsersByStatus(ctx context.Context, status Status) (int64, error)
+	CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error)
+	CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error)
+	CreateGroupIfMissing(ctx context.Context, arg CreateGroupIfMissingParams) (Group, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserGroup(ctx context.Context, arg CreateUserGroupParams) (UserGroup, error)
+	CreateUserIfMissing(ctx context.Context, arg CreateUserIfMissingParams) (User, error)
+	DeleteAttachment(ctx context.Context, id int64) error
+	DeleteGroup(ctx context.Context, telegramID int64) (Group, error)
+	DeleteJob(ctx context.Context, id int64) (int64, error)
+	DetachTag(ctx context.Context, arg DetachTagParams) error
+	EnqueueJob(ctx context.Context, arg EnqueueJobParams) (Job, error)
+	FailOutboxEvent(ctx context.Context, arg FailOutboxEventParams) error
+	GetAncestors(ctx context.Context, arg GetAncestorsParams) ([]GetAncestorsRow, error)
+	GetAttachmentMeta(ctx context.Context, id int64) (GetAttachmentMetaRow, error)
+	GetAuditLog(ctx context.Context, arg GetAuditLogParams) ([]AuditLog, error)
+	GetChildCategoryByName(ctx context.Context, arg GetChildCategoryByNameParams) (Category, error)
+	GetDescendants(ctx context.Context, arg GetDescendantsParams) ([]GetDescendantsRow, error)
+	GetGroupBalanceSummary(ctx context.Context, groupTelegramID int64) (GetGroupBalanceSummaryRow, error)
+	GetGroupByTelegramID(ctx context.Context, telegramID int64) (Group, error)
+	GetGroupHistory(ctx context.Context, id int64) ([]GroupHistory, error)
+	GetOrCreateUserGroup(ctx context.Context, arg GetOrCreateUserGroupParams) (UserGroup, error)
+	GetTopGroupsForUser(ctx context.Context, userTelegramID int64) ([]GetTopGroupsForUserRow, error)
+	GetTopUsersByBalance(ctx context.Context, limit int64) ([]User, error)
+	GetTopUsersInGroup(ctx context.Context, groupTelegramID int64) ([]GetTopUsersInGroupRow, error)
+	GetTotalGroupBalance(ctx context.Context, groupTelegramID int64) (interface{}, error)
+	GetTotalUserBalance(ctx context.Context, userTelegramID int64) (interface{}, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByID(ctx context.Context, id int64) (User, error)
+	GetUserByNationalIDIndex(ctx context.Context, nationalIDIndex []byte) (User, error)
+	GetUserByTelegramID(ctx context.Context, telegramID int64) (User, error)
+	GetUserGroup(ctx context.Context, arg GetUserGroupParams) (UserGroup, error)
+	GetUserHistory(ctx context.Context, id int64) ([]UserHistory, error)
+	GetUserIDRange(ctx context.Context) (GetUserIDRangeRow, error)
+	GetUserNationalID(ctx context.Context, userTelegramID int64) ([]byte, error)
+	GetUserPosition(ctx context.Context, balanceGame sql.Null[Money]) (int64, error)
+	InsertOutboxEvent(ctx context.Context, arg InsertOutboxEventParams) (Outbox, error)
+	ListAuditLogByActor(ctx context.Context, arg ListAuditLogByActorParams) ([]AuditLog, error)
+	ListAuditLogFieldChanges(ctx context.Context, arg ListAuditLogFieldChangesParams) ([]AuditLog, error)
+	ListCategoriesByName(ctx context.Context, name string) ([]Category, error)
+	ListDeadJobs(ctx context.Context, limit int64) ([]Job, error)
+	ListGroupMembers(ctx context.Context, groupTelegramID int64) ([]ListGroupMembersRow, error)
+	ListGroupTags(ctx context.Context, groupTelegramID int64) ([]Tag, error)
+	ListGroupsByTag(ctx context.Context, arg ListGroupsByTagParams) ([]Group, error)
+	ListGroupsByTitle(ctx context.Context, limit int64) ([]Group, error)
+	ListGroupsWithAllTags(ctx context.Context, arg ListGroupsWithAllTagsParams) ([]Group, error)
+	ListNationalIDCiphertexts(ctx context.Context, arg ListNationalIDCiphertextsParams) ([]ListNationalIDCiphertextsRow, error)
+	ListUsersByCreatedAt(ctx context.Context, arg ListUsersByCreatedAtParams) ([]User, error)
+	ListUsersByFirstName(ctx context.Context, limit int64) ([]User, error)
+	ListUsersByIDs(ctx context.Context, ids []int64) ([]User, error)
+	ListUsersByStatus(ctx context.Context, arg ListUsersByStatusParams) ([]User, error)
+	ListUsersMatchingUsername(ctx context.Context, arg ListUsersMatchingUsernameParams) ([]User, error)
+	ListUsersWithGroups(ctx context.Context, limit int64) ([]ListUsersWithGroupsRow, error)
+	MarkOutboxEventDelivered(ctx context.Context, id int64) error
+	PruneAuditLog(ctx context.Context, arg PruneAuditLogParams) (int64, error)
+	PurgeDeletedUsers(ctx context.Context, olderThanSeconds int64) (int64, error)
+	PutAttachment(ctx context.Context, arg PutAttachmentParams) (PutAttachmentRow, error)
+	ReadAttachmentChunk(ctx context.Context, arg ReadAttachmentChunkParams) ([]byte, error)
+	RenameCategory(ctx context.Context, arg RenameCategoryParams) (Category, error)
+	ReplaceNationalIDCiphertext(ctx context.Context, arg ReplaceNationalIDCiphertextParams) (int64, error)
+	RequeueDeadJob(ctx context.Context, arg RequeueDeadJobParams) (int64, error)
+	RestoreUser(ctx context.Context, id int64) (int64, error)
+	RetryJob(ctx context.Context, arg RetryJobParams) error
+	SampleUsers(ctx context.Context, limit int64) ([]User, error)
+	SampleUsersSeeded(ctx context.Context, arg SampleUsersSeededParams) ([]User, error)
+	SearchGroups(ctx context.Context, arg SearchGroupsParams) ([]SearchGroupsRow, error)
+	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error)
+	SetCategoryParent(ctx context.Context, arg SetCategoryParentParams) (Category, error)
+	SetUserNationalID(ctx context.Context, arg SetUserNationalIDParams) error
+	SoftDeleteUser(ctx context.Context, id int64) (int64, error)
+	TouchStorageProbe(ctx context.Context) error
+	UpdateStatusByIDs(ctx context.Context, arg UpdateStatusByIDsParams) (int64, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserBalanceChats(ctx context.Context, arg UpdateUserBalanceChatsParams) (User, error)
+	UpdateUserBalanceChatsIfVersion(ctx context.Context, arg UpdateUserBalanceChatsIfVersionParams) (int64, error)
+	UpdateUserGroupBalance(ctx context.Context, arg UpdateUserGroupBalanceParams) (UserGroup, error)
+	UpdateUserIfVersion(ctx context.Context, arg UpdateUserIfVersionParams) (int64, error)
+	UpsertGroup(ctx context.Context, arg UpsertGroupParams) (Group, error)
+	UpsertTag(ctx context.Context, name string) (Tag, error)
+	UpsertUser(ctx context.Context, arg UpsertUserParams) (User, error)
+	UpsertUserByEmail(ctx context.Context, arg UpsertUserByEmailParams) (User, error)
+	UpsertUserGroupBalance(ctx context.Context, arg UpsertUserGroupBalanceParams) (UserGroup, error)
+
+	// WithTx is the generated queries on a transaction begun on db.Conn
+	// by hand. They run without db.Q's middleware and return the driver's
+	// errors; InTx gives a Tx whose queries have both.
+	WithTx(tx *sql.Tx) *Queries
+}
+
+var _ Querier = (*Queries)(nil)
+
+// querySQL maps each query name to the SQL sqlc generated for it, for
+// tooling like Explain that runs a query by name. Keep it in step with
+// Querier; the constants have the same names in both dialects.
+var querySQL = map[string]string{
+	"AddToUserGroupBalance":           addToUserGroupBalance,
+	"AttachTag":                       attachTag,
+	"BuryJob":                         buryJob,
+	"ClaimJobs":                       claimJobs,
+	"ClaimOutboxEvents":               claimOutboxEvents,
+	"CompleteJob":                     completeJob,
+	"CountUsersByStatus":              countUsersByStatus,
+	"CreateCategory":                  createCategory,
+	"CreateGroup":                     createGroup,
+	"CreateGroupIfMissing":            createGroupIfMissing,
+	"CreateUser":                      createUser,
+	"CreateUserGroup":                 createUserGroup,
+	"CreateUserIfMissing":             createUserIfMissing,
+	"DeleteAttachment":                deleteAttachment,
+	"DeleteGroup":                     deleteGroup,
+	"DeleteJob":                       deleteJob,
+	"DetachTag":                       detachTag,
+	"EnqueueJob":                      enqueueJob,
+	"FailOutboxEvent":                 failOutboxEvent,
+	"GetAncestors":                    getAncestors,
+	"GetAttachmentMeta":               getAttachmentMeta,
+	"GetAuditLog":                     getAuditLog,
+	"GetChildCategoryByName":          getChildCategoryByName,
+	"GetDescendants":                  getDescendants,
+	"GetGroupBalanceSummary":          getGroupBalanceSummary,
+	"GetGroupByTelegramID":            getGroupByTelegramID,
+	"GetGroupHistory":                 getGroupHistory,
+	"GetOrCreateUserGroup":            getOrCreateUserGroup,
+	"GetTopGroupsForUser":             getTopGroupsForUser,
+	"GetTopUsersByBalance":            getTopUsersByBalance,
+	"GetTopUsersInGroup":              getTopUsersInGroup,
+	"GetTotalGroupBalance":            getTotalGroupBalance,
+	"GetTotalUserBalance":             getTotalUserBalance,
+	"GetUserByEmail":                  getUserByEmail,
+	"GetUserByID":                     getUserByID,
+	"GetUserByNationalIDIndex":        getUserByNationalIDIndex,
+	"GetUserByTelegramID":             getUserByTelegramID,
+	"GetUserGroup":                    getUserGroup,
+	"GetUserHistory":                  getUserHistory,
+	"GetUserIDRange":                  getUserIDRange,
+	"GetUserNationalID":               getUserNationalID,
+	"GetUserPosition":                 getUserPosition,
+	"InsertOutboxEvent":               insertOutboxEvent,
+	"ListAuditLogByActor":             listAuditLogByActor,
+	"ListAuditLogFieldChanges":        listAuditLogFieldChanges,
+	"ListCategoriesByName":            listCategoriesByName,
+	"ListDeadJobs":                    listDeadJobs,
+	"ListGroupMembers":                listGroupMembers,
+	"ListGroupTags":                   listGroupTags,
+	"ListGroupsByTag":                 listGroupsByTag,
+	"ListGroupsByTitle":               listGroupsByTitle,
+	"ListGroupsWithAllTags":           listGroupsWithAllTags,
+	"ListNationalIDCiphertexts":       listNationalIDCiphertexts,
+	"ListUsersByCreatedAt":            listUsersByCreatedAt,
+	"ListUsersByFirstName":            listUsersByFirstName,
+	"ListUsersByIDs":                  listUsersByIDs,
+	"ListUsersByStatus":               listUsersByStatus,
+	"ListUsersMatchingUsername":       listUsersMatchingUsername,
+	"ListUsersWithGroups":             listUsersWithGroups,
+	"MarkOutboxEventDelivered":        markOutboxEventDelivered,
+	"PruneAuditLog":                   pruneAuditLog,
+	"PurgeDeletedUsers":               purgeDeletedUsers,
+	"PutAttachment":                   putAttachment,
+	"ReadAttachmentChunk":             readAttachmentChunk,
+	"RenameCategory":                  renameCategory,
+	"ReplaceNationalIDCiphertext":     replaceNationalIDCiphertext,
+	"RequeueDeadJob":                  requeueDeadJob,
+	"RestoreUser":                     restoreUser,
+	"RetryJob":                        retryJob,
+	"SampleUsers":                     sampleUsers,
+	"SampleUsersSeeded":               sampleUsersSeeded,
+	"SearchGroups":                    searchGroups,
+	"SearchUsers":                     searchUsers,
+	"SetCategoryParent":               setCategoryParent,
+	"SetUserNationalID":               setUserNationalID,
+	"SoftDeleteUser":                  softDeleteUser,
+	"TouchStorageProbe":               touchStorageProbe,
+	"UpdateStatusByIDs":               updateStatusByIDs,
+	"UpdateUser":                      updateUser,
+	"UpdateUserBalanceChats":          updateUserBalanceChats,
+	"UpdateUserBalanceChatsIfVersion": updateUserBalanceChatsIfVersion,
+	"UpdateUserGroupBalance":          updateUserGroupBalance,
+	"UpdateUserIfVersion":             updateUserIfVersion,
+	"UpsertGroup":                     upsertGroup,
+	"UpsertTag":                       upsertTag,
+	"UpsertUser":                      upsertUser,
+	"UpsertUserByEmail":               upsertUserByEmail,
+	"UpsertUserGroupBalance":          upsertUserGroupBalance,
+}
@@ -0,0 +1,70 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// TestQuerierMatchesGenerated fails when sqlc generates a query Querier
+// doesn't list, which the assertion in querier.go can't catch. Run with
+// -tags postgres too, to check the other dialect.
+func TestQuerierMatchesGenerated(t *testing.T) {
+	generated := reflect.TypeOf((*database.Queries)(nil))
+	querier := reflect.TypeOf((*database.Querier)(nil)).Elem()
+	for i := range generated.NumMethod() {
+		if name := generated.Method(i).Name; !hasMethod(querier, name) {
+			t.Errorf("%s is generated but missing from Querier", name)
+		}
+	}
+	for i := range querier.NumMethod() {
+		if name := querier.Method(i).Name; !hasMethod(generated, name) {
+			t.Errorf("%s is in Querier but not generated", name)
+		}
+	}
+}
+
+func hasMethod(typ reflect.Type, name string) bool {
+	_, ok := typ.MethodByName(name)
+	return ok
+}
+
+func TestTxQueriesTranslated(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	newUsers(t, db, 1)
+
+	err := db.InTx(ctx, func(tx *database.Tx) error {
+		if _, err := tx.GetUserByTelegramID(ctx, 2); !errors.Is(err, database.ErrNotFound) {
+			t.Errorf("a missing user in a transaction: %v, want ErrNotFound", err)
+		}
+		_, err := tx.CreateUser(ctx, database.CreateUserParams{TelegramID: 1, FirstName: "again"})
+		if !errors.Is(err, database.ErrDuplicate) {
+			t.Errorf("a duplicate in a transaction: %v, want ErrDuplicate", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// db.Q.WithTx on a transaction begun by hand, as before Querier
+	sqlTx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlTx.Rollback()
+	if _, err := db.Q.WithTx(sqlTx).CreateUser(ctx, database.CreateUserParams{TelegramID: 2, FirstName: "user"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Q.GetUserByTelegramID(ctx, 2); err != nil {
+		t.Errorf("the user created through WithTx: %v", err)
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !postgres
+
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
 //   sqlc v1.30.0
@@ -8,25 +10,254 @@ package database
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
 )
 
-const addToUserGroupBalance = `-- name: AddToUserGroupBalance :exec
+const addToUserGroupBalance = `-- name: AddToUserGroupBalance :one
 UPDATE user_group 
 SET balance = balance + ? 
 WHERE user_telegram_id = ? AND group_telegram_id = ?
+RETURNING id, user_telegram_id, group_telegram_id, balance
 `
 
 type AddToUserGroupBalanceParams struct {
-	Balance         sql.NullFloat64 `json:"balance"`
+	Balance         sql.Null[Money] `json:"balance"`
 	UserTelegramID  int64           `json:"user_telegram_id"`
 	GroupTelegramID int64           `json:"group_telegram_id"`
 }
 
-func (q *Queries) AddToUserGroupBalance(ctx context.Context, arg AddToUserGroupBalanceParams) error {
-	_, err := q.db.ExecContext(ctx, addToUserGroupBalance, arg.Balance, arg.UserTelegramID, arg.GroupTelegramID)
+func (q *Queries) AddToUserGroupBalance(ctx context.Context, arg AddToUserGroupBalanceParams) (UserGroup, error) {
+	row := q.db.QueryRowContext(ctx, addToUserGroupBalance, arg.Balance, arg.UserTelegramID, arg.GroupTelegramID)
+	var i UserGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserTelegramID,
+		&i.GroupTelegramID,
+		&i.Balance,
+	)
+	return i, err
+}
+
+const attachTag = `-- name: AttachTag :exec
+INSERT INTO group_tags (group_telegram_id, tag_id)
+VALUES (?, ?)
+ON CONFLICT DO NOTHING
+`
+
+type AttachTagParams struct {
+	GroupTelegramID int64 `json:"group_telegram_id"`
+	TagID           int64 `json:"tag_id"`
+}
+
+func (q *Queries) AttachTag(ctx context.Context, arg AttachTagParams) error {
+	_, err := q.db.ExecContext(ctx, attachTag, arg.GroupTelegramID, arg.TagID)
+	return err
+}
+
+const buryJob = `-- name: BuryJob :exec
+UPDATE jobs
+SET status = 'dead', last_error = ?
+WHERE id = ? AND attempts = ?
+`
+
+type BuryJobParams struct {
+	LastError sql.Null[string] `json:"last_error"`
+	ID        int64            `json:"id"`
+	Attempts  int64            `json:"attempts"`
+}
+
+// Moves the job to the dead letters, out of attempts or failed for good
+func (q *Queries) BuryJob(ctx context.Context, arg BuryJobParams) error {
+	_, err := q.db.ExecContext(ctx, buryJob, arg.LastError, arg.ID, arg.Attempts)
+	return err
+}
+
+const claimJobs = `-- name: ClaimJobs :many
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, run_at = ?
+WHERE id IN (
+    SELECT id FROM jobs
+    WHERE status <> 'dead'
+      AND run_at <= ?
+      AND type IN (/*SLICE:types*/?)
+    ORDER BY run_at, id
+    LIMIT ?
+)
+RETURNING id, type, payload, status, attempts, max_attempts, last_error, run_at, created_at
+`
+
+type ClaimJobsParams struct {
+	VisibleAt time.Time `json:"visible_at"`
+	Now       time.Time `json:"now"`
+	Types     []string  `json:"types"`
+	Limit     int64     `json:"limit"`
+}
+
+// Claims up to LIMIT due jobs of the given types, oldest first: marks
+// them running, counts the attempt and hides them until visible_at. A
+// running job whose visible_at has passed is claimed again.
+func (q *Queries) ClaimJobs(ctx context.Context, arg ClaimJobsParams) ([]Job, error) {
+	query := claimJobs
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.VisibleAt)
+	queryParams = append(queryParams, arg.Now)
+	if len(arg.Types) > 0 {
+		for _, v := range arg.Types {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:types*/?", strings.Repeat(",?", len(arg.Types))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:types*/?", "NULL", 1)
+	}
+	queryParams = append(queryParams, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Job{}
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.MaxAttempts,
+			&i.LastError,
+			&i.RunAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimOutboxEvents = `-- name: ClaimOutboxEvents :many
+UPDATE outbox
+SET available_at = ?
+WHERE id IN (
+    SELECT o.id FROM outbox o
+    WHERE o.delivered_at IS NULL
+      AND o.available_at <= CURRENT_TIMESTAMP
+      AND NOT EXISTS (
+          SELECT 1 FROM outbox e
+          WHERE e.topic = o.topic
+            AND e.delivered_at IS NULL
+            AND e.id < o.id
+            AND e.available_at > CURRENT_TIMESTAMP
+      )
+    ORDER BY o.id
+    LIMIT ?
+)
+RETURNING id, topic, payload, attempts, last_error, available_at, created_at, delivered_at
+`
+
+type ClaimOutboxEventsParams struct {
+	AvailableAt time.Time `json:"available_at"`
+	Limit       int64     `json:"limit"`
+}
+
+// Claims up to LIMIT pending events by pushing available_at forward (a lease).
+// An event is skipped while an earlier event of the same topic is leased or backing off.
+func (q *Queries) ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error) {
+	rows, err := q.db.QueryContext(ctx, claimOutboxEvents, arg.AvailableAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Outbox{}
+	for rows.Next() {
+		var i Outbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.Topic,
+			&i.Payload,
+			&i.Attempts,
+			&i.LastError,
+			&i.AvailableAt,
+			&i.CreatedAt,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeJob = `-- name: CompleteJob :exec
+DELETE FROM jobs
+WHERE id = ? AND attempts = ?
+`
+
+type CompleteJobParams struct {
+	ID       int64 `json:"id"`
+	Attempts int64 `json:"attempts"`
+}
+
+// The job succeeded. The attempts check leaves a job alone that was
+// claimed again after its visibility timeout, as do RetryJob and BuryJob.
+func (q *Queries) CompleteJob(ctx context.Context, arg CompleteJobParams) error {
+	_, err := q.db.ExecContext(ctx, completeJob, arg.ID, arg.Attempts)
 	return err
 }
 
+const countUsersByStatus = `-- name: CountUsersByStatus :one
+SELECT COUNT(*) FROM users
+WHERE status = ? AND deleted_at IS NULL
+`
+
+func (q *Queries) CountUsersByStatus(ctx context.Context, status Status) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsersByStatus, status)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createCategory = `-- name: CreateCategory :one
+
+INSERT INTO categories (parent_id, name)
+VALUES (?, ?)
+RETURNING id, parent_id, name, name_normalized
+`
+
+type CreateCategoryParams struct {
+	ParentID sql.Null[int64] `json:"parent_id"`
+	Name     string          `json:"name"`
+}
+
+// =====================
+// CATEGORY QUERIES
+// =====================
+func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error) {
+	row := q.db.QueryRowContext(ctx, createCategory, arg.ParentID, arg.Name)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.ParentID,
+		&i.Name,
+		&i.NameNormalized,
+	)
+	return i, err
+}
+
 const createGroup = `-- name: CreateGroup :one
 INSERT INTO groups (telegram_id, title)
 VALUES (?, ?)
@@ -34,8 +265,8 @@ RETURNING id, balance, telegram_id, title, url, created_at, updated_at
 `
 
 type CreateGroupParams struct {
-	TelegramID int64          `json:"telegram_id"`
-	Title      sql.NullString `json:"title"`
+	TelegramID int64            `json:"telegram_id"`
+	Title      sql.Null[string] `json:"title"`
 }
 
 func (q *Queries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error) {
@@ -53,20 +284,49 @@ func (q *Queries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group
 	return i, err
 }
 
+const createGroupIfMissing = `-- name: CreateGroupIfMissing :one
+INSERT INTO groups (telegram_id, title)
+VALUES (?, ?)
+ON CONFLICT (telegram_id) DO NOTHING
+RETURNING id, balance, telegram_id, title, url, created_at, updated_at
+`
+
+type CreateGroupIfMissingParams struct {
+	TelegramID int64            `json:"telegram_id"`
+	Title      sql.Null[string] `json:"title"`
+}
+
+// No row when a group with that telegram_id exists; GetOrCreateGroup then reads it
+func (q *Queries) CreateGroupIfMissing(ctx context.Context, arg CreateGroupIfMissingParams) (Group, error) {
+	row := q.db.QueryRowContext(ctx, createGroupIfMissing, arg.TelegramID, arg.Title)
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.Balance,
+		&i.TelegramID,
+		&i.Title,
+		&i.Url,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (
-    telegram_id, first_name, username, status, language, refer_from_id
-) VALUES (?, ?, ?, ?, ?, ?)
-RETURNING id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at
+    telegram_id, first_name, username, status, language, refer_from_id, email
+) VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version
 `
 
 type CreateUserParams struct {
-	TelegramID  int64          `json:"telegram_id"`
-	FirstName   string         `json:"first_name"`
-	Username    sql.NullString `json:"username"`
-	Status      string         `json:"status"`
-	Language    string         `json:"language"`
-	ReferFromID sql.NullInt64  `json:"refer_from_id"`
+	TelegramID  int64            `json:"telegram_id"`
+	FirstName   string           `json:"first_name"`
+	Username    sql.Null[string] `json:"username"`
+	Status      Status           `json:"status"`
+	Language    string           `json:"language"`
+	ReferFromID sql.Null[int64]  `json:"refer_from_id"`
+	Email       sql.Null[string] `json:"email"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -77,6 +337,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.Status,
 		arg.Language,
 		arg.ReferFromID,
+		arg.Email,
 	)
 	var i User
 	err := row.Scan(
@@ -92,6 +353,9 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.LastStreakClaimAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -119,16 +383,72 @@ func (q *Queries) CreateUserGroup(ctx context.Context, arg CreateUserGroupParams
 	return i, err
 }
 
-const getGroupByTelegramID = `-- name: GetGroupByTelegramID :one
+const createUserIfMissing = `-- name: CreateUserIfMissing :one
+INSERT INTO users (
+    telegram_id, first_name, username, status, language, refer_from_id, email
+) VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (telegram_id) DO NOTHING
+RETURNING id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version
+`
 
-SELECT id, balance, telegram_id, title, url, created_at, updated_at FROM groups WHERE telegram_id = ? LIMIT 1
+type CreateUserIfMissingParams struct {
+	TelegramID  int64            `json:"telegram_id"`
+	FirstName   string           `json:"first_name"`
+	Username    sql.Null[string] `json:"username"`
+	Status      Status           `json:"status"`
+	Language    string           `json:"language"`
+	ReferFromID sql.Null[int64]  `json:"refer_from_id"`
+	Email       sql.Null[string] `json:"email"`
+}
+
+// No row when a user with that telegram_id exists; GetOrCreateUser then reads it
+func (q *Queries) CreateUserIfMissing(ctx context.Context, arg CreateUserIfMissingParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, createUserIfMissing,
+		arg.TelegramID,
+		arg.FirstName,
+		arg.Username,
+		arg.Status,
+		arg.Language,
+		arg.ReferFromID,
+		arg.Email,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.TelegramID,
+		&i.FirstName,
+		&i.Username,
+		&i.BalanceGame,
+		&i.BalanceChats,
+		&i.Status,
+		&i.Language,
+		&i.ReferFromID,
+		&i.LastStreakClaimAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const deleteAttachment = `-- name: DeleteAttachment :exec
+DELETE FROM attachments WHERE id = ?
 `
 
-// =====================
-// GROUP QUERIES
-// =====================
-func (q *Queries) GetGroupByTelegramID(ctx context.Context, telegramID int64) (Group, error) {
-	row := q.db.QueryRowContext(ctx, getGroupByTelegramID, telegramID)
+func (q *Queries) DeleteAttachment(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteAttachment, id)
+	return err
+}
+
+const deleteGroup = `-- name: DeleteGroup :one
+DELETE FROM groups WHERE telegram_id = ?
+RETURNING id, balance, telegram_id, title, url, created_at, updated_at
+`
+
+func (q *Queries) DeleteGroup(ctx context.Context, telegramID int64) (Group, error) {
+	row := q.db.QueryRowContext(ctx, deleteGroup, telegramID)
 	var i Group
 	err := row.Scan(
 		&i.ID,
@@ -142,57 +462,1856 @@ func (q *Queries) GetGroupByTelegramID(ctx context.Context, telegramID int64) (G
 	return i, err
 }
 
-const getOrCreateUserGroup = `-- name: GetOrCreateUserGroup :one
-INSERT INTO user_group (user_telegram_id, group_telegram_id, balance)
-VALUES (?, ?, 0)
-ON CONFLICT(user_telegram_id, group_telegram_id) DO UPDATE SET
-    balance = user_group.balance
-RETURNING id, user_telegram_id, group_telegram_id, balance
+const deleteJob = `-- name: DeleteJob :execrows
+DELETE FROM jobs WHERE id = ?
 `
 
-type GetOrCreateUserGroupParams struct {
-	UserTelegramID  int64 `json:"user_telegram_id"`
+func (q *Queries) DeleteJob(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteJob, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const detachTag = `-- name: DetachTag :exec
+DELETE FROM group_tags
+WHERE group_telegram_id = ? AND tag_id = ?
+`
+
+type DetachTagParams struct {
 	GroupTelegramID int64 `json:"group_telegram_id"`
+	TagID           int64 `json:"tag_id"`
 }
 
-func (q *Queries) GetOrCreateUserGroup(ctx context.Context, arg GetOrCreateUserGroupParams) (UserGroup, error) {
-	row := q.db.QueryRowContext(ctx, getOrCreateUserGroup, arg.UserTelegramID, arg.GroupTelegramID)
-	var i UserGroup
+func (q *Queries) DetachTag(ctx context.Context, arg DetachTagParams) error {
+	_, err := q.db.ExecContext(ctx, detachTag, arg.GroupTelegramID, arg.TagID)
+	return err
+}
+
+const enqueueJob = `-- name: EnqueueJob :one
+INSERT INTO jobs (type, payload, max_attempts, run_at)
+VALUES (?, ?, ?, ?)
+RETURNING id, type, payload, status, attempts, max_attempts, last_error, run_at, created_at
+`
+
+type EnqueueJobParams struct {
+	Type        string    `json:"type"`
+	Payload     []byte    `json:"payload"`
+	MaxAttempts int64     `json:"max_attempts"`
+	RunAt       time.Time `json:"run_at"`
+}
+
+func (q *Queries) EnqueueJob(ctx context.Context, arg EnqueueJobParams) (Job, error) {
+	row := q.db.QueryRowContext(ctx, enqueueJob,
+		arg.Type,
+		arg.Payload,
+		arg.MaxAttempts,
+		arg.RunAt,
+	)
+	var i Job
 	err := row.Scan(
 		&i.ID,
-		&i.UserTelegramID,
-		&i.GroupTelegramID,
-		&i.Balance,
+		&i.Type,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.LastError,
+		&i.RunAt,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getTopGroupsForUser = `-- name: GetTopGroupsForUser :many
-SELECT 
-    g.title,
-    ug.balance
-FROM user_group ug
-LEFT JOIN groups g ON g.telegram_id = ug.group_telegram_id
-WHERE ug.user_telegram_id = ?
-ORDER BY ug.balance DESC
-LIMIT 10
+const failOutboxEvent = `-- name: FailOutboxEvent :exec
+UPDATE outbox
+SET attempts = attempts + 1, last_error = ?, available_at = ?
+WHERE id = ?
 `
 
-type GetTopGroupsForUserRow struct {
-	Title   sql.NullString  `json:"title"`
-	Balance sql.NullFloat64 `json:"balance"`
+type FailOutboxEventParams struct {
+	LastError   sql.Null[string] `json:"last_error"`
+	AvailableAt time.Time        `json:"available_at"`
+	ID          int64            `json:"id"`
 }
 
-func (q *Queries) GetTopGroupsForUser(ctx context.Context, userTelegramID int64) ([]GetTopGroupsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getTopGroupsForUser, userTelegramID)
+func (q *Queries) FailOutboxEvent(ctx context.Context, arg FailOutboxEventParams) error {
+	_, err := q.db.ExecContext(ctx, failOutboxEvent, arg.LastError, arg.AvailableAt, arg.ID)
+	return err
+}
+
+const getAncestors = `-- name: GetAncestors :many
+WITH RECURSIVE ancestors(id, parent_id, name, depth) AS (
+    SELECT c.id, c.parent_id, c.name, 0
+    FROM categories c
+    WHERE c.id = ?
+    UNION ALL
+    SELECT c.id, c.parent_id, c.name, a.depth + 1
+    FROM categories c
+    JOIN ancestors a ON c.id = a.parent_id
+    WHERE a.depth < ?
+)
+SELECT id, parent_id, name, depth FROM ancestors
+ORDER BY depth
+`
+
+type GetAncestorsParams struct {
+	ID       int64 `json:"id"`
+	MaxDepth int64 `json:"max_depth"`
+}
+
+type GetAncestorsRow struct {
+	ID       int64           `json:"id"`
+	ParentID sql.Null[int64] `json:"parent_id"`
+	Name     string          `json:"name"`
+	Depth    int64           `json:"depth"`
+}
+
+// The path from the category up to its root, depth 0 being the category itself
+func (q *Queries) GetAncestors(ctx context.Context, arg GetAncestorsParams) ([]GetAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAncestors, arg.ID, arg.MaxDepth)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []GetTopGroupsForUserRow{}
+	items := []GetAncestorsRow{}
 	for rows.Next() {
-		var i GetTopGroupsForUserRow
-		if err := rows.Scan(&i.Title, &i.Balance); err != nil {
+		var i GetAncestorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentID,
+			&i.Name,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAttachmentMeta = `-- name: GetAttachmentMeta :one
+SELECT id, sha256, content_type, size, created_at FROM attachments
+WHERE id = ?
+`
+
+type GetAttachmentMetaRow struct {
+	ID          int64               `json:"id"`
+	Sha256      []byte              `json:"sha256"`
+	ContentType string              `json:"content_type"`
+	Size        int64               `json:"size"`
+	CreatedAt   sql.Null[time.Time] `json:"created_at"`
+}
+
+func (q *Queries) GetAttachmentMeta(ctx context.Context, id int64) (GetAttachmentMetaRow, error) {
+	row := q.db.QueryRowContext(ctx, getAttachmentMeta, id)
+	var i GetAttachmentMetaRow
+	err := row.Scan(
+		&i.ID,
+		&i.Sha256,
+		&i.ContentType,
+		&i.Size,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAuditLog = `-- name: GetAuditLog :many
+SELECT id, table_name, row_id, operation, actor, old_data, new_data, changed_at FROM audit_log
+WHERE table_name = ? AND row_id = ?
+ORDER BY id
+`
+
+type GetAuditLogParams struct {
+	TableName string `json:"table_name"`
+	RowID     int64  `json:"row_id"`
+}
+
+// Every recorded change to one row, oldest first; table_name is users,
+// groups or user_group
+func (q *Queries) GetAuditLog(ctx context.Context, arg GetAuditLogParams) ([]AuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, getAuditLog, arg.TableName, arg.RowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditLog{}
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.TableName,
+			&i.RowID,
+			&i.Operation,
+			&i.Actor,
+			&i.OldData,
+			&i.NewData,
+			&i.ChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChildCategoryByName = `-- name: GetChildCategoryByName :one
+SELECT id, parent_id, name, name_normalized FROM categories
+WHERE parent_id = ? AND name_normalized = lower(trim(?))
+LIMIT 1
+`
+
+type GetChildCategoryByNameParams struct {
+	ParentID sql.Null[int64] `json:"parent_id"`
+	Name     string          `json:"name"`
+}
+
+func (q *Queries) GetChildCategoryByName(ctx context.Context, arg GetChildCategoryByNameParams) (Category, error) {
+	row := q.db.QueryRowContext(ctx, getChildCategoryByName, arg.ParentID, arg.Name)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.ParentID,
+		&i.Name,
+		&i.NameNormalized,
+	)
+	return i, err
+}
+
+const getDescendants = `-- name: GetDescendants :many
+WITH RECURSIVE subtree(id, parent_id, name, depth) AS (
+    SELECT c.id, c.parent_id, c.name, 0
+    FROM categories c
+    WHERE c.id = ?
+    UNION ALL
+    SELECT c.id, c.parent_id, c.name, s.depth + 1
+    FROM categories c
+    JOIN subtree s ON c.parent_id = s.id
+    WHERE s.depth < ?
+)
+SELECT id, parent_id, name, depth FROM subtree
+ORDER BY depth, id
+`
+
+type GetDescendantsParams struct {
+	ID       int64 `json:"id"`
+	MaxDepth int64 `json:"max_depth"`
+}
+
+type GetDescendantsRow struct {
+	ID       int64           `json:"id"`
+	ParentID sql.Null[int64] `json:"parent_id"`
+	Name     string          `json:"name"`
+	Depth    int64           `json:"depth"`
+}
+
+// The category and everything below it, depth 0 being the category itself.
+// max_depth stops the recursion if the data ever contains a cycle.
+func (q *Queries) GetDescendants(ctx context.Context, arg GetDescendantsParams) ([]GetDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDescendants, arg.ID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetDescendantsRow{}
+	for rows.Next() {
+		var i GetDescendantsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentID,
+			&i.Name,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGroupBalanceSummary = `-- name: GetGroupBalanceSummary :one
+SELECT COUNT(*) AS members,
+    COALESCE(SUM(balance), 0) AS total,
+    COALESCE(ROUND(AVG(balance)), 0) AS average,
+    COALESCE(ROUND(SUM(balance) / 100.0), 0) * 100 AS total_rounded,
+    COALESCE(MAX(balance), 0) AS largest
+FROM user_group
+WHERE group_telegram_id = ?
+`
+
+type GetGroupBalanceSummaryRow struct {
+	Members      int64       `json:"members"`
+	Total        interface{} `json:"total"`
+	Average      interface{} `json:"average"`
+	TotalRounded interface{} `json:"total_rounded"`
+	Largest      interface{} `json:"largest"`
+}
+
+// Sums and rounding that come out the same on SQLite, PostgreSQL and
+// MySQL (DB.GroupBalanceSummary reads them as Money). The balances are
+// whole cents, so SUM is exact on all three, and ROUND takes exact halves
+// away from zero on all three; / 100.0 keeps them exact, as a double in
+// SQLite and a NUMERIC or DECIMAL elsewhere.
+func (q *Queries) GetGroupBalanceSummary(ctx context.Context, groupTelegramID int64) (GetGroupBalanceSummaryRow, error) {
+	row := q.db.QueryRowContext(ctx, getGroupBalanceSummary, groupTelegramID)
+	var i GetGroupBalanceSummaryRow
+	err := row.Scan(
+		&i.Members,
+		&i.Total,
+		&i.Average,
+		&i.TotalRounded,
+		&i.Largest,
+	)
+	return i, err
+}
+
+const getGroupByTelegramID = `-- name: GetGroupByTelegramID :one
+
+SELECT id, balance, telegram_id, title, url, created_at, updated_at FROM groups WHERE telegram_id = ? LIMIT 1
+`
+
+// =====================
+// GROUP QUERIES
+// =====================
+func (q *Queries) GetGroupByTelegramID(ctx context.Context, telegramID int64) (Group, error) {
+	row := q.db.QueryRowContext(ctx, getGroupByTelegramID, telegramID)
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.Balance,
+		&i.TelegramID,
+		&i.Title,
+		&i.Url,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getGroupHistory = `-- name: GetGroupHistory :many
+SELECT history_id, operation, changed_at, id, balance, telegram_id, title, url, created_at, updated_at FROM group_history
+WHERE id = ?
+ORDER BY history_id
+`
+
+func (q *Queries) GetGroupHistory(ctx context.Context, id int64) ([]GroupHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getGroupHistory, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GroupHistory{}
+	for rows.Next() {
+		var i GroupHistory
+		if err := rows.Scan(
+			&i.HistoryID,
+			&i.Operation,
+			&i.ChangedAt,
+			&i.ID,
+			&i.Balance,
+			&i.TelegramID,
+			&i.Title,
+			&i.Url,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOrCreateUserGroup = `-- name: GetOrCreateUserGroup :one
+INSERT INTO user_group (user_telegram_id, group_telegram_id, balance)
+VALUES (?, ?, 0)
+ON CONFLICT(user_telegram_id, group_telegram_id) DO UPDATE SET
+    balance = user_group.balance
+RETURNING id, user_telegram_id, group_telegram_id, balance
+`
+
+type GetOrCreateUserGroupParams struct {
+	UserTelegramID  int64 `json:"user_telegram_id"`
+	GroupTelegramID int64 `json:"group_telegram_id"`
+}
+
+func (q *Queries) GetOrCreateUserGroup(ctx context.Context, arg GetOrCreateUserGroupParams) (UserGroup, error) {
+	row := q.db.QueryRowContext(ctx, getOrCreateUserGroup, arg.UserTelegramID, arg.GroupTelegramID)
+	var i UserGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserTelegramID,
+		&i.GroupTelegramID,
+		&i.Balance,
+	)
+	return i, err
+}
+
+const getTopGroupsForUser = `-- name: GetTopGroupsForUser :many
+SELECT 
+    g.title,
+    ug.balance
+FROM user_group ug
+LEFT JOIN groups g ON g.telegram_id = ug.group_telegram_id
+WHERE ug.user_telegram_id = ?
+ORDER BY ug.balance DESC
+LIMIT 10
+`
+
+type GetTopGroupsForUserRow struct {
+	Title   sql.Null[string] `json:"title"`
+	Balance sql.Null[Money]  `json:"balance"`
+}
+
+func (q *Queries) GetTopGroupsForUser(ctx context.Context, userTelegramID int64) ([]GetTopGroupsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTopGroupsForUser, userTelegramID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetTopGroupsForUserRow{}
+	for rows.Next() {
+		var i GetTopGroupsForUserRow
+		if err := rows.Scan(&i.Title, &i.Balance); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTopUsersByBalance = `-- name: GetTopUsersByBalance :many
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users 
+WHERE deleted_at IS NULL
+ORDER BY (balance_game + balance_chats) DESC 
+LIMIT ?
+`
+
+func (q *Queries) GetTopUsersByBalance(ctx context.Context, limit int64) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getTopUsersByBalance, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.TelegramID,
+			&i.FirstName,
+			&i.Username,
+			&i.BalanceGame,
+			&i.BalanceChats,
+			&i.Status,
+			&i.Language,
+			&i.ReferFromID,
+			&i.LastStreakClaimAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.DeletedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTopUsersInGroup = `-- name: GetTopUsersInGroup :many
+
+SELECT 
+    u.first_name,
+    u.username,
+    ug.balance
+FROM user_group ug
+LEFT JOIN users u ON u.telegram_id = ug.user_telegram_id
+WHERE ug.group_telegram_id = ? AND u.deleted_at IS NULL
+ORDER BY ug.balance DESC
+LIMIT 10
+`
+
+type GetTopUsersInGroupRow struct {
+	FirstName sql.Null[string] `json:"first_name"`
+	Username  sql.Null[string] `json:"username"`
+	Balance   sql.Null[Money]  `json:"balance"`
+}
+
+// =====================
+// STATS QUERIES
+// =====================
+func (q *Queries) GetTopUsersInGroup(ctx context.Context, groupTelegramID int64) ([]GetTopUsersInGroupRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTopUsersInGroup, groupTelegramID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetTopUsersInGroupRow{}
+	for rows.Next() {
+		var i GetTopUsersInGroupRow
+		if err := rows.Scan(&i.FirstName, &i.Username, &i.Balance); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTotalGroupBalance = `-- name: GetTotalGroupBalance :one
+SELECT COALESCE(SUM(balance), 0) AS total FROM user_group 
+WHERE group_telegram_id = ?
+`
+
+func (q *Queries) GetTotalGroupBalance(ctx context.Context, groupTelegramID int64) (interface{}, error) {
+	row := q.db.QueryRowContext(ctx, getTotalGroupBalance, groupTelegramID)
+	var total interface{}
+	err := row.Scan(&total)
+	return total, err
+}
+
+const getTotalUserBalance = `-- name: GetTotalUserBalance :one
+SELECT COALESCE(SUM(balance), 0) AS total FROM user_group 
+WHERE user_telegram_id = ?
+`
+
+func (q *Queries) GetTotalUserBalance(ctx context.Context, userTelegramID int64) (interface{}, error) {
+	row := q.db.QueryRowContext(ctx, getTotalUserBalance, userTelegramID)
+	var total interface{}
+	err := row.Scan(&total)
+	return total, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users WHERE lower(email) = lower(?) AND deleted_at IS NULL LIMIT 1
+`
+
+// Matches any casing; the lower(email) index makes this an index lookup
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.TelegramID,
+		&i.FirstName,
+		&i.Username,
+		&i.BalanceGame,
+		&i.BalanceChats,
+		&i.Status,
+		&i.Language,
+		&i.ReferFromID,
+		&i.LastStreakClaimAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users WHERE id = ? AND deleted_at IS NULL LIMIT 1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.TelegramID,
+		&i.FirstName,
+		&i.Username,
+		&i.BalanceGame,
+		&i.BalanceChats,
+		&i.Status,
+		&i.Language,
+		&i.ReferFromID,
+		&i.LastStreakClaimAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getUserByNationalIDIndex = `-- name: GetUserByNationalIDIndex :one
+SELECT u.id, u.telegram_id, u.first_name, u.username, u.balance_game, u.balance_chats, u.status, u.language, u.refer_from_id, u.last_streak_claim_at, u.created_at, u.updated_at, u.email, u.deleted_at, u.version FROM users u
+JOIN user_national_ids n ON n.user_telegram_id = u.telegram_id
+WHERE n.national_id_index = ? AND u.deleted_at IS NULL
+`
+
+// Looks the user up by BlindIndex of the national ID
+func (q *Queries) GetUserByNationalIDIndex(ctx context.Context, nationalIDIndex []byte) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByNationalIDIndex, nationalIDIndex)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.TelegramID,
+		&i.FirstName,
+		&i.Username,
+		&i.BalanceGame,
+		&i.BalanceChats,
+		&i.Status,
+		&i.Language,
+		&i.ReferFromID,
+		&i.LastStreakClaimAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getUserByTelegramID = `-- name: GetUserByTelegramID :one
+
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users WHERE telegram_id = ? AND deleted_at IS NULL LIMIT 1
+`
+
+// =====================
+// USER QUERIES
+// =====================
+func (q *Queries) GetUserByTelegramID(ctx context.Context, telegramID int64) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByTelegramID, telegramID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.TelegramID,
+		&i.FirstName,
+		&i.Username,
+		&i.BalanceGame,
+		&i.BalanceChats,
+		&i.Status,
+		&i.Language,
+		&i.ReferFromID,
+		&i.LastStreakClaimAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getUserGroup = `-- name: GetUserGroup :one
+
+SELECT id, user_telegram_id, group_telegram_id, balance FROM user_group 
+WHERE user_telegram_id = ? AND group_telegram_id = ? 
+LIMIT 1
+`
+
+type GetUserGroupParams struct {
+	UserTelegramID  int64 `json:"user_telegram_id"`
+	GroupTelegramID int64 `json:"group_telegram_id"`
+}
+
+// =====================
+// USER-GROUP QUERIES
+// =====================
+func (q *Queries) GetUserGroup(ctx context.Context, arg GetUserGroupParams) (UserGroup, error) {
+	row := q.db.QueryRowContext(ctx, getUserGroup, arg.UserTelegramID, arg.GroupTelegramID)
+	var i UserGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserTelegramID,
+		&i.GroupTelegramID,
+		&i.Balance,
+	)
+	return i, err
+}
+
+const getUserHistory = `-- name: GetUserHistory :many
+SELECT history_id, operation, changed_at, id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM user_history
+WHERE id = ?
+ORDER BY history_id
+`
+
+// Every change to the user, oldest first (rows are written by triggers)
+func (q *Queries) GetUserHistory(ctx context.Context, id int64) ([]UserHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getUserHistory, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UserHistory{}
+	for rows.Next() {
+		var i UserHistory
+		if err := rows.Scan(
+			&i.HistoryID,
+			&i.Operation,
+			&i.ChangedAt,
+			&i.ID,
+			&i.TelegramID,
+			&i.FirstName,
+			&i.Username,
+			&i.BalanceGame,
+			&i.BalanceChats,
+			&i.Status,
+			&i.Language,
+			&i.ReferFromID,
+			&i.LastStreakClaimAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.DeletedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserIDRange = `-- name: GetUserIDRange :one
+SELECT CAST(COALESCE(MIN(id), 0) AS INTEGER) AS min_id, CAST(COALESCE(MAX(id), 0) AS INTEGER) AS max_id
+FROM users
+`
+
+type GetUserIDRangeRow struct {
+	MinID int64 `json:"min_id"`
+	MaxID int64 `json:"max_id"`
+}
+
+func (q *Queries) GetUserIDRange(ctx context.Context) (GetUserIDRangeRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserIDRange)
+	var i GetUserIDRangeRow
+	err := row.Scan(&i.MinID, &i.MaxID)
+	return i, err
+}
+
+const getUserNationalID = `-- name: GetUserNationalID :one
+SELECT national_id FROM user_national_ids
+WHERE user_telegram_id = ?
+`
+
+func (q *Queries) GetUserNationalID(ctx context.Context, userTelegramID int64) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getUserNationalID, userTelegramID)
+	var national_id []byte
+	err := row.Scan(&national_id)
+	return national_id, err
+}
+
+const getUserPosition = `-- name: GetUserPosition :one
+SELECT COUNT(*) + 1 AS position FROM users 
+WHERE (balance_game + balance_chats) > ? AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserPosition(ctx context.Context, balanceGame sql.Null[Money]) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getUserPosition, balanceGame)
+	var position int64
+	err := row.Scan(&position)
+	return position, err
+}
+
+const insertOutboxEvent = `-- name: InsertOutboxEvent :one
+
+INSERT INTO outbox (topic, payload)
+VALUES (?, ?)
+RETURNING id, topic, payload, attempts, last_error, available_at, created_at, delivered_at
+`
+
+type InsertOutboxEventParams struct {
+	Topic   string `json:"topic"`
+	Payload []byte `json:"payload"`
+}
+
+// =====================
+// OUTBOX QUERIES
+// =====================
+func (q *Queries) InsertOutboxEvent(ctx context.Context, arg InsertOutboxEventParams) (Outbox, error) {
+	row := q.db.QueryRowContext(ctx, insertOutboxEvent, arg.Topic, arg.Payload)
+	var i Outbox
+	err := row.Scan(
+		&i.ID,
+		&i.Topic,
+		&i.Payload,
+		&i.Attempts,
+		&i.LastError,
+		&i.AvailableAt,
+		&i.CreatedAt,
+		&i.DeliveredAt,
+	)
+	return i, err
+}
+
+const listAuditLogByActor = `-- name: ListAuditLogByActor :many
+SELECT id, table_name, row_id, operation, actor, old_data, new_data, changed_at FROM audit_log
+WHERE actor = CAST(? AS TEXT)
+ORDER BY id DESC
+LIMIT ?
+`
+
+type ListAuditLogByActorParams struct {
+	Actor string `json:"actor"`
+	Limit int64  `json:"limit"`
+}
+
+// What one actor changed, newest first
+func (q *Queries) ListAuditLogByActor(ctx context.Context, arg ListAuditLogByActorParams) ([]AuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, listAuditLogByActor, arg.Actor, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditLog{}
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.TableName,
+			&i.RowID,
+			&i.Operation,
+			&i.Actor,
+			&i.OldData,
+			&i.NewData,
+			&i.ChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAuditLogFieldChanges = `-- name: ListAuditLogFieldChanges :many
+SELECT audit_log.id, audit_log.table_name, audit_log.row_id, audit_log.operation, audit_log.actor, audit_log.old_data, audit_log.new_data, audit_log.changed_at FROM audit_log, (SELECT '$.' || CAST(? AS TEXT) AS path) AS p
+WHERE table_name = ? AND operation = 'UPDATE'
+    AND json_extract(old_data, p.path) IS NOT json_extract(new_data, p.path)
+ORDER BY id DESC
+LIMIT ?
+`
+
+type ListAuditLogFieldChangesParams struct {
+	Field     string `json:"field"`
+	TableName string `json:"table_name"`
+	Limit     int64  `json:"limit"`
+}
+
+// The updates of table_name that changed one column, newest first:
+// field is the column's name, a key of the JSON the triggers write. The
+// path is built once, in p, so field is one parameter.
+func (q *Queries) ListAuditLogFieldChanges(ctx context.Context, arg ListAuditLogFieldChangesParams) ([]AuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, listAuditLogFieldChanges, arg.Field, arg.TableName, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditLog{}
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.TableName,
+			&i.RowID,
+			&i.Operation,
+			&i.Actor,
+			&i.OldData,
+			&i.NewData,
+			&i.ChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCategoriesByName = `-- name: ListCategoriesByName :many
+SELECT id, parent_id, name, name_normalized FROM categories
+WHERE name_normalized = lower(trim(?))
+ORDER BY id
+`
+
+// name matches after trimming and lowercasing, like name_normalized
+func (q *Queries) ListCategoriesByName(ctx context.Context, name string) ([]Category, error) {
+	rows, err := q.db.QueryContext(ctx, listCategoriesByName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Category{}
+	for rows.Next() {
+		var i Category
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentID,
+			&i.Name,
+			&i.NameNormalized,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeadJobs = `-- name: ListDeadJobs :many
+SELECT id, type, payload, status, attempts, max_attempts, last_error, run_at, created_at FROM jobs
+WHERE status = 'dead'
+ORDER BY id
+LIMIT ?
+`
+
+func (q *Queries) ListDeadJobs(ctx context.Context, limit int64) ([]Job, error) {
+	rows, err := q.db.QueryContext(ctx, listDeadJobs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Job{}
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.MaxAttempts,
+			&i.LastError,
+			&i.RunAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupMembers = `-- name: ListGroupMembers :many
+SELECT ug.id, ug.user_telegram_id, ug.group_telegram_id, ug.balance, u.first_name, u.username
+FROM user_group ug
+JOIN users u ON u.telegram_id = ug.user_telegram_id
+WHERE ug.group_telegram_id = ? AND u.deleted_at IS NULL
+ORDER BY ug.balance DESC, ug.id
+`
+
+type ListGroupMembersRow struct {
+	ID              int64            `json:"id"`
+	UserTelegramID  int64            `json:"user_telegram_id"`
+	GroupTelegramID int64            `json:"group_telegram_id"`
+	Balance         sql.Null[Money]  `json:"balance"`
+	FirstName       string           `json:"first_name"`
+	Username        sql.Null[string] `json:"username"`
+}
+
+// =====================
+// RELATION QUERIES
+// =====================
+// Members of a group with their names, in one query instead of N+1
+func (q *Queries) ListGroupMembers(ctx context.Context, groupTelegramID int64) ([]ListGroupMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listGroupMembers, groupTelegramID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListGroupMembersRow{}
+	for rows.Next() {
+		var i ListGroupMembersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserTelegramID,
+			&i.GroupTelegramID,
+			&i.Balance,
+			&i.FirstName,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupsByTag = `-- name: ListGroupsByTag :many
+SELECT g.id, g.balance, g.telegram_id, g.title, g.url, g.created_at, g.updated_at FROM groups g
+JOIN group_tags gt ON gt.group_telegram_id = g.telegram_id
+JOIN tags t ON t.id = gt.tag_id
+WHERE t.name = ? AND g.id > ?
+ORDER BY g.id
+LIMIT ?
+`
+
+type ListGroupsByTagParams struct {
+	Tag      string `json:"tag"`
+	AfterID  int64  `json:"after_id"`
+	PageSize int64  `json:"page_size"`
+}
+
+// Keyset pagination: pass the last seen group id as after_id (0 for the first page)
+func (q *Queries) ListGroupsByTag(ctx context.Context, arg ListGroupsByTagParams) ([]Group, error) {
+	rows, err := q.db.QueryContext(ctx, listGroupsByTag, arg.Tag, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Group{}
+	for rows.Next() {
+		var i Group
+		if err := rows.Scan(
+			&i.ID,
+			&i.Balance,
+			&i.TelegramID,
+			&i.Title,
+			&i.Url,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupsByTitle = `-- name: ListGroupsByTitle :many
+SELECT id, balance, telegram_id, title, url, created_at, updated_at FROM groups
+ORDER BY title COLLATE NOCASE_UNICODE, id
+LIMIT ?
+`
+
+// Alphabetical by title in any language and casing (idx_groups_title)
+func (q *Queries) ListGroupsByTitle(ctx context.Context, limit int64) ([]Group, error) {
+	rows, err := q.db.QueryContext(ctx, listGroupsByTitle, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Group{}
+	for rows.Next() {
+		var i Group
+		if err := rows.Scan(
+			&i.ID,
+			&i.Balance,
+			&i.TelegramID,
+			&i.Title,
+			&i.Url,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupsWithAllTags = `-- name: ListGroupsWithAllTags :many
+SELECT g.id, g.balance, g.telegram_id, g.title, g.url, g.created_at, g.updated_at FROM groups g
+JOIN group_tags gt ON gt.group_telegram_id = g.telegram_id
+JOIN tags t ON t.id = gt.tag_id
+WHERE t.name IN (/*SLICE:tags*/?) AND g.id > ?
+GROUP BY g.id
+HAVING COUNT(DISTINCT t.id) = ?
+ORDER BY g.id
+LIMIT ?
+`
+
+type ListGroupsWithAllTagsParams struct {
+	Tags     []string `json:"tags"`
+	AfterID  int64    `json:"after_id"`
+	TagCount int64    `json:"tag_count"`
+	PageSize int64    `json:"page_size"`
+}
+
+// Groups carrying every tag in the set (relational division).
+// tag_count must be the number of distinct tags passed in.
+func (q *Queries) ListGroupsWithAllTags(ctx context.Context, arg ListGroupsWithAllTagsParams) ([]Group, error) {
+	query := listGroupsWithAllTags
+	var queryParams []interface{}
+	if len(arg.Tags) > 0 {
+		for _, v := range arg.Tags {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:tags*/?", strings.Repeat(",?", len(arg.Tags))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:tags*/?", "NULL", 1)
+	}
+	queryParams = append(queryParams, arg.AfterID)
+	queryParams = append(queryParams, arg.TagCount)
+	queryParams = append(queryParams, arg.PageSize)
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Group{}
+	for rows.Next() {
+		var i Group
+		if err := rows.Scan(
+			&i.ID,
+			&i.Balance,
+			&i.TelegramID,
+			&i.Title,
+			&i.Url,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupTags = `-- name: ListGroupTags :many
+SELECT t.id, t.name FROM tags t
+JOIN group_tags gt ON gt.tag_id = t.id
+WHERE gt.group_telegram_id = ?
+ORDER BY t.name
+`
+
+func (q *Queries) ListGroupTags(ctx context.Context, groupTelegramID int64) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, listGroupTags, groupTelegramID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Tag{}
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNationalIDCiphertexts = `-- name: ListNationalIDCiphertexts :many
+SELECT user_telegram_id, CAST(national_id AS BLOB) AS ciphertext
+FROM user_national_ids
+WHERE user_telegram_id > ?
+ORDER BY user_telegram_id
+LIMIT ?
+`
+
+type ListNationalIDCiphertextsParams struct {
+	AfterTelegramID int64 `json:"after_telegram_id"`
+	Limit           int64 `json:"limit"`
+}
+
+type ListNationalIDCiphertextsRow struct {
+	UserTelegramID int64  `json:"user_telegram_id"`
+	Ciphertext     []byte `json:"ciphertext"`
+}
+
+// Raw ciphertexts for key rotation, by user
+func (q *Queries) ListNationalIDCiphertexts(ctx context.Context, arg ListNationalIDCiphertextsParams) ([]ListNationalIDCiphertextsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listNationalIDCiphertexts, arg.AfterTelegramID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListNationalIDCiphertextsRow{}
+	for rows.Next() {
+		var i ListNationalIDCiphertextsRow
+		if err := rows.Scan(&i.UserTelegramID, &i.Ciphertext); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersByCreatedAt = `-- name: ListUsersByCreatedAt :many
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users
+WHERE (datetime(created_at), id) > (datetime(?), CAST(? AS INTEGER)) AND deleted_at IS NULL
+ORDER BY datetime(created_at), id
+LIMIT ?
+`
+
+type ListUsersByCreatedAtParams struct {
+	AfterCreatedAt interface{} `json:"after_created_at"`
+	AfterID        int64       `json:"after_id"`
+	PageSize       int64       `json:"page_size"`
+}
+
+// Keyset pagination on two columns: pass the created_at and id of the last
+// user seen (the zero time and 0 for the first page). Users whose
+// created_at was set to NULL never show up.
+func (q *Queries) ListUsersByCreatedAt(ctx context.Context, arg ListUsersByCreatedAtParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByCreatedAt, arg.AfterCreatedAt, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.TelegramID,
+			&i.FirstName,
+			&i.Username,
+			&i.BalanceGame,
+			&i.BalanceChats,
+			&i.Status,
+			&i.Language,
+			&i.ReferFromID,
+			&i.LastStreakClaimAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.DeletedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersByFirstName = `-- name: ListUsersByFirstName :many
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users
+WHERE deleted_at IS NULL
+ORDER BY first_name COLLATE NOCASE_UNICODE, id
+LIMIT ?
+`
+
+// Alphabetical by first name in any language and casing (idx_users_first_name)
+func (q *Queries) ListUsersByFirstName(ctx context.Context, limit int64) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByFirstName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.TelegramID,
+			&i.FirstName,
+			&i.Username,
+			&i.BalanceGame,
+			&i.BalanceChats,
+			&i.Status,
+			&i.Language,
+			&i.ReferFromID,
+			&i.LastStreakClaimAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.DeletedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersByIDs = `-- name: ListUsersByIDs :many
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users
+WHERE id IN (/*SLICE:ids*/?) AND deleted_at IS NULL
+ORDER BY id
+`
+
+func (q *Queries) ListUsersByIDs(ctx context.Context, ids []int64) ([]User, error) {
+	query := listUsersByIDs
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.TelegramID,
+			&i.FirstName,
+			&i.Username,
+			&i.BalanceGame,
+			&i.BalanceChats,
+			&i.Status,
+			&i.Language,
+			&i.ReferFromID,
+			&i.LastStreakClaimAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.DeletedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersByStatus = `-- name: ListUsersByStatus :many
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users
+WHERE status = ? AND deleted_at IS NULL
+ORDER BY id
+LIMIT ?
+`
+
+type ListUsersByStatusParams struct {
+	Status Status `json:"status"`
+	Limit  int64  `json:"limit"`
+}
+
+func (q *Queries) ListUsersByStatus(ctx context.Context, arg ListUsersByStatusParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByStatus, arg.Status, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.TelegramID,
+			&i.FirstName,
+			&i.Username,
+			&i.BalanceGame,
+			&i.BalanceChats,
+			&i.Status,
+			&i.Language,
+			&i.ReferFromID,
+			&i.LastStreakClaimAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.DeletedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersMatchingUsername = `-- name: ListUsersMatchingUsername :many
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users
+WHERE username REGEXP CAST(? AS TEXT) AND deleted_at IS NULL
+ORDER BY id
+LIMIT ?
+`
+
+type ListUsersMatchingUsernameParams struct {
+	Pattern string `json:"pattern"`
+	Limit   int64  `json:"limit"`
+}
+
+// Usernames matching a regular expression: Go syntax via the regexp
+// function Open registers on SQLite, POSIX on PostgreSQL
+func (q *Queries) ListUsersMatchingUsername(ctx context.Context, arg ListUsersMatchingUsernameParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersMatchingUsername, arg.Pattern, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.TelegramID,
+			&i.FirstName,
+			&i.Username,
+			&i.BalanceGame,
+			&i.BalanceChats,
+			&i.Status,
+			&i.Language,
+			&i.ReferFromID,
+			&i.LastStreakClaimAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.DeletedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersWithGroups = `-- name: ListUsersWithGroups :many
+SELECT
+    u.id, u.telegram_id, u.first_name, u.username, u.balance_game, u.balance_chats, u.status, u.language, u.refer_from_id, u.last_streak_claim_at, u.created_at, u.updated_at, u.email, u.deleted_at, u.version,
+    g.telegram_id AS group_telegram_id,
+    g.title AS group_title,
+    ug.balance AS group_balance
+FROM users u
+LEFT JOIN user_group ug ON ug.user_telegram_id = u.telegram_id
+LEFT JOIN groups g ON g.telegram_id = ug.group_telegram_id
+WHERE u.id IN (SELECT id FROM users WHERE deleted_at IS NULL ORDER BY id LIMIT ?)
+ORDER BY u.id, ug.id
+`
+
+type ListUsersWithGroupsRow struct {
+	User            User             `json:"user"`
+	GroupTelegramID sql.Null[int64]  `json:"group_telegram_id"`
+	GroupTitle      sql.Null[string] `json:"group_title"`
+	GroupBalance    sql.Null[Money]  `json:"group_balance"`
+}
+
+// One row per (user, group) pair; users without groups get a single row
+// with NULL group columns. Assemble with GroupGroupsByUser.
+func (q *Queries) ListUsersWithGroups(ctx context.Context, limit int64) ([]ListUsersWithGroupsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersWithGroups, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUsersWithGroupsRow{}
+	for rows.Next() {
+		var i ListUsersWithGroupsRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.TelegramID,
+			&i.User.FirstName,
+			&i.User.Username,
+			&i.User.BalanceGame,
+			&i.User.BalanceChats,
+			&i.User.Status,
+			&i.User.Language,
+			&i.User.ReferFromID,
+			&i.User.LastStreakClaimAt,
+			&i.User.CreatedAt,
+			&i.User.UpdatedAt,
+			&i.User.Email,
+			&i.User.DeletedAt,
+			&i.User.Version,
+			&i.GroupTelegramID,
+			&i.GroupTitle,
+			&i.GroupBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOutboxEventDelivered = `-- name: MarkOutboxEventDelivered :exec
+UPDATE outbox
+SET delivered_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+func (q *Queries) MarkOutboxEventDelivered(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markOutboxEventDelivered, id)
+	return err
+}
+
+const pruneAuditLog = `-- name: PruneAuditLog :execrows
+DELETE FROM audit_log
+WHERE id IN (
+    SELECT id FROM audit_log
+    WHERE changed_at < strftime('%Y-%m-%d %H:%M:%f', 'now', printf('-%d seconds', CAST(? AS INTEGER)))
+    ORDER BY id
+    LIMIT ?
+)
+`
+
+type PruneAuditLogParams struct {
+	OlderThanSeconds int64 `json:"older_than_seconds"`
+	BatchSize        int64 `json:"batch_size"`
+}
+
+// Deletes up to batch_size entries older than older_than_seconds, oldest
+// first, so a long backlog goes in short transactions; see
+// DB.PruneAuditLog
+func (q *Queries) PruneAuditLog(ctx context.Context, arg PruneAuditLogParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, pruneAuditLog, arg.OlderThanSeconds, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeDeletedUsers = `-- name: PurgeDeletedUsers :execrows
+DELETE FROM users
+WHERE deleted_at IS NOT NULL
+  AND datetime(deleted_at) < datetime('now', printf('-%d seconds', CAST(? AS INTEGER)))
+`
+
+// Hard-deletes the users soft-deleted more than older_than_seconds ago,
+// by the database's clock, which set deleted_at; see DB.Purge
+func (q *Queries) PurgeDeletedUsers(ctx context.Context, olderThanSeconds int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedUsers, olderThanSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const putAttachment = `-- name: PutAttachment :one
+
+INSERT INTO attachments (sha256, content_type, size, data)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(sha256) DO UPDATE SET sha256 = excluded.sha256
+RETURNING id, sha256, content_type, size, created_at
+`
+
+type PutAttachmentParams struct {
+	Sha256      []byte `json:"sha256"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Data        []byte `json:"data"`
+}
+
+type PutAttachmentRow struct {
+	ID          int64               `json:"id"`
+	Sha256      []byte              `json:"sha256"`
+	ContentType string              `json:"content_type"`
+	Size        int64               `json:"size"`
+	CreatedAt   sql.Null[time.Time] `json:"created_at"`
+}
+
+// =====================
+// ATTACHMENT QUERIES
+// =====================
+// Same content twice returns the existing row instead of storing a copy
+func (q *Queries) PutAttachment(ctx context.Context, arg PutAttachmentParams) (PutAttachmentRow, error) {
+	row := q.db.QueryRowContext(ctx, putAttachment,
+		arg.Sha256,
+		arg.ContentType,
+		arg.Size,
+		arg.Data,
+	)
+	var i PutAttachmentRow
+	err := row.Scan(
+		&i.ID,
+		&i.Sha256,
+		&i.ContentType,
+		&i.Size,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const readAttachmentChunk = `-- name: ReadAttachmentChunk :one
+SELECT CAST(substr(data, CAST(? AS INTEGER), CAST(? AS INTEGER)) AS BLOB) AS chunk
+FROM attachments
+WHERE id = ?
+`
+
+type ReadAttachmentChunkParams struct {
+	Start  int64 `json:"start"`
+	Length int64 `json:"length"`
+	ID     int64 `json:"id"`
+}
+
+// start is 1-based, like substr
+func (q *Queries) ReadAttachmentChunk(ctx context.Context, arg ReadAttachmentChunkParams) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, readAttachmentChunk, arg.Start, arg.Length, arg.ID)
+	var chunk []byte
+	err := row.Scan(&chunk)
+	return chunk, err
+}
+
+const renameCategory = `-- name: RenameCategory :one
+UPDATE categories
+SET name = ?
+WHERE id = ?
+RETURNING id, parent_id, name, name_normalized
+`
+
+type RenameCategoryParams struct {
+	Name string `json:"name"`
+	ID   int64  `json:"id"`
+}
+
+func (q *Queries) RenameCategory(ctx context.Context, arg RenameCategoryParams) (Category, error) {
+	row := q.db.QueryRowContext(ctx, renameCategory, arg.Name, arg.ID)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.ParentID,
+		&i.Name,
+		&i.NameNormalized,
+	)
+	return i, err
+}
+
+const replaceNationalIDCiphertext = `-- name: ReplaceNationalIDCiphertext :execrows
+UPDATE user_national_ids
+SET national_id = CAST(? AS BLOB)
+WHERE user_telegram_id = ?
+  AND national_id = CAST(? AS BLOB)
+`
+
+type ReplaceNationalIDCiphertextParams struct {
+	NewCiphertext  []byte `json:"new_ciphertext"`
+	UserTelegramID int64  `json:"user_telegram_id"`
+	OldCiphertext  []byte `json:"old_ciphertext"`
+}
+
+// Swaps in a re-encrypted value, unless it changed since it was read
+func (q *Queries) ReplaceNationalIDCiphertext(ctx context.Context, arg ReplaceNationalIDCiphertextParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, replaceNationalIDCiphertext, arg.NewCiphertext, arg.UserTelegramID, arg.OldCiphertext)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const requeueDeadJob = `-- name: RequeueDeadJob :execrows
+UPDATE jobs
+SET status = 'pending', attempts = 0, last_error = NULL, run_at = ?
+WHERE id = ? AND status = 'dead'
+`
+
+type RequeueDeadJobParams struct {
+	RunAt time.Time `json:"run_at"`
+	ID    int64     `json:"id"`
+}
+
+// Gives a dead job a fresh set of attempts
+func (q *Queries) RequeueDeadJob(ctx context.Context, arg RequeueDeadJobParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requeueDeadJob, arg.RunAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreUser = `-- name: RestoreUser :execrows
+UPDATE users
+SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const retryJob = `-- name: RetryJob :exec
+UPDATE jobs
+SET status = 'pending', last_error = ?, run_at = ?
+WHERE id = ? AND attempts = ?
+`
+
+type RetryJobParams struct {
+	LastError sql.Null[string] `json:"last_error"`
+	RunAt     time.Time        `json:"run_at"`
+	ID        int64            `json:"id"`
+	Attempts  int64            `json:"attempts"`
+}
+
+func (q *Queries) RetryJob(ctx context.Context, arg RetryJobParams) error {
+	_, err := q.db.ExecContext(ctx, retryJob,
+		arg.LastError,
+		arg.RunAt,
+		arg.ID,
+		arg.Attempts,
+	)
+	return err
+}
+
+const sampleUsers = `-- name: SampleUsers :many
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users
+WHERE deleted_at IS NULL
+ORDER BY RANDOM()
+LIMIT ?
+`
+
+// A uniform random sample. Sorts the whole table; DB.SampleUsers avoids
+// that on large ones.
+func (q *Queries) SampleUsers(ctx context.Context, limit int64) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, sampleUsers, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.TelegramID,
+			&i.FirstName,
+			&i.Username,
+			&i.BalanceGame,
+			&i.BalanceChats,
+			&i.Status,
+			&i.Language,
+			&i.ReferFromID,
+			&i.LastStreakClaimAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.DeletedAt,
+			&i.Version,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -206,14 +2325,24 @@ func (q *Queries) GetTopGroupsForUser(ctx context.Context, userTelegramID int64)
 	return items, nil
 }
 
-const getTopUsersByBalance = `-- name: GetTopUsersByBalance :many
-SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at FROM users 
-ORDER BY (balance_game + balance_chats) DESC 
+const sampleUsersSeeded = `-- name: SampleUsersSeeded :many
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version FROM users
+WHERE deleted_at IS NULL
+ORDER BY (abs(id) % 2147483647 * CAST(? AS INTEGER) + CAST(? AS INTEGER)) % 2147483647, id
 LIMIT ?
 `
 
-func (q *Queries) GetTopUsersByBalance(ctx context.Context, limit int64) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, getTopUsersByBalance, limit)
+type SampleUsersSeededParams struct {
+	Multiplier int64 `json:"multiplier"`
+	Increment  int64 `json:"increment"`
+	Limit      int64 `json:"limit"`
+}
+
+// The same rows for the same multiplier and increment until users change:
+// a hash of id orders them (2147483647 is prime, so it's a permutation of
+// ids below it). Still sorts the whole table.
+func (q *Queries) SampleUsersSeeded(ctx context.Context, arg SampleUsersSeededParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, sampleUsersSeeded, arg.Multiplier, arg.Increment, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -234,6 +2363,9 @@ func (q *Queries) GetTopUsersByBalance(ctx context.Context, limit int64) ([]User
 			&i.LastStreakClaimAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Email,
+			&i.DeletedAt,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -248,38 +2380,46 @@ func (q *Queries) GetTopUsersByBalance(ctx context.Context, limit int64) ([]User
 	return items, nil
 }
 
-const getTopUsersInGroup = `-- name: GetTopUsersInGroup :many
-
-SELECT 
-    u.first_name,
-    u.username,
-    ug.balance
-FROM user_group ug
-LEFT JOIN users u ON u.telegram_id = ug.user_telegram_id
-WHERE ug.group_telegram_id = ?
-ORDER BY ug.balance DESC
-LIMIT 10
+const searchGroups = `-- name: SearchGroups :many
+SELECT
+    g.id,
+    CAST(COALESCE(highlight(groups_fts, 0, char(2), char(3)), '') AS TEXT) AS title,
+    CAST(COALESCE(snippet(groups_fts, 1, char(2), char(3), '…', 10), '') AS TEXT) AS snippet,
+    CAST(-groups_fts.rank AS REAL) AS score
+FROM groups_fts
+JOIN groups g ON g.id = groups_fts.rowid
+WHERE groups_fts MATCH CAST(? AS TEXT)
+ORDER BY groups_fts.rank
+LIMIT ?
 `
 
-type GetTopUsersInGroupRow struct {
-	FirstName sql.NullString  `json:"first_name"`
-	Username  sql.NullString  `json:"username"`
-	Balance   sql.NullFloat64 `json:"balance"`
+type SearchGroupsParams struct {
+	Query string `json:"query"`
+	Limit int64  `json:"limit"`
 }
 
-// =====================
-// STATS QUERIES
-// =====================
-func (q *Queries) GetTopUsersInGroup(ctx context.Context, groupTelegramID int64) ([]GetTopUsersInGroupRow, error) {
-	rows, err := q.db.QueryContext(ctx, getTopUsersInGroup, groupTelegramID)
+type SearchGroupsRow struct {
+	ID      int64   `json:"id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+func (q *Queries) SearchGroups(ctx context.Context, arg SearchGroupsParams) ([]SearchGroupsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchGroups, arg.Query, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []GetTopUsersInGroupRow{}
+	items := []SearchGroupsRow{}
 	for rows.Next() {
-		var i GetTopUsersInGroupRow
-		if err := rows.Scan(&i.FirstName, &i.Username, &i.Balance); err != nil {
+		var i SearchGroupsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Snippet,
+			&i.Score,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -293,132 +2433,178 @@ func (q *Queries) GetTopUsersInGroup(ctx context.Context, groupTelegramID int64)
 	return items, nil
 }
 
-const getTotalGroupBalance = `-- name: GetTotalGroupBalance :one
-SELECT COALESCE(SUM(balance), 0) AS total FROM user_group 
-WHERE group_telegram_id = ?
+const searchUsers = `-- name: SearchUsers :many
+SELECT
+    u.id,
+    CAST(COALESCE(highlight(users_fts, 0, char(2), char(3)), '') AS TEXT) AS title,
+    CAST(COALESCE(snippet(users_fts, 1, char(2), char(3), '…', 10), '') AS TEXT) AS snippet,
+    CAST(-users_fts.rank AS REAL) AS score
+FROM users_fts
+JOIN users u ON u.id = users_fts.rowid
+WHERE users_fts MATCH CAST(? AS TEXT) AND u.deleted_at IS NULL
+ORDER BY users_fts.rank
+LIMIT ?
 `
 
-func (q *Queries) GetTotalGroupBalance(ctx context.Context, groupTelegramID int64) (interface{}, error) {
-	row := q.db.QueryRowContext(ctx, getTotalGroupBalance, groupTelegramID)
-	var total interface{}
-	err := row.Scan(&total)
-	return total, err
+type SearchUsersParams struct {
+	Query string `json:"query"`
+	Limit int64  `json:"limit"`
 }
 
-const getTotalUserBalance = `-- name: GetTotalUserBalance :one
-SELECT COALESCE(SUM(balance), 0) AS total FROM user_group 
-WHERE user_telegram_id = ?
-`
+type SearchUsersRow struct {
+	ID      int64   `json:"id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
 
-func (q *Queries) GetTotalUserBalance(ctx context.Context, userTelegramID int64) (interface{}, error) {
-	row := q.db.QueryRowContext(ctx, getTotalUserBalance, userTelegramID)
-	var total interface{}
-	err := row.Scan(&total)
-	return total, err
+// Full-text matches for DB.Search, best first; query is an FTS5 MATCH
+// expression. The matches in title and snippet are between char(2) and
+// char(3), which Search replaces with the caller's markers.
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchUsers, arg.Query, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchUsersRow{}
+	for rows.Next() {
+		var i SearchUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Snippet,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getUserByID = `-- name: GetUserByID :one
-SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at FROM users WHERE id = ? LIMIT 1
+const setCategoryParent = `-- name: SetCategoryParent :one
+UPDATE categories
+SET parent_id = ?
+WHERE id = ?
+RETURNING id, parent_id, name, name_normalized
 `
 
-func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByID, id)
-	var i User
+type SetCategoryParentParams struct {
+	ParentID sql.Null[int64] `json:"parent_id"`
+	ID       int64           `json:"id"`
+}
+
+func (q *Queries) SetCategoryParent(ctx context.Context, arg SetCategoryParentParams) (Category, error) {
+	row := q.db.QueryRowContext(ctx, setCategoryParent, arg.ParentID, arg.ID)
+	var i Category
 	err := row.Scan(
 		&i.ID,
-		&i.TelegramID,
-		&i.FirstName,
-		&i.Username,
-		&i.BalanceGame,
-		&i.BalanceChats,
-		&i.Status,
-		&i.Language,
-		&i.ReferFromID,
-		&i.LastStreakClaimAt,
-		&i.CreatedAt,
-		&i.UpdatedAt,
+		&i.ParentID,
+		&i.Name,
+		&i.NameNormalized,
 	)
 	return i, err
 }
 
-const getUserByTelegramID = `-- name: GetUserByTelegramID :one
-
-SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at FROM users WHERE telegram_id = ? LIMIT 1
+const setUserNationalID = `-- name: SetUserNationalID :exec
+INSERT INTO user_national_ids (user_telegram_id, national_id, national_id_index)
+VALUES (?, ?, ?)
+ON CONFLICT (user_telegram_id) DO UPDATE SET
+    national_id = excluded.national_id,
+    national_id_index = excluded.national_id_index
 `
 
-// =====================
-// USER QUERIES
-// =====================
-func (q *Queries) GetUserByTelegramID(ctx context.Context, telegramID int64) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByTelegramID, telegramID)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.TelegramID,
-		&i.FirstName,
-		&i.Username,
-		&i.BalanceGame,
-		&i.BalanceChats,
-		&i.Status,
-		&i.Language,
-		&i.ReferFromID,
-		&i.LastStreakClaimAt,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+type SetUserNationalIDParams struct {
+	UserTelegramID  int64  `json:"user_telegram_id"`
+	NationalID      []byte `json:"national_id"`
+	NationalIDIndex []byte `json:"national_id_index"`
 }
 
-const getUserGroup = `-- name: GetUserGroup :one
+// Set through DB.SetUserNationalID, which computes the blind index
+func (q *Queries) SetUserNationalID(ctx context.Context, arg SetUserNationalIDParams) error {
+	_, err := q.db.ExecContext(ctx, setUserNationalID, arg.UserTelegramID, arg.NationalID, arg.NationalIDIndex)
+	return err
+}
 
-SELECT id, user_telegram_id, group_telegram_id, balance FROM user_group 
-WHERE user_telegram_id = ? AND group_telegram_id = ? 
-LIMIT 1
+const softDeleteUser = `-- name: SoftDeleteUser :execrows
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL
 `
 
-type GetUserGroupParams struct {
-	UserTelegramID  int64 `json:"user_telegram_id"`
-	GroupTelegramID int64 `json:"group_telegram_id"`
+// Soft delete: the queries above that read users leave out the ones with
+// deleted_at set (DB.WithDeleted and DB.OnlyDeleted see them). Writes by
+// id still reach them. No rows when the user is already deleted.
+func (q *Queries) SoftDeleteUser(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-// =====================
-// USER-GROUP QUERIES
-// =====================
-func (q *Queries) GetUserGroup(ctx context.Context, arg GetUserGroupParams) (UserGroup, error) {
-	row := q.db.QueryRowContext(ctx, getUserGroup, arg.UserTelegramID, arg.GroupTelegramID)
-	var i UserGroup
-	err := row.Scan(
-		&i.ID,
-		&i.UserTelegramID,
-		&i.GroupTelegramID,
-		&i.Balance,
-	)
-	return i, err
+const touchStorageProbe = `-- name: TouchStorageProbe :exec
+INSERT INTO storage_probe (id, checked_at)
+VALUES (1, CURRENT_TIMESTAMP)
+ON CONFLICT (id) DO UPDATE SET
+    checked_at = excluded.checked_at
+`
+
+// Rewrites one row; success means the database takes writes again
+func (q *Queries) TouchStorageProbe(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, touchStorageProbe)
+	return err
 }
 
-const getUserPosition = `-- name: GetUserPosition :one
-SELECT COUNT(*) + 1 AS position FROM users 
-WHERE (balance_game + balance_chats) > ?
+const updateStatusByIDs = `-- name: UpdateStatusByIDs :execrows
+UPDATE users
+SET status = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id IN (/*SLICE:ids*/?)
 `
 
-func (q *Queries) GetUserPosition(ctx context.Context, balanceGame sql.NullFloat64) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getUserPosition, balanceGame)
-	var position int64
-	err := row.Scan(&position)
-	return position, err
+type UpdateStatusByIDsParams struct {
+	Status Status  `json:"status"`
+	IDs    []int64 `json:"ids"`
+}
+
+// Sets the status of every listed user; DB.UpdateStatusByIDs splits long lists
+func (q *Queries) UpdateStatusByIDs(ctx context.Context, arg UpdateStatusByIDsParams) (int64, error) {
+	query := updateStatusByIDs
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.Status)
+	if len(arg.IDs) > 0 {
+		for _, v := range arg.IDs {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(arg.IDs))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	result, err := q.db.ExecContext(ctx, query, queryParams...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const updateUser = `-- name: UpdateUser :one
 UPDATE users 
-SET first_name = ?, username = ?, updated_at = CURRENT_TIMESTAMP
+SET first_name = ?, username = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
 WHERE telegram_id = ?
-RETURNING id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at
+RETURNING id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version
 `
 
 type UpdateUserParams struct {
-	FirstName  string         `json:"first_name"`
-	Username   sql.NullString `json:"username"`
-	TelegramID int64          `json:"telegram_id"`
+	FirstName  string           `json:"first_name"`
+	Username   sql.Null[string] `json:"username"`
+	TelegramID int64            `json:"telegram_id"`
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
@@ -437,41 +2623,121 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.LastStreakClaimAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
 	)
 	return i, err
 }
 
-const updateUserBalanceChats = `-- name: UpdateUserBalanceChats :exec
+const updateUserBalanceChats = `-- name: UpdateUserBalanceChats :one
 UPDATE users 
-SET balance_chats = ?, updated_at = CURRENT_TIMESTAMP
+SET balance_chats = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
 WHERE telegram_id = ?
+RETURNING id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version
 `
 
 type UpdateUserBalanceChatsParams struct {
-	BalanceChats sql.NullFloat64 `json:"balance_chats"`
+	BalanceChats sql.Null[Money] `json:"balance_chats"`
 	TelegramID   int64           `json:"telegram_id"`
 }
 
-func (q *Queries) UpdateUserBalanceChats(ctx context.Context, arg UpdateUserBalanceChatsParams) error {
-	_, err := q.db.ExecContext(ctx, updateUserBalanceChats, arg.BalanceChats, arg.TelegramID)
-	return err
+func (q *Queries) UpdateUserBalanceChats(ctx context.Context, arg UpdateUserBalanceChatsParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserBalanceChats, arg.BalanceChats, arg.TelegramID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.TelegramID,
+		&i.FirstName,
+		&i.Username,
+		&i.BalanceGame,
+		&i.BalanceChats,
+		&i.Status,
+		&i.Language,
+		&i.ReferFromID,
+		&i.LastStreakClaimAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const updateUserBalanceChatsIfVersion = `-- name: UpdateUserBalanceChatsIfVersion :execrows
+UPDATE users
+SET balance_chats = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND version = ?
+`
+
+type UpdateUserBalanceChatsIfVersionParams struct {
+	BalanceChats sql.Null[Money] `json:"balance_chats"`
+	ID           int64           `json:"id"`
+	Version      int64           `json:"version"`
+}
+
+func (q *Queries) UpdateUserBalanceChatsIfVersion(ctx context.Context, arg UpdateUserBalanceChatsIfVersionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserBalanceChatsIfVersion, arg.BalanceChats, arg.ID, arg.Version)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const updateUserGroupBalance = `-- name: UpdateUserGroupBalance :exec
+const updateUserGroupBalance = `-- name: UpdateUserGroupBalance :one
 UPDATE user_group 
 SET balance = ? 
 WHERE user_telegram_id = ? AND group_telegram_id = ?
+RETURNING id, user_telegram_id, group_telegram_id, balance
 `
 
 type UpdateUserGroupBalanceParams struct {
-	Balance         sql.NullFloat64 `json:"balance"`
+	Balance         sql.Null[Money] `json:"balance"`
 	UserTelegramID  int64           `json:"user_telegram_id"`
 	GroupTelegramID int64           `json:"group_telegram_id"`
 }
 
-func (q *Queries) UpdateUserGroupBalance(ctx context.Context, arg UpdateUserGroupBalanceParams) error {
-	_, err := q.db.ExecContext(ctx, updateUserGroupBalance, arg.Balance, arg.UserTelegramID, arg.GroupTelegramID)
-	return err
+func (q *Queries) UpdateUserGroupBalance(ctx context.Context, arg UpdateUserGroupBalanceParams) (UserGroup, error) {
+	row := q.db.QueryRowContext(ctx, updateUserGroupBalance, arg.Balance, arg.UserTelegramID, arg.GroupTelegramID)
+	var i UserGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserTelegramID,
+		&i.GroupTelegramID,
+		&i.Balance,
+	)
+	return i, err
+}
+
+const updateUserIfVersion = `-- name: UpdateUserIfVersion :execrows
+UPDATE users
+SET first_name = ?, username = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND version = ?
+`
+
+type UpdateUserIfVersionParams struct {
+	FirstName string           `json:"first_name"`
+	Username  sql.Null[string] `json:"username"`
+	ID        int64            `json:"id"`
+	Version   int64            `json:"version"`
+}
+
+// Optimistic locking: these update the user only while it's still at the
+// version the caller read, and bump it. No rows when another update got
+// there first; DB.UpdateUserIfVersion and
+// DB.UpdateUserBalanceChatsIfVersion return ErrStaleVersion then.
+func (q *Queries) UpdateUserIfVersion(ctx context.Context, arg UpdateUserIfVersionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserIfVersion,
+		arg.FirstName,
+		arg.Username,
+		arg.ID,
+		arg.Version,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const upsertGroup = `-- name: UpsertGroup :one
@@ -484,8 +2750,8 @@ RETURNING id, balance, telegram_id, title, url, created_at, updated_at
 `
 
 type UpsertGroupParams struct {
-	TelegramID int64          `json:"telegram_id"`
-	Title      sql.NullString `json:"title"`
+	TelegramID int64            `json:"telegram_id"`
+	Title      sql.Null[string] `json:"title"`
 }
 
 func (q *Queries) UpsertGroup(ctx context.Context, arg UpsertGroupParams) (Group, error) {
@@ -503,20 +2769,40 @@ func (q *Queries) UpsertGroup(ctx context.Context, arg UpsertGroupParams) (Group
 	return i, err
 }
 
+const upsertTag = `-- name: UpsertTag :one
+
+INSERT INTO tags (name)
+VALUES (?)
+ON CONFLICT(name) DO UPDATE SET
+    name = excluded.name
+RETURNING id, name
+`
+
+// =====================
+// TAG QUERIES
+// =====================
+func (q *Queries) UpsertTag(ctx context.Context, name string) (Tag, error) {
+	row := q.db.QueryRowContext(ctx, upsertTag, name)
+	var i Tag
+	err := row.Scan(&i.ID, &i.Name)
+	return i, err
+}
+
 const upsertUser = `-- name: UpsertUser :one
 INSERT INTO users (telegram_id, first_name, username, status, language)
 VALUES (?, ?, ?, 'active', 'en')
 ON CONFLICT(telegram_id) DO UPDATE SET
     first_name = CASE WHEN excluded.first_name != '' THEN excluded.first_name ELSE users.first_name END,
     username = CASE WHEN excluded.username != '' THEN excluded.username ELSE users.username END,
+    version = users.version + 1,
     updated_at = CURRENT_TIMESTAMP
-RETURNING id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at
+RETURNING id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version
 `
 
 type UpsertUserParams struct {
-	TelegramID int64          `json:"telegram_id"`
-	FirstName  string         `json:"first_name"`
-	Username   sql.NullString `json:"username"`
+	TelegramID int64            `json:"telegram_id"`
+	FirstName  string           `json:"first_name"`
+	Username   sql.Null[string] `json:"username"`
 }
 
 func (q *Queries) UpsertUser(ctx context.Context, arg UpsertUserParams) (User, error) {
@@ -535,6 +2821,85 @@ func (q *Queries) UpsertUser(ctx context.Context, arg UpsertUserParams) (User, e
 		&i.LastStreakClaimAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const upsertUserByEmail = `-- name: UpsertUserByEmail :one
+INSERT INTO users (telegram_id, first_name, username, email, status, language)
+VALUES (?, ?, ?, ?, 'active', 'en')
+ON CONFLICT (lower(email)) DO UPDATE SET
+    first_name = CASE WHEN excluded.first_name != '' THEN excluded.first_name ELSE users.first_name END,
+    username = CASE WHEN excluded.username != '' THEN excluded.username ELSE users.username END,
+    version = users.version + 1,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version
+`
+
+type UpsertUserByEmailParams struct {
+	TelegramID int64            `json:"telegram_id"`
+	FirstName  string           `json:"first_name"`
+	Username   sql.Null[string] `json:"username"`
+	Email      sql.Null[string] `json:"email"`
+}
+
+// Creates the user, or updates the one with that email in any casing;
+// DB.UpsertUserByEmail normalizes the email. telegram_id is only set on
+// insert, and one that's taken by another user is a unique violation.
+func (q *Queries) UpsertUserByEmail(ctx context.Context, arg UpsertUserByEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, upsertUserByEmail,
+		arg.TelegramID,
+		arg.FirstName,
+		arg.Username,
+		arg.Email,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.TelegramID,
+		&i.FirstName,
+		&i.Username,
+		&i.BalanceGame,
+		&i.BalanceChats,
+		&i.Status,
+		&i.Language,
+		&i.ReferFromID,
+		&i.LastStreakClaimAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const upsertUserGroupBalance = `-- name: UpsertUserGroupBalance :one
+INSERT INTO user_group (user_telegram_id, group_telegram_id, balance)
+VALUES (?, ?, ?)
+ON CONFLICT(user_telegram_id, group_telegram_id) DO UPDATE SET
+    balance = excluded.balance
+RETURNING id, user_telegram_id, group_telegram_id, balance
+`
+
+type UpsertUserGroupBalanceParams struct {
+	UserTelegramID  int64           `json:"user_telegram_id"`
+	GroupTelegramID int64           `json:"group_telegram_id"`
+	Balance         sql.Null[Money] `json:"balance"`
+}
+
+// Sets the balance, adding the membership if there's none
+func (q *Queries) UpsertUserGroupBalance(ctx context.Context, arg UpsertUserGroupBalanceParams) (UserGroup, error) {
+	row := q.db.QueryRowContext(ctx, upsertUserGroupBalance, arg.UserTelegramID, arg.GroupTelegramID, arg.Balance)
+	var i UserGroup
+	err := row.Scan(
+		&i.ID,
+		&i.UserTelegramID,
+		&i.GroupTelegramID,
+		&i.Balance,
 	)
 	return i, err
 }
@@ -0,0 +1,348 @@
+// Package queue runs background jobs from the jobs table, for apps that
+// would rather not run Redis beside their database:
+//
+//	q := queue.New(db, queue.Options{Workers: 4})
+//	q.Handle("welcome-email", func(ctx context.Context, job queue.Job) error {
+//		return sendWelcome(ctx, job.Payload)
+//	})
+//	go q.Run(ctx)
+//
+//	id, err := q.Enqueue(ctx, "welcome-email", payload, time.Time{}) // run now
+//
+// Enqueue with a transaction's context (Tx.Context) commits the job with
+// the transaction, or not at all.
+//
+// Delivery is at least once. A worker claims a job in a transaction, which
+// hides it from the other workers, in this process or another, for
+// Options.Timeout; if the worker dies, the job runs again after that. A
+// failed job is retried with backoff until it runs out of attempts, then
+// kept as a dead letter for Dead and Retry. Handlers should therefore be
+// safe to run twice.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"your-project/database"
+	"your-project/database/nulls"
+)
+
+// Job is a job handed to its Handler
+type Job struct {
+	ID          int64
+	Type        string
+	Payload     []byte
+	Attempt     int64  // 1 on the first run
+	MaxAttempts int64  // Runs it gets before it's a dead letter
+	LastError   string // Why the previous attempt failed, "" on the first
+	CreatedAt   time.Time
+}
+
+// Handler runs a job. An error retries it, unless it's Permanent. The
+// context ends when the job's Timeout does, or the worker stops.
+type Handler func(ctx context.Context, job Job) error
+
+// Options configures a Queue
+type Options struct {
+	Workers      int           // Jobs run at once, each by a worker of its own (default 1)
+	PollInterval time.Duration // How often an idle worker looks for due jobs (default 1s)
+	Timeout      time.Duration // How long a claimed job is hidden from other workers, and may run (default 5m)
+	MaxAttempts  int64         // Runs Enqueue gives a job (default 5)
+	MinBackoff   time.Duration // Delay before the first retry, doubled per attempt (default 1s)
+	MaxBackoff   time.Duration // Upper bound for the retry delay (default 1h)
+}
+
+// Queue enqueues jobs and runs them with the handlers registered on it.
+// It's safe for concurrent use.
+type Queue struct {
+	db   *database.DB
+	opts Options
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// New returns a queue on db's jobs table
+func New(db *database.DB, opts Options) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Minute
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Hour
+	}
+	return &Queue{db: db, opts: opts, handlers: map[string]Handler{}}
+}
+
+// Handle registers the handler for jobs of jobType, replacing any before
+// it. Workers only claim jobs of the types they have handlers for, so
+// processes may each handle some types of one table.
+func (q *Queue) Handle(jobType string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = h
+}
+
+// Enqueue adds a job of jobType that runs at runAt, or as soon as a worker
+// is free for a zero runAt, and returns its ID
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload []byte, runAt time.Time) (int64, error) {
+	if jobType == "" {
+		return 0, errors.New("queue: empty job type")
+	}
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+	var job database.Job
+	err := q.db.Transaction(ctx, func(tq *database.Queries) error {
+		var err error
+		job, err = tq.EnqueueJob(ctx, database.EnqueueJobParams{
+			Type:        jobType,
+			Payload:     payload,
+			MaxAttempts: q.opts.MaxAttempts,
+			RunAt:       runAt.UTC(),
+		})
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue %s job: %w", jobType, err)
+	}
+	return job.ID, nil
+}
+
+// Run runs jobs with Options.Workers workers until ctx is canceled, then
+// waits for the jobs they're running. A job stopped that way is released
+// for another worker at once; the attempt still counts.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Straight on to the next job while there are any due
+		ran, err := q.runOne(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("job queue: %v", err)
+		}
+		if ran && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs the jobs that are due, one at a time until there are none,
+// and returns how many ran, for tests and cron-style jobs
+func (q *Queue) RunDue(ctx context.Context) (int, error) {
+	n := 0
+	for {
+		ran, err := q.runOne(ctx)
+		if err != nil || !ran {
+			return n, err
+		}
+		n++
+	}
+}
+
+// runOne claims a due job and runs it, if there is one
+func (q *Queue) runOne(ctx context.Context) (bool, error) {
+	q.mu.RLock()
+	types := make([]string, 0, len(q.handlers))
+	for t := range q.handlers {
+		types = append(types, t)
+	}
+	q.mu.RUnlock()
+	if len(types) == 0 {
+		return false, nil
+	}
+	slices.Sort(types)
+
+	var jobs []database.Job
+	now := time.Now().UTC()
+	err := q.db.Transaction(ctx, func(tq *database.Queries) error {
+		var err error
+		jobs, err = tq.ClaimJobs(ctx, database.ClaimJobsParams{
+			VisibleAt: now.Add(q.opts.Timeout),
+			Now:       now,
+			Types:     types,
+			Limit:     1,
+		})
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim a job: %w", err)
+	}
+	if len(jobs) == 0 {
+		return false, nil
+	}
+	return true, q.run(ctx, jobs[0])
+}
+
+// run runs a claimed job and records how it went. The records are made
+// even when ctx has ended, so a stopping worker releases its job.
+func (q *Queue) run(ctx context.Context, j database.Job) error {
+	q.mu.RLock()
+	h := q.handlers[j.Type]
+	q.mu.RUnlock()
+
+	var err error
+	if j.Attempts > j.MaxAttempts {
+		// Its last attempt outlasted its timeout without the worker saying
+		// how it went, most likely because the worker died
+		err = Permanent(errors.New("timed out on its last attempt"))
+	} else {
+		err = q.call(ctx, h, j)
+	}
+
+	done := context.WithoutCancel(ctx)
+	switch {
+	case err == nil:
+		err = q.db.Q.CompleteJob(done, database.CompleteJobParams{ID: j.ID, Attempts: j.Attempts})
+	case ctx.Err() != nil:
+		err = q.db.Q.RetryJob(done, database.RetryJobParams{
+			LastError: j.LastError,
+			RunAt:     time.Now().UTC(),
+			ID:        j.ID,
+			Attempts:  j.Attempts,
+		})
+	case isPermanent(err) || j.Attempts >= j.MaxAttempts:
+		err = q.db.Q.BuryJob(done, database.BuryJobParams{
+			LastError: nulls.String(err.Error()),
+			ID:        j.ID,
+			Attempts:  j.Attempts,
+		})
+	default:
+		backoff := min(q.opts.MinBackoff<<(j.Attempts-1), q.opts.MaxBackoff)
+		if backoff <= 0 { // shift overflow
+			backoff = q.opts.MaxBackoff
+		}
+		err = q.db.Q.RetryJob(done, database.RetryJobParams{
+			LastError: nulls.String(err.Error()),
+			RunAt:     time.Now().UTC().Add(backoff),
+			ID:        j.ID,
+			Attempts:  j.Attempts,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("job %d: %w", j.ID, err)
+	}
+	return nil
+}
+
+// call runs the handler within the job's timeout, turning a panic into an
+// error so it doesn't take the worker down
+func (q *Queue) call(ctx context.Context, h Handler, j database.Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, q.opts.Timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(ctx, Job{
+		ID:          j.ID,
+		Type:        j.Type,
+		Payload:     j.Payload,
+		Attempt:     j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		LastError:   j.LastError.V,
+		CreatedAt:   j.CreatedAt.V,
+	})
+}
+
+// Dead returns up to limit dead letters, oldest first: jobs that ran out
+// of attempts or failed with a Permanent error. LastError says why.
+func (q *Queue) Dead(ctx context.Context, limit int) ([]Job, error) {
+	rows, err := q.db.Q.ListDeadJobs(ctx, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]Job, len(rows))
+	for i, j := range rows {
+		jobs[i] = Job{
+			ID:          j.ID,
+			Type:        j.Type,
+			Payload:     j.Payload,
+			Attempt:     j.Attempts,
+			MaxAttempts: j.MaxAttempts,
+			LastError:   j.LastError.V,
+			CreatedAt:   j.CreatedAt.V,
+		}
+	}
+	return jobs, nil
+}
+
+// Retry gives a dead letter a fresh set of attempts, starting now. It
+// returns database.ErrNotFound if there's no dead job with that ID.
+func (q *Queue) Retry(ctx context.Context, id int64) error {
+	n, err := q.db.Q.RequeueDeadJob(ctx, database.RequeueDeadJobParams{RunAt: time.Now().UTC(), ID: id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("dead job %d: %w", id, database.ErrNotFound)
+	}
+	return nil
+}
+
+// Delete removes a job, pending or dead. A job a worker is running still
+// finishes. It returns database.ErrNotFound if there's no such job.
+func (q *Queue) Delete(ctx context.Context, id int64) error {
+	n, err := q.db.Q.DeleteJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("job %d: %w", id, database.ErrNotFound)
+	}
+	return nil
+}
+
+// permanentError is a handler error that retrying won't fix
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler's error as one retrying won't fix, such as a
+// payload that doesn't parse: the job goes to the dead letters at once
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+func isPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
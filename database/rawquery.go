@@ -0,0 +1,258 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotReadOnly is returned by RawQuery for a statement that could
+// write, unless RawQueryOptions.AllowWrites is set
+var ErrNotReadOnly = errors.New("only SELECT, EXPLAIN and read-only PRAGMA statements are allowed")
+
+// RawQuery defaults
+const (
+	defaultRawMaxRows = 1000
+	defaultRawTimeout = 30 * time.Second
+)
+
+// RawQueryOptions configures RawQueryWith
+type RawQueryOptions struct {
+	AllowWrites bool          // Allow any statement, not just SELECT, EXPLAIN and the PRAGMAs that only read
+	MaxRows     int           // Rows read before the result is cut off (0 = 1000)
+	Timeout     time.Duration // Longest the statement may run (0 = 30s)
+}
+
+// RawResult is whatever an ad-hoc statement returned
+type RawResult struct {
+	Columns   []string         // In select order; a repeated name gets a suffix ("id", "id_2")
+	Types     []string         // Database type of each column as the driver names it ("INTEGER", "int8"), "" if unknown
+	Rows      []map[string]any // int64, float64, bool, string, []byte, time.Time or nil, by column name
+	Truncated bool             // There were more than MaxRows rows; the rest weren't read
+}
+
+// RawQuery runs one ad-hoc read-only statement, for admin consoles and
+// other tools rendering whatever comes back. It's RawQueryWith with the
+// default options.
+func (db *DB) RawQuery(ctx context.Context, query string, args ...any) (*RawResult, error) {
+	return db.RawQueryWith(ctx, RawQueryOptions{}, query, args...)
+}
+
+// RawQueryWith runs one statement and reads its rows, at most
+// opts.MaxRows of them. Unless opts.AllowWrites is set the statement must
+// begin with SELECT or EXPLAIN, or be one of the PRAGMAs that only read
+// (table_info(users), integrity_check, journal_mode, but no "= value"), and
+// it runs on a connection put in read-only mode for it (query_only on
+// SQLite, a read-only session on PostgreSQL), so an EXPLAIN ANALYZE of an
+// UPDATE fails too. Several statements separated by ";" are refused.
+//
+// A statement that may change the connection's settings (PRAGMA, SET,
+// ATTACH, BEGIN and the like, with AllowWrites) gets the connection closed
+// afterwards rather than handed back to the pool. On a :memory: database
+// that closes the database, too.
+func (db *DB) RawQueryWith(ctx context.Context, opts RawQueryOptions, query string, args ...any) (*RawResult, error) {
+	if hasSecondStatement(query) {
+		return nil, errors.New("only one statement can be run at a time")
+	}
+	if !opts.AllowWrites {
+		switch kw := firstKeyword(query); {
+		case kw == "SELECT", kw == "EXPLAIN":
+		case kw == "PRAGMA" && readOnlyPragma(query):
+		default:
+			return nil, ErrNotReadOnly
+		}
+	}
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = defaultRawMaxRows
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultRawTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	conn, err := db.Conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if changesSession(query) {
+		defer conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+
+	if d := defaultDialect(); !opts.AllowWrites && d.readOnlyOn != "" {
+		if _, err := conn.ExecContext(ctx, d.readOnlyOn); err != nil {
+			return nil, fmt.Errorf("failed to make the connection read-only: %w", err)
+		}
+		defer func() {
+			// The connection goes back to the pool, and mustn't stay read-only
+			if _, err := conn.ExecContext(context.Background(), d.readOnlyOff); err != nil {
+				conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}()
+	}
+
+	rows, err := db.wrap(conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return readRawRows(rows, opts.MaxRows)
+}
+
+func readRawRows(rows *sql.Rows, maxRows int) (*RawResult, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	res := &RawResult{
+		Columns: make([]string, len(types)),
+		Types:   make([]string, len(types)),
+		Rows:    []map[string]any{},
+	}
+	seen := map[string]int{}
+	for i, t := range types {
+		name := t.Name()
+		if seen[name]++; seen[name] > 1 {
+			name += "_" + strconv.Itoa(seen[name])
+		}
+		res.Columns[i], res.Types[i] = name, t.DatabaseTypeName()
+	}
+
+	vals := make([]any, len(types))
+	dest := make([]any, len(types))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	for rows.Next() {
+		if len(res.Rows) == maxRows {
+			res.Truncated = true
+			break
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(vals))
+		for i, v := range vals {
+			row[res.Columns[i]] = rawValue(v, res.Types[i])
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	return res, rows.Err()
+}
+
+// rawValue narrows what drivers hand back to a few Go types. Text some
+// drivers return as []byte becomes a string, going by the column type.
+func rawValue(v any, dbType string) any {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case []byte:
+		if isTextType(dbType) {
+			return string(v)
+		}
+		return v
+	}
+	return v
+}
+
+func isTextType(dbType string) bool {
+	t := strings.ToUpper(dbType)
+	for _, s := range []string{"CHAR", "TEXT", "CLOB", "JSON", "UUID", "NUMERIC", "DECIMAL"} {
+		if strings.Contains(t, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// The PRAGMAs RawQuery runs read-only: those taking a table, index or row
+// limit in parentheses, and those reading a setting, which with an
+// argument would set it instead ("PRAGMA journal_mode(wal)").
+var (
+	readPragmasWithArg = map[string]bool{
+		"table_info": true, "table_xinfo": true, "table_list": true,
+		"index_list": true, "index_info": true, "index_xinfo": true,
+		"foreign_key_list": true, "foreign_key_check": true,
+		"integrity_check": true, "quick_check": true,
+	}
+	readPragmas = map[string]bool{
+		"compile_options": true, "database_list": true, "collation_list": true,
+		"function_list": true, "module_list": true, "pragma_list": true,
+		"page_count": true, "page_size": true, "freelist_count": true,
+		"encoding": true, "user_version": true, "schema_version": true,
+		"application_id": true, "data_version": true, "journal_mode": true,
+		"foreign_keys": true, "query_only": true, "synchronous": true,
+		"busy_timeout": true, "cache_size": true, "auto_vacuum": true,
+	}
+	pragmaRe = regexp.MustCompile(`(?is)^PRAGMA\s+(?:\w+\.)?(\w+)\s*(\(\s*[\w"'.]*\s*\))?\s*;?$`)
+)
+
+// readOnlyPragma reports a PRAGMA from the lists above, in a form that
+// doesn't set anything
+func readOnlyPragma(query string) bool {
+	m := pragmaRe.FindStringSubmatch(skipComments(query))
+	if m == nil {
+		return false
+	}
+	name := strings.ToLower(m[1])
+	if m[2] != "" {
+		return readPragmasWithArg[name]
+	}
+	return readPragmasWithArg[name] || readPragmas[name]
+}
+
+// changesSession reports a statement that may leave the connection's
+// settings other than the pool set them up: attached databases, pragmas,
+// session variables, an open transaction
+func changesSession(query string) bool {
+	switch firstKeyword(query) {
+	case "PRAGMA":
+		return !readOnlyPragma(query)
+	case "SET", "RESET", "DISCARD", "ATTACH", "DETACH", "BEGIN", "START", "SAVEPOINT",
+		"PREPARE", "DEALLOCATE", "DECLARE", "LISTEN", "UNLISTEN", "LOAD", "USE":
+		return true
+	}
+	return false
+}
+
+// hasSecondStatement reports SQL after a ";" other than comments. A ";"
+// inside a string literal counts too, which errs on the safe side.
+func hasSecondStatement(query string) bool {
+	_, rest, ok := strings.Cut(query, ";")
+	if !ok {
+		return false
+	}
+	for {
+		rest = strings.TrimSpace(rest)
+		switch {
+		case rest == "":
+			return false
+		case strings.HasPrefix(rest, "--"):
+			_, rest, _ = strings.Cut(rest, "\n")
+		case strings.HasPrefix(rest, "/*"):
+			_, rest, _ = strings.Cut(rest, "*/")
+		case strings.HasPrefix(rest, ";"):
+			rest = rest[1:]
+		default:
+			return true
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ReadinessReport says whether the app should get traffic, and why not
+type ReadinessReport struct {
+	Ready             bool      `json:"ready"`
+	Reason            string    `json:"reason,omitempty"`
+	Schema            string    `json:"schema"`                       // "current", "outdated" or "unknown"
+	MissingTables     []string  `json:"missing_tables,omitempty"`     // Tables from schema.sql the database lacks
+	Migration         int64     `json:"migration"`                    // Newest migration the database has applied, 0 for none
+	PendingMigrations []int64   `json:"pending_migrations,omitempty"` // Embedded migrations it hasn't
+	Pool              PoolUsage `json:"pool"`
+	Breaker           string    `json:"breaker"`                  // "closed", "open", "half-open" or "disabled"
+	WriteDegraded     bool      `json:"write_degraded,omitempty"` // Writes paused after a storage error; reads still work, so still ready
+
+	// The breakers of Config.QueryPolicies, by Match. They guard only
+	// their own queries, so an open one doesn't fail readiness.
+	QueryBreakers map[string]string `json:"query_breakers,omitempty"`
+}
+
+// PoolUsage is how busy the connection pool is
+type PoolUsage struct {
+	Open      int   `json:"open"`
+	InUse     int   `json:"in_use"`
+	MaxOpen   int   `json:"max_open"`            // 0 = unlimited
+	WaitCount int64 `json:"wait_count"`          // Total waits for a free connection
+	Saturated bool  `json:"saturated,omitempty"` // Every allowed connection is in use
+}
+
+// Liveness fails only when the database layer is wedged: a ping that
+// doesn't come back before ctx ends. An unreachable database is not a
+// liveness failure, since restarting the process wouldn't fix it; that's
+// what Readiness is for.
+func (db *DB) Liveness(ctx context.Context) error {
+	err := db.Conn.PingContext(ctx)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("database ping did not return: %w", ctx.Err())
+	}
+	return nil
+}
+
+// Readiness checks everything that should take the app out of a load
+// balancer: shutdown drain, an open circuit breaker, a health monitor that
+// sees the database Down, an unreachable database, and a schema behind the
+// build: tables missing from schema.sql or migrations not yet applied.
+// Pool saturation is reported but doesn't fail readiness.
+func (db *DB) Readiness(ctx context.Context) ReadinessReport {
+	stats := db.Conn.Stats()
+	r := ReadinessReport{
+		Ready:         true,
+		Schema:        "unknown",
+		Breaker:       db.breaker.current(),
+		WriteDegraded: db.WriteDegraded(),
+		QueryBreakers: db.policies.breakers(),
+		Pool: PoolUsage{
+			Open:      stats.OpenConnections,
+			InUse:     stats.InUse,
+			MaxOpen:   stats.MaxOpenConnections,
+			WaitCount: stats.WaitCount,
+			Saturated: stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections,
+		},
+	}
+	notReady := func(reason string) ReadinessReport {
+		r.Ready, r.Reason = false, reason
+		return r
+	}
+
+	if db.draining.Load() {
+		return notReady("shutting down")
+	}
+	if r.Breaker == "open" {
+		return notReady("circuit breaker is open")
+	}
+	if h := db.HealthState(); h.Status == Down {
+		return notReady(fmt.Sprintf("database is down: %v", h.LastError))
+	}
+
+	d := defaultDialect()
+	if d.listTables == nil {
+		if err := db.Conn.PingContext(ctx); err != nil {
+			return notReady(fmt.Sprintf("database is unreachable: %v", err))
+		}
+		return r
+	}
+
+	have, err := d.listTables(ctx, db.Conn)
+	if err != nil {
+		return notReady(fmt.Sprintf("database is unreachable: %v", err))
+	}
+	for table := range schemaTables(d.schema) {
+		if !slices.Contains(have, table) {
+			r.MissingTables = append(r.MissingTables, table)
+		}
+	}
+	if len(r.MissingTables) > 0 {
+		slices.Sort(r.MissingTables)
+		r.Schema = "outdated"
+		return notReady("schema is outdated, missing " + strings.Join(r.MissingTables, ", "))
+	}
+
+	migrations, err := loadMigrations(d.migrations)
+	if err != nil {
+		return notReady(fmt.Sprintf("failed to read the embedded migrations: %v", err))
+	}
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return notReady(fmt.Sprintf("failed to read schema_migrations: %v", err))
+	}
+	for v := range applied {
+		r.Migration = max(r.Migration, v)
+	}
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			r.PendingMigrations = append(r.PendingMigrations, m.Version)
+		}
+	}
+	if len(r.PendingMigrations) > 0 {
+		r.Schema = "outdated"
+		return notReady(fmt.Sprintf("schema is outdated, %d migrations pending", len(r.PendingMigrations)))
+	}
+	r.Schema = "current"
+	return r
+}
+
+// Drain marks the database not ready, so load balancers stop sending new
+// requests while in-flight ones finish. Queries keep working; Close also
+// drains.
+func (db *DB) Drain() {
+	db.draining.Store(true)
+}
+
+// HealthHandler serves liveness on paths ending in "/livez" and readiness
+// everywhere else, as 200 or 503 with a small JSON body:
+//
+//	mux.Handle("/livez", database.HealthHandler(db))
+//	mux.Handle("/readyz", database.HealthHandler(db))
+func HealthHandler(db *DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.HasSuffix(r.URL.Path, "/livez") {
+			if err := db.Liveness(ctx); err != nil {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				json.NewEncoder(w).Encode(map[string]any{"live": false, "reason": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"live": true})
+			return
+		}
+
+		report := db.Readiness(ctx)
+		if !report.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
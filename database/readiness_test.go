@@ -0,0 +1,85 @@
+package database_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// probe gets path from HealthHandler and decodes the JSON body
+func probe(t *testing.T, db *database.DB, path string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	database.HealthHandler(db).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s: %v in %q", path, err, rec.Body)
+	}
+	return rec.Code, body
+}
+
+func TestReadiness(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ready", func(t *testing.T) {
+		db := dbtest.NewTestDB(t)
+		r := db.Readiness(ctx)
+		if !r.Ready || r.Schema != "current" || r.Migration == 0 || r.Breaker == "" || r.Pool.Open == 0 {
+			t.Errorf("a fresh database: %+v, want ready with a current schema", r)
+		}
+		if code, body := probe(t, db, "/readyz"); code != http.StatusOK || body["ready"] != true {
+			t.Errorf("/readyz: %d %v, want 200", code, body)
+		}
+		if code, body := probe(t, db, "/livez"); code != http.StatusOK || body["live"] != true {
+			t.Errorf("/livez: %d %v, want 200", code, body)
+		}
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		db := dbtest.NewTestDB(t)
+		db.Drain()
+		if r := db.Readiness(ctx); r.Ready || r.Reason != "shutting down" {
+			t.Errorf("after Drain: %+v, want not ready", r)
+		}
+		if code, _ := probe(t, db, "/readyz"); code != http.StatusServiceUnavailable {
+			t.Errorf("/readyz after Drain: %d, want 503", code)
+		}
+		// Draining isn't being wedged
+		if code, _ := probe(t, db, "/livez"); code != http.StatusOK {
+			t.Errorf("/livez after Drain: %d, want 200", code)
+		}
+	})
+
+	t.Run("schema outdated", func(t *testing.T) {
+		db := dbtest.NewTestDB(t)
+		if _, err := db.DBTX().ExecContext(ctx, "DROP TABLE attachments"); err != nil {
+			t.Fatal(err)
+		}
+		r := db.Readiness(ctx)
+		if r.Ready || r.Schema != "outdated" || len(r.MissingTables) != 1 || r.MissingTables[0] != "attachments" {
+			t.Errorf("a table missing: %+v, want not ready, missing attachments", r)
+		}
+		code, body := probe(t, db, "/healthz")
+		if code != http.StatusServiceUnavailable || !strings.Contains(body["reason"].(string), "attachments") {
+			t.Errorf("/healthz with a table missing: %d %v, want 503 naming it", code, body)
+		}
+	})
+
+	t.Run("migration pending", func(t *testing.T) {
+		db := dbtest.NewTestDB(t)
+		latest := db.Readiness(ctx).Migration
+		if _, err := db.DBTX().ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = (SELECT max(version) FROM schema_migrations)"); err != nil {
+			t.Fatal(err)
+		}
+		r := db.Readiness(ctx)
+		if r.Ready || r.Schema != "outdated" || len(r.PendingMigrations) != 1 || r.PendingMigrations[0] != latest {
+			t.Errorf("migration %d not applied: %+v, want not ready with it pending", latest, r)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// readOnlyDBTX is in front of every statement of a DB opened with
+// Config.ReadOnly. It refuses writes and schema changes by their first
+// keyword, with ErrReadOnly, before they reach the database, which is
+// there to refuse what it can't tell: a write inside a WITH, or a
+// function that writes.
+type readOnlyDBTX struct {
+	DBTX
+}
+
+// isMutatingQuery is true for statements that change rows or the schema
+func isMutatingQuery(query string) bool {
+	if isWriteQuery(query) {
+		return true
+	}
+	switch firstKeyword(query) {
+	case "MERGE", "CREATE", "DROP", "ALTER", "TRUNCATE", "VACUUM", "REINDEX", "GRANT", "REVOKE":
+		return true
+	}
+	return false
+}
+
+func readOnlyError(query string) error {
+	if name := queryName(query); name != "other" {
+		return fmt.Errorf("%w: refused %s", ErrReadOnly, name)
+	}
+	return fmt.Errorf("%w: refused %s statement", ErrReadOnly, firstKeyword(query))
+}
+
+func (d readOnlyDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if isMutatingQuery(query) {
+		return nil, readOnlyError(query)
+	}
+	return d.DBTX.ExecContext(ctx, query, args...)
+}
+
+func (d readOnlyDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if isMutatingQuery(query) {
+		return nil, readOnlyError(query)
+	}
+	return d.DBTX.QueryContext(ctx, query, args...)
+}
+
+func (d readOnlyDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if isMutatingQuery(query) {
+		return errRow(ctx, readOnlyError(query))
+	}
+	return d.DBTX.QueryRowContext(ctx, query, args...)
+}
+
+// ReadOnly reports whether db was opened with Config.ReadOnly or
+// WithReadOnly
+func (db *DB) ReadOnly() bool {
+	return db.readOnly
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"sync/atomic"
+)
+
+// replicaPool spreads the reads made through db.Q outside a transaction
+// over the read replicas, round robin. Writes and transactions stay on
+// the primary.
+type replicaPool struct {
+	conns []*sql.DB
+	owned []*DB // Opened from Config.ReadDSNs, closed with the primary
+	next  atomic.Uint32
+}
+
+type primaryReadsKey struct{}
+
+// ContextWithPrimaryReads returns ctx for reads that have to see the
+// primary's latest writes, such as the read right after a write that
+// redirects to the new row. Replicas may lag behind the primary for a
+// moment; PostgreSQL streaming replication usually does, by milliseconds.
+func ContextWithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// openReplicas opens cfg.ReadDSNs with cfg's other settings. They're only
+// queried, so they skip migrations and everything that writes.
+func openReplicas(ctx context.Context, cfg Config) (*replicaPool, error) {
+	if len(cfg.ReadDSNs) == 0 {
+		return nil, nil
+	}
+
+	rc := cfg
+	rc.ReadDSNs, rc.SkipMigrations, rc.ShadowDSN, rc.Backup, rc.AuditRetention, rc.Maintenance, rc.Retention, rc.Replication = nil, true, "", BackupSchedule{}, 0, MaintenanceSchedule{}, RetentionPolicy{}, ReplicationConfig{}
+	rc.JournalMode = ""    // The primary's to set, a replica of the same file has it already
+	rc.SchemaCheck = "off" // Likewise the schema, and a PostgreSQL standby can't build the scratch one
+	rc.SlowQueries, rc.QueryLatency, rc.LogQueries, rc.QueryLogger, rc.TraceQueries, rc.Hooks = 0, false, false, nil, false, nil
+
+	p := &replicaPool{}
+	for _, dsn := range cfg.ReadDSNs {
+		rc.DSN = dsn
+		r, err := OpenContext(ctx, rc)
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+		p.conns = append(p.conns, r.Conn)
+		p.owned = append(p.owned, r)
+	}
+	return p, nil
+}
+
+func (p *replicaPool) close() {
+	if p == nil {
+		return
+	}
+	for _, r := range p.owned {
+		r.Close()
+	}
+}
+
+// Words that make a SELECT or WITH statement write or lock rows, which a
+// replica refuses
+var writeWordRe = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|REPLACE|FOR\s+SHARE)\b`)
+
+// replica returns the connection a query goes to, nil for the primary
+func (p *replicaPool) replica(ctx context.Context, query string) *sql.DB {
+	if p == nil || len(p.conns) == 0 || ctx.Value(primaryReadsKey{}) != nil {
+		return nil
+	}
+	if kw := firstKeyword(query); kw != "SELECT" && kw != "WITH" || writeWordRe.MatchString(query) {
+		return nil
+	}
+	return p.conns[p.next.Add(1)%uint32(len(p.conns))]
+}
+
+// replicaDBTX sends reads to the replicas, see replicaPool
+type replicaDBTX struct {
+	DBTX
+	pool *replicaPool
+}
+
+func (d *replicaDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if r := d.pool.replica(ctx, query); r != nil {
+		return r.QueryContext(ctx, query, args...)
+	}
+	return d.DBTX.QueryContext(ctx, query, args...)
+}
+
+func (d *replicaDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if r := d.pool.replica(ctx, query); r != nil {
+		return r.QueryRowContext(ctx, query, args...)
+	}
+	return d.DBTX.QueryRowContext(ctx, query, args...)
+}
+
+// routeReads is conn with the replicas in front, for db.Q
+func (db *DB) routeReads(conn *sql.DB) DBTX {
+	if db.replicas == nil {
+		return conn
+	}
+	return &replicaDBTX{DBTX: conn, pool: db.replicas}
+}
@@ -0,0 +1,417 @@
+package database
+
+import (
+	"cmp"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How Config.Redaction shows an argument
+const (
+	RedactAll    = "all"    // As <redacted>
+	RedactLength = "length" // Strings and bytes reduced to their length, numbers, times and NULL as they are
+	RedactNone   = "none"   // As it is
+)
+
+// RedactionPolicy decides what the query log, the slow query log and the
+// OpenTelemetry spans show of a statement's arguments, which may hold
+// personal data. Default applies to the arguments no rule names; Mask
+// names arguments that are always masked, and Show ones that are shown as
+// they are unless Mask names them too. Query hooks get the arguments as
+// they are either way.
+//
+//	redaction:
+//	  default: length
+//	  mask:
+//	    - column: email
+//	    - query: SetUserNationalID
+//	  show:
+//	    - column: telegram_id
+type RedactionPolicy struct {
+	Default string       `config:"default"` // RedactAll (the default), RedactLength or RedactNone
+	Mask    []RedactRule `config:"mask"`
+	Show    []RedactRule `config:"show"`
+}
+
+// RedactRule names arguments by any of its fields, all of the ones set:
+// the query's, the column the argument is compared with or stored in,
+// its position. The column is read from the SQL around the placeholder
+// (email = ?, lower(email) = lower(?), INSERT INTO users (email) VALUES
+// (?), LIMIT ?), so an argument in an expression it can't tell, such as
+// a row comparison, has none and only a rule without Column names it.
+type RedactRule struct {
+	Query  string `config:"query"`  // Query name as in Querier
+	Column string `config:"column"` // email, or limit and offset for those clauses
+	Param  int    `config:"param"`  // Position of the argument, 1 for the first
+}
+
+func (p RedactionPolicy) validate() error {
+	var errs []error
+	switch p.Default {
+	case "", RedactAll, RedactLength, RedactNone:
+	default:
+		errs = append(errs, fmt.Errorf("unknown redaction.default %q (want %s, %s or %s)", p.Default, RedactAll, RedactLength, RedactNone))
+	}
+	for _, rules := range []struct {
+		key   string
+		rules []RedactRule
+	}{{"mask", p.Mask}, {"show", p.Show}} {
+		for _, r := range rules.rules {
+			if _, ok := querySQL[r.Query]; r.Query != "" && !ok {
+				errs = append(errs, fmt.Errorf("redaction.%s: unknown query %q", rules.key, r.Query))
+			}
+			if r.Param < 0 {
+				errs = append(errs, fmt.Errorf("redaction.%s: param can't be negative", rules.key))
+			}
+			if r == (RedactRule{}) {
+				errs = append(errs, fmt.Errorf("redaction.%s: a rule needs a query, column or param", rules.key))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Statements whose parameter columns redactor keeps; the generated
+// queries take a fraction of it, the rest is for SQL built at run time
+const maxRedactColumns = 1000
+
+// redactor applies a RedactionPolicy
+type redactor struct {
+	policy  RedactionPolicy
+	columns bool // Some rule has a Column, so the SQL is parsed
+
+	mu    sync.Mutex
+	cache map[string][]string // Statement to its parameters' columns
+}
+
+func newRedactor(p RedactionPolicy) *redactor {
+	r := &redactor{policy: p, cache: make(map[string][]string)}
+	for _, rule := range append(p.Mask, p.Show...) {
+		r.columns = r.columns || rule.Column != ""
+	}
+	return r
+}
+
+// render shows args as the policy says, with def in place of its Default
+// if set
+func (r *redactor) render(query string, args []any, def string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	modes := r.modes(query, len(args), def)
+	out := make([]string, len(args))
+	for i, a := range args {
+		out[i] = showArg(a, modes[i])
+	}
+	return out
+}
+
+// modes is how to show each of n arguments of query
+func (r *redactor) modes(query string, n int, def string) []string {
+	name := queryName(query)
+	var cols []string
+	if r.columns {
+		cols = r.paramColumns(query)
+	}
+	def = cmp.Or(def, r.policy.Default, RedactAll)
+
+	modes := make([]string, n)
+	for i := range modes {
+		col := ""
+		if i < len(cols) {
+			col = cols[i]
+		}
+		switch {
+		case redactRulesMatch(r.policy.Mask, name, col, i):
+			modes[i] = RedactAll
+		case redactRulesMatch(r.policy.Show, name, col, i):
+			modes[i] = RedactNone
+		default:
+			modes[i] = def
+		}
+	}
+	return modes
+}
+
+func redactRulesMatch(rules []RedactRule, name, col string, i int) bool {
+	for _, r := range rules {
+		if (r.Query == "" || r.Query == name) && (r.Column == "" || strings.EqualFold(r.Column, col)) && (r.Param == 0 || r.Param == i+1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) paramColumns(query string) []string {
+	r.mu.Lock()
+	cols, ok := r.cache[query]
+	r.mu.Unlock()
+	if ok {
+		return cols
+	}
+	cols = paramColumns(query)
+	r.mu.Lock()
+	if len(r.cache) < maxRedactColumns {
+		r.cache[query] = cols
+	}
+	r.mu.Unlock()
+	return cols
+}
+
+func showArg(v any, mode string) string {
+	switch mode {
+	case RedactAll:
+		return "<redacted>"
+	case RedactLength:
+		return redactArg(v)
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return "<invalid>"
+		}
+	}
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []byte:
+		return fmt.Sprintf("%x", v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// paramColumns reads the column of each parameter of query from the SQL
+// around its placeholder, "" where it can't tell. ? placeholders are
+// numbered in order, $N and ?N by N.
+func paramColumns(query string) []string {
+	toks := sqlTokens(query)
+	var cols []string
+	set := func(i int, col string) {
+		for len(cols) <= i {
+			cols = append(cols, "")
+		}
+		if cols[i] == "" {
+			cols[i] = col
+		}
+	}
+
+	var insertCols []string // Of the INSERT being read
+	inValues, inTuple := false, false
+	depth, tupleDepth, pos, next := 0, 0, 0, 0
+	for i, t := range toks {
+		switch t {
+		case "(":
+			depth++
+			if i >= 2 && strings.EqualFold(toks[i-2], "INTO") {
+				insertCols = insertCols[:0]
+				for _, c := range toks[i+1:] {
+					if c == ")" {
+						break
+					}
+					if c != "," {
+						insertCols = append(insertCols, unqualified(c))
+					}
+				}
+			}
+			if i >= 1 && (strings.EqualFold(toks[i-1], "VALUES") || (inValues && !inTuple && toks[i-1] == ",")) {
+				inValues, inTuple, tupleDepth, pos = true, true, depth, 0
+			}
+			continue
+		case ")":
+			if inTuple && depth == tupleDepth {
+				inTuple = false
+			}
+			depth--
+			continue
+		case ",":
+			if inTuple && depth == tupleDepth {
+				pos++
+			}
+			continue
+		}
+
+		n, ok := placeholder(t, &next)
+		switch {
+		case !ok:
+			if inValues && !inTuple && !strings.EqualFold(t, "VALUES") {
+				inValues = false // ON CONFLICT, RETURNING, ...
+			}
+		case inTuple:
+			if pos < len(insertCols) {
+				set(n, insertCols[pos])
+			}
+		default:
+			set(n, columnBefore(toks, i))
+		}
+	}
+	return cols
+}
+
+// placeholder reports the parameter t stands for, counting bare ? with
+// next
+func placeholder(t string, next *int) (int, bool) {
+	switch {
+	case t == "?":
+		*next++
+		return *next - 1, true
+	case len(t) > 1 && (t[0] == '$' || t[0] == '?'):
+		if n, err := strconv.Atoi(t[1:]); err == nil && n > 0 {
+			return n - 1, true
+		}
+	}
+	return 0, false
+}
+
+// columnBefore is the column compared with, added to or limited by the
+// placeholder at toks[i]: email in "email = ?", "u.email LIKE ?",
+// "id IN (?, ?)", "lower(email) = lower(?)", limit in "LIMIT ?"
+func columnBefore(toks []string, i int) string {
+	var next int
+	for i >= 2 && toks[i-1] == "," {
+		if _, ok := placeholder(toks[i-2], &next); !ok {
+			break
+		}
+		i -= 2
+	}
+	// In a function call or a CAST, the call stands for the placeholder
+	for i >= 2 && toks[i-1] == "(" && !strings.EqualFold(toks[i-2], "IN") && identAt(toks, i-2) != "" {
+		i -= 2
+	}
+	if i < 1 {
+		return ""
+	}
+	prev := strings.ToUpper(toks[i-1])
+	switch prev {
+	case "LIMIT", "OFFSET":
+		return strings.ToLower(prev)
+	case "(":
+		if i >= 3 && strings.EqualFold(toks[i-2], "IN") {
+			return identAt(toks, i-3)
+		}
+		return ""
+	case "=", "==", "<>", "!=", "<", ">", "<=", ">=", "+", "-", "*", "/", "||", "LIKE", "ILIKE", "GLOB", "REGEXP", "IS":
+		j := i - 2
+		if j >= 0 && strings.EqualFold(toks[j], "NOT") {
+			j--
+		}
+		return operandAt(toks, j)
+	}
+	return ""
+}
+
+// operandAt is the column of the operand ending at toks[j]: a column, or
+// the last one in the parentheses it closes, as in lower(email)
+func operandAt(toks []string, j int) string {
+	for j >= 2 && toks[j-1] == "::" {
+		j -= 2 // email::text
+	}
+	if j < 0 || toks[j] != ")" {
+		return identAt(toks, j)
+	}
+	for k := j - 1; k >= 0 && toks[k] != "("; k-- {
+		if col := identAt(toks, k); col != "" {
+			return col
+		}
+	}
+	return ""
+}
+
+// identAt is toks[j] without its table, if it's a column name and not a
+// type cast to
+func identAt(toks []string, j int) string {
+	if j < 0 || (j >= 1 && (toks[j-1] == "::" || strings.EqualFold(toks[j-1], "AS"))) {
+		return ""
+	}
+	t := toks[j]
+	if c := t[0]; c != '_' && c != '"' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+		return ""
+	}
+	switch strings.ToUpper(t) {
+	case "AND", "OR", "NOT", "WHERE", "SET", "ON", "WHEN", "THEN", "ELSE", "CASE", "END", "SELECT", "FROM", "AS", "IN", "IS", "NULL":
+		return ""
+	}
+	return unqualified(t)
+}
+
+func unqualified(ident string) string {
+	if i := strings.LastIndexByte(ident, '.'); i >= 0 {
+		ident = ident[i+1:]
+	}
+	return strings.ToLower(strings.Trim(ident, `"`))
+}
+
+// sqlTokens splits query into identifiers (dots included), placeholders,
+// numbers and operators, dropping comments and string literals
+func sqlTokens(query string) []string {
+	var toks []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(query[i:], "--"):
+			if j := strings.IndexByte(query[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(query)
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+				i += j + 4
+			} else {
+				i = len(query)
+			}
+		case c == '\'':
+			j := i + 1
+			for j < len(query) && (query[j] != '\'' || strings.HasPrefix(query[j:], "''")) {
+				if query[j] == '\'' {
+					j++
+				}
+				j++
+			}
+			toks = append(toks, "''")
+			i = j + 1
+		case c == '?' || c == '$' || c == '"' || c == '_' || c == '.' || (c >= '0' && c <= '9') || (c|0x20 >= 'a' && c|0x20 <= 'z'):
+			j := i + 1
+			if c == '"' {
+				for j < len(query) && query[j] != '"' {
+					j++
+				}
+				j++
+			}
+			for j < len(query) {
+				d := query[j]
+				if d == '_' || d == '.' || d == '"' || (d >= '0' && d <= '9') || (d|0x20 >= 'a' && d|0x20 <= 'z') {
+					j++
+					continue
+				}
+				break
+			}
+			j = min(j, len(query))
+			toks = append(toks, query[i:j])
+			i = j
+		default:
+			if i+1 < len(query) {
+				switch two := query[i : i+2]; two {
+				case "<=", ">=", "<>", "!=", "==", "||", "::":
+					toks = append(toks, two)
+					i += 2
+					continue
+				}
+			}
+			toks = append(toks, string(c))
+			i++
+		}
+	}
+	return toks
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// DefaultName is the registry name used by Init, Get and Close
+const DefaultName = "default"
+
+// ErrNotInitialized is returned by GetNamed for unknown names
+var ErrNotInitialized = errors.New("database not initialized")
+
+type registryEntry struct {
+	db  *DB
+	cfg Config
+}
+
+// Registry of named instances, in the order they were initialized
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*registryEntry{}
+	order      []string
+)
+
+// Init opens the default database and registers it for Get. It's a thin
+// wrapper over Open for apps that want one global instance.
+func Init(cfg Config) (*DB, error) {
+	return InitNamedContext(context.Background(), DefaultName, cfg)
+}
+
+// InitContext is Init giving up once ctx ends, like OpenContext
+func InitContext(ctx context.Context, cfg Config) (*DB, error) {
+	return InitNamedContext(ctx, DefaultName, cfg)
+}
+
+// InitNamed initializes a named database. Calling it again for the same
+// name returns the existing instance if the config matches, otherwise an error.
+func InitNamed(name string, cfg Config) (*DB, error) {
+	return InitNamedContext(context.Background(), name, cfg)
+}
+
+// InitNamedContext is InitNamed giving up once ctx ends. Other Init and
+// Get calls wait while it connects.
+func InitNamedContext(ctx context.Context, name string, cfg Config) (*DB, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if e, ok := registry[name]; ok {
+		if !sameConfig(e.cfg, cfg) {
+			return nil, fmt.Errorf("database %q already initialized with a different config", name)
+		}
+		return e.db, nil
+	}
+
+	db, err := OpenContext(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	registry[name] = &registryEntry{db: db, cfg: cfg}
+	order = append(order, name)
+	return db, nil
+}
+
+// MustInit initializes the database and panics on error
+func MustInit(cfg Config) *DB {
+	db, err := Init(cfg)
+	if err != nil {
+		log.Fatalf("Database initialization failed: %v", err)
+	}
+	return db
+}
+
+// Get returns the default database instance
+func Get() *DB {
+	db, err := GetNamed(DefaultName)
+	if err != nil {
+		log.Fatal("Database not initialized. Call Init() first.")
+	}
+	return db
+}
+
+// GetNamed returns the database registered under name
+func GetNamed(name string) (*DB, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	e, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNotInitialized, name)
+	}
+	return e.db, nil
+}
+
+// Close closes the default database connection
+func Close() error {
+	return CloseNamed(DefaultName)
+}
+
+// CloseNamed closes the named database and removes it from the registry
+func CloseNamed(name string) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return closeLocked(name)
+}
+
+// CloseAll closes every registered database in reverse initialization order
+func CloseAll() error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	names := slices.Clone(order)
+	slices.Reverse(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := closeLocked(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func closeLocked(name string) error {
+	e, ok := registry[name]
+	if !ok {
+		return nil
+	}
+
+	delete(registry, name)
+	order = slices.DeleteFunc(order, func(n string) bool { return n == name })
+
+	if err := e.db.Close(); err != nil {
+		return fmt.Errorf("failed to close database %q: %w", name, err)
+	}
+	return nil
+}
+
+// sameConfig compares Configs, which can't use == or reflect.DeepEqual:
+// they hold funcs (SQLiteFuncs, Collations, Hooks), and DeepEqual counts
+// any two non-nil funcs as different, so the same config would be
+// refused. Funcs count as the same when they're the same code.
+func sameConfig(a, b Config) bool {
+	return sameValue(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func sameValue(a, b reflect.Value) bool {
+	if a.Kind() != b.Kind() || a.Type() != b.Type() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Func:
+		return a.IsNil() == b.IsNil() && (a.IsNil() || a.Pointer() == b.Pointer())
+	case reflect.Pointer:
+		if a.Pointer() == b.Pointer() {
+			return true
+		}
+		return !a.IsNil() && !b.IsNil() && sameValue(a.Elem(), b.Elem())
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return sameValue(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if !sameValue(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := range a.Len() {
+			if !sameValue(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, k := range a.MapKeys() {
+			v := b.MapIndex(k)
+			if !v.IsValid() || !sameValue(a.MapIndex(k), v) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.Equal(b)
+	}
+}
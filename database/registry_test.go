@@ -0,0 +1,135 @@
+//go:build !postgres
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNamedInstances(t *testing.T) {
+	t.Cleanup(func() { CloseAll() })
+	dir := t.TempDir()
+	hook := QueryHook{After: func(context.Context, QueryEvent) {}}
+	primaryCfg := Config{DSN: filepath.Join(dir, "primary.db"), LogLevel: "silent", Hooks: []QueryHook{hook}}
+	analyticsCfg := Config{DSN: filepath.Join(dir, "analytics.db"), LogLevel: "silent", SkipMigrations: true}
+
+	primary, err := InitNamed("primary", primaryCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analytics, err := InitNamed("analytics", analyticsCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := analytics.Conn.Exec("CREATE TABLE page_views (path TEXT NOT NULL, at INTEGER NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each has its own schema
+	for _, c := range []struct {
+		name  string
+		db    *DB
+		table string
+		want  bool
+	}{
+		{"primary", primary, "users", true},
+		{"primary", primary, "page_views", false},
+		{"analytics", analytics, "page_views", true},
+		{"analytics", analytics, "users", false},
+	} {
+		var n int
+		if err := c.db.Conn.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", c.table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if got := n == 1; got != c.want {
+			t.Errorf("%s in %s: %v, want %v", c.table, c.name, got, c.want)
+		}
+	}
+
+	if got, err := GetNamed("analytics"); err != nil || got != analytics {
+		t.Errorf("GetNamed(analytics): %p, %v; want %p", got, err, analytics)
+	}
+	if _, err := GetNamed("missing"); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("GetNamed(missing): %v, want ErrNotInitialized", err)
+	}
+
+	// The same config again, hooks and all, gets the same DB
+	again := primaryCfg
+	again.Hooks = slices.Clone(primaryCfg.Hooks)
+	if got, err := InitNamed("primary", again); err != nil || got != primary {
+		t.Errorf("InitNamed with the same config: %p, %v; want %p", got, err, primary)
+	}
+	other := primaryCfg
+	other.Hooks = []QueryHook{{After: func(context.Context, QueryEvent) { t.Log("another hook") }}}
+	if _, err := InitNamed("primary", other); err == nil || !strings.Contains(err.Error(), "different config") {
+		t.Errorf("InitNamed with another hook: %v, want an error", err)
+	}
+	other = primaryCfg
+	other.DSN = analyticsCfg.DSN
+	if _, err := InitNamed("primary", other); err == nil {
+		t.Error("InitNamed with another DSN: no error")
+	}
+}
+
+// closeRecorder is a driver whose connections note the name of their DB
+// when they close
+type closeRecorder struct {
+	name   string
+	mu     *sync.Mutex
+	closed *[]string
+}
+
+func (c closeRecorder) Connect(context.Context) (driver.Conn, error) { return recordedConn{c}, nil }
+func (c closeRecorder) Driver() driver.Driver                        { return nil }
+
+type recordedConn struct {
+	closeRecorder
+}
+
+func (c recordedConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c recordedConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c recordedConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.closed = append(*c.closed, c.name)
+	return nil
+}
+
+func TestCloseAllOrder(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		closed []string
+	)
+	names := []string{"first", "second", "third"}
+	registryMu.Lock()
+	for _, name := range names {
+		conn := sql.OpenDB(closeRecorder{name: name, mu: &mu, closed: &closed})
+		if err := conn.Ping(); err != nil { // Leaves an idle connection to close
+			t.Fatal(err)
+		}
+		registry[name] = &registryEntry{db: &DB{Conn: conn}}
+		order = append(order, name)
+	}
+	registryMu.Unlock()
+
+	if err := CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"third", "second", "first"}; !slices.Equal(closed, want) {
+		t.Errorf("closed %v, want %v: the last initialized first", closed, want)
+	}
+	for _, name := range names {
+		if _, err := GetNamed(name); !errors.Is(err, ErrNotInitialized) {
+			t.Errorf("GetNamed(%s) after CloseAll: %v, want ErrNotInitialized", name, err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package database
+
+import "database/sql"
+
+// UserWithGroups is a user together with every group they belong to
+type UserWithGroups struct {
+	User   User             `json:"user"`
+	Groups []UserGroupEntry `json:"groups"`
+}
+
+// UserGroupEntry is one group membership of a UserWithGroups
+type UserGroupEntry struct {
+	TelegramID int64            `json:"telegram_id"`
+	Title      sql.Null[string] `json:"title"`
+	Balance    sql.Null[Money]  `json:"balance"`
+}
+
+// GroupGroupsByUser folds the flat ListUsersWithGroups rows into one entry
+// per user, keeping the order users first appear in. Users without groups
+// get an empty (non-nil) Groups slice.
+func GroupGroupsByUser(rows []ListUsersWithGroupsRow) []UserWithGroups {
+	out := []UserWithGroups{}
+	index := map[int64]int{}
+
+	for _, r := range rows {
+		i, ok := index[r.User.ID]
+		if !ok {
+			i = len(out)
+			index[r.User.ID] = i
+			out = append(out, UserWithGroups{User: r.User, Groups: []UserGroupEntry{}})
+		}
+
+		// LEFT JOIN miss: the user has no groups
+		if !r.GroupTelegramID.Valid {
+			continue
+		}
+		out[i].Groups = append(out[i].Groups, UserGroupEntry{
+			TelegramID: r.GroupTelegramID.V,
+			Title:      r.GroupTitle,
+			Balance:    r.GroupBalance,
+		})
+	}
+
+	return out
+}
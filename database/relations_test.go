@@ -0,0 +1,139 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+	"your-project/database/nulls"
+)
+
+// newMembers seeds users 1 to 3, groups 10 and 20, and makes user 1 a
+// member of both groups and user 3 of group 20; user 2 has none
+func newMembers(t testing.TB, db *database.DB) {
+	t.Helper()
+	ctx := context.Background()
+	for _, id := range []int64{1, 2, 3} {
+		if _, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: id, FirstName: "user"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []int64{10, 20} {
+		if _, err := db.Q.CreateGroup(ctx, database.CreateGroupParams{TelegramID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range [][2]int64{{1, 10}, {1, 20}, {3, 20}} {
+		if _, err := db.Q.CreateUserGroup(ctx, database.CreateUserGroupParams{UserTelegramID: m[0], GroupTelegramID: m[1]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUsersWithGroups(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	newMembers(t, db)
+
+	rows, err := db.Q.ListUsersWithGroups(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := database.GroupGroupsByUser(rows)
+
+	want := map[int64][]int64{1: {10, 20}, 2: {}, 3: {20}}
+	var order []int64
+	for _, u := range users {
+		order = append(order, u.User.TelegramID)
+		var groups []int64
+		for _, g := range u.Groups {
+			groups = append(groups, g.TelegramID)
+		}
+		if u.Groups == nil || !slices.Equal(groups, want[u.User.TelegramID]) {
+			t.Errorf("user %d in groups %v (nil: %v), want %v", u.User.TelegramID, groups, u.Groups == nil, want[u.User.TelegramID])
+		}
+	}
+	if !slices.Equal(order, []int64{1, 2, 3}) {
+		t.Errorf("users in order %v, want 1, 2, 3", order)
+	}
+
+	members, err := db.Q.ListGroupMembers(ctx, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, m := range members {
+		ids = append(ids, m.UserTelegramID)
+		if m.FirstName != "user" {
+			t.Errorf("member %d named %q, want the user's name", m.UserTelegramID, m.FirstName)
+		}
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []int64{1, 3}) {
+		t.Errorf("group 20's members %v, want 1 and 3", ids)
+	}
+}
+
+func TestGroupGroupsByUserKeepsOrder(t *testing.T) {
+	row := func(user int64, group sql.Null[int64]) database.ListUsersWithGroupsRow {
+		return database.ListUsersWithGroupsRow{User: database.User{ID: user}, GroupTelegramID: group}
+	}
+	got := database.GroupGroupsByUser([]database.ListUsersWithGroupsRow{
+		row(5, nulls.Int64(7)),
+		row(2, sql.Null[int64]{}), // No groups
+		row(9, nulls.Int64(8)),
+		row(5, nulls.Int64(6)),
+	})
+	if len(got) != 3 || got[0].User.ID != 5 || got[1].User.ID != 2 || got[2].User.ID != 9 {
+		t.Fatalf("grouped into %+v, want users 5, 2, 9 in the order they first appear", got)
+	}
+	if len(got[0].Groups) != 2 || got[0].Groups[0].TelegramID != 7 || got[0].Groups[1].TelegramID != 6 {
+		t.Errorf("user 5 in %+v, want groups 7 then 6", got[0].Groups)
+	}
+	if got[1].Groups == nil || len(got[1].Groups) != 0 {
+		t.Errorf("user 2 in %#v, want an empty slice", got[1].Groups)
+	}
+}
+
+// BenchmarkUsersWithGroups compares the one-query join with a query per user
+func BenchmarkUsersWithGroups(b *testing.B) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(b)
+	for id := int64(1); id <= 100; id++ {
+		if _, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: id, FirstName: "user"}); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := db.Q.CreateGroup(ctx, database.CreateGroupParams{TelegramID: 1000 + id}); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := db.Q.CreateUserGroup(ctx, database.CreateUserGroupParams{UserTelegramID: id, GroupTelegramID: 1000 + id}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("join", func(b *testing.B) {
+		for b.Loop() {
+			rows, err := db.Q.ListUsersWithGroups(ctx, 100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			database.GroupGroupsByUser(rows)
+		}
+	})
+	b.Run("n+1", func(b *testing.B) {
+		for b.Loop() {
+			users, err := db.Q.ListUsersByFirstName(ctx, 100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, u := range users {
+				if _, err := db.Q.GetTopGroupsForUser(ctx, u.TelegramID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
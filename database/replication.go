@@ -0,0 +1,218 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// ReplicationConfig readies a SQLite database for a replicator that ships
+// its WAL elsewhere as it's written, such as Litestream:
+//
+//	replication:
+//	  enabled: true
+//	  external_checkpoints: true # Litestream checkpoints, the app doesn't
+//	  size_interval: 1m
+//
+// Enabled makes the settings the replicator relies on a condition of
+// Open: the WAL journal mode and synchronous = NORMAL unless Config or the
+// DSN says otherwise, but never synchronous = OFF, a database in memory or
+// ReadOnly. With ExternalCheckpoints, SQLite doesn't checkpoint on its own
+// (wal_autocheckpoint = 0), Maintain and Shutdown skip theirs, and only
+// DB.Checkpoint still checkpoints, so the replicator is the one deciding
+// when the WAL goes into the database file.
+type ReplicationConfig struct {
+	Enabled             bool          `config:"enabled"`
+	ExternalCheckpoints bool          `config:"external_checkpoints"`
+	SizeInterval        time.Duration `config:"size_interval"` // How often OnEvent gets a ReplicationSize event (0 = never)
+
+	// OnEvent is told of every checkpoint the DB makes, by DB.Checkpoint
+	// or Maintain, and of the database's size every SizeInterval. It's
+	// called from the goroutine that checkpointed, so it shouldn't block.
+	OnEvent func(ReplicationEvent) `config:"-"`
+}
+
+// The kinds of ReplicationEvent
+const (
+	ReplicationCheckpoint = "checkpoint" // A checkpoint finished, see Checkpoint
+	ReplicationSize       = "size"       // The database's size, every SizeInterval
+)
+
+// ReplicationEvent is what ReplicationConfig.OnEvent is told
+type ReplicationEvent struct {
+	Kind       string
+	At         time.Time
+	Checkpoint *CheckpointResult // For ReplicationCheckpoint, nil otherwise
+	Size       DatabaseSize      // Read right after; zero if that failed
+}
+
+// The modes of DB.Checkpoint, as PRAGMA wal_checkpoint takes them
+const (
+	CheckpointPassive  = "passive"  // Copy what it can without waiting for anyone
+	CheckpointFull     = "full"     // Wait for writers, then copy every frame
+	CheckpointRestart  = "restart"  // Full, then wait for readers, so the next write starts the WAL over
+	CheckpointTruncate = "truncate" // Restart, and truncate the WAL file to zero bytes
+)
+
+var checkpointModes = []string{CheckpointPassive, CheckpointFull, CheckpointRestart, CheckpointTruncate}
+
+// CheckpointResult is what a checkpoint did
+type CheckpointResult struct {
+	Mode     string
+	Busy     bool // A reader or writer held it up; the frames left are copied by a later one
+	Frames   int  // Frames in the WAL, -1 outside WAL mode
+	Copied   int  // Frames copied into the database file
+	Duration time.Duration
+}
+
+// detail is r as Maintain reports it
+func (r CheckpointResult) detail() string {
+	switch {
+	case r.Frames < 0:
+		return "skipped, not in WAL mode"
+	case r.Busy:
+		return fmt.Sprintf("held up by readers, %d of %d frames copied", r.Copied, r.Frames)
+	case r.Mode == CheckpointTruncate:
+		return fmt.Sprintf("%d frames copied, WAL truncated", r.Frames)
+	}
+	return fmt.Sprintf("%d frames copied", r.Copied)
+}
+
+// DatabaseSize is how much disk a SQLite database takes
+type DatabaseSize struct {
+	Bytes     int64 // The database file: pages times page size
+	FreeBytes int64 // Of Bytes, the free pages a vacuum would hand back
+	WALBytes  int64 // The -wal file, 0 without one
+}
+
+type replicationState struct {
+	onEvent  func(ReplicationEvent)
+	external bool               // ExternalCheckpoints: only DB.Checkpoint checkpoints
+	stop     context.CancelFunc // Ends the size events Config.Replication started, nil without them
+}
+
+// validateReplication reports what's wrong with c.Replication without
+// connecting; the SQLite settings it needs are sqliteCheckConfig's
+func validateReplication(c Config, d *dialect) error {
+	r := c.Replication
+	if !r.Enabled {
+		if r.ExternalCheckpoints || r.SizeInterval != 0 {
+			return errors.New("replication settings need replication.enabled")
+		}
+		return nil
+	}
+	if d.walCheckpoint == nil {
+		return fmt.Errorf("replication is for SQLite, %s ships its own WAL (streaming replication, archive_command)", d.name)
+	}
+
+	var errs []error
+	if c.ReadOnly {
+		errs = append(errs, errors.New("replication.enabled and read_only: replicate the database that's written to"))
+	}
+	if r.ExternalCheckpoints && c.Maintenance.Checkpoint > 0 {
+		errs = append(errs, errors.New("maintenance.checkpoint and replication.external_checkpoints: the replicator checkpoints"))
+	}
+	if r.SizeInterval < 0 {
+		errs = append(errs, errors.New("replication.size_interval can't be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// Checkpoint copies the WAL into the database file now, in mode (""
+// is CheckpointPassive), and tells Config.Replication.OnEvent. This is
+// the checkpoint control for replication.external_checkpoints, where
+// nothing else checkpoints; Maintain's is CheckpointTruncate. A Busy
+// result isn't an error.
+func (db *DB) Checkpoint(ctx context.Context, mode string) (CheckpointResult, error) {
+	d := defaultDialect()
+	if d.walCheckpoint == nil {
+		return CheckpointResult{}, fmt.Errorf("%s has no WAL of its own to checkpoint", d.name)
+	}
+	mode = cmp.Or(mode, CheckpointPassive)
+	if !slices.Contains(checkpointModes, mode) {
+		return CheckpointResult{}, fmt.Errorf("unknown checkpoint mode %q (want passive, full, restart or truncate)", mode)
+	}
+	return db.checkpoint(ctx, d, mode)
+}
+
+func (db *DB) checkpoint(ctx context.Context, d *dialect, mode string) (CheckpointResult, error) {
+	start := time.Now()
+	r, err := d.walCheckpoint(ctx, db.Conn, mode)
+	if err != nil {
+		return CheckpointResult{}, err
+	}
+	r.Mode, r.Duration = mode, time.Since(start)
+	db.replicationEvent(ctx, ReplicationCheckpoint, &r)
+	return r, nil
+}
+
+// replicationEvent tells OnEvent, if there is one
+func (db *DB) replicationEvent(ctx context.Context, kind string, r *CheckpointResult) {
+	if db.replication.onEvent == nil {
+		return
+	}
+	size, _ := db.DatabaseSize(ctx)
+	db.replication.onEvent(ReplicationEvent{Kind: kind, At: time.Now(), Checkpoint: r, Size: size})
+}
+
+// DatabaseSize reports the size of the database file and its WAL
+func (db *DB) DatabaseSize(ctx context.Context) (DatabaseSize, error) {
+	d := defaultDialect()
+	if d.databaseSize == nil {
+		return DatabaseSize{}, fmt.Errorf("%s has no database file to measure", d.name)
+	}
+	return d.databaseSize(ctx, db.Conn)
+}
+
+// startSizeEvents sends a ReplicationSize event every interval until ctx
+// is canceled
+func (db *DB) startSizeEvents(ctx context.Context, every time.Duration) {
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			db.replicationEvent(ctx, ReplicationSize, nil)
+		}
+	}()
+}
+
+// SnapshotTo writes a consistent copy of the whole database to w, as it
+// was when the copy began, and returns the bytes written: the base a
+// replicator of your own ships before the WAL that follows it, say to
+// S3. Writers carry on meanwhile. SQLite can only write the copy to a
+// file (VACUUM INTO), so it's made in a directory of its own under
+// os.TempDir first, which needs room for it.
+func (db *DB) SnapshotTo(ctx context.Context, w io.Writer) (int64, error) {
+	d := defaultDialect()
+	if err := backupSupported(d); err != nil {
+		return 0, err
+	}
+	dir, err := os.MkdirTemp("", "snapshot-") // 0700, since the copy holds everything
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot.db")
+	if err := d.backup(ctx, db.Conn, path); err != nil {
+		return 0, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
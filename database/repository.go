@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// The repositories split Querier by domain area, so a service can ask for
+// the few queries it uses and its tests can stub just those. Every Querier
+// is each of them: pass db.Q, or the Querier of a transaction. The mocks
+// package has a generated mock of each; regenerate it after changing one:
+//
+//go:generate go run github.com/matryer/moq@v0.5.3 -rm -out mocks/repository_mock.go -pkg mocks . UserRepository GroupRepository MembershipRepository CategoryRepository AttachmentRepository AuditRepository
+//
+// The queue, outbox, key rotation and upkeep queries are left out; the
+// packages using them take a Querier.
+
+// UserRepository is the queries on users: creating, finding, listing
+// and updating them, soft deletes, history and the national ID column
+type UserRepository interface {
+	CountUsersByStatus(ctx context.Context, status Status) (int64, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserIfMissing(ctx context.Context, arg CreateUserIfMissingParams) (User, error)
+	GetTopUsersByBalance(ctx context.Context, limit int64) ([]User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByID(ctx context.Context, id int64) (User, error)
+	GetUserByNationalIDIndex(ctx context.Context, nationalIDIndex []byte) (User, error)
+	GetUserByTelegramID(ctx context.Context, telegramID int64) (User, error)
+	GetUserHistory(ctx context.Context, id int64) ([]UserHistory, error)
+	GetUserIDRange(ctx context.Context) (GetUserIDRangeRow, error)
+	GetUserNationalID(ctx context.Context, userTelegramID int64) ([]byte, error)
+	GetUserPosition(ctx context.Context, balanceGame sql.Null[Money]) (int64, error)
+	ListUsersByCreatedAt(ctx context.Context, arg ListUsersByCreatedAtParams) ([]User, error)
+	ListUsersByFirstName(ctx context.Context, limit int64) ([]User, error)
+	ListUsersByIDs(ctx context.Context, ids []int64) ([]User, error)
+	ListUsersByStatus(ctx context.Context, arg ListUsersByStatusParams) ([]User, error)
+	ListUsersMatchingUsername(ctx context.Context, arg ListUsersMatchingUsernameParams) ([]User, error)
+	ListUsersWithGroups(ctx context.Context, limit int64) ([]ListUsersWithGroupsRow, error)
+	PurgeDeletedUsers(ctx context.Context, olderThanSeconds int64) (int64, error)
+	RestoreUser(ctx context.Context, id int64) (int64, error)
+	SampleUsers(ctx context.Context, limit int64) ([]User, error)
+	SampleUsersSeeded(ctx context.Context, arg SampleUsersSeededParams) ([]User, error)
+	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error)
+	SetUserNationalID(ctx context.Context, arg SetUserNationalIDParams) error
+	SoftDeleteUser(ctx context.Context, id int64) (int64, error)
+	UpdateStatusByIDs(ctx context.Context, arg UpdateStatusByIDsParams) (int64, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserBalanceChats(ctx context.Context, arg UpdateUserBalanceChatsParams) (User, error)
+	UpdateUserBalanceChatsIfVersion(ctx context.Context, arg UpdateUserBalanceChatsIfVersionParams) (int64, error)
+	UpdateUserIfVersion(ctx context.Context, arg UpdateUserIfVersionParams) (int64, error)
+	UpsertUser(ctx context.Context, arg UpsertUserParams) (User, error)
+	UpsertUserByEmail(ctx context.Context, arg UpsertUserByEmailParams) (User, error)
+}
+
+// GroupRepository is the queries on groups and their tags
+type GroupRepository interface {
+	AttachTag(ctx context.Context, arg AttachTagParams) error
+	CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error)
+	CreateGroupIfMissing(ctx context.Context, arg CreateGroupIfMissingParams) (Group, error)
+	DeleteGroup(ctx context.Context, telegramID int64) (Group, error)
+	DetachTag(ctx context.Context, arg DetachTagParams) error
+	GetGroupByTelegramID(ctx context.Context, telegramID int64) (Group, error)
+	GetGroupHistory(ctx context.Context, id int64) ([]GroupHistory, error)
+	ListGroupTags(ctx context.Context, groupTelegramID int64) ([]Tag, error)
+	ListGroupsByTag(ctx context.Context, arg ListGroupsByTagParams) ([]Group, error)
+	ListGroupsByTitle(ctx context.Context, limit int64) ([]Group, error)
+	ListGroupsWithAllTags(ctx context.Context, arg ListGroupsWithAllTagsParams) ([]Group, error)
+	SearchGroups(ctx context.Context, arg SearchGroupsParams) ([]SearchGroupsRow, error)
+	UpsertGroup(ctx context.Context, arg UpsertGroupParams) (Group, error)
+	UpsertTag(ctx context.Context, name string) (Tag, error)
+}
+
+// MembershipRepository is the queries on user_group: who is in which
+// group, and the balances kept per membership
+type MembershipRepository interface {
+	AddToUserGroupBalance(ctx context.Context, arg AddToUserGroupBalanceParams) (UserGroup, error)
+	CreateUserGroup(ctx context.Context, arg CreateUserGroupParams) (UserGroup, error)
+	GetOrCreateUserGroup(ctx context.Context, arg GetOrCreateUserGroupParams) (UserGroup, error)
+	GetTopGroupsForUser(ctx context.Context, userTelegramID int64) ([]GetTopGroupsForUserRow, error)
+	GetTopUsersInGroup(ctx context.Context, groupTelegramID int64) ([]GetTopUsersInGroupRow, error)
+	GetTotalGroupBalance(ctx context.Context, groupTelegramID int64) (interface{}, error)
+	GetTotalUserBalance(ctx context.Context, userTelegramID int64) (interface{}, error)
+	GetUserGroup(ctx context.Context, arg GetUserGroupParams) (UserGroup, error)
+	ListGroupMembers(ctx context.Context, groupTelegramID int64) ([]ListGroupMembersRow, error)
+	UpdateUserGroupBalance(ctx context.Context, arg UpdateUserGroupBalanceParams) (UserGroup, error)
+	UpsertUserGroupBalance(ctx context.Context, arg UpsertUserGroupBalanceParams) (UserGroup, error)
+}
+
+// CategoryRepository is the queries on the category tree
+type CategoryRepository interface {
+	CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error)
+	GetAncestors(ctx context.Context, arg GetAncestorsParams) ([]GetAncestorsRow, error)
+	GetChildCategoryByName(ctx context.Context, arg GetChildCategoryByNameParams) (Category, error)
+	GetDescendants(ctx context.Context, arg GetDescendantsParams) ([]GetDescendantsRow, error)
+	ListCategoriesByName(ctx context.Context, name string) ([]Category, error)
+	RenameCategory(ctx context.Context, arg RenameCategoryParams) (Category, error)
+	SetCategoryParent(ctx context.Context, arg SetCategoryParentParams) (Category, error)
+}
+
+// AttachmentRepository is the queries on attachments. DB.PutAttachment
+// and DB.OpenAttachment, which check sizes and stream, are on DB.
+type AttachmentRepository interface {
+	DeleteAttachment(ctx context.Context, id int64) error
+	GetAttachmentMeta(ctx context.Context, id int64) (GetAttachmentMetaRow, error)
+	PutAttachment(ctx context.Context, arg PutAttachmentParams) (PutAttachmentRow, error)
+	ReadAttachmentChunk(ctx context.Context, arg ReadAttachmentChunkParams) ([]byte, error)
+}
+
+// AuditRepository reads the audit log
+type AuditRepository interface {
+	GetAuditLog(ctx context.Context, arg GetAuditLogParams) ([]AuditLog, error)
+	ListAuditLogByActor(ctx context.Context, arg ListAuditLogByActorParams) ([]AuditLog, error)
+	ListAuditLogFieldChanges(ctx context.Context, arg ListAuditLogFieldChangesParams) ([]AuditLog, error)
+}
+
+var (
+	_ UserRepository       = Querier(nil)
+	_ GroupRepository      = Querier(nil)
+	_ MembershipRepository = Querier(nil)
+	_ CategoryRepository   = Querier(nil)
+	_ AttachmentRepository = Querier(nil)
+	_ AuditRepository      = Querier(nil)
+)
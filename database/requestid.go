@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// What the query log and SlowQueryRecord show for an ID the context
+// doesn't carry
+const noRequestID = "-"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns ctx carrying id, which the query log and
+// the slow query log attach to every statement run with it: queries on
+// db.Q and in Transaction or InTx alike, as long as fn passes the ctx on.
+// Set it once per request, in the HTTP middleware or the bot's update
+// handler.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID ContextWithRequestID put in ctx, ""
+// if there's none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// statementIDs returns the request and trace IDs to log for a statement
+// run with ctx, "-" for those ctx doesn't have. The trace ID comes from
+// an OpenTelemetry span in ctx in builds with -tags otel.
+func statementIDs(ctx context.Context) (requestID, traceID string) {
+	requestID, traceID = RequestIDFromContext(ctx), traceIDFromContext(ctx)
+	if requestID == "" {
+		requestID = noRequestID
+	}
+	if traceID == "" {
+		traceID = noRequestID
+	}
+	return requestID, traceID
+}
+
+// Config.LogQueryArgs values
+const (
+	LogArgsNone     = ""         // Arguments are left out, as they may hold personal data
+	LogArgsRedacted = "redacted" // As Config.Redaction shows them, as in SlowQueryRecord
+	LogArgsFull     = "full"     // As they are, save those Config.Redaction masks; only where nobody's data is at stake
+)
+
+// queryLog writes a line per statement, or per statement that failed or
+// took at least threshold
+type queryLog struct {
+	logger    *slog.Logger
+	args      string
+	redact    *redactor
+	threshold time.Duration
+}
+
+func newQueryLog(enabled bool, logger *slog.Logger, args string, threshold time.Duration, redact *redactor) (*queryLog, error) {
+	switch args {
+	case LogArgsNone, LogArgsRedacted, LogArgsFull:
+	default:
+		return nil, fmt.Errorf("unknown query log args mode %q (want %q, %q or %q)", args, LogArgsNone, LogArgsRedacted, LogArgsFull)
+	}
+	if !enabled && logger == nil {
+		return nil, nil
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &queryLog{logger: logger, args: args, redact: redact, threshold: threshold}, nil
+}
+
+func (l *queryLog) log(ctx context.Context, query, name string, d time.Duration, args []any, rows int64, err error) {
+	level := slog.LevelInfo
+	switch {
+	case err != nil:
+		level = slog.LevelWarn
+	case l.threshold > 0 && d < l.threshold:
+		return
+	case l.threshold > 0:
+		level = slog.LevelWarn
+	}
+
+	requestID, traceID := statementIDs(ctx)
+	attrs := []slog.Attr{
+		slog.String("query", name),
+		slog.Duration("duration", d),
+		slog.String("request_id", requestID),
+		slog.String("trace_id", traceID),
+	}
+	switch l.args {
+	case LogArgsRedacted:
+		attrs = append(attrs, slog.Any("args", l.redact.render(query, args, "")))
+	case LogArgsFull:
+		attrs = append(attrs, slog.Any("args", l.redact.render(query, args, RedactNone)))
+	}
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", rows))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.logger.LogAttrs(ctx, level, "database query", attrs...)
+}
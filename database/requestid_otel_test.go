@@ -0,0 +1,51 @@
+//go:build otel
+
+package database_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestTraceIDInQueryLogs(t *testing.T) {
+	logs := &captureHandler{}
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		c.QueryLogger = slog.New(logs)
+		c.SlowQueries = 10
+	}})
+	logs.take()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+	err := db.Transaction(ctx, func(q *database.Queries) error {
+		_, err := q.CountUsersByStatus(ctx, database.StatusActive)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := logs.take()
+	if len(records) == 0 {
+		t.Fatal("nothing logged")
+	}
+	for _, r := range records {
+		if r["trace_id"] != traceID.String() || r["request_id"] != "-" {
+			t.Errorf("%s logged with trace_id %q and request_id %q, want the span's and -", r["query"], r["trace_id"], r["request_id"])
+		}
+	}
+	for _, rec := range db.SlowQueries() {
+		if rec.Query == "CountUsersByStatus" && rec.TraceID != traceID.String() {
+			t.Errorf("slow query record trace ID %q, want the span's", rec.TraceID)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package database_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// captureHandler keeps the records logged through it
+type captureHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *captureHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r.Clone())
+	return nil
+}
+
+// take returns the records so far, each as its attributes, and forgets them
+func (h *captureHandler) take() []map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []map[string]string
+	for _, r := range h.records {
+		attrs := map[string]string{}
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value.String()
+			return true
+		})
+		out = append(out, attrs)
+	}
+	h.records = nil
+	return out
+}
+
+func TestRequestIDInQueryLogs(t *testing.T) {
+	logs := &captureHandler{}
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		c.QueryLogger = slog.New(logs)
+		c.SlowQueries = 100
+	}})
+	logs.take() // Open's own statements
+	ctx := database.ContextWithRequestID(context.Background(), "req-1")
+
+	if _, err := db.Q.CountUsersByStatus(ctx, database.StatusActive); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Transaction(ctx, func(q *database.Queries) error {
+		if _, err := q.CreateUser(ctx, database.CreateUserParams{TelegramID: 1, FirstName: "user"}); err != nil {
+			return err
+		}
+		_, err := q.GetUserByTelegramID(ctx, 1)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		_, err := tx.GetUserByTelegramID(ctx, 1)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]int{}
+	for _, r := range logs.take() {
+		seen[r["query"]]++
+		if r["request_id"] != "req-1" || r["trace_id"] != "-" {
+			t.Errorf("%s logged with request_id %q and trace_id %q, want req-1 and -", r["query"], r["request_id"], r["trace_id"])
+		}
+	}
+	if seen["CountUsersByStatus"] != 1 || seen["CreateUser"] != 1 || seen["GetUserByTelegramID"] != 2 {
+		t.Errorf("logged %v, want every statement", seen)
+	}
+
+	if _, err := db.Q.CountUsersByStatus(context.Background(), database.StatusActive); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range logs.take() {
+		if r["request_id"] != "-" {
+			t.Errorf("without an ID: request_id %q, want -", r["request_id"])
+		}
+	}
+
+	ids := map[string]int{}
+	for _, rec := range db.SlowQueries() {
+		if rec.Query == "CountUsersByStatus" || rec.Query == "CreateUser" || rec.Query == "GetUserByTelegramID" {
+			ids[rec.RequestID]++
+		}
+		if rec.TraceID != "-" {
+			t.Errorf("%s recorded trace ID %q, want -", rec.Query, rec.TraceID)
+		}
+	}
+	if ids["req-1"] != 4 || ids["-"] != 1 {
+		t.Errorf("slow query records by request ID %v, want 4 for req-1 and 1 without", ids)
+	}
+}
@@ -0,0 +1,288 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy keeps tables that only grow, such as audit_log or your
+// own event tables, to the rows still worth having: each rule deletes the
+// rows older than its age, or moves them to an archive table first.
+//
+//	retention:
+//	  interval: 1h
+//	  archive_db: /var/lib/app/archive.db # SQLite, attached as "archive"
+//	  rules:
+//	    - table: audit_log
+//	      column: changed_at
+//	      older_than: 2160h # 90 days
+//	      archive_to: archive.audit_log
+//	    - table: outbox
+//	      column: delivered_at # NULL until delivered
+//	      older_than: 168h
+type RetentionPolicy struct {
+	Interval  time.Duration   `config:"interval"`   // How often Open's loop applies the rules (0 = hourly)
+	ArchiveDB string          `config:"archive_db"` // SQLite: database file attached as "archive" while the rules run, created if missing
+	Rules     []RetentionRule `config:"rules"`
+}
+
+func (p RetentionPolicy) enabled() bool {
+	return len(p.Rules) > 0
+}
+
+// RetentionRule is what to prune from one table. Rows are taken oldest
+// key first, BatchSize at a time, each batch in a transaction of its own,
+// so other writers wait at most one batch. An archive table that doesn't
+// exist is made with the table's columns; a column added to the table
+// later has to be added to the archive too.
+type RetentionRule struct {
+	Table     string        `config:"table"`
+	Column    string        `config:"column"`     // Timestamp the age is measured by, rows where it's NULL stay (default created_at)
+	Key       string        `config:"key"`        // Unique, increasing column to batch by (default id)
+	OlderThan time.Duration `config:"older_than"` // Rows whose Column is older than this go
+	ArchiveTo string        `config:"archive_to"` // Table to move them to, "" to only delete them; schema.table for an attached database or PostgreSQL schema
+	BatchSize int           `config:"batch_size"` // Rows per transaction (default 1000)
+}
+
+// RetentionResult is what one rule did in an ApplyRetention run
+type RetentionResult struct {
+	Table    string
+	Rows     int64  // Rows deleted, or moved to Archive
+	Archive  string // The rule's ArchiveTo
+	Duration time.Duration
+	Err      error
+}
+
+const (
+	retentionInterval = time.Hour
+	retentionBatch    = 1000
+	retentionProgress = 30 * time.Second // How often a long rule logs how far it got
+)
+
+// Plain identifiers only: rules come from config, and their names go into
+// SQL as they are
+var (
+	retentionIdent   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	retentionArchive = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*\.)?[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+func (r RetentionRule) withDefaults() RetentionRule {
+	r.Column = cmp.Or(r.Column, "created_at")
+	r.Key = cmp.Or(r.Key, "id")
+	r.BatchSize = cmp.Or(r.BatchSize, retentionBatch)
+	return r
+}
+
+// validate reports what's wrong with p without connecting
+func (p RetentionPolicy) validate(d *dialect) error {
+	var errs []error
+	if p.Interval < 0 {
+		errs = append(errs, errors.New("retention.interval can't be negative"))
+	}
+	if p.ArchiveDB != "" && d.attachArchive == "" {
+		errs = append(errs, fmt.Errorf("retention.archive_db is SQLite-only, use a %s schema in archive_to instead", d.name))
+	}
+	for i, r := range p.Rules {
+		r = r.withDefaults()
+		switch {
+		case !retentionIdent.MatchString(r.Table):
+			errs = append(errs, fmt.Errorf("retention.rules[%d]: table %q isn't a plain table name", i, r.Table))
+		case !retentionIdent.MatchString(r.Column) || !retentionIdent.MatchString(r.Key):
+			errs = append(errs, fmt.Errorf("retention.rules[%d]: column and key must be plain column names", i))
+		case r.ArchiveTo != "" && !retentionArchive.MatchString(r.ArchiveTo):
+			errs = append(errs, fmt.Errorf("retention.rules[%d]: archive_to %q isn't a table or schema.table name", i, r.ArchiveTo))
+		case r.ArchiveTo == r.Table:
+			errs = append(errs, fmt.Errorf("retention.rules[%d]: archive_to is the table itself", i))
+		case r.OlderThan <= 0:
+			errs = append(errs, fmt.Errorf("retention.rules[%d]: older_than must be positive", i))
+		case r.BatchSize < 0:
+			errs = append(errs, fmt.Errorf("retention.rules[%d]: batch_size can't be negative", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ApplyRetention runs p's rules once, in order, and returns what each
+// did. A rule that fails keeps the batches it committed and doesn't stop
+// the rules after it; the error joins every rule's. Set Config.Retention
+// to have Open run them on a schedule.
+func (db *DB) ApplyRetention(ctx context.Context, p RetentionPolicy) ([]RetentionResult, error) {
+	d := defaultDialect()
+	if err := p.validate(d); err != nil {
+		return nil, err
+	}
+
+	// One connection for the run, since an attached database is only
+	// there on the connection that attached it
+	c, err := db.Conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if p.ArchiveDB != "" {
+		if _, err := c.ExecContext(ctx, d.attachArchive, p.ArchiveDB); err != nil {
+			return nil, fmt.Errorf("failed to attach archive database: %w", err)
+		}
+		defer func() {
+			if _, err := c.ExecContext(context.Background(), "DETACH DATABASE archive"); err != nil {
+				c.Raw(func(any) error { return driver.ErrBadConn }) // Not back to the pool with it attached
+			}
+		}()
+	}
+
+	var (
+		results []RetentionResult
+		errs    []error
+	)
+	for _, r := range p.Rules {
+		r = r.withDefaults()
+		start := time.Now()
+		n, err := db.applyRule(ctx, d, c, r)
+		if err != nil {
+			err = fmt.Errorf("retention of %s: %w", r.Table, err)
+			errs = append(errs, err)
+		}
+		results = append(results, RetentionResult{Table: r.Table, Rows: n, Archive: r.ArchiveTo, Duration: time.Since(start), Err: err})
+	}
+	return results, errors.Join(errs...)
+}
+
+// applyRule prunes r's table batch by batch until no row is old enough
+func (db *DB) applyRule(ctx context.Context, d *dialect, c DBTX, r RetentionRule) (int64, error) {
+	if r.ArchiveTo != "" {
+		if _, err := c.ExecContext(ctx, fmt.Sprintf(d.createArchive, r.ArchiveTo, r.Table)); err != nil {
+			return 0, fmt.Errorf("failed to create %s: %w", r.ArchiveTo, err)
+		}
+	}
+	begin := cmp.Or(d.beginImmediate, "BEGIN")
+	cutoff := time.Now().Add(-r.OlderThan).UTC()
+
+	var total int64
+	logged := time.Now()
+	for {
+		n, more, err := db.retentionBatch(ctx, d, c, r, begin, cutoff)
+		total += n
+		if err != nil || !more {
+			return total, err
+		}
+		if time.Since(logged) >= retentionProgress {
+			log.Printf("database retention: %s: %d rows so far", r.Table, total)
+			logged = time.Now()
+		}
+	}
+}
+
+// retentionBatch moves or deletes up to r.BatchSize rows in a transaction,
+// reporting whether a full batch was found, so there may be more
+func (db *DB) retentionBatch(ctx context.Context, d *dialect, c DBTX, r RetentionRule, begin string, cutoff time.Time) (n int64, more bool, err error) {
+	if err := db.writes.acquire(ctx); err != nil {
+		return 0, false, err
+	}
+	defer db.writes.release()
+
+	if _, err := c.ExecContext(ctx, begin); err != nil {
+		return 0, false, err
+	}
+	defer func() {
+		if err != nil {
+			c.ExecContext(context.Background(), "ROLLBACK")
+		}
+	}()
+
+	q := &queryBuilder{numbered: d.numberedParams}
+	rows, err := c.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s < %s ORDER BY %s LIMIT %d",
+		r.Key, r.Table, fmt.Sprintf(d.timeOrder, r.Column), fmt.Sprintf(d.timeOrder, q.param(cutoff)), r.Key, r.BatchSize), q.args...)
+	if err != nil {
+		return 0, false, err
+	}
+	var keys []any
+	for rows.Next() {
+		var k any
+		if err := rows.Scan(&k); err != nil {
+			rows.Close()
+			return 0, false, err
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+	if len(keys) == 0 {
+		_, err := c.ExecContext(ctx, "COMMIT")
+		return 0, false, err
+	}
+
+	q = &queryBuilder{numbered: d.numberedParams}
+	params := make([]string, len(keys))
+	for i, k := range keys {
+		params[i] = q.param(k)
+	}
+	in := fmt.Sprintf("%s IN (%s)", r.Key, strings.Join(params, ", "))
+	if r.ArchiveTo != "" {
+		if _, err := c.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s SELECT * FROM %s WHERE %s", r.ArchiveTo, r.Table, in), q.args...); err != nil {
+			return 0, false, fmt.Errorf("failed to archive: %w", err)
+		}
+	}
+	res, err := c.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", r.Table, in), q.args...)
+	if err != nil {
+		return 0, false, err
+	}
+	if n, err = res.RowsAffected(); err != nil {
+		return 0, false, err
+	}
+	if _, err := c.ExecContext(ctx, "COMMIT"); err != nil {
+		return 0, false, err
+	}
+	return n, len(keys) == r.BatchSize, nil
+}
+
+// StartRetentionLoop applies p every p.Interval (hourly if 0) until ctx
+// is canceled, logging what each rule removed and the failures
+func (db *DB) StartRetentionLoop(ctx context.Context, p RetentionPolicy) error {
+	if err := p.validate(defaultDialect()); err != nil {
+		return err
+	}
+	if !p.enabled() {
+		return errors.New("retention policy has no rules")
+	}
+
+	go func() {
+		ticker := time.NewTicker(cmp.Or(p.Interval, retentionInterval))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			results, err := db.ApplyRetention(ctx, p)
+			if ctx.Err() != nil {
+				return
+			}
+			if len(results) == 0 && err != nil {
+				log.Printf("database retention failed: %v", err)
+			}
+			for _, r := range results {
+				switch {
+				case r.Err != nil:
+					log.Printf("database retention failed: %v", r.Err)
+				case r.Rows > 0 && r.Archive != "":
+					log.Printf("database retention: %s: %d rows moved to %s in %v", r.Table, r.Rows, r.Archive, r.Duration.Round(time.Millisecond))
+				case r.Rows > 0:
+					log.Printf("database retention: %s: %d rows deleted in %v", r.Table, r.Rows, r.Duration.Round(time.Millisecond))
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryOptions controls RetryTransaction
+type RetryOptions struct {
+	MaxAttempts int            // Runs of fn before giving up, the first one included (default 5)
+	MinBackoff  time.Duration  // Longest wait before the first retry, doubled per attempt (default 10ms)
+	MaxBackoff  time.Duration  // Upper bound for the longest wait (default 1s)
+	Tx          *sql.TxOptions // As for TransactionWithOptions; nil is a plain transaction
+}
+
+// IsRetryable reports lock contention that running the transaction again
+// may get past: "database is locked" on SQLite, a serialization failure
+// or a deadlock on PostgreSQL
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	d := defaultDialect()
+	return d.isRetryable != nil && d.isRetryable(err)
+}
+
+// RetryTransaction is TransactionWithOptions that runs fn again, in a new
+// transaction, while it fails with an error IsRetryable accepts, up to
+// MaxAttempts times. The wait before each retry is random up to a bound
+// that doubles every time, so writers that collided don't collide again.
+// Once the attempts run out, or ctx ends, the last error is returned.
+//
+// fn may run more than once, so anything it does besides queries on q
+// (sending a message, charging a card) belongs after RetryTransaction
+// returns.
+func (db *DB) RetryTransaction(ctx context.Context, opts RetryOptions, fn func(*Queries) error) error {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 10 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Second
+	}
+
+	backoff := opts.MinBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.TransactionWithOptions(ctx, opts.Tx, fn)
+		if err == nil || attempt >= opts.MaxAttempts || !IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(rand.N(backoff) + 1):
+		}
+		db.counters.retries.Add(1)
+		backoff = min(2*backoff, opts.MaxBackoff)
+	}
+}
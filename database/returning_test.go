@@ -0,0 +1,61 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestWritesReturnRows(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	created, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: 1, FirstName: "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := db.Q.GetUserByTelegramID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(created, fresh) {
+		t.Errorf("CreateUser returned %+v, a fresh Get %+v", created, fresh)
+	}
+	// Filled in by the database's defaults, not by the insert
+	if created.ID == 0 || !created.CreatedAt.Valid || !created.UpdatedAt.Valid || created.Version != 1 ||
+		created.BalanceGame != (sql.Null[database.Money]{Valid: true}) {
+		t.Errorf("defaults missing from the returned row: %+v", created)
+	}
+
+	updated, err := db.Q.UpdateUserBalanceChats(ctx, database.UpdateUserBalanceChatsParams{
+		BalanceChats: sql.Null[database.Money]{V: 250, Valid: true}, TelegramID: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh, _ = db.Q.GetUserByTelegramID(ctx, 1); !reflect.DeepEqual(updated, fresh) || updated.Version != 2 {
+		t.Errorf("UpdateUserBalanceChats returned %+v, a fresh Get %+v", updated, fresh)
+	}
+
+	group, err := db.Q.CreateGroup(ctx, database.CreateGroupParams{TelegramID: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := db.Q.DeleteGroup(ctx, 10)
+	if err != nil || !reflect.DeepEqual(deleted, group) {
+		t.Errorf("DeleteGroup returned %+v, %v; want the row it deleted, %+v", deleted, err, group)
+	}
+
+	// With nothing to return, a write to a missing row fails
+	if _, err := db.Q.DeleteGroup(ctx, 10); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("deleting a missing group: %v, want ErrNotFound", err)
+	}
+	if _, err := db.Q.UpdateUserBalanceChats(ctx, database.UpdateUserBalanceChatsParams{TelegramID: 2}); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("updating a missing user: %v, want ErrNotFound", err)
+	}
+}
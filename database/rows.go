@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// A query's rows are read after QueryContext returns: mattn/go-sqlite3
+// doesn't even run the statement until the first Next. A DBTX layer that
+// has to know when they're done with (to stop a timer, free a write slot)
+// hands them to watchRows, which puts rows of its own in front that read
+// through to them and tell it once they're closed. database/sql can only
+// make a *sql.Rows or *sql.Row from a driver, so the rows in front come
+// from watchedDB, whose one query returns the rows given as its argument.
+type rowsWatch struct {
+	// mapErr, if set, replaces an error reading the rows
+	mapErr func(error) error
+	// done runs once, when the rows are closed (a *sql.Row's by Scan),
+	// with how many were read and the error reading them ended with
+	done func(n int64, err error)
+}
+
+var errWatchedQuery = errors.New("watched rows: not a query")
+
+var watchedDB = func() *sql.DB {
+	db := sql.OpenDB(watchedConnector{})
+	db.SetMaxIdleConns(64) // They cost nothing, see watchedConn
+	return db
+}()
+
+// watchRows returns rows that read rows and report to w. If they can't
+// be made (ctx is done), rows are closed and w told.
+func watchRows(ctx context.Context, rows *sql.Rows, w rowsWatch) (*sql.Rows, error) {
+	r, err := newWatchedRows(rows, w)
+	if err != nil {
+		return nil, err
+	}
+	out, err := watchedDB.QueryContext(ctx, "", r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return out, nil
+}
+
+// watchRow is watchRows for QueryRowContext
+func watchRow(ctx context.Context, rows *sql.Rows, w rowsWatch) *sql.Row {
+	r, err := newWatchedRows(rows, w)
+	if err != nil {
+		return errRow(ctx, err)
+	}
+	row := watchedDB.QueryRowContext(ctx, "", r)
+	if row.Err() != nil {
+		r.Close()
+	}
+	return row
+}
+
+// watchedRows is the driver.Rows of watchedDB's query
+type watchedRows struct {
+	rows   *sql.Rows
+	w      rowsWatch
+	cols   []string
+	vals   []any
+	ptrs   []any
+	types  []*sql.ColumnType // Read on first use
+	n      int64
+	err    error
+	closed sync.Once
+}
+
+func newWatchedRows(rows *sql.Rows, w rowsWatch) (*watchedRows, error) {
+	r := &watchedRows{rows: rows, w: w}
+	cols, err := rows.Columns()
+	if err != nil {
+		r.fail(err)
+		r.Close()
+		return nil, r.err
+	}
+	r.cols = cols
+	r.vals = make([]any, len(cols))
+	r.ptrs = make([]any, len(cols))
+	for i := range r.vals {
+		r.ptrs[i] = &r.vals[i]
+	}
+	return r, nil
+}
+
+func (r *watchedRows) fail(err error) error {
+	if r.w.mapErr != nil {
+		err = r.w.mapErr(err)
+	}
+	r.err = err
+	return err
+}
+
+func (r *watchedRows) Columns() []string { return r.cols }
+
+// Next scans into any, which leaves the driver's values as they are
+// (copying []byte), so the caller's Scan converts what it would have
+func (r *watchedRows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return r.fail(err)
+		}
+		return io.EOF
+	}
+	if err := r.rows.Scan(r.ptrs...); err != nil {
+		return r.fail(err)
+	}
+	for i, v := range r.vals {
+		dest[i] = v
+	}
+	r.n++
+	return nil
+}
+
+func (r *watchedRows) Close() error {
+	err := r.rows.Close()
+	r.closed.Do(func() {
+		if r.w.done != nil {
+			r.w.done(r.n, r.err)
+		}
+	})
+	return err
+}
+
+func (r *watchedRows) columnType(i int) *sql.ColumnType {
+	if r.types == nil {
+		r.types, _ = r.rows.ColumnTypes()
+	}
+	if i < len(r.types) {
+		return r.types[i]
+	}
+	return nil
+}
+
+func (r *watchedRows) ColumnTypeScanType(i int) reflect.Type {
+	if t := r.columnType(i); t != nil && t.ScanType() != nil {
+		return t.ScanType()
+	}
+	return reflect.TypeFor[any]()
+}
+
+func (r *watchedRows) ColumnTypeDatabaseTypeName(i int) string {
+	if t := r.columnType(i); t != nil {
+		return t.DatabaseTypeName()
+	}
+	return ""
+}
+
+func (r *watchedRows) ColumnTypeNullable(i int) (nullable, ok bool) {
+	if t := r.columnType(i); t != nil {
+		return t.Nullable()
+	}
+	return false, false
+}
+
+func (r *watchedRows) ColumnTypeLength(i int) (int64, bool) {
+	if t := r.columnType(i); t != nil {
+		return t.Length()
+	}
+	return 0, false
+}
+
+func (r *watchedRows) ColumnTypePrecisionScale(i int) (precision, scale int64, ok bool) {
+	if t := r.columnType(i); t != nil {
+		return t.DecimalSize()
+	}
+	return 0, 0, false
+}
+
+type watchedConnector struct{}
+
+func (watchedConnector) Connect(context.Context) (driver.Conn, error) { return watchedConn{}, nil }
+func (watchedConnector) Driver() driver.Driver                        { return watchedDriver{} }
+
+type watchedDriver struct{}
+
+func (watchedDriver) Open(string) (driver.Conn, error) { return watchedConn{}, nil }
+
+// watchedConn holds nothing: its query's rows are its argument
+type watchedConn struct{}
+
+func (watchedConn) Prepare(string) (driver.Stmt, error) { return nil, errWatchedQuery }
+func (watchedConn) Close() error                        { return nil }
+func (watchedConn) Begin() (driver.Tx, error)           { return nil, errWatchedQuery }
+
+// CheckNamedValue passes the *watchedRows through as it is
+func (watchedConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (watchedConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) != 1 {
+		return nil, errWatchedQuery
+	}
+	r, ok := args[0].Value.(*watchedRows)
+	if !ok {
+		return nil, errWatchedQuery
+	}
+	return r, nil
+}
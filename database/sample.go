@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"math/rand/v2"
+)
+
+const (
+	// Tables with fewer rows are sampled with ORDER BY RANDOM(), which
+	// sorts all of them but is cheap at that size
+	sampleSortBelow = 10000
+
+	// Rounds of SampleByIDRange before giving up on a sparse range
+	sampleMaxRounds = 20
+
+	// The prime SampleUsersSeeded hashes ids modulo
+	sampleModulus = 2147483647
+)
+
+// SampleUsers returns n users picked uniformly at random, fewer if there
+// aren't that many. Small tables, or a sample that's a large part of the
+// table, use ORDER BY RANDOM(), which reads and sorts every row; larger
+// ones draw random ids from the id range instead (SampleByIDRange), a few
+// indexed lookups whatever the table size. Each call returns a different
+// sample; SampleUsersSeeded returns the same one for a seed.
+func (db *DB) SampleUsers(ctx context.Context, n int) ([]User, error) {
+	if n <= 0 {
+		return []User{}, nil
+	}
+	rows, _, err := db.ApproxCount(ctx, "users")
+	if err != nil {
+		return nil, err
+	}
+	if rows < sampleSortBelow || int64(n)*4 > rows {
+		return db.Q.SampleUsers(ctx, int64(n))
+	}
+
+	r, err := db.Q.GetUserIDRange(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users, err := SampleByIDRange(n, r.MinID, r.MaxID, func(ids []int64) ([]User, error) {
+		return db.Q.ListUsersByIDs(ctx, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) < n {
+		// Too many gaps in the ids for drawing to pay off
+		return db.Q.SampleUsers(ctx, int64(n))
+	}
+	return users, nil
+}
+
+// SampleByIDRange samples up to n rows of a table with integer ids between
+// minID and maxID by rejection: it draws distinct random ids from the
+// range, fetches those that exist and keeps drawing until it has n rows.
+// Every existing row is equally likely, however the ids are spread, as
+// long as they're all within the range. Each round costs one fetch of at
+// most 1000 ids, and the number of rounds grows with the share of the
+// range deleted rows left empty; after 20 it returns what it has, which
+// can be fewer than n. fetch returns the rows with the given ids.
+func SampleByIDRange[T any](n int, minID, maxID int64, fetch func(ids []int64) ([]T, error)) ([]T, error) {
+	out := []T{}
+	span := maxID - minID + 1
+	if n <= 0 || span <= 0 {
+		return out, nil
+	}
+
+	drawn := map[int64]bool{}
+	for round := 0; len(out) < n && round < sampleMaxRounds && int64(len(drawn)) < span; round++ {
+		want := n - len(out)
+		// Draw as many ids as the hit rate so far says it takes, and then some
+		ratio := 2
+		if len(out) > 0 {
+			ratio = max(ratio, 2*len(drawn)/len(out))
+		}
+		ids := make([]int64, 0, min(want*ratio, bulkChunkSize))
+		for len(ids) < cap(ids) && int64(len(drawn)) < span {
+			id := minID + rand.Int64N(span)
+			if !drawn[id] {
+				drawn[id] = true
+				ids = append(ids, id)
+			}
+		}
+
+		rows, err := fetch(ids)
+		if err != nil {
+			return nil, err
+		}
+		// fetch may return them sorted; a sample cut short shouldn't favor low ids
+		rand.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+		out = append(out, rows[:min(want, len(rows))]...)
+	}
+	return out, nil
+}
+
+// SampleUsersSeeded returns n users in an order derived from seed: the
+// same seed gives the same users, in the same order, until users are
+// added or removed, which makes a sample reproducible in a report. The
+// order hashes each id (abs(id) % p * a + b) % p with a and b from the
+// seed, which like ORDER BY RANDOM() reads and sorts the whole table.
+func (db *DB) SampleUsersSeeded(ctx context.Context, n int, seed int64) ([]User, error) {
+	if n <= 0 {
+		return []User{}, nil
+	}
+	mult, inc := sampleHash(seed)
+	return db.Q.SampleUsersSeeded(ctx, SampleUsersSeededParams{
+		Multiplier: mult,
+		Increment:  inc,
+		Limit:      int64(n),
+	})
+}
+
+// sampleHash derives the multiplier (never 0, so the hash is a
+// permutation) and increment of SampleUsersSeeded from seed, mixing it
+// with splitmix64 so nearby seeds give unrelated orders
+func sampleHash(seed int64) (mult, inc int64) {
+	state := uint64(seed)
+	next := func() uint64 {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+	mult = 1 + int64(next()%(sampleModulus-1))
+	inc = int64(next() % sampleModulus)
+	return mult, inc
+}
@@ -0,0 +1,149 @@
+package database_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// bulkUsers creates users with Telegram IDs 1 to n
+func bulkUsers(t *testing.T, db *database.DB, n int) {
+	t.Helper()
+	users := make([]database.CreateUserParams, n)
+	for i := range users {
+		users[i] = database.CreateUserParams{TelegramID: int64(i + 1), FirstName: "user", Status: database.StatusActive, Language: "en"}
+	}
+	if _, err := db.BulkCreateUsers(context.Background(), users); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ids lists the users' IDs, failing on a duplicate
+func ids(t *testing.T, users []database.User) []int64 {
+	t.Helper()
+	seen := map[int64]bool{}
+	var out []int64
+	for _, u := range users {
+		if seen[u.ID] {
+			t.Errorf("user %d sampled twice", u.ID)
+		}
+		seen[u.ID] = true
+		out = append(out, u.ID)
+	}
+	return out
+}
+
+func TestSampleUsers(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	bulkUsers(t, db, 50)
+
+	a, err := db.SampleUsers(ctx, 10)
+	if err != nil || len(ids(t, a)) != 10 {
+		t.Fatalf("SampleUsers(10): %d users, %v", len(a), err)
+	}
+	b, _ := db.SampleUsers(ctx, 10)
+	if slices.Equal(ids(t, a), ids(t, b)) {
+		t.Error("two samples of 10 in 50 came out the same")
+	}
+	if all, err := db.SampleUsers(ctx, 80); err != nil || len(ids(t, all)) != 50 {
+		t.Errorf("more than there are: %d users, %v; want all 50", len(all), err)
+	}
+	if none, err := db.SampleUsers(ctx, 0); err != nil || none == nil || len(none) != 0 {
+		t.Errorf("SampleUsers(0): %v, %v", none, err)
+	}
+}
+
+func TestSampleUsersLargeTable(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	bulkUsers(t, db, 24000) // 12000 left, past where ORDER BY RANDOM() is used
+	// Gaps in the ids, which drawing from the range has to skip
+	for _, q := range []string{"DELETE FROM users WHERE id % 2 = 0", "ANALYZE"} {
+		if _, err := db.DBTX().ExecContext(ctx, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	users, err := db.SampleUsers(ctx, 100)
+	if err != nil || len(ids(t, users)) != 100 {
+		t.Fatalf("SampleUsers(100) of 12000: %d users, %v", len(users), err)
+	}
+	for _, u := range users {
+		if u.ID%2 == 0 {
+			t.Errorf("sampled deleted user %d", u.ID)
+		}
+	}
+}
+
+func TestSampleByIDRange(t *testing.T) {
+	// Rows exist for the even ids only
+	even := func(ids []int64) ([]int64, error) {
+		var rows []int64
+		for _, id := range ids {
+			if id%2 == 0 {
+				rows = append(rows, id)
+			}
+		}
+		slices.Sort(rows) // As an IN query might return them
+		return rows, nil
+	}
+
+	rows, err := database.SampleByIDRange(50, 1, 2000, even)
+	if err != nil || len(rows) != 50 {
+		t.Fatalf("50 of 1000 rows: %d, %v", len(rows), err)
+	}
+	seen := map[int64]bool{}
+	for _, id := range rows {
+		if id%2 != 0 || seen[id] {
+			t.Errorf("sampled %d, which doesn't exist or came twice", id)
+		}
+		seen[id] = true
+	}
+	if rows, _ := database.SampleByIDRange(50, 1, 20, even); len(rows) != 10 {
+		t.Errorf("50 of 10 rows: %d, want all", len(rows))
+	}
+	if rows, _ := database.SampleByIDRange(5, 1, 1<<40, even); len(rows) != 5 {
+		t.Errorf("a huge range: %d rows, want 5", len(rows))
+	}
+
+	// Each row is about equally likely
+	picked := map[int64]int{}
+	for range 5000 {
+		rows, _ := database.SampleByIDRange(1, 1, 20, even)
+		picked[rows[0]]++
+	}
+	for id := int64(2); id <= 20; id += 2 {
+		if n := picked[id]; n < 350 || n > 650 {
+			t.Errorf("row %d picked %d times in 5000, want about 500", id, n)
+		}
+	}
+}
+
+func TestSampleUsersSeeded(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	bulkUsers(t, db, 200)
+
+	sample := func(seed int64) []int64 {
+		t.Helper()
+		users, err := db.SampleUsersSeeded(ctx, 20, seed)
+		if err != nil || len(users) != 20 {
+			t.Fatalf("SampleUsersSeeded(20, %d): %d users, %v", seed, len(users), err)
+		}
+		return ids(t, users)
+	}
+	a := sample(42)
+	if b := sample(42); !slices.Equal(a, b) {
+		t.Errorf("seed 42 gave %v, then %v", a, b)
+	}
+	if c := sample(43); slices.Equal(a, c) {
+		t.Error("seeds 42 and 43 gave the same sample")
+	}
+	if n, err := db.SampleUsersSeeded(ctx, 500, 42); err != nil || len(n) != 200 {
+		t.Errorf("more than there are: %d users, %v; want all 200", len(n), err)
+	}
+}
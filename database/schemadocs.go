@@ -0,0 +1,296 @@
+package database
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ExpectedSchema introspects the schema this build creates: the embedded
+// schema.sql and migrations applied to a scratch database, as SchemaDrift
+// compares the live one with
+func (db *DB) ExpectedSchema(ctx context.Context) (SchemaInfo, error) {
+	d := defaultDialect()
+	if d.expectedSchema == nil {
+		return SchemaInfo{}, fmt.Errorf("%s has no embedded schema to introspect here", d.name)
+	}
+	info, err := d.expectedSchema(ctx, db.Conn, db.schemaCfg)
+	if err != nil {
+		return SchemaInfo{}, fmt.Errorf("failed to build the expected schema: %w", err)
+	}
+	for i := range info.Tables {
+		info.Tables[i].Internal = internalTables[info.Tables[i].Name]
+	}
+	return info, nil
+}
+
+// SchemaDocs renders a schema as documentation: Markdown with a table per
+// table and an ER diagram, or the diagram alone as Mermaid or Graphviz
+// DOT. The output only changes with the schema, so it can be committed
+// next to schema.sql and checked in CI.
+type SchemaDocs struct {
+	Schema   SchemaInfo
+	Drift    *SchemaDrift // How the live database differs, noted at the top of the Markdown; nil or none leaves it out
+	Internal bool         // Include the package's own tables (history, audit log, search index)
+}
+
+// SchemaDocs documents the embedded schema, with the live database's
+// drift from it
+func (db *DB) SchemaDocs(ctx context.Context) (SchemaDocs, error) {
+	schema, err := db.ExpectedSchema(ctx)
+	if err != nil {
+		return SchemaDocs{}, err
+	}
+	drift, err := db.SchemaDrift(ctx)
+	if err != nil {
+		return SchemaDocs{}, err
+	}
+	return SchemaDocs{Schema: schema, Drift: &drift}, nil
+}
+
+// tables are the ones documented, by name
+func (s SchemaDocs) tables() []TableInfo {
+	var tables []TableInfo
+	for _, t := range s.Schema.Tables {
+		if s.Internal || (!t.Internal && !driftIgnoredTables[t.Name]) {
+			tables = append(tables, t)
+		}
+	}
+	slices.SortFunc(tables, func(a, b TableInfo) int { return strings.Compare(a.Name, b.Name) })
+	return tables
+}
+
+// Markdown writes a heading per table with its columns, indexes, foreign
+// keys and the tables referencing it, after a Mermaid ER diagram, which
+// GitHub and GitLab draw
+func (s SchemaDocs) Markdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	tables := s.tables()
+
+	fmt.Fprintf(bw, "# Database schema\n\n")
+	fmt.Fprintf(bw, "Generated from the embedded schema.sql and migrations by `dbctl docs`; don't edit it by hand.\n\n")
+	if s.Drift != nil && !s.Drift.None() {
+		fmt.Fprintf(bw, "> **The live database differs:** %s.\n\n", mdEscape(s.Drift.String()))
+	}
+	fmt.Fprintf(bw, "```mermaid\n")
+	s.mermaid(bw, tables)
+	fmt.Fprintf(bw, "```\n")
+
+	for _, t := range tables {
+		kind := ""
+		if t.View {
+			kind = " (view)"
+		}
+		fmt.Fprintf(bw, "\n## %s%s\n\n", t.Name, kind)
+		fmt.Fprintf(bw, "| Column | Type | Null | Default | Key |\n|---|---|---|---|---|\n")
+		for _, c := range t.Columns {
+			null, def := "no", ""
+			if c.Nullable {
+				null = "yes"
+			}
+			if c.Default != nil {
+				def = "`" + mdEscape(*c.Default) + "`"
+			} else if c.Generated {
+				def = "generated"
+			}
+			fmt.Fprintf(bw, "| %s | %s | %s | %s | %s |\n", c.Name, mdEscape(c.Type), null, def, strings.Join(columnKeys(t, c.Name), ", "))
+		}
+
+		if len(t.Indexes) > 0 {
+			fmt.Fprintf(bw, "\nIndexes:\n\n")
+			for _, ix := range t.Indexes {
+				kind := ""
+				switch {
+				case ix.Primary:
+					kind = "primary key "
+				case ix.Unique:
+					kind = "unique "
+				}
+				fmt.Fprintf(bw, "- `%s`: %s(%s)\n", ix.Name, kind, indexColumns(ix))
+			}
+		}
+		if len(t.ForeignKeys) > 0 {
+			fmt.Fprintf(bw, "\nForeign keys:\n\n")
+			for _, fk := range t.ForeignKeys {
+				fmt.Fprintf(bw, "- (%s) → [%s](#%s) (%s)%s\n", strings.Join(fk.Columns, ", "), fk.RefTable, fk.RefTable,
+					strings.Join(fk.RefColumns, ", "), fkActions(fk))
+			}
+		}
+		var refs []string
+		for _, other := range tables {
+			for _, fk := range other.ForeignKeys {
+				if fk.RefTable == t.Name {
+					refs = append(refs, fmt.Sprintf("- [%s](#%s) (%s)\n", other.Name, other.Name, strings.Join(fk.Columns, ", ")))
+				}
+			}
+		}
+		if len(refs) > 0 {
+			fmt.Fprintf(bw, "\nReferenced by:\n\n%s", strings.Join(refs, ""))
+		}
+	}
+	return bw.Flush()
+}
+
+// Mermaid writes the ER diagram as a Mermaid erDiagram
+func (s SchemaDocs) Mermaid(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	s.mermaid(bw, s.tables())
+	return bw.Flush()
+}
+
+func (s SchemaDocs) mermaid(w io.Writer, tables []TableInfo) {
+	fmt.Fprintf(w, "erDiagram\n")
+	for _, t := range tables {
+		fmt.Fprintf(w, "    %s {\n", t.Name)
+		for _, c := range t.Columns {
+			line := fmt.Sprintf("        %s %s", mermaidType(c.Type), c.Name)
+			if keys := columnKeys(t, c.Name); len(keys) > 0 {
+				line += " " + strings.Join(keys, ",")
+			}
+			if c.Nullable {
+				line += ` "null"`
+			}
+			fmt.Fprintln(w, line)
+		}
+		fmt.Fprintf(w, "    }\n")
+	}
+	for _, t := range tables {
+		for _, fk := range t.ForeignKeys {
+			if !slices.ContainsFunc(tables, func(r TableInfo) bool { return r.Name == fk.RefTable }) {
+				continue
+			}
+			// The parent's end: exactly one, or zero or one when the key may be NULL
+			parent := "||"
+			if fkNullable(t, fk) {
+				parent = "|o"
+			}
+			// The child's end: zero or more, or zero or one when the key is unique
+			child := "o{"
+			if fkUnique(t, fk) {
+				child = "o|"
+			}
+			fmt.Fprintf(w, "    %s %s--%s %s : %q\n", fk.RefTable, parent, child, t.Name, strings.Join(fk.Columns, ", "))
+		}
+	}
+}
+
+// DOT writes the ER diagram for Graphviz: dot -Tsvg schema.dot > schema.svg
+func (s SchemaDocs) DOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	tables := s.tables()
+	fmt.Fprintf(bw, "digraph schema {\n    rankdir=LR;\n    node [shape=record, fontname=\"Helvetica\", fontsize=10];\n    edge [fontname=\"Helvetica\", fontsize=9];\n\n")
+	for _, t := range tables {
+		fields := make([]string, 0, len(t.Columns))
+		for _, c := range t.Columns {
+			f := c.Name + " " + c.Type
+			if keys := columnKeys(t, c.Name); len(keys) > 0 {
+				f += " (" + strings.Join(keys, ", ") + ")"
+			}
+			if !c.Nullable {
+				f += " NOT NULL"
+			}
+			fields = append(fields, fmt.Sprintf("<%s> %s\\l", c.Name, dotEscape(f)))
+		}
+		name := dotEscape(t.Name)
+		if t.View {
+			name += " (view)"
+		}
+		fmt.Fprintf(bw, "    %q [label=\"{%s|%s}\"];\n", t.Name, name, strings.Join(fields, "|"))
+	}
+	fmt.Fprintln(bw)
+	for _, t := range tables {
+		for _, fk := range t.ForeignKeys {
+			if !slices.ContainsFunc(tables, func(r TableInfo) bool { return r.Name == fk.RefTable }) {
+				continue
+			}
+			style := ""
+			if fkNullable(t, fk) {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(bw, "    %q:%q -> %q:%q [label=%q%s];\n", t.Name, fk.Columns[0], fk.RefTable, fk.RefColumns[0], strings.Join(fk.Columns, ", "), style)
+		}
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
+
+// columnKeys is PK, FK and UK for a column in the table's primary key, a
+// foreign key, and a unique index of that column alone
+func columnKeys(t TableInfo, col string) []string {
+	var keys []string
+	if slices.Contains(t.PrimaryKey(), col) {
+		keys = append(keys, "PK")
+	}
+	if slices.ContainsFunc(t.ForeignKeys, func(fk ForeignKeyInfo) bool { return slices.Contains(fk.Columns, col) }) {
+		keys = append(keys, "FK")
+	}
+	if slices.ContainsFunc(t.Indexes, func(ix IndexInfo) bool {
+		return ix.Unique && !ix.Primary && slices.Equal(ix.Columns, []string{col})
+	}) {
+		keys = append(keys, "UK")
+	}
+	return keys
+}
+
+func fkNullable(t TableInfo, fk ForeignKeyInfo) bool {
+	return slices.ContainsFunc(t.Columns, func(c ColumnInfo) bool { return c.Nullable && slices.Contains(fk.Columns, c.Name) })
+}
+
+// fkUnique reports a one-to-one key: the foreign key's columns are the
+// primary key or a unique index
+func fkUnique(t TableInfo, fk ForeignKeyInfo) bool {
+	if slices.Equal(t.PrimaryKey(), fk.Columns) {
+		return true
+	}
+	return slices.ContainsFunc(t.Indexes, func(ix IndexInfo) bool { return ix.Unique && slices.Equal(ix.Columns, fk.Columns) })
+}
+
+func fkActions(fk ForeignKeyInfo) string {
+	var actions []string
+	if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+		actions = append(actions, "on delete "+fk.OnDelete)
+	}
+	if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+		actions = append(actions, "on update "+fk.OnUpdate)
+	}
+	if len(actions) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(actions, ", ")
+}
+
+func indexColumns(ix IndexInfo) string {
+	cols := make([]string, len(ix.Columns))
+	for i, c := range ix.Columns {
+		cols[i] = cmp.Or(c, "expression")
+	}
+	return strings.Join(cols, ", ")
+}
+
+// mermaidType makes a column type a Mermaid attribute type, which is one
+// word: "timestamp with time zone" is timestamp_with_time_zone
+func mermaidType(t string) string {
+	if t == "" {
+		return "ANY" // SQLite lets a column go without one
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '_' || r == '(' || r == ')' || r == '[' || r == ']' || r == '-',
+			r >= '0' && r <= '9', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			return r
+		}
+		return '_'
+	}, t)
+}
+
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "{", `\{`, "}", `\}`, "|", `\|`, "<", `\<`, ">", `\>`, "\n", " ").Replace(s)
+}
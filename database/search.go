@@ -0,0 +1,149 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// What Search can look for
+const (
+	SearchKindUser  = "user"  // Users by first name and username
+	SearchKindGroup = "group" // Groups by title and URL
+)
+
+// ErrSearchUnavailable means the database can't run Search: a SQLite
+// built without FTS5, or a dialect with no full-text search. Everything
+// else works without it.
+var ErrSearchUnavailable = errors.New("full-text search is unavailable")
+
+// Hits Search returns when SearchOptions.Limit is 0
+const defaultSearchLimit = 20
+
+// SearchOptions adjusts DB.Search
+type SearchOptions struct {
+	Kinds     []string  // SearchKindUser, SearchKindGroup; empty searches all of them
+	Limit     int       // Hits at most, default 20
+	Highlight [2]string // Put around matches in Title and Snippet, e.g. {"<mark>", "</mark>"}; nothing by default
+	HTML      bool      // Escape Title and Snippet for HTML, but not the Highlight markers
+	Raw       bool      // The query is an FTS5 MATCH expression (SQLite) or to_tsquery input (PostgreSQL), not words
+}
+
+// SearchHit is one row Search found
+type SearchHit struct {
+	Kind    string  // SearchKindUser or SearchKindGroup
+	ID      int64   // users.id or groups.id
+	Title   string  // First name or group title
+	Snippet string  // Username or group URL, cut to the part around the matches
+	Score   float64 // Higher is better. Scores compare within one Search, not across dialects.
+}
+
+// Search finds users and groups whose names contain every word of query,
+// each as a word or the start of one, in any casing, best matches first.
+// Soft-deleted users aren't found. With SQLite it uses the FTS5 tables the
+// schema keeps in step by triggers, with PostgreSQL its text search; in
+// both, nothing beyond the database is needed. A SQLite built without
+// FTS5 has no search tables, and Search returns ErrSearchUnavailable. A
+// query without words finds nothing.
+//
+//	hits, err := db.Search(ctx, r.FormValue("q"), database.SearchOptions{
+//		Highlight: [2]string{"<mark>", "</mark>"},
+//		HTML:      true,
+//	})
+//
+// Raw passes query on as the dialect's own syntax, for phrases, OR and
+// column filters; a syntax error in it is returned as the driver's error.
+func (db *DB) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchHit, error) {
+	d := defaultDialect()
+	if d.searchQuery == nil {
+		return nil, fmt.Errorf("%w: %s has none here", ErrSearchUnavailable, d.name)
+	}
+	if d.searchAvailable != nil {
+		if err := d.searchAvailable(ctx, db.Conn); err != nil {
+			return nil, err
+		}
+	}
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = []string{SearchKindUser, SearchKindGroup}
+	}
+	for _, kind := range kinds {
+		if kind != SearchKindUser && kind != SearchKindGroup {
+			return nil, fmt.Errorf("search: unknown kind %q", kind)
+		}
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	match := strings.TrimSpace(query)
+	if !opts.Raw {
+		words := strings.FieldsFunc(query, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+		})
+		if len(words) == 0 {
+			return nil, nil
+		}
+		match = d.searchQuery(words)
+	}
+	if match == "" {
+		return nil, errors.New("search: empty query")
+	}
+
+	var hits []SearchHit
+	for _, kind := range kinds {
+		found, err := db.searchKind(ctx, kind, match, int64(limit))
+		if err != nil {
+			return nil, fmt.Errorf("search %ss: %w", kind, err)
+		}
+		hits = append(hits, found...)
+	}
+	slices.SortStableFunc(hits, func(a, b SearchHit) int { return cmp.Compare(b.Score, a.Score) })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	for i := range hits {
+		hits[i].Title = opts.mark(hits[i].Title)
+		hits[i].Snippet = opts.mark(hits[i].Snippet)
+	}
+	return hits, nil
+}
+
+func (db *DB) searchKind(ctx context.Context, kind, match string, limit int64) ([]SearchHit, error) {
+	var hits []SearchHit
+	switch kind {
+	case SearchKindUser:
+		rows, err := db.Q.SearchUsers(ctx, SearchUsersParams{Query: match, Limit: limit})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			hits = append(hits, SearchHit{Kind: kind, ID: r.ID, Title: r.Title, Snippet: r.Snippet, Score: r.Score})
+		}
+	case SearchKindGroup:
+		rows, err := db.Q.SearchGroups(ctx, SearchGroupsParams{Query: match, Limit: limit})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			hits = append(hits, SearchHit{Kind: kind, ID: r.ID, Title: r.Title, Snippet: r.Snippet, Score: r.Score})
+		}
+	}
+	return hits, nil
+}
+
+// mark puts the Highlight markers where the queries put \x02 and \x03
+// around the matches. Escaping first leaves those two alone.
+func (o SearchOptions) mark(s string) string {
+	if o.HTML {
+		s = html.EscapeString(s)
+	}
+	return strings.NewReplacer("\x02", o.Highlight[0], "\x03", o.Highlight[1]).Replace(s)
+}
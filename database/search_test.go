@@ -0,0 +1,46 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	for i, name := range []string{"Zoë", "Zola", "Anna"} {
+		_, err := db.Q.CreateUser(ctx, database.CreateUserParams{
+			TelegramID: int64(i + 1),
+			FirstName:  name,
+			Username:   sql.Null[string]{V: "user" + name, Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hits, err := db.Search(ctx, "zo", database.SearchOptions{Highlight: [2]string{"[", "]"}})
+	if errors.Is(err, database.ErrSearchUnavailable) {
+		t.Skipf("the rest of the database works, but: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("got %d hits, want Zoë and Zola: %+v", len(hits), hits)
+	}
+	for _, h := range hits {
+		if h.Kind != database.SearchKindUser || h.Title[0] != '[' {
+			t.Errorf("hit %+v: want a user with the match marked", h)
+		}
+	}
+
+	if hits, err := db.Search(ctx, "  ", database.SearchOptions{}); err != nil || len(hits) != 0 {
+		t.Errorf("a query without words: got %v, %v; want nothing", hits, err)
+	}
+}
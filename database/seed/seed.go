@@ -0,0 +1,175 @@
+// Package seed fills a new database with the rows an environment starts
+// with, and records what it ran in seed_history so running it again only
+// adds what's new:
+//
+//	ran, err := seed.Seed(ctx, db, "dev")
+//
+// Seeds are SQL files embedded from seeds/<env>/, where seeds/all/ runs
+// in every environment, and Go functions added with Register. They run in
+// name order, whichever kind they are, each in a transaction of its own
+// together with its seed_history row. Name them with a number first
+// (010_tags) so the order is obvious. SQL seeds have to be valid for the
+// dialect the app is built with.
+package seed
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"your-project/database"
+)
+
+//go:embed seeds
+var files embed.FS
+
+// AllEnvs is the seeds directory, and the Envs entry, for seeds every
+// environment gets
+const AllEnvs = "all"
+
+// Seeder is a seed written in Go, for data SQL can't express well
+type Seeder struct {
+	Name string   // Orders it among the other seeds and names it in seed_history
+	Envs []string // Environments it runs in, AllEnvs for every one
+	Run  func(ctx context.Context, tx *database.Tx) error
+}
+
+var (
+	mu       sync.Mutex
+	registry []Seeder
+)
+
+// Register adds a Go seed, usually from an init function of the package
+// that owns the data. It panics on an invalid name.
+func Register(s Seeder) {
+	if !nameRe.MatchString(s.Name) {
+		panic(fmt.Sprintf("seed: invalid name %q, use letters, digits and _", s.Name))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	registry = append(registry, s)
+}
+
+// Seed names and environments end up in the SQL that records them
+var nameRe = regexp.MustCompile(`^\w+$`)
+
+// Written with plain SQL every dialect accepts, like schema_migrations
+const createHistoryTable = `CREATE TABLE IF NOT EXISTS seed_history (
+    name TEXT PRIMARY KEY,
+    env TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Seed runs the seeds for env that the database hasn't had yet, in name
+// order, and returns their names. It stops at the first that fails; the
+// ones before it stay applied.
+func Seed(ctx context.Context, db *database.DB, env string) ([]string, error) {
+	if !nameRe.MatchString(env) || env == AllEnvs {
+		return nil, fmt.Errorf("invalid environment %q", env)
+	}
+	seeds, err := seedsFor(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Conn.ExecContext(ctx, createHistoryTable); err != nil {
+		return nil, fmt.Errorf("failed to create seed_history: %w", err)
+	}
+	done, err := applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []string
+	for _, s := range seeds {
+		if done[s.Name] {
+			continue
+		}
+		err := db.InTx(ctx, func(tx *database.Tx) error {
+			if err := s.Run(ctx, tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO seed_history (name, env) VALUES ('%s', '%s')", s.Name, env))
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("seed %s: %w", s.Name, err)
+		}
+		ran = append(ran, s.Name)
+	}
+	return ran, nil
+}
+
+// seedsFor collects the embedded and registered seeds env runs, sorted by
+// name
+func seedsFor(env string) ([]Seeder, error) {
+	var seeds []Seeder
+	for _, dir := range []string{AllEnvs, env} {
+		entries, err := fs.ReadDir(files, path.Join("seeds", dir))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			name, ok := strings.CutSuffix(e.Name(), ".sql")
+			if e.IsDir() || !ok {
+				continue
+			}
+			if !nameRe.MatchString(name) {
+				return nil, fmt.Errorf("seed file %s/%s: invalid name, use letters, digits and _", dir, e.Name())
+			}
+			script, err := fs.ReadFile(files, path.Join("seeds", dir, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			seeds = append(seeds, Seeder{Name: name, Run: func(ctx context.Context, tx *database.Tx) error {
+				_, err := tx.Exec(ctx, string(script))
+				return err
+			}})
+		}
+	}
+
+	mu.Lock()
+	for _, s := range registry {
+		if slices.Contains(s.Envs, env) || slices.Contains(s.Envs, AllEnvs) {
+			seeds = append(seeds, s)
+		}
+	}
+	mu.Unlock()
+
+	slices.SortFunc(seeds, func(a, b Seeder) int { return strings.Compare(a.Name, b.Name) })
+	for i := 1; i < len(seeds); i++ {
+		if seeds[i].Name == seeds[i-1].Name {
+			return nil, fmt.Errorf("two seeds are named %s", seeds[i].Name)
+		}
+	}
+	return seeds, nil
+}
+
+// applied returns the names seed_history has, in any environment
+func applied(ctx context.Context, db *database.DB) (map[string]bool, error) {
+	rows, err := db.Conn.QueryContext(ctx, "SELECT name FROM seed_history")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed_history: %w", err)
+	}
+	defer rows.Close()
+
+	done := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		done[name] = true
+	}
+	return done, rows.Err()
+}
@@ -0,0 +1,3 @@
+-- Tags every environment offers groups from the start
+INSERT INTO tags (name) VALUES ('games'), ('news'), ('study'), ('chat')
+ON CONFLICT (name) DO NOTHING;
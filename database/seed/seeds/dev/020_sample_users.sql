@@ -0,0 +1,17 @@
+-- A few users and groups to click around in locally
+INSERT INTO users (telegram_id, first_name, username) VALUES
+    (1001, 'Ann', 'ann'),
+    (1002, 'Bob', 'bob'),
+    (1003, 'Cid', NULL)
+ON CONFLICT (telegram_id) DO NOTHING;
+
+INSERT INTO groups (telegram_id, title) VALUES
+    (-2001, 'Chess club'),
+    (-2002, 'Book club')
+ON CONFLICT (telegram_id) DO NOTHING;
+
+INSERT INTO user_group (user_telegram_id, group_telegram_id) VALUES
+    (1001, -2001),
+    (1002, -2001),
+    (1002, -2002)
+ON CONFLICT (user_telegram_id, group_telegram_id) DO NOTHING;
@@ -0,0 +1,433 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Shadow mirroring limits
+const (
+	shadowQueueSize      = 1000             // Writes waiting for the shadow before new ones are dropped
+	shadowKeepDivergence = 1000             // Divergences ShadowReport keeps, the most recent
+	shadowTimeout        = 30 * time.Second // Longest a replayed write may take
+)
+
+// Statements a transaction replay leaves out: bookkeeping of this process
+// (the outbox dispatcher, the job queue, the storage probe) the shadow
+// has no part in
+var shadowSkip = map[string]bool{
+	"InsertOutboxEvent":        true,
+	"ClaimOutboxEvents":        true,
+	"MarkOutboxEventDelivered": true,
+	"FailOutboxEvent":          true,
+	"EnqueueJob":               true,
+	"ClaimJobs":                true,
+	"CompleteJob":              true,
+	"RetryJob":                 true,
+	"BuryJob":                  true,
+	"RequeueDeadJob":           true,
+	"DeleteJob":                true,
+	"TouchStorageProbe":        true,
+}
+
+// ShadowDivergence is a write the shadow database didn't take the way the
+// primary did
+type ShadowDivergence struct {
+	At        time.Time // When the primary ran the write
+	Query     string    // sqlc query name, "other" for SQL that isn't a generated query
+	Kind      string    // "error", "result", "rows" or "dropped"
+	Detail    string    // The shadow's error, the fields that differ, or the row counts; never values
+	Args      []string  // Arguments, with strings and bytes reduced to their length
+	RequestID string    // From ContextWithRequestID, "-" if there was none
+}
+
+// ShadowReport is how the shadow database has kept up
+type ShadowReport struct {
+	Enabled     bool
+	Err         error              // Why there's no shadow although Config.ShadowDSN is set
+	Mirrored    int64              // Writes and transactions replayed on the shadow
+	Diverged    int64              // Writes the shadow took differently, dropped ones included
+	Dropped     int64              // Writes never replayed because the queue was full; the shadow is out of step after one
+	Pending     int                // Writes waiting to be replayed
+	Divergences []ShadowDivergence // The most recent ones, oldest first
+}
+
+// ShadowReport returns what the shadow database has diverged on so far.
+// It's the zero report without Config.ShadowDSN.
+func (db *DB) ShadowReport() ShadowReport {
+	m := db.shadow
+	if m == nil {
+		return ShadowReport{}
+	}
+	if m.db == nil {
+		return ShadowReport{Err: m.err}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return ShadowReport{
+		Enabled:     true,
+		Mirrored:    m.mirrored.Load(),
+		Diverged:    m.diverged.Load(),
+		Dropped:     m.dropped.Load(),
+		Pending:     len(m.queue),
+		Divergences: append([]ShadowDivergence(nil), m.recent...),
+	}
+}
+
+// shadowMirror replays the primary's writes on the shadow database, one at
+// a time and in order, from a goroutine of its own. The primary never
+// waits for it: a write that finds the queue full is dropped and reported.
+type shadowMirror struct {
+	db  *DB   // The shadow, nil if it couldn't be opened
+	err error // Why it couldn't
+
+	queue chan shadowOp
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+
+	mirrored, diverged, dropped atomic.Int64
+
+	mu     sync.Mutex
+	recent []ShadowDivergence
+}
+
+// shadowOp is one write, or one committed transaction, to replay. run
+// returns nil if the shadow agreed.
+type shadowOp struct {
+	ctx  context.Context
+	at   time.Time
+	name string
+	args []string
+	run  func(ctx context.Context) *ShadowDivergence
+}
+
+func newShadowMirror(shadow *DB) *shadowMirror {
+	m := &shadowMirror{
+		db:    shadow,
+		queue: make(chan shadowOp, shadowQueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go m.loop()
+	return m
+}
+
+// openShadow opens the shadow database cfg names, with its own copy of
+// the settings. A shadow that can't be opened is logged and left out, so
+// the primary carries on; ShadowReport tells why.
+func openShadow(cfg Config, d *dialect) *shadowMirror {
+	sc := cfg
+	sc.DSN, sc.Driver = cfg.ShadowDSN, cmp.Or(cfg.ShadowDriver, cfg.Driver)
+	sc.ShadowDSN, sc.ShadowDriver, sc.ConnectRetries = "", "", 0 // A missing shadow mustn't hold up startup
+	sc.Backup, sc.AuditRetention, sc.Maintenance, sc.Retention, sc.ReadDSNs, sc.SchemaCheck = BackupSchedule{}, 0, MaintenanceSchedule{}, RetentionPolicy{}, nil, "off"
+	sc.SlowQueries, sc.QueryLatency, sc.LogQueries, sc.QueryLogger, sc.TraceQueries, sc.Hooks = 0, false, false, nil, false, nil
+
+	m := &shadowMirror{}
+	if sd, err := dialectFor(sc.Driver); err != nil {
+		m.err = err
+	} else if sd != d {
+		m.err = fmt.Errorf("the shadow database must be %s like the primary, the build has one dialect's queries", d.name)
+	} else if m.db, m.err = Open(sc); m.err == nil {
+		return newShadowMirror(m.db)
+	}
+	m.err = fmt.Errorf("shadow database disabled: %w", m.err)
+	log.Print(m.err)
+	return m
+}
+
+func (m *shadowMirror) loop() {
+	defer close(m.done)
+	for {
+		select {
+		case op := <-m.queue:
+			m.replay(op)
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *shadowMirror) replay(op shadowOp) {
+	ctx, cancel := context.WithTimeout(op.ctx, shadowTimeout)
+	defer cancel()
+
+	div := op.run(ctx)
+	m.mirrored.Add(1)
+	if div != nil {
+		div.At, div.Args = op.at, op.args
+		div.Query = cmp.Or(div.Query, op.name)
+		div.RequestID, _ = statementIDs(op.ctx)
+		m.record(*div)
+	}
+}
+
+func (m *shadowMirror) record(div ShadowDivergence) {
+	m.diverged.Add(1)
+	m.mu.Lock()
+	if len(m.recent) == shadowKeepDivergence {
+		m.recent = m.recent[1:]
+	}
+	m.recent = append(m.recent, div)
+	m.mu.Unlock()
+}
+
+// enqueue hands a write to the shadow without waiting. ctx loses its
+// deadline and cancellation, since the request it came with is usually
+// over by the time the shadow gets to it, but keeps its request ID.
+func (m *shadowMirror) enqueue(ctx context.Context, name string, args []any, run func(ctx context.Context) *ShadowDivergence) {
+	if m == nil || m.db == nil {
+		return
+	}
+	op := shadowOp{ctx: context.WithoutCancel(ctx), at: time.Now(), name: name, run: run}
+	for _, a := range args {
+		op.args = append(op.args, redactArg(a))
+	}
+
+	select {
+	case <-m.stop:
+	case m.queue <- op:
+	default:
+		m.dropped.Add(1)
+		requestID, _ := statementIDs(ctx)
+		m.record(ShadowDivergence{
+			At: op.at, Query: name, Kind: "dropped", Args: op.args, RequestID: requestID,
+			Detail: "the shadow fell behind; this write was never replayed",
+		})
+	}
+}
+
+// close stops the replay, dropping what's still queued, and closes the
+// shadow if it was opened from Config.ShadowDSN
+func (m *shadowMirror) close() error {
+	if m == nil || m.db == nil {
+		return nil
+	}
+	m.once.Do(func() { close(m.stop) })
+	<-m.done
+	return m.db.Close()
+}
+
+// shadowResult replays a write that returns something on the shadow, if
+// it succeeded on the primary, and reports a different result
+func shadowResult[T any](m *shadowMirror, ctx context.Context, name string, arg any, res T, err error, call func(context.Context, Querier) (T, error)) (T, error) {
+	if err != nil || m == nil {
+		return res, err
+	}
+	m.enqueue(ctx, name, flattenArgs(arg), func(ctx context.Context) *ShadowDivergence {
+		got, err := call(ctx, m.db.Q)
+		if err != nil {
+			return &ShadowDivergence{Kind: "error", Detail: err.Error()}
+		}
+		if n, ok := any(res).(int64); ok {
+			if g := any(got).(int64); g != n {
+				return &ShadowDivergence{Kind: "rows", Detail: fmt.Sprintf("%d rows affected, %d on the shadow", n, g)}
+			}
+			return nil
+		}
+		if fields := shadowDiff(res, got); len(fields) > 0 {
+			return &ShadowDivergence{Kind: "result", Detail: "different " + strings.Join(fields, ", ")}
+		}
+		return nil
+	})
+	return res, err
+}
+
+// shadowExec is shadowResult for writes that only return an error
+func shadowExec(m *shadowMirror, ctx context.Context, name string, arg any, err error, call func(context.Context, Querier) error) error {
+	if err != nil || m == nil {
+		return err
+	}
+	m.enqueue(ctx, name, flattenArgs(arg), func(ctx context.Context) *ShadowDivergence {
+		if err := call(ctx, m.db.Q); err != nil {
+			return &ShadowDivergence{Kind: "error", Detail: err.Error()}
+		}
+		return nil
+	})
+	return nil
+}
+
+var nullTimeType = reflect.TypeOf(sql.Null[time.Time]{})
+
+// shadowDiff names the fields of two results that differ. Timestamps are
+// left out: the shadow runs the write a moment later, so CURRENT_TIMESTAMP
+// defaults differ without anything being wrong.
+func shadowDiff(a, b any) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(a, b) {
+			return []string{"value"}
+		}
+		return nil
+	}
+	var fields []string
+	for i := 0; i < va.NumField(); i++ {
+		f := va.Type().Field(i)
+		if f.Type == timeType || f.Type == nullTimeType {
+			continue
+		}
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			fields = append(fields, f.Name)
+		}
+	}
+	return fields
+}
+
+// flattenArgs lists the fields of a Params struct, or arg itself, for
+// redactArg
+func flattenArgs(arg any) []any {
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Struct {
+		return []any{arg}
+	}
+	args := make([]any, v.NumField())
+	for i := range args {
+		args[i] = v.Field(i).Interface()
+	}
+	return args
+}
+
+// shadowStmt is a write a transaction ran, to replay it
+type shadowStmt struct {
+	query string
+	args  []any
+	rows  int64 // Rows affected, -1 for statements run as queries (RETURNING)
+}
+
+// shadowTxDBTX records a transaction's writes and, once it commits, hands
+// them to the shadow to replay in a transaction of its own. Statements go
+// to the shadow as they were run, so only rows affected and errors are
+// compared; RETURNING values aren't seen.
+type shadowTxDBTX struct {
+	DBTX
+	m          *shadowMirror
+	tx         *Tx
+	stmts      []shadowStmt
+	savepoints []shadowSavepoint
+}
+
+// shadowSavepoint is an open SAVEPOINT and how many writes came before it
+type shadowSavepoint struct {
+	name  string
+	stmts int
+}
+
+// savepoint keeps the recorded writes in step with SAVEPOINT, ROLLBACK TO
+// and RELEASE, so writes a savepoint undid aren't replayed. It reports
+// whether query was one of them.
+func (d *shadowTxDBTX) savepoint(query string) bool {
+	f := strings.Fields(strings.ToUpper(query))
+	if len(f) < 2 {
+		return false
+	}
+	name := f[len(f)-1]
+	open := len(d.savepoints) - 1 // The newest of that name, as the databases pick
+	for open >= 0 && d.savepoints[open].name != name {
+		open--
+	}
+	switch {
+	case f[0] == "SAVEPOINT":
+		d.savepoints = append(d.savepoints, shadowSavepoint{name: name, stmts: len(d.stmts)})
+	case f[0] == "ROLLBACK" && f[1] == "TO":
+		if open >= 0 {
+			d.stmts = d.stmts[:d.savepoints[open].stmts]
+			d.savepoints = d.savepoints[:open+1]
+		}
+	case f[0] == "RELEASE":
+		if open >= 0 {
+			d.savepoints = d.savepoints[:open]
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (d *shadowTxDBTX) note(query string, args []any, rows int64) {
+	if !isWriteQuery(query) || shadowSkip[queryName(query)] {
+		return
+	}
+	if d.stmts == nil {
+		d.tx.OnCommit(func() {
+			d.m.enqueue(d.tx.ctx, "transaction", nil, func(ctx context.Context) *ShadowDivergence {
+				return replayTx(ctx, d.m.db, d.stmts)
+			})
+		})
+	}
+	d.stmts = append(d.stmts, shadowStmt{query: query, args: args, rows: rows})
+}
+
+func (d *shadowTxDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := d.DBTX.ExecContext(ctx, query, args...)
+	if err == nil && !d.savepoint(query) {
+		rows := int64(-1)
+		if n, err := res.RowsAffected(); err == nil {
+			rows = n
+		}
+		d.note(query, args, rows)
+	}
+	return res, err
+}
+
+func (d *shadowTxDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := d.DBTX.QueryContext(ctx, query, args...)
+	if err == nil {
+		d.note(query, args, -1)
+	}
+	return rows, err
+}
+
+// A *sql.Row holds its error until Scan; if the statement failed, the
+// transaction normally rolls back and nothing is replayed
+func (d *shadowTxDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := d.DBTX.QueryRowContext(ctx, query, args...)
+	d.note(query, args, -1)
+	return row
+}
+
+// replayTx runs a committed transaction's writes on the shadow, all or
+// nothing, and reports the first statement that went differently
+func replayTx(ctx context.Context, shadow *DB, stmts []shadowStmt) *ShadowDivergence {
+	var div *ShadowDivergence
+	err := shadow.InTx(ctx, func(tx *Tx) error {
+		for i, s := range stmts {
+			at := fmt.Sprintf("statement %d of %d", i+1, len(stmts))
+			if s.rows < 0 {
+				rows, err := tx.queries.db.QueryContext(ctx, s.query, s.args...)
+				if err == nil {
+					for rows.Next() {
+					}
+					err = cmp.Or(rows.Err(), rows.Close())
+				}
+				if err != nil {
+					div = &ShadowDivergence{Query: queryName(s.query), Kind: "error", Detail: at + ": " + err.Error()}
+					return err
+				}
+				continue
+			}
+
+			res, err := tx.queries.db.ExecContext(ctx, s.query, s.args...)
+			if err != nil {
+				div = &ShadowDivergence{Query: queryName(s.query), Kind: "error", Detail: at + ": " + err.Error()}
+				return err
+			}
+			if n, err := res.RowsAffected(); err == nil && n != s.rows && div == nil {
+				div = &ShadowDivergence{Query: queryName(s.query), Kind: "rows", Detail: fmt.Sprintf("%s: %d rows affected, %d on the shadow", at, s.rows, n)}
+			}
+		}
+		return nil
+	})
+	if err != nil && div == nil {
+		div = &ShadowDivergence{Kind: "error", Detail: err.Error()}
+	}
+	return div
+}
@@ -0,0 +1,236 @@
+package database
+
+import "context"
+
+// shadowQuerier is db.Q with a shadow database: the writes below are
+// replayed on the shadow once they succeed, and what it returns is compared
+// with the primary's result. Every other method goes to the primary alone.
+// A new write query needs a method here too, or it isn't mirrored.
+type shadowQuerier struct {
+	Querier
+	m *shadowMirror
+}
+
+func (s *shadowQuerier) AddToUserGroupBalance(ctx context.Context, arg AddToUserGroupBalanceParams) (UserGroup, error) {
+	res, err := s.Querier.AddToUserGroupBalance(ctx, arg)
+	return shadowResult(s.m, ctx, "AddToUserGroupBalance", arg, res, err, func(ctx context.Context, q Querier) (UserGroup, error) {
+		return q.AddToUserGroupBalance(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) AttachTag(ctx context.Context, arg AttachTagParams) error {
+	err := s.Querier.AttachTag(ctx, arg)
+	return shadowExec(s.m, ctx, "AttachTag", arg, err, func(ctx context.Context, q Querier) error {
+		return q.AttachTag(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error) {
+	res, err := s.Querier.CreateCategory(ctx, arg)
+	return shadowResult(s.m, ctx, "CreateCategory", arg, res, err, func(ctx context.Context, q Querier) (Category, error) {
+		return q.CreateCategory(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error) {
+	res, err := s.Querier.CreateGroup(ctx, arg)
+	return shadowResult(s.m, ctx, "CreateGroup", arg, res, err, func(ctx context.Context, q Querier) (Group, error) {
+		return q.CreateGroup(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) CreateGroupIfMissing(ctx context.Context, arg CreateGroupIfMissingParams) (Group, error) {
+	res, err := s.Querier.CreateGroupIfMissing(ctx, arg)
+	return shadowResult(s.m, ctx, "CreateGroupIfMissing", arg, res, err, func(ctx context.Context, q Querier) (Group, error) {
+		return q.CreateGroupIfMissing(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	res, err := s.Querier.CreateUser(ctx, arg)
+	return shadowResult(s.m, ctx, "CreateUser", arg, res, err, func(ctx context.Context, q Querier) (User, error) {
+		return q.CreateUser(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) CreateUserGroup(ctx context.Context, arg CreateUserGroupParams) (UserGroup, error) {
+	res, err := s.Querier.CreateUserGroup(ctx, arg)
+	return shadowResult(s.m, ctx, "CreateUserGroup", arg, res, err, func(ctx context.Context, q Querier) (UserGroup, error) {
+		return q.CreateUserGroup(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) CreateUserIfMissing(ctx context.Context, arg CreateUserIfMissingParams) (User, error) {
+	res, err := s.Querier.CreateUserIfMissing(ctx, arg)
+	return shadowResult(s.m, ctx, "CreateUserIfMissing", arg, res, err, func(ctx context.Context, q Querier) (User, error) {
+		return q.CreateUserIfMissing(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) DeleteAttachment(ctx context.Context, id int64) error {
+	err := s.Querier.DeleteAttachment(ctx, id)
+	return shadowExec(s.m, ctx, "DeleteAttachment", id, err, func(ctx context.Context, q Querier) error {
+		return q.DeleteAttachment(ctx, id)
+	})
+}
+
+func (s *shadowQuerier) DeleteGroup(ctx context.Context, telegramID int64) (Group, error) {
+	res, err := s.Querier.DeleteGroup(ctx, telegramID)
+	return shadowResult(s.m, ctx, "DeleteGroup", telegramID, res, err, func(ctx context.Context, q Querier) (Group, error) {
+		return q.DeleteGroup(ctx, telegramID)
+	})
+}
+
+func (s *shadowQuerier) DetachTag(ctx context.Context, arg DetachTagParams) error {
+	err := s.Querier.DetachTag(ctx, arg)
+	return shadowExec(s.m, ctx, "DetachTag", arg, err, func(ctx context.Context, q Querier) error {
+		return q.DetachTag(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) GetOrCreateUserGroup(ctx context.Context, arg GetOrCreateUserGroupParams) (UserGroup, error) {
+	res, err := s.Querier.GetOrCreateUserGroup(ctx, arg)
+	return shadowResult(s.m, ctx, "GetOrCreateUserGroup", arg, res, err, func(ctx context.Context, q Querier) (UserGroup, error) {
+		return q.GetOrCreateUserGroup(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) PruneAuditLog(ctx context.Context, arg PruneAuditLogParams) (int64, error) {
+	res, err := s.Querier.PruneAuditLog(ctx, arg)
+	return shadowResult(s.m, ctx, "PruneAuditLog", arg, res, err, func(ctx context.Context, q Querier) (int64, error) {
+		return q.PruneAuditLog(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) PurgeDeletedUsers(ctx context.Context, olderThanSeconds int64) (int64, error) {
+	res, err := s.Querier.PurgeDeletedUsers(ctx, olderThanSeconds)
+	return shadowResult(s.m, ctx, "PurgeDeletedUsers", olderThanSeconds, res, err, func(ctx context.Context, q Querier) (int64, error) {
+		return q.PurgeDeletedUsers(ctx, olderThanSeconds)
+	})
+}
+
+func (s *shadowQuerier) PutAttachment(ctx context.Context, arg PutAttachmentParams) (PutAttachmentRow, error) {
+	res, err := s.Querier.PutAttachment(ctx, arg)
+	return shadowResult(s.m, ctx, "PutAttachment", arg, res, err, func(ctx context.Context, q Querier) (PutAttachmentRow, error) {
+		return q.PutAttachment(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) RenameCategory(ctx context.Context, arg RenameCategoryParams) (Category, error) {
+	res, err := s.Querier.RenameCategory(ctx, arg)
+	return shadowResult(s.m, ctx, "RenameCategory", arg, res, err, func(ctx context.Context, q Querier) (Category, error) {
+		return q.RenameCategory(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) ReplaceNationalIDCiphertext(ctx context.Context, arg ReplaceNationalIDCiphertextParams) (int64, error) {
+	res, err := s.Querier.ReplaceNationalIDCiphertext(ctx, arg)
+	return shadowResult(s.m, ctx, "ReplaceNationalIDCiphertext", arg, res, err, func(ctx context.Context, q Querier) (int64, error) {
+		return q.ReplaceNationalIDCiphertext(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) RestoreUser(ctx context.Context, id int64) (int64, error) {
+	res, err := s.Querier.RestoreUser(ctx, id)
+	return shadowResult(s.m, ctx, "RestoreUser", id, res, err, func(ctx context.Context, q Querier) (int64, error) {
+		return q.RestoreUser(ctx, id)
+	})
+}
+
+func (s *shadowQuerier) SetCategoryParent(ctx context.Context, arg SetCategoryParentParams) (Category, error) {
+	res, err := s.Querier.SetCategoryParent(ctx, arg)
+	return shadowResult(s.m, ctx, "SetCategoryParent", arg, res, err, func(ctx context.Context, q Querier) (Category, error) {
+		return q.SetCategoryParent(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) SetUserNationalID(ctx context.Context, arg SetUserNationalIDParams) error {
+	err := s.Querier.SetUserNationalID(ctx, arg)
+	return shadowExec(s.m, ctx, "SetUserNationalID", arg, err, func(ctx context.Context, q Querier) error {
+		return q.SetUserNationalID(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) SoftDeleteUser(ctx context.Context, id int64) (int64, error) {
+	res, err := s.Querier.SoftDeleteUser(ctx, id)
+	return shadowResult(s.m, ctx, "SoftDeleteUser", id, res, err, func(ctx context.Context, q Querier) (int64, error) {
+		return q.SoftDeleteUser(ctx, id)
+	})
+}
+
+func (s *shadowQuerier) UpdateStatusByIDs(ctx context.Context, arg UpdateStatusByIDsParams) (int64, error) {
+	res, err := s.Querier.UpdateStatusByIDs(ctx, arg)
+	return shadowResult(s.m, ctx, "UpdateStatusByIDs", arg, res, err, func(ctx context.Context, q Querier) (int64, error) {
+		return q.UpdateStatusByIDs(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	res, err := s.Querier.UpdateUser(ctx, arg)
+	return shadowResult(s.m, ctx, "UpdateUser", arg, res, err, func(ctx context.Context, q Querier) (User, error) {
+		return q.UpdateUser(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) UpdateUserBalanceChats(ctx context.Context, arg UpdateUserBalanceChatsParams) (User, error) {
+	res, err := s.Querier.UpdateUserBalanceChats(ctx, arg)
+	return shadowResult(s.m, ctx, "UpdateUserBalanceChats", arg, res, err, func(ctx context.Context, q Querier) (User, error) {
+		return q.UpdateUserBalanceChats(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) UpdateUserBalanceChatsIfVersion(ctx context.Context, arg UpdateUserBalanceChatsIfVersionParams) (int64, error) {
+	res, err := s.Querier.UpdateUserBalanceChatsIfVersion(ctx, arg)
+	return shadowResult(s.m, ctx, "UpdateUserBalanceChatsIfVersion", arg, res, err, func(ctx context.Context, q Querier) (int64, error) {
+		return q.UpdateUserBalanceChatsIfVersion(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) UpdateUserGroupBalance(ctx context.Context, arg UpdateUserGroupBalanceParams) (UserGroup, error) {
+	res, err := s.Querier.UpdateUserGroupBalance(ctx, arg)
+	return shadowResult(s.m, ctx, "UpdateUserGroupBalance", arg, res, err, func(ctx context.Context, q Querier) (UserGroup, error) {
+		return q.UpdateUserGroupBalance(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) UpdateUserIfVersion(ctx context.Context, arg UpdateUserIfVersionParams) (int64, error) {
+	res, err := s.Querier.UpdateUserIfVersion(ctx, arg)
+	return shadowResult(s.m, ctx, "UpdateUserIfVersion", arg, res, err, func(ctx context.Context, q Querier) (int64, error) {
+		return q.UpdateUserIfVersion(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) UpsertGroup(ctx context.Context, arg UpsertGroupParams) (Group, error) {
+	res, err := s.Querier.UpsertGroup(ctx, arg)
+	return shadowResult(s.m, ctx, "UpsertGroup", arg, res, err, func(ctx context.Context, q Querier) (Group, error) {
+		return q.UpsertGroup(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) UpsertTag(ctx context.Context, name string) (Tag, error) {
+	res, err := s.Querier.UpsertTag(ctx, name)
+	return shadowResult(s.m, ctx, "UpsertTag", name, res, err, func(ctx context.Context, q Querier) (Tag, error) {
+		return q.UpsertTag(ctx, name)
+	})
+}
+
+func (s *shadowQuerier) UpsertUser(ctx context.Context, arg UpsertUserParams) (User, error) {
+	res, err := s.Querier.UpsertUser(ctx, arg)
+	return shadowResult(s.m, ctx, "UpsertUser", arg, res, err, func(ctx context.Context, q Querier) (User, error) {
+		return q.UpsertUser(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) UpsertUserByEmail(ctx context.Context, arg UpsertUserByEmailParams) (User, error) {
+	res, err := s.Querier.UpsertUserByEmail(ctx, arg)
+	return shadowResult(s.m, ctx, "UpsertUserByEmail", arg, res, err, func(ctx context.Context, q Querier) (User, error) {
+		return q.UpsertUserByEmail(ctx, arg)
+	})
+}
+
+func (s *shadowQuerier) UpsertUserGroupBalance(ctx context.Context, arg UpsertUserGroupBalanceParams) (UserGroup, error) {
+	res, err := s.Querier.UpsertUserGroupBalance(ctx, arg)
+	return shadowResult(s.m, ctx, "UpsertUserGroupBalance", arg, res, err, func(ctx context.Context, q Querier) (UserGroup, error) {
+		return q.UpsertUserGroupBalance(ctx, arg)
+	})
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrShuttingDown means Shutdown has begun: statements and transactions
+// that weren't already running are refused
+var ErrShuttingDown = errors.New("database is shutting down")
+
+// How often Shutdown looks whether the pool has gone idle
+const shutdownPollInterval = 10 * time.Millisecond
+
+// Shutdown closes the DB once the work it's doing is done. It marks the
+// DB not ready, as Drain does, and shuts down the WriteBatchers and
+// Writers still running, which write what they have queued. It refuses
+// new statements outside a transaction and new transactions with
+// ErrShuttingDown from then on, and waits until no connection is in
+// use: the transactions running may finish, statements nested in them
+// included, and rows being read are read to the end. With SQLite it then
+// checkpoints the WAL, so the database file is complete on its own,
+// unless replication.external_checkpoints leaves that to the replicator,
+// and last it closes the DB.
+//
+// If ctx ends first, Shutdown closes the DB anyway, without the
+// checkpoint, and returns ctx's error. Statements still running on the
+// server hold up the close until they finish, as with Close.
+//
+// Call it after the HTTP server's Shutdown and with the background
+// workers stopped, so nothing still needs the database:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	if err := db.Shutdown(ctx); err != nil {
+//		log.Printf("database shutdown: %v", err)
+//	}
+func (db *DB) Shutdown(ctx context.Context) error {
+	db.Drain()
+	flushErr := db.batchers.shutdown(ctx)
+	db.shuttingDown.Store(true)
+	// The scheduled jobs would only be refused from now on
+	if db.backups.stop != nil {
+		db.backups.stop()
+	}
+	if db.stopAuditPrune != nil {
+		db.stopAuditPrune()
+	}
+	if db.stopRetention != nil {
+		db.stopRetention()
+	}
+	if db.maintenance.stop != nil {
+		db.maintenance.stop()
+	}
+	if db.replication.stop != nil {
+		db.replication.stop()
+	}
+
+	err := db.waitIdle(ctx)
+	if d := defaultDialect(); err == nil && d.checkpoint != nil && db.Conn != nil && !db.replication.external {
+		err = d.checkpoint(ctx, db.Conn)
+	}
+	return errors.Join(flushErr, err, db.Close())
+}
+
+// waitIdle waits until no connection of the pool is in use
+func (db *DB) waitIdle(ctx context.Context) error {
+	if db.Conn == nil {
+		return nil
+	}
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		inUse := db.Conn.Stats().InUse
+		if inUse == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d connections still in use: %w", inUse, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SlowQueryRecord is one of the slowest executions of a query
+type SlowQueryRecord struct {
+	Query    string // sqlc query name, "other" for SQL that isn't a generated query
+	Duration time.Duration
+	At       time.Time // When the statement started
+	Args     []string  // Arguments, as Config.Redaction shows them
+	Rows     int64     // Rows affected by an Exec, or read from a query; -1 if it failed before that
+	Err      error
+
+	RequestID string // From ContextWithRequestID, "-" if the context had none
+	TraceID   string // Of the OpenTelemetry span in the context (-tags otel), "-" if none
+}
+
+// slowLog keeps the k slowest executions per query name. Names come from
+// the generated SQL, so memory is bounded by k times the number of queries.
+type slowLog struct {
+	k      int
+	redact *redactor
+	mu     sync.Mutex
+	byName map[string][]SlowQueryRecord // Slowest first, at most k
+}
+
+func newSlowLog(k int, redact *redactor) *slowLog {
+	if k <= 0 {
+		return nil
+	}
+	return &slowLog{k: k, redact: redact, byName: map[string][]SlowQueryRecord{}}
+}
+
+func (l *slowLog) record(ctx context.Context, query, name string, d time.Duration, args []any, start time.Time, rows int64, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recs := l.byName[name]
+	if len(recs) == l.k && d <= recs[len(recs)-1].Duration {
+		return
+	}
+
+	rec := SlowQueryRecord{Query: name, Duration: d, At: start, Args: l.redact.render(query, args, ""), Rows: rows, Err: err}
+	rec.RequestID, rec.TraceID = statementIDs(ctx)
+
+	i, _ := slices.BinarySearchFunc(recs, d, func(r SlowQueryRecord, d time.Duration) int {
+		return cmp.Compare(d, r.Duration)
+	})
+	recs = slices.Insert(recs, i, rec)
+	if len(recs) > l.k {
+		recs = recs[:l.k]
+	}
+	l.byName[name] = recs
+}
+
+// SlowQueries returns the slowest recorded executions of every query,
+// slowest first. It's empty unless Config.SlowQueries is set.
+func (db *DB) SlowQueries() []SlowQueryRecord {
+	if db.slow == nil {
+		return nil
+	}
+
+	db.slow.mu.Lock()
+	var all []SlowQueryRecord
+	for _, recs := range db.slow.byName {
+		all = append(all, recs...)
+	}
+	db.slow.mu.Unlock()
+
+	slices.SortFunc(all, func(a, b SlowQueryRecord) int { return cmp.Compare(b.Duration, a.Duration) })
+	return all
+}
+
+// ResetSlowQueries forgets everything SlowQueries has recorded
+func (db *DB) ResetSlowQueries() {
+	if db.slow == nil {
+		return
+	}
+	db.slow.mu.Lock()
+	clear(db.slow.byName)
+	db.slow.mu.Unlock()
+}
+
+// Strings and bytes may hold personal data, so only their size is kept,
+// RedactLength's way
+func redactArg(v any) string {
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return "<invalid>"
+		}
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("<%d chars>", len(v))
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return fmt.Sprint(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("<%T>", v)
+	}
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Snapshot is a copy of a database, taken by DB.Snapshot, that CloneFrom
+// makes new databases from in about the time it takes to copy the data,
+// instead of running the schema, migrations and seeds again: for test
+// suites, and for preview environments that start out populated. It's
+// JSON-ready, so a CI job can take one and hand it to later jobs.
+type Snapshot struct {
+	Name   string `json:"name"`   // The file (SQLite) or template database (PostgreSQL) holding the copy
+	System string `json:"system"` // The kind of database it's a copy of: "sqlite" or "postgresql"
+}
+
+func snapshotSupported(d *dialect) error {
+	if d.snapshot == nil {
+		return fmt.Errorf("%s has no snapshots here", d.name)
+	}
+	return nil
+}
+
+// Snapshot copies the database, as it is when the copy begins, into the
+// snapshot name, replacing one already there. For SQLite, name is the
+// file to write, with VACUUM INTO, while writers carry on. For PostgreSQL
+// it's a database created on the same server with the live one as its
+// TEMPLATE, which the server can only copy while nothing is connected to
+// it: Snapshot closes the DB's idle connections, and fails while another
+// one is in use, another process's included. Take it before the app gets
+// busy, or from a DB opened for the purpose.
+func (db *DB) Snapshot(ctx context.Context, name string) (Snapshot, error) {
+	d := defaultDialect()
+	if err := snapshotSupported(d); err != nil {
+		return Snapshot{}, err
+	}
+	if name == "" {
+		return Snapshot{}, errors.New("a snapshot needs a name")
+	}
+	if err := d.snapshot(ctx, db.Conn, db.dsn, name, db.resetIdleConns); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to take snapshot %s: %w", name, err)
+	}
+	return Snapshot{Name: name, System: d.system}, nil
+}
+
+// DropSnapshot deletes a snapshot from Snapshot. Databases cloned from it
+// don't depend on it.
+func (db *DB) DropSnapshot(ctx context.Context, snap Snapshot) error {
+	d := defaultDialect()
+	if err := snapshotSupported(d); err != nil {
+		return err
+	}
+	if snap.System != d.system {
+		return fmt.Errorf("snapshot %s is of a %s database, not %s", snap.Name, snap.System, d.name)
+	}
+	if err := d.dropSnapshot(ctx, db.dsn, snap.Name); err != nil {
+		return fmt.Errorf("failed to drop snapshot %s: %w", snap.Name, err)
+	}
+	return nil
+}
+
+// CloneFrom creates the database cfg points at as a copy of snap and
+// opens it, applying the migrations the snapshot predates. The database
+// mustn't exist yet: for SQLite, cfg.DSN is a file CloneFrom copies the
+// snapshot to; for PostgreSQL, a database it creates with the snapshot as
+// its template, which needs the CREATEDB privilege. A clone is a
+// database of its own, so any number of them can be used at once.
+func CloneFrom(ctx context.Context, snap Snapshot, cfg Config) (*DB, error) {
+	d, err := dialectFor(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+	if err := snapshotSupported(d); err != nil {
+		return nil, err
+	}
+	if snap.System != d.system {
+		return nil, fmt.Errorf("snapshot %s is of a %s database, not %s", snap.Name, snap.System, d.name)
+	}
+	if err := d.clone(ctx, snap.Name, cfg); err != nil {
+		return nil, fmt.Errorf("failed to clone snapshot %s: %w", snap.Name, err)
+	}
+	return OpenContext(ctx, cfg)
+}
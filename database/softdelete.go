@@ -0,0 +1,278 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Soft-deleted users (SoftDeleteUser) keep their row with deleted_at set,
+// and the queries that read users filter them out with "deleted_at IS
+// NULL". WithDeleted and OnlyDeleted get past that filter by rewriting it
+// in the reads they make.
+type deletedScope int
+
+const (
+	scopeWithDeleted deletedScope = iota + 1 // Live and deleted rows
+	scopeOnlyDeleted                         // Deleted rows only
+)
+
+// The soft delete filter as the queries write it, with or without a table
+// alias
+var liveFilterRe = regexp.MustCompile(`\b((?:\w+\.)?deleted_at) IS NULL\b`)
+
+// softDeleteDBTX rewrites the soft delete filter of reads for a scope;
+// writes, such as SoftDeleteUser's own check, run as written
+type softDeleteDBTX struct {
+	DBTX
+	scope deletedScope
+}
+
+func (d *softDeleteDBTX) rewrite(query string) string {
+	if kw := firstKeyword(query); kw != "SELECT" && kw != "WITH" {
+		return query
+	}
+	if d.scope == scopeOnlyDeleted {
+		return liveFilterRe.ReplaceAllString(query, "$1 IS NOT NULL")
+	}
+	return liveFilterRe.ReplaceAllString(query, "TRUE")
+}
+
+func (d *softDeleteDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.DBTX.QueryContext(ctx, d.rewrite(query), args...)
+}
+
+func (d *softDeleteDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.DBTX.QueryRowContext(ctx, d.rewrite(query), args...)
+}
+
+// WithDeleted returns db.Q with soft-deleted users included wherever the
+// queries would leave them out, for admin screens and audits
+func (db *DB) WithDeleted() Querier {
+	return db.scoped(scopeWithDeleted)
+}
+
+// OnlyDeleted returns db.Q showing just the soft-deleted users, for a
+// trash view before RestoreUser
+func (db *DB) OnlyDeleted() Querier {
+	return db.scoped(scopeOnlyDeleted)
+}
+
+func (db *DB) scoped(scope deletedScope) Querier {
+	var q Querier = translatingQuerier{New(&softDeleteDBTX{DBTX: db.wrap(db.routeReads(db.Conn)), scope: scope})}
+	if db.shadow != nil && db.shadow.db != nil {
+		q = &shadowQuerier{Querier: q, m: db.shadow}
+	}
+	return q
+}
+
+// WithDeleted is DB.WithDeleted inside the transaction
+func (t *Tx) WithDeleted() *Queries {
+	return New(&softDeleteDBTX{DBTX: t.queries.db, scope: scopeWithDeleted})
+}
+
+// OnlyDeleted is DB.OnlyDeleted inside the transaction
+func (t *Tx) OnlyDeleted() *Queries {
+	return New(&softDeleteDBTX{DBTX: t.queries.db, scope: scopeOnlyDeleted})
+}
+
+// Purge hard-deletes the users soft-deleted more than olderThan ago and
+// returns how many, with PurgeSoftDeleted. Their memberships and national
+// IDs go too, and user_history records the deletion. Run it from a
+// scheduled job, StartPurgeLoop, or app db purge; with users cached,
+// invalidate users and user_group after it, or call
+// CachedQueries.PurgeDeletedUsers instead.
+func (db *DB) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	report, err := PurgeSoftDeleted(ctx, db, olderThan)
+	return report.Rows("users"), err
+}
+
+// softDeleteTable is a table with a deleted_at column. Its children are
+// the tables whose rows point at it by key; they're deleted first.
+type softDeleteTable struct {
+	table    string
+	key      string
+	children []softDeleteChild
+}
+
+type softDeleteChild struct {
+	table  string
+	column string // References the parent's key
+}
+
+// softDeleteTables in the order they're purged: a table before any it
+// references, as its children are
+var softDeleteTables = []softDeleteTable{
+	{table: "users", key: "telegram_id", children: []softDeleteChild{
+		{table: "user_group", column: "user_telegram_id"},
+		{table: "user_national_ids", column: "user_telegram_id"},
+	}},
+}
+
+const (
+	purgeBatch    = 500 // Parent rows per transaction
+	purgeInterval = 24 * time.Hour
+)
+
+// PurgeReport is what a PurgeSoftDeleted run removed
+type PurgeReport struct {
+	Tables   []PurgedTable // In the order they were purged, children first
+	Duration time.Duration
+}
+
+// PurgedTable is what PurgeSoftDeleted removed from one table
+type PurgedTable struct {
+	Table   string
+	Rows    int64
+	Batches int // Transactions that deleted from it
+}
+
+// Rows returns how many rows were purged from table
+func (r PurgeReport) Rows(table string) int64 {
+	for _, t := range r.Tables {
+		if t.Table == table {
+			return t.Rows
+		}
+	}
+	return 0
+}
+
+func (r PurgeReport) String() string {
+	parts := make([]string, 0, len(r.Tables))
+	for _, t := range r.Tables {
+		parts = append(parts, fmt.Sprintf("%s: %d", t.Table, t.Rows))
+	}
+	return fmt.Sprintf("%s rows in %v", strings.Join(parts, ", "), r.Duration.Round(time.Millisecond))
+}
+
+// PurgeSoftDeleted hard-deletes the rows soft-deleted more than olderThan
+// ago, by this process's clock, from every table that soft-deletes, and
+// the rows pointing at them first: a user's memberships and national IDs
+// before the user. It goes 500 rows at a time, children and parents in
+// one transaction, so other writers wait at most one batch. On an error
+// the batches before stay committed, and the report counts them.
+func PurgeSoftDeleted(ctx context.Context, db *DB, olderThan time.Duration) (PurgeReport, error) {
+	start := time.Now()
+	if olderThan < 0 {
+		return PurgeReport{}, fmt.Errorf("purge: negative age %v", olderThan)
+	}
+	d := defaultDialect()
+	cutoff := start.Add(-olderThan).UTC()
+
+	var report PurgeReport
+	for _, t := range softDeleteTables {
+		counts := make([]PurgedTable, len(t.children)+1)
+		for i, c := range t.children {
+			counts[i].Table = c.table
+		}
+		counts[len(t.children)].Table = t.table
+
+		for more := true; more; {
+			err := db.InTx(ctx, func(tx *Tx) (err error) {
+				more, err = purgeBatchOf(ctx, d, tx, t, cutoff, counts)
+				return err
+			})
+			if err != nil {
+				report.Tables = append(report.Tables, counts...)
+				report.Duration = time.Since(start)
+				return report, fmt.Errorf("purge of %s: %w", t.table, err)
+			}
+		}
+		report.Tables = append(report.Tables, counts...)
+	}
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// purgeBatchOf deletes up to purgeBatch of t's purgeable rows, their
+// children first, adding to counts (the children's, then t's), and
+// reports whether a full batch was found, so there may be more
+func purgeBatchOf(ctx context.Context, d *dialect, tx *Tx, t softDeleteTable, cutoff time.Time, counts []PurgedTable) (bool, error) {
+	q := &queryBuilder{numbered: d.numberedParams}
+	rows, err := tx.queries.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM %s WHERE deleted_at IS NOT NULL AND %s < %s ORDER BY %s LIMIT %d",
+		t.key, t.table, fmt.Sprintf(d.timeOrder, "deleted_at"), fmt.Sprintf(d.timeOrder, q.param(cutoff)), t.key, purgeBatch), q.args...)
+	if err != nil {
+		return false, err
+	}
+	var keys []any
+	for rows.Next() {
+		var k any
+		if err := rows.Scan(&k); err != nil {
+			rows.Close()
+			return false, err
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	q = &queryBuilder{numbered: d.numberedParams}
+	params := make([]string, len(keys))
+	for i, k := range keys {
+		params[i] = q.param(k)
+	}
+	in := strings.Join(params, ", ")
+	deletes := make([]string, 0, len(counts))
+	for _, c := range t.children {
+		deletes = append(deletes, fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", c.table, c.column, in))
+	}
+	deletes = append(deletes, fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", t.table, t.key, in))
+	for i, del := range deletes {
+		res, err := tx.Exec(ctx, del, q.args...)
+		if err != nil {
+			return false, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			counts[i].Rows += n
+			counts[i].Batches++
+		}
+	}
+	return len(keys) == purgeBatch, nil
+}
+
+// StartPurgeLoop runs PurgeSoftDeleted every interval (daily if 0) until
+// ctx is canceled, logging what each run removed and the failures
+func (db *DB) StartPurgeLoop(ctx context.Context, interval, olderThan time.Duration) error {
+	if interval < 0 || olderThan < 0 {
+		return fmt.Errorf("purge loop: negative interval %v or age %v", interval, olderThan)
+	}
+
+	go func() {
+		ticker := time.NewTicker(cmp.Or(interval, purgeInterval))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			report, err := PurgeSoftDeleted(ctx, db, olderThan)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Printf("database purge failed after %s: %v", report, err)
+			} else {
+				log.Printf("database purge: %s", report)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,78 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestPurgeSoftDeleted(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	// 1201 users deleted long ago, more than two batches; 1202 deleted
+	// just now; 1203 live. The first three and 1203 are in a group.
+	const old, recent, live = 1201, 1202, 1203
+	err := db.InTx(ctx, func(tx *database.Tx) error {
+		if _, err := tx.CreateGroup(ctx, database.CreateGroupParams{TelegramID: 1}); err != nil {
+			return err
+		}
+		for id := int64(1); id <= live; id++ {
+			if _, err := tx.CreateUser(ctx, database.CreateUserParams{TelegramID: id, FirstName: "user"}); err != nil {
+				return err
+			}
+		}
+		for _, id := range []int64{1, 2, 3, live} {
+			if _, err := tx.CreateUserGroup(ctx, database.CreateUserGroupParams{UserTelegramID: id, GroupTelegramID: 1}); err != nil {
+				return err
+			}
+		}
+		for _, stmt := range []string{
+			"UPDATE users SET deleted_at = '2000-01-01 00:00:00' WHERE telegram_id <= 1201",
+			"UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE telegram_id = 1202",
+		} {
+			if _, err := tx.Exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := database.PurgeSoftDeleted(ctx, db, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []database.PurgedTable{
+		{Table: "user_group", Rows: 3, Batches: 1}, // Deleted, not left to the cascade
+		{Table: "user_national_ids"},
+		{Table: "users", Rows: old, Batches: 3},
+	}
+	if len(report.Tables) != len(want) {
+		t.Fatalf("report %+v, want %+v", report.Tables, want)
+	}
+	for i := range want {
+		if report.Tables[i] != want[i] {
+			t.Errorf("report entry %d: %+v, want %+v", i, report.Tables[i], want[i])
+		}
+	}
+
+	for _, id := range []int64{recent, live} {
+		if _, err := db.WithDeleted().GetUserByTelegramID(ctx, id); err != nil {
+			t.Errorf("user %d, inside the window or live: %v", id, err)
+		}
+	}
+	if _, err := db.Q.GetUserGroup(ctx, database.GetUserGroupParams{UserTelegramID: live, GroupTelegramID: 1}); err != nil {
+		t.Errorf("the live user's membership: %v", err)
+	}
+
+	report, err = database.PurgeSoftDeleted(ctx, db, 24*time.Hour)
+	if err != nil || report.Rows("users") != 0 {
+		t.Errorf("a second run: %v, %v; want nothing purged", report, err)
+	}
+}
@@ -0,0 +1,57 @@
+-- Migration 0000_baseline, created 2026-10-14.
+-- Brings databases made before there were migrations, by the schema.sql
+-- this project started from, up to the schema 0001 starts from. Open runs
+-- it once, in a transaction, only on a database without schema_migrations:
+-- a schema.sql that made one had all of this already. It has no down.
+
+-- Emails (GetUserByEmail, UpsertUserByEmail), unique regardless of case
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(lower(email));
+
+-- The status CHECK, and user_group's foreign keys, named as PostgreSQL
+-- names those schema.sql declares. A status other than active, blocked or
+-- banned fails the migration; fix those rows first. Memberships of a user
+-- or group that's gone, which the foreign keys refuse, are dropped.
+ALTER TABLE users ADD CONSTRAINT users_status_check CHECK (status IN ('active', 'blocked', 'banned'));
+
+DELETE FROM user_group
+WHERE user_telegram_id NOT IN (SELECT telegram_id FROM users)
+   OR group_telegram_id NOT IN (SELECT telegram_id FROM groups);
+ALTER TABLE user_group
+    ADD CONSTRAINT user_group_user_telegram_id_fkey FOREIGN KEY (user_telegram_id) REFERENCES users(telegram_id) ON DELETE CASCADE,
+    ADD CONSTRAINT user_group_group_telegram_id_fkey FOREIGN KEY (group_telegram_id) REFERENCES groups(telegram_id) ON DELETE RESTRICT;
+
+-- The history tables, as 0001 and the migrations after it change them;
+-- schema.sql, which runs after the migrations, adds the triggers that
+-- fill them
+CREATE TABLE IF NOT EXISTS user_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    operation TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp(),
+    id BIGINT NOT NULL,
+    telegram_id BIGINT NOT NULL,
+    first_name TEXT NOT NULL,
+    username TEXT,
+    balance_game DOUBLE PRECISION,
+    balance_chats DOUBLE PRECISION,
+    status TEXT NOT NULL,
+    language TEXT NOT NULL,
+    refer_from_id BIGINT,
+    last_streak_claim_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    email TEXT
+);
+
+CREATE TABLE IF NOT EXISTS group_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    operation TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp(),
+    id BIGINT NOT NULL,
+    balance DOUBLE PRECISION,
+    telegram_id BIGINT NOT NULL,
+    title TEXT,
+    url TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
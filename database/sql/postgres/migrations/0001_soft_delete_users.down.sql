@@ -0,0 +1,17 @@
+-- Undoes migration 0001_soft_delete_users, for `db migrate down`.
+
+-- Soft-deleted users come back as live ones. The trigger function names
+-- the column, so it gets its older version back first.
+CREATE OR REPLACE FUNCTION record_user_history() RETURNS trigger AS $$
+DECLARE
+    r users;
+BEGIN
+    IF TG_OP = 'DELETE' THEN r := OLD; ELSE r := NEW; END IF;
+    INSERT INTO user_history (operation, id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email)
+    VALUES (TG_OP, r.id, r.telegram_id, r.first_name, r.username, r.balance_game, r.balance_chats, r.status, r.language, r.refer_from_id, r.last_streak_claim_at, r.created_at, r.updated_at, r.email);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE user_history DROP COLUMN IF EXISTS deleted_at;
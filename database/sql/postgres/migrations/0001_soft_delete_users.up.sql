@@ -0,0 +1,9 @@
+-- Migration 0001_soft_delete_users, created 2026-10-14.
+-- Brings databases made by an older schema.sql up to date; make the same
+-- change in schema.sql, which new databases are created from. Runs once,
+-- in a transaction, when Open finds it hasn't been applied.
+
+-- schema.sql, which runs right after, replaces record_user_history() with
+-- the version that copies the column
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE user_history ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
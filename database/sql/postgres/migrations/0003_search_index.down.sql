@@ -0,0 +1,4 @@
+-- Undoes migration 0003_search_index, for `db migrate down`.
+
+DROP INDEX IF EXISTS idx_users_search;
+DROP INDEX IF EXISTS idx_groups_search;
@@ -0,0 +1,9 @@
+-- Migration 0003_search_index, created 2026-10-14.
+-- Brings databases made by an older schema.sql up to date; make the same
+-- change in schema.sql, which new databases are created from. Runs once,
+-- in a transaction, when Open finds it hasn't been applied.
+
+-- schema.sql would create the indexes as well; this keeps the versions in
+-- step with SQLite's, where the search tables need filling
+CREATE INDEX IF NOT EXISTS idx_users_search ON users USING GIN (to_tsvector('simple', first_name || ' ' || COALESCE(username, '')));
+CREATE INDEX IF NOT EXISTS idx_groups_search ON groups USING GIN (to_tsvector('simple', COALESCE(title, '') || ' ' || COALESCE(url, '')));
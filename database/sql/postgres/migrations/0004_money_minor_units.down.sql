@@ -0,0 +1,11 @@
+-- Undoes migration 0004_money_minor_units, for `db migrate down`.
+
+ALTER TABLE users
+    ALTER COLUMN balance_game TYPE DOUBLE PRECISION USING balance_game / 100.0,
+    ALTER COLUMN balance_chats TYPE DOUBLE PRECISION USING balance_chats / 100.0;
+ALTER TABLE groups ALTER COLUMN balance TYPE DOUBLE PRECISION USING balance / 100.0;
+ALTER TABLE user_group ALTER COLUMN balance TYPE DOUBLE PRECISION USING balance / 100.0;
+ALTER TABLE user_history
+    ALTER COLUMN balance_game TYPE DOUBLE PRECISION USING balance_game / 100.0,
+    ALTER COLUMN balance_chats TYPE DOUBLE PRECISION USING balance_chats / 100.0;
+ALTER TABLE group_history ALTER COLUMN balance TYPE DOUBLE PRECISION USING balance / 100.0;
@@ -0,0 +1,18 @@
+-- Migration 0004_money_minor_units, created 2026-10-14.
+-- Brings databases made by an older schema.sql up to date; make the same
+-- change in schema.sql, which new databases are created from. Runs once,
+-- in a transaction, when Open finds it hasn't been applied.
+
+-- Balances become whole cents (database.Money) in BIGINT columns instead
+-- of fractional units in DOUBLE PRECISION ones. ALTER COLUMN ... TYPE
+-- rewrites the rows without firing the history and audit triggers. The
+-- audit log's JSON from before keeps the old units.
+ALTER TABLE users
+    ALTER COLUMN balance_game TYPE BIGINT USING round(balance_game::numeric * 100),
+    ALTER COLUMN balance_chats TYPE BIGINT USING round(balance_chats::numeric * 100);
+ALTER TABLE groups ALTER COLUMN balance TYPE BIGINT USING round(balance::numeric * 100);
+ALTER TABLE user_group ALTER COLUMN balance TYPE BIGINT USING round(balance::numeric * 100);
+ALTER TABLE user_history
+    ALTER COLUMN balance_game TYPE BIGINT USING round(balance_game::numeric * 100),
+    ALTER COLUMN balance_chats TYPE BIGINT USING round(balance_chats::numeric * 100);
+ALTER TABLE group_history ALTER COLUMN balance TYPE BIGINT USING round(balance::numeric * 100);
@@ -0,0 +1,690 @@
+-- =====================
+-- USER QUERIES
+-- =====================
+
+-- name: GetUserByTelegramID :one
+SELECT * FROM users WHERE telegram_id = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- Matches any casing; the lower(email) index makes this an index lookup
+-- name: GetUserByEmail :one
+SELECT * FROM users WHERE lower(email) = lower(sqlc.arg(email)) AND deleted_at IS NULL LIMIT 1;
+
+-- name: CreateUser :one
+INSERT INTO users (
+    telegram_id, first_name, username, status, language, refer_from_id, email
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- No row when a user with that telegram_id exists; GetOrCreateUser then reads it
+-- name: CreateUserIfMissing :one
+INSERT INTO users (
+    telegram_id, first_name, username, status, language, refer_from_id, email
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (telegram_id) DO NOTHING
+RETURNING *;
+
+-- name: UpdateUser :one
+UPDATE users 
+SET first_name = $1, username = $2, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE telegram_id = $3
+RETURNING *;
+
+-- name: UpdateUserBalanceChats :one
+UPDATE users 
+SET balance_chats = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE telegram_id = $2
+RETURNING *;
+
+-- name: GetTopUsersByBalance :many
+SELECT * FROM users 
+WHERE deleted_at IS NULL
+ORDER BY (balance_game + balance_chats) DESC 
+LIMIT sqlc.arg('limit')::bigint;
+
+-- name: GetUserPosition :one
+SELECT COUNT(*) + 1 AS position FROM users 
+WHERE (balance_game + balance_chats) > $1 AND deleted_at IS NULL;
+
+-- name: ListUsersByStatus :many
+SELECT * FROM users
+WHERE status = sqlc.arg(status) AND deleted_at IS NULL
+ORDER BY id
+LIMIT sqlc.arg('limit')::bigint;
+
+-- Usernames matching a regular expression: Go syntax via the regexp
+-- function Open registers on SQLite, POSIX on PostgreSQL
+-- name: ListUsersMatchingUsername :many
+SELECT * FROM users
+WHERE username ~ sqlc.arg(pattern)::text AND deleted_at IS NULL
+ORDER BY id
+LIMIT sqlc.arg('limit')::bigint;
+
+-- Alphabetical by first name in any language and casing (idx_users_first_name)
+-- name: ListUsersByFirstName :many
+SELECT * FROM users
+WHERE deleted_at IS NULL
+ORDER BY first_name COLLATE nocase_unicode, id
+LIMIT sqlc.arg('limit')::bigint;
+
+-- A uniform random sample. Sorts the whole table; DB.SampleUsers avoids
+-- that on large ones.
+-- name: SampleUsers :many
+SELECT * FROM users
+WHERE deleted_at IS NULL
+ORDER BY RANDOM()
+LIMIT sqlc.arg('limit')::bigint;
+
+-- The same rows for the same multiplier and increment until users change:
+-- a hash of id orders them (2147483647 is prime, so it's a permutation of
+-- ids below it). Still sorts the whole table.
+-- name: SampleUsersSeeded :many
+SELECT * FROM users
+WHERE deleted_at IS NULL
+ORDER BY (abs(id) % 2147483647 * sqlc.arg(multiplier)::bigint + sqlc.arg(increment)::bigint) % 2147483647, id
+LIMIT sqlc.arg('limit')::bigint;
+
+-- name: GetUserIDRange :one
+SELECT COALESCE(MIN(id), 0)::bigint AS min_id, COALESCE(MAX(id), 0)::bigint AS max_id
+FROM users;
+
+-- name: ListUsersByIDs :many
+SELECT * FROM users
+WHERE id = ANY(sqlc.arg(ids)::bigint[]) AND deleted_at IS NULL
+ORDER BY id;
+
+-- name: CountUsersByStatus :one
+SELECT COUNT(*) FROM users
+WHERE status = $1 AND deleted_at IS NULL;
+
+-- name: UpsertUser :one
+INSERT INTO users (telegram_id, first_name, username, status, language)
+VALUES ($1, $2, $3, 'active', 'en')
+ON CONFLICT(telegram_id) DO UPDATE SET
+    first_name = CASE WHEN excluded.first_name != '' THEN excluded.first_name ELSE users.first_name END,
+    username = CASE WHEN excluded.username != '' THEN excluded.username ELSE users.username END,
+    version = users.version + 1,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- Creates the user, or updates the one with that email in any casing;
+-- DB.UpsertUserByEmail normalizes the email. telegram_id is only set on
+-- insert, and one that's taken by another user is a unique violation.
+-- name: UpsertUserByEmail :one
+INSERT INTO users (telegram_id, first_name, username, email, status, language)
+VALUES ($1, $2, $3, $4, 'active', 'en')
+ON CONFLICT ((lower(email))) DO UPDATE SET
+    first_name = CASE WHEN excluded.first_name != '' THEN excluded.first_name ELSE users.first_name END,
+    username = CASE WHEN excluded.username != '' THEN excluded.username ELSE users.username END,
+    version = users.version + 1,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- Sets the status of every listed user; DB.UpdateStatusByIDs splits long lists
+-- name: UpdateStatusByIDs :execrows
+UPDATE users
+SET status = sqlc.arg(status), version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- Optimistic locking: these update the user only while it's still at the
+-- version the caller read, and bump it. No rows when another update got
+-- there first; DB.UpdateUserIfVersion and
+-- DB.UpdateUserBalanceChatsIfVersion return ErrStaleVersion then.
+-- name: UpdateUserIfVersion :execrows
+UPDATE users
+SET first_name = $1, username = $2, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $3 AND version = $4;
+
+-- name: UpdateUserBalanceChatsIfVersion :execrows
+UPDATE users
+SET balance_chats = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2 AND version = $3;
+
+-- Soft delete: the queries above that read users leave out the ones with
+-- deleted_at set (DB.WithDeleted and DB.OnlyDeleted see them). Writes by
+-- id still reach them. No rows when the user is already deleted.
+-- name: SoftDeleteUser :execrows
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreUser :execrows
+UPDATE users
+SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- Hard-deletes the users soft-deleted more than older_than_seconds ago,
+-- by the database's clock, which set deleted_at; see DB.Purge
+-- name: PurgeDeletedUsers :execrows
+DELETE FROM users
+WHERE deleted_at IS NOT NULL
+  AND deleted_at < now() - make_interval(secs => sqlc.arg(older_than_seconds)::bigint);
+
+-- =====================
+-- GROUP QUERIES  
+-- =====================
+
+-- name: GetGroupByTelegramID :one
+SELECT * FROM groups WHERE telegram_id = $1 LIMIT 1;
+
+-- Alphabetical by title in any language and casing (idx_groups_title)
+-- name: ListGroupsByTitle :many
+SELECT * FROM groups
+ORDER BY title COLLATE nocase_unicode, id
+LIMIT sqlc.arg('limit')::bigint;
+
+-- name: CreateGroup :one
+INSERT INTO groups (telegram_id, title)
+VALUES ($1, $2)
+RETURNING *;
+
+-- No row when a group with that telegram_id exists; GetOrCreateGroup then reads it
+-- name: CreateGroupIfMissing :one
+INSERT INTO groups (telegram_id, title)
+VALUES ($1, $2)
+ON CONFLICT (telegram_id) DO NOTHING
+RETURNING *;
+
+-- name: UpsertGroup :one
+INSERT INTO groups (telegram_id, title)
+VALUES ($1, $2)
+ON CONFLICT(telegram_id) DO UPDATE SET
+    title = CASE WHEN excluded.title != '' THEN excluded.title ELSE groups.title END,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteGroup :one
+DELETE FROM groups WHERE telegram_id = $1
+RETURNING *;
+
+-- =====================
+-- USER-GROUP QUERIES
+-- =====================
+
+-- name: GetUserGroup :one
+SELECT * FROM user_group 
+WHERE user_telegram_id = $1 AND group_telegram_id = $2 
+LIMIT 1;
+
+-- name: CreateUserGroup :one
+INSERT INTO user_group (user_telegram_id, group_telegram_id, balance)
+VALUES ($1, $2, 0)
+RETURNING *;
+
+-- name: GetOrCreateUserGroup :one
+INSERT INTO user_group (user_telegram_id, group_telegram_id, balance)
+VALUES ($1, $2, 0)
+ON CONFLICT(user_telegram_id, group_telegram_id) DO UPDATE SET
+    balance = user_group.balance
+RETURNING *;
+
+-- Sets the balance, adding the membership if there's none
+-- name: UpsertUserGroupBalance :one
+INSERT INTO user_group (user_telegram_id, group_telegram_id, balance)
+VALUES ($1, $2, $3)
+ON CONFLICT(user_telegram_id, group_telegram_id) DO UPDATE SET
+    balance = excluded.balance
+RETURNING *;
+
+-- name: UpdateUserGroupBalance :one
+UPDATE user_group 
+SET balance = $1 
+WHERE user_telegram_id = $2 AND group_telegram_id = $3
+RETURNING *;
+
+-- name: AddToUserGroupBalance :one
+UPDATE user_group 
+SET balance = balance + $1 
+WHERE user_telegram_id = $2 AND group_telegram_id = $3
+RETURNING *;
+
+-- name: GetTotalUserBalance :one
+SELECT COALESCE(SUM(balance), 0) AS total FROM user_group 
+WHERE user_telegram_id = $1;
+
+-- name: GetTotalGroupBalance :one
+SELECT COALESCE(SUM(balance), 0) AS total FROM user_group 
+WHERE group_telegram_id = $1;
+
+-- Sums and rounding that come out the same on SQLite, PostgreSQL and
+-- MySQL (DB.GroupBalanceSummary reads them as Money). The balances are
+-- whole cents, so SUM is exact on all three, and ROUND takes exact halves
+-- away from zero on all three; / 100.0 keeps them exact, as a double in
+-- SQLite and a NUMERIC or DECIMAL elsewhere.
+-- name: GetGroupBalanceSummary :one
+SELECT COUNT(*) AS members,
+    COALESCE(SUM(balance), 0) AS total,
+    COALESCE(ROUND(AVG(balance)), 0) AS average,
+    COALESCE(ROUND(SUM(balance) / 100.0), 0) * 100 AS total_rounded,
+    COALESCE(MAX(balance), 0) AS largest
+FROM user_group
+WHERE group_telegram_id = $1;
+
+-- =====================
+-- STATS QUERIES
+-- =====================
+
+-- name: GetTopUsersInGroup :many
+SELECT 
+    u.first_name,
+    u.username,
+    ug.balance
+FROM user_group ug
+LEFT JOIN users u ON u.telegram_id = ug.user_telegram_id
+WHERE ug.group_telegram_id = $1 AND u.deleted_at IS NULL
+ORDER BY ug.balance DESC
+LIMIT 10;
+
+-- name: GetTopGroupsForUser :many
+SELECT 
+    g.title,
+    ug.balance
+FROM user_group ug
+LEFT JOIN groups g ON g.telegram_id = ug.group_telegram_id
+WHERE ug.user_telegram_id = $1
+ORDER BY ug.balance DESC
+LIMIT 10;
+
+-- =====================
+-- RELATION QUERIES
+-- =====================
+
+-- Members of a group with their names, in one query instead of N+1
+-- name: ListGroupMembers :many
+SELECT ug.*, u.first_name, u.username
+FROM user_group ug
+JOIN users u ON u.telegram_id = ug.user_telegram_id
+WHERE ug.group_telegram_id = $1 AND u.deleted_at IS NULL
+ORDER BY ug.balance DESC, ug.id;
+
+-- One row per (user, group) pair; users without groups get a single row
+-- with NULL group columns. Assemble with GroupGroupsByUser.
+-- name: ListUsersWithGroups :many
+SELECT
+    sqlc.embed(u),
+    g.telegram_id AS group_telegram_id,
+    g.title AS group_title,
+    ug.balance AS group_balance
+FROM users u
+LEFT JOIN user_group ug ON ug.user_telegram_id = u.telegram_id
+LEFT JOIN groups g ON g.telegram_id = ug.group_telegram_id
+WHERE u.id IN (SELECT id FROM users WHERE deleted_at IS NULL ORDER BY id LIMIT sqlc.arg('limit')::bigint)
+ORDER BY u.id, ug.id;
+
+-- =====================
+-- TAG QUERIES
+-- =====================
+
+-- name: UpsertTag :one
+INSERT INTO tags (name)
+VALUES ($1)
+ON CONFLICT(name) DO UPDATE SET
+    name = excluded.name
+RETURNING *;
+
+-- name: AttachTag :exec
+INSERT INTO group_tags (group_telegram_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DetachTag :exec
+DELETE FROM group_tags
+WHERE group_telegram_id = $1 AND tag_id = $2;
+
+-- name: ListGroupTags :many
+SELECT t.* FROM tags t
+JOIN group_tags gt ON gt.tag_id = t.id
+WHERE gt.group_telegram_id = $1
+ORDER BY t.name;
+
+-- Keyset pagination: pass the last seen group id as after_id (0 for the first page)
+-- name: ListGroupsByTag :many
+SELECT g.* FROM groups g
+JOIN group_tags gt ON gt.group_telegram_id = g.telegram_id
+JOIN tags t ON t.id = gt.tag_id
+WHERE t.name = sqlc.arg(tag) AND g.id > sqlc.arg(after_id)
+ORDER BY g.id
+LIMIT sqlc.arg(page_size)::bigint;
+
+-- Keyset pagination on two columns: pass the created_at and id of the last
+-- user seen (the zero time and 0 for the first page). Users whose
+-- created_at was set to NULL never show up.
+-- name: ListUsersByCreatedAt :many
+SELECT * FROM users
+WHERE (created_at, id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::bigint) AND deleted_at IS NULL
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_size)::bigint;
+
+-- Groups carrying every tag in the set (relational division).
+-- tag_count must be the number of distinct tags passed in.
+-- name: ListGroupsWithAllTags :many
+SELECT g.* FROM groups g
+JOIN group_tags gt ON gt.group_telegram_id = g.telegram_id
+JOIN tags t ON t.id = gt.tag_id
+WHERE t.name = ANY(sqlc.arg(tags)::text[]) AND g.id > sqlc.arg(after_id)
+GROUP BY g.id
+HAVING COUNT(DISTINCT t.id) = sqlc.arg(tag_count)
+ORDER BY g.id
+LIMIT sqlc.arg(page_size)::bigint;
+
+-- =====================
+-- CATEGORY QUERIES
+-- =====================
+
+-- name: CreateCategory :one
+INSERT INTO categories (parent_id, name)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name matches after trimming and lowercasing, like name_normalized
+-- name: ListCategoriesByName :many
+SELECT * FROM categories
+WHERE name_normalized = lower(trim(sqlc.arg(name)))
+ORDER BY id;
+
+-- name: GetChildCategoryByName :one
+SELECT * FROM categories
+WHERE parent_id = sqlc.arg(parent_id) AND name_normalized = lower(trim(sqlc.arg(name)))
+LIMIT 1;
+
+-- name: RenameCategory :one
+UPDATE categories
+SET name = $1
+WHERE id = $2
+RETURNING *;
+
+-- The category and everything below it, depth 0 being the category itself.
+-- max_depth stops the recursion if the data ever contains a cycle.
+-- name: GetDescendants :many
+WITH RECURSIVE subtree(id, parent_id, name, depth) AS (
+    SELECT c.id, c.parent_id, c.name, 0::bigint
+    FROM categories c
+    WHERE c.id = sqlc.arg(id)
+    UNION ALL
+    SELECT c.id, c.parent_id, c.name, s.depth + 1
+    FROM categories c
+    JOIN subtree s ON c.parent_id = s.id
+    WHERE s.depth < sqlc.arg(max_depth)
+)
+SELECT id, parent_id, name, depth FROM subtree
+ORDER BY depth, id;
+
+-- The path from the category up to its root, depth 0 being the category itself
+-- name: GetAncestors :many
+WITH RECURSIVE ancestors(id, parent_id, name, depth) AS (
+    SELECT c.id, c.parent_id, c.name, 0::bigint
+    FROM categories c
+    WHERE c.id = sqlc.arg(id)
+    UNION ALL
+    SELECT c.id, c.parent_id, c.name, a.depth + 1
+    FROM categories c
+    JOIN ancestors a ON c.id = a.parent_id
+    WHERE a.depth < sqlc.arg(max_depth)
+)
+SELECT id, parent_id, name, depth FROM ancestors
+ORDER BY depth;
+
+-- name: SetCategoryParent :one
+UPDATE categories
+SET parent_id = $1
+WHERE id = $2
+RETURNING *;
+
+-- =====================
+-- ATTACHMENT QUERIES
+-- =====================
+
+-- Same content twice returns the existing row instead of storing a copy
+-- name: PutAttachment :one
+INSERT INTO attachments (sha256, content_type, size, data)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT(sha256) DO UPDATE SET sha256 = excluded.sha256
+RETURNING id, sha256, content_type, size, created_at;
+
+-- name: GetAttachmentMeta :one
+SELECT id, sha256, content_type, size, created_at FROM attachments
+WHERE id = $1;
+
+-- start is 1-based, like substr
+-- name: ReadAttachmentChunk :one
+SELECT substring(data FROM sqlc.arg(start)::bigint::int FOR sqlc.arg(length)::bigint::int) AS chunk
+FROM attachments
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteAttachment :exec
+DELETE FROM attachments WHERE id = $1;
+
+-- =====================
+-- OUTBOX QUERIES
+-- =====================
+
+-- name: InsertOutboxEvent :one
+INSERT INTO outbox (topic, payload)
+VALUES ($1, $2)
+RETURNING *;
+
+-- Claims up to LIMIT pending events by pushing available_at forward (a lease).
+-- An event is skipped while an earlier event of the same topic is leased or backing off.
+-- name: ClaimOutboxEvents :many
+UPDATE outbox
+SET available_at = sqlc.arg(available_at)
+WHERE id IN (
+    SELECT o.id FROM outbox o
+    WHERE o.delivered_at IS NULL
+      AND o.available_at <= CURRENT_TIMESTAMP
+      AND NOT EXISTS (
+          SELECT 1 FROM outbox e
+          WHERE e.topic = o.topic
+            AND e.delivered_at IS NULL
+            AND e.id < o.id
+            AND e.available_at > CURRENT_TIMESTAMP
+      )
+    ORDER BY o.id
+    LIMIT sqlc.arg('limit')::bigint
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEventDelivered :exec
+UPDATE outbox
+SET delivered_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: FailOutboxEvent :exec
+UPDATE outbox
+SET attempts = attempts + 1, last_error = $1, available_at = $2
+WHERE id = $3;
+
+-- =====================
+-- JOB QUEUE QUERIES
+-- =====================
+
+-- name: EnqueueJob :one
+INSERT INTO jobs (type, payload, max_attempts, run_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- Claims up to LIMIT due jobs of the given types, oldest first: marks
+-- them running, counts the attempt and hides them until visible_at. A
+-- running job whose visible_at has passed is claimed again.
+-- name: ClaimJobs :many
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, run_at = sqlc.arg(visible_at)
+WHERE id IN (
+    SELECT id FROM jobs
+    WHERE status <> 'dead'
+      AND run_at <= sqlc.arg(now)
+      AND type = ANY(sqlc.arg(types)::text[])
+    ORDER BY run_at, id
+    LIMIT sqlc.arg('limit')::bigint
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- The job succeeded. The attempts check leaves a job alone that was
+-- claimed again after its visibility timeout, as do RetryJob and BuryJob.
+-- name: CompleteJob :exec
+DELETE FROM jobs
+WHERE id = $1 AND attempts = $2;
+
+-- name: RetryJob :exec
+UPDATE jobs
+SET status = 'pending', last_error = $1, run_at = $2
+WHERE id = $3 AND attempts = $4;
+
+-- Moves the job to the dead letters, out of attempts or failed for good
+-- name: BuryJob :exec
+UPDATE jobs
+SET status = 'dead', last_error = $1
+WHERE id = $2 AND attempts = $3;
+
+-- name: ListDeadJobs :many
+SELECT * FROM jobs
+WHERE status = 'dead'
+ORDER BY id
+LIMIT sqlc.arg('limit')::bigint;
+
+-- Gives a dead job a fresh set of attempts
+-- name: RequeueDeadJob :execrows
+UPDATE jobs
+SET status = 'pending', attempts = 0, last_error = NULL, run_at = $1
+WHERE id = $2 AND status = 'dead';
+
+-- name: DeleteJob :execrows
+DELETE FROM jobs WHERE id = $1;
+
+-- =====================
+-- STORAGE PROBE
+-- =====================
+
+-- Rewrites one row; success means the database takes writes again
+-- name: TouchStorageProbe :exec
+INSERT INTO storage_probe (id, checked_at)
+VALUES (1, CURRENT_TIMESTAMP)
+ON CONFLICT (id) DO UPDATE SET
+    checked_at = excluded.checked_at;
+
+-- =====================
+-- NATIONAL ID QUERIES
+-- =====================
+
+-- Set through DB.SetUserNationalID, which computes the blind index
+-- name: SetUserNationalID :exec
+INSERT INTO user_national_ids (user_telegram_id, national_id, national_id_index)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_telegram_id) DO UPDATE SET
+    national_id = excluded.national_id,
+    national_id_index = excluded.national_id_index;
+
+-- name: GetUserNationalID :one
+SELECT national_id FROM user_national_ids
+WHERE user_telegram_id = $1;
+
+-- Looks the user up by BlindIndex of the national ID
+-- name: GetUserByNationalIDIndex :one
+SELECT u.* FROM users u
+JOIN user_national_ids n ON n.user_telegram_id = u.telegram_id
+WHERE n.national_id_index = $1 AND u.deleted_at IS NULL;
+
+-- Raw ciphertexts for key rotation, by user
+-- name: ListNationalIDCiphertexts :many
+SELECT user_telegram_id, national_id::bytea AS ciphertext
+FROM user_national_ids
+WHERE user_telegram_id > sqlc.arg(after_telegram_id)
+ORDER BY user_telegram_id
+LIMIT sqlc.arg('limit')::bigint;
+
+-- Swaps in a re-encrypted value, unless it changed since it was read
+-- name: ReplaceNationalIDCiphertext :execrows
+UPDATE user_national_ids
+SET national_id = sqlc.arg(new_ciphertext)::bytea
+WHERE user_telegram_id = sqlc.arg(user_telegram_id)
+  AND national_id = sqlc.arg(old_ciphertext)::bytea;
+
+-- =====================
+-- HISTORY QUERIES
+-- =====================
+
+-- Every change to the user, oldest first (rows are written by triggers)
+-- name: GetUserHistory :many
+SELECT * FROM user_history
+WHERE id = $1
+ORDER BY history_id;
+
+-- name: GetGroupHistory :many
+SELECT * FROM group_history
+WHERE id = $1
+ORDER BY history_id;
+
+-- =====================
+-- SEARCH QUERIES
+-- =====================
+
+-- Full-text matches for DB.Search, best first; query is a to_tsquery
+-- expression. Each matches on the expression of its GIN index
+-- (idx_users_search, idx_groups_search). The matches in title and snippet
+-- are between chr(2) and chr(3), which Search replaces with the caller's
+-- markers.
+-- name: SearchUsers :many
+SELECT
+    u.id,
+    ts_headline('simple', u.first_name, q, format('StartSel=%s, StopSel=%s, HighlightAll=true', chr(2), chr(3))) AS title,
+    ts_headline('simple', COALESCE(u.username, ''), q, format('StartSel=%s, StopSel=%s, MaxWords=10, MinWords=5', chr(2), chr(3))) AS snippet,
+    ts_rank(to_tsvector('simple', u.first_name || ' ' || COALESCE(u.username, '')), q)::float8 AS score
+FROM users u, to_tsquery('simple', sqlc.arg(query)::text) q
+WHERE to_tsvector('simple', u.first_name || ' ' || COALESCE(u.username, '')) @@ q AND u.deleted_at IS NULL
+ORDER BY score DESC, u.id
+LIMIT sqlc.arg('limit')::bigint;
+
+-- name: SearchGroups :many
+SELECT
+    g.id,
+    ts_headline('simple', COALESCE(g.title, ''), q, format('StartSel=%s, StopSel=%s, HighlightAll=true', chr(2), chr(3))) AS title,
+    ts_headline('simple', COALESCE(g.url, ''), q, format('StartSel=%s, StopSel=%s, MaxWords=10, MinWords=5', chr(2), chr(3))) AS snippet,
+    ts_rank(to_tsvector('simple', COALESCE(g.title, '') || ' ' || COALESCE(g.url, '')), q)::float8 AS score
+FROM groups g, to_tsquery('simple', sqlc.arg(query)::text) q
+WHERE to_tsvector('simple', COALESCE(g.title, '') || ' ' || COALESCE(g.url, '')) @@ q
+ORDER BY score DESC, g.id
+LIMIT sqlc.arg('limit')::bigint;
+
+-- =====================
+-- AUDIT QUERIES
+-- =====================
+
+-- Every recorded change to one row, oldest first; table_name is users,
+-- groups or user_group
+-- name: GetAuditLog :many
+SELECT * FROM audit_log
+WHERE table_name = sqlc.arg(table_name) AND row_id = sqlc.arg(row_id)
+ORDER BY id;
+
+-- What one actor changed, newest first
+-- name: ListAuditLogByActor :many
+SELECT * FROM audit_log
+WHERE actor = sqlc.arg(actor)::text
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')::bigint;
+
+-- The updates of table_name that changed one column, newest first:
+-- field is the column's name, a key of the row to_jsonb wrote, and comes
+-- first so the parameters are in SQLite's order.
+-- name: ListAuditLogFieldChanges :many
+SELECT * FROM audit_log
+WHERE old_data ->> sqlc.arg(field)::text IS DISTINCT FROM new_data ->> sqlc.arg(field)::text
+    AND table_name = sqlc.arg(table_name)::text AND operation = 'UPDATE'
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')::bigint;
+
+-- Deletes up to batch_size entries older than older_than_seconds, oldest
+-- first, so a long backlog goes in short transactions; see
+-- DB.PruneAuditLog
+-- name: PruneAuditLog :execrows
+DELETE FROM audit_log
+WHERE id IN (
+    SELECT id FROM audit_log
+    WHERE changed_at < now() - make_interval(secs => sqlc.arg(older_than_seconds)::bigint)
+    ORDER BY id
+    LIMIT sqlc.arg(batch_size)::bigint
+);
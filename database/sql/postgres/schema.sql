@@ -0,0 +1,299 @@
+-- Users table
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    telegram_id BIGINT NOT NULL UNIQUE,
+    first_name TEXT NOT NULL DEFAULT '',
+    username TEXT DEFAULT '',
+    balance_game BIGINT DEFAULT 0, -- Balances are in cents (database.Money)
+    balance_chats BIGINT DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'active' CONSTRAINT users_status_check CHECK (status IN ('active', 'blocked', 'banned')),
+    language TEXT NOT NULL DEFAULT 'en',
+    refer_from_id BIGINT,
+    last_streak_claim_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    email TEXT,
+    deleted_at TIMESTAMPTZ, -- Set by SoftDeleteUser; most queries leave such users out
+    version BIGINT NOT NULL DEFAULT 1 -- Bumped by every update, for the IfVersion queries
+);
+
+-- Groups table
+CREATE TABLE IF NOT EXISTS groups (
+    id BIGSERIAL PRIMARY KEY,
+    balance BIGINT DEFAULT 0, -- In cents
+    telegram_id BIGINT NOT NULL UNIQUE,
+    title TEXT DEFAULT '',
+    url TEXT DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- User-Group relationship table. Deleting a user drops their memberships;
+-- a group can't be deleted while it still has members (their balances would
+-- silently disappear).
+CREATE TABLE IF NOT EXISTS user_group (
+    id BIGSERIAL PRIMARY KEY,
+    user_telegram_id BIGINT NOT NULL REFERENCES users(telegram_id) ON DELETE CASCADE,
+    group_telegram_id BIGINT NOT NULL REFERENCES groups(telegram_id) ON DELETE RESTRICT,
+    balance BIGINT DEFAULT 0, -- In cents
+    UNIQUE(user_telegram_id, group_telegram_id)
+);
+
+-- Tags, attached to groups through group_tags
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+-- Group-Tag relationship table (many-to-many)
+CREATE TABLE IF NOT EXISTS group_tags (
+    group_telegram_id BIGINT NOT NULL REFERENCES groups(telegram_id) ON DELETE CASCADE,
+    tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_telegram_id, tag_id)
+);
+
+-- Category tree: parent_id points at the parent category, NULL for roots
+CREATE TABLE IF NOT EXISTS categories (
+    id BIGSERIAL PRIMARY KEY,
+    parent_id BIGINT REFERENCES categories(id) ON DELETE CASCADE,
+    name TEXT NOT NULL CONSTRAINT categories_name_check CHECK (trim(name) <> ''),
+    -- Search key maintained by the database; never insert or update it
+    name_normalized TEXT NOT NULL GENERATED ALWAYS AS (lower(trim(name))) STORED
+);
+
+-- Binary attachments (avatars), deduplicated by content hash
+CREATE TABLE IF NOT EXISTS attachments (
+    id BIGSERIAL PRIMARY KEY,
+    sha256 BYTEA NOT NULL UNIQUE,
+    content_type TEXT NOT NULL DEFAULT '',
+    size BIGINT NOT NULL,
+    data BYTEA NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Transactional outbox: events written in the same tx as the change they describe
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    topic TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    attempts BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMPTZ
+);
+
+-- Background jobs for the queue package. Claiming a job marks it running
+-- and moves run_at to when its visibility timeout ends, so a job whose
+-- worker died runs again; finished jobs are deleted, and dead ones (out of
+-- attempts) stay for Queue.Dead and Queue.Retry.
+CREATE TABLE IF NOT EXISTS jobs (
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'dead')),
+    attempts BIGINT NOT NULL DEFAULT 0, -- Claims so far, including the current one
+    max_attempts BIGINT NOT NULL DEFAULT 5,
+    last_error TEXT,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A single row rewritten to check the disk takes writes again after it filled up
+CREATE TABLE IF NOT EXISTS storage_probe (
+    id BIGINT PRIMARY KEY,
+    checked_at TIMESTAMPTZ NOT NULL
+);
+
+-- National ID numbers, encrypted by the app (DB.EncryptField) so backups and
+-- dumps don't expose them. Ciphertext can't be searched, so lookups go by
+-- national_id_index, a keyed hash of the plaintext (BlindIndex). Kept out
+-- of users so the history triggers never copy them.
+CREATE TABLE IF NOT EXISTS user_national_ids (
+    user_telegram_id BIGINT PRIMARY KEY REFERENCES users(telegram_id) ON DELETE CASCADE,
+    national_id BYTEA NOT NULL,
+    national_id_index BYTEA NOT NULL UNIQUE
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
+-- Emails are unique regardless of case; the row keeps the casing as entered
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(lower(email));
+CREATE INDEX IF NOT EXISTS idx_groups_telegram_id ON groups(telegram_id);
+CREATE INDEX IF NOT EXISTS idx_user_group_user ON user_group(user_telegram_id);
+CREATE INDEX IF NOT EXISTS idx_user_group_group ON user_group(group_telegram_id);
+CREATE INDEX IF NOT EXISTS idx_group_tags_tag ON group_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);
+CREATE INDEX IF NOT EXISTS idx_categories_name ON categories(name_normalized);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(delivered_at, topic, id);
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(run_at, id) WHERE status <> 'dead';
+-- Keyset pagination by signup time (ListUsersByCreatedAt)
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at, id);
+-- Alphabetical order for people, whatever the language and casing. ICU's
+-- root locale at secondary strength ignores case (needs a server built
+-- with ICU, which the official packages are).
+CREATE COLLATION IF NOT EXISTS nocase_unicode (provider = icu, locale = 'und-u-ks-level2', deterministic = false);
+CREATE INDEX IF NOT EXISTS idx_users_first_name ON users(first_name COLLATE nocase_unicode, id);
+CREATE INDEX IF NOT EXISTS idx_groups_title ON groups(title COLLATE nocase_unicode, id);
+
+-- Full-text search (DB.Search), in place of SQLite's FTS5 tables: GIN
+-- indexes over the documents SearchUsers and SearchGroups match, which
+-- have to be written exactly like this in the queries to use them. The
+-- 'simple' configuration lowercases words and does no stemming, close to
+-- FTS5's unicode61 tokenizer, but keeps diacritics.
+CREATE INDEX IF NOT EXISTS idx_users_search ON users USING GIN (to_tsvector('simple', first_name || ' ' || COALESCE(username, '')));
+CREATE INDEX IF NOT EXISTS idx_groups_search ON groups USING GIN (to_tsvector('simple', COALESCE(title, '') || ' ' || COALESCE(url, '')));
+
+-- Change history, written by triggers so every change is captured, including
+-- ones made outside the app (migrations, manual fixes in psql).
+-- Each row is the full row image after an INSERT or UPDATE, or the last one
+-- before a DELETE; the old values of a change are in the entry before it.
+-- The history tables have no triggers of their own.
+CREATE TABLE IF NOT EXISTS user_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    operation TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp(),
+    id BIGINT NOT NULL,
+    telegram_id BIGINT NOT NULL,
+    first_name TEXT NOT NULL,
+    username TEXT,
+    balance_game BIGINT,
+    balance_chats BIGINT,
+    status TEXT NOT NULL,
+    language TEXT NOT NULL,
+    refer_from_id BIGINT,
+    last_streak_claim_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    email TEXT,
+    deleted_at TIMESTAMPTZ,
+    version BIGINT NOT NULL DEFAULT 0 -- 0 for entries from before the column
+);
+
+CREATE TABLE IF NOT EXISTS group_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    operation TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp(),
+    id BIGINT NOT NULL,
+    balance BIGINT,
+    telegram_id BIGINT NOT NULL,
+    title TEXT,
+    url TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_history_id ON user_history(id, history_id);
+CREATE INDEX IF NOT EXISTS idx_group_history_id ON group_history(id, history_id);
+
+CREATE OR REPLACE FUNCTION record_user_history() RETURNS trigger AS $$
+DECLARE
+    r users;
+BEGIN
+    IF TG_OP = 'DELETE' THEN r := OLD; ELSE r := NEW; END IF;
+    INSERT INTO user_history (operation, id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version)
+    VALUES (TG_OP, r.id, r.telegram_id, r.first_name, r.username, r.balance_game, r.balance_chats, r.status, r.language, r.refer_from_id, r.last_streak_claim_at, r.created_at, r.updated_at, r.email, r.deleted_at, r.version);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_group_history() RETURNS trigger AS $$
+DECLARE
+    r groups;
+BEGIN
+    IF TG_OP = 'DELETE' THEN r := OLD; ELSE r := NEW; END IF;
+    INSERT INTO group_history (operation, id, balance, telegram_id, title, url, created_at, updated_at)
+    VALUES (TG_OP, r.id, r.balance, r.telegram_id, r.title, r.url, r.created_at, r.updated_at);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_history ON users;
+CREATE TRIGGER users_history AFTER INSERT OR UPDATE OR DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION record_user_history();
+
+DROP TRIGGER IF EXISTS groups_history ON groups;
+CREATE TRIGGER groups_history AFTER INSERT OR UPDATE OR DELETE ON groups
+    FOR EACH ROW EXECUTE FUNCTION record_group_history();
+
+-- Change notifications for DB.Listen: every committed change to users,
+-- groups or user_group, whoever made it, is announced on <table>_changes
+-- with a small JSON payload (NOTIFY payloads are capped at 8000 bytes, so
+-- not the row itself). Keep the list in sync with changeTables in changes.go.
+CREATE OR REPLACE FUNCTION notify_change() RETURNS trigger AS $$
+DECLARE
+    r record;
+BEGIN
+    IF TG_OP = 'DELETE' THEN r := OLD; ELSE r := NEW; END IF;
+    PERFORM pg_notify(TG_TABLE_NAME || '_changes', json_build_object(
+        'table', TG_TABLE_NAME,
+        'op', TG_OP,
+        'id', r.id,
+        'telegram_id', to_jsonb(r) -> 'telegram_id'
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_notify ON users;
+CREATE TRIGGER users_notify AFTER INSERT OR UPDATE OR DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION notify_change();
+
+DROP TRIGGER IF EXISTS groups_notify ON groups;
+CREATE TRIGGER groups_notify AFTER INSERT OR UPDATE OR DELETE ON groups
+    FOR EACH ROW EXECUTE FUNCTION notify_change();
+
+DROP TRIGGER IF EXISTS user_group_notify ON user_group;
+CREATE TRIGGER user_group_notify AFTER INSERT OR UPDATE OR DELETE ON user_group
+    FOR EACH ROW EXECUTE FUNCTION notify_change();
+
+-- Audit log (GetAuditLog, ListAuditLogByActor): who changed which row of
+-- users, groups and user_group, and how, as JSON of the row before and
+-- after. Written by the trigger below, so every write is recorded,
+-- whoever makes it; the actor is only known for transactions whose
+-- context has one (ContextWithActor). Unlike the history tables it covers
+-- the memberships too, and DB.PruneAuditLog (Config.AuditRetention) keeps
+-- it from growing forever.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    table_name TEXT NOT NULL,
+    row_id BIGINT NOT NULL,
+    operation TEXT NOT NULL, -- INSERT, UPDATE or DELETE
+    actor TEXT,              -- NULL when the change had no actor
+    old_data JSONB,          -- The row before; NULL for an INSERT
+    new_data JSONB,          -- The row after; NULL for a DELETE
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_row ON audit_log(table_name, row_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_changed_at ON audit_log(changed_at);
+
+-- The actor comes from app.audit_actor, which Transaction and InTx set
+-- for the transaction (set_config(..., true)) when the context has one
+CREATE OR REPLACE FUNCTION record_audit() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO audit_log (table_name, row_id, operation, actor, old_data, new_data)
+    VALUES (
+        TG_TABLE_NAME,
+        CASE WHEN TG_OP = 'DELETE' THEN OLD.id ELSE NEW.id END,
+        TG_OP,
+        NULLIF(current_setting('app.audit_actor', true), ''),
+        CASE WHEN TG_OP <> 'INSERT' THEN to_jsonb(OLD) END,
+        CASE WHEN TG_OP <> 'DELETE' THEN to_jsonb(NEW) END
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_audit ON users;
+CREATE TRIGGER users_audit AFTER INSERT OR UPDATE OR DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION record_audit();
+
+DROP TRIGGER IF EXISTS groups_audit ON groups;
+CREATE TRIGGER groups_audit AFTER INSERT OR UPDATE OR DELETE ON groups
+    FOR EACH ROW EXECUTE FUNCTION record_audit();
+
+DROP TRIGGER IF EXISTS user_group_audit ON user_group;
+CREATE TRIGGER user_group_audit AFTER INSERT OR UPDATE OR DELETE ON user_group
+    FOR EACH ROW EXECUTE FUNCTION record_audit();
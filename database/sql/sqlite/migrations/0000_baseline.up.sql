@@ -0,0 +1,93 @@
+-- Migration 0000_baseline, created 2026-10-14.
+-- Brings databases made before there were migrations, by the schema.sql
+-- this project started from, up to the schema 0001 starts from. Open runs
+-- it once, in a transaction, only on a database without schema_migrations:
+-- a schema.sql that made one had all of this already. It has no down.
+
+-- users gets the status CHECK and emails (GetUserByEmail,
+-- UpsertUserByEmail), user_group its foreign keys. SQLite can't add a
+-- constraint to a table, so both are rebuilt: a new table, the rows copied
+-- over, the old one dropped and the new one renamed into place, with the
+-- AUTOINCREMENT counter carried along. users goes first, while nothing
+-- references it yet; dropping it then can't cascade. schema.sql puts back
+-- the indexes that go with the old tables.
+--
+-- A status other than active, blocked or banned fails the migration; fix
+-- those rows first. Memberships of a user or group that's gone, which the
+-- foreign keys refuse, are dropped.
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    telegram_id INTEGER NOT NULL UNIQUE,
+    first_name TEXT NOT NULL DEFAULT '',
+    username TEXT DEFAULT '',
+    balance_game REAL DEFAULT 0,
+    balance_chats REAL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'active' CONSTRAINT users_status_check CHECK (status IN ('active', 'blocked', 'banned')),
+    language TEXT NOT NULL DEFAULT 'en',
+    refer_from_id INTEGER,
+    last_streak_claim_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    email TEXT
+);
+INSERT INTO users_new (id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at)
+SELECT id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at
+FROM users;
+DELETE FROM sqlite_sequence WHERE name = 'users_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'users_new', seq FROM sqlite_sequence WHERE name = 'users';
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(lower(email));
+
+CREATE TABLE user_group_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_telegram_id INTEGER NOT NULL REFERENCES users(telegram_id) ON DELETE CASCADE,
+    group_telegram_id INTEGER NOT NULL REFERENCES groups(telegram_id) ON DELETE RESTRICT,
+    balance REAL DEFAULT 0,
+    UNIQUE(user_telegram_id, group_telegram_id)
+);
+INSERT INTO user_group_new (id, user_telegram_id, group_telegram_id, balance)
+SELECT id, user_telegram_id, group_telegram_id, balance
+FROM user_group
+WHERE user_telegram_id IN (SELECT telegram_id FROM users)
+  AND group_telegram_id IN (SELECT telegram_id FROM groups);
+DELETE FROM sqlite_sequence WHERE name = 'user_group_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'user_group_new', seq FROM sqlite_sequence WHERE name = 'user_group';
+DROP TABLE user_group;
+ALTER TABLE user_group_new RENAME TO user_group;
+
+-- The history tables, as 0001 and the migrations after it change them;
+-- schema.sql, which runs after the migrations, adds the triggers that
+-- fill them
+CREATE TABLE IF NOT EXISTS user_history (
+    history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    operation TEXT NOT NULL,
+    changed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    first_name TEXT NOT NULL,
+    username TEXT,
+    balance_game REAL,
+    balance_chats REAL,
+    status TEXT NOT NULL,
+    language TEXT NOT NULL,
+    refer_from_id INTEGER,
+    last_streak_claim_at DATETIME,
+    created_at DATETIME,
+    updated_at DATETIME,
+    email TEXT
+);
+
+CREATE TABLE IF NOT EXISTS group_history (
+    history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    operation TEXT NOT NULL,
+    changed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    id INTEGER NOT NULL,
+    balance REAL,
+    telegram_id INTEGER NOT NULL,
+    title TEXT,
+    url TEXT,
+    created_at DATETIME,
+    updated_at DATETIME
+);
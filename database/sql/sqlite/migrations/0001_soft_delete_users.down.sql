@@ -0,0 +1,11 @@
+-- Undoes migration 0001_soft_delete_users, for `db migrate down`.
+
+-- The triggers name the column, so they go first; the older schema.sql
+-- recreates them without it on the next Open. Soft-deleted users come
+-- back as live ones.
+DROP TRIGGER IF EXISTS users_history_insert;
+DROP TRIGGER IF EXISTS users_history_update;
+DROP TRIGGER IF EXISTS users_history_delete;
+
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE user_history DROP COLUMN deleted_at;
@@ -0,0 +1,13 @@
+-- Migration 0001_soft_delete_users, created 2026-10-14.
+-- Brings databases made by an older schema.sql up to date; make the same
+-- change in schema.sql, which new databases are created from. Runs once,
+-- in a transaction, when Open finds it hasn't been applied.
+
+ALTER TABLE users ADD COLUMN deleted_at DATETIME;
+ALTER TABLE user_history ADD COLUMN deleted_at DATETIME;
+
+-- The history triggers are CREATE ... IF NOT EXISTS, so schema.sql, which
+-- runs right after, only recreates them with deleted_at once they're gone
+DROP TRIGGER IF EXISTS users_history_insert;
+DROP TRIGGER IF EXISTS users_history_update;
+DROP TRIGGER IF EXISTS users_history_delete;
@@ -0,0 +1,10 @@
+-- Undoes migration 0002_user_versions, for `db migrate down`.
+
+-- The triggers name the column, so they go first; the older schema.sql
+-- recreates them without it on the next Open
+DROP TRIGGER IF EXISTS users_history_insert;
+DROP TRIGGER IF EXISTS users_history_update;
+DROP TRIGGER IF EXISTS users_history_delete;
+
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE user_history DROP COLUMN version;
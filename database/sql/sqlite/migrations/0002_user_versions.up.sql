@@ -0,0 +1,12 @@
+-- Migration 0002_user_versions, created 2026-10-14.
+-- Brings databases made by an older schema.sql up to date; make the same
+-- change in schema.sql, which new databases are created from. Runs once,
+-- in a transaction, when Open finds it hasn't been applied.
+
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE user_history ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+
+-- As in 0001, schema.sql recreates the history triggers with the column
+DROP TRIGGER IF EXISTS users_history_insert;
+DROP TRIGGER IF EXISTS users_history_update;
+DROP TRIGGER IF EXISTS users_history_delete;
@@ -0,0 +1,11 @@
+-- Undoes migration 0003_search_index, for `db migrate down`.
+
+DROP TRIGGER IF EXISTS users_fts_insert;
+DROP TRIGGER IF EXISTS users_fts_update;
+DROP TRIGGER IF EXISTS users_fts_delete;
+DROP TRIGGER IF EXISTS groups_fts_insert;
+DROP TRIGGER IF EXISTS groups_fts_update;
+DROP TRIGGER IF EXISTS groups_fts_delete;
+
+DROP TABLE IF EXISTS users_fts;
+DROP TABLE IF EXISTS groups_fts;
@@ -0,0 +1,8 @@
+-- Migration 0003_search_index, created 2026-10-14.
+-- Brings databases made by an older schema.sql up to date; make the same
+-- change in schema.sql, which new databases are created from. Runs once,
+-- in a transaction, when Open finds it hasn't been applied.
+
+-- Nothing left to do here: the FTS5 tables are in search.sql, which Open
+-- runs once the migrations are done, on SQLite builds that have FTS5,
+-- and fills from the rows already there when it creates them.
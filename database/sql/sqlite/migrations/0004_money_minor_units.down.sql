@@ -0,0 +1,16 @@
+-- Undoes migration 0004_money_minor_units, for `db migrate down`.
+
+-- As in the up file, the older schema.sql recreates the triggers on the
+-- next Open. The columns stay INTEGER, which keeps the fractions as REAL
+-- values.
+DROP TRIGGER IF EXISTS users_history_update;
+DROP TRIGGER IF EXISTS groups_history_update;
+DROP TRIGGER IF EXISTS users_audit_update;
+DROP TRIGGER IF EXISTS groups_audit_update;
+DROP TRIGGER IF EXISTS user_group_audit_update;
+
+UPDATE users SET balance_game = balance_game / 100.0, balance_chats = balance_chats / 100.0;
+UPDATE groups SET balance = balance / 100.0;
+UPDATE user_group SET balance = balance / 100.0;
+UPDATE user_history SET balance_game = balance_game / 100.0, balance_chats = balance_chats / 100.0;
+UPDATE group_history SET balance = balance / 100.0;
@@ -0,0 +1,122 @@
+-- Migration 0004_money_minor_units, created 2026-10-14.
+-- Brings databases made by an older schema.sql up to date; make the same
+-- change in schema.sql, which new databases are created from. Runs once,
+-- in a transaction, when Open finds it hasn't been applied.
+
+-- Balances become whole cents (database.Money) instead of fractional
+-- units, in INTEGER columns, as schema.sql declares them. SQLite can't
+-- change a column's type, so each table with a balance is rebuilt: a new
+-- table, the rows copied over with the balances times 100, rounded, the
+-- old one dropped and the new one renamed into place, with the
+-- AUTOINCREMENT counter carried along. Open runs migrations with foreign
+-- keys off, so dropping users and groups doesn't cascade to the tables
+-- that reference them, and checks them before committing. Dropping a
+-- table drops its triggers; schema.sql, which runs right after, recreates
+-- them and the indexes, and Open the search triggers. The rewrite isn't a
+-- change worth a history or audit entry per row. The audit log's JSON
+-- from before keeps the old units.
+
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    telegram_id INTEGER NOT NULL UNIQUE,
+    first_name TEXT NOT NULL DEFAULT '',
+    username TEXT DEFAULT '',
+    balance_game INTEGER DEFAULT 0,
+    balance_chats INTEGER DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'active' CONSTRAINT users_status_check CHECK (status IN ('active', 'blocked', 'banned')),
+    language TEXT NOT NULL DEFAULT 'en',
+    refer_from_id INTEGER,
+    last_streak_claim_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    email TEXT,
+    deleted_at DATETIME,
+    version INTEGER NOT NULL DEFAULT 1
+);
+INSERT INTO users_new (id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version)
+SELECT id, telegram_id, first_name, username, round(balance_game * 100), round(balance_chats * 100), status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version
+FROM users;
+DELETE FROM sqlite_sequence WHERE name = 'users_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'users_new', seq FROM sqlite_sequence WHERE name = 'users';
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE TABLE groups_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    balance INTEGER DEFAULT 0,
+    telegram_id INTEGER NOT NULL UNIQUE,
+    title TEXT DEFAULT '',
+    url TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO groups_new (id, balance, telegram_id, title, url, created_at, updated_at)
+SELECT id, round(balance * 100), telegram_id, title, url, created_at, updated_at
+FROM groups;
+DELETE FROM sqlite_sequence WHERE name = 'groups_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'groups_new', seq FROM sqlite_sequence WHERE name = 'groups';
+DROP TABLE groups;
+ALTER TABLE groups_new RENAME TO groups;
+
+CREATE TABLE user_group_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_telegram_id INTEGER NOT NULL REFERENCES users(telegram_id) ON DELETE CASCADE,
+    group_telegram_id INTEGER NOT NULL REFERENCES groups(telegram_id) ON DELETE RESTRICT,
+    balance INTEGER DEFAULT 0,
+    UNIQUE(user_telegram_id, group_telegram_id)
+);
+INSERT INTO user_group_new (id, user_telegram_id, group_telegram_id, balance)
+SELECT id, user_telegram_id, group_telegram_id, round(balance * 100)
+FROM user_group;
+DELETE FROM sqlite_sequence WHERE name = 'user_group_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'user_group_new', seq FROM sqlite_sequence WHERE name = 'user_group';
+DROP TABLE user_group;
+ALTER TABLE user_group_new RENAME TO user_group;
+
+CREATE TABLE user_history_new (
+    history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    operation TEXT NOT NULL,
+    changed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    first_name TEXT NOT NULL,
+    username TEXT,
+    balance_game INTEGER,
+    balance_chats INTEGER,
+    status TEXT NOT NULL,
+    language TEXT NOT NULL,
+    refer_from_id INTEGER,
+    last_streak_claim_at DATETIME,
+    created_at DATETIME,
+    updated_at DATETIME,
+    email TEXT,
+    deleted_at DATETIME,
+    version INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO user_history_new (history_id, operation, changed_at, id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version)
+SELECT history_id, operation, changed_at, id, telegram_id, first_name, username, round(balance_game * 100), round(balance_chats * 100), status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version
+FROM user_history;
+DELETE FROM sqlite_sequence WHERE name = 'user_history_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'user_history_new', seq FROM sqlite_sequence WHERE name = 'user_history';
+DROP TABLE user_history;
+ALTER TABLE user_history_new RENAME TO user_history;
+
+CREATE TABLE group_history_new (
+    history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    operation TEXT NOT NULL,
+    changed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    id INTEGER NOT NULL,
+    balance INTEGER,
+    telegram_id INTEGER NOT NULL,
+    title TEXT,
+    url TEXT,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+INSERT INTO group_history_new (history_id, operation, changed_at, id, balance, telegram_id, title, url, created_at, updated_at)
+SELECT history_id, operation, changed_at, id, round(balance * 100), telegram_id, title, url, created_at, updated_at
+FROM group_history;
+DELETE FROM sqlite_sequence WHERE name = 'group_history_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'group_history_new', seq FROM sqlite_sequence WHERE name = 'group_history';
+DROP TABLE group_history;
+ALTER TABLE group_history_new RENAME TO group_history;
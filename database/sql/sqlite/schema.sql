@@ -0,0 +1,337 @@
+-- Users table
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    telegram_id INTEGER NOT NULL UNIQUE,
+    first_name TEXT NOT NULL DEFAULT '',
+    username TEXT DEFAULT '',
+    balance_game INTEGER DEFAULT 0, -- Balances are in cents (database.Money)
+    balance_chats INTEGER DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'active' CONSTRAINT users_status_check CHECK (status IN ('active', 'blocked', 'banned')),
+    language TEXT NOT NULL DEFAULT 'en',
+    refer_from_id INTEGER,
+    last_streak_claim_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    email TEXT,
+    deleted_at DATETIME, -- Set by SoftDeleteUser; most queries leave such users out
+    version INTEGER NOT NULL DEFAULT 1 -- Bumped by every update, for the IfVersion queries
+);
+
+-- Groups table
+CREATE TABLE IF NOT EXISTS groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    balance INTEGER DEFAULT 0, -- In cents
+    telegram_id INTEGER NOT NULL UNIQUE,
+    title TEXT DEFAULT '',
+    url TEXT DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- User-Group relationship table. Deleting a user drops their memberships;
+-- a group can't be deleted while it still has members (their balances would
+-- silently disappear).
+CREATE TABLE IF NOT EXISTS user_group (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_telegram_id INTEGER NOT NULL REFERENCES users(telegram_id) ON DELETE CASCADE,
+    group_telegram_id INTEGER NOT NULL REFERENCES groups(telegram_id) ON DELETE RESTRICT,
+    balance INTEGER DEFAULT 0, -- In cents
+    UNIQUE(user_telegram_id, group_telegram_id)
+);
+
+-- Tags, attached to groups through group_tags
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE
+);
+
+-- Group-Tag relationship table (many-to-many)
+CREATE TABLE IF NOT EXISTS group_tags (
+    group_telegram_id INTEGER NOT NULL REFERENCES groups(telegram_id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_telegram_id, tag_id)
+);
+
+-- Category tree: parent_id points at the parent category, NULL for roots
+CREATE TABLE IF NOT EXISTS categories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    parent_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+    name TEXT NOT NULL CONSTRAINT categories_name_check CHECK (trim(name) <> ''),
+    -- Search key maintained by the database; never insert or update it
+    name_normalized TEXT NOT NULL GENERATED ALWAYS AS (lower(trim(name))) STORED
+);
+
+-- Binary attachments (avatars), deduplicated by content hash
+CREATE TABLE IF NOT EXISTS attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sha256 BLOB NOT NULL UNIQUE,
+    content_type TEXT NOT NULL DEFAULT '',
+    size INTEGER NOT NULL,
+    data BLOB NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Transactional outbox: events written in the same tx as the change they describe
+CREATE TABLE IF NOT EXISTS outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    topic TEXT NOT NULL,
+    payload BLOB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    delivered_at DATETIME
+);
+
+-- Background jobs for the queue package. Claiming a job marks it running
+-- and moves run_at to when its visibility timeout ends, so a job whose
+-- worker died runs again; finished jobs are deleted, and dead ones (out of
+-- attempts) stay for Queue.Dead and Queue.Retry.
+CREATE TABLE IF NOT EXISTS jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type TEXT NOT NULL,
+    payload BLOB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0, -- Claims so far, including the current one
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    last_error TEXT,
+    run_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A single row rewritten to check the disk takes writes again after it filled up
+CREATE TABLE IF NOT EXISTS storage_probe (
+    id INTEGER PRIMARY KEY,
+    checked_at DATETIME NOT NULL
+);
+
+-- The named locks WithLock holds, one row each while held. A holder renews
+-- expires_at (Unix milliseconds) every 10s, so a crashed one's lock can
+-- be taken over 30s later. PostgreSQL uses advisory locks instead.
+CREATE TABLE IF NOT EXISTS locks (
+    name TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+
+-- National ID numbers, encrypted by the app (DB.EncryptField) so backups and
+-- dumps don't expose them. Ciphertext can't be searched, so lookups go by
+-- national_id_index, a keyed hash of the plaintext (BlindIndex). Kept out
+-- of users so the history triggers never copy them.
+CREATE TABLE IF NOT EXISTS user_national_ids (
+    user_telegram_id INTEGER PRIMARY KEY REFERENCES users(telegram_id) ON DELETE CASCADE,
+    national_id BLOB NOT NULL,
+    national_id_index BLOB NOT NULL UNIQUE
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
+-- Emails are unique regardless of case; the row keeps the casing as entered
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(lower(email));
+CREATE INDEX IF NOT EXISTS idx_groups_telegram_id ON groups(telegram_id);
+CREATE INDEX IF NOT EXISTS idx_user_group_user ON user_group(user_telegram_id);
+CREATE INDEX IF NOT EXISTS idx_user_group_group ON user_group(group_telegram_id);
+CREATE INDEX IF NOT EXISTS idx_group_tags_tag ON group_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);
+CREATE INDEX IF NOT EXISTS idx_categories_name ON categories(name_normalized);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(delivered_at, topic, id);
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(run_at, id) WHERE status <> 'dead';
+-- Keyset pagination by signup time (ListUsersByCreatedAt). datetime()
+-- makes the text timestamps SQLite stores and the ones the driver binds
+-- compare alike.
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(datetime(created_at), id);
+-- Alphabetical order for people, whatever the language and casing.
+-- NOCASE_UNICODE is registered by Open on every connection; writing to
+-- these tables from a connection without it fails.
+CREATE INDEX IF NOT EXISTS idx_users_first_name ON users(first_name COLLATE NOCASE_UNICODE, id);
+CREATE INDEX IF NOT EXISTS idx_groups_title ON groups(title COLLATE NOCASE_UNICODE, id);
+
+-- Version of each collation the indexes above were built with; Open runs
+-- REINDEX when the compiled-in version differs
+CREATE TABLE IF NOT EXISTS collation_versions (
+    name TEXT PRIMARY KEY,
+    version TEXT NOT NULL
+);
+
+-- Change history, written by triggers so every change is captured, including
+-- ones made outside the app (migrations, manual fixes in the sqlite3 shell).
+-- Each row is the full row image after an INSERT or UPDATE, or the last one
+-- before a DELETE; the old values of a change are in the entry before it.
+-- The history tables have no triggers of their own.
+CREATE TABLE IF NOT EXISTS user_history (
+    history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    operation TEXT NOT NULL,
+    changed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    id INTEGER NOT NULL,
+    telegram_id INTEGER NOT NULL,
+    first_name TEXT NOT NULL,
+    username TEXT,
+    balance_game INTEGER,
+    balance_chats INTEGER,
+    status TEXT NOT NULL,
+    language TEXT NOT NULL,
+    refer_from_id INTEGER,
+    last_streak_claim_at DATETIME,
+    created_at DATETIME,
+    updated_at DATETIME,
+    email TEXT,
+    deleted_at DATETIME,
+    version INTEGER NOT NULL DEFAULT 0 -- 0 for entries from before the column
+);
+
+CREATE TABLE IF NOT EXISTS group_history (
+    history_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    operation TEXT NOT NULL,
+    changed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    id INTEGER NOT NULL,
+    balance INTEGER,
+    telegram_id INTEGER NOT NULL,
+    title TEXT,
+    url TEXT,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_history_id ON user_history(id, history_id);
+CREATE INDEX IF NOT EXISTS idx_group_history_id ON group_history(id, history_id);
+
+CREATE TRIGGER IF NOT EXISTS users_history_insert AFTER INSERT ON users
+BEGIN
+    INSERT INTO user_history (operation, id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version)
+    VALUES ('INSERT', NEW.id, NEW.telegram_id, NEW.first_name, NEW.username, NEW.balance_game, NEW.balance_chats, NEW.status, NEW.language, NEW.refer_from_id, NEW.last_streak_claim_at, NEW.created_at, NEW.updated_at, NEW.email, NEW.deleted_at, NEW.version);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_history_update AFTER UPDATE ON users
+BEGIN
+    INSERT INTO user_history (operation, id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version)
+    VALUES ('UPDATE', NEW.id, NEW.telegram_id, NEW.first_name, NEW.username, NEW.balance_game, NEW.balance_chats, NEW.status, NEW.language, NEW.refer_from_id, NEW.last_streak_claim_at, NEW.created_at, NEW.updated_at, NEW.email, NEW.deleted_at, NEW.version);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_history_delete AFTER DELETE ON users
+BEGIN
+    INSERT INTO user_history (operation, id, telegram_id, first_name, username, balance_game, balance_chats, status, language, refer_from_id, last_streak_claim_at, created_at, updated_at, email, deleted_at, version)
+    VALUES ('DELETE', OLD.id, OLD.telegram_id, OLD.first_name, OLD.username, OLD.balance_game, OLD.balance_chats, OLD.status, OLD.language, OLD.refer_from_id, OLD.last_streak_claim_at, OLD.created_at, OLD.updated_at, OLD.email, OLD.deleted_at, OLD.version);
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_history_insert AFTER INSERT ON groups
+BEGIN
+    INSERT INTO group_history (operation, id, balance, telegram_id, title, url, created_at, updated_at)
+    VALUES ('INSERT', NEW.id, NEW.balance, NEW.telegram_id, NEW.title, NEW.url, NEW.created_at, NEW.updated_at);
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_history_update AFTER UPDATE ON groups
+BEGIN
+    INSERT INTO group_history (operation, id, balance, telegram_id, title, url, created_at, updated_at)
+    VALUES ('UPDATE', NEW.id, NEW.balance, NEW.telegram_id, NEW.title, NEW.url, NEW.created_at, NEW.updated_at);
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_history_delete AFTER DELETE ON groups
+BEGIN
+    INSERT INTO group_history (operation, id, balance, telegram_id, title, url, created_at, updated_at)
+    VALUES ('DELETE', OLD.id, OLD.balance, OLD.telegram_id, OLD.title, OLD.url, OLD.created_at, OLD.updated_at);
+END;
+
+-- Audit log (GetAuditLog, ListAuditLogByActor): who changed which row of users, groups and
+-- user_group, and how, as JSON of the row before and after. Written by
+-- the triggers below, so every write is recorded, whoever makes it; the
+-- actor is only known for transactions whose context has one
+-- (ContextWithActor). Unlike the history tables it covers the
+-- memberships too, and DB.PruneAuditLog (Config.AuditRetention) keeps
+-- it from growing forever.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    table_name TEXT NOT NULL,
+    row_id INTEGER NOT NULL,
+    operation TEXT NOT NULL, -- INSERT, UPDATE or DELETE
+    actor TEXT,              -- NULL when the change had no actor
+    old_data TEXT,           -- The row before, as JSON; NULL for an INSERT
+    new_data TEXT,           -- The row after; NULL for a DELETE
+    changed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_row ON audit_log(table_name, row_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_changed_at ON audit_log(changed_at);
+
+-- The actor of the transaction being written, for the audit triggers.
+-- Transaction and InTx put the one row in when the context has an actor
+-- and take it out again before committing, so no other connection ever
+-- sees it; SQLite has a single writer, so no two transactions share it.
+CREATE TABLE IF NOT EXISTS audit_actor (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    actor TEXT NOT NULL
+);
+
+-- The JSON lists every column; a new column goes in here too
+
+CREATE TRIGGER IF NOT EXISTS users_audit_insert AFTER INSERT ON users
+BEGIN
+    INSERT INTO audit_log (table_name, row_id, operation, actor, old_data, new_data)
+    VALUES ('users', NEW.id, 'INSERT', (SELECT actor FROM audit_actor),
+        NULL,
+        json_object('id', NEW.id, 'telegram_id', NEW.telegram_id, 'first_name', NEW.first_name, 'username', NEW.username, 'balance_game', NEW.balance_game, 'balance_chats', NEW.balance_chats, 'status', NEW.status, 'language', NEW.language, 'refer_from_id', NEW.refer_from_id, 'last_streak_claim_at', NEW.last_streak_claim_at, 'created_at', NEW.created_at, 'updated_at', NEW.updated_at, 'email', NEW.email, 'deleted_at', NEW.deleted_at, 'version', NEW.version));
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_audit_update AFTER UPDATE ON users
+BEGIN
+    INSERT INTO audit_log (table_name, row_id, operation, actor, old_data, new_data)
+    VALUES ('users', NEW.id, 'UPDATE', (SELECT actor FROM audit_actor),
+        json_object('id', OLD.id, 'telegram_id', OLD.telegram_id, 'first_name', OLD.first_name, 'username', OLD.username, 'balance_game', OLD.balance_game, 'balance_chats', OLD.balance_chats, 'status', OLD.status, 'language', OLD.language, 'refer_from_id', OLD.refer_from_id, 'last_streak_claim_at', OLD.last_streak_claim_at, 'created_at', OLD.created_at, 'updated_at', OLD.updated_at, 'email', OLD.email, 'deleted_at', OLD.deleted_at, 'version', OLD.version),
+        json_object('id', NEW.id, 'telegram_id', NEW.telegram_id, 'first_name', NEW.first_name, 'username', NEW.username, 'balance_game', NEW.balance_game, 'balance_chats', NEW.balance_chats, 'status', NEW.status, 'language', NEW.language, 'refer_from_id', NEW.refer_from_id, 'last_streak_claim_at', NEW.last_streak_claim_at, 'created_at', NEW.created_at, 'updated_at', NEW.updated_at, 'email', NEW.email, 'deleted_at', NEW.deleted_at, 'version', NEW.version));
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_audit_delete AFTER DELETE ON users
+BEGIN
+    INSERT INTO audit_log (table_name, row_id, operation, actor, old_data, new_data)
+    VALUES ('users', OLD.id, 'DELETE', (SELECT actor FROM audit_actor),
+        json_object('id', OLD.id, 'telegram_id', OLD.telegram_id, 'first_name', OLD.first_name, 'username', OLD.username, 'balance_game', OLD.balance_game, 'balance_chats', OLD.balance_chats, 'status', OLD.status, 'language', OLD.language, 'refer_from_id', OLD.refer_from_id, 'last_streak_claim_at', OLD.last_streak_claim_at, 'created_at', OLD.created_at, 'updated_at', OLD.updated_at, 'email', OLD.email, 'deleted_at', OLD.deleted_at, 'version', OLD.version),
+        NULL);
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_audit_insert AFTER INSERT ON groups
+BEGIN
+    INSERT INTO audit_log (table_name, row_id, operation, actor, old_data, new_data)
+    VALUES ('groups', NEW.id, 'INSERT', (SELECT actor FROM audit_actor),
+        NULL,
+        json_object('id', NEW.id, 'balance', NEW.balance, 'telegram_id', NEW.telegram_id, 'title', NEW.title, 'url', NEW.url, 'created_at', NEW.created_at, 'updated_at', NEW.updated_at));
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_audit_update AFTER UPDATE ON groups
+BEGIN
+    INSERT INTO audit_log (table_name, row_id, operation, actor, old_data, new_data)
+    VALUES ('groups', NEW.id, 'UPDATE', (SELECT actor FROM audit_actor),
+        json_object('id', OLD.id, 'balance', OLD.balance, 'telegram_id', OLD.telegram_id, 'title', OLD.title, 'url', OLD.url, 'created_at', OLD.created_at, 'updated_at', OLD.updated_at),
+        json_object('id', NEW.id, 'balance', NEW.balance, 'telegram_id', NEW.telegram_id, 'title', NEW.title, 'url', NEW.url, 'created_at', NEW.created_at, 'updated_at', NEW.updated_at));
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_audit_delete AFTER DELETE ON groups
+BEGIN
+    INSERT INTO audit_log (table_name, row_id, operation, actor, old_data, new_data)
+    VALUES ('groups', OLD.id, 'DELETE', (SELECT actor FROM audit_actor),
+        json_object('id', OLD.id, 'balance', OLD.balance, 'telegram_id', OLD.telegram_id, 'title', OLD.title, 'url', OLD.url, 'created_at', OLD.created_at, 'updated_at', OLD.updated_at),
+        NULL);
+END;
+
+CREATE TRIGGER IF NOT EXISTS user_group_audit_insert AFTER INSERT ON user_group
+BEGIN
+    INSERT INTO audit_log (table_name, row_id, operation, actor, old_data, new_data)
+    VALUES ('user_group', NEW.id, 'INSERT', (SELECT actor FROM audit_actor),
+        NULL,
+        json_object('id', NEW.id, 'user_telegram_id', NEW.user_telegram_id, 'group_telegram_id', NEW.group_telegram_id, 'balance', NEW.balance));
+END;
+
+CREATE TRIGGER IF NOT EXISTS user_group_audit_update AFTER UPDATE ON user_group
+BEGIN
+    INSERT INTO audit_log (table_name, row_id, operation, actor, old_data, new_data)
+    VALUES ('user_group', NEW.id, 'UPDATE', (SELECT actor FROM audit_actor),
+        json_object('id', OLD.id, 'user_telegram_id', OLD.user_telegram_id, 'group_telegram_id', OLD.group_telegram_id, 'balance', OLD.balance),
+        json_object('id', NEW.id, 'user_telegram_id', NEW.user_telegram_id, 'group_telegram_id', NEW.group_telegram_id, 'balance', NEW.balance));
+END;
+
+CREATE TRIGGER IF NOT EXISTS user_group_audit_delete AFTER DELETE ON user_group
+BEGIN
+    INSERT INTO audit_log (table_name, row_id, operation, actor, old_data, new_data)
+    VALUES ('user_group', OLD.id, 'DELETE', (SELECT actor FROM audit_actor),
+        json_object('id', OLD.id, 'user_telegram_id', OLD.user_telegram_id, 'group_telegram_id', OLD.group_telegram_id, 'balance', OLD.balance),
+        NULL);
+END;
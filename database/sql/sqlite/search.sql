@@ -0,0 +1,53 @@
+-- Full-text search (DB.Search): FTS5 indexes over the names of users and
+-- the titles of groups. They're external content tables, so the text
+-- itself stays in users and groups, and these triggers keep the indexes
+-- in step with every change. An index needs the old values to remove a
+-- row, hence 'delete' with OLD before the new row goes in.
+--
+-- Apart from schema.sql, since FTS5 has to be compiled in: mattn/go-sqlite3
+-- needs -tags sqlite_fts5, modernc always has it. Open runs this file after
+-- schema.sql when the library has FTS5 and skips it otherwise, and then
+-- DB.Search returns ErrSearchUnavailable. sqlc reads both files.
+CREATE VIRTUAL TABLE IF NOT EXISTS users_fts USING fts5(
+    first_name, username,
+    content = 'users', content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS groups_fts USING fts5(
+    title, url,
+    content = 'groups', content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS users_fts_insert AFTER INSERT ON users
+BEGIN
+    INSERT INTO users_fts (rowid, first_name, username) VALUES (NEW.id, NEW.first_name, NEW.username);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_update AFTER UPDATE OF first_name, username ON users
+BEGIN
+    INSERT INTO users_fts (users_fts, rowid, first_name, username) VALUES ('delete', OLD.id, OLD.first_name, OLD.username);
+    INSERT INTO users_fts (rowid, first_name, username) VALUES (NEW.id, NEW.first_name, NEW.username);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_delete AFTER DELETE ON users
+BEGIN
+    INSERT INTO users_fts (users_fts, rowid, first_name, username) VALUES ('delete', OLD.id, OLD.first_name, OLD.username);
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_fts_insert AFTER INSERT ON groups
+BEGIN
+    INSERT INTO groups_fts (rowid, title, url) VALUES (NEW.id, NEW.title, NEW.url);
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_fts_update AFTER UPDATE OF title, url ON groups
+BEGIN
+    INSERT INTO groups_fts (groups_fts, rowid, title, url) VALUES ('delete', OLD.id, OLD.title, OLD.url);
+    INSERT INTO groups_fts (rowid, title, url) VALUES (NEW.id, NEW.title, NEW.url);
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_fts_delete AFTER DELETE ON groups
+BEGIN
+    INSERT INTO groups_fts (groups_fts, rowid, title, url) VALUES ('delete', OLD.id, OLD.title, OLD.url);
+END;
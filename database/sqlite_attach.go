@@ -0,0 +1,47 @@
+//go:build !postgres
+
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// attachDatabases attaches Config.Attach to a new connection. keyed is
+// whether the connection has an encryption key, which SQLCipher would
+// otherwise reuse for every attached file; readOnly, Config.ReadOnly,
+// attaches every one read-only.
+func attachDatabases(c *sqlite3.SQLiteConn, dbs []AttachedDB, keyed, readOnly bool) error {
+	for _, a := range dbs {
+		a.ReadOnly = a.ReadOnly || readOnly
+		query, args := "ATTACH DATABASE ? AS "+a.Name, []driver.Value{sqliteAttachPath(a)}
+		if keyed {
+			query += " KEY ?"
+			args = append(args, a.Key)
+		}
+		if _, err := c.Exec(query, args); err != nil {
+			return fmt.Errorf("failed to attach %s (%s): %w", a.Name, a.Path, err)
+		}
+	}
+	return nil
+}
+
+// sqliteAttachPath is the file name ATTACH takes for a: a URI with
+// mode=ro if it's read-only, which mattn/go-sqlite3 lets through since it
+// opens connections with SQLITE_OPEN_URI
+func sqliteAttachPath(a AttachedDB) string {
+	if !a.ReadOnly {
+		return a.Path
+	}
+	if strings.HasPrefix(a.Path, "file:") {
+		sep := "?"
+		if strings.Contains(a.Path, "?") {
+			sep = "&"
+		}
+		return a.Path + sep + "mode=ro"
+	}
+	return "file:" + sqlitePathEscaper.Replace(a.Path) + "?mode=ro"
+}
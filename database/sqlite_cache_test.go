@@ -0,0 +1,44 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"your-project/database"
+)
+
+// TestCachedQueriesShared has two DBs on one file, as two processes would
+// be, sharing one cache store: a write through either is seen by both
+func TestCachedQueriesShared(t *testing.T) {
+	ctx := context.Background()
+	cfg := database.Config{LogLevel: "silent", JournalMode: "wal", DSN: filepath.Join(t.TempDir(), "shared.db")}
+	var dbs [2]*database.DB
+	for i := range dbs {
+		db, err := database.Open(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		dbs[i] = db
+	}
+	store := database.NewLRU(100)
+	a, b := dbs[0].CachedQueries(store, time.Hour), dbs[1].CachedQueries(store, time.Hour)
+
+	if _, err := a.CreateGroup(ctx, database.CreateGroupParams{TelegramID: 1, Title: sql.Null[string]{V: "old", Valid: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := b.GetGroupByTelegramID(ctx, 1); err != nil || g.Title.V != "old" { // Now cached
+		t.Fatalf("%+v, %v", g, err)
+	}
+	if _, err := a.UpsertGroup(ctx, database.UpsertGroupParams{TelegramID: 1, Title: sql.Null[string]{V: "new", Valid: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := b.GetGroupByTelegramID(ctx, 1); err != nil || g.Title.V != "new" {
+		t.Errorf("the other DB read %q, %v; want the title a wrote", g.Title.V, err)
+	}
+}
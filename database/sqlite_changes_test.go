@@ -0,0 +1,119 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestListenOwnWrites(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := dbtest.NewTestDB(t)
+	users, err := db.Listen(ctx, "users_changes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := db.Listen(ctx, "groups_changes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newUsers(t, db, 1)
+	if _, err := db.Q.UpdateUser(ctx, database.UpdateUserParams{FirstName: "renamed", TelegramID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DBTX().ExecContext(ctx, "DELETE FROM users WHERE telegram_id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []string{"INSERT", "UPDATE", "DELETE"} {
+		if ev := nextChange(t, users); ev != (database.ChangeEvent{Table: "users", Operation: op}) {
+			t.Errorf("got %+v, want a users %s", ev, op)
+		}
+	}
+	noChange(t, groups) // Listens to another table
+
+	// Inside a transaction, on commit and not before
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		if _, err := tx.CreateUser(ctx, database.CreateUserParams{TelegramID: 2, FirstName: "user"}); err != nil {
+			return err
+		}
+		noChange(t, users)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextChange(t, users); ev.Operation != "INSERT" {
+		t.Errorf("after commit: %+v, want the INSERT", ev)
+	}
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		if _, err := tx.CreateUser(ctx, database.CreateUserParams{TelegramID: 3, FirstName: "user"}); err != nil {
+			return err
+		}
+		return errors.New("roll back")
+	})
+	if err == nil {
+		t.Fatal("the transaction didn't fail")
+	}
+	noChange(t, users)
+
+	// A read is no change
+	if _, err := db.Q.GetUserByTelegramID(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	noChange(t, users)
+
+	cancel()
+	select {
+	case _, ok := <-users:
+		if ok {
+			t.Error("an event after ctx was done")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the channel stayed open after ctx was done")
+	}
+}
+
+func TestListenSlowReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := dbtest.NewTestDB(t)
+	ch, err := db.Listen(ctx, "users_changes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody reads while 300 users are created, so the writes go on and
+	// what doesn't fit in the buffer is dropped
+	const n, buffer = 300, 256
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	newUsers(t, db, ids...)
+	if got := db.DroppedChanges(); got != n-buffer {
+		t.Errorf("DroppedChanges %d, want %d", got, n-buffer)
+	}
+	for range buffer {
+		if ev := nextChange(t, ch); ev.Operation != "INSERT" {
+			t.Fatalf("a buffered event: %+v, want an INSERT", ev)
+		}
+	}
+	noChange(t, ch)
+
+	// The next change is announced by a resync for the ones lost
+	newUsers(t, db, n+1)
+	if ev := nextChange(t, ch); ev != (database.ChangeEvent{Table: "users", Operation: database.ChangeResync}) {
+		t.Errorf("after the drops: %+v, want a resync", ev)
+	}
+	if ev := nextChange(t, ch); ev.Operation != "INSERT" {
+		t.Errorf("after the resync: %+v, want the INSERT", ev)
+	}
+}
@@ -0,0 +1,163 @@
+//go:build !postgres
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteCipher holds the key of a database opened with
+// Config.EncryptionKey. Rekey swaps the key holding mu, so no connection
+// opens with the old one meanwhile, and bumps gen, which retires the
+// connections opened before.
+type sqliteCipher struct {
+	mu  sync.RWMutex
+	key string
+	gen atomic.Uint64
+}
+
+// sqliteCipherDriver is what sql.DB.Driver returns for an encrypted
+// database, so Rekey can find its key
+type sqliteCipherDriver struct {
+	*sqlite3.SQLiteDriver
+	cipher *sqliteCipher
+}
+
+// sqliteCipherConnector opens the connections of an encrypted database
+type sqliteCipherConnector struct {
+	drv sqliteCipherDriver
+	dsn string
+}
+
+func (c sqliteCipherConnector) Connect(context.Context) (driver.Conn, error) {
+	gen := c.drv.cipher.gen.Load()
+	conn, err := c.drv.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteCipherConn{SQLiteConn: conn.(*sqlite3.SQLiteConn), gen: gen, cipher: c.drv.cipher}, nil
+}
+
+func (c sqliteCipherConnector) Driver() driver.Driver { return c.drv }
+
+// sqliteCipherConn is a connection that remembers which key it was opened
+// with. database/sql asks IsValid before it puts a connection back in the
+// pool, so one holding a key Rekey has replaced is closed instead.
+type sqliteCipherConn struct {
+	*sqlite3.SQLiteConn
+	gen    uint64
+	cipher *sqliteCipher
+}
+
+func (c *sqliteCipherConn) IsValid() bool { return c.gen == c.cipher.gen.Load() }
+
+// sqliteRawConn is the mattn/go-sqlite3 connection behind what Conn.Raw
+// hands out, encrypted or not
+func sqliteRawConn(driverConn any) (*sqlite3.SQLiteConn, bool) {
+	switch c := driverConn.(type) {
+	case *sqlite3.SQLiteConn:
+		return c, true
+	case *sqliteCipherConn:
+		return c.SQLiteConn, true
+	}
+	return nil, false
+}
+
+// unlock gives c the key before anything reads the file, then checks it
+// took. A SQLite without SQLCipher ignores PRAGMA key, which would leave
+// the database unencrypted, and a wrong key only shows on the first read.
+// The journal mode is set after, since switching it reads the file.
+func (k *sqliteCipher) unlock(c *sqlite3.SQLiteConn, journalMode string) error {
+	k.mu.RLock()
+	key := k.key
+	k.mu.RUnlock()
+
+	if _, err := c.Exec("PRAGMA key = "+sqliteKeyLiteral(key), nil); err != nil {
+		return fmt.Errorf("failed to set the encryption key: %w", err)
+	}
+	if !sqliteHasCipher(c) {
+		return errors.New("EncryptionKey needs a SQLite built with SQLCipher, and this one isn't (PRAGMA cipher_version is empty)")
+	}
+	if _, err := c.Exec("SELECT count(*) FROM sqlite_master", nil); err != nil {
+		var se sqlite3.Error
+		if errors.As(err, &se) && se.Code == sqlite3.ErrNotADB {
+			return ErrWrongEncryptionKey
+		}
+		return fmt.Errorf("failed to read the encrypted database: %w", err)
+	}
+	if journalMode != "" {
+		if _, err := c.Exec("PRAGMA journal_mode = "+strings.ToLower(journalMode), nil); err != nil {
+			return fmt.Errorf("failed to set journal_mode: %w", err)
+		}
+	}
+	return nil
+}
+
+// sqliteHasCipher tells SQLCipher and builds compatible with it (SQLite3
+// Multiple Ciphers) from plain SQLite, which knows neither function
+func sqliteHasCipher(c *sqlite3.SQLiteConn) bool {
+	for _, query := range []string{"PRAGMA cipher_version", "SELECT sqlite3mc_version()"} {
+		rows, err := c.Query(query, nil)
+		if err != nil {
+			continue // SQLCipher has no sqlite3mc_version()
+		}
+		dest := make([]driver.Value, len(rows.Columns()))
+		err = rows.Next(dest) // io.EOF from plain SQLite, which ignores the pragma
+		rows.Close()
+		if err == nil && len(dest) > 0 && dest[0] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// A raw 256-bit key, optionally followed by a 128-bit salt, as SQLCipher
+// takes it: x'<64 or 96 hex digits>'. Anything else is a passphrase.
+var sqliteRawKey = regexp.MustCompile(`^x'[0-9A-Fa-f]{64}([0-9A-Fa-f]{32})?'$`)
+
+func sqliteKeyLiteral(key string) string {
+	if sqliteRawKey.MatchString(key) {
+		return `"` + key + `"`
+	}
+	return "'" + strings.ReplaceAll(key, "'", "''") + "'"
+}
+
+// sqliteRekey re-encrypts the database with newKey on a connection of its
+// own. PRAGMA rekey rewrites every page under the write lock, so it waits
+// for the other connections' transactions, as any writer does.
+func sqliteRekey(ctx context.Context, conn *sql.DB, newKey string) error {
+	drv, ok := conn.Driver().(sqliteCipherDriver)
+	if !ok {
+		return ErrNotEncrypted
+	}
+	k := drv.cipher
+
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close() // Opened with the old key, so the pool closes it
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, err := c.ExecContext(ctx, "PRAGMA rekey = "+sqliteKeyLiteral(newKey)); err != nil {
+		return fmt.Errorf("failed to re-encrypt the database: %w", err)
+	}
+	var n int
+	if err := c.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master").Scan(&n); err != nil {
+		return fmt.Errorf("failed to read the database after re-encrypting it: %w", err)
+	}
+	k.key = newKey
+	k.gen.Add(1)
+	return nil
+}
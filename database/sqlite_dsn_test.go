@@ -0,0 +1,20 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"your-project/database"
+)
+
+func TestOpenErrorRedacted(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	_, err := database.Open(database.Config{DSN: "file:" + dir + "/app.db?_auth&_auth_user=admin&_auth_pass=s3cr%3Aet", LogLevel: "silent"})
+	checkRedacted(t, err, "s3cr")
+	if !strings.Contains(err.Error(), dir) {
+		t.Errorf("error %q doesn't name the database", err)
+	}
+}
@@ -0,0 +1,45 @@
+//go:build !postgres && sqlite_extensions
+
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// loadExtensions loads each library into c. mattn/go-sqlite3 enables
+// extension loading only for the duration of the call, so SQL can't load
+// anything itself. The error carries the dlopen message for a bad path.
+func loadExtensions(c *sqlite3.SQLiteConn, libs []string) error {
+	for _, lib := range libs {
+		// LoadExtension can't pass SQLite a NULL entry point, so look for
+		// the two names SQLite would: the generic one, then the one derived
+		// from the file name
+		err := c.LoadExtension(lib, "sqlite3_extension_init")
+		if err != nil && strings.Contains(err.Error(), "undefined symbol") {
+			err = c.LoadExtension(lib, extensionEntryPoint(lib))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load SQLite extension %s: %w", lib, err)
+		}
+	}
+	return nil
+}
+
+// extensionEntryPoint is sqlite3_X_init, X being the file name's letters
+// up to the first dot, lowercased and without a "lib" prefix
+func extensionEntryPoint(lib string) string {
+	name := strings.TrimPrefix(filepath.Base(lib), "lib")
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.Map(func(r rune) rune {
+		if !unicode.IsLetter(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+	return "sqlite3_" + name + "_init"
+}
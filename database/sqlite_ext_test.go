@@ -0,0 +1,58 @@
+//go:build !postgres && sqlite_extensions
+
+package database_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// buildExtension compiles testdata/hello_ext.c into a shared library
+func buildExtension(t *testing.T, file string, flags ...string) string {
+	t.Helper()
+	cc, err := exec.Command("go", "env", "CC").Output()
+	if err != nil {
+		t.Skipf("no C compiler: %v", err)
+	}
+	lib := filepath.Join(t.TempDir(), file)
+	args := append([]string{"-shared", "-fPIC", "-o", lib, filepath.Join("testdata", "hello_ext.c")}, flags...)
+	out, err := exec.Command(strings.TrimSpace(string(cc)), args...).CombinedOutput()
+	if err != nil {
+		t.Skipf("building the test extension (needs sqlite3ext.h): %v\n%s", err, out)
+	}
+	return lib
+}
+
+func TestSQLiteExtensions(t *testing.T) {
+	lib := buildExtension(t, "hello.so")
+	for name, path := range map[string]string{
+		"sqlite3_extension_init": lib,
+		"sqlite3_hello_init":     buildExtension(t, "libhello.so.1", "-DNAMED_ENTRY"),
+	} {
+		db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { c.SQLiteExtensions = []string{path} }})
+		var got string
+		if err := db.Conn.QueryRow("SELECT hello()").Scan(&got); err != nil || got != "hello from an extension" {
+			t.Errorf("the function of an extension entered by %s: %q, %v", name, got, err)
+		}
+	}
+
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { c.SQLiteExtensions = []string{lib} }})
+	// Loading is enabled only while Open loads the list
+	if _, err := db.Conn.Exec("SELECT load_extension(?)", lib); err == nil {
+		t.Error("SQL loaded an extension itself")
+	}
+
+	_, err := database.Open(database.Config{
+		DSN:              filepath.Join(t.TempDir(), "app.db"),
+		LogLevel:         "silent",
+		SQLiteExtensions: []string{filepath.Join(t.TempDir(), "missing.so")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "missing.so") {
+		t.Errorf("a wrong extension path: %v, want Open to fail with the loader's message", err)
+	}
+}
@@ -0,0 +1,88 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestForeignKeysOn(t *testing.T) {
+	db := dbtest.NewTestDB(t)
+	var on bool
+	if err := db.Conn.QueryRow("PRAGMA foreign_keys").Scan(&on); err != nil || !on {
+		t.Errorf("foreign_keys %v, %v; want on", on, err)
+	}
+}
+
+func TestForeignKeyCheck(t *testing.T) {
+	ctx := context.Background()
+	// As a legacy database written with foreign keys off
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { c.NoForeignKeys = true }})
+	exec := func(query string) {
+		t.Helper()
+		if _, err := db.DBTX().ExecContext(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func(query string) (n int) {
+		t.Helper()
+		if err := db.DBTX().QueryRowContext(ctx, query).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	newUsers(t, db, 1)
+	exec("INSERT INTO groups (telegram_id) VALUES (10)")
+	exec("INSERT INTO user_group (user_telegram_id, group_telegram_id) VALUES (1, 10), (2, 10), (1, 20)")
+	exec("INSERT INTO categories (id, parent_id, name) VALUES (1, NULL, 'root'), (2, 1, 'child'), (3, 99, 'lost')")
+
+	orphans, err := db.ForeignKeyCheck(ctx, database.ReportOrphans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, o := range orphans {
+		found = append(found, o.Table+"->"+o.Parent)
+	}
+	slices.Sort(found)
+	if want := []string{"categories->categories", "user_group->groups", "user_group->users"}; !slices.Equal(found, want) {
+		t.Errorf("orphans %v (%+v), want %v", found, orphans, want)
+	}
+	if n := count("SELECT count(*) FROM user_group"); n != 3 {
+		t.Errorf("%d memberships after only reporting, want all 3", n)
+	}
+
+	// NULL won't go into user_group's NOT NULL keys, so nothing changes
+	if _, err := db.ForeignKeyCheck(ctx, database.NullOrphans); err == nil {
+		t.Error("nulling NOT NULL keys: no error")
+	}
+	if n := count("SELECT count(*) FROM categories WHERE parent_id IS NULL"); n != 1 {
+		t.Errorf("%d root categories after the failed repair, want it rolled back to 1", n)
+	}
+
+	if _, err := db.ForeignKeyCheck(ctx, database.DeleteOrphans); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("SELECT count(*) FROM user_group"); n != 1 {
+		t.Errorf("%d memberships after deleting orphans, want 1", n)
+	}
+	if n := count("SELECT count(*) FROM categories"); n != 2 {
+		t.Errorf("%d categories after deleting orphans, want 2", n)
+	}
+	if orphans, err := db.ForeignKeyCheck(ctx, database.ReportOrphans); err != nil || len(orphans) != 0 {
+		t.Errorf("after the repair: %+v, %v; want no orphans", orphans, err)
+	}
+
+	exec("INSERT INTO categories (id, parent_id, name) VALUES (4, 99, 'lost again')")
+	if _, err := db.ForeignKeyCheck(ctx, database.NullOrphans); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("SELECT count(*) FROM categories WHERE id = 4 AND parent_id IS NULL"); n != 1 {
+		t.Error("the orphaned category kept its broken parent_id, want it NULL")
+	}
+}
@@ -0,0 +1,181 @@
+//go:build !postgres
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// SQLite parses "x REGEXP y" but leaves the function behind it to the
+// application, so every connection gets regexp(pattern, value) next to
+// Config.SQLiteFuncs, and the NOCASE_UNICODE collation the schema's
+// indexes use next to Config.Collations. mattn/go-sqlite3 installs both
+// per connection from a ConnectHook, which needs a driver of our own
+// instead of the one registered as "sqlite3".
+//
+// With modernc.org/sqlite, they are registered once for the whole process
+// instead, from an init func:
+//
+//	sqlite.MustRegisterDeterministicScalarFunction("regexp", 2,
+//		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+//			pattern, _ := args[0].(string)
+//			return sqliteRegexp(pattern, args[1])
+//		})
+//	sqlite.RegisterCollationUtf8("NOCASE_UNICODE", ...) // one collator behind a mutex
+func sqliteOpen(driverName, dsn string, cfg Config) (*sql.DB, error) {
+	if driverName != "sqlite3" {
+		if len(cfg.SQLiteExtensions) > 0 {
+			return nil, fmt.Errorf("SQLiteExtensions are unsupported by the %q driver, use mattn/go-sqlite3 (\"sqlite3\")", driverName)
+		}
+		if len(cfg.Attach) > 0 {
+			return nil, fmt.Errorf("Attach is unsupported by the %q driver, use mattn/go-sqlite3 (\"sqlite3\")", driverName)
+		}
+		return sql.Open(driverName, dsn)
+	}
+
+	cfg = sqliteReplicationDefaults(cfg) // The cipher sets the journal mode itself
+	var cipher *sqliteCipher
+	if cfg.EncryptionKey != "" {
+		cipher = &sqliteCipher{key: cfg.EncryptionKey}
+	}
+
+	drv := &sqlite3.SQLiteDriver{
+		ConnectHook: func(c *sqlite3.SQLiteConn) error {
+			if cipher != nil {
+				if err := cipher.unlock(c, cfg.JournalMode); err != nil {
+					return err
+				}
+			}
+			if err := c.RegisterFunc("regexp", sqliteRegexp, true); err != nil {
+				return fmt.Errorf("failed to register regexp: %w", err)
+			}
+			for _, f := range cfg.SQLiteFuncs {
+				if err := c.RegisterFunc(f.Name, f.Impl, f.Pure); err != nil {
+					return fmt.Errorf("failed to register SQLite function %q: %w", f.Name, err)
+				}
+			}
+
+			// A Collator isn't safe for concurrent use, and a connection
+			// only runs one statement at a time, so each gets its own
+			collator := collate.New(language.Und, collate.IgnoreCase)
+			if err := c.RegisterCollation("NOCASE_UNICODE", collator.CompareString); err != nil {
+				return fmt.Errorf("failed to register NOCASE_UNICODE: %w", err)
+			}
+			for name, cmp := range cfg.Collations {
+				if err := c.RegisterCollation(name, cmp); err != nil {
+					return fmt.Errorf("failed to register SQLite collation %q: %w", name, err)
+				}
+			}
+			if err := loadExtensions(c, cfg.SQLiteExtensions); err != nil {
+				return err
+			}
+			if cfg.Replication.ExternalCheckpoints {
+				if _, err := c.Exec("PRAGMA wal_autocheckpoint = 0", nil); err != nil {
+					return fmt.Errorf("failed to turn off automatic checkpoints: %w", err)
+				}
+			}
+			return attachDatabases(c, cfg.Attach, cipher != nil, cfg.ReadOnly)
+		},
+	}
+	if cipher != nil {
+		return sql.OpenDB(sqliteCipherConnector{sqliteCipherDriver{drv, cipher}, dsn}), nil
+	}
+	return sql.OpenDB(dsnConnector{drv, dsn}), nil
+}
+
+// dsnConnector opens connections of a driver that isn't registered under a name
+type dsnConnector struct {
+	drv driver.Driver
+	dsn string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+// Compiled patterns; a query runs regexp once per row with the same one
+const regexpCacheSize = 256
+
+var (
+	regexpMu    sync.Mutex
+	regexpCache = map[string]*regexp.Regexp{}
+)
+
+// sqliteRegexp backs "value REGEXP pattern" with Go's regexp syntax. An
+// invalid pattern fails the query; NULL never matches.
+func sqliteRegexp(pattern string, value any) (bool, error) {
+	regexpMu.Lock()
+	re, ok := regexpCache[pattern]
+	regexpMu.Unlock()
+
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return false, fmt.Errorf("invalid REGEXP pattern: %w", err)
+		}
+		regexpMu.Lock()
+		if len(regexpCache) >= regexpCacheSize {
+			clear(regexpCache)
+		}
+		regexpCache[pattern] = re
+		regexpMu.Unlock()
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case string:
+		return re.MatchString(v), nil
+	case []byte:
+		return re.Match(v), nil
+	default:
+		return re.MatchString(fmt.Sprint(v)), nil
+	}
+}
+
+// The collations whose order comes from tables that change between
+// releases of golang.org/x/text, with the version of those tables
+var sqliteCollationVersions = map[string]string{
+	"NOCASE_UNICODE": "cldr " + collate.CLDRVersion + ", unicode " + collate.UnicodeVersion,
+}
+
+// sqliteReindexCollations rebuilds the indexes using a collation whose
+// order changed since they were built; a stale index returns rows in the
+// old order and misses lookups. A database without a recorded version has
+// only just created those indexes.
+func sqliteReindexCollations(ctx context.Context, conn *sql.DB) error {
+	for name, version := range sqliteCollationVersions {
+		var built string
+		err := conn.QueryRowContext(ctx, "SELECT version FROM collation_versions WHERE name = ?", name).Scan(&built)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if built == version {
+			continue
+		}
+
+		if err == nil {
+			log.Printf("rebuilding indexes using %s, its order changed (%s, was %s)", name, version, built)
+			if _, err := conn.ExecContext(ctx, "REINDEX "+name); err != nil {
+				return fmt.Errorf("failed to reindex %s: %w", name, err)
+			}
+		}
+		_, err = conn.ExecContext(ctx,
+			"INSERT INTO collation_versions (name, version) VALUES (?, ?) ON CONFLICT (name) DO UPDATE SET version = excluded.version",
+			name, version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
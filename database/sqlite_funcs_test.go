@@ -0,0 +1,131 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+	"your-project/database/nulls"
+)
+
+func TestRegexp(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	for i, username := range []string{"ann_1", "bob", "ann_22", "carl_3", ""} {
+		_, err := db.CreateUser(ctx, database.CreateUserParams{TelegramID: int64(i + 1), FirstName: "user", Username: nulls.String(username)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	users, err := db.Q.ListUsersMatchingUsername(ctx, database.ListUsersMatchingUsernameParams{Pattern: `^ann_\d+$`, Limit: 10})
+	var got []string
+	for _, u := range users {
+		got = append(got, u.Username.V)
+	}
+	if err != nil || strings.Join(got, ",") != "ann_1,ann_22" {
+		t.Errorf("usernames matching ^ann_\\d+$: %v, %v; want ann_1,ann_22", got, err)
+	}
+	// The same pattern again comes from the cache
+	if users, err := db.Q.ListUsersMatchingUsername(ctx, database.ListUsersMatchingUsernameParams{Pattern: `_\d$`, Limit: 10}); err != nil || len(users) != 2 {
+		t.Errorf("usernames matching _\\d$: %d, %v; want 2 (ann_1, carl_3)", len(users), err)
+	}
+
+	_, err = db.Q.ListUsersMatchingUsername(ctx, database.ListUsersMatchingUsernameParams{Pattern: `(`, Limit: 10})
+	if err == nil || !strings.Contains(err.Error(), "invalid REGEXP pattern") {
+		t.Errorf("an invalid pattern: %v, want the query to fail", err)
+	}
+}
+
+func TestSQLiteFuncs(t *testing.T) {
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		c.SQLiteFuncs = []database.CustomFunc{
+			{Name: "double", Impl: func(n int64) int64 { return 2 * n }, Pure: true},
+			{Name: "shout", Impl: func(s string) string { return strings.ToUpper(s) + "!" }},
+		}
+	}})
+
+	var n int64
+	var s string
+	if err := db.Conn.QueryRow("SELECT double(21), shout('hi')").Scan(&n, &s); err != nil || n != 42 || s != "HI!" {
+		t.Errorf("the registered functions: %d, %q, %v; want 42, HI!", n, s, err)
+	}
+}
+
+func TestCollations(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		c.Collations = map[string]database.CollationFunc{"REVERSE": func(a, b string) int { return strings.Compare(b, a) }}
+	}})
+
+	// Byte order would put the capitals first and the accented letters last
+	for i, name := range []string{"Zoe", "åse", "eve", "Ångström", "Bob", "zed", "Émile", "anna"} {
+		if _, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: int64(i + 1), FirstName: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	users, err := db.Q.ListUsersByFirstName(ctx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, u := range users {
+		got = append(got, u.FirstName)
+	}
+	if want := "Ångström anna åse Bob Émile eve zed Zoe"; strings.Join(got, " ") != want {
+		t.Errorf("ordered by first name: %s, want %s", strings.Join(got, " "), want)
+	}
+	plan, err := db.Explain(ctx, "ListUsersByFirstName", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbtest.AssertUsesIndex(t, plan, "idx_users_first_name")
+
+	rows, err := db.Conn.Query("SELECT column1 FROM (VALUES ('a'), ('c'), ('b')) ORDER BY column1 COLLATE REVERSE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var reversed string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		reversed += s
+	}
+	if reversed != "cba" {
+		t.Errorf("ordered by a Config.Collations collation: %q, want cba", reversed)
+	}
+}
+
+func TestCollationReindex(t *testing.T) {
+	cfg := database.Config{DSN: filepath.Join(t.TempDir(), "app.db"), LogLevel: "silent"}
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var current string
+	if err := db.Conn.QueryRow("SELECT version FROM collation_versions WHERE name = 'NOCASE_UNICODE'").Scan(&current); err != nil || current == "" {
+		t.Fatalf("the recorded collation version: %q, %v", current, err)
+	}
+	// As if the indexes were built by an older golang.org/x/text
+	if _, err := db.Conn.Exec("UPDATE collation_versions SET version = 'cldr 1.0'"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if db, err = database.Open(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version string
+	if err := db.Conn.QueryRow("SELECT version FROM collation_versions WHERE name = 'NOCASE_UNICODE'").Scan(&version); err != nil || version != current {
+		t.Errorf("after reopening: version %q, %v; want the indexes rebuilt for %q", version, err, current)
+	}
+}
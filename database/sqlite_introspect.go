@@ -0,0 +1,182 @@
+//go:build !postgres
+
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+)
+
+// sqliteIntrospect reads sqlite_master and the table-valued PRAGMA
+// functions. Every result is read completely before the next query, which
+// matters on a pool of one connection.
+func sqliteIntrospect(ctx context.Context, dbtx DBTX) (SchemaInfo, error) {
+	return sqliteIntrospectSchema(ctx, dbtx, "main")
+}
+
+// sqliteIntrospectSchema is sqliteIntrospect for the main database or an
+// attached one; schema is a plain identifier, checked by the caller
+func sqliteIntrospectSchema(ctx context.Context, dbtx DBTX, schema string) (SchemaInfo, error) {
+	rows, err := dbtx.QueryContext(ctx, `SELECT name, type FROM `+schema+`.sqlite_master
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY name`)
+	if err != nil {
+		return SchemaInfo{}, err
+	}
+	var info SchemaInfo
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			rows.Close()
+			return SchemaInfo{}, err
+		}
+		info.Tables = append(info.Tables, TableInfo{Name: name, View: typ == "view"})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return SchemaInfo{}, err
+	}
+
+	for i := range info.Tables {
+		t := &info.Tables[i]
+		if t.Columns, err = sqliteColumns(ctx, dbtx, schema, t.Name); err != nil {
+			return SchemaInfo{}, err
+		}
+		if t.Indexes, err = sqliteIndexes(ctx, dbtx, schema, t.Name); err != nil {
+			return SchemaInfo{}, err
+		}
+		if t.ForeignKeys, err = sqliteForeignKeyList(ctx, dbtx, schema, t.Name); err != nil {
+			return SchemaInfo{}, err
+		}
+	}
+
+	// REFERENCES parent without columns means the parent's primary key
+	for _, t := range info.Tables {
+		for j, fk := range t.ForeignKeys {
+			if !slices.Contains(fk.RefColumns, "") {
+				continue
+			}
+			if parent, ok := info.Table(fk.RefTable); ok {
+				t.ForeignKeys[j].RefColumns = parent.PrimaryKey()
+			}
+		}
+	}
+	return info, nil
+}
+
+// sqliteExpectedSchema creates the embedded schema in a scratch database
+// in memory, opened like the real one so the schema finds the same
+// functions, collations and extensions, and introspects it
+func sqliteExpectedSchema(ctx context.Context, _ *sql.DB, cfg Config) (SchemaInfo, error) {
+	d := defaultDialect()
+	cfg.Attach = nil // Only main is compared
+	scratch, err := sqliteOpen(cmp.Or(cfg.Driver, d.drivers[0]), ":memory:", cfg)
+	if err != nil {
+		return SchemaInfo{}, err
+	}
+	defer scratch.Close()
+	scratch.SetMaxOpenConns(1) // Every connection to :memory: is a database of its own
+
+	if err := migrate(ctx, d, scratch); err != nil {
+		return SchemaInfo{}, err
+	}
+	return sqliteIntrospect(ctx, scratch)
+}
+
+func sqliteColumns(ctx context.Context, dbtx DBTX, schema, table string) ([]ColumnInfo, error) {
+	// table_xinfo, unlike table_info, has generated columns (hidden 2 or
+	// 3); hidden 1 is a virtual table's hidden column, which stays out
+	rows, err := dbtx.QueryContext(ctx, `SELECT name, type, "notnull", dflt_value, pk, hidden IN (2, 3)
+		FROM pragma_table_xinfo(?, ?) WHERE hidden <> 1 ORDER BY cid`, table, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []ColumnInfo
+	for rows.Next() {
+		var c ColumnInfo
+		var notNull bool
+		var def sql.NullString
+		if err := rows.Scan(&c.Name, &c.Type, &notNull, &def, &c.PrimaryKey, &c.Generated); err != nil {
+			return nil, err
+		}
+		// PostgreSQL's rule: SQLite only keeps NULL out of an INTEGER
+		// PRIMARY KEY unless the column says NOT NULL
+		c.Nullable = !notNull && c.PrimaryKey == 0
+		if def.Valid {
+			c.Default = &def.String
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+func sqliteIndexes(ctx context.Context, dbtx DBTX, schema, table string) ([]IndexInfo, error) {
+	rows, err := dbtx.QueryContext(ctx, `SELECT name, "unique", origin = 'pk' FROM pragma_index_list(?, ?) ORDER BY name`, table, schema)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []IndexInfo
+	for rows.Next() {
+		var ix IndexInfo
+		if err := rows.Scan(&ix.Name, &ix.Unique, &ix.Primary); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		indexes = append(indexes, ix)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range indexes {
+		// name is NULL for an expression
+		rows, err := dbtx.QueryContext(ctx, "SELECT coalesce(name, '') FROM pragma_index_info(?, ?) ORDER BY seqno", indexes[i].Name, schema)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var col string
+			if err := rows.Scan(&col); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			indexes[i].Columns = append(indexes[i].Columns, col)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return indexes, nil
+}
+
+func sqliteForeignKeyList(ctx context.Context, dbtx DBTX, schema, table string) ([]ForeignKeyInfo, error) {
+	rows, err := dbtx.QueryContext(ctx, `SELECT id, "table", "from", coalesce("to", ''), on_update, on_delete
+		FROM pragma_foreign_key_list(?, ?) ORDER BY id, seq`, table, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fks []ForeignKeyInfo
+	last := -1
+	for rows.Next() {
+		var id int
+		var parent, from, to, onUpdate, onDelete string
+		if err := rows.Scan(&id, &parent, &from, &to, &onUpdate, &onDelete); err != nil {
+			return nil, err
+		}
+		if id != last {
+			fks = append(fks, ForeignKeyInfo{RefTable: parent, OnDelete: onDelete, OnUpdate: onUpdate})
+			last = id
+		}
+		fk := &fks[len(fks)-1]
+		fk.Columns = append(fk.Columns, from)
+		fk.RefColumns = append(fk.RefColumns, to)
+	}
+	return fks, rows.Err()
+}
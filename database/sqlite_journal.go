@@ -0,0 +1,184 @@
+//go:build !postgres
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func sqliteJournalMode(ctx context.Context, conn *sql.DB) (string, error) {
+	var mode string
+	if err := conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode); err != nil {
+		return "", fmt.Errorf("failed to read SQLite journal mode: %w", err)
+	}
+	return strings.ToLower(mode), nil
+}
+
+// sqliteCheckpoint empties the WAL, so the database file alone is
+// complete; outside WAL mode the pragma does nothing
+func sqliteCheckpoint(ctx context.Context, conn *sql.DB) error {
+	r, err := sqliteWALCheckpoint(ctx, conn, CheckpointTruncate)
+	if err != nil {
+		return err
+	}
+	if r.Busy {
+		return fmt.Errorf("WAL checkpoint was blocked by another connection, %d of %d frames copied", r.Copied, r.Frames)
+	}
+	return nil
+}
+
+// sqliteWALCheckpoint is the checkpoint of DB.Checkpoint and Maintain:
+// unlike sqliteCheckpoint, readers holding it up aren't an error, the
+// frames they still need are copied next time
+func sqliteWALCheckpoint(ctx context.Context, conn *sql.DB, mode string) (CheckpointResult, error) {
+	var busy int
+	r := CheckpointResult{Mode: mode}
+	if err := conn.QueryRowContext(ctx, "PRAGMA wal_checkpoint("+strings.ToUpper(mode)+")").Scan(&busy, &r.Frames, &r.Copied); err != nil {
+		return CheckpointResult{}, fmt.Errorf("failed to checkpoint the WAL: %w", err)
+	}
+	r.Busy = busy != 0
+	return r, nil
+}
+
+// sqliteDatabaseSize reads the file's size from its pages, so it's right
+// inside a transaction too, and the WAL's from the file system
+func sqliteDatabaseSize(ctx context.Context, conn *sql.DB) (DatabaseSize, error) {
+	var pageSize, pages, free int64
+	var file string
+	if err := conn.QueryRowContext(ctx, `SELECT s.page_size, c.page_count, f.freelist_count, l.file
+		FROM pragma_page_size() s, pragma_page_count() c, pragma_freelist_count() f, pragma_database_list() l
+		WHERE l.name = 'main'`).Scan(&pageSize, &pages, &free, &file); err != nil {
+		return DatabaseSize{}, fmt.Errorf("failed to read the database size: %w", err)
+	}
+	size := DatabaseSize{Bytes: pages * pageSize, FreeBytes: free * pageSize}
+	if file == "" { // In memory
+		return size, nil
+	}
+	wal, err := os.Stat(file + "-wal")
+	switch {
+	case err == nil:
+		size.WALBytes = wal.Size()
+	case !errors.Is(err, fs.ErrNotExist):
+		return DatabaseSize{}, fmt.Errorf("failed to read the WAL size: %w", err)
+	}
+	return size, nil
+}
+
+// sqliteAnalyze refreshes the statistics in sqlite_stat1, which the query
+// planner and ApproxCount read
+func sqliteAnalyze(ctx context.Context, conn *sql.DB) (string, error) {
+	if _, err := conn.ExecContext(ctx, "ANALYZE"); err != nil {
+		return "", fmt.Errorf("failed to analyze: %w", err)
+	}
+	return "", nil
+}
+
+// sqliteIncrementalVacuum hands up to pages free pages (0 = all) back to
+// the file system. It only works on a database with auto_vacuum =
+// INCREMENTAL, which has to be set before the first table is made, or
+// followed by a full VACUUM.
+func sqliteIncrementalVacuum(ctx context.Context, conn *sql.DB, pages int) (string, error) {
+	var mode, free int
+	if err := conn.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return "", fmt.Errorf("failed to read auto_vacuum: %w", err)
+	}
+	if mode != 2 {
+		return "skipped, auto_vacuum isn't INCREMENTAL", nil
+	}
+	if err := conn.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&free); err != nil {
+		return "", fmt.Errorf("failed to read the free page count: %w", err)
+	}
+	if free == 0 {
+		return "no free pages", nil
+	}
+
+	// The pragma frees a page per step, so it's read to the end rather
+	// than run with Exec, which may step only once
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("PRAGMA incremental_vacuum(%d)", max(pages, 0)))
+	if err != nil {
+		return "", fmt.Errorf("failed to vacuum: %w", err)
+	}
+	for rows.Next() {
+	}
+	if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+		return "", fmt.Errorf("failed to vacuum: %w", err)
+	}
+
+	var left int
+	if err := conn.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&left); err != nil {
+		return "", fmt.Errorf("failed to read the free page count: %w", err)
+	}
+	return fmt.Sprintf("%d of %d free pages released", free-left, free), nil
+}
+
+func sqliteSetJournalMode(ctx context.Context, conn *sql.DB, dsn, mode string) error {
+	mode = strings.ToLower(mode)
+	if mode != "wal" && mode != "delete" {
+		return fmt.Errorf("journal mode %q can't be switched for the whole database, set it in the DSN (_journal_mode=%s)", mode, mode)
+	}
+	if mode == "wal" {
+		if err := sqliteCanWAL(ctx, conn, dsn); err != nil {
+			return err
+		}
+	}
+
+	for wait := 10 * time.Millisecond; ; wait = min(2*wait, time.Second) {
+		var got string
+		err := conn.QueryRowContext(ctx, "PRAGMA journal_mode = "+mode).Scan(&got)
+		if err == nil {
+			// SQLite answers with the mode in effect, the old one if it
+			// couldn't switch
+			if got = strings.ToLower(got); got != mode {
+				return fmt.Errorf("journal mode is still %s after switching to %s", got, mode)
+			}
+			return nil
+		}
+		if !sqliteBusy(err) {
+			return fmt.Errorf("failed to set journal mode %s: %w", mode, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to set journal mode %s, the database stayed busy: %w", mode, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+// sqliteCanWAL refuses databases WAL would break or silently not apply to
+func sqliteCanWAL(ctx context.Context, conn *sql.DB, dsn string) error {
+	params := strings.ToLower(dsn)
+	if _, p, ok := strings.Cut(params, "?"); ok && (strings.Contains(p, "mode=ro") || strings.Contains(p, "immutable=1")) {
+		return errors.New("WAL needs a writable database, the DSN opens it read-only")
+	}
+
+	var file string
+	if err := conn.QueryRowContext(ctx, "SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file); err != nil {
+		return fmt.Errorf("failed to find the database file: %w", err)
+	}
+	if file == "" {
+		return errors.New("WAL needs a database file, this one is in memory")
+	}
+	if fs, ok := networkFilesystem(file); ok {
+		return fmt.Errorf("WAL doesn't work over a network filesystem (%s holds %s)", fs, file)
+	}
+	return nil
+}
+
+func sqliteBusy(err error) bool {
+	var se sqlite3.Error
+	if errors.As(err, &se) {
+		return se.Code == sqlite3.ErrBusy || se.Code == sqlite3.ErrLocked
+	}
+	// modernc.org/sqlite reports the same message as SQLite itself
+	return strings.Contains(err.Error(), "database is locked")
+}
@@ -0,0 +1,131 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// sidecars reports whether path's -wal and -shm files exist
+func sidecars(path string) (wal, shm bool) {
+	_, err := os.Stat(path + "-wal")
+	wal = err == nil
+	_, err = os.Stat(path + "-shm")
+	return wal, err == nil
+}
+
+func TestSetJournalMode(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		c.JournalMode, c.MaxOpenConns = "delete", 2
+	}})
+	var path string
+	if err := db.Conn.QueryRow("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&path); err != nil {
+		t.Fatal(err)
+	}
+	newUsers(t, db, 1, 2)
+	users := func(when string) {
+		t.Helper()
+		if n, err := db.Q.CountUsersByStatus(ctx, database.StatusActive); err != nil || n != 2 {
+			t.Errorf("users %s: %d, %v; want 2", when, n, err)
+		}
+	}
+
+	if mode, err := db.JournalMode(ctx); err != nil || mode != "delete" {
+		t.Fatalf("JournalMode: %q, %v; want delete", mode, err)
+	}
+	if err := db.SetJournalMode(ctx, "WAL"); err != nil {
+		t.Fatal(err)
+	}
+	if mode, _ := db.JournalMode(ctx); mode != "wal" {
+		t.Errorf("JournalMode after switching to WAL: %q", mode)
+	}
+	newUsers(t, db, 3)
+	if _, err := db.Conn.Exec("DELETE FROM users WHERE telegram_id = 3"); err != nil {
+		t.Fatal(err)
+	}
+	if wal, shm := sidecars(path); !wal || !shm {
+		t.Errorf("in WAL mode: -wal %v, -shm %v; want both", wal, shm)
+	}
+	users("in WAL mode")
+
+	// A reader holds the switch back until it's done
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := tx.QueryRow("SELECT count(*) FROM users").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(200*time.Millisecond, func() { tx.Rollback() })
+	start := time.Now()
+	if err := db.SetJournalMode(ctx, "delete"); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 150*time.Millisecond {
+		t.Errorf("switched back after %v, while a read transaction was open", waited)
+	}
+	if wal, shm := sidecars(path); wal || shm {
+		t.Errorf("back in DELETE mode: -wal %v, -shm %v; want neither", wal, shm)
+	}
+	users("back in DELETE mode")
+
+	for _, mode := range []string{"truncate", "bogus"} {
+		if err := db.SetJournalMode(ctx, mode); err == nil {
+			t.Errorf("SetJournalMode(%q) succeeded", mode)
+		}
+	}
+	if mode, _ := db.JournalMode(ctx); mode != "delete" {
+		t.Errorf("JournalMode after the refused switches: %q", mode)
+	}
+}
+
+func TestSetJournalModeRefusesWAL(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "app.db")
+	rw, err := database.Open(database.Config{DSN: path, JournalMode: "delete", LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw.Close()
+
+	for what, cfg := range map[string]database.Config{
+		"a read-only DSN": {DSN: "file:" + path + "?mode=ro", SkipMigrations: true, LogLevel: "silent"},
+		"memory":          {DSN: ":memory:", LogLevel: "silent"},
+	} {
+		db, err := database.Open(cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+		if err := db.SetJournalMode(ctx, "wal"); err == nil {
+			t.Errorf("WAL on %s: no error", what)
+		}
+		db.Close()
+	}
+}
+
+func TestOpenLogsJournalMode(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	db, err := database.Open(database.Config{DSN: filepath.Join(t.TempDir(), "app.db"), JournalMode: "delete", LogLevel: "info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if !strings.Contains(buf.String(), "journal mode: delete") {
+		t.Errorf("Open logged %q, want the journal mode", buf.String())
+	}
+}
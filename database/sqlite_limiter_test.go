@@ -0,0 +1,67 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// TestWriteLimiterStress has 100 goroutines creating users, which are
+// INSERT ... RETURNING queries, through one write slot and a busy timeout
+// too short to wait out another writer: any write that runs outside its
+// slot fails with "database is locked".
+func TestWriteLimiterStress(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		c.MaxConcurrentWrites = 1
+		c.BusyTimeout = time.Millisecond
+		c.MaxOpenConns = 8
+	}})
+
+	const workers, writes = 100, 20
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		errs      []error
+		wg        sync.WaitGroup
+	)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range writes {
+				start := time.Now()
+				_, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: int64(w*writes + i + 1), FirstName: "user"})
+				mu.Lock()
+				latencies = append(latencies, time.Since(start))
+				if err != nil {
+					errs = append(errs, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		t.Fatalf("%d of %d writes failed, first: %v", len(errs), workers*writes, errs[0])
+	}
+	slices.Sort(latencies)
+	if p99 := latencies[len(latencies)*99/100]; p99 > 2*time.Second {
+		t.Errorf("p99 write latency %v, want it bounded by the queue (under 2s)", p99)
+	}
+	if n := db.WriteWaiters(); n != 0 {
+		t.Errorf("%d writers still waiting, want 0", n)
+	}
+	n, err := db.Q.CountUsersByStatus(ctx, database.StatusActive)
+	if err != nil || n != workers*writes {
+		t.Errorf("%d users, %v; want %d", n, err, workers*writes)
+	}
+}
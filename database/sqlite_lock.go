@@ -0,0 +1,82 @@
+//go:build !postgres
+
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+// sqliteLock holds a row of the locks table on a lease, renewed every
+// lockCheck. Another process can take a lease nobody renewed for
+// lockLease, so lost is called once renewing has failed for that long,
+// or at once if the row now belongs to someone else.
+func sqliteLock(ctx context.Context, conn *sql.DB, name string, try bool, lost func()) (func() error, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	holder := hex.EncodeToString(b)
+
+	for {
+		now := time.Now()
+		res, err := conn.ExecContext(ctx, `INSERT INTO locks (name, holder, expires_at) VALUES (?, ?, ?)
+ON CONFLICT (name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+WHERE locks.expires_at <= ?`, name, holder, now.Add(lockLease).UnixMilli(), now.UnixMilli())
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n > 0 {
+			break
+		}
+		if try {
+			return nil, ErrLockHeld
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPoll):
+		}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(lockCheck)
+		defer ticker.Stop()
+		renewed := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			now := time.Now()
+			res, err := conn.ExecContext(context.Background(), "UPDATE locks SET expires_at = ? WHERE name = ? AND holder = ?",
+				now.Add(lockLease).UnixMilli(), name, holder)
+			if err == nil {
+				if n, _ := res.RowsAffected(); n == 0 {
+					lost()
+					return
+				}
+				renewed = now
+			} else if now.Sub(renewed) >= lockLease {
+				lost()
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		close(done)
+		<-stopped
+		_, err := conn.ExecContext(context.Background(), "DELETE FROM locks WHERE name = ? AND holder = ?", name, holder)
+		return err
+	}, nil
+}
@@ -0,0 +1,32 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"your-project/database"
+)
+
+// baselineDB creates a database with testdata/baseline/sqlite.sql, a copy
+// of the first schema.sql, and rows, and returns the config to open it
+func baselineDB(t *testing.T, rows string) database.Config {
+	t.Helper()
+	schema, err := os.ReadFile("testdata/baseline/sqlite.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "old.db")
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(string(schema) + rows); err != nil {
+		t.Fatal(err)
+	}
+	return database.Config{DSN: path, LogLevel: "silent"}
+}
@@ -0,0 +1,28 @@
+//go:build !postgres && linux
+
+package database
+
+import (
+	"path/filepath"
+	"syscall"
+)
+
+// Filesystem magic numbers from statfs(2)
+var networkFilesystems = map[uint32]string{
+	0x6969:     "NFS",
+	0x517b:     "SMB",
+	0xff534d42: "CIFS",
+	0xfe534d42: "SMB2",
+	0x01021997: "9P",
+}
+
+// networkFilesystem reports whether path lives on a filesystem whose
+// locking and shared memory span machines unreliably
+func networkFilesystem(path string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(filepath.Dir(path), &st); err != nil {
+		return "", false
+	}
+	name, ok := networkFilesystems[uint32(st.Type)] // int32 on some platforms
+	return name, ok
+}
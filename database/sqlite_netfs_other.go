@@ -0,0 +1,8 @@
+//go:build !postgres && !linux
+
+package database
+
+// networkFilesystem can't tell outside Linux, so it trusts the path
+func networkFilesystem(path string) (string, bool) {
+	return "", false
+}
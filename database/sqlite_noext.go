@@ -0,0 +1,18 @@
+//go:build !postgres && !sqlite_extensions
+
+package database
+
+import (
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Extension loading is opt-in at build time, since it lets the process
+// run native code named in its config
+func loadExtensions(_ *sqlite3.SQLiteConn, libs []string) error {
+	if len(libs) > 0 {
+		return fmt.Errorf("SQLiteExtensions needs a build with -tags sqlite_extensions")
+	}
+	return nil
+}
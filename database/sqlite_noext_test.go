@@ -0,0 +1,22 @@
+//go:build !postgres && !sqlite_extensions
+
+package database_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"your-project/database"
+)
+
+func TestSQLiteExtensionsNeedTag(t *testing.T) {
+	_, err := database.Open(database.Config{
+		DSN:              filepath.Join(t.TempDir(), "app.db"),
+		LogLevel:         "silent",
+		SQLiteExtensions: []string{"hello.so"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "-tags sqlite_extensions") {
+		t.Errorf("SQLiteExtensions without the tag: %v, want Open to name the tag", err)
+	}
+}
@@ -0,0 +1,61 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestRawQueryPragmas(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	for _, query := range []string{
+		"PRAGMA table_info(users)",
+		"pragma main.index_list('users');",
+		"PRAGMA integrity_check",
+		"PRAGMA journal_mode",
+		"/* settings */ PRAGMA foreign_keys",
+	} {
+		if _, err := db.RawQuery(ctx, query); err != nil {
+			t.Errorf("%s: %v", query, err)
+		}
+	}
+	for _, query := range []string{
+		"PRAGMA foreign_keys=OFF",
+		"PRAGMA foreign_keys = 0",
+		"PRAGMA journal_mode(delete)",
+		"PRAGMA query_only=0",
+		"PRAGMA writable_schema",
+		"PRAGMA optimize",
+		"PRAGMA wal_checkpoint(TRUNCATE)",
+	} {
+		if _, err := db.RawQuery(ctx, query); !errors.Is(err, database.ErrNotReadOnly) {
+			t.Errorf("%s: got %v, want ErrNotReadOnly", query, err)
+		}
+	}
+}
+
+// TestRawQuerySessionNotReused checks a PRAGMA run with AllowWrites
+// doesn't stay on a connection the pool hands out again
+func TestRawQuerySessionNotReused(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	db.Conn.SetMaxOpenConns(1)
+
+	if _, err := db.RawQueryWith(ctx, database.RawQueryOptions{AllowWrites: true}, "PRAGMA foreign_keys = OFF"); err != nil {
+		t.Fatal(err)
+	}
+	var on bool
+	if err := db.Conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&on); err != nil {
+		t.Fatal(err)
+	}
+	if !on {
+		t.Error("foreign keys are still off on the pooled connection")
+	}
+}
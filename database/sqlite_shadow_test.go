@@ -0,0 +1,148 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+	"your-project/database/nulls"
+)
+
+// waitMirrored waits for the shadow to have replayed n writes and
+// transactions in all
+func waitMirrored(t *testing.T, db *database.DB, n int64) database.ShadowReport {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r := db.ShadowReport()
+		if r.Mirrored >= n {
+			if r.Mirrored > n {
+				t.Errorf("%d writes mirrored, want %d", r.Mirrored, n)
+			}
+			return r
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d writes mirrored after 5s", r.Mirrored, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestShadowDivergences(t *testing.T) {
+	ctx := context.Background()
+	shadowDSN := filepath.Join(t.TempDir(), "shadow.db")
+
+	// The shadow starts out with rows the primary hasn't got
+	seed, err := database.Open(database.Config{DSN: shadowDSN, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seed.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: 1, FirstName: "shadow", Username: nulls.String("seeded")}); err != nil {
+		t.Fatal(err)
+	}
+	seed.Close()
+
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { c.ShadowDSN = shadowDSN }})
+	if r := db.ShadowReport(); !r.Enabled || r.Err != nil {
+		t.Fatalf("ShadowReport: %+v, want the shadow enabled", r)
+	}
+
+	// Taken by the primary; the shadow already has user 1
+	rctx := database.ContextWithRequestID(ctx, "req-1")
+	if _, err := db.Q.CreateUser(rctx, database.CreateUserParams{TelegramID: 1, FirstName: "user"}); err != nil {
+		t.Fatalf("the primary failed on the shadow's account: %v", err)
+	}
+	r := waitMirrored(t, db, 1)
+	if len(r.Divergences) != 1 {
+		t.Fatalf("divergences %+v, want the shadow's duplicate", r.Divergences)
+	}
+	div := r.Divergences[0]
+	if div.Query != "CreateUser" || div.Kind != "error" || !strings.Contains(div.Detail, "UNIQUE") || div.RequestID != "req-1" {
+		t.Errorf("divergence %+v, want CreateUser's unique error for req-1", div)
+	}
+
+	// The same row, different results: the shadow's has a username
+	if _, err := db.Q.UpdateUserBalanceChats(ctx, database.UpdateUserBalanceChatsParams{BalanceChats: nulls.Of[database.Money](100), TelegramID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	r = waitMirrored(t, db, 2)
+	if div := r.Divergences[len(r.Divergences)-1]; div.Kind != "result" || !strings.Contains(div.Detail, "Username") || strings.Contains(div.Detail, "seeded") {
+		t.Errorf("divergence %+v, want a different Username, without its value", div)
+	}
+
+	// One that agrees
+	newUsers(t, db, 2)
+	if r = waitMirrored(t, db, 3); r.Diverged != 2 {
+		t.Errorf("%d divergences after a write both took alike, want 2", r.Diverged)
+	}
+
+	// A committed transaction is replayed; only the shadow's user 1 has a
+	// username
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		if _, err := tx.UpdateUser(ctx, database.UpdateUserParams{FirstName: "renamed", TelegramID: 2}); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "UPDATE users SET first_name = 'x' WHERE username = 'seeded'")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = waitMirrored(t, db, 4)
+	if div := r.Divergences[len(r.Divergences)-1]; div.Query != "other" || div.Kind != "rows" || !strings.Contains(div.Detail, "statement 2 of 2: 0 rows affected, 1 on the shadow") {
+		t.Errorf("divergence %+v, want the transaction's second statement", div)
+	}
+
+	// A rolled back one isn't
+	err = db.InTx(ctx, func(tx *database.Tx) error {
+		if _, err := tx.CreateUser(ctx, database.CreateUserParams{TelegramID: 3, FirstName: "user"}); err != nil {
+			return err
+		}
+		return errors.New("roll back")
+	})
+	if err == nil {
+		t.Fatal("the transaction didn't fail")
+	}
+	newUsers(t, db, 4) // Replayed after anything queued before it
+	if r = waitMirrored(t, db, 5); r.Diverged != 3 || r.Dropped != 0 || r.Pending != 0 {
+		t.Errorf("report %+v, want 3 divergences and nothing dropped or pending", r)
+	}
+
+	// Reads come from the primary
+	u, err := db.Q.GetUserByTelegramID(ctx, 1)
+	if err != nil || u.FirstName != "user" || u.Username.Valid {
+		t.Errorf("read user 1 as %+v, %v; want the primary's", u, err)
+	}
+}
+
+func TestShadowUnavailable(t *testing.T) {
+	ctx := context.Background()
+	missing := filepath.Join(t.TempDir(), "no", "such", "dir", "shadow.db")
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		c.ShadowDSN = missing + "?mode=rw" // Not created
+	}})
+
+	r := db.ShadowReport()
+	if r.Enabled || r.Err == nil {
+		t.Errorf("ShadowReport: %+v, want it disabled with the reason", r)
+	}
+	newUsers(t, db, 1)
+	if _, err := db.Q.GetUserByTelegramID(ctx, 1); err != nil {
+		t.Errorf("the primary without its shadow: %v", err)
+	}
+}
+
+func TestNoShadow(t *testing.T) {
+	db := dbtest.NewTestDB(t)
+	newUsers(t, db, 1)
+	if r := db.ShadowReport(); r.Enabled || r.Err != nil || r.Mirrored != 0 {
+		t.Errorf("ShadowReport without ShadowDSN: %+v, want the zero report", r)
+	}
+}
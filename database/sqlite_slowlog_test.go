@@ -0,0 +1,71 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+// countTo is a query SQLite does all its work for while its row is read,
+// longer the bigger n
+const countTo = `WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < ?)
+SELECT count(*) FROM c`
+
+func TestSlowQueries(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		c.SlowQueries = 3
+		c.QueryLatency = true
+	}})
+	dbtx := db.DBTX()
+
+	// Fast and slow statements interleaved; the slow ones take long
+	// enough apart to come back in order
+	sizes := []int64{1, 300_000, 1, 30_000, 1, 3_000_000, 1}
+	for _, n := range sizes {
+		var got int64
+		if err := dbtx.QueryRowContext(ctx, countTo, n).Scan(&got); err != nil || got != n {
+			t.Fatalf("counting to %d: %d, %v", n, got, err)
+		}
+	}
+	rows, err := dbtx.QueryContext(ctx, countTo, 100_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+
+	slow := db.SlowQueries()
+	if len(slow) != 3 {
+		t.Fatalf("%d slow queries, want 3: %+v", len(slow), slow)
+	}
+	for i := 1; i < len(slow); i++ {
+		if slow[i].Duration > slow[i-1].Duration {
+			t.Errorf("slow query %d took %v, longer than the one before it (%v)", i, slow[i].Duration, slow[i-1].Duration)
+		}
+	}
+	// Counting to 3,000,000 takes a while on any machine; timing only the
+	// call, as ExecContext would, it's microseconds
+	if slow[0].Duration < 10*time.Millisecond || slow[0].Rows != 1 {
+		t.Errorf("slowest: %v for %d rows, want it timed while its row was read", slow[0].Duration, slow[0].Rows)
+	}
+
+	// A failure reading the row counts as one
+	var v string
+	if err := dbtx.QueryRowContext(ctx, "SELECT json_extract('not json', '$')").Scan(&v); err == nil {
+		t.Fatal("malformed JSON read without an error")
+	}
+	var errors uint64
+	for _, h := range db.LatencyHistograms(nil) {
+		errors += h.Errors
+	}
+	if errors != 1 {
+		t.Errorf("%d failed queries in the latency stats, want 1", errors)
+	}
+}
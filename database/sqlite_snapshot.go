@@ -0,0 +1,58 @@
+//go:build !postgres
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// sqliteSnapshot writes the snapshot with VACUUM INTO next to where it
+// goes and renames it there, so a clone never copies half of one
+func sqliteSnapshot(ctx context.Context, conn *sql.DB, _, name string, _ func()) error {
+	tmp := name + ".tmp"
+	os.Remove(tmp) // VACUUM INTO won't write over a file
+	defer os.Remove(tmp)
+
+	if err := sqliteBackup(ctx, conn, tmp); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0o600); err != nil { // it holds everything the database does
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// sqliteClone copies the snapshot file to the database file, which
+// appears once it's complete
+func sqliteClone(_ context.Context, name string, cfg Config) error {
+	path, err := databaseFile(cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	tmp := path + ".clone"
+	defer os.Remove(tmp)
+	if err := copyBackup(name, tmp); err != nil {
+		return err
+	}
+	// A WAL left by an earlier database of that name would be replayed into the clone
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmp, path)
+}
+
+func sqliteDropSnapshot(_ context.Context, _, name string) error {
+	return os.Remove(name)
+}
@@ -0,0 +1,57 @@
+//go:build !postgres
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) {
+		c.QueryTimeout = 20 * time.Millisecond
+	}})
+	dbtx := db.DBTX()
+	const long = 3_000_000 // countTo this takes well over 20ms
+
+	var n int64
+	if err := dbtx.QueryRowContext(ctx, countTo, 10).Scan(&n); err != nil {
+		t.Fatalf("a fast query: %v", err)
+	}
+
+	// SQLite runs the statement as the row is read, so that's where the
+	// timeout shows
+	err := dbtx.QueryRowContext(ctx, countTo, long).Scan(&n)
+	var te *database.QueryTimeoutError
+	if !errors.As(err, &te) || te.Timeout != 20*time.Millisecond {
+		t.Errorf("QueryRow past the timeout: %v, want a QueryTimeoutError", err)
+	}
+
+	rows, err := dbtx.QueryContext(ctx, countTo, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); !errors.Is(err, database.ErrQueryTimeout) {
+		t.Errorf("reading rows past the timeout: %v, want ErrQueryTimeout", err)
+	}
+	rows.Close()
+
+	// The caller's own deadline isn't the query timeout
+	cctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := dbtx.QueryRowContext(cctx, countTo, long).Scan(&n); err == nil || errors.Is(err, database.ErrQueryTimeout) {
+		t.Errorf("past the caller's deadline: %v, want the driver's error", err)
+	}
+
+	if err := dbtx.QueryRowContext(database.ContextWithoutQueryTimeout(ctx), countTo, long).Scan(&n); err != nil || n != long {
+		t.Errorf("opted out: %d, %v; want it to finish", n, err)
+	}
+}
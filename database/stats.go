@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is the connection pool's sql.DBStats, plus counts of what the
+// package ran on it since Open
+type Stats struct {
+	sql.DBStats
+	Queries      int64 // Statements sent through db.Q, transactions and the helpers that build SQL
+	Transactions int64 // Transactions begun; nested ones are savepoints and don't count
+	Rollbacks    int64 // Transactions that didn't commit, failed commits included
+	Retries      int64 // Times RetryTransaction ran a transaction again, or a QueryPolicy a statement
+}
+
+type statsCounters struct {
+	queries      atomic.Int64
+	transactions atomic.Int64
+	rollbacks    atomic.Int64
+	retries      atomic.Int64
+}
+
+// Stats returns the pool's stats and the package's counters. The
+// counters only go up; take the difference of two calls for a rate.
+func (db *DB) Stats() Stats {
+	return Stats{
+		DBStats:      db.Conn.Stats(),
+		Queries:      db.counters.queries.Load(),
+		Transactions: db.counters.transactions.Load(),
+		Rollbacks:    db.counters.rollbacks.Load(),
+		Retries:      db.counters.retries.Load(),
+	}
+}
+
+// StartPoolMonitor checks the connection pool every interval until ctx
+// is canceled, and logs each check that finds it saturated: every allowed
+// connection in use, or callers that waited for one since the check
+// before. A saturated pool that ran no statements in between is logged as
+// a likely leak, rows or transactions that are never closed, since load
+// alone keeps statements running. It logs once more when it recovers.
+func (db *DB) StartPoolMonitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		prev := db.Stats()
+		saturated := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			s := db.Stats()
+			full := s.MaxOpenConnections > 0 && s.InUse >= s.MaxOpenConnections
+			waits := s.WaitCount - prev.WaitCount
+			switch {
+			case full && s.Queries == prev.Queries:
+				log.Printf("database pool saturated: %d/%d connections in use and no statements run in %v; connections may be leaking (unclosed rows or transactions)",
+					s.InUse, s.MaxOpenConnections, interval)
+			case full || waits > 0:
+				log.Printf("database pool saturated: %d/%d connections in use, %d waits for a connection (%v) in %v",
+					s.InUse, s.MaxOpenConnections, waits, (s.WaitDuration - prev.WaitDuration).Round(time.Millisecond), interval)
+			case saturated:
+				log.Printf("database pool no longer saturated: %d/%d connections in use", s.InUse, s.MaxOpenConnections)
+			}
+			saturated = full || waits > 0
+			prev = s
+		}
+	}()
+}
+
+// countingDBTX counts the statements that get past the rest of the
+// middleware, for Stats
+type countingDBTX struct {
+	DBTX
+	n *atomic.Int64
+}
+
+func (d *countingDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	d.n.Add(1)
+	return d.DBTX.ExecContext(ctx, query, args...)
+}
+
+func (d *countingDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	d.n.Add(1)
+	return d.DBTX.QueryContext(ctx, query, args...)
+}
+
+func (d *countingDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	d.n.Add(1)
+	return d.DBTX.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Status is the users.status column. The allowed values mirror the CHECK
+// constraint in schema.sql; keep the two in sync.
+type Status string
+
+const (
+	StatusActive  Status = "active"
+	StatusBlocked Status = "blocked" // The user blocked the bot
+	StatusBanned  Status = "banned"
+)
+
+// Statuses lists every valid Status
+var Statuses = []Status{StatusActive, StatusBlocked, StatusBanned}
+
+// Valid reports whether s is one of the Status constants. The zero value is
+// not valid, although Value writes it as StatusActive (the column default).
+func (s Status) Valid() bool {
+	switch s {
+	case StatusActive, StatusBlocked, StatusBanned:
+		return true
+	}
+	return false
+}
+
+// Value implements driver.Valuer. An empty Status is stored as StatusActive,
+// anything else not in Statuses is rejected before it reaches the database.
+func (s Status) Value() (driver.Value, error) {
+	if s == "" {
+		return string(StatusActive), nil
+	}
+	if !s.Valid() {
+		return nil, fmt.Errorf("invalid user status %q", string(s))
+	}
+	return string(s), nil
+}
+
+// Scan implements sql.Scanner and rejects values outside Statuses, so rows
+// written behind the CHECK constraint's back don't go unnoticed
+func (s *Status) Scan(src any) error {
+	var v Status
+	switch src := src.(type) {
+	case string:
+		v = Status(src)
+	case []byte:
+		v = Status(src)
+	default:
+		return fmt.Errorf("cannot scan %T into Status", src)
+	}
+	if !v.Valid() {
+		return fmt.Errorf("invalid user status %q in database", string(v))
+	}
+	*s = v
+	return nil
+}
@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+)
+
+func TestStatusColumn(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+
+	for i, s := range append(database.Statuses, "") {
+		u, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: int64(i + 1), FirstName: "user", Status: s})
+		if err != nil {
+			t.Fatalf("creating a user with status %q: %v", s, err)
+		}
+		want := s
+		if s == "" {
+			want = database.StatusActive // The zero value is the column default
+		}
+		if u.Status != want {
+			t.Errorf("created with %q: status %q, want %q", s, u.Status, want)
+		}
+	}
+
+	// A typo is refused by the Valuer before it reaches the database
+	if _, err := db.Q.CreateUser(ctx, database.CreateUserParams{TelegramID: 10, FirstName: "user", Status: "actve"}); err == nil {
+		t.Error("an unknown status was written")
+	}
+	// and by the CHECK constraint for SQL that doesn't go through it
+	_, err := db.DBTX().ExecContext(ctx, "UPDATE users SET status = 'deleted'")
+	var ve *database.ValidationError
+	if !errors.As(database.Translate(err), &ve) || ve.Field != "status" {
+		t.Errorf("an unknown status behind the Valuer's back: %v, want a ValidationError for status", err)
+	}
+
+	for _, c := range []struct {
+		status database.Status
+		want   int64
+	}{{database.StatusActive, 2}, {database.StatusBlocked, 1}, {database.StatusBanned, 1}} {
+		n, err := db.Q.CountUsersByStatus(ctx, c.status)
+		if err != nil || n != c.want {
+			t.Errorf("CountUsersByStatus(%s): %d, %v; want %d", c.status, n, err, c.want)
+		}
+		users, err := db.Q.ListUsersByStatus(ctx, database.ListUsersByStatusParams{Status: c.status, Limit: 10})
+		if err != nil || int64(len(users)) != c.want {
+			t.Errorf("ListUsersByStatus(%s): %d users, %v; want %d", c.status, len(users), err, c.want)
+		}
+		for _, u := range users {
+			if u.Status != c.status {
+				t.Errorf("ListUsersByStatus(%s) returned a user with status %s", c.status, u.Status)
+			}
+		}
+	}
+}
+
+func TestStatusScan(t *testing.T) {
+	var s database.Status
+	if err := s.Scan([]byte("banned")); err != nil || s != database.StatusBanned {
+		t.Errorf("scanning banned: %q, %v", s, err)
+	}
+	for _, src := range []any{"deleted", "", int64(1), nil} {
+		if err := s.Scan(src); err == nil {
+			t.Errorf("scanning %#v: no error", src)
+		}
+	}
+	if database.Status("").Valid() {
+		t.Error("the zero Status is valid, want it to stand only for the default on write")
+	}
+}
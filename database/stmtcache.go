@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+)
+
+// StatementCacheStats is how Config.StatementCache is doing
+type StatementCacheStats struct {
+	Statements    int    // Prepared statements held now
+	Hits          uint64 // Runs of a statement prepared earlier
+	Misses        uint64 // Statements prepared, the first run of each query
+	Stale         uint64 // Statements the server refused as stale and dropped
+	Invalidations uint64 // Times the whole cache was dropped for a schema change
+}
+
+// stmtCache holds a prepared statement per sqlc query, on the pool: the
+// *sql.Stmt prepares itself again on each connection it meets, once. Only
+// generated queries are cached, so the SQL built at run time can't fill
+// it up.
+type stmtCache struct {
+	db *sql.DB
+
+	// Held for reading while a statement runs, so invalidate can't close
+	// one between lookup and use
+	use sync.RWMutex
+
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+
+	hits, misses, stale, invalidations atomic.Uint64
+}
+
+func newStmtCache(enabled bool, db *sql.DB) *stmtCache {
+	if !enabled {
+		return nil
+	}
+	return &stmtCache{db: db, stmts: map[string]*sql.Stmt{}}
+}
+
+// StatementCacheStats returns the statement cache's counters; all zero
+// without Config.StatementCache
+func (db *DB) StatementCacheStats() StatementCacheStats {
+	c := db.stmts
+	if c == nil {
+		return StatementCacheStats{}
+	}
+	c.mu.Lock()
+	n := len(c.stmts)
+	c.mu.Unlock()
+	return StatementCacheStats{
+		Statements:    n,
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Stale:         c.stale.Load(),
+		Invalidations: c.invalidations.Load(),
+	}
+}
+
+// invalidate closes every statement, after a migration changed the
+// tables they were prepared against. A nil cache does nothing.
+func (c *stmtCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.use.Lock()
+	defer c.use.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.stmts {
+		s.Close()
+	}
+	c.stmts = map[string]*sql.Stmt{}
+	c.invalidations.Add(1)
+}
+
+// stmt returns query's statement, preparing it on first use; nil when
+// query isn't a generated one or doesn't prepare, so it runs as it is
+// and fails there if it's broken
+func (c *stmtCache) stmt(ctx context.Context, query string) *sql.Stmt {
+	if queryName(query) == "other" {
+		return nil
+	}
+	c.mu.Lock()
+	s, ok := c.stmts[query]
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+		return s
+	}
+
+	s, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if had, ok := c.stmts[query]; ok { // Prepared meanwhile by another caller
+		s.Close()
+		c.hits.Add(1)
+		return had
+	}
+	c.stmts[query] = s
+	c.misses.Add(1)
+	return s
+}
+
+// drop forgets query's statement after the server refused it
+func (c *stmtCache) drop(query string, s *sql.Stmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stmts[query] == s {
+		delete(c.stmts, query)
+		s.Close()
+		c.stale.Add(1)
+	}
+}
+
+// wrap puts the cache under dbtx: the pool itself, a replica router's
+// primary or a *sql.Tx. Other transactions, begun by hand on a connection,
+// run their statements as they are.
+func (c *stmtCache) wrap(dbtx DBTX) DBTX {
+	if c == nil {
+		return dbtx
+	}
+	switch t := dbtx.(type) {
+	case *sql.DB:
+		if t == c.db {
+			return &stmtCacheDBTX{DBTX: t, c: c}
+		}
+	case *replicaDBTX:
+		return &replicaDBTX{DBTX: c.wrap(t.DBTX), pool: t.pool}
+	case *sql.Tx:
+		return &stmtCacheDBTX{DBTX: t, c: c, tx: t}
+	}
+	return dbtx
+}
+
+// stmtCacheDBTX runs generated queries through their prepared statements.
+// In a transaction the statement is bound to it, which reuses what the
+// connection has already prepared.
+type stmtCacheDBTX struct {
+	DBTX
+	c  *stmtCache
+	tx *sql.Tx
+}
+
+// stmtRun looks query's statement up and calls fn with it, nil for none.
+// A statement the server calls stale, from a schema another process
+// changed, is dropped, and fn runs once more without it; not in a
+// transaction, which PostgreSQL aborts on the error, so the caller
+// retries the whole of it.
+func stmtRun[T any](d *stmtCacheDBTX, ctx context.Context, query string, fn func(*sql.Stmt) (T, error)) (T, error) {
+	d.c.use.RLock()
+	defer d.c.use.RUnlock()
+
+	s := d.c.stmt(ctx, query)
+	if s == nil {
+		return fn(nil)
+	}
+	bound := s
+	if d.tx != nil {
+		bound = d.tx.StmtContext(ctx, s)
+	}
+	v, err := fn(bound)
+	if stale := defaultDialect().isStaleStatement; err != nil && stale != nil && stale(err) {
+		d.c.drop(query, s)
+		if d.tx == nil {
+			return fn(nil)
+		}
+	}
+	return v, err
+}
+
+func (d *stmtCacheDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return stmtRun(d, ctx, query, func(s *sql.Stmt) (sql.Result, error) {
+		if s == nil {
+			return d.DBTX.ExecContext(ctx, query, args...)
+		}
+		return s.ExecContext(ctx, args...)
+	})
+}
+
+func (d *stmtCacheDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return stmtRun(d, ctx, query, func(s *sql.Stmt) (*sql.Rows, error) {
+		if s == nil {
+			return d.DBTX.QueryContext(ctx, query, args...)
+		}
+		return s.QueryContext(ctx, args...)
+	})
+}
+
+// A *sql.Row holds its error until Scan, or Err, which is where a stale
+// statement shows up
+func (d *stmtCacheDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row, _ := stmtRun(d, ctx, query, func(s *sql.Stmt) (*sql.Row, error) {
+		if s == nil {
+			return d.DBTX.QueryRowContext(ctx, query, args...), nil
+		}
+		row := s.QueryRowContext(ctx, args...)
+		return row, row.Err()
+	})
+	return row
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How often a write-degraded DB tries its probe write
+var storageProbeInterval = 5 * time.Second // A var so tests can shorten it
+
+// storageMonitor pauses writes once the database reports it's out of
+// space, until a probe write succeeds. Reads keep working.
+type storageMonitor struct {
+	degraded atomic.Bool
+	mu       sync.Mutex
+	lastErr  error
+}
+
+// WriteDegraded reports whether writes are paused after a storage error
+func (db *DB) WriteDegraded() bool {
+	return db.storage.degraded.Load()
+}
+
+func (db *DB) storageErr() error {
+	db.storage.mu.Lock()
+	defer db.storage.mu.Unlock()
+	return fmt.Errorf("%w: writes are paused until a probe write succeeds (last error: %v)",
+		ErrStorageExhausted, db.storage.lastErr)
+}
+
+// noteStorageError switches to write-degraded mode on a storage error and
+// starts probing for recovery
+func (db *DB) noteStorageError(err error) {
+	if err == nil || !isStorageError(err) {
+		return
+	}
+
+	db.storage.mu.Lock()
+	db.storage.lastErr = err
+	db.storage.mu.Unlock()
+
+	if db.storage.degraded.CompareAndSwap(false, true) {
+		log.Printf("database writes paused, storage exhausted: %v", err)
+		go db.probeStorage()
+	}
+}
+
+// probeStorage retries a one-row write until it succeeds or the DB is
+// closed. It goes to the connection directly, past the fast-fail.
+func (db *DB) probeStorage() {
+	probe := New(db.Conn)
+	for {
+		time.Sleep(storageProbeInterval)
+		if db.closed.Load() {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), storageProbeInterval)
+		err := probe.TouchStorageProbe(ctx)
+		cancel()
+
+		if err == nil {
+			db.storage.degraded.Store(false)
+			log.Println("database writes resumed, the probe write succeeded")
+			return
+		}
+		if isStorageError(err) {
+			db.storage.mu.Lock()
+			db.storage.lastErr = err
+			db.storage.mu.Unlock()
+		}
+	}
+}
+
+// storageDBTX watches for storage errors and, outside a transaction, fails
+// writes fast while the DB is write-degraded. It sits right on the *sql.DB
+// or the transaction.
+type storageDBTX struct {
+	DBTX
+	db *DB
+}
+
+func (d *storageDBTX) failFast(query string) bool {
+	_, inTx := d.DBTX.(sqlTx) // InTx already refused to start
+	return !inTx && d.db.storage.degraded.Load() && isWriteQuery(query)
+}
+
+// refused is ErrShuttingDown once Shutdown has begun, for statements
+// outside a transaction; the transactions already running may finish
+func (d *storageDBTX) refused() error {
+	if _, inTx := d.DBTX.(sqlTx); inTx || !d.db.shuttingDown.Load() {
+		return nil
+	}
+	return ErrShuttingDown
+}
+
+func (d *storageDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := d.refused(); err != nil {
+		return nil, err
+	}
+	if d.failFast(query) {
+		return nil, d.db.storageErr()
+	}
+	res, err := d.DBTX.ExecContext(ctx, query, args...)
+	d.db.noteStorageError(err)
+	return res, err
+}
+
+func (d *storageDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if err := d.refused(); err != nil {
+		return nil, err
+	}
+	if d.failFast(query) {
+		return nil, d.db.storageErr()
+	}
+	rows, err := d.DBTX.QueryContext(ctx, query, args...)
+	d.db.noteStorageError(err)
+	return rows, err
+}
+
+func (d *storageDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if err := d.refused(); err != nil {
+		return errRow(ctx, err)
+	}
+	if d.failFast(query) {
+		return errRow(ctx, d.db.storageErr())
+	}
+	row := d.DBTX.QueryRowContext(ctx, query, args...)
+	d.db.noteStorageError(row.Err())
+	return row
+}
@@ -0,0 +1,130 @@
+//go:build !postgres
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// fullConnector is a driver whose disk can fill up: writes then fail as
+// SQLite's do, while reads keep answering
+type fullConnector struct {
+	full   atomic.Bool
+	writes atomic.Int64 // Writes that reached the driver
+}
+
+func (c *fullConnector) Connect(context.Context) (driver.Conn, error) { return fullConn{c}, nil }
+func (c *fullConnector) Driver() driver.Driver                        { return nil }
+
+type fullConn struct {
+	c *fullConnector
+}
+
+func (c fullConn) write() error {
+	c.c.writes.Add(1)
+	if c.c.full.Load() {
+		return errors.New("database or disk is full")
+	}
+	return nil
+}
+
+func (c fullConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if err := c.write(); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c fullConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if isWriteQuery(query) {
+		if err := c.write(); err != nil {
+			return nil, err
+		}
+	}
+	return &versionRows{version: "1"}, nil
+}
+
+func (c fullConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fullConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (c fullConn) Close() error                        { return nil }
+
+func TestStorageExhausted(t *testing.T) {
+	defer func(d time.Duration) { storageProbeInterval = d }(storageProbeInterval)
+	storageProbeInterval = 10 * time.Millisecond
+
+	ctx := context.Background()
+	fc := &fullConnector{}
+	db, err := NewFromConn(sql.OpenDB(fc), Config{LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	touch := func() error { return db.Q.TouchStorageProbe(ctx) } // Any write through db.Q
+
+	fc.full.Store(true)
+	if err := touch(); !errors.Is(err, ErrStorageExhausted) {
+		t.Fatalf("a write on a full disk: %v, want ErrStorageExhausted", err)
+	}
+	if !db.WriteDegraded() || !db.HealthState().WriteDegraded || !db.Readiness(ctx).WriteDegraded {
+		t.Error("not write-degraded after a storage error")
+	}
+
+	// Fail fast instead of hammering the disk; reads still get through.
+	// Only the probe, every 10ms, still writes.
+	before := fc.writes.Load()
+	for range 100 {
+		if err := touch(); !errors.Is(err, ErrStorageExhausted) {
+			t.Fatalf("a write while degraded: %v, want ErrStorageExhausted", err)
+		}
+	}
+	if err := db.InTx(ctx, func(*Tx) error { return nil }); !errors.Is(err, ErrStorageExhausted) {
+		t.Errorf("a transaction while degraded: %v, want ErrStorageExhausted", err)
+	}
+	if n := fc.writes.Load() - before; n > 5 {
+		t.Errorf("%d writes reached the driver while degraded, want only the probe's", n)
+	}
+	var one int
+	if err := db.DBTX().QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		t.Errorf("a read while degraded: %v", err)
+	}
+
+	// The probe keeps failing while the disk is full, then clears the flag
+	time.Sleep(50 * time.Millisecond)
+	if !db.WriteDegraded() {
+		t.Fatal("the flag cleared while the disk was still full")
+	}
+	fc.full.Store(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for db.WriteDegraded() {
+		if time.Now().After(deadline) {
+			t.Fatal("still write-degraded 5s after the disk had room again")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := touch(); err != nil {
+		t.Errorf("a write once recovered: %v", err)
+	}
+}
+
+func TestTranslateStorageErrors(t *testing.T) {
+	for _, err := range []error{
+		sqlite3.Error{Code: sqlite3.ErrFull},
+		sqlite3.Error{Code: sqlite3.ErrIoErr},
+		errors.New("database or disk is full"), // modernc.org/sqlite
+	} {
+		if got := Translate(err); !errors.Is(got, ErrStorageExhausted) || !errors.Is(got, err) {
+			t.Errorf("%v: %v, want ErrStorageExhausted wrapping it", err, got)
+		}
+	}
+	if err := Translate(sqlite3.Error{Code: sqlite3.ErrBusy}); errors.Is(err, ErrStorageExhausted) {
+		t.Errorf("SQLITE_BUSY: %v, want it not a storage error", err)
+	}
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+	"strings"
+)
+
+// StreamUsers is ListUsersWhere for exports and batch jobs: every user
+// matching filter, in id order, read one row at a time over one query
+// instead of into a slice. Errors come as the second value, once, after
+// which the loop ends:
+//
+//	for u, err := range db.StreamUsers(ctx, filter) {
+//		if err != nil {
+//			return err
+//		}
+//		// ...
+//	}
+//
+// Breaking out of the loop or canceling ctx closes the rows. Each range
+// runs the query again. The loop holds a connection until it ends, and on
+// SQLite a read open that long keeps checkpoints from reaching the end of
+// the WAL, so don't linger in it.
+func (db *DB) StreamUsers(ctx context.Context, filter UserFilter) iter.Seq2[User, error] {
+	d := defaultDialect()
+	q := &queryBuilder{numbered: d.numberedParams}
+	query := "SELECT " + userColumns + " FROM users"
+	if where := filter.where(q, d); len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id"
+	return streamRows(ctx, db.wrap(db.routeReads(db.Conn)), scanUser, query, q.args...)
+}
+
+// ForEachUser calls fn with every user StreamUsers yields, stopping at
+// the first error, fn's included, and returning it
+func (db *DB) ForEachUser(ctx context.Context, filter UserFilter, fn func(User) error) error {
+	for u, err := range db.StreamUsers(ctx, filter) {
+		if err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamRows runs query when the loop starts and yields what scan makes
+// of each row, then the error that ended it, if any, Translated
+func streamRows[T any](ctx context.Context, conn DBTX, scan func(*sql.Rows) (T, error), query string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		rows, err := conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			yield(zero, Translate(err))
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			v, err := scan(rows)
+			if err != nil {
+				yield(zero, Translate(err))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, Translate(err))
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"strings"
+)
+
+// SetTags makes the tags of a group exactly match tags, attaching and
+// detaching only what differs, in one transaction. Names are trimmed,
+// blanks dropped and duplicates ignored.
+func SetTags(ctx context.Context, db *DB, groupTelegramID int64, tags []string) error {
+	desired := map[string]bool{}
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); t != "" {
+			desired[t] = true
+		}
+	}
+
+	return db.Transaction(ctx, func(q *Queries) error {
+		current, err := q.ListGroupTags(ctx, groupTelegramID)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range current {
+			if desired[t.Name] {
+				delete(desired, t.Name) // already attached
+				continue
+			}
+			if err := q.DetachTag(ctx, DetachTagParams{GroupTelegramID: groupTelegramID, TagID: t.ID}); err != nil {
+				return err
+			}
+		}
+
+		for name := range desired {
+			tag, err := q.UpsertTag(ctx, name)
+			if err != nil {
+				return err
+			}
+			if err := q.AttachTag(ctx, AttachTagParams{GroupTelegramID: groupTelegramID, TagID: tag.ID}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GroupsWithAllTags returns a page of groups tagged with every one of tags.
+// Pass the ID of the last group from the previous page as afterID (0 to start).
+func GroupsWithAllTags(ctx context.Context, q Querier, tags []string, afterID, pageSize int64) ([]Group, error) {
+	seen := map[string]bool{}
+	var unique []string
+	for _, t := range tags {
+		if !seen[t] {
+			seen[t] = true
+			unique = append(unique, t)
+		}
+	}
+
+	return q.ListGroupsWithAllTags(ctx, ListGroupsWithAllTagsParams{
+		Tags:     unique,
+		AfterID:  afterID,
+		TagCount: int64(len(unique)),
+		PageSize: pageSize,
+	})
+}
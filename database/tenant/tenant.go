@@ -0,0 +1,453 @@
+// Package tenant keeps a database per tenant, for apps that give every
+// customer data of their own: a SQLite file each, or a PostgreSQL schema
+// each in one database.
+//
+//	tenants, err := tenant.New(tenant.Options{
+//		Config: tenant.SQLiteFiles(cfg, "/var/lib/app/tenants"),
+//	})
+//	defer tenants.Close()
+//
+//	q, err := tenants.ForTenant(ctx, "acme")
+//	user, err := q.GetUserByTelegramID(ctx, id)
+//
+// A tenant's database is opened, and migrated, on first use; concurrent
+// first uses wait for the same open. Databases nobody has used for
+// Options.IdleTimeout are closed again, except those held with Acquire.
+// With ContextWithTenant set by a middleware, handlers call Queries(ctx)
+// and never name the tenant.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"your-project/database"
+)
+
+// ErrClosed is returned once the Manager is closed
+var ErrClosed = errors.New("tenant: manager is closed")
+
+// ErrNoTenant is returned by Queries and DBFromContext for a context
+// without ContextWithTenant
+var ErrNoTenant = errors.New("tenant: no tenant in context")
+
+// Tenant IDs name files and schemas, so they're kept to what's safe in
+// both: lowercase letters, digits, _ and -, at most 56 (a schema name,
+// "tenant_" included, has 63 bytes at most)
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,55}$`)
+
+// ValidID reports whether id can name a tenant
+func ValidID(id string) error {
+	if !validID.MatchString(id) {
+		return fmt.Errorf("tenant: invalid ID %q (want lowercase letters, digits, _ and -, at most 56)", id)
+	}
+	return nil
+}
+
+// ConfigFunc returns the Config a tenant's database is opened with. It
+// may prepare the database first, as PostgresSchemas creates the schema.
+type ConfigFunc func(ctx context.Context, tenantID string) (database.Config, error)
+
+// Options configures a Manager
+type Options struct {
+	Config      ConfigFunc    // Where each tenant's database is: SQLiteFiles, PostgresSchemas or your own (required)
+	IdleTimeout time.Duration // A tenant's database unused this long is closed (default 10m, negative = never)
+	MaxOpen     int           // Tenant databases kept open; past it the least recently used idle one is closed (0 = no limit)
+	MinIdle     time.Duration // How long a database must have gone unused before MaxOpen may close it (default 1m)
+}
+
+// Manager opens tenants' databases as they're asked for and closes the
+// idle ones. It's safe for concurrent use.
+type Manager struct {
+	opts Options
+
+	mu      sync.Mutex
+	tenants map[string]*entry
+	closed  bool
+	stop    context.CancelFunc // Ends the idle sweep, nil without one
+}
+
+type entry struct {
+	ready    chan struct{} // Closed once the open is done, with db or err set
+	db       *database.DB
+	err      error
+	lastUsed time.Time // Guarded by Manager.mu
+	refs     int       // Acquire calls not yet released, guarded by Manager.mu
+}
+
+// New returns a Manager; it opens nothing until a tenant is asked for
+func New(opts Options) (*Manager, error) {
+	if opts.Config == nil {
+		return nil, errors.New("tenant: Options.Config is required")
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = 10 * time.Minute
+	}
+	if opts.MinIdle == 0 {
+		opts.MinIdle = time.Minute
+	}
+	m := &Manager{opts: opts, tenants: map[string]*entry{}}
+
+	if opts.IdleTimeout > 0 {
+		ctx, stop := context.WithCancel(context.Background())
+		m.stop = stop
+		go m.sweep(ctx, max(opts.IdleTimeout/2, time.Second))
+	}
+	return m, nil
+}
+
+// ForTenant returns the queries on tenantID's database, opening it first
+// if it isn't open. Use them for the request at hand only: the database
+// may be closed once it has been idle for IdleTimeout, or MinIdle when
+// MaxOpen needs room. A handler that runs longer should Acquire it.
+func (m *Manager) ForTenant(ctx context.Context, tenantID string) (database.Querier, error) {
+	db, err := m.DB(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return db.Q, nil
+}
+
+// DB is ForTenant returning the whole DB, for Transaction and the other
+// helpers beyond the queries
+func (m *Manager) DB(ctx context.Context, tenantID string) (*database.DB, error) {
+	e, err := m.get(ctx, tenantID, false)
+	if err != nil {
+		return nil, err
+	}
+	return e.db, nil
+}
+
+// Acquire is DB for work that may outlast IdleTimeout, such as a long
+// export or a background job: neither the idle sweep nor MaxOpen closes
+// the database until release is called, once. Evict and Close still do.
+func (m *Manager) Acquire(ctx context.Context, tenantID string) (db *database.DB, release func(), err error) {
+	e, err := m.get(ctx, tenantID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	return e.db, func() {
+		once.Do(func() {
+			m.mu.Lock()
+			e.refs--
+			e.lastUsed = time.Now()
+			m.mu.Unlock()
+		})
+	}, nil
+}
+
+// get returns tenantID's entry once it's open, holding a reference to it
+// if acquire is set
+func (m *Manager) get(ctx context.Context, tenantID string, acquire bool) (*entry, error) {
+	if err := ValidID(tenantID); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	e, ok := m.tenants[tenantID]
+	if !ok {
+		e = &entry{ready: make(chan struct{})}
+		m.tenants[tenantID] = e
+	}
+	e.lastUsed = time.Now()
+	if acquire {
+		e.refs++
+	}
+	var evicted map[string]*database.DB
+	if !ok {
+		evicted = m.evictOverLimitLocked(tenantID)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		closeAll(evicted)
+		m.open(ctx, tenantID, e)
+	}
+
+	var err error
+	select {
+	case <-e.ready:
+		err = e.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		if acquire {
+			m.mu.Lock()
+			e.refs--
+			m.mu.Unlock()
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// Queries is ForTenant for the tenant ContextWithTenant put in ctx
+func (m *Manager) Queries(ctx context.Context) (database.Querier, error) {
+	db, err := m.DBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.Q, nil
+}
+
+// DBFromContext is DB for the tenant ContextWithTenant put in ctx
+func (m *Manager) DBFromContext(ctx context.Context) (*database.DB, error) {
+	id := FromContext(ctx)
+	if id == "" {
+		return nil, ErrNoTenant
+	}
+	return m.DB(ctx, id)
+}
+
+// open opens a new entry's database; a failed open is forgotten, so the
+// next call tries again
+func (m *Manager) open(ctx context.Context, tenantID string, e *entry) {
+	defer close(e.ready)
+
+	cfg, err := m.opts.Config(ctx, tenantID)
+	var db *database.DB
+	if err == nil {
+		db, err = database.OpenContext(ctx, cfg)
+	}
+
+	m.mu.Lock()
+	closed := m.closed
+	switch {
+	case err != nil:
+		e.err = fmt.Errorf("tenant %s: %w", tenantID, err)
+		if m.tenants[tenantID] == e {
+			delete(m.tenants, tenantID)
+		}
+	case closed:
+		e.err = ErrClosed
+	default:
+		e.db = db
+		e.lastUsed = time.Now()
+	}
+	m.mu.Unlock()
+
+	if err == nil && closed {
+		db.Close()
+	}
+}
+
+// OpenTenants returns the IDs of the tenants whose databases are open
+func (m *Manager) OpenTenants() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for id, e := range m.tenants {
+		if isOpen(e) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Evict closes tenantID's database now, if it's open, such as before its
+// file is deleted. Statements running on it finish first, as with
+// DB.Close; the next call for the tenant opens it again.
+func (m *Manager) Evict(tenantID string) error {
+	m.mu.Lock()
+	e, ok := m.tenants[tenantID]
+	if !ok || !isOpen(e) {
+		m.mu.Unlock()
+		return nil
+	}
+	delete(m.tenants, tenantID)
+	m.mu.Unlock()
+	return e.db.Close()
+}
+
+// Close closes every tenant's database, and makes further calls fail
+// with ErrClosed
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	if m.stop != nil {
+		m.stop()
+	}
+	dbs := map[string]*database.DB{}
+	for id, e := range m.tenants {
+		if isOpen(e) {
+			dbs[id] = e.db
+		}
+	}
+	clear(m.tenants)
+	m.mu.Unlock()
+
+	var errs []error
+	for id, db := range dbs {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sweep closes the databases idle for longer than IdleTimeout, every
+// interval until ctx is canceled
+func (m *Manager) sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		m.mu.Lock()
+		var idle map[string]*database.DB
+		for id, e := range m.tenants {
+			if isIdle(e, m.opts.IdleTimeout) {
+				if idle == nil {
+					idle = map[string]*database.DB{}
+				}
+				idle[id] = e.db
+				delete(m.tenants, id)
+			}
+		}
+		m.mu.Unlock()
+		closeAll(idle)
+	}
+}
+
+// evictOverLimitLocked takes the least recently used databases idle for
+// MinIdle out, but never keep, until MaxOpen is kept. They're returned
+// for closing outside the lock. When none of them are that idle, the
+// limit is exceeded instead of failing the new tenant, or closing a
+// database a caller has just been handed.
+func (m *Manager) evictOverLimitLocked(keep string) map[string]*database.DB {
+	if m.opts.MaxOpen <= 0 {
+		return nil
+	}
+	var evicted map[string]*database.DB
+	for len(m.tenants) > m.opts.MaxOpen {
+		var oldest string
+		for id, e := range m.tenants {
+			if id != keep && isIdle(e, m.opts.MinIdle) && (oldest == "" || e.lastUsed.Before(m.tenants[oldest].lastUsed)) {
+				oldest = id
+			}
+		}
+		if oldest == "" {
+			return evicted
+		}
+		if evicted == nil {
+			evicted = map[string]*database.DB{}
+		}
+		evicted[oldest] = m.tenants[oldest].db
+		delete(m.tenants, oldest)
+	}
+	return evicted
+}
+
+func isOpen(e *entry) bool {
+	select {
+	case <-e.ready:
+		return e.db != nil
+	default:
+		return false
+	}
+}
+
+// isIdle tells an open database nobody has asked for in the last d, nor
+// holds with Acquire, and that no statement or transaction is using
+func isIdle(e *entry, d time.Duration) bool {
+	return isOpen(e) && e.refs == 0 && time.Since(e.lastUsed) > d && e.db.Conn.Stats().InUse == 0
+}
+
+func closeAll(dbs map[string]*database.DB) {
+	for id, db := range dbs {
+		if err := db.Close(); err != nil {
+			log.Printf("tenant %s: failed to close its database: %v", id, err)
+		}
+	}
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns ctx carrying tenantID, for Queries and
+// DBFromContext. Set it once per request, in the middleware that tells
+// the tenant from the host name, token or path.
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// FromContext returns the tenant ContextWithTenant put in ctx, "" if
+// there's none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantKey{}).(string)
+	return id
+}
+
+// SQLiteFiles keeps each tenant in a file of its own, <dir>/<id>.db,
+// opened with base otherwise; dir is created with 0700 if missing. A
+// scheduled backup goes to a directory per tenant under base.Backup.Dir.
+// Read replicas and shadow databases aren't per tenant, so they're left
+// out.
+func SQLiteFiles(base database.Config, dir string) ConfigFunc {
+	return func(_ context.Context, tenantID string) (database.Config, error) {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return database.Config{}, fmt.Errorf("failed to create the tenant dir: %w", err)
+		}
+		cfg := base
+		cfg.DSN = filepath.Join(dir, tenantID+".db")
+		cfg.ReadDSNs, cfg.ShadowDSN = nil, ""
+		if cfg.Backup.Dir != "" {
+			cfg.Backup.Dir = filepath.Join(cfg.Backup.Dir, tenantID)
+		}
+		return cfg, nil
+	}
+}
+
+// PostgresSchemas keeps each tenant in a schema of its own, tenant_<id>,
+// of base's database. admin, a DB on that database, creates the schema if
+// it's missing; the tenant's connections get it as their search_path, so
+// the migrations and queries run in it. base.DSN may be a URL or
+// key=value pairs. Every tenant gets a pool of its own, so keep
+// base.MaxOpenConns small.
+func PostgresSchemas(base database.Config, admin *database.DB) ConfigFunc {
+	return func(ctx context.Context, tenantID string) (database.Config, error) {
+		schema := `"tenant_` + tenantID + `"` // ValidID leaves nothing to escape
+		if _, err := admin.Conn.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+schema); err != nil {
+			return database.Config{}, fmt.Errorf("failed to create schema %s: %w", schema, err)
+		}
+
+		cfg := base
+		cfg.ReadDSNs, cfg.ShadowDSN = nil, ""
+		if strings.HasPrefix(base.DSN, "postgres://") || strings.HasPrefix(base.DSN, "postgresql://") {
+			u, err := url.Parse(base.DSN)
+			if err != nil {
+				return database.Config{}, fmt.Errorf("malformed DSN %s", database.RedactDSN(base.Driver, base.DSN))
+			}
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			cfg.DSN = u.String()
+		} else {
+			cfg.DSN = strings.TrimSpace(base.DSN + " search_path='" + schema + "'")
+		}
+		return cfg, nil
+	}
+}
@@ -0,0 +1,95 @@
+//go:build !postgres
+
+package tenant_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"your-project/database"
+	"your-project/database/tenant"
+)
+
+func newManager(t *testing.T, opts tenant.Options) *tenant.Manager {
+	t.Helper()
+	opts.Config = tenant.SQLiteFiles(database.Config{LogLevel: "silent"}, t.TempDir())
+	m, err := tenant.New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+// use runs a query on db, which fails if it was closed under the caller
+func use(t *testing.T, db *database.DB, what string) {
+	t.Helper()
+	if _, err := db.Q.CountUsersByStatus(context.Background(), database.StatusActive); err != nil {
+		t.Errorf("%s: %v", what, err)
+	}
+}
+
+func TestMaxOpenSparesDatabasesInUse(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, tenant.Options{MaxOpen: 1})
+
+	// a was handed out a moment ago, so b's open doesn't close it, though
+	// no statement is running on it yet
+	a, err := m.DB(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.DB(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	use(t, a, "a's database after b was opened")
+	if got := m.OpenTenants(); len(got) != 2 {
+		t.Errorf("open tenants %v, want both: MaxOpen waits for MinIdle", got)
+	}
+}
+
+func TestMaxOpenEvictsIdle(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, tenant.Options{MaxOpen: 1, MinIdle: time.Nanosecond})
+
+	held, release, err := m.Acquire(ctx, "held")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.DB(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	use(t, held, "an acquired database after another was opened")
+
+	release()
+	release() // A second call does nothing
+	time.Sleep(time.Millisecond)
+	if _, err := m.DB(ctx, "c"); err != nil {
+		t.Fatal(err)
+	}
+	got := m.OpenTenants()
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"c"}) {
+		t.Errorf("open tenants %v, want only c: the others were idle", got)
+	}
+}
+
+func TestIdleSweepSparesAcquired(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, tenant.Options{IdleTimeout: 10 * time.Millisecond}) // Swept every second
+
+	db, release, err := m.Acquire(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	use(t, db, "an acquired database past IdleTimeout")
+
+	release()
+	time.Sleep(1500 * time.Millisecond)
+	if got := m.OpenTenants(); len(got) != 0 {
+		t.Errorf("open tenants %v, want none once released and idle", got)
+	}
+}
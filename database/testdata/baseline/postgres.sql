@@ -0,0 +1,54 @@
+-- Users table
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    telegram_id BIGINT NOT NULL UNIQUE,
+    first_name TEXT NOT NULL DEFAULT '',
+    username TEXT DEFAULT '',
+    balance_game DOUBLE PRECISION DEFAULT 0,
+    balance_chats DOUBLE PRECISION DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'active',
+    language TEXT NOT NULL DEFAULT 'en',
+    refer_from_id BIGINT,
+    last_streak_claim_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Groups table
+CREATE TABLE IF NOT EXISTS groups (
+    id BIGSERIAL PRIMARY KEY,
+    balance DOUBLE PRECISION DEFAULT 0,
+    telegram_id BIGINT NOT NULL UNIQUE,
+    title TEXT DEFAULT '',
+    url TEXT DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- User-Group relationship table
+CREATE TABLE IF NOT EXISTS user_group (
+    id BIGSERIAL PRIMARY KEY,
+    user_telegram_id BIGINT NOT NULL,
+    group_telegram_id BIGINT NOT NULL,
+    balance DOUBLE PRECISION DEFAULT 0,
+    UNIQUE(user_telegram_id, group_telegram_id)
+);
+
+-- Transactional outbox: events written in the same tx as the change they describe
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    topic TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    attempts BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMPTZ
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
+CREATE INDEX IF NOT EXISTS idx_groups_telegram_id ON groups(telegram_id);
+CREATE INDEX IF NOT EXISTS idx_user_group_user ON user_group(user_telegram_id);
+CREATE INDEX IF NOT EXISTS idx_user_group_group ON user_group(group_telegram_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(delivered_at, topic, id);
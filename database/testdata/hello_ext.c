@@ -0,0 +1,20 @@
+/* A loadable SQLite extension for sqlite_ext_test.go: hello() returns a
+   fixed string. Built by the test with the C compiler cgo uses; with
+   -DNAMED_ENTRY its entry point is the one SQLite derives for libhello.so. */
+#include <sqlite3ext.h>
+SQLITE_EXTENSION_INIT1
+
+static void hello(sqlite3_context *ctx, int argc, sqlite3_value **argv) {
+	sqlite3_result_text(ctx, "hello from an extension", -1, SQLITE_STATIC);
+}
+
+#ifdef NAMED_ENTRY
+#define ENTRY sqlite3_hello_init
+#else
+#define ENTRY sqlite3_extension_init
+#endif
+
+int ENTRY(sqlite3 *db, char **err, const sqlite3_api_routines *api) {
+	SQLITE_EXTENSION_INIT2(api);
+	return sqlite3_create_function(db, "hello", 0, SQLITE_UTF8 | SQLITE_DETERMINISTIC, 0, hello, 0, 0);
+}
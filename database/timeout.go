@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrQueryTimeout matches a QueryTimeoutError with errors.Is
+var ErrQueryTimeout = errors.New("query timed out")
+
+// QueryTimeoutError is a statement cut off by Config.QueryTimeout, or a
+// QueryPolicy's Timeout, as
+// opposed to the caller's own deadline or cancellation running out. Err
+// is what the driver returned, context.DeadlineExceeded or its own error
+// for an interrupted statement.
+type QueryTimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *QueryTimeoutError) Error() string {
+	return fmt.Sprintf("query timed out after %v: %v", e.Timeout, e.Err)
+}
+
+func (e *QueryTimeoutError) Is(target error) bool {
+	return target == ErrQueryTimeout
+}
+
+func (e *QueryTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+type noQueryTimeoutKey struct{}
+
+// ContextWithoutQueryTimeout returns ctx for statements that may run past
+// Config.QueryTimeout or their policy's Timeout, such as a StreamUsers loop over a whole table. A
+// deadline of ctx's own has the same effect, and still applies.
+func ContextWithoutQueryTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noQueryTimeoutKey{}, true)
+}
+
+// timeoutDBTX gives every statement whose context has no deadline one
+// timeout from now, or its policy's Timeout. A query's deadline covers
+// reading its rows too.
+type timeoutDBTX struct {
+	DBTX
+	timeout  time.Duration
+	policies *queryPolicies
+}
+
+// withTimeout is ctx with the query's timeout, or ctx as it is if it has
+// a deadline, opted out or the query has no timeout
+func (d *timeoutDBTX) withTimeout(ctx context.Context, query string) (context.Context, context.CancelFunc, time.Duration) {
+	timeout := d.policies.timeout(query, d.timeout)
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 || ctx.Value(noQueryTimeoutKey{}) != nil {
+		return ctx, func() {}, 0
+	}
+	tctx, cancel := context.WithTimeout(ctx, timeout)
+	return tctx, cancel, timeout
+}
+
+// timedOut wraps err when tctx's deadline ran out while ctx, the
+// caller's, is still live
+func timedOut(ctx, tctx context.Context, timeout time.Duration, err error) error {
+	if err != nil && ctx.Err() == nil && errors.Is(tctx.Err(), context.DeadlineExceeded) {
+		return &QueryTimeoutError{Timeout: timeout, Err: err}
+	}
+	return err
+}
+
+func (d *timeoutDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	tctx, cancel, timeout := d.withTimeout(ctx, query)
+	defer cancel()
+	res, err := d.DBTX.ExecContext(tctx, query, args...)
+	return res, timedOut(ctx, tctx, timeout, err)
+}
+
+func (d *timeoutDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	tctx, cancel, timeout := d.withTimeout(ctx, query)
+	defer cancel()
+	stmt, err := d.DBTX.PrepareContext(tctx, query)
+	return stmt, timedOut(ctx, tctx, timeout, err)
+}
+
+// The rows are read under tctx, so it's canceled once they're closed,
+// and an error reading them past the deadline is a QueryTimeoutError too
+func (d *timeoutDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	tctx, cancel, timeout := d.withTimeout(ctx, query)
+	rows, err := d.DBTX.QueryContext(tctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, timedOut(ctx, tctx, timeout, err)
+	}
+	if timeout == 0 {
+		return rows, nil
+	}
+	return watchRows(ctx, rows, timeoutWatch(ctx, tctx, cancel, timeout))
+}
+
+// Likewise for the row, until Scan
+func (d *timeoutDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	tctx, cancel, timeout := d.withTimeout(ctx, query)
+	if timeout == 0 {
+		return d.DBTX.QueryRowContext(ctx, query, args...)
+	}
+	rows, err := d.DBTX.QueryContext(tctx, query, args...)
+	if err != nil {
+		cancel()
+		return errRow(ctx, timedOut(ctx, tctx, timeout, err))
+	}
+	return watchRow(ctx, rows, timeoutWatch(ctx, tctx, cancel, timeout))
+}
+
+func timeoutWatch(ctx, tctx context.Context, cancel context.CancelFunc, timeout time.Duration) rowsWatch {
+	return rowsWatch{
+		mapErr: func(err error) error { return timedOut(ctx, tctx, timeout, err) },
+		done:   func(int64, error) { cancel() },
+	}
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// queryName reads the name from the "-- name: GetUserByID :one" line sqlc
+// puts in front of every query. Anything that isn't a generated query is
+// "other", which keeps the set of names (and the memory spent per name)
+// bounded.
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(query), "-- name: ")
+	if !ok {
+		return "other"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	if _, ok := querySQL[name]; !ok {
+		return "other"
+	}
+	return name
+}
+
+// timingDBTX times every statement for the slow query log, the latency
+// stats and the query log; any of them may be nil
+type timingDBTX struct {
+	DBTX
+	slow    *slowLog
+	latency *latencyStats
+	log     *queryLog
+}
+
+func (d *timingDBTX) observe(ctx context.Context, query string, args []any, start time.Time, rows int64, err error) {
+	elapsed := time.Since(start)
+	name := queryName(query)
+
+	if d.latency != nil {
+		d.latency.observe(name, elapsed, err != nil)
+	}
+	if d.slow != nil {
+		d.slow.record(ctx, query, name, elapsed, args, start, rows, err)
+	}
+	if d.log != nil {
+		d.log.log(ctx, query, name, elapsed, args, rows, err)
+	}
+}
+
+func (d *timingDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := d.DBTX.ExecContext(ctx, query, args...)
+
+	rows := int64(-1)
+	if err == nil {
+		if n, err := res.RowsAffected(); err == nil {
+			rows = n
+		}
+	}
+	d.observe(ctx, query, args, start, rows, err)
+	return res, err
+}
+
+// A query is timed until its rows are closed, since reading them is most
+// of its work (all of it on SQLite, which runs the statement from Next)
+func (d *timingDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := d.DBTX.QueryContext(ctx, query, args...)
+	if err != nil {
+		d.observe(ctx, query, args, start, -1, err)
+		return nil, err
+	}
+	return watchRows(ctx, rows, d.watch(ctx, query, args, start))
+}
+
+// Likewise until the row is scanned, with the error Scan got
+func (d *timingDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	rows, err := d.DBTX.QueryContext(ctx, query, args...)
+	if err != nil {
+		d.observe(ctx, query, args, start, -1, err)
+		return errRow(ctx, err)
+	}
+	return watchRow(ctx, rows, d.watch(ctx, query, args, start))
+}
+
+func (d *timingDBTX) watch(ctx context.Context, query string, args []any, start time.Time) rowsWatch {
+	return rowsWatch{done: func(n int64, err error) {
+		d.observe(ctx, query, args, start, n, err)
+	}}
+}
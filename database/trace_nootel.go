@@ -0,0 +1,32 @@
+//go:build !otel
+
+package database
+
+import (
+	"context"
+	"errors"
+)
+
+// Trace IDs are read from OpenTelemetry spans in builds with -tags otel,
+// so that the dependency is opt-in
+func traceIDFromContext(context.Context) string {
+	return ""
+}
+
+// queryTracer is never created without -tags otel
+type queryTracer struct{}
+
+func newQueryTracer(enabled bool, _ *redactor) (*queryTracer, error) {
+	if enabled {
+		return nil, errors.New("TraceQueries needs a build with -tags otel")
+	}
+	return nil, nil
+}
+
+func (t *queryTracer) startTx(ctx context.Context) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+
+func (t *queryTracer) wrap(dbtx DBTX, _ *Tx) DBTX {
+	return dbtx
+}
@@ -0,0 +1,139 @@
+//go:build otel
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceIDFromContext returns the trace ID of the OpenTelemetry span in
+// ctx, "" if there's none
+func traceIDFromContext(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// queryTracer makes the spans of Config.TraceQueries, from the global
+// TracerProvider
+type queryTracer struct {
+	tracer trace.Tracer
+	system string // db.system
+	redact *redactor
+}
+
+func newQueryTracer(enabled bool, redact *redactor) (*queryTracer, error) {
+	if !enabled {
+		return nil, nil
+	}
+	return &queryTracer{
+		tracer: otel.Tracer("your-project/database"),
+		system: defaultDialect().system,
+		redact: redact,
+	}, nil
+}
+
+// startTx starts the span of a transaction; end closes it with the
+// transaction's outcome
+func (t *queryTracer) startTx(ctx context.Context) (_ context.Context, end func(error)) {
+	if t == nil {
+		return ctx, func(error) {}
+	}
+	ctx, span := t.tracer.Start(ctx, "transaction", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", t.system)))
+	return ctx, func(err error) {
+		endSpan(span, -1, err)
+	}
+}
+
+// wrap traces the statements run through dbtx; tx is the transaction it
+// belongs to, nil outside one
+func (t *queryTracer) wrap(dbtx DBTX, tx *Tx) DBTX {
+	if t == nil {
+		return dbtx
+	}
+	return &tracingDBTX{DBTX: dbtx, t: t, tx: tx}
+}
+
+// tracingDBTX gives every statement a span named after its query. In a
+// transaction the span is a child of the transaction's, whichever
+// context the statement was run with.
+type tracingDBTX struct {
+	DBTX
+	t  *queryTracer
+	tx *Tx
+}
+
+// start starts the statement's span. The arguments Config.Redaction
+// doesn't mask entirely are db.query.parameter.<i> attributes, from 0.
+func (d *tracingDBTX) start(ctx context.Context, query string, args []any) (context.Context, trace.Span) {
+	if d.tx != nil {
+		ctx = trace.ContextWithSpan(ctx, trace.SpanFromContext(d.tx.ctx))
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", d.t.system),
+		attribute.String("db.operation", firstKeyword(query)),
+		attribute.String("db.statement", query), // Placeholders only, the arguments follow
+	}
+	if len(args) > 0 {
+		modes := d.t.redact.modes(query, len(args), "")
+		for i, a := range args {
+			if modes[i] != RedactAll {
+				attrs = append(attrs, attribute.String(fmt.Sprintf("db.query.parameter.%d", i), showArg(a, modes[i])))
+			}
+		}
+	}
+	return d.t.tracer.Start(ctx, queryName(query), trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan records rows affected, if known, and a failure; sql.ErrNoRows
+// is an answer, not a failure
+func endSpan(span trace.Span, rows int64, err error) {
+	if rows >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", rows))
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (d *tracingDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := d.start(ctx, query, args)
+	res, err := d.DBTX.ExecContext(ctx, query, args...)
+	rows := int64(-1)
+	if err == nil {
+		if n, err := res.RowsAffected(); err == nil {
+			rows = n
+		}
+	}
+	endSpan(span, rows, err)
+	return res, err
+}
+
+// The span ends when the call returns, before the rows are read
+func (d *tracingDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := d.start(ctx, query, args)
+	rows, err := d.DBTX.QueryContext(ctx, query, args...)
+	endSpan(span, -1, err)
+	return rows, err
+}
+
+// A *sql.Row holds its error until Scan, so there's none to record here
+func (d *tracingDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := d.start(ctx, query, args)
+	row := d.DBTX.QueryRowContext(ctx, query, args...)
+	endSpan(span, -1, nil)
+	return row
+}
@@ -0,0 +1,448 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// translatingQuerier is db.Q with every error passed through Translate, so
+// callers can use errors.Is(err, ErrNotFound) and the other errors of this
+// package on any query. It doesn't embed Querier, so a new query fails
+// the build until it has a method here too.
+type translatingQuerier struct {
+	q Querier
+}
+
+func (t translatingQuerier) WithTx(tx *sql.Tx) *Queries {
+	return t.q.WithTx(tx)
+}
+
+func (t translatingQuerier) AddToUserGroupBalance(ctx context.Context, arg AddToUserGroupBalanceParams) (UserGroup, error) {
+	res, err := t.q.AddToUserGroupBalance(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) AttachTag(ctx context.Context, arg AttachTagParams) error {
+	return Translate(t.q.AttachTag(ctx, arg))
+}
+
+func (t translatingQuerier) BuryJob(ctx context.Context, arg BuryJobParams) error {
+	return Translate(t.q.BuryJob(ctx, arg))
+}
+
+func (t translatingQuerier) ClaimJobs(ctx context.Context, arg ClaimJobsParams) ([]Job, error) {
+	res, err := t.q.ClaimJobs(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error) {
+	res, err := t.q.ClaimOutboxEvents(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) CompleteJob(ctx context.Context, arg CompleteJobParams) error {
+	return Translate(t.q.CompleteJob(ctx, arg))
+}
+
+func (t translatingQuerier) CountUsersByStatus(ctx context.Context, status Status) (int64, error) {
+	res, err := t.q.CountUsersByStatus(ctx, status)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error) {
+	res, err := t.q.CreateCategory(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error) {
+	res, err := t.q.CreateGroup(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) CreateGroupIfMissing(ctx context.Context, arg CreateGroupIfMissingParams) (Group, error) {
+	res, err := t.q.CreateGroupIfMissing(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	res, err := t.q.CreateUser(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) CreateUserGroup(ctx context.Context, arg CreateUserGroupParams) (UserGroup, error) {
+	res, err := t.q.CreateUserGroup(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) CreateUserIfMissing(ctx context.Context, arg CreateUserIfMissingParams) (User, error) {
+	res, err := t.q.CreateUserIfMissing(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) DeleteAttachment(ctx context.Context, id int64) error {
+	return Translate(t.q.DeleteAttachment(ctx, id))
+}
+
+func (t translatingQuerier) DeleteGroup(ctx context.Context, telegramID int64) (Group, error) {
+	res, err := t.q.DeleteGroup(ctx, telegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) DeleteJob(ctx context.Context, id int64) (int64, error) {
+	res, err := t.q.DeleteJob(ctx, id)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) DetachTag(ctx context.Context, arg DetachTagParams) error {
+	return Translate(t.q.DetachTag(ctx, arg))
+}
+
+func (t translatingQuerier) EnqueueJob(ctx context.Context, arg EnqueueJobParams) (Job, error) {
+	res, err := t.q.EnqueueJob(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) FailOutboxEvent(ctx context.Context, arg FailOutboxEventParams) error {
+	return Translate(t.q.FailOutboxEvent(ctx, arg))
+}
+
+func (t translatingQuerier) GetAncestors(ctx context.Context, arg GetAncestorsParams) ([]GetAncestorsRow, error) {
+	res, err := t.q.GetAncestors(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetAttachmentMeta(ctx context.Context, id int64) (GetAttachmentMetaRow, error) {
+	res, err := t.q.GetAttachmentMeta(ctx, id)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetAuditLog(ctx context.Context, arg GetAuditLogParams) ([]AuditLog, error) {
+	res, err := t.q.GetAuditLog(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetChildCategoryByName(ctx context.Context, arg GetChildCategoryByNameParams) (Category, error) {
+	res, err := t.q.GetChildCategoryByName(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetDescendants(ctx context.Context, arg GetDescendantsParams) ([]GetDescendantsRow, error) {
+	res, err := t.q.GetDescendants(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetGroupBalanceSummary(ctx context.Context, groupTelegramID int64) (GetGroupBalanceSummaryRow, error) {
+	res, err := t.q.GetGroupBalanceSummary(ctx, groupTelegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetGroupByTelegramID(ctx context.Context, telegramID int64) (Group, error) {
+	res, err := t.q.GetGroupByTelegramID(ctx, telegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetGroupHistory(ctx context.Context, id int64) ([]GroupHistory, error) {
+	res, err := t.q.GetGroupHistory(ctx, id)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetOrCreateUserGroup(ctx context.Context, arg GetOrCreateUserGroupParams) (UserGroup, error) {
+	res, err := t.q.GetOrCreateUserGroup(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetTopGroupsForUser(ctx context.Context, userTelegramID int64) ([]GetTopGroupsForUserRow, error) {
+	res, err := t.q.GetTopGroupsForUser(ctx, userTelegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetTopUsersByBalance(ctx context.Context, limit int64) ([]User, error) {
+	res, err := t.q.GetTopUsersByBalance(ctx, limit)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetTopUsersInGroup(ctx context.Context, groupTelegramID int64) ([]GetTopUsersInGroupRow, error) {
+	res, err := t.q.GetTopUsersInGroup(ctx, groupTelegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetTotalGroupBalance(ctx context.Context, groupTelegramID int64) (interface{}, error) {
+	res, err := t.q.GetTotalGroupBalance(ctx, groupTelegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetTotalUserBalance(ctx context.Context, userTelegramID int64) (interface{}, error) {
+	res, err := t.q.GetTotalUserBalance(ctx, userTelegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	res, err := t.q.GetUserByEmail(ctx, email)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetUserByID(ctx context.Context, id int64) (User, error) {
+	res, err := t.q.GetUserByID(ctx, id)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetUserByNationalIDIndex(ctx context.Context, nationalIDIndex []byte) (User, error) {
+	res, err := t.q.GetUserByNationalIDIndex(ctx, nationalIDIndex)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetUserByTelegramID(ctx context.Context, telegramID int64) (User, error) {
+	res, err := t.q.GetUserByTelegramID(ctx, telegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetUserGroup(ctx context.Context, arg GetUserGroupParams) (UserGroup, error) {
+	res, err := t.q.GetUserGroup(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetUserHistory(ctx context.Context, id int64) ([]UserHistory, error) {
+	res, err := t.q.GetUserHistory(ctx, id)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetUserIDRange(ctx context.Context) (GetUserIDRangeRow, error) {
+	res, err := t.q.GetUserIDRange(ctx)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetUserNationalID(ctx context.Context, userTelegramID int64) ([]byte, error) {
+	res, err := t.q.GetUserNationalID(ctx, userTelegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) GetUserPosition(ctx context.Context, balanceGame sql.Null[Money]) (int64, error) {
+	res, err := t.q.GetUserPosition(ctx, balanceGame)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) InsertOutboxEvent(ctx context.Context, arg InsertOutboxEventParams) (Outbox, error) {
+	res, err := t.q.InsertOutboxEvent(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListAuditLogByActor(ctx context.Context, arg ListAuditLogByActorParams) ([]AuditLog, error) {
+	res, err := t.q.ListAuditLogByActor(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListAuditLogFieldChanges(ctx context.Context, arg ListAuditLogFieldChangesParams) ([]AuditLog, error) {
+	res, err := t.q.ListAuditLogFieldChanges(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListCategoriesByName(ctx context.Context, name string) ([]Category, error) {
+	res, err := t.q.ListCategoriesByName(ctx, name)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListDeadJobs(ctx context.Context, limit int64) ([]Job, error) {
+	res, err := t.q.ListDeadJobs(ctx, limit)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListGroupMembers(ctx context.Context, groupTelegramID int64) ([]ListGroupMembersRow, error) {
+	res, err := t.q.ListGroupMembers(ctx, groupTelegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListGroupTags(ctx context.Context, groupTelegramID int64) ([]Tag, error) {
+	res, err := t.q.ListGroupTags(ctx, groupTelegramID)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListGroupsByTag(ctx context.Context, arg ListGroupsByTagParams) ([]Group, error) {
+	res, err := t.q.ListGroupsByTag(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListGroupsByTitle(ctx context.Context, limit int64) ([]Group, error) {
+	res, err := t.q.ListGroupsByTitle(ctx, limit)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListGroupsWithAllTags(ctx context.Context, arg ListGroupsWithAllTagsParams) ([]Group, error) {
+	res, err := t.q.ListGroupsWithAllTags(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListNationalIDCiphertexts(ctx context.Context, arg ListNationalIDCiphertextsParams) ([]ListNationalIDCiphertextsRow, error) {
+	res, err := t.q.ListNationalIDCiphertexts(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListUsersByCreatedAt(ctx context.Context, arg ListUsersByCreatedAtParams) ([]User, error) {
+	res, err := t.q.ListUsersByCreatedAt(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListUsersByFirstName(ctx context.Context, limit int64) ([]User, error) {
+	res, err := t.q.ListUsersByFirstName(ctx, limit)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListUsersByIDs(ctx context.Context, ids []int64) ([]User, error) {
+	res, err := t.q.ListUsersByIDs(ctx, ids)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListUsersByStatus(ctx context.Context, arg ListUsersByStatusParams) ([]User, error) {
+	res, err := t.q.ListUsersByStatus(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListUsersMatchingUsername(ctx context.Context, arg ListUsersMatchingUsernameParams) ([]User, error) {
+	res, err := t.q.ListUsersMatchingUsername(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ListUsersWithGroups(ctx context.Context, limit int64) ([]ListUsersWithGroupsRow, error) {
+	res, err := t.q.ListUsersWithGroups(ctx, limit)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) MarkOutboxEventDelivered(ctx context.Context, id int64) error {
+	return Translate(t.q.MarkOutboxEventDelivered(ctx, id))
+}
+
+func (t translatingQuerier) PruneAuditLog(ctx context.Context, arg PruneAuditLogParams) (int64, error) {
+	res, err := t.q.PruneAuditLog(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) PurgeDeletedUsers(ctx context.Context, olderThanSeconds int64) (int64, error) {
+	res, err := t.q.PurgeDeletedUsers(ctx, olderThanSeconds)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) PutAttachment(ctx context.Context, arg PutAttachmentParams) (PutAttachmentRow, error) {
+	res, err := t.q.PutAttachment(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ReadAttachmentChunk(ctx context.Context, arg ReadAttachmentChunkParams) ([]byte, error) {
+	res, err := t.q.ReadAttachmentChunk(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) RenameCategory(ctx context.Context, arg RenameCategoryParams) (Category, error) {
+	res, err := t.q.RenameCategory(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) ReplaceNationalIDCiphertext(ctx context.Context, arg ReplaceNationalIDCiphertextParams) (int64, error) {
+	res, err := t.q.ReplaceNationalIDCiphertext(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) RequeueDeadJob(ctx context.Context, arg RequeueDeadJobParams) (int64, error) {
+	res, err := t.q.RequeueDeadJob(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) RestoreUser(ctx context.Context, id int64) (int64, error) {
+	res, err := t.q.RestoreUser(ctx, id)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) RetryJob(ctx context.Context, arg RetryJobParams) error {
+	return Translate(t.q.RetryJob(ctx, arg))
+}
+
+func (t translatingQuerier) SampleUsers(ctx context.Context, limit int64) ([]User, error) {
+	res, err := t.q.SampleUsers(ctx, limit)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) SampleUsersSeeded(ctx context.Context, arg SampleUsersSeededParams) ([]User, error) {
+	res, err := t.q.SampleUsersSeeded(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) SearchGroups(ctx context.Context, arg SearchGroupsParams) ([]SearchGroupsRow, error) {
+	res, err := t.q.SearchGroups(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	res, err := t.q.SearchUsers(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) SetCategoryParent(ctx context.Context, arg SetCategoryParentParams) (Category, error) {
+	res, err := t.q.SetCategoryParent(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) SetUserNationalID(ctx context.Context, arg SetUserNationalIDParams) error {
+	return Translate(t.q.SetUserNationalID(ctx, arg))
+}
+
+func (t translatingQuerier) SoftDeleteUser(ctx context.Context, id int64) (int64, error) {
+	res, err := t.q.SoftDeleteUser(ctx, id)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) TouchStorageProbe(ctx context.Context) error {
+	return Translate(t.q.TouchStorageProbe(ctx))
+}
+
+func (t translatingQuerier) UpdateStatusByIDs(ctx context.Context, arg UpdateStatusByIDsParams) (int64, error) {
+	res, err := t.q.UpdateStatusByIDs(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	res, err := t.q.UpdateUser(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) UpdateUserBalanceChats(ctx context.Context, arg UpdateUserBalanceChatsParams) (User, error) {
+	res, err := t.q.UpdateUserBalanceChats(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) UpdateUserBalanceChatsIfVersion(ctx context.Context, arg UpdateUserBalanceChatsIfVersionParams) (int64, error) {
+	res, err := t.q.UpdateUserBalanceChatsIfVersion(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) UpdateUserGroupBalance(ctx context.Context, arg UpdateUserGroupBalanceParams) (UserGroup, error) {
+	res, err := t.q.UpdateUserGroupBalance(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) UpdateUserIfVersion(ctx context.Context, arg UpdateUserIfVersionParams) (int64, error) {
+	res, err := t.q.UpdateUserIfVersion(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) UpsertGroup(ctx context.Context, arg UpsertGroupParams) (Group, error) {
+	res, err := t.q.UpsertGroup(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) UpsertTag(ctx context.Context, name string) (Tag, error) {
+	res, err := t.q.UpsertTag(ctx, name)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) UpsertUser(ctx context.Context, arg UpsertUserParams) (User, error) {
+	res, err := t.q.UpsertUser(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) UpsertUserByEmail(ctx context.Context, arg UpsertUserByEmailParams) (User, error) {
+	res, err := t.q.UpsertUserByEmail(ctx, arg)
+	return res, Translate(err)
+}
+
+func (t translatingQuerier) UpsertUserGroupBalance(ctx context.Context, arg UpsertUserGroupBalanceParams) (UserGroup, error) {
+	res, err := t.q.UpsertUserGroupBalance(ctx, arg)
+	return res, Translate(err)
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// defaultMaxTreeDepth caps the recursive category queries when
+// Config.MaxTreeDepth isn't set
+const defaultMaxTreeDepth = 100
+
+var (
+	// ErrTreeCycle is returned by MoveSubtree when the new parent is the
+	// category itself or one of its descendants
+	ErrTreeCycle = errors.New("move would create a cycle in the category tree")
+
+	// ErrTreeTooDeep is returned when a traversal hits the depth cap, which
+	// means the tree is deeper than MaxTreeDepth or already has a cycle
+	ErrTreeTooDeep = errors.New("category tree is deeper than the configured MaxTreeDepth")
+)
+
+func (db *DB) treeDepth() int64 {
+	if db.maxTreeDepth > 0 {
+		return int64(db.maxTreeDepth)
+	}
+	return defaultMaxTreeDepth
+}
+
+// GetDescendants returns the category and its whole subtree, ordered by
+// depth (the category itself is depth 0)
+func (db *DB) GetDescendants(ctx context.Context, id int64) ([]GetDescendantsRow, error) {
+	return descendants(ctx, db.Q, id, db.treeDepth())
+}
+
+// GetAncestors returns the path from the category up to its root, starting
+// with the category itself at depth 0
+func (db *DB) GetAncestors(ctx context.Context, id int64) ([]GetAncestorsRow, error) {
+	maxDepth := db.treeDepth()
+	rows, err := db.Q.GetAncestors(ctx, GetAncestorsParams{ID: id, MaxDepth: maxDepth})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 && rows[len(rows)-1].Depth >= maxDepth && rows[len(rows)-1].ParentID.Valid {
+		return nil, ErrTreeTooDeep
+	}
+	return rows, nil
+}
+
+// MoveSubtree re-parents the category, taking its subtree along. An invalid
+// newParent makes it a root. Moving a category under itself or one of its
+// descendants fails with ErrTreeCycle.
+func (db *DB) MoveSubtree(ctx context.Context, id int64, newParent sql.Null[int64]) error {
+	maxDepth := db.treeDepth()
+
+	return db.Transaction(ctx, func(q *Queries) error {
+		if newParent.Valid {
+			subtree, err := descendants(ctx, q, id, maxDepth)
+			if err != nil {
+				return err
+			}
+			for _, c := range subtree {
+				if c.ID == newParent.V {
+					return ErrTreeCycle
+				}
+			}
+		}
+
+		if _, err := q.SetCategoryParent(ctx, SetCategoryParentParams{ParentID: newParent, ID: id}); err != nil {
+			return fmt.Errorf("failed to move category %d: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// descendants runs GetDescendants and fails if the cap cut the subtree off,
+// since a truncated subtree can't prove the absence of a cycle
+func descendants(ctx context.Context, q Querier, id, maxDepth int64) ([]GetDescendantsRow, error) {
+	rows, err := q.GetDescendants(ctx, GetDescendantsParams{ID: id, MaxDepth: maxDepth})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 && rows[len(rows)-1].Depth >= maxDepth {
+		return nil, ErrTreeTooDeep
+	}
+	return rows, nil
+}
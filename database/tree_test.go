@@ -0,0 +1,102 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"your-project/database"
+	"your-project/database/dbtest"
+	"your-project/database/nulls"
+)
+
+// newTree makes root > a > a1, and root > b, returning the ids by name
+func newTree(t *testing.T, db *database.DB) map[string]int64 {
+	t.Helper()
+	ids := map[string]int64{}
+	for _, c := range [][2]string{{"root", ""}, {"a", "root"}, {"b", "root"}, {"a1", "a"}} {
+		var parent sql.Null[int64]
+		if c[1] != "" {
+			parent = nulls.Int64(ids[c[1]])
+		}
+		cat, err := db.Q.CreateCategory(context.Background(), database.CreateCategoryParams{ParentID: parent, Name: c[0]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[c[0]] = cat.ID
+	}
+	return ids
+}
+
+// path lists the rows' names with their depths
+func path[T database.GetDescendantsRow | database.GetAncestorsRow](rows []T) string {
+	s := ""
+	for _, r := range rows {
+		r := database.GetDescendantsRow(r)
+		s += fmt.Sprintf("%s:%d ", r.Name, r.Depth)
+	}
+	return s
+}
+
+func TestCategoryTree(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDB(t)
+	ids := newTree(t, db)
+
+	desc, err := db.GetDescendants(ctx, ids["root"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := path(desc), "root:0 a:1 b:1 a1:2 "; got != want {
+		t.Errorf("descendants of root: %s, want %s", got, want)
+	}
+	anc, err := db.GetAncestors(ctx, ids["a1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := path(anc), "a1:0 a:1 root:2 "; got != want {
+		t.Errorf("ancestors of a1: %s, want %s", got, want)
+	}
+
+	for _, under := range []string{"a", "a1"} {
+		if err := db.MoveSubtree(ctx, ids["a"], nulls.Int64(ids[under])); !errors.Is(err, database.ErrTreeCycle) {
+			t.Errorf("moving a under %s: %v, want ErrTreeCycle", under, err)
+		}
+	}
+	if err := db.MoveSubtree(ctx, ids["a"], nulls.Int64(ids["b"])); err != nil {
+		t.Fatal(err)
+	}
+	desc, err = db.GetDescendants(ctx, ids["b"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := path(desc), "b:0 a:1 a1:2 "; got != want {
+		t.Errorf("descendants of b after moving a there: %s, want %s", got, want)
+	}
+}
+
+func TestCategoryTreeDepthCap(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.NewTestDBWith(t, dbtest.Options{Config: func(c *database.Config) { c.MaxTreeDepth = 1 }})
+	ids := newTree(t, db)
+
+	if _, err := db.GetDescendants(ctx, ids["root"]); !errors.Is(err, database.ErrTreeTooDeep) {
+		t.Errorf("descendants two levels down with a cap of 1: %v, want ErrTreeTooDeep", err)
+	}
+	if _, err := db.GetAncestors(ctx, ids["a1"]); !errors.Is(err, database.ErrTreeTooDeep) {
+		t.Errorf("ancestors two levels up with a cap of 1: %v, want ErrTreeTooDeep", err)
+	}
+	if _, err := db.GetAncestors(ctx, ids["a"]); err != nil {
+		t.Errorf("ancestors within the cap: %v", err)
+	}
+
+	// A cycle written past MoveSubtree stops at the cap instead of looping
+	if _, err := db.Q.SetCategoryParent(ctx, database.SetCategoryParentParams{ParentID: nulls.Int64(ids["a"]), ID: ids["root"]}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetAncestors(ctx, ids["a"]); !errors.Is(err, database.ErrTreeTooDeep) {
+		t.Errorf("ancestors in a cycle: %v, want ErrTreeTooDeep", err)
+	}
+}
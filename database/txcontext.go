@@ -0,0 +1,136 @@
+package database
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// WithTx runs fn in a transaction carried by the ctx fn gets, for code
+// that finds its queries with QueriesFromContext instead of being handed
+// a *Queries: whatever fn calls with that ctx, however deep, runs in the
+// one transaction. It commits when fn returns nil and rolls back
+// otherwise, like InTx, and nests in a transaction ctx already carries.
+func (db *DB) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return db.InTx(ctx, func(tx *Tx) error {
+		return fn(tx.Context())
+	})
+}
+
+// TxFromContext returns db's transaction that ctx carries, from WithTx,
+// TxMiddleware or Tx.Context, while it's open
+func (db *DB) TxFromContext(ctx context.Context) (*Tx, bool) {
+	t, ok := ctx.Value(txKey{db}).(*Tx)
+	if !ok || t.done {
+		return nil, false
+	}
+	return t, true
+}
+
+// QueriesFromContext returns the queries of db's transaction that ctx
+// carries, or db.Q outside one, so service code needs only the ctx it
+// was called with to run in its caller's transaction:
+//
+//	func (s *Billing) Charge(ctx context.Context, userID int64, amount float64) error {
+//		q := s.db.QueriesFromContext(ctx)
+//		// ...
+//	}
+//
+// Errors are translated as db.Q's are. A transaction that has ended
+// isn't found, so a goroutine that outlives it runs on db.Q.
+func (db *DB) QueriesFromContext(ctx context.Context) Querier {
+	if t, ok := db.TxFromContext(ctx); ok {
+		return t.Querier
+	}
+	return db.Q
+}
+
+// errRequestFailed rolls back TxMiddleware's transaction
+var errRequestFailed = errors.New("request failed")
+
+// TxMiddleware runs each request in a transaction of db, whose ctx the
+// handler gets through r.Context(), so everything it calls through
+// QueriesFromContext or WithTx shares it. The transaction commits when
+// the handler answers with a status below 400 and rolls back when it
+// answers 400 or above, or panics. GET, HEAD and OPTIONS requests get a
+// read-only transaction.
+//
+// The response is held back until the commit, so a commit that fails is
+// answered with a 500 instead of the success the handler wrote. That
+// makes it unfit for streaming responses, which arrive all at once, and
+// each request holds a connection, and a write slot unless it's
+// read-only, until it's answered; keep slow handlers out from behind it.
+// A request that can't begin its transaction (ErrShuttingDown,
+// ErrCircuitOpen) is a 503 without the handler running.
+func (db *DB) TxMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opts *sql.TxOptions
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			opts = &sql.TxOptions{ReadOnly: true}
+		}
+
+		res := &txResponse{header: w.Header()}
+		ran := false
+		var panicked any
+		err := db.inTx(r.Context(), db.immediateTx, opts, func(tx *Tx) (err error) {
+			ran = true
+			defer func() {
+				if p := recover(); p != nil {
+					panicked, err = p, errRequestFailed
+				}
+			}()
+			next.ServeHTTP(res, r.WithContext(tx.Context()))
+			if res.status >= http.StatusBadRequest {
+				return errRequestFailed
+			}
+			return nil
+		})
+		if panicked != nil {
+			panic(panicked) // rolled back; net/http logs it and drops the connection
+		}
+
+		switch {
+		case err == nil || errors.Is(err, errRequestFailed):
+			res.send(w)
+		case !ran:
+			log.Printf("database tx middleware: %s %s: %v", r.Method, r.URL.Path, err)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		default:
+			log.Printf("database tx middleware: %s %s: %v", r.Method, r.URL.Path, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	})
+}
+
+// txResponse holds TxMiddleware's response until the transaction is over.
+// The header is the real one's, which nothing is sent of before send.
+type txResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *txResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *txResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *txResponse) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+func (r *txResponse) send(w http.ResponseWriter) {
+	w.WriteHeader(cmp.Or(r.status, http.StatusOK))
+	w.Write(r.body.Bytes())
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"log"
+)
+
+// Writer writes rows of one kind in the background, for telemetry-style
+// writes nobody waits on: Submit queues the params and returns, and the
+// rows are written in batches by a WriteBatcher, so thousands of them
+// take a few transactions, and SQLite's write lock, instead of one each.
+// A row whose write fails goes to WriterOptions.OnError; the rest of its
+// batch still commits.
+type Writer[P any] struct {
+	b       *WriteBatcher
+	write   func(ctx context.Context, q *Queries, p P) error
+	onError func(p P, err error)
+}
+
+// WriterOptions configures a Writer. MaxItems, MaxDelay and QueueSize
+// are the WriteBatcher's; QueueSize is how many rows may wait before
+// Submit blocks.
+type WriterOptions[P any] struct {
+	BatcherOptions
+
+	// OnError is told of each row that wasn't written, with why: its
+	// query failed, or its batch didn't commit. It's called from the
+	// Writer's goroutine, so it shouldn't block. Without it, the errors
+	// are logged.
+	OnError func(p P, err error)
+}
+
+// NewWriter starts a Writer that writes each row with write:
+//
+//	w := database.NewWriter(db, database.WriterOptions[database.AddToUserGroupBalanceParams]{},
+//		func(ctx context.Context, q *database.Queries, p database.AddToUserGroupBalanceParams) error {
+//			_, err := q.AddToUserGroupBalance(ctx, p)
+//			return err
+//		})
+//
+// Shut it down with Shutdown, or DB.Shutdown, to write what's queued.
+func NewWriter[P any](db *DB, opts WriterOptions[P], write func(ctx context.Context, q *Queries, p P) error) *Writer[P] {
+	w := &Writer[P]{b: db.NewWriteBatcher(opts.BatcherOptions), write: write, onError: opts.OnError}
+	if w.onError == nil {
+		w.onError = func(_ P, err error) { log.Printf("database writer: row not written: %v", err) }
+	}
+	return w
+}
+
+// Submit queues p. It blocks while the queue is full, until ctx ends,
+// which is the backpressure that keeps a burst from piling up in memory;
+// it returns ctx's error then, and ErrBatcherClosed after Shutdown.
+func (w *Writer[P]) Submit(ctx context.Context, p P) error {
+	return w.b.enqueue(ctx, w.item(p), true)
+}
+
+// TrySubmit queues p if there's room, and reports whether there was, for
+// writes that may be dropped rather than hold up the caller
+func (w *Writer[P]) TrySubmit(p P) bool {
+	return w.b.enqueue(context.Background(), w.item(p), false) == nil
+}
+
+func (w *Writer[P]) item(p P) *batchItem {
+	return &batchItem{
+		exec: func(ctx context.Context, q *Queries) error {
+			return w.write(ctx, q, p)
+		},
+		deliver: func(err error) {
+			if err != nil {
+				w.onError(p, err)
+			}
+		},
+	}
+}
+
+// Flush writes everything submitted so far now, see WriteBatcher.Flush
+func (w *Writer[P]) Flush(ctx context.Context) error {
+	return w.b.Flush(ctx)
+}
+
+// Shutdown stops accepting rows and writes everything already queued
+func (w *Writer[P]) Shutdown(ctx context.Context) error {
+	return w.b.Shutdown(ctx)
+}